@@ -114,7 +114,7 @@ func runPause() {
 		exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
 	}
 
-	uids, err := cluster.Pause(cr, r, []string{"kube-system"})
+	uids, err := cluster.Pause(cr, r, []string{"kube-system"}, nil)
 	if err != nil {
 		exit.Error(reason.GuestPause, "Pause", err)
 	}
@@ -136,7 +136,7 @@ func runUnpause() {
 		exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
 	}
 
-	uids, err := cluster.Unpause(cr, r, nil)
+	uids, err := cluster.Unpause(cr, r, nil, nil)
 	if err != nil {
 		exit.Error(reason.GuestUnpause, "Unpause", err)
 	}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/detect"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/image"
+	"k8s.io/minikube/pkg/minikube/localpath"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/perf"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+var (
+	benchmarkImage      string
+	benchmarkIterations int
+	benchmarkProfiles   []string
+	benchmarkOutput     string
+)
+
+// benchmarkCmd represents the benchmark command
+var benchmarkCmd = &cobra.Command{
+	Use:   "benchmark",
+	Short: "Measure the performance of a cluster",
+	Long:  "Measure the performance of a cluster",
+}
+
+var benchmarkRuntimeCmd = &cobra.Command{
+	Use:   "runtime",
+	Short: "Time the operations of the container runtime of running clusters, to compare runtimes.",
+	Long: `Time the operations of the container runtime in the control plane of running clusters, for an image:
+pulling it after removing it, loading it from the image cache of the host after removing it, and creating, starting and removing a container of it running true.
+
+The durations are measured on the monotonic clock of the host, and the round trip of a no-op command is subtracted once for each command sent to the node.
+To compare runtimes, start a cluster with each --container-runtime and benchmark them together with --profiles.
+The image is removed from the runtimes, so it must not be used by the containers of the clusters.`,
+	Example: `minikube benchmark runtime --image busybox --iterations 10
+minikube benchmark runtime --profiles docker,containerd,crio -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube benchmark runtime [--image ref] [--iterations N] [--profiles p1,p2] [-o table|json]")
+		}
+		if benchmarkOutput != "table" && benchmarkOutput != "json" {
+			exit.Message(reason.Usage, "invalid output format: {{.output}}. Valid values: 'table', 'json'", out.V{"output": benchmarkOutput})
+		}
+		if benchmarkIterations < 1 {
+			exit.Message(reason.Usage, "The number of iterations must be at least 1")
+		}
+		profiles := benchmarkProfiles
+		if len(profiles) == 0 {
+			profiles = []string{ClusterFlagValue()}
+		}
+
+		archive, err := cacheBenchmarkImage(benchmarkImage)
+		if err != nil {
+			out.WarningT("Unable to cache {{.image}} on the host, its load will not be timed: {{.error}}", out.V{"image": benchmarkImage, "error": err})
+		}
+
+		ctx := interruptContext()
+		reports := []*perf.RuntimeReport{}
+		for _, p := range profiles {
+			r, err := benchmarkProfile(ctx, p, archive)
+			if errors.Is(err, context.Canceled) {
+				os.Exit(interruptedExitCode)
+			}
+			if err != nil {
+				exit.Error(reason.GuestRuntimeBenchmark, "Failed to benchmark the container runtime", err)
+			}
+			reports = append(reports, r)
+		}
+
+		if benchmarkOutput == "json" {
+			b, err := json.Marshal(reports)
+			if err != nil {
+				exit.Error(reason.InternalJSONMarshal, "Failed to marshal the benchmark", err)
+			}
+			fmt.Println(string(b))
+			return
+		}
+		renderBenchmarkTable(profiles, reports)
+	},
+}
+
+// cacheBenchmarkImage saves the image to the image cache of the host, returning the path of its archive
+func cacheBenchmarkImage(img string) (string, error) {
+	if err := image.SaveToDir([]string{img}, detect.ImageCacheDir(), false); err != nil {
+		return "", err
+	}
+	archive := localpath.SanitizeCacheDir(filepath.Join(detect.ImageCacheDir(), img))
+	if _, err := os.Stat(archive); err != nil {
+		return "", err
+	}
+	return archive, nil
+}
+
+// benchmarkProfile benchmarks the container runtime of the control plane of a running cluster
+func benchmarkProfile(ctx context.Context, profile string, archive string) (*perf.RuntimeReport, error) {
+	co := mustload.Running(profile)
+	if co.Config.AssumeOffline {
+		exit.Message(reason.Usage, "The cluster {{.profile}} was started with --assume-offline, and can not pull images", out.V{"profile": profile})
+	}
+	out.ErrT(style.Waiting, "Benchmarking {{.runtime}} of {{.profile}} with {{.image}} ...", out.V{"runtime": co.Config.KubernetesConfig.ContainerRuntime, "profile": profile, "image": benchmarkImage})
+	start := time.Now()
+	r, err := perf.BenchmarkRuntime(ctx, cruntime.Config{
+		Type:   co.Config.KubernetesConfig.ContainerRuntime,
+		Runner: co.CP.Runner,
+		Socket: co.Config.KubernetesConfig.CRISocket,
+	}, perf.RuntimeBenchmarkOptions{Image: benchmarkImage, Archive: archive, Iterations: benchmarkIterations})
+	klog.Infof("benchmarking %s took %s", profile, time.Since(start))
+	return r, err
+}
+
+// renderBenchmarkTable renders the statistics of each operation of each cluster
+func renderBenchmarkTable(profiles []string, reports []*perf.RuntimeReport) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Profile", "Runtime", "Operation", "Mean", "P95", "Min", "Max", "Samples"})
+	table.SetAutoFormatHeaders(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("|")
+	ms := func(d time.Duration) string {
+		return d.Round(time.Millisecond).String()
+	}
+	for i, r := range reports {
+		for _, s := range r.Operations {
+			table.Append([]string{profiles[i], r.Runtime, string(s.Operation), ms(s.Mean), ms(s.P95), ms(s.Min), ms(s.Max), strconv.Itoa(s.Samples)})
+		}
+	}
+	table.Render()
+}
+
+func init() {
+	benchmarkRuntimeCmd.Flags().StringVar(&benchmarkImage, "image", "gcr.io/k8s-minikube/busybox:latest", "The image to pull, load and run, which must have the true command.")
+	benchmarkRuntimeCmd.Flags().IntVar(&benchmarkIterations, "iterations", 5, "How many times each operation is timed.")
+	benchmarkRuntimeCmd.Flags().StringSliceVar(&benchmarkProfiles, "profiles", nil, "The running clusters to benchmark, to compare their runtimes. Defaults to the current profile.")
+	benchmarkRuntimeCmd.Flags().StringVarP(&benchmarkOutput, "output", "o", "table", "Format to print stdout in. Options include: [table,json]")
+	benchmarkCmd.AddCommand(benchmarkRuntimeCmd)
+}
@@ -17,17 +17,25 @@ limitations under the License.
 package cmd
 
 import (
+	"path/filepath"
+
+	units "github.com/docker/go-units"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"k8s.io/klog/v2"
 	cmdConfig "k8s.io/minikube/cmd/minikube/cmd/config"
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/detect"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/image"
+	"k8s.io/minikube/pkg/minikube/localpath"
 	"k8s.io/minikube/pkg/minikube/machine"
 	"k8s.io/minikube/pkg/minikube/node"
 	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/util"
 )
 
 // cacheImageConfigKey is the config field name used to store which images we have previously cached
@@ -35,6 +43,11 @@ const cacheImageConfigKey = "cache"
 
 const allFlag = "all"
 
+var (
+	cacheMaxSize   string
+	cacheOlderThan string
+)
+
 // cacheCmd represents the cache command
 var cacheCmd = &cobra.Command{
 	Use:   "cache",
@@ -50,7 +63,7 @@ var addCacheCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		out.WarningT("\"minikube cache\" will be deprecated in upcoming versions, please switch to \"minikube image load\"")
 		// Cache and load images into docker daemon
-		if err := machine.CacheAndLoadImages(args, cacheAddProfiles(), false); err != nil {
+		if err := machine.CacheAndLoadImages(args, cacheAddProfiles(), false, true, cruntime.ImageOutputBuffered); err != nil {
 			exit.Error(reason.InternalCacheLoad, "Failed to cache and load images", err)
 		}
 		// Add images to config file
@@ -110,9 +123,82 @@ var reloadCacheCmd = &cobra.Command{
 	},
 }
 
+// pruneCacheCmd represents the cache prune command
+var pruneCacheCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Evict the least recently used images from the local cache.",
+	Long:  "Evict the images not loaded into a node for a while, or the least recently used images until the local cache fits the size, from the local cache of the host.",
+	Example: `minikube cache prune --max-size=10GB
+minikube cache prune --older-than=30d`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cacheMaxSize == "" && cacheOlderThan == "" {
+			exit.Message(reason.Usage, "Specify --max-size, --older-than, or both")
+		}
+		o := image.CachePruneOptions{}
+		if cacheMaxSize != "" {
+			size, err := units.RAMInBytes(cacheMaxSize)
+			if err != nil {
+				exit.Message(reason.Usage, "Invalid --max-size: {{.error}}", out.V{"error": err})
+			}
+			o.MaxSize = size
+		}
+		if cacheOlderThan != "" {
+			age, err := util.ParseAge(cacheOlderThan)
+			if err != nil {
+				exit.Message(reason.Usage, "Invalid --older-than: {{.error}}", out.V{"error": err})
+			}
+			o.OlderThan = age
+		}
+		cacheDir := detect.ImageCacheDir()
+		evicted, err := image.PruneCache(cacheDir, o)
+		if err != nil {
+			exit.Error(reason.HostDelCache, "Failed to prune the image cache", err)
+		}
+		// the evicted images are not cached again on start
+		if images := evictedCacheImages(cacheDir, evicted); len(images) > 0 {
+			if err := cmdConfig.DeleteFromConfigMap(cacheImageConfigKey, images); err != nil {
+				exit.Error(reason.InternalDelConfig, "Failed to delete images from config", err)
+			}
+		}
+		var freed int64
+		for _, e := range evicted {
+			freed += e.Size
+		}
+		size, err := image.CacheSize(cacheDir)
+		if err != nil {
+			klog.Warningf("unable to get the size of the image cache: %v", err)
+		}
+		out.Step(style.Deleted, "Evicted {{.count}} images from the cache, freeing {{.freed}}. The cache now holds {{.size}}.",
+			out.V{"count": len(evicted), "freed": units.BytesSize(float64(freed)), "size": units.BytesSize(float64(size))})
+	},
+}
+
+// evictedCacheImages returns the images of the 'cache' config whose archives were evicted from the cache
+func evictedCacheImages(cacheDir string, evicted []image.CacheEntry) []string {
+	paths := map[string]bool{}
+	for _, e := range evicted {
+		paths[filepath.Join(cacheDir, filepath.FromSlash(e.Path))] = true
+	}
+	images := []string{}
+	cached, err := node.ImagesInConfigFile()
+	if err != nil {
+		klog.Warningf("unable to list the cached images of the config: %v", err)
+		return images
+	}
+	for _, img := range cached {
+		if paths[localpath.SanitizeCacheDir(filepath.Join(cacheDir, img))] {
+			images = append(images, img)
+		}
+	}
+	return images
+}
+
 func init() {
 	addCacheCmdFlags()
+	pruneCacheCmd.Flags().StringVar(&cacheMaxSize, "max-size", "", "Evict the least recently used images until the cache fits this size, such as 10GB")
+	pruneCacheCmd.Flags().StringVar(&cacheOlderThan, "older-than", "", "Evict the images not cached or loaded into a node within this period, such as 30d or 12h")
 	cacheCmd.AddCommand(addCacheCmd)
 	cacheCmd.AddCommand(deleteCacheCmd)
 	cacheCmd.AddCommand(reloadCacheCmd)
+	cacheCmd.AddCommand(pruneCacheCmd)
 }
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/download"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/version"
+)
+
+var (
+	inspectPreloadK8sVersion string
+	inspectPreloadRuntime    string
+	inspectPreloadOutput     string
+)
+
+// inspectPreloadCacheCmd represents the cache inspect-preload command
+var inspectPreloadCacheCmd = &cobra.Command{
+	Use:   "inspect-preload",
+	Short: "List the images of a preload tarball.",
+	Long:  "List the images of the preload tarball for a Kubernetes version and container runtime, downloading the tarball unless it is cached. The images are read from the metadata of the container runtime in the tarball, without extracting it.",
+	Example: `minikube cache inspect-preload
+minikube cache inspect-preload --k8s-version v1.25.3 --runtime containerd -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube cache inspect-preload [--k8s-version version] [--runtime runtime] [-o table|json]")
+		}
+		if inspectPreloadOutput != "table" && inspectPreloadOutput != "json" {
+			exit.Message(reason.Usage, "invalid output format: {{.output}}. Valid values: 'table', 'json'", out.V{"output": inspectPreloadOutput})
+		}
+		switch inspectPreloadRuntime {
+		case constants.Docker, constants.Containerd, constants.CRIO, "cri-o":
+		default:
+			exit.Message(reason.Usage, "invalid container runtime: {{.runtime}}. Valid values: 'docker', 'containerd', 'crio'", out.V{"runtime": inspectPreloadRuntime})
+		}
+		k8sVersion := inspectPreloadK8sVersion
+		if !strings.HasPrefix(k8sVersion, version.VersionPrefix) {
+			k8sVersion = version.VersionPrefix + k8sVersion
+		}
+		if inspectPreloadOutput == "json" {
+			// keep the progress of the download out of the JSON
+			out.SetSilent(true)
+		}
+
+		path, err := download.FetchPreload(k8sVersion, inspectPreloadRuntime)
+		if err != nil {
+			exit.Error(reason.InetCacheTar, "Failed to download the preload tarball", err)
+		}
+		images, err := download.TarballImages(path)
+		if err != nil {
+			exit.Error(reason.InternalCacheList, "Failed to list the images of the preload tarball", err)
+		}
+
+		if inspectPreloadOutput == "json" {
+			b, err := json.Marshal(images)
+			if err != nil {
+				exit.Error(reason.InternalJSONMarshal, "Failed to marshal the images", err)
+			}
+			fmt.Println(string(b))
+			return
+		}
+		renderPreloadImagesTable(images)
+	},
+}
+
+// renderPreloadImagesTable renders the images of a preload tarball, with the short IDs which 'docker images' shows
+func renderPreloadImagesTable(images []download.PreloadImage) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Repository", "Tag", "Image ID"})
+	table.SetAutoFormatHeaders(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("|")
+	for _, img := range images {
+		tag := img.Tag
+		if tag == "" {
+			tag = "<none>"
+		}
+		id := strings.TrimPrefix(img.ID, "sha256:")
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		table.Append([]string{img.Repository, tag, id})
+	}
+	table.Render()
+}
+
+func init() {
+	inspectPreloadCacheCmd.Flags().StringVar(&inspectPreloadK8sVersion, "k8s-version", constants.DefaultKubernetesVersion, "The Kubernetes version of the preload tarball")
+	inspectPreloadCacheCmd.Flags().StringVar(&inspectPreloadRuntime, "runtime", constants.Docker, "The container runtime of the preload tarball: docker, containerd or crio")
+	inspectPreloadCacheCmd.Flags().StringVarP(&inspectPreloadOutput, "output", "o", "table", "Format to print stdout in. Options include: [table,json]")
+	cacheCmd.AddCommand(inspectPreloadCacheCmd)
+}
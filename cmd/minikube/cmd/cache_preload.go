@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+	"k8s.io/minikube/pkg/minikube/download"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+// preloadCacheCmd represents the cache preload command
+var preloadCacheCmd = &cobra.Command{
+	Use:   "preload",
+	Short: "Manage the local preloads",
+	Long:  "Manage the preloads saved from running nodes, which later starts load when no downloadable preload applies.",
+}
+
+// listPreloadCacheCmd represents the cache preload list command
+var listPreloadCacheCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the local preloads and their size.",
+	Long:  "List the local preloads and their size.",
+	Run: func(cmd *cobra.Command, args []string) {
+		preloads, err := download.LocalPreloads()
+		if err != nil {
+			exit.Error(reason.InternalCacheList, "Failed to list local preloads", err)
+		}
+		var total int64
+		for _, p := range preloads {
+			out.Ln("%s\t%s", p.Name, units.HumanSize(float64(p.Size)))
+			total += p.Size
+		}
+		out.Ln("total\t%s", units.HumanSize(float64(total)))
+	},
+}
+
+// deletePreloadCacheCmd represents the cache preload delete command
+var deletePreloadCacheCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete the local preloads.",
+	Long:  "Delete the local preloads. The next start without a downloadable preload generates them again.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := download.DeleteLocalPreloads(); err != nil {
+			exit.Error(reason.HostDelCache, "Failed to delete local preloads", err)
+		}
+		out.Step(style.Deleted, "Deleted the local preloads")
+	},
+}
+
+func init() {
+	preloadCacheCmd.AddCommand(listPreloadCacheCmd)
+	preloadCacheCmd.AddCommand(deletePreloadCacheCmd)
+	cacheCmd.AddCommand(preloadCacheCmd)
+}
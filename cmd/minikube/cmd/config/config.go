@@ -172,6 +172,11 @@ var settings = []Setting{
 		name: config.MaxAuditEntries,
 		set:  SetInt,
 	},
+	{
+		name:        config.ImageCacheWarnSize,
+		set:         SetString,
+		validations: []setFn{IsValidDiskSize},
+	},
 }
 
 // ConfigCmd represents the config command
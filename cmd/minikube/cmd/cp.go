@@ -27,6 +27,7 @@ import (
 
 	"k8s.io/minikube/pkg/minikube/assets"
 	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/machine"
 	"k8s.io/minikube/pkg/minikube/mustload"
@@ -35,9 +36,18 @@ import (
 	"k8s.io/minikube/pkg/minikube/reason"
 )
 
+var (
+	cpContainer string
+	cpChmod     string
+	cpChown     string
+)
+
 type remotePath struct {
 	node string
 	path string
+	// pod and namespace are the pod to copy into, if given as <pod>_<namespace>:<path>
+	pod       string
+	namespace string
 }
 
 // cpCmd represents the cp command, similar to docker cp
@@ -46,10 +56,12 @@ var cpCmd = &cobra.Command{
 	Short: "Copy the specified file into minikube",
 	Long: `Copy the specified file into minikube, it will be saved at path <target file absolute path> in your minikube.
 Default target node controlplane and If <source node name> is omitted, It will trying to copy from host.
+To copy into a container of a pod, even a crashed one, give the target as <pod>_<namespace>:<file absolute path>, or the container with --container.
 
 Example Command : "minikube cp a.txt /home/docker/b.txt" +
                   "minikube cp a.txt minikube-m02:/home/docker/b.txt"
-                  "minikube cp minikube-m01:a.txt minikube-m02:/home/docker/b.txt"`,
+                  "minikube cp minikube-m01:a.txt minikube-m02:/home/docker/b.txt"
+                  "minikube cp a.txt web-5d8f7_default:/etc/app/a.txt --container app --chown 1000:1000 --chmod 0600"`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 2 {
 			exit.Message(reason.Usage, `Please specify the path to copy: 
@@ -61,6 +73,10 @@ Example Command : "minikube cp a.txt /home/docker/b.txt" +
 		validateArgs(src, dst)
 
 		co := mustload.Running(ClusterFlagValue())
+		if dst.pod != "" || cpContainer != "" {
+			copyToContainer(&co, src, dst)
+			return
+		}
 		var runner command.Runner
 
 		if dst.node != "" {
@@ -81,6 +97,10 @@ Example Command : "minikube cp a.txt /home/docker/b.txt" +
 }
 
 func init() {
+	cpCmd.Flags().StringVarP(&cpContainer, "container", "c", "", "The container to copy into: its name within the pod of the target, which may be omitted if the pod has a single container, or else its ID.")
+	cpCmd.Flags().StringVar(&cpChmod, "chmod", "", "The octal permissions of the file copied into a container, such as 0644.")
+	cpCmd.Flags().StringVar(&cpChown, "chown", "", "The numeric owner of the file copied into a container, as UID or UID:GID. Defaults to root.")
+	cpCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node running the container to copy into. Defaults to the node of the target, or else the primary control plane.")
 }
 
 // split path to node name and file path
@@ -88,12 +108,46 @@ func newRemotePath(path string) *remotePath {
 	// if destination path is not a absolute path, trying to parse with <node>:<abs path> format
 	sp := strings.SplitN(path, ":", 2)
 	if len(sp) == 2 && len(sp[0]) > 0 && !strings.Contains(sp[0], "/") && strings.HasPrefix(sp[1], "/") {
+		// names of nodes, pods and namespaces have no underscore, which separates the pod from its namespace
+		if pod, namespace, ok := strings.Cut(sp[0], "_"); ok && pod != "" && namespace != "" {
+			return &remotePath{path: sp[1], pod: pod, namespace: namespace}
+		}
 		return &remotePath{node: sp[0], path: sp[1]}
 	}
 
 	return &remotePath{node: "", path: path}
 }
 
+// copyToContainer copies the file into the container of the target, through its runtime
+func copyToContainer(co *mustload.ClusterController, src, dst *remotePath) {
+	o := cruntime.CopyOptions{Mode: cpChmod, Owner: cpChown}
+	if err := o.Validate(); err != nil {
+		exit.Message(reason.Usage, "{{.error}}", out.V{"error": err})
+	}
+
+	n := co.CP.Node
+	name := nodeName
+	if name == "" {
+		name = dst.node
+	}
+	if name != "" {
+		var err error
+		if n, _, err = node.Retrieve(*co.Config, name); err != nil {
+			exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": name})
+		}
+	}
+
+	t := machine.ContainerTarget{Pod: dst.pod, Namespace: dst.namespace, Container: cpContainer}
+	fa := copyableFile(co, src, dst)
+	if _, err := machine.CopyToContainer(co.API, *co.Config, *n, t, fa, dst.path, o); err != nil {
+		var unsupported *cruntime.ErrUnsupported
+		if errors.As(err, &unsupported) {
+			exit.Message(reason.Unimplemented, "{{.error}}. Copy into the container once it runs, or use the containerd runtime.", out.V{"error": unsupported})
+		}
+		exit.Error(reason.InternalCommandRunner, fmt.Sprintf("Fail to copy file %s into container %s", fa.GetSourcePath(), t), err)
+	}
+}
+
 func remoteCommandRunner(co *mustload.ClusterController, nodeName string) command.Runner {
 	n, _, err := node.Retrieve(*co.Config, nodeName)
 	if err != nil {
@@ -158,6 +212,18 @@ func validateArgs(src, dst *remotePath) {
 		exit.Message(reason.Usage, "Target {{.path}} can not be empty", out.V{"path": dst.path})
 	}
 
+	if src.pod != "" {
+		exit.Message(reason.Usage, "Copying from containers is not supported, copy from the node or the host instead")
+	}
+
+	if (dst.pod != "" || cpContainer != "") && !strings.HasPrefix(dst.path, "/") {
+		exit.Message(reason.Usage, `Target <container file path> must be an absolute Path (example: "web-5d8f7_default:/etc/app/a.txt")`)
+	}
+
+	if dst.pod == "" && cpContainer == "" && (cpChmod != "" || cpChown != "") {
+		exit.Message(reason.Usage, "--chmod and --chown only apply when copying into a container")
+	}
+
 	// if node name not explicitly specified in both of source and target,
 	// consider target node is controlpanel for backward compatibility.
 	if src.node == "" && dst.node == "" && !strings.HasPrefix(dst.path, "/") {
@@ -60,3 +60,23 @@ func TestParsePath(t *testing.T) {
 		}
 	}
 }
+
+func TestParseContainerPath(t *testing.T) {
+	var cases = []struct {
+		path     string
+		expected remotePath
+	}{
+		{"web_default:/a", remotePath{pod: "web", namespace: "default", path: "/a"}},
+		{"web-5d8f7_kube-system:/a/b:c", remotePath{pod: "web-5d8f7", namespace: "kube-system", path: "/a/b:c"}},
+		{"web_default:a", remotePath{path: "web_default:a"}},
+		{"_default:/a", remotePath{node: "_default", path: "/a"}},
+		{"web_:/a", remotePath{node: "web_", path: "/a"}},
+		{"minikube-m02:/a", remotePath{node: "minikube-m02", path: "/a"}},
+	}
+
+	for _, c := range cases {
+		if rp := newRemotePath(c.path); *rp != c.expected {
+			t.Errorf("newRemotePath(%q) = %+v, want %+v", c.path, *rp, c.expected)
+		}
+	}
+}
@@ -37,11 +37,13 @@ import (
 	cmdcfg "k8s.io/minikube/cmd/minikube/cmd/config"
 	"k8s.io/minikube/pkg/drivers/kic"
 	"k8s.io/minikube/pkg/drivers/kic/oci"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
 	"k8s.io/minikube/pkg/minikube/cluster"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/delete"
+	"k8s.io/minikube/pkg/minikube/dockercontext"
 	"k8s.io/minikube/pkg/minikube/download"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/exit"
@@ -52,11 +54,13 @@ import (
 	"k8s.io/minikube/pkg/minikube/out/register"
 	"k8s.io/minikube/pkg/minikube/reason"
 	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/util"
 )
 
 var (
-	deleteAll bool
-	purge     bool
+	deleteAll  bool
+	purge      bool
+	keepImages bool
 )
 
 // deleteCmd represents the delete command
@@ -110,6 +114,7 @@ var hostAndDirsDeleter = func(api libmachine.API, cc *config.ClusterConfig, prof
 func init() {
 	deleteCmd.Flags().BoolVar(&deleteAll, "all", false, "Set flag to delete all profiles")
 	deleteCmd.Flags().BoolVar(&purge, "purge", false, "Set this flag to delete the '.minikube' folder from your user directory.")
+	deleteCmd.Flags().BoolVar(&keepImages, "keep-images", false, "With --purge, keep the Kubernetes images in the container runtime of the host of the none and ssh drivers.")
 	deleteCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Format to print stdout in. Options include: [text,json]")
 
 	if err := viper.BindPFlags(deleteCmd.Flags()); err != nil {
@@ -367,6 +372,12 @@ func deleteProfile(ctx context.Context, profile *config.Profile) error {
 			}
 			return err
 		}
+		// the runtime of the host outlives the cluster, unlike the one of a VM or a container
+		if purge {
+			if err := purgeRuntime(api, *cc, cc.Nodes[0]); err != nil {
+				out.WarningT("Unable to purge the container runtime of {{.name}}: {{.error}}", out.V{"name": profile.Name, "error": err})
+			}
+		}
 	}
 
 	if err := hostAndDirsDeleter(api, cc, profile.Name); err != nil {
@@ -416,7 +427,7 @@ func unpauseIfNeeded(profile *config.Profile) error {
 	}
 
 	klog.Infof("Unpause cluster %q", profile.Name)
-	_, err = cluster.Unpause(cr, r, nil)
+	_, err = cluster.Unpause(cr, r, nil, nil)
 	return err
 }
 
@@ -456,6 +467,12 @@ func deleteContext(machineName string) error {
 		return DeletionError{Err: fmt.Errorf("update config: %v", err), Errtype: Fatal}
 	}
 
+	for _, name := range []string{machineName, builderContextName(machineName)} {
+		if err := dockercontext.Remove(dockercontext.ConfigDir(), name); err != nil {
+			out.WarningT("Unable to remove the {{.name}} docker context: {{.error}}", out.V{"name": name, "error": err})
+		}
+	}
+
 	if err := cmdcfg.Unset(config.ProfileName); err != nil {
 		return DeletionError{Err: fmt.Errorf("unset minikube profile: %v", err), Errtype: Fatal}
 	}
@@ -505,13 +522,13 @@ func uninstallKubernetes(api libmachine.API, cc config.ClusterConfig, n config.N
 		return DeletionError{Err: fmt.Errorf("unable to get bootstrapper: %v", err), Errtype: Fatal}
 	}
 
-	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Runner: r})
+	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Driver: cc.Driver, Runner: r})
 	if err != nil {
 		return DeletionError{Err: fmt.Errorf("unable to get runtime: %v", err), Errtype: Fatal}
 	}
 
 	// Unpause the cluster if necessary to avoid hung kubeadm
-	_, err = cluster.Unpause(cr, r, nil)
+	_, err = cluster.Unpause(cr, r, nil, nil)
 	if err != nil {
 		klog.Errorf("unpause failed: %v", err)
 	}
@@ -522,6 +539,40 @@ func uninstallKubernetes(api libmachine.API, cc config.ClusterConfig, n config.N
 	return nil
 }
 
+// purgeRuntime removes the Kubernetes containers, the Kubernetes images unless --keep-images is set,
+// and the settings minikube wrote from the container runtime of a none or ssh host
+func purgeRuntime(api libmachine.API, cc config.ClusterConfig, n config.Node) error {
+	k8s := cc.KubernetesConfig
+	out.Styled(style.Resetting, "Purging Kubernetes from {{.runtime}} ...", out.V{"runtime": k8s.ContainerRuntime})
+	host, err := machine.LoadHost(api, config.MachineName(cc, n))
+	if err != nil {
+		return errors.Wrap(err, "load host")
+	}
+	r, err := machine.CommandRunner(host)
+	if err != nil {
+		return errors.Wrap(err, "command runner")
+	}
+	version, err := util.ParseKubernetesVersion(k8s.KubernetesVersion)
+	if err != nil {
+		return errors.Wrap(err, "parse kubernetes version")
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: r, Socket: k8s.CRISocket, KubernetesVersion: version})
+	if err != nil {
+		return errors.Wrap(err, "runtime")
+	}
+	if !cr.Active() {
+		klog.Infof("%s is not running, nothing to purge", cr.Name())
+		return nil
+	}
+	o := cruntime.PurgeOptions{}
+	if keepImages {
+		klog.Infof("keeping the Kubernetes images of %s", cr.Name())
+	} else if o.Images, err = images.Kubeadm(k8s.ImageRepository, k8s.KubernetesVersion); err != nil {
+		return errors.Wrap(err, "kubernetes images")
+	}
+	return cr.Purge(o)
+}
+
 // HandleDeletionErrors handles deletion errors from DeleteProfiles
 func HandleDeletionErrors(errors []error) {
 	if len(errors) == 1 {
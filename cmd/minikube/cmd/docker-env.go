@@ -41,7 +41,10 @@ import (
 	"k8s.io/minikube/pkg/drivers/qemu"
 	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil/kverify"
 	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/dockercontext"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/localpath"
@@ -49,65 +52,24 @@ import (
 	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/reason"
 	"k8s.io/minikube/pkg/minikube/shell"
+	"k8s.io/minikube/pkg/minikube/style"
 	"k8s.io/minikube/pkg/minikube/sysinit"
 	pkgnetwork "k8s.io/minikube/pkg/network"
+	"k8s.io/minikube/pkg/util"
+	"k8s.io/minikube/pkg/util/retry"
 	kconst "k8s.io/minikube/third_party/kubeadm/app/constants"
 )
 
 const minLogCheckTime = 60 * time.Second
 
-var dockerEnvTCPTmpl = fmt.Sprintf(
-	"{{ .Prefix }}%s{{ .Delimiter }}{{ .DockerTLSVerify }}{{ .Suffix }}"+
-		"{{ .Prefix }}%s{{ .Delimiter }}{{ .DockerHost }}{{ .Suffix }}"+
-		"{{ .Prefix }}%s{{ .Delimiter }}{{ .DockerCertPath }}{{ .Suffix }}"+
-		"{{ if .ExistingDockerTLSVerify }}"+
-		"{{ .Prefix }}%s{{ .Delimiter }}{{ .ExistingDockerTLSVerify }}{{ .Suffix }}"+
-		"{{ end }}"+
-		"{{ if .ExistingDockerHost }}"+
-		"{{ .Prefix }}%s{{ .Delimiter }}{{ .ExistingDockerHost }}{{ .Suffix }}"+
-		"{{ end }}"+
-		"{{ if .ExistingDockerCertPath }}"+
-		"{{ .Prefix }}%s{{ .Delimiter }}{{ .ExistingDockerCertPath }}{{ .Suffix }}"+
-		"{{ end }}"+
-		"{{ .Prefix }}%s{{ .Delimiter }}{{ .MinikubeDockerdProfile }}{{ .Suffix }}"+
-		"{{ if .NoProxyVar }}"+
-		"{{ .Prefix }}{{ .NoProxyVar }}{{ .Delimiter }}{{ .NoProxyValue }}{{ .Suffix }}"+
-		"{{ end }}"+
-		"{{ .UsageHint }}",
-	constants.DockerTLSVerifyEnv,
-	constants.DockerHostEnv,
-	constants.DockerCertPathEnv,
-	constants.ExistingDockerTLSVerifyEnv,
-	constants.ExistingDockerHostEnv,
-	constants.ExistingDockerCertPathEnv,
-	constants.MinikubeActiveDockerdEnv)
-var dockerEnvSSHTmpl = fmt.Sprintf(
-	"{{ .Prefix }}%s{{ .Delimiter }}{{ .DockerHost }}{{ .Suffix }}"+
-		"{{ .Prefix }}%s{{ .Delimiter }}{{ .MinikubeDockerdProfile }}{{ .Suffix }}"+
-		"{{ .UsageHint }}",
-	constants.DockerHostEnv,
-	constants.MinikubeActiveDockerdEnv)
-
-// DockerShellConfig represents the shell config for Docker
-type DockerShellConfig struct {
-	shell.Config
-	DockerCertPath         string
-	DockerHost             string
-	DockerTLSVerify        string
-	MinikubeDockerdProfile string
-	NoProxyVar             string
-	NoProxyValue           string
-
-	ExistingDockerCertPath  string
-	ExistingDockerHost      string
-	ExistingDockerTLSVerify string
-}
-
 var (
 	noProxy              bool
 	sshHost              bool
 	sshAdd               bool
 	dockerUnset          bool
+	criEnv               bool
+	dockerContext        bool
+	builderEnv           bool
 	defaultNoProxyGetter NoProxyGetter
 )
 
@@ -119,49 +81,32 @@ type NoProxyGetter interface {
 // EnvNoProxyGetter gets the no_proxy variable, using environment
 type EnvNoProxyGetter struct{}
 
-// dockerShellCfgSet generates context variables for "docker-env"
-func dockerShellCfgSet(ec DockerEnvConfig, envMap map[string]string) *DockerShellConfig {
-	profile := ec.profile
-	const usgPlz = "To point your shell to minikube's docker-daemon, run:"
-	usgCmd := fmt.Sprintf("minikube -p %s docker-env", profile)
+// dockerNoProxyVar returns the no_proxy variable of the environment, with the address of the node added idempotently
+func dockerNoProxyVar(ec DockerEnvConfig) shell.EnvVar {
+	noProxyVar, noProxyValue := defaultNoProxyGetter.GetNoProxyVar()
+	switch {
+	case noProxyValue == "":
+		noProxyValue = ec.hostIP
+	case strings.Contains(noProxyValue, ec.hostIP):
+	// ip already in no_proxy list, nothing to do
+	default:
+		noProxyValue = fmt.Sprintf("%s,%s", noProxyValue, ec.hostIP)
+	}
+	return shell.EnvVar{Name: noProxyVar, Value: noProxyValue}
+}
+
+// dockerUsageHint returns the usage hint of the 'docker-env' script
+func dockerUsageHint(ec DockerEnvConfig) (string, string) {
+	usgPlz := "To point your shell to minikube's docker-daemon, run:"
+	usgCmd := fmt.Sprintf("minikube -p %s docker-env", ec.profile)
 	if ec.ssh {
 		usgCmd += " --ssh-host"
 	}
-	s := &DockerShellConfig{
-		Config: *shell.CfgSet(ec.EnvConfig, usgPlz, usgCmd),
-	}
-	if !ec.ssh {
-		s.DockerCertPath = envMap[constants.DockerCertPathEnv]
-	}
-	s.DockerHost = envMap[constants.DockerHostEnv]
-	if !ec.ssh {
-		s.DockerTLSVerify = envMap[constants.DockerTLSVerifyEnv]
-	}
-
-	s.ExistingDockerCertPath = envMap[constants.ExistingDockerCertPathEnv]
-	s.ExistingDockerHost = envMap[constants.ExistingDockerHostEnv]
-	s.ExistingDockerTLSVerify = envMap[constants.ExistingDockerTLSVerifyEnv]
-
-	s.MinikubeDockerdProfile = envMap[constants.MinikubeActiveDockerdEnv]
-
-	if ec.noProxy {
-		noProxyVar, noProxyValue := defaultNoProxyGetter.GetNoProxyVar()
-
-		// add the docker host to the no_proxy list idempotently
-		switch {
-		case noProxyValue == "":
-			noProxyValue = ec.hostIP
-		case strings.Contains(noProxyValue, ec.hostIP):
-		// ip already in no_proxy list, nothing to do
-		default:
-			noProxyValue = fmt.Sprintf("%s,%s", noProxyValue, ec.hostIP)
-		}
-
-		s.NoProxyVar = noProxyVar
-		s.NoProxyValue = noProxyValue
+	if ec.cri {
+		usgPlz = "To point crictl to the cri-dockerd socket of minikube, run:"
+		usgCmd += " --cri"
 	}
-
-	return s
+	return usgPlz, usgCmd
 }
 
 // GetNoProxyVar gets the no_proxy var
@@ -191,6 +136,15 @@ func isDockerActive(r command.Runner) bool {
 	return sysinit.New(r).Active("docker")
 }
 
+// activateDockerd starts a socket activated dockerd by connecting to its socket, and waits until it serves requests
+func activateDockerd(r command.Runner) error {
+	klog.Infof("activating socket activated dockerd ...")
+	return retry.Expo(func() error {
+		_, err := r.RunCmd(exec.Command("sudo", "docker", "version", "--format", "{{.Server.Version}}"))
+		return err
+	}, time.Second, 30*time.Second)
+}
+
 // mustRestartDockerd will attempt to reload dockerd if fails, will try restart and exit if fails again
 func mustRestartDockerd(name string, runner command.Runner) {
 	// Docker Docs: https://docs.docker.com/config/containers/live-restore
@@ -249,27 +203,39 @@ For example, you can do all docker operations such as docker build, docker run,
 Note: You need the docker-cli to be installed on your machine.
 docker-cli install instructions: https://minikube.sigs.k8s.io/docs/tutorials/docker_desktop_replacement/#steps`,
 	Run: func(cmd *cobra.Command, args []string) {
-		var err error
-
-		shl := shell.ForceShell
-		if shl == "" {
-			shl, err = shell.Detect()
-			if err != nil {
-				exit.Error(reason.InternalShellDetect, "Error detecting shell", err)
-			}
+		shl, err := shell.Resolve(shell.ForceShell)
+		if err != nil {
+			exit.Error(reason.InternalShellDetect, "Error detecting shell", err)
 		}
 		sh := shell.EnvConfig{
 			Shell: shl,
 		}
 
+		if criEnv && sshHost {
+			exit.Message(reason.Usage, "The --cri flag can not be used with --ssh-host, as crictl does not connect over SSH")
+		}
+
+		if dockerContext && criEnv {
+			exit.Message(reason.Usage, "The --cri flag can not be used with --context, as crictl does not use docker contexts")
+		}
+
+		if builderEnv && (criEnv || sshHost) {
+			exit.Message(reason.Usage, "The --builder flag can not be used with --cri or --ssh-host, as the isolated builder is only reached over HTTPS")
+		}
+
+		if dockerContext && dockerUnset {
+			removeDockerContext(dockerContextName(ClusterFlagValue()))
+			return
+		}
+
 		if dockerUnset {
-			if err := dockerUnsetScript(DockerEnvConfig{EnvConfig: sh}, os.Stdout); err != nil {
+			if err := dockerUnsetScript(DockerEnvConfig{EnvConfig: sh, cri: criEnv}, os.Stdout); err != nil {
 				exit.Error(reason.InternalEnvScript, "Error generating unset output", err)
 			}
 			return
 		}
 
-		if !out.IsTerminal(os.Stdout) {
+		if !out.IsTerminal(os.Stdout) && !dockerContext {
 			out.SetSilent(true)
 			exit.SetShell(true)
 		}
@@ -287,18 +253,43 @@ docker-cli install instructions: https://minikube.sigs.k8s.io/docs/tutorials/doc
 			exit.Message(reason.EnvMultiConflict, `The docker-env command is incompatible with multi-node clusters. Use the 'registry' add-on: https://minikube.sigs.k8s.io/docs/handbook/registry/`)
 		}
 
-		if co.Config.KubernetesConfig.ContainerRuntime != constants.Docker {
+		onDemand := co.Config.KubernetesConfig.ContainerRuntime != constants.Docker
+		if onDemand && co.Config.KubernetesConfig.RuntimeFallbackFrom == constants.Docker {
+			exit.Message(reason.Usage, `The docker-env command is only compatible with the "docker" runtime, but this cluster uses the "{{.runtime}}" runtime instead, as its image lacks the cri-dockerd required by Kubernetes {{.version}}. To use docker, run 'minikube delete' and start the cluster again with the latest image.`,
+				out.V{"runtime": co.Config.KubernetesConfig.ContainerRuntime, "version": co.Config.KubernetesConfig.KubernetesVersion})
+		}
+		if onDemand && !co.Config.KubernetesConfig.DockerOnDemand {
 			exit.Message(reason.Usage, `The docker-env command is only compatible with the "docker" runtime, but this cluster was configured to use the "{{.runtime}}" runtime.`,
 				out.V{"runtime": co.Config.KubernetesConfig.ContainerRuntime})
 		}
 
+		if builderEnv && !co.Config.KubernetesConfig.IsolatedBuilder {
+			exit.Message(reason.Usage, "The isolated builder is not enabled for this cluster, start it with --isolated-builder to use --builder")
+		}
+
 		r := co.CP.Runner
-		ensureDockerd(cname, r)
+		if onDemand {
+			if err := activateDockerd(r); err != nil {
+				exit.Error(reason.EnvDockerUnavailable, "docker did not start", err)
+			}
+		} else {
+			ensureDockerd(cname, r)
+		}
 
 		d := co.CP.Host.Driver
 		port := constants.DockerDaemonPort
+		if builderEnv {
+			port = constants.BuilderDaemonPort
+		}
+		if builderEnv && driver.IsQEMU(driverName) && pkgnetwork.IsUser(co.Config.Network) {
+			exit.Message(reason.Usage, "The isolated builder can not be reached with the user network of the qemu driver")
+		}
 		if driver.NeedsPortForward(driverName) {
 			port, err = oci.ForwardedPort(driverName, cname, port)
+			if err != nil && builderEnv {
+				// the port is only published when the container is created with --isolated-builder
+				exit.Message(reason.Usage, "The isolated builder of '{{.name}}' is not published, as it was enabled after the cluster was created: recreate it with 'minikube delete' and 'minikube start --isolated-builder'", out.V{"name": cname})
+			}
 			if err != nil {
 				exit.Message(reason.DrvPortForward, "Error getting port binding for '{{.driver_name}} driver: {{.error}}", out.V{"driver_name": driverName, "error": err})
 			}
@@ -316,20 +307,32 @@ docker-cli install instructions: https://minikube.sigs.k8s.io/docs/tutorials/doc
 			exit.Error(reason.IfSSHClient, "Error getting ssh client", err)
 		}
 
+		dr := dockerRuntime(co.Config.KubernetesConfig, r, onDemand)
 		hostIP := co.CP.IP.String()
+		tcp := dr.DaemonEndpoint(dockerURL(hostIP, port), localpath.MakeMiniPath("certs"))
 		ec := DockerEnvConfig{
 			EnvConfig: sh,
 			profile:   cname,
 			driver:    driverName,
 			ssh:       sshHost,
+			cri:       criEnv,
 			hostIP:    hostIP,
-			port:      port,
-			certsDir:  localpath.MakeMiniPath("certs"),
 			noProxy:   noProxy,
-			username:  d.GetSSHUsername(),
-			hostname:  hostname,
-			sshport:   sshport,
-			keypath:   d.GetSSHKeyPath(),
+			daemon:    tcp,
+		}
+		if sshHost {
+			ec.daemon = dr.DaemonEndpoint(sshURL(d.GetSSHUsername(), hostname, sshport), "")
+		}
+		if builderEnv {
+			ec.daemon = dr.BuilderEndpoint(tcp.Host, tcp.TLSDir)
+		}
+		if criEnv && ec.daemon.CRISocket == "" {
+			exit.Message(reason.Usage, "The docker of this cluster is not reached through cri-dockerd, there is no CRI socket to point crictl at")
+		}
+		if builderEnv && !ec.daemon.BuildTarget {
+			out.WarningT("Kubernetes only runs the images built with the isolated builder once promoted, as 'minikube image build' does, unless both daemons store their images in containerd with --docker-feature=containerd-snapshotter=true")
+		} else if !ec.daemon.BuildTarget {
+			out.WarningT("Kubernetes does not run the images built with this docker, as it uses the {{.runtime}} runtime", out.V{"runtime": co.Config.KubernetesConfig.ContainerRuntime})
 		}
 
 		dockerPath, err := exec.LookPath("docker")
@@ -338,9 +341,11 @@ docker-cli install instructions: https://minikube.sigs.k8s.io/docs/tutorials/doc
 			dockerPath = ""
 		}
 
-		if dockerPath != "" {
-			out, err := tryDockerConnectivity("docker", ec)
-			if err != nil { // docker might be up but been loaded with wrong certs/config
+		if dockerPath != "" && !criEnv {
+			out, err := tryDockerConnectivity("docker", tcp, cname)
+			if err != nil && builderEnv {
+				klog.Warningf("couldn't connect to the isolated builder inside minikube.  output: %s error: %v", string(out), err)
+			} else if err != nil && !onDemand { // docker might be up but been loaded with wrong certs/config
 				// to fix issues like this #8185
 				// even though docker maybe running just fine it could be holding on to old certs and needs a refresh
 				klog.Warningf("couldn't connect to docker inside minikube.  output: %s error: %v", string(out), err)
@@ -348,7 +353,9 @@ docker-cli install instructions: https://minikube.sigs.k8s.io/docs/tutorials/doc
 			}
 		}
 
-		if err := dockerSetScript(ec, os.Stdout); err != nil {
+		if dockerContext {
+			createDockerContext(dockerContextName(cname), ec.daemon)
+		} else if err := dockerSetScript(ec, os.Stdout); err != nil {
 			exit.Error(reason.InternalDockerScript, "Error generating set output", err)
 		}
 
@@ -373,36 +380,84 @@ docker-cli install instructions: https://minikube.sigs.k8s.io/docs/tutorials/doc
 // DockerEnvConfig encapsulates all external inputs into shell generation for Docker
 type DockerEnvConfig struct {
 	shell.EnvConfig
-	profile  string
-	driver   string
-	ssh      bool
-	hostIP   string
-	port     int
-	certsDir string
-	noProxy  bool
-	username string
-	hostname string
-	sshport  int
-	keypath  string
+	profile string
+	driver  string
+	ssh     bool
+	// cri points crictl at the socket of cri-dockerd, instead of the docker CLI at the daemon
+	cri     bool
+	hostIP  string
+	noProxy bool
+	// daemon is the endpoint of the docker daemon the shell is pointed at
+	daemon cruntime.DaemonEndpoint
+}
+
+// dockerRuntime returns the docker runtime of the node, whose daemon the shell is pointed at
+func dockerRuntime(k8s config.KubernetesConfig, r command.Runner, onDemand bool) *cruntime.Docker {
+	socket := k8s.CRISocket
+	if onDemand {
+		socket = ""
+	}
+	version, err := util.ParseKubernetesVersion(k8s.KubernetesVersion)
+	if err != nil {
+		exit.Error(reason.InternalNewRuntime, "Failed to parse the kubernetes version", err)
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: constants.Docker, Runner: r, Socket: socket, KubernetesVersion: version})
+	if err != nil {
+		exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
+	}
+	dr, ok := cr.(*cruntime.Docker)
+	if !ok {
+		exit.Message(reason.InternalNewRuntime, "Failed to get the docker runtime")
+	}
+	dr.OnDemand = onDemand
+	return dr
+}
+
+// dockerContextName returns the name of the docker context of the profile, or of its isolated builder with --builder
+func dockerContextName(profile string) string {
+	if builderEnv {
+		return builderContextName(profile)
+	}
+	return profile
+}
+
+// builderContextName returns the name of the docker context of the isolated builder of the profile
+func builderContextName(profile string) string {
+	return profile + "-builder"
+}
+
+// createDockerContext idempotently creates the docker context of the profile, pointing at the daemon
+func createDockerContext(profile string, e cruntime.DaemonEndpoint) {
+	c := dockercontext.Context{Name: profile, Description: fmt.Sprintf("minikube %s cluster", profile), Endpoint: e}
+	if err := dockercontext.Create(dockercontext.ConfigDir(), c); err != nil {
+		exit.Error(reason.InternalDockerContext, "Failed to create the docker context", err)
+	}
+	out.Step(style.Celebrate, `The "{{.name}}" docker context points at the docker daemon of minikube. To use it, run: docker context use {{.name}}`, out.V{"name": profile})
+}
+
+// removeDockerContext removes the docker context of the profile, if any
+func removeDockerContext(profile string) {
+	if err := dockercontext.Remove(dockercontext.ConfigDir(), profile); err != nil {
+		exit.Error(reason.InternalDockerContext, "Failed to remove the docker context", err)
+	}
+	out.Step(style.Deleted, `Removed the "{{.name}}" docker context`, out.V{"name": profile})
 }
 
 // dockerSetScript writes out a shell-compatible 'docker-env' script
 func dockerSetScript(ec DockerEnvConfig, w io.Writer) error {
-	var dockerSetEnvTmpl string
-	if ec.ssh {
-		dockerSetEnvTmpl = dockerEnvSSHTmpl
-	} else {
-		dockerSetEnvTmpl = dockerEnvTCPTmpl
-	}
-	envVars := dockerEnvVars(ec)
+	vars := dockerEnvVars(ec)
 	if ec.Shell == "none" {
+		envVars := map[string]string{}
+		for _, v := range vars {
+			envVars[v.Name] = v.Value
+		}
 		switch outputFormat {
 		case "":
 			// shell "none"
 			break
 		case "text":
-			for k, v := range envVars {
-				_, err := fmt.Fprintf(w, "%s=%s\n", k, v)
+			for _, v := range vars {
+				_, err := fmt.Fprintf(w, "%s=%s\n", v.Name, v.Value)
 				if err != nil {
 					return err
 				}
@@ -436,7 +491,12 @@ func dockerSetScript(ec DockerEnvConfig, w io.Writer) error {
 			exit.Message(reason.InternalOutputUsage, "error: --output must be 'text', 'yaml' or 'json'")
 		}
 	}
-	return shell.SetScript(ec.EnvConfig, w, dockerSetEnvTmpl, dockerShellCfgSet(ec, envVars))
+	// no_proxy is only needed to reach the daemon over TCP
+	if ec.noProxy && !ec.ssh && !ec.cri {
+		vars = append(vars, dockerNoProxyVar(ec))
+	}
+	usgPlz, usgCmd := dockerUsageHint(ec)
+	return shell.SetVarsScript(ec.EnvConfig, w, vars, usgPlz, usgCmd)
 }
 
 // dockerSetScript writes out a shell-compatible 'docker-env unset' script
@@ -497,38 +557,36 @@ func sshURL(username string, hostname string, port int) string {
 	return fmt.Sprintf("ssh://%s@%s", username, net.JoinHostPort(hostname, strconv.Itoa(port)))
 }
 
-// dockerEnvVars gets the necessary docker env variables to allow the use of minikube's docker daemon
-func dockerEnvVars(ec DockerEnvConfig) map[string]string {
-	envTCP := map[string]string{
-		constants.DockerTLSVerifyEnv:       "1",
-		constants.DockerHostEnv:            dockerURL(ec.hostIP, ec.port),
-		constants.DockerCertPathEnv:        ec.certsDir,
-		constants.MinikubeActiveDockerdEnv: ec.profile,
+// dockerEnvVars returns the variables pointing a shell at the docker daemon of minikube, or crictl at the socket of cri-dockerd,
+// in the order they are set. The variables a shell had before it was pointed at a daemon are saved, to restore them on unset.
+func dockerEnvVars(ec DockerEnvConfig) []shell.EnvVar {
+	if ec.cri {
+		return []shell.EnvVar{{Name: constants.ContainerRuntimeEndpointEnv, Value: ec.daemon.CRISocket}}
 	}
-	envSSH := map[string]string{
-		constants.DockerHostEnv:            sshURL(ec.username, ec.hostname, ec.sshport),
-		constants.MinikubeActiveDockerdEnv: ec.profile,
+	vars := []shell.EnvVar{}
+	tls := ec.daemon.TLSDir != ""
+	if tls {
+		vars = append(vars, shell.EnvVar{Name: constants.DockerTLSVerifyEnv, Value: "1"})
 	}
-
-	var rt map[string]string
-	if ec.ssh {
-		rt = envSSH
-	} else {
-		rt = envTCP
-	}
-	if os.Getenv(constants.MinikubeActiveDockerdEnv) == "" {
-		for _, env := range constants.DockerDaemonEnvs {
-			if v := oci.InitialEnv(env); v != "" {
-				key := constants.MinikubeExistingPrefix + env
-				rt[key] = v
+	vars = append(vars, shell.EnvVar{Name: constants.DockerHostEnv, Value: ec.daemon.Host})
+	if tls {
+		vars = append(vars, shell.EnvVar{Name: constants.DockerCertPathEnv, Value: ec.daemon.TLSDir})
+		if os.Getenv(constants.MinikubeActiveDockerdEnv) == "" {
+			for _, env := range []string{constants.DockerTLSVerifyEnv, constants.DockerHostEnv, constants.DockerCertPathEnv} {
+				if v := oci.InitialEnv(env); v != "" {
+					vars = append(vars, shell.EnvVar{Name: constants.MinikubeExistingPrefix + env, Value: v})
+				}
 			}
 		}
 	}
-	return rt
+	return append(vars, shell.EnvVar{Name: constants.MinikubeActiveDockerdEnv, Value: ec.profile})
 }
 
 // dockerEnvNames gets the necessary docker env variables to reset after using minikube's docker daemon
 func dockerEnvNames(ec DockerEnvConfig) []string {
+	if ec.cri {
+		return []string{constants.ContainerRuntimeEndpointEnv}
+	}
 	vars := []string{
 		constants.DockerTLSVerifyEnv,
 		constants.DockerHostEnv,
@@ -546,12 +604,12 @@ func dockerEnvNames(ec DockerEnvConfig) []string {
 }
 
 // dockerEnvVarsList gets the necessary docker env variables to allow the use of minikube's docker daemon to be used in a exec.Command
-func dockerEnvVarsList(ec DockerEnvConfig) []string {
+func dockerEnvVarsList(e cruntime.DaemonEndpoint, profile string) []string {
 	return []string{
 		fmt.Sprintf("%s=%s", constants.DockerTLSVerifyEnv, "1"),
-		fmt.Sprintf("%s=%s", constants.DockerHostEnv, dockerURL(ec.hostIP, ec.port)),
-		fmt.Sprintf("%s=%s", constants.DockerCertPathEnv, ec.certsDir),
-		fmt.Sprintf("%s=%s", constants.MinikubeActiveDockerdEnv, ec.profile),
+		fmt.Sprintf("%s=%s", constants.DockerHostEnv, e.Host),
+		fmt.Sprintf("%s=%s", constants.DockerCertPathEnv, e.TLSDir),
+		fmt.Sprintf("%s=%s", constants.MinikubeActiveDockerdEnv, profile),
 	}
 }
 
@@ -585,12 +643,12 @@ func removeInvalidDockerProxy() {
 }
 
 // tryDockerConnectivity will try to connect to docker env from user's POV to detect the problem if it needs reset or not
-func tryDockerConnectivity(bin string, ec DockerEnvConfig) ([]byte, error) {
+func tryDockerConnectivity(bin string, e cruntime.DaemonEndpoint, profile string) ([]byte, error) {
 	c := exec.Command(bin, "version", "--format={{.Server}}")
 
 	// See #10098 for details
 	removeInvalidDockerProxy()
-	c.Env = append(os.Environ(), dockerEnvVarsList(ec)...)
+	c.Env = append(os.Environ(), dockerEnvVarsList(e, profile)...)
 	klog.Infof("Testing Docker connectivity with: %v", c)
 	return c.CombinedOutput()
 }
@@ -600,7 +658,10 @@ func init() {
 	dockerEnvCmd.Flags().BoolVar(&noProxy, "no-proxy", false, "Add machine IP to NO_PROXY environment variable")
 	dockerEnvCmd.Flags().BoolVar(&sshHost, "ssh-host", false, "Use SSH connection instead of HTTPS (port 2376)")
 	dockerEnvCmd.Flags().BoolVar(&sshAdd, "ssh-add", false, "Add SSH identity key to SSH authentication agent")
-	dockerEnvCmd.Flags().StringVar(&shell.ForceShell, "shell", "", "Force environment to be configured for a specified shell: [fish, cmd, powershell, tcsh, bash, zsh], default is auto-detect")
+	dockerEnvCmd.Flags().StringVar(&shell.ForceShell, "shell", "", "Force environment to be configured for a specified shell: [fish, cmd, powershell, pwsh, tcsh, bash, zsh, nushell], default is auto-detect")
 	dockerEnvCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "One of 'text', 'yaml' or 'json'.")
 	dockerEnvCmd.Flags().BoolVarP(&dockerUnset, "unset", "u", false, "Unset variables instead of setting them")
+	dockerEnvCmd.Flags().BoolVar(&dockerContext, "context", false, "Create a docker context named after the profile, pointing at the docker daemon of minikube, instead of printing the variables. Use 'docker context use' to switch to it, and --unset to remove it.")
+	dockerEnvCmd.Flags().BoolVar(&builderEnv, "builder", false, "Point at the isolated builder of the cluster, started with --isolated-builder, instead of the docker daemon running Kubernetes. With --context, the context is named after the profile followed by -builder.")
+	dockerEnvCmd.Flags().BoolVar(&criEnv, "cri", false, "Set CONTAINER_RUNTIME_ENDPOINT to the cri-dockerd socket of the node for crictl, instead of the docker variables. The socket is a path in the node, such as for crictl run with 'minikube ssh'.")
 }
@@ -20,12 +20,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"gopkg.in/yaml.v2"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/shell"
 )
 
 type FakeNoProxyGetter struct {
@@ -37,6 +41,11 @@ func (f FakeNoProxyGetter) GetNoProxyVar() (string, string) {
 	return f.NoProxyVar, f.NoProxyValue
 }
 
+// tcpEndpoint returns the endpoint of a daemon reached over TCP, with the certificates in /certs
+func tcpEndpoint(ip string, port int) cruntime.DaemonEndpoint {
+	return cruntime.DaemonEndpoint{Host: dockerURL(ip, port), TLSDir: "/certs", BuildTarget: true}
+}
+
 func TestGenerateDockerScripts(t *testing.T) {
 	var tests = []struct {
 		shell         string
@@ -50,7 +59,7 @@ func TestGenerateDockerScripts(t *testing.T) {
 		{
 			"bash",
 			"",
-			DockerEnvConfig{profile: "dockerdriver", driver: "docker", hostIP: "127.0.0.1", port: 32842, certsDir: "/certs"},
+			DockerEnvConfig{profile: "dockerdriver", driver: "docker", hostIP: "127.0.0.1", daemon: tcpEndpoint("127.0.0.1", 32842)},
 			nil,
 			`export DOCKER_TLS_VERIFY="1"
 export DOCKER_HOST="tcp://127.0.0.1:32842"
@@ -70,7 +79,7 @@ unset MINIKUBE_ACTIVE_DOCKERD;
 		{
 			"bash",
 			"",
-			DockerEnvConfig{profile: "dockerdriver", driver: "docker", ssh: true, username: "root", hostname: "host", sshport: 22},
+			DockerEnvConfig{profile: "dockerdriver", driver: "docker", ssh: true, daemon: cruntime.DaemonEndpoint{Host: sshURL("root", "host", 22)}},
 			nil,
 			`export DOCKER_HOST="ssh://root@host:22"
 export MINIKUBE_ACTIVE_DOCKERD="dockerdriver"
@@ -88,7 +97,7 @@ unset MINIKUBE_ACTIVE_DOCKERD;
 		{
 			"bash",
 			"",
-			DockerEnvConfig{profile: "bash", driver: "kvm2", hostIP: "127.0.0.1", port: 2376, certsDir: "/certs"},
+			DockerEnvConfig{profile: "bash", driver: "kvm2", hostIP: "127.0.0.1", daemon: tcpEndpoint("127.0.0.1", 2376)},
 			nil,
 			`export DOCKER_TLS_VERIFY="1"
 export DOCKER_HOST="tcp://127.0.0.1:2376"
@@ -108,7 +117,7 @@ unset MINIKUBE_ACTIVE_DOCKERD;
 		{
 			"bash",
 			"",
-			DockerEnvConfig{profile: "ipv6", driver: "kvm2", hostIP: "fe80::215:5dff:fe00:a903", port: 2376, certsDir: "/certs"},
+			DockerEnvConfig{profile: "ipv6", driver: "kvm2", hostIP: "fe80::215:5dff:fe00:a903", daemon: tcpEndpoint("fe80::215:5dff:fe00:a903", 2376)},
 			nil,
 			`export DOCKER_TLS_VERIFY="1"
 export DOCKER_HOST="tcp://[fe80::215:5dff:fe00:a903]:2376"
@@ -128,7 +137,7 @@ unset MINIKUBE_ACTIVE_DOCKERD;
 		{
 			"fish",
 			"",
-			DockerEnvConfig{profile: "fish", driver: "kvm2", hostIP: "127.0.0.1", port: 2376, certsDir: "/certs"},
+			DockerEnvConfig{profile: "fish", driver: "kvm2", hostIP: "127.0.0.1", daemon: tcpEndpoint("127.0.0.1", 2376)},
 			nil,
 			`set -gx DOCKER_TLS_VERIFY "1";
 set -gx DOCKER_HOST "tcp://127.0.0.1:2376";
@@ -148,7 +157,7 @@ set -e MINIKUBE_ACTIVE_DOCKERD;
 		{
 			"powershell",
 			"",
-			DockerEnvConfig{profile: "powershell", driver: "hyperv", hostIP: "192.168.0.1", port: 2376, certsDir: "/certs"},
+			DockerEnvConfig{profile: "powershell", driver: "hyperv", hostIP: "192.168.0.1", daemon: tcpEndpoint("192.168.0.1", 2376)},
 			nil,
 			`$Env:DOCKER_TLS_VERIFY = "1"
 $Env:DOCKER_HOST = "tcp://192.168.0.1:2376"
@@ -168,7 +177,7 @@ Remove-Item Env:\\MINIKUBE_ACTIVE_DOCKERD
 		{
 			"cmd",
 			"",
-			DockerEnvConfig{profile: "cmd", driver: "hyperv", hostIP: "192.168.0.1", port: 2376, certsDir: "/certs"},
+			DockerEnvConfig{profile: "cmd", driver: "hyperv", hostIP: "192.168.0.1", daemon: tcpEndpoint("192.168.0.1", 2376)},
 			nil,
 			`SET DOCKER_TLS_VERIFY=1
 SET DOCKER_HOST=tcp://192.168.0.1:2376
@@ -188,7 +197,7 @@ SET MINIKUBE_ACTIVE_DOCKERD=
 		{
 			"emacs",
 			"",
-			DockerEnvConfig{profile: "emacs", driver: "hyperv", hostIP: "192.168.0.1", port: 2376, certsDir: "/certs"},
+			DockerEnvConfig{profile: "emacs", driver: "hyperv", hostIP: "192.168.0.1", daemon: tcpEndpoint("192.168.0.1", 2376)},
 			nil,
 			`(setenv "DOCKER_TLS_VERIFY" "1")
 (setenv "DOCKER_HOST" "tcp://192.168.0.1:2376")
@@ -207,7 +216,7 @@ SET MINIKUBE_ACTIVE_DOCKERD=
 		{
 			"bash",
 			"",
-			DockerEnvConfig{profile: "bash-no-proxy", driver: "kvm2", hostIP: "127.0.0.1", port: 2376, certsDir: "/certs", noProxy: true},
+			DockerEnvConfig{profile: "bash-no-proxy", driver: "kvm2", hostIP: "127.0.0.1", daemon: tcpEndpoint("127.0.0.1", 2376), noProxy: true},
 			&FakeNoProxyGetter{"NO_PROXY", "127.0.0.1"},
 			`export DOCKER_TLS_VERIFY="1"
 export DOCKER_HOST="tcp://127.0.0.1:2376"
@@ -230,7 +239,7 @@ unset NO_PROXY;
 		{
 			"bash",
 			"",
-			DockerEnvConfig{profile: "bash-no-proxy-lower", driver: "kvm2", hostIP: "127.0.0.1", port: 2376, certsDir: "/certs", noProxy: true},
+			DockerEnvConfig{profile: "bash-no-proxy-lower", driver: "kvm2", hostIP: "127.0.0.1", daemon: tcpEndpoint("127.0.0.1", 2376), noProxy: true},
 			&FakeNoProxyGetter{"no_proxy", "127.0.0.1"},
 			`export DOCKER_TLS_VERIFY="1"
 export DOCKER_HOST="tcp://127.0.0.1:2376"
@@ -253,7 +262,7 @@ unset no_proxy;
 		{
 			"powershell",
 			"",
-			DockerEnvConfig{profile: "powershell-no-proxy-idempotent", driver: "hyperv", hostIP: "192.168.0.1", port: 2376, certsDir: "/certs", noProxy: true},
+			DockerEnvConfig{profile: "powershell-no-proxy-idempotent", driver: "hyperv", hostIP: "192.168.0.1", daemon: tcpEndpoint("192.168.0.1", 2376), noProxy: true},
 			&FakeNoProxyGetter{"no_proxy", "192.168.0.1"},
 			`$Env:DOCKER_TLS_VERIFY = "1"
 $Env:DOCKER_HOST = "tcp://192.168.0.1:2376"
@@ -275,7 +284,7 @@ Remove-Item Env:\\no_proxy
 		{
 			"bash",
 			"",
-			DockerEnvConfig{profile: "sh-no-proxy-add", driver: "kvm2", hostIP: "127.0.0.1", port: 2376, certsDir: "/certs", noProxy: true},
+			DockerEnvConfig{profile: "sh-no-proxy-add", driver: "kvm2", hostIP: "127.0.0.1", daemon: tcpEndpoint("127.0.0.1", 2376), noProxy: true},
 			&FakeNoProxyGetter{"NO_PROXY", "192.168.0.1,10.0.0.4"},
 			`export DOCKER_TLS_VERIFY="1"
 export DOCKER_HOST="tcp://127.0.0.1:2376"
@@ -298,7 +307,7 @@ unset NO_PROXY;
 		{
 			"none",
 			"",
-			DockerEnvConfig{profile: "noneshell", driver: "docker", hostIP: "127.0.0.1", port: 32842, certsDir: "/certs"},
+			DockerEnvConfig{profile: "noneshell", driver: "docker", hostIP: "127.0.0.1", daemon: tcpEndpoint("127.0.0.1", 32842)},
 			nil,
 			`DOCKER_TLS_VERIFY=1
 DOCKER_HOST=tcp://127.0.0.1:32842
@@ -315,7 +324,7 @@ MINIKUBE_ACTIVE_DOCKERD
 		{
 			"none",
 			"text",
-			DockerEnvConfig{profile: "nonetext", driver: "docker", hostIP: "127.0.0.1", port: 32842, certsDir: "/certs"},
+			DockerEnvConfig{profile: "nonetext", driver: "docker", hostIP: "127.0.0.1", daemon: tcpEndpoint("127.0.0.1", 32842)},
 			nil,
 			`DOCKER_TLS_VERIFY=1
 DOCKER_HOST=tcp://127.0.0.1:32842
@@ -339,7 +348,7 @@ MINIKUBE_ACTIVE_DOCKERD
 		{
 			"none",
 			"json",
-			DockerEnvConfig{profile: "nonejson", driver: "docker", hostIP: "127.0.0.1", port: 32842, certsDir: "/certs"},
+			DockerEnvConfig{profile: "nonejson", driver: "docker", hostIP: "127.0.0.1", daemon: tcpEndpoint("127.0.0.1", 32842)},
 			nil,
 			`{
 				"DOCKER_TLS_VERIFY": "1",
@@ -368,7 +377,7 @@ MINIKUBE_ACTIVE_DOCKERD
 		{
 			"none",
 			"yaml",
-			DockerEnvConfig{profile: "noneyaml", driver: "docker", hostIP: "127.0.0.1", port: 32842, certsDir: "/certs"},
+			DockerEnvConfig{profile: "noneyaml", driver: "docker", hostIP: "127.0.0.1", daemon: tcpEndpoint("127.0.0.1", 32842)},
 			nil,
 			`DOCKER_TLS_VERIFY: "1"
 DOCKER_HOST: tcp://127.0.0.1:32842
@@ -452,3 +461,37 @@ func TestValidDockerProxy(t *testing.T) {
 		}
 	}
 }
+
+// TestDockerScriptsGolden checks the set and unset scripts of each shell, separated by ---, with a Windows path with spaces to quote
+func TestDockerScriptsGolden(t *testing.T) {
+	t.Setenv(constants.MinikubeActiveDockerdEnv, "minikube")
+	daemon := cruntime.DaemonEndpoint{Host: "tcp://172.17.50.3:2376", TLSDir: `C:\Users\Jane Doe\.minikube\certs`, BuildTarget: true, CRISocket: "unix:///var/run/cri-dockerd.sock"}
+	defaultNoProxyGetter = &FakeNoProxyGetter{"NO_PROXY", "localhost"}
+	outputFormat = ""
+	for _, sh := range []string{"bash", "fish", "powershell", "pwsh", "cmd", "tcsh", "emacs", "nushell", "none"} {
+		for _, cri := range []bool{false, true} {
+			name := sh
+			if cri {
+				name += "-cri"
+			}
+			t.Run(name, func(t *testing.T) {
+				ec := DockerEnvConfig{EnvConfig: shell.EnvConfig{Shell: sh}, profile: "minikube", driver: "hyperv", cri: cri, hostIP: "172.17.50.3", noProxy: true, daemon: daemon}
+				var b bytes.Buffer
+				if err := dockerSetScript(ec, &b); err != nil {
+					t.Fatalf("dockerSetScript: %v", err)
+				}
+				b.WriteString("---\n")
+				if err := dockerUnsetScript(ec, &b); err != nil {
+					t.Fatalf("dockerUnsetScript: %v", err)
+				}
+				want, err := os.ReadFile(filepath.Join("testdata", "docker-env", name+".golden"))
+				if err != nil {
+					t.Fatalf("unable to read testdata: %v", err)
+				}
+				if diff := cmp.Diff(string(want), b.String()); diff != "" {
+					t.Errorf("%s script mismatch (-want +got):\n%s\n\nraw output:\n%s", name, diff, b.String())
+				}
+			})
+		}
+	}
+}
@@ -17,25 +17,55 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	cmdConfig "k8s.io/minikube/cmd/minikube/cmd/config"
+	"k8s.io/minikube/pkg/addons"
+	"k8s.io/minikube/pkg/kapi"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/audit"
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/image"
+	"k8s.io/minikube/pkg/minikube/image/manifest"
+	"k8s.io/minikube/pkg/minikube/image/verify"
 	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/out/register"
 	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
 	docker "k8s.io/minikube/third_party/go-dockerclient"
 )
 
 var (
-	allNodes bool
+	allNodes      bool
+	pushRegistry  string
+	historyFormat string
+	scanFormat    string
+	scanOffline   bool
+	statsStarts   int
+	layoutRef     string
+	layoutTag     string
+	// verifySignatures and signatureKey verify the cosign signatures of the images loaded or pulled, before the runtime gets them
+	verifySignatures bool
+	signatureKey     string
 )
 
 // imageCmd represents the image command
@@ -45,18 +75,96 @@ var imageCmd = &cobra.Command{
 }
 
 var (
-	pull       bool
-	imgDaemon  bool
-	imgRemote  bool
-	overwrite  bool
-	tag        string
-	push       bool
-	dockerFile string
-	buildEnv   []string
-	buildOpt   []string
-	format     string
+	pull            bool
+	imgDaemon       bool
+	imgRemote       bool
+	hostEngine      string
+	fromManifests   []string
+	imgCache        bool
+	overwrite       bool
+	remapRepository bool
+	tag             string
+	push            bool
+	dockerFile      string
+	buildEnv        []string
+	buildOpt        []string
+	cacheFrom       []string
+	cacheTo         []string
+	noCtxCache      bool
+	buildOutput     string
+	forceRemove     bool
+	format          string
+	listFilters     []string
 )
 
+// archiveLayouts replaces the directories of OCI image layouts in args with docker archives of their image, which all the runtimes load,
+// and returns a function removing the archives once they are loaded
+func archiveLayouts(args []string) ([]string, func()) {
+	archives := []string{}
+	cleanup := func() {
+		for _, a := range archives {
+			if err := os.Remove(a); err != nil {
+				klog.Warningf("failed to remove %s: %v", a, err)
+			}
+		}
+	}
+	for i, a := range args {
+		if fi, err := os.Stat(a); err != nil || !fi.IsDir() {
+			continue
+		}
+		if !image.IsOCILayout(a) {
+			exit.Message(reason.Usage, "{{.dir}} is a directory, but not an OCI image layout holding oci-layout and index.json", out.V{"dir": a})
+		}
+		tmp, err := os.CreateTemp("", "oci-layout.*.tar")
+		if err != nil {
+			exit.Error(reason.GuestImageLoad, "Failed to create archive", err)
+		}
+		archives = append(archives, tmp.Name())
+		if err := tmp.Close(); err != nil {
+			cleanup()
+			exit.Error(reason.GuestImageLoad, "Failed to create archive", err)
+		}
+		name, err := image.ArchiveFromLayout(a, layoutRef, layoutTag, tmp.Name())
+		if err != nil {
+			cleanup()
+			exit.Message(reason.GuestImageLoad, "Failed to read the OCI image layout {{.dir}}: {{.error}}. Select an image with --oci-ref, and name it with --tag.", out.V{"dir": a, "error": err})
+		}
+		out.Step(style.Check, "Loading {{.image}} from the OCI image layout {{.dir}}", out.V{"image": name, "dir": a})
+		args[i] = tmp.Name()
+	}
+	return args, cleanup
+}
+
+// verifyImageSignatures verifies that the images of registries are signed with the cosign public key of keyPath, set by the
+// keyFlag flag, exiting on the first image which fails the checks, and returns the references pinning the verified digests of the images
+func verifyImageSignatures(images []string, keyPath, keyFlag string) map[string]string {
+	if keyPath == "" {
+		exit.Message(reason.Usage, "Please provide the public key to verify the image signatures with, with --{{.flag}}=cosign.pub", out.V{"flag": keyFlag})
+	}
+	key, err := verify.LoadKey(keyPath)
+	if err != nil {
+		exit.Message(reason.Usage, "Unable to read the signature key: {{.error}}", out.V{"error": err})
+	}
+	pinned := map[string]string{}
+	for _, img := range images {
+		_, err := os.Stat(img)
+		if img == "-" || strings.HasPrefix(img, "/") || strings.HasPrefix(img, ".") || err == nil {
+			exit.Message(reason.Usage, "Unable to verify the signature of {{.image}}: only the images of registries hold cosign signatures", out.V{"image": img})
+		}
+		p, err := verify.Verify(img, key)
+		if err != nil {
+			var uerr *verify.ErrUnverified
+			if errors.As(err, &uerr) {
+				exit.Message(reason.GuestImageVerify, "Refusing {{.image}}, which failed signature verification:\n{{.checks}}", out.V{"image": img, "checks": "  - " + strings.Join(uerr.Failed, "\n  - ")})
+			}
+			exit.Error(reason.GuestImageVerify, "Failed to verify image signature", err)
+		}
+		out.Step(style.Check, "Verified the signature of {{.image}}: {{.pinned}}", out.V{"image": img, "pinned": p})
+		pinned[img] = p
+	}
+	return pinned
+}
+
 func saveFile(r io.Reader) (string, error) {
 	tmp, err := os.CreateTemp("", "build.*.tar")
 	if err != nil {
@@ -75,11 +183,18 @@ func saveFile(r io.Reader) (string, error) {
 
 // loadImageCmd represents the image load command
 var loadImageCmd = &cobra.Command{
-	Use:     "load IMAGE | ARCHIVE | -",
+	Use:     "load IMAGE | ARCHIVE | OCI_LAYOUT_DIR | -",
 	Short:   "Load an image into minikube",
-	Long:    "Load an image into minikube",
-	Example: "minikube image load image\nminikube image load image.tar",
+	Long:    "Load an image into minikube, from the local daemon, a remote registry, an archive, or the directory of an OCI image layout such as the ones written by buildah and rules_oci. The images of the local docker, podman or nerdctl engine are loaded from the engine holding them, docker first. With --from-manifest, the images run by the workloads of Kubernetes manifests are loaded into the nodes missing them.",
+	Example: "minikube image load image\nminikube image load image.tar\nminikube image load --host-engine podman localhost/image\nminikube image load --oci-ref v1.0 --tag example.com/app:v1.0 ./oci-layout-dir\nhelm template ./chart | minikube image load --from-manifest -",
 	Run: func(cmd *cobra.Command, args []string) {
+		if len(fromManifests) > 0 {
+			if len(args) != 0 {
+				exit.Message(reason.Usage, "Images can not be given with --from-manifest")
+			}
+			loadManifestImages(fromManifests)
+			return
+		}
 		if len(args) == 0 {
 			exit.Message(reason.Usage, "Please provide an image in your local daemon to load into minikube via <minikube image load IMAGE_NAME>")
 		}
@@ -89,15 +204,33 @@ var loadImageCmd = &cobra.Command{
 			exit.Error(reason.Usage, "loading profile", err)
 		}
 
+		var pinned map[string]string
+		if verifySignatures {
+			pinned = verifyImageSignatures(args, signatureKey, "signature-key")
+		}
+
 		if pull {
 			// Pull image from remote registry, without doing any caching except in container runtime.
 			// This is similar to daemon.Image but it is done by the container runtime in the cluster.
-			if err := machine.PullImages(args, profile); err != nil {
+			if err := machine.PullVerifiedImages(args, pinned, profile, imageOutput()); err != nil {
 				exit.Error(reason.GuestImageLoad, "Failed to pull image", err)
 			}
 			return
 		}
 
+		if verifySignatures {
+			// the images of the local daemon may differ from the verified ones of the registry
+			loadVerifiedImages(args, pinned, profile)
+			return
+		}
+
+		if err := image.UseHostEngine(hostEngine); err != nil {
+			exit.Message(reason.Usage, "{{.error}}", out.V{"error": err})
+		}
+		if hostEngine != "" {
+			imgDaemon = true
+		}
+
 		var local bool
 		if imgRemote || imgDaemon {
 			local = false
@@ -124,6 +257,9 @@ var loadImageCmd = &cobra.Command{
 			}
 		}
 
+		args, removeArchives := archiveLayouts(args)
+		defer removeArchives()
+
 		if args[0] == "-" {
 			tmp, err := saveFile(os.Stdin)
 			if err != nil {
@@ -135,19 +271,118 @@ var loadImageCmd = &cobra.Command{
 		if imgDaemon || imgRemote {
 			image.UseDaemon(imgDaemon)
 			image.UseRemote(imgRemote)
-			if err := machine.CacheAndLoadImages(args, []*config.Profile{profile}, overwrite); err != nil {
+			if err := machine.CacheAndLoadImages(args, []*config.Profile{profile}, overwrite, remapRepository, imageOutput()); err != nil {
 				exit.Error(reason.GuestImageLoad, "Failed to load image", err)
 			}
 		} else if local {
 			// Load images from local files, without doing any caching or checks in container runtime
 			// This is similar to tarball.Image but it is done by the container runtime in the cluster.
-			if err := machine.DoLoadImages(args, []*config.Profile{profile}, "", overwrite); err != nil {
+			loaded, err := machine.DoLoadImages(args, []*config.Profile{profile}, "", overwrite, remapRepository, imageOutput())
+			if err != nil {
 				exit.Error(reason.GuestImageLoad, "Failed to load image", err)
 			}
+			printLoadedImages(profile.Name, loaded)
 		}
 	},
 }
 
+// loadManifestImages loads the images run by the workloads of manifests which the nodes are missing, reading "-" from stdin
+func loadManifestImages(manifests []string) {
+	profile, err := config.LoadProfile(viper.GetString(config.ProfileName))
+	if err != nil {
+		exit.Error(reason.Usage, "loading profile", err)
+	}
+
+	readers := []io.Reader{}
+	for _, m := range manifests {
+		if m == "-" {
+			readers = append(readers, os.Stdin)
+			continue
+		}
+		f, err := os.Open(m)
+		if err != nil {
+			exit.Message(reason.Usage, "Unable to read manifest: {{.error}}", out.V{"error": err})
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	images, err := manifest.Images(readers...)
+	if err != nil {
+		exit.Message(reason.Usage, "Unable to parse manifests: {{.error}}", out.V{"error": err})
+	}
+	if len(images) == 0 {
+		out.Step(style.Empty, "The manifests do not reference any image")
+		return
+	}
+
+	if !pull {
+		if err := image.UseHostEngine(hostEngine); err != nil {
+			exit.Message(reason.Usage, "{{.error}}", out.V{"error": err})
+		}
+		if hostEngine != "" {
+			imgDaemon = true
+		}
+		if !imgDaemon && !imgRemote {
+			imgDaemon = true
+			imgRemote = true
+		}
+		image.UseDaemon(imgDaemon)
+		image.UseRemote(imgRemote)
+	}
+	summary, err := machine.LoadMissingImages(images, profile, pull, remapRepository)
+	if err != nil {
+		exit.Error(reason.GuestImageLoad, "Failed to load images", err)
+	}
+
+	for _, img := range summary.Loaded {
+		out.Step(style.Check, "Loaded {{.image}}", out.V{"image": img})
+	}
+	for _, img := range summary.Skipped {
+		out.Step(style.Option, "Skipped {{.image}}, present on all nodes", out.V{"image": img})
+	}
+	for _, img := range images {
+		if err := summary.Failed[img]; err != nil {
+			out.FailureT("Failed to load {{.image}}: {{.error}}", out.V{"image": img, "error": err})
+		}
+	}
+	out.Step(style.Success, "{{.loaded}} images loaded, {{.skipped}} skipped, {{.failed}} failed", out.V{"loaded": len(summary.Loaded), "skipped": len(summary.Skipped), "failed": len(summary.Failed)})
+	if len(summary.Failed) > 0 {
+		exit.Message(reason.GuestImageLoad, "Failed to load {{.count}} images", out.V{"count": len(summary.Failed)})
+	}
+}
+
+// loadVerifiedImages caches and loads the images of the registry by their verified digests, and tags them with their names
+func loadVerifiedImages(images []string, pinned map[string]string, profile *config.Profile) {
+	refs := []string{}
+	for _, img := range images {
+		refs = append(refs, pinned[img])
+	}
+	image.UseDaemon(false)
+	image.UseRemote(true)
+	if err := machine.CacheAndLoadImages(refs, []*config.Profile{profile}, overwrite, remapRepository, imageOutput()); err != nil {
+		exit.Error(reason.GuestImageLoad, "Failed to load image", err)
+	}
+	for _, img := range images {
+		if img == pinned[img] || strings.Contains(img, "@") {
+			continue
+		}
+		if err := machine.TagImage(profile, pinned[img], img); err != nil {
+			exit.Error(reason.GuestImageLoad, "Failed to tag the verified image", err)
+		}
+	}
+}
+
+// printLoadedImages shows the references of the images loaded from archives, whose names users may not know
+func printLoadedImages(profile string, refs []string) {
+	for _, ref := range refs {
+		if cruntime.IsImageID(ref) {
+			out.Step(style.Tip, "Loaded the untagged image {{.id}}, name it with: minikube image tag -p {{.profile}} {{.id}} NAME:TAG", out.V{"id": ref, "profile": profile})
+			continue
+		}
+		out.Step(style.Check, "Loaded {{.image}}", out.V{"image": ref})
+	}
+}
+
 func readFile(w io.Writer, tmp string) error {
 	r, err := os.Open(tmp)
 	if err != nil {
@@ -169,11 +404,14 @@ var saveImageCmd = &cobra.Command{
 	Use:     "save IMAGE [ARCHIVE | -]",
 	Short:   "Save a image from minikube",
 	Long:    "Save a image from minikube",
-	Example: "minikube image save image\nminikube image save image image.tar",
+	Example: "minikube image save image\nminikube image save image image.tar\nminikube image save image --cache",
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
 			exit.Message(reason.Usage, "Please provide an image in the container runtime to save from minikube via <minikube image save IMAGE_NAME>")
 		}
+		if imgCache && len(args) > 1 {
+			exit.Message(reason.Usage, "The --cache flag cannot be used together with an archive")
+		}
 		// Save images from container runtime
 		profile, err := config.LoadProfile(viper.GetString(config.ProfileName))
 		if err != nil {
@@ -204,9 +442,18 @@ var saveImageCmd = &cobra.Command{
 				os.Remove(output)
 			}
 		} else {
+			if imgCache {
+				out.Step(style.Caching, "Saving {{.image}} into the minikube cache ...", out.V{"image": args[0]})
+			}
 			if err := machine.SaveAndCacheImages([]string{args[0]}, []*config.Profile{profile}); err != nil {
 				exit.Error(reason.GuestImageSave, "Failed to save image", err)
 			}
+			if imgCache {
+				// Add image to config file, so that it is loaded on the next start
+				if err := cmdConfig.AddToConfigMap(cacheImageConfigKey, []string{args[0]}); err != nil {
+					exit.Error(reason.InternalAddConfig, "Failed to update config", err)
+				}
+			}
 			if imgDaemon || imgRemote {
 				image.UseDaemon(imgDaemon)
 				image.UseRemote(imgRemote)
@@ -226,6 +473,8 @@ var removeImageCmd = &cobra.Command{
 $ minikube image rm image busybox
 
 $ minikube image unload image busybox
+
+$ minikube image rm --force registry.k8s.io/pause@sha256:9001185023633d17a2f98ff69b6ff2615b8ea02a825adffa40422f51dfdcde9d
 `,
 	Args:    cobra.MinimumNArgs(1),
 	Aliases: []string{"remove", "unload"},
@@ -234,7 +483,7 @@ $ minikube image unload image busybox
 		if err != nil {
 			exit.Error(reason.Usage, "loading profile", err)
 		}
-		if err := machine.RemoveImages(args, profile); err != nil {
+		if err := machine.RemoveImages(args, profile, forceRemove); err != nil {
 			exit.Error(reason.GuestImageRemove, "Failed to remove image", err)
 		}
 	},
@@ -252,12 +501,31 @@ $ minikube image pull busybox
 			exit.Error(reason.Usage, "loading profile", err)
 		}
 
-		if err := machine.PullImages(args, profile); err != nil {
+		var pinned map[string]string
+		if verifySignatures {
+			pinned = verifyImageSignatures(args, signatureKey, "signature-key")
+		}
+		if err := machine.PullVerifiedImages(args, pinned, profile, imageOutput()); err != nil {
 			exit.Error(reason.GuestImagePull, "Failed to pull images", err)
 		}
 	},
 }
 
+var verifyImageCmd = &cobra.Command{
+	Use:   "verify IMAGE [IMAGE...]",
+	Short: "Verify the cosign signatures of images",
+	Long:  "Verify that images of registries are signed with a cosign public key, from the host, without loading them into minikube. The transparency log is not checked.",
+	Example: `
+$ minikube image verify --key cosign.pub example.com/app:v1
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			exit.Message(reason.Usage, "Please provide an image to verify")
+		}
+		verifyImageSignatures(args, signatureKey, "key")
+	},
+}
+
 func createTar(dir string) (string, error) {
 	tar, err := docker.CreateTarStream(dir, dockerFile)
 	if err != nil {
@@ -268,14 +536,23 @@ func createTar(dir string) (string, error) {
 
 // buildImageCmd represents the image build command
 var buildImageCmd = &cobra.Command{
-	Use:     "build PATH | URL | -",
-	Short:   "Build a container image in minikube",
-	Long:    "Build a container image, using the container runtime.",
-	Example: `minikube image build .`,
+	Use:   "build PATH | URL | -",
+	Short: "Build a container image in minikube",
+	Long:  "Build a container image, using the container runtime.",
+	Example: `minikube image build .
+
+$ minikube image build -t my-app --cache-from addon/my-app:cache --cache-to type=registry,ref=addon/my-app:cache,mode=max .`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 1 {
 			exit.Message(reason.Usage, "Please provide a path or url to build")
 		}
+		if buildOutput != "text" && buildOutput != "json" {
+			exit.Message(reason.Usage, "Invalid output format {{.format}}, expected text or json", out.V{"format": buildOutput})
+		}
+		if err := cruntime.ValidateCaches(append(append([]string{}, cacheFrom...), cacheTo...)); err != nil {
+			exit.Message(reason.Usage, "Invalid build cache: {{.error}}", out.V{"error": err})
+		}
+		out.SetJSON(buildOutput == "json")
 		// Build images into container runtime
 		profile, err := config.LoadProfile(viper.GetString(config.ProfileName))
 		if err != nil {
@@ -284,6 +561,7 @@ var buildImageCmd = &cobra.Command{
 
 		img := args[0]
 		var tmp string
+		var contextDir string
 		if img == "-" {
 			tmp, err = saveFile(os.Stdin)
 			if err != nil {
@@ -305,12 +583,33 @@ var buildImageCmd = &cobra.Command{
 					if err != nil {
 						exit.Error(reason.GuestImageBuild, "Failed to save dir", err)
 					}
+					if !noCtxCache {
+						contextDir = img
+					}
 					img = tmp
 				}
 				// Otherwise, assume it's a tar
 			}
 		}
-		if err := machine.BuildImage(img, dockerFile, tag, push, buildEnv, buildOpt, []*config.Profile{profile}, allNodes, nodeName); err != nil {
+		opts := cruntime.BuildOptions{File: dockerFile, Tag: tag, Push: push, Env: buildEnv, Opts: buildOpt, CacheFrom: cacheFrom, CacheTo: cacheTo}
+		if cruntime.UsesCacheRegistry(append(append([]string{}, cacheFrom...), cacheTo...), cruntime.AddonCacheRegistry) {
+			addr := registryAddonAddress(profile.Name)
+			if opts.CacheFrom, err = cruntime.RetagCaches(cacheFrom, cruntime.AddonCacheRegistry, addr); err != nil {
+				exit.Error(reason.Usage, "Invalid build cache", err)
+			}
+			if opts.CacheTo, err = cruntime.RetagCaches(cacheTo, cruntime.AddonCacheRegistry, addr); err != nil {
+				exit.Error(reason.Usage, "Invalid build cache", err)
+			}
+		}
+		var output machine.BuildOutput
+		if out.JSON {
+			artifact := tag
+			if artifact == "" {
+				artifact = args[0]
+			}
+			output = &jsonBuildOutput{artifact: artifact}
+		}
+		if err := machine.BuildImage(img, opts, []*config.Profile{profile}, allNodes, nodeName, contextDir, output); err != nil {
 			exit.Error(reason.GuestImageBuild, "Failed to build image", err)
 		}
 		if tmp != "" {
@@ -319,25 +618,164 @@ var buildImageCmd = &cobra.Command{
 	},
 }
 
+// jsonBuildOutput reports the output of the builds as image progress events, one per line, for IDE integrations
+type jsonBuildOutput struct {
+	artifact string
+	start    time.Time
+	log      *cruntime.BuildLog
+}
+
+// Start reports the start of the build on a node, returning the writers turning its output into events
+func (o *jsonBuildOutput) Start(node string) (io.Writer, io.Writer) {
+	o.start = time.Now()
+	o.log = &cruntime.BuildLog{OnLine: func(stream, step, line string) {
+		register.PrintImageProgress(o.artifact, register.ImageBuild, register.ImageInProgress, time.Since(o.start), map[string]string{"node": node, "stream": stream, "step": step, "line": line})
+	}}
+	register.PrintImageProgress(o.artifact, register.ImageBuild, register.ImageStarted, 0, map[string]string{"node": node})
+	return o.log.Stream("stdout"), o.log.Stream("stderr")
+}
+
+// Done reports the end of the build on a node, with the ID of the built image or the error
+func (o *jsonBuildOutput) Done(node string, err error) {
+	o.log.Flush()
+	data := map[string]string{"node": node}
+	if err != nil {
+		data["error"] = err.Error()
+	} else if id := o.log.ImageID(); id != "" {
+		data["imageid"] = id
+	}
+	register.PrintImageProgress(o.artifact, register.ImageBuild, register.ImageFinished, time.Since(o.start), data)
+}
+
 var listImageCmd = &cobra.Command{
-	Use:   "ls",
+	Use:   "ls [PATTERN]",
 	Short: "List images",
+	Long:  "List the images on all nodes, optionally only those whose reference matches a glob pattern or the given filters.",
 	Example: `
 $ minikube image ls
+
+$ minikube image ls 'registry.k8s.io/*'
+
+$ minikube image ls --filter dangling=true
+
+$ minikube image ls --filter platform=linux/amd64
 `,
 	Aliases: []string{"list"},
 	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			exit.Message(reason.Usage, "Please provide at most one pattern to match the images")
+		}
+		pattern := ""
+		if len(args) == 1 {
+			pattern = args[0]
+		}
+		opts, err := parseImageListFilters(pattern, listFilters)
+		if err != nil {
+			exit.Error(reason.Usage, "invalid filter", err)
+		}
 		profile, err := config.LoadProfile(viper.GetString(config.ProfileName))
 		if err != nil {
 			exit.Error(reason.Usage, "loading profile", err)
 		}
 
-		if err := machine.ListImages(profile, format); err != nil {
+		if err := machine.ListImages(profile, format, opts); err != nil {
 			exit.Error(reason.GuestImageList, "Failed to list images", err)
 		}
 	},
 }
 
+// parseImageListFilters returns the options listing the images matching pattern and the key=value filters,
+// which are the same as those of 'docker images': reference=PATTERN and dangling=true|false.
+func parseImageListFilters(pattern string, filters []string) (cruntime.ListImagesOptions, error) {
+	opts := cruntime.ListImagesOptions{Reference: pattern}
+	for _, f := range filters {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return opts, fmt.Errorf("filter %q is not in the key=value format", f)
+		}
+		switch kv[0] {
+		case "reference":
+			if opts.Reference != "" && opts.Reference != kv[1] {
+				return opts, fmt.Errorf("only one reference pattern is supported, got %q and %q", opts.Reference, kv[1])
+			}
+			opts.Reference = kv[1]
+		case "dangling":
+			dangling, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				return opts, fmt.Errorf("filter %q must be dangling=true or dangling=false", f)
+			}
+			opts.Dangling = &dangling
+		case "platform":
+			if opts.Platform != "" && opts.Platform != kv[1] {
+				return opts, fmt.Errorf("only one platform is supported, got %q and %q", opts.Platform, kv[1])
+			}
+			if err := cruntime.ValidatePlatform(kv[1]); err != nil {
+				return opts, err
+			}
+			opts.Platform = kv[1]
+		default:
+			return opts, fmt.Errorf("unknown filter %q, supported filters are reference, dangling and platform", kv[0])
+		}
+	}
+	if opts.Reference != "" {
+		if _, err := path.Match(opts.Reference, ""); err != nil {
+			return opts, fmt.Errorf("invalid pattern %q: %v", opts.Reference, err)
+		}
+	}
+	return opts, nil
+}
+
+var historyImageCmd = &cobra.Command{
+	Use:   "history IMAGE [IMAGE...]",
+	Short: "Show the layers of images",
+	Long:  "Show the layers of images on the primary control plane, with their sizes and the total size of the images, counting the layers they share once.",
+	Example: `
+$ minikube image history busybox
+
+$ minikube image history -o json registry.k8s.io/kube-apiserver:v1.25.3 registry.k8s.io/kube-controller-manager:v1.25.3
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			exit.Message(reason.Usage, "Please provide an image to show the history of")
+		}
+		profile, err := config.LoadProfile(viper.GetString(config.ProfileName))
+		if err != nil {
+			exit.Error(reason.Usage, "loading profile", err)
+		}
+
+		if err := machine.ShowImageHistory(profile, args, historyFormat); err != nil {
+			exit.Error(reason.GuestImageList, "Failed to show image history", err)
+		}
+	},
+}
+
+var scanImageCmd = &cobra.Command{
+	Use:   "scan IMAGE",
+	Short: "Scan an image for vulnerabilities",
+	Long:  "Scan an image on the primary control plane for vulnerabilities with trivy, which is installed in the node on first use.",
+	Example: `
+$ minikube image scan my-app:latest
+
+$ minikube image scan --offline -o json my-app:latest
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exit.Message(reason.Usage, "Please provide an image to scan")
+		}
+		profile, err := config.LoadProfile(viper.GetString(config.ProfileName))
+		if err != nil {
+			exit.Error(reason.Usage, "loading profile", err)
+		}
+
+		if err := machine.ScanImage(profile, args[0], scanFormat, scanOffline); err != nil {
+			if errors.Is(err, machine.ErrScanOffline) {
+				exit.Message(reason.GuestImageScan, "Unable to scan offline: {{.error}}. Run 'minikube image scan' once without --offline to cache trivy and its vulnerability database.", out.V{"error": err})
+			}
+			exit.Error(reason.GuestImageScan, "Failed to scan image", err)
+		}
+	},
+}
+
 var tagImageCmd = &cobra.Command{
 	Use:   "tag",
 	Short: "Tag images",
@@ -365,6 +803,8 @@ var pushImageCmd = &cobra.Command{
 	Short: "Push images",
 	Example: `
 $ minikube image push busybox
+
+$ minikube image push --registry addon my-app:latest
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		profile, err := config.LoadProfile(viper.GetString(config.ProfileName))
@@ -372,20 +812,92 @@ $ minikube image push busybox
 			exit.Error(reason.Usage, "loading profile", err)
 		}
 
+		if pushRegistry != "" {
+			addr := pushRegistry
+			if pushRegistry == "addon" {
+				addr = registryAddonAddress(profile.Name)
+			}
+			refs, err := machine.PushImagesToRegistry(args, profile, addr)
+			for _, ref := range refs {
+				out.Step(style.Check, "Pushed {{.image}}", out.V{"image": ref})
+			}
+			if err != nil {
+				exit.Error(reason.GuestImagePush, "Failed to push images", err)
+			}
+			return
+		}
+
 		if err := machine.PushImages(args, profile); err != nil {
 			exit.Error(reason.GuestImagePush, "Failed to push images", err)
 		}
 	},
 }
 
+var statsImageCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show how the last starts got their images",
+	Long:  "Show how the last starts got their images from the audit log: how many images were pulled and for how long, how many bytes were loaded from the cache and preload of minikube rather than the network, and how often the cache was hit. Nothing leaves this machine.",
+	Example: `
+$ minikube image stats
+
+$ minikube image stats --starts 3
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		r, err := audit.ImageStats(statsStarts)
+		if err != nil {
+			exit.Error(reason.HostImageStats, "Failed to read the image operations from the audit log", err)
+		}
+		if len(r.Starts) == 0 {
+			out.Styled(style.Empty, "No start has been logged yet")
+			return
+		}
+		out.Styled(style.Empty, r.ASCIITable())
+	},
+}
+
+// registryAddonAddress returns the in-cluster address of the registry addon, exiting if it is not running
+func registryAddonAddress(profile string) string {
+	co := mustload.Running(profile)
+	if !assets.Addons["registry"].IsEnabled(co.Config) {
+		exit.Message(reason.AddonNotEnabled, `The registry addon is not enabled. To enable it, run:
+
+	minikube addons enable registry -p {{.profile}}`, out.V{"profile": profile})
+	}
+	client, err := kapi.Client(profile)
+	if err != nil {
+		exit.Error(reason.InternalKubernetesClient, "error creating clientset", err)
+	}
+	svc, err := client.CoreV1().Services("kube-system").Get(context.Background(), "registry", meta.GetOptions{})
+	if err != nil {
+		exit.Message(reason.SvcNotFound, "The registry addon service was not found, it may still be starting: {{.error}}", out.V{"error": err})
+	}
+	addr, err := addons.RegistryServiceAddress(svc, co.CP.Node.IP)
+	if err != nil {
+		exit.Error(reason.SvcNotFound, "Unable to find the registry address", err)
+	}
+	return addr
+}
+
 func init() {
 	loadImageCmd.Flags().BoolVarP(&pull, "pull", "", false, "Pull the remote image (no caching)")
 	loadImageCmd.Flags().BoolVar(&imgDaemon, "daemon", false, "Cache image from docker daemon")
 	loadImageCmd.Flags().BoolVar(&imgRemote, "remote", false, "Cache image from remote registry")
+	loadImageCmd.Flags().StringSliceVar(&fromManifests, "from-manifest", nil, "Load the images run by the workloads of these Kubernetes manifests, such as the output of 'helm template', which the nodes are missing. '-' reads a manifest from stdin")
+	loadImageCmd.Flags().StringVar(&hostEngine, "host-engine", "", fmt.Sprintf("The container engine of the host to load the image from, one of: %s. Defaults to the first one holding the image", strings.Join(image.HostEngineNames(), "|")))
 	loadImageCmd.Flags().BoolVar(&overwrite, "overwrite", true, "Overwrite image even if same image:tag name exists")
+	loadImageCmd.Flags().StringVar(&layoutRef, "oci-ref", "", "The org.opencontainers.image.ref.name or io.containerd.image.name annotation of the image to load, when an OCI image layout holds several")
+	loadImageCmd.Flags().StringVar(&layoutTag, "tag", "", "The name of the image loaded from an OCI image layout, defaulting to the full reference in its annotations")
+	loadImageCmd.Flags().BoolVar(&remapRepository, "remap-repository", true, "Also tag the loaded image with its name in the --image-repository of the profile, if set, which the manifests of minikube use")
+	loadImageCmd.Flags().BoolVar(&verifySignatures, "verify-signatures", false, "Verify the cosign signatures of the images of registries with --signature-key before loading them, refusing the unsigned ones")
+	loadImageCmd.Flags().StringVar(&signatureKey, "signature-key", "", "The cosign public key to verify the image signatures with, such as cosign.pub")
 	imageCmd.AddCommand(loadImageCmd)
+	removeImageCmd.Flags().BoolVar(&forceRemove, "force", false, "Untag all the references of the images, and remove them")
 	imageCmd.AddCommand(removeImageCmd)
+	pullImageCmd.Flags().BoolVar(&verifySignatures, "verify-signatures", false, "Verify the cosign signatures of the images with --signature-key before pulling them, refusing the unsigned ones")
+	pullImageCmd.Flags().StringVar(&signatureKey, "signature-key", "", "The cosign public key to verify the image signatures with, such as cosign.pub")
 	imageCmd.AddCommand(pullImageCmd)
+	verifyImageCmd.Flags().StringVar(&signatureKey, "key", "", "The cosign public key to verify the image signatures with, such as cosign.pub")
+	imageCmd.AddCommand(verifyImageCmd)
 	buildImageCmd.Flags().StringVarP(&tag, "tag", "t", "", "Tag to apply to the new image (optional)")
 	buildImageCmd.Flags().BoolVarP(&push, "push", "", false, "Push the new image (requires tag)")
 	buildImageCmd.Flags().StringVarP(&dockerFile, "file", "f", "", "Path to the Dockerfile to use (optional)")
@@ -393,12 +905,31 @@ func init() {
 	buildImageCmd.Flags().StringArrayVar(&buildOpt, "build-opt", nil, "Specify arbitrary flags to pass to the build. (format: key=value)")
 	buildImageCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to build on. Defaults to the primary control plane.")
 	buildImageCmd.Flags().BoolVarP(&allNodes, "all", "", false, "Build image on all nodes.")
+	buildImageCmd.Flags().StringArrayVar(&cacheFrom, "cache-from", nil, "Import build cache layers from an image, or a registry cache spec (format: type=registry,ref=REF[,key=value...]), which may be repeated. The registry 'addon' points at the registry addon, as in addon/my-app:cache")
+	buildImageCmd.Flags().StringArrayVar(&cacheTo, "cache-to", nil, "Export the build cache layers to an image, or a registry cache spec (format: type=registry,ref=REF[,key=value...]), which may be repeated. Requires BuildKit with docker, that is buildx in the node")
+	buildImageCmd.Flags().BoolVar(&noCtxCache, "no-context-cache", false, "Transfer the whole build context, instead of only the files changed since the last build.")
+	buildImageCmd.Flags().StringVarP(&buildOutput, "output", "o", "text", "Format to print stdout in. Options include: [text,json]. With json, each line of the build output is printed as an event, with the step it belongs to.")
 	imageCmd.AddCommand(buildImageCmd)
 	saveImageCmd.Flags().BoolVar(&imgDaemon, "daemon", false, "Cache image to docker daemon")
 	saveImageCmd.Flags().BoolVar(&imgRemote, "remote", false, "Cache image to remote registry")
+	saveImageCmd.Flags().BoolVar(&imgCache, "cache", false, "Save image into the minikube cache directory, so that it is loaded on the next start")
 	imageCmd.AddCommand(saveImageCmd)
 	listImageCmd.Flags().StringVar(&format, "format", "short", "Format output. One of: short|table|json|yaml")
+	listImageCmd.Flags().StringArrayVar(&listFilters, "filter", nil, "Only list the images matching the filter, which may be repeated. One of: reference=PATTERN|dangling=true|dangling=false|platform=OS/ARCH[/VARIANT]")
 	imageCmd.AddCommand(listImageCmd)
+	historyImageCmd.Flags().StringVarP(&historyFormat, "output", "o", "table", "Format output. One of: table|json|yaml")
+	imageCmd.AddCommand(historyImageCmd)
+	scanImageCmd.Flags().StringVarP(&scanFormat, "output", "o", "table", "Format output. One of: table|json")
+	scanImageCmd.Flags().BoolVar(&scanOffline, "offline", false, "Scan without downloading trivy or updating its vulnerability database, failing if they are not cached yet")
+	imageCmd.AddCommand(scanImageCmd)
 	imageCmd.AddCommand(tagImageCmd)
+	pushImageCmd.Flags().StringVar(&pushRegistry, "registry", "", "Retag and push the images to this registry (host:port), or to the registry addon with 'addon'. Prints the references to use in manifests.")
 	imageCmd.AddCommand(pushImageCmd)
+	statsImageCmd.Flags().IntVar(&statsStarts, "starts", 10, "Number of the last starts to show")
+	imageCmd.AddCommand(statsImageCmd)
+}
+
+// imageOutput selects how the output of the runtime pulling images is shown, from the output flags and the terminal
+func imageOutput() cruntime.ImageOutput {
+	return cruntime.SelectImageOutput(out.JSON, out.IsSilent(), out.IsTerminal(os.Stdout))
 }
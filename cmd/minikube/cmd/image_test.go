@@ -0,0 +1,176 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/out/register"
+)
+
+func TestParseImageListFilters(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		description string
+		pattern     string
+		filters     []string
+		want        cruntime.ListImagesOptions
+		wantErr     bool
+	}{
+		{description: "none"},
+		{description: "pattern", pattern: "registry.k8s.io/*", want: cruntime.ListImagesOptions{Reference: "registry.k8s.io/*"}},
+		{description: "reference with registry port", filters: []string{"reference=localhost:5000/*"}, want: cruntime.ListImagesOptions{Reference: "localhost:5000/*"}},
+		{description: "dangling", filters: []string{"dangling=true"}, want: cruntime.ListImagesOptions{Dangling: &yes}},
+		{description: "pattern and not dangling", pattern: "busybox", filters: []string{"dangling=false", "reference=busybox"}, want: cruntime.ListImagesOptions{Reference: "busybox", Dangling: &no}},
+		{description: "two references", pattern: "busybox", filters: []string{"reference=nginx"}, wantErr: true},
+		{description: "bad dangling", filters: []string{"dangling=maybe"}, wantErr: true},
+		{description: "unknown filter", filters: []string{"label=app"}, wantErr: true},
+		{description: "no value", filters: []string{"dangling"}, wantErr: true},
+		{description: "bad pattern", pattern: "localhost:5000/[app", wantErr: true},
+		{description: "platform", filters: []string{"platform=linux/arm64/v8"}, want: cruntime.ListImagesOptions{Platform: "linux/arm64/v8"}},
+		{description: "two platforms", filters: []string{"platform=linux/amd64", "platform=linux/arm64"}, wantErr: true},
+		{description: "bad platform", filters: []string{"platform=amd64"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := parseImageListFilters(tc.pattern, tc.filters)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseImageListFilters() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); !tc.wantErr && diff != "" {
+				t.Errorf("parseImageListFilters() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJSONBuildOutput(t *testing.T) {
+	const id = "sha256:4a1d3f0b5e2c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e"
+	tests := []struct {
+		description string
+		// stdout and stderr are the transcript of the builder, written in chunks which split lines
+		stdout, stderr string
+		err            error
+		want           []string
+	}{
+		{
+			description: "buildkit",
+			stderr:      "#1 [internal] load build definition from Dockerfile\n#1 DONE 0.0s\n\n#5 [2/2] RUN make\n#5 0.412 ok\n#6 exporting to image\n#6 writing image " + id + " done\n#6 DONE 0.1s",
+			want: []string{
+				"started",
+				"progress stderr #1 #1 [internal] load build definition from Dockerfile",
+				"progress stderr #1 #1 DONE 0.0s",
+				"progress stderr #5 #5 [2/2] RUN make",
+				"progress stderr #5 #5 0.412 ok",
+				"progress stderr #6 #6 exporting to image",
+				"progress stderr #6 #6 writing image " + id + " done",
+				"progress stderr #6 #6 DONE 0.1s",
+				"finished imageid=" + id,
+			},
+		},
+		{
+			description: "classic builder",
+			stdout:      "Sending build context to Docker daemon  2.048kB\nStep 1/2 : FROM busybox\n ---> 3f57d9401f8d\nStep 2/2 : RUN true\n ---> Running in 0d3c4b5a6e7f\nSuccessfully built 9c4f1a2b3d5e\nSuccessfully tagged app:latest\n",
+			want: []string{
+				"started",
+				"progress stdout  Sending build context to Docker daemon  2.048kB",
+				"progress stdout 1/2 Step 1/2 : FROM busybox",
+				"progress stdout 1/2  ---> 3f57d9401f8d",
+				"progress stdout 2/2 Step 2/2 : RUN true",
+				"progress stdout 2/2  ---> Running in 0d3c4b5a6e7f",
+				"progress stdout 2/2 Successfully built 9c4f1a2b3d5e",
+				"progress stdout 2/2 Successfully tagged app:latest",
+				"finished imageid=9c4f1a2b3d5e",
+			},
+		},
+		{
+			description: "failure",
+			stdout:      "STEP 1/2: FROM alpine\nSTEP 2/2: RUN false\n",
+			stderr:      "Error: building at STEP \"RUN false\": exit status 1\n",
+			err:         errors.New("crio build image: exit status 1"),
+			want: []string{
+				"started",
+				"progress stdout 1/2 STEP 1/2: FROM alpine",
+				"progress stdout 2/2 STEP 2/2: RUN false",
+				"progress stderr 2/2 Error: building at STEP \"RUN false\": exit status 1",
+				"finished error=crio build image: exit status 1",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			register.SetOutputFile(buf)
+			defer register.SetOutputFile(os.Stdout)
+
+			o := &jsonBuildOutput{artifact: "app"}
+			stdout, stderr := o.Start("minikube")
+			for _, w := range []struct {
+				w io.Writer
+				s string
+			}{{stdout, tc.stdout}, {stderr, tc.stderr}} {
+				for s := w.s; s != ""; {
+					n := len(s)
+					if n > 7 {
+						n = 7
+					}
+					if _, err := w.w.Write([]byte(s[:n])); err != nil {
+						t.Fatalf("Write: %v", err)
+					}
+					s = s[n:]
+				}
+			}
+			o.Done("minikube", tc.err)
+
+			got := []string{}
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var e struct {
+					Data map[string]string `json:"data"`
+				}
+				if err := json.Unmarshal([]byte(line), &e); err != nil {
+					t.Fatalf("invalid JSON event %q: %v", line, err)
+				}
+				if e.Data["action"] != "build" || e.Data["artifact"] != "app" || e.Data["node"] != "minikube" {
+					t.Errorf("event %v is not about building app on minikube", e.Data)
+				}
+				switch e.Data["status"] {
+				case "started":
+					got = append(got, "started")
+				case "finished":
+					got = append(got, fmt.Sprintf("finished imageid=%s", e.Data["imageid"]))
+					if e.Data["error"] != "" {
+						got[len(got)-1] = fmt.Sprintf("finished error=%s", e.Data["error"])
+					}
+				default:
+					got = append(got, fmt.Sprintf("%s %s %s %s", e.Data["status"], e.Data["stream"], e.Data["step"], e.Data["line"]))
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("build events mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
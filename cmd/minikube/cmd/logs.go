@@ -31,6 +31,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/logs"
 	"k8s.io/minikube/pkg/minikube/machine"
 	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/node"
 	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/reason"
 )
@@ -44,6 +45,8 @@ var (
 	nodeName string
 	// followLogs triggers tail -f mode
 	followLogs bool
+	// logComponents are the containers whose logs to follow
+	logComponents []string
 	// numberOfLines is how many lines to output, set via -n
 	numberOfLines int
 	// showProblems only shows lines that match known issues
@@ -95,20 +98,28 @@ var logsCmd = &cobra.Command{
 			exit.Error(reason.InternalBootstrapper, "Error getting cluster bootstrapper", err)
 		}
 
-		cr, err := cruntime.New(cruntime.Config{Type: co.Config.KubernetesConfig.ContainerRuntime, Runner: co.CP.Runner})
+		cr, err := cruntime.New(cruntime.Config{Type: co.Config.KubernetesConfig.ContainerRuntime, Driver: co.Config.Driver, Runner: co.CP.Runner})
 		if err != nil {
 			exit.Error(reason.InternalNewRuntime, "Unable to get runtime", err)
 		}
 		if followLogs {
-			err := logs.Follow(cr, bs, *co.Config, co.CP.Runner, logOutput)
-			if err != nil {
-				exit.Error(reason.InternalLogFollow, "Follow", err)
+			if nodeName != "" {
+				n, _, err := node.Retrieve(*co.Config, nodeName)
+				if err != nil {
+					exit.Error(reason.GuestNodeRetrieve, "retrieving node", err)
+				}
+				if !n.ControlPlane {
+					exit.Message(reason.Usage, "The node {{.name}} runs no control plane containers to follow, follow the logs of the control plane node instead", out.V{"name": nodeName})
+				}
 			}
+			units := logs.FollowUnitCommands(cr, bs, *co.Config, numberOfLines)
+			logs.Stream(interruptContext(), cr, co.CP.Runner, logOutput, logs.StreamOptions{Components: logComponents, Units: units, Lines: numberOfLines, Color: out.WantsColor(logOutput)})
 			return
 		}
 		if showProblems {
 			problems := logs.FindProblems(cr, bs, *co.Config, co.CP.Runner)
 			logs.OutputProblems(problems, numberOfProblems, logOutput)
+			logs.OutputRuntimeEvents(cr, numberOfProblems, logOutput)
 			return
 		}
 		err = logs.Output(cr, bs, *co.Config, co.CP.Runner, numberOfLines, logOutput)
@@ -144,7 +155,8 @@ func shouldSilentFail() bool {
 }
 
 func init() {
-	logsCmd.Flags().BoolVarP(&followLogs, "follow", "f", false, "Show only the most recent journal entries, and continuously print new entries as they are appended to the journal.")
+	logsCmd.Flags().BoolVarP(&followLogs, "follow", "f", false, "Continuously print the new entries of the logs of the --component containers and of the journals of the kubelet and the container runtime, each after the name of its component, until interrupted.")
+	logsCmd.Flags().StringSliceVar(&logComponents, "component", logs.StreamComponents, "The control plane components whose containers to follow with --follow.")
 	logsCmd.Flags().BoolVar(&showProblems, "problems", false, "Show only log entries which point to known problems")
 	logsCmd.Flags().IntVarP(&numberOfLines, "length", "n", 60, "Number of lines back to go within the log")
 	logsCmd.Flags().StringVar(&nodeName, "node", "", "The node to get logs from. Defaults to the primary control plane.")
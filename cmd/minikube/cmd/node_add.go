@@ -19,6 +19,7 @@ package cmd
 import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"k8s.io/minikube/pkg/addons"
 	"k8s.io/minikube/pkg/minikube/cni"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/driver"
@@ -32,8 +33,9 @@ import (
 )
 
 var (
-	cp     bool
-	worker bool
+	cp          bool
+	worker      bool
+	nodeRuntime string
 )
 
 var nodeAddCmd = &cobra.Command{
@@ -56,6 +58,13 @@ var nodeAddCmd = &cobra.Command{
 			cp = false
 		}
 
+		if nodeRuntime != "" {
+			var err error
+			if nodeRuntime, err = validateRuntime(nodeRuntime); err != nil {
+				exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
+			}
+		}
+
 		out.Step(style.Happy, "Adding node {{.name}} to cluster {{.cluster}}", out.V{"name": name, "cluster": cc.Name})
 		// TODO: Deal with parameters better. Ideally we should be able to acceot any node-specific minikube start params here.
 		n := config.Node{
@@ -63,6 +72,10 @@ var nodeAddCmd = &cobra.Command{
 			Worker:            worker,
 			ControlPlane:      cp,
 			KubernetesVersion: cc.KubernetesConfig.KubernetesVersion,
+			ContainerRuntime:  nodeRuntime,
+		}
+		if rt := config.NodeRuntime(*cc, n); rt != cc.KubernetesConfig.ContainerRuntime {
+			out.Step(style.ContainerRuntime, "Node {{.name}} will use the {{.runtime}} container runtime", out.V{"name": name, "runtime": rt})
 		}
 
 		// Make sure to decrease the default amount of memory we use per VM if this is the first worker node
@@ -77,8 +90,8 @@ var nodeAddCmd = &cobra.Command{
 		}
 
 		register.Reg.SetStep(register.InitialSetup)
-		if err := node.Add(cc, n, false); err != nil {
-			_, err := maybeDeleteAndRetry(cmd, *cc, n, nil, err)
+		if err := node.Add(cc, n, false, cc.KubernetesConfig.ImageRepository); err != nil {
+			_, err := maybeDeleteAndRetry(cmd, *cc, n, nil, cc.KubernetesConfig.ImageRepository, err)
 			if err != nil {
 				exit.Error(reason.GuestNodeAdd, "failed to add node", err)
 			}
@@ -89,6 +102,7 @@ var nodeAddCmd = &cobra.Command{
 		}
 
 		out.Step(style.Ready, "Successfully added {{.name}} to {{.cluster}}!", out.V{"name": name, "cluster": cc.Name})
+		addons.WaitPrefetch()
 	},
 }
 
@@ -96,6 +110,7 @@ func init() {
 	// TODO(https://github.com/kubernetes/minikube/issues/7366): We should figure out which minikube start flags to actually import
 	nodeAddCmd.Flags().BoolVar(&cp, "control-plane", false, "This flag is currently unsupported.")
 	nodeAddCmd.Flags().BoolVar(&worker, "worker", true, "If true, the added node will be marked for work. Defaults to true.")
+	nodeAddCmd.Flags().StringVar(&nodeRuntime, containerRuntime, "", "The container runtime of the added node. Defaults to the container runtime of the cluster.")
 	nodeAddCmd.Flags().Bool(deleteOnFailure, false, "If set, delete the current cluster if start fails and try again. Defaults to false.")
 
 	nodeCmd.AddCommand(nodeAddCmd)
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	units "github.com/docker/go-units"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	cmdConfig "k8s.io/minikube/cmd/minikube/cmd/config"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/node"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+var analyzeImagesOutput string
+
+// imageAnalysisReport is the report of 'minikube node analyze-images'
+type imageAnalysisReport struct {
+	Nodes []machine.ImageAnalysis `json:"nodes"`
+	// Prune are the unreferenced images which no node uses, and PruneCommand the command removing them
+	Prune        []string `json:"prune"`
+	PruneCommand string   `json:"pruneCommand,omitempty"`
+}
+
+var nodeAnalyzeImagesCmd = &cobra.Command{
+	Use:   "analyze-images",
+	Short: "Report what takes the disk space of the images of nodes.",
+	Long:  "Categorize the images of the container runtime of each node: required by the control plane, used by the containers of workloads, used by the enabled addons, cached on the host, or unreferenced and safe to prune. Sizes include the layers images share, so the sizes of the categories add up to more than the disk space used.",
+	Example: `minikube node analyze-images
+minikube node analyze-images --node m02 -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube node analyze-images [--node name] [-o table|json]")
+		}
+		if analyzeImagesOutput != "table" && analyzeImagesOutput != "json" {
+			exit.Message(reason.Usage, "invalid output format: {{.output}}. Valid values: 'table', 'json'", out.V{"output": analyzeImagesOutput})
+		}
+		cached, err := cmdConfig.ListConfigMap(cacheImageConfigKey)
+		if err != nil {
+			exit.Error(reason.InternalListConfig, "Failed to get image map", err)
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		shown := ""
+		if nodeName != "" {
+			n, _, err := node.Retrieve(*co.Config, nodeName)
+			if err != nil {
+				exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": nodeName})
+			}
+			shown = config.MachineName(*co.Config, *n)
+		}
+
+		// the images to prune are checked against all nodes, as 'minikube image rm' removes them from all nodes
+		all := []machine.ImageAnalysis{}
+		report := imageAnalysisReport{Nodes: []machine.ImageAnalysis{}}
+		for _, n := range co.Config.Nodes {
+			a, err := machine.AnalyzeNodeImages(co.API, config.ForNode(*co.Config, n), n, cached)
+			if err != nil {
+				exit.Error(reason.GuestImageList, "Failed to analyze the images", err)
+			}
+			all = append(all, *a)
+			if shown == "" || a.Node == shown {
+				report.Nodes = append(report.Nodes, *a)
+			}
+		}
+		report.Prune = machine.PruneCandidates(all)
+		if len(report.Prune) > 0 {
+			report.PruneCommand = fmt.Sprintf("minikube image rm -p %s %s", co.Config.Name, strings.Join(report.Prune, " "))
+		}
+
+		if analyzeImagesOutput == "json" {
+			b, err := json.Marshal(report)
+			if err != nil {
+				exit.Error(reason.InternalJSONMarshal, "Failed to marshal the image analysis", err)
+			}
+			fmt.Println(string(b))
+			return
+		}
+		renderImageAnalysisTable(report.Nodes)
+		if len(report.Prune) == 0 {
+			out.Styled(style.Empty, "No image is safe to prune")
+			return
+		}
+		out.Styled(style.Tip, "To remove the {{.count}} unreferenced images, which no node uses, run:\n\n\t{{.command}}", out.V{"count": len(report.Prune), "command": report.PruneCommand})
+	},
+}
+
+// renderImageAnalysisTable renders the categories of the images of each node
+func renderImageAnalysisTable(analyses []machine.ImageAnalysis) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Node", "Category", "Images", "Size"})
+	table.SetAutoFormatHeaders(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("|")
+	for _, a := range analyses {
+		for _, c := range a.Categories {
+			table.Append([]string{a.Node, string(c.Category), strconv.Itoa(c.Images), units.HumanSize(float64(c.Size))})
+		}
+		table.Append([]string{a.Node, "reclaimable", "", units.HumanSize(float64(a.Reclaimable))})
+	}
+	table.Render()
+}
+
+func init() {
+	nodeAnalyzeImagesCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to report the images of. Defaults to all nodes.")
+	nodeAnalyzeImagesCmd.Flags().StringVarP(&analyzeImagesOutput, "output", "o", "table", "Format to print stdout in. Options include: [table,json]")
+	nodeCmd.AddCommand(nodeAnalyzeImagesCmd)
+}
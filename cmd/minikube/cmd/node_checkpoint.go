@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/node"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+var (
+	checkpointOutput       string
+	checkpointNamespace    string
+	checkpointExperimental bool
+)
+
+var nodeCheckpointCmd = &cobra.Command{
+	Use:   "checkpoint (POD/CONTAINER | CONTAINER_ID)",
+	Short: "Checkpoint a running container into a tarball (experimental)",
+	Long:  "Checkpoint a running container with CRIU into a tarball on the host, for forensic analysis. This is experimental, requires the containerd or cri-o runtime, and the ContainerCheckpoint feature gate of kubelet.",
+	Example: `minikube node checkpoint --experimental nginx/nginx
+minikube node checkpoint --experimental --namespace kube-system --output etcd.tar etcd-minikube/etcd
+minikube node checkpoint --experimental --node m02 3f5a9c`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exit.Message(reason.Usage, "Usage: minikube node checkpoint --experimental (POD/CONTAINER | CONTAINER_ID) [--namespace ns] [--output file] [--node name]")
+		}
+		if !checkpointExperimental {
+			exit.Message(reason.Usage, "Checkpointing containers is experimental, run this command with --experimental to use it")
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		if !bsutil.FeatureGateEnabled(co.Config.KubernetesConfig.FeatureGates, cruntime.CheckpointFeatureGate) {
+			exit.Message(reason.Usage, `Checkpointing containers requires the {{.gate}} feature gate of kubelet. To enable it, run:
+
+	minikube start --feature-gates={{.gate}}=true -p {{.profile}}`, out.V{"gate": cruntime.CheckpointFeatureGate, "profile": co.Config.Name})
+		}
+		n := co.CP.Node
+		if nodeName != "" {
+			var err error
+			if n, _, err = node.Retrieve(*co.Config, nodeName); err != nil {
+				exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": nodeName})
+			}
+		}
+
+		output := checkpointOutput
+		if output == "" {
+			output = checkpointFileName(args[0], time.Now())
+		}
+		if abs, err := filepath.Abs(output); err == nil {
+			output = abs
+		}
+		id, err := machine.CheckpointContainer(co.API, *co.Config, *n, checkpointNamespace, args[0], output)
+		var unsupported *cruntime.ErrUnsupported
+		if errors.As(err, &unsupported) {
+			exit.Message(reason.Unimplemented, `{{.error}}. Checkpointing containers requires the containerd or cri-o runtime, for example:
+
+	minikube start --container-runtime=containerd --feature-gates={{.gate}}=true`, out.V{"error": unsupported, "gate": cruntime.CheckpointFeatureGate})
+		}
+		if err != nil {
+			exit.Error(reason.GuestCheckpoint, "Failed to checkpoint the container", err)
+		}
+		out.Step(style.Success, "Checkpointed container {{.id}} of {{.node}} into {{.output}}", out.V{"id": id, "node": config.MachineName(*co.Config, *n), "output": output})
+	},
+}
+
+// checkpointFileName returns the default name of the tarball of a checkpoint
+func checkpointFileName(target string, now time.Time) string {
+	return fmt.Sprintf("checkpoint-%s-%s.tar", strings.ReplaceAll(target, "/", "_"), now.Format("2006-01-02T15-04-05"))
+}
+
+func init() {
+	nodeCheckpointCmd.Flags().BoolVar(&checkpointExperimental, "experimental", false, "Acknowledge that checkpointing containers is experimental, which is required to use it.")
+	nodeCheckpointCmd.Flags().StringVarP(&checkpointOutput, "output", "o", "", "The tarball to write the checkpoint to. Defaults to checkpoint-TARGET-TIME.tar in the current directory.")
+	nodeCheckpointCmd.Flags().StringVar(&checkpointNamespace, "namespace", "default", "The namespace of the pod, when the container is given as POD/CONTAINER.")
+	nodeCheckpointCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node running the container. Defaults to the primary control plane.")
+	nodeCmd.AddCommand(nodeCheckpointCmd)
+}
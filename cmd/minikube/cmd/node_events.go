@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/node"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/util"
+)
+
+var (
+	eventsSince  time.Duration
+	eventsOutput string
+)
+
+// nodeEvent is an event of a container runtime as listed by 'minikube node events'
+type nodeEvent struct {
+	Node string `json:"node"`
+	cruntime.RuntimeEvent
+}
+
+var nodeEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "List the recent incidents of the container runtimes of nodes.",
+	Long:  "List the recent incidents of the container runtime of each node which the events of Kubernetes do not capture, such as OOM kills, restarts of the runtime and images deleted by the image garbage collection of kubelet, oldest first.",
+	Example: `minikube node events
+minikube node events --since 10m --node m02 -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube node events [--since 1h] [--node name]")
+		}
+		if eventsOutput != "table" && eventsOutput != "json" {
+			exit.Message(reason.Usage, "invalid output format: {{.output}}. Valid values: 'table', 'json'", out.V{"output": eventsOutput})
+		}
+		if eventsSince <= 0 {
+			exit.Message(reason.Usage, "--since must be positive, such as 30m or 2h")
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		version, err := util.ParseKubernetesVersion(co.Config.KubernetesConfig.KubernetesVersion)
+		if err != nil {
+			exit.Error(reason.InternalNewRuntime, "Failed to parse Kubernetes version", err)
+		}
+		nodes := co.Config.Nodes
+		if nodeName != "" {
+			n, _, err := node.Retrieve(*co.Config, nodeName)
+			if err != nil {
+				exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": nodeName})
+			}
+			nodes = []config.Node{*n}
+		}
+
+		events := []nodeEvent{}
+		for _, n := range nodes {
+			machineName := config.MachineName(*co.Config, n)
+			host, err := machine.LoadHost(co.API, machineName)
+			if err != nil {
+				exit.Error(reason.GuestLoadHost, "Error getting host", err)
+			}
+			runner, err := machine.CommandRunner(host)
+			if err != nil {
+				exit.Error(reason.InternalCommandRunner, "Failed to get command runner", err)
+			}
+			k8s := config.ForNode(*co.Config, n).KubernetesConfig
+			cr, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: runner, Socket: k8s.CRISocket, KubernetesVersion: version})
+			if err != nil {
+				exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
+			}
+			list, err := cr.Events(eventsSince)
+			if err != nil {
+				exit.Error(reason.RuntimeEvents, "Failed to read the events of the container runtime", err)
+			}
+			for _, e := range list {
+				events = append(events, nodeEvent{Node: machineName, RuntimeEvent: e})
+			}
+		}
+		// the events of each node are sorted already, merge them
+		sort.SliceStable(events, func(i, j int) bool {
+			return events[i].Time.Before(events[j].Time)
+		})
+
+		if eventsOutput == "json" {
+			b, err := json.Marshal(events)
+			if err != nil {
+				exit.Error(reason.InternalJSONMarshal, "Failed to marshal events", err)
+			}
+			fmt.Println(string(b))
+			return
+		}
+		if len(events) == 0 {
+			out.Step(style.Check, "No incidents of the container runtimes in the last {{.since}}", out.V{"since": eventsSince})
+			return
+		}
+		renderEventsTable(events)
+	},
+}
+
+// renderEventsTable renders pretty table for runtime events
+func renderEventsTable(events []nodeEvent) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Time", "Node", "Source", "Type", "Subject", "Message"})
+	table.SetAutoFormatHeaders(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("|")
+	for _, e := range events {
+		table.Append([]string{e.Time.Local().Format(time.RFC3339), e.Node, e.Source, e.Type, e.Subject, e.Message})
+	}
+	table.Render()
+}
+
+func init() {
+	nodeEventsCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to list the events of. Defaults to all nodes.")
+	nodeEventsCmd.Flags().DurationVar(&eventsSince, "since", time.Hour, "How far back to list the events, such as 30m or 2h.")
+	nodeEventsCmd.Flags().StringVarP(&eventsOutput, "output", "o", "table", "Format to print stdout in. Options include: [table,json]")
+	nodeCmd.AddCommand(nodeEventsCmd)
+}
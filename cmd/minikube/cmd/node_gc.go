@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/node"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/util"
+)
+
+// defaultGCAge is how long containers have to be stopped for before they are garbage collected
+const defaultGCAge = 24 * time.Hour
+
+var gcAge time.Duration
+
+var nodeGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove stopped containers from nodes.",
+	Long:  "Remove the Kubernetes containers and pod sandboxes which stopped a while ago from the container runtime of each node, and those left behind by 'minikube node run' as soon as they stopped. Running containers are never removed.",
+	Example: `minikube node gc
+minikube node gc --node m02 --age 1h`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube node gc [--node name] [--age duration]")
+		}
+		if gcAge < 0 {
+			exit.Message(reason.Usage, "invalid age: {{.age}}. It must not be negative", out.V{"age": gcAge})
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		nodes := co.Config.Nodes
+		if nodeName != "" {
+			n, _, err := node.Retrieve(*co.Config, nodeName)
+			if err != nil {
+				exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": nodeName})
+			}
+			nodes = []config.Node{*n}
+		}
+
+		for _, n := range nodes {
+			machineName := config.MachineName(*co.Config, n)
+			removed, err := garbageCollectNode(co.API, config.ForNode(*co.Config, n), machineName, gcAge)
+			if err != nil {
+				exit.Error(reason.RuntimeGarbageCollect, "Failed to remove stopped containers", err)
+			}
+			out.Step(style.Deleted, "Removed {{.count}} stopped containers from {{.node}}", out.V{"count": removed, "node": machineName})
+		}
+	},
+}
+
+// garbageCollectNode removes the containers of a node which stopped longer ago than the given age
+func garbageCollectNode(api libmachine.API, cc config.ClusterConfig, machineName string, age time.Duration) (int, error) {
+	version, err := util.ParseKubernetesVersion(cc.KubernetesConfig.KubernetesVersion)
+	if err != nil {
+		return 0, errors.Wrap(err, "parse kubernetes version")
+	}
+	host, err := machine.LoadHost(api, machineName)
+	if err != nil {
+		return 0, errors.Wrap(err, "load host")
+	}
+	runner, err := machine.CommandRunner(host)
+	if err != nil {
+		return 0, errors.Wrap(err, "command runner")
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Driver: cc.Driver, Runner: runner, Socket: cc.KubernetesConfig.CRISocket, KubernetesVersion: version})
+	if err != nil {
+		return 0, errors.Wrap(err, "runtime")
+	}
+	if !cr.Active() {
+		klog.Infof("%s is not running on %s, nothing to remove", cr.Name(), machineName)
+		return 0, nil
+	}
+	return cr.GarbageCollect(age)
+}
+
+// maybeGarbageCollect removes the stopped containers of a node before stopping it, if it is running
+func maybeGarbageCollect(api libmachine.API, cc config.ClusterConfig, machineName string) {
+	st, err := machine.Status(api, machineName)
+	if err != nil || st != state.Running.String() {
+		klog.Infof("skipping garbage collection of %s, status %q: %v", machineName, st, err)
+		return
+	}
+	removed, err := garbageCollectNode(api, cc, machineName, defaultGCAge)
+	if err != nil {
+		out.WarningT("Unable to remove stopped containers from {{.node}}: {{.error}}", out.V{"node": machineName, "error": err})
+		return
+	}
+	klog.Infof("removed %d stopped containers from %s", removed, machineName)
+}
+
+func init() {
+	nodeGCCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to remove stopped containers from. Defaults to all nodes.")
+	nodeGCCmd.Flags().DurationVar(&gcAge, "age", defaultGCAge, "Only remove containers which stopped longer ago than this.")
+	nodeCmd.AddCommand(nodeGCCmd)
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/driver"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/node"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/minikube/tunnel/kic"
+)
+
+var (
+	portForwardAddress   string
+	portForwardNamespace string
+)
+
+var nodePortForwardCmd = &cobra.Command{
+	Use:   "port-forward (CONTAINER | POD/CONTAINER) [[LOCAL_PORT]:PORT...]",
+	Short: "Forward ports of the host to a container of a node",
+	Long: `Forward ports of the host to the ports a container of a node exposes or publishes, such as the containers run with 'minikube docker-env', over ssh. The forwards last until interrupted, and follow the container when it restarts at a new address.
+
+A PORT forwards the same port of the host, or a free one if it is in use, LOCAL_PORT:PORT forwards LOCAL_PORT, and :PORT a free port. Without ports, all the TCP ports of the container are forwarded.`,
+	Example: `minikube node port-forward web 80
+minikube node port-forward web 8080:80 :443
+minikube node port-forward --namespace kube-system --node m02 coredns-565d847f94-7xg2v/coredns 9153`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			exit.Message(reason.Usage, "Usage: minikube node port-forward (CONTAINER | POD/CONTAINER) [[LOCAL_PORT]:PORT...] [--namespace ns] [--node name] [--address address]")
+		}
+		forwards, err := parsePortForwards(args[1:])
+		if err != nil {
+			exit.Message(reason.Usage, "Invalid port: {{.error}}", out.V{"error": err})
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		if driver.BareMetal(co.Config.Driver) {
+			exit.Message(reason.Usage, "The ports of the containers are already on the host with the {{.driver}} driver", out.V{"driver": co.Config.Driver})
+		}
+		n := co.CP.Node
+		if nodeName != "" {
+			if n, _, err = node.Retrieve(*co.Config, nodeName); err != nil {
+				exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": nodeName})
+			}
+		}
+
+		container := args[0]
+		resolve, err := machine.ContainerPortResolver(co.API, *co.Config, *n, portForwardNamespace, container)
+		if err != nil {
+			exit.Error(reason.GuestPortForward, "Failed to find the container runtime of the node", err)
+		}
+		mappings, err := resolve()
+		if err != nil {
+			exit.Error(reason.GuestPortForward, "Failed to find the ports of the container", err)
+		}
+		forwards = containerForwards(forwards, mappings)
+		if len(forwards) == 0 {
+			exit.Message(reason.Usage, "Container {{.container}} exposes no TCP port, give the ports to forward", out.V{"container": container})
+		}
+		for _, f := range forwards {
+			if _, ok := tcpPort(mappings, f.Port); !ok {
+				exit.Message(reason.Usage, "Container {{.container}} does not expose port {{.port}}/tcp, it exposes: {{.ports}}", out.V{"container": container, "port": f.Port, "ports": describePorts(mappings)})
+			}
+		}
+
+		tunnel := kic.NewContainerTunnel(nodeSSHEndpoint(co, n), portForwardAddress, container, resolve)
+		forwards, err = tunnel.AllocateLocalPorts(forwards)
+		if err != nil {
+			exit.Error(reason.IfPortInUse, "Failed to listen on the host", err)
+		}
+		for _, f := range forwards {
+			m, _ := tcpPort(mappings, f.Port)
+			out.Step(style.Connectivity, "Forwarding {{.local}} to {{.container}}:{{.port}} ({{.target}} in the node)", out.V{"local": fmt.Sprintf("%s:%d", portForwardAddress, f.LocalPort), "container": container, "port": f.Port, "target": kic.ContainerAddress(m)})
+		}
+		out.Styled(style.Notice, "Press Ctrl-C to stop forwarding.")
+		if err := tunnel.Run(interruptContext(), forwards); err != nil {
+			exit.Error(reason.GuestPortForward, "Failed to forward the ports of the container", err)
+		}
+	},
+}
+
+// parsePortForwards parses the [LOCAL_PORT]:PORT arguments of port-forward
+func parsePortForwards(args []string) ([]kic.ContainerForward, error) {
+	forwards := []kic.ContainerForward{}
+	for _, arg := range args {
+		local, remote, fixed := strings.Cut(arg, ":")
+		if !fixed {
+			remote = arg
+		}
+		port, err := strconv.Atoi(remote)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("%q is not a port", remote)
+		}
+		f := kic.ContainerForward{LocalPort: port, Port: port}
+		if fixed {
+			f.LocalPort = 0
+			if local != "" {
+				if f.LocalPort, err = strconv.Atoi(local); err != nil || f.LocalPort < 1 || f.LocalPort > 65535 {
+					return nil, fmt.Errorf("%q is not a port", local)
+				}
+				f.Fixed = true
+			}
+		}
+		forwards = append(forwards, f)
+	}
+	return forwards, nil
+}
+
+// containerForwards returns the forwards, which are those of all the TCP ports of the container if none was given
+func containerForwards(forwards []kic.ContainerForward, mappings []cruntime.PortMapping) []kic.ContainerForward {
+	if len(forwards) > 0 {
+		return forwards
+	}
+	seen := map[int]bool{}
+	for _, m := range mappings {
+		if m.Protocol == "tcp" && !seen[m.ContainerPort] {
+			seen[m.ContainerPort] = true
+			forwards = append(forwards, kic.ContainerForward{LocalPort: m.ContainerPort, Port: m.ContainerPort})
+		}
+	}
+	return forwards
+}
+
+// tcpPort returns the mapping of a TCP port of a container
+func tcpPort(mappings []cruntime.PortMapping, port int) (cruntime.PortMapping, bool) {
+	for _, m := range mappings {
+		if m.Protocol == "tcp" && m.ContainerPort == port {
+			return m, true
+		}
+	}
+	return cruntime.PortMapping{}, false
+}
+
+// describePorts returns the PORT/PROTOCOL ports of a container
+func describePorts(mappings []cruntime.PortMapping) string {
+	ports := []string{}
+	seen := map[string]bool{}
+	for _, m := range mappings {
+		if p := fmt.Sprintf("%d/%s", m.ContainerPort, m.Protocol); !seen[p] {
+			seen[p] = true
+			ports = append(ports, p)
+		}
+	}
+	sort.Strings(ports)
+	if len(ports) == 0 {
+		return "none"
+	}
+	return strings.Join(ports, ", ")
+}
+
+// nodeSSHEndpoint returns the ssh server of a node
+func nodeSSHEndpoint(co mustload.ClusterController, n *config.Node) kic.SSHEndpoint {
+	h, err := machine.GetHost(co.API, *co.Config, *n)
+	if err != nil {
+		exit.Error(reason.GuestLoadHost, "Error getting host", err)
+	}
+	hostname, err := h.Driver.GetSSHHostname()
+	if err != nil {
+		exit.Error(reason.IfSSHClient, "Error getting ssh client", err)
+	}
+	port, err := h.Driver.GetSSHPort()
+	if err != nil {
+		exit.Error(reason.IfSSHClient, "Error getting ssh client", err)
+	}
+	return kic.SSHEndpoint{Host: hostname, Port: port, User: h.Driver.GetSSHUsername(), Key: h.Driver.GetSSHKeyPath()}
+}
+
+func init() {
+	nodePortForwardCmd.Flags().StringVar(&portForwardAddress, "address", "127.0.0.1", "The address of the host to listen on, or '*' for all of its addresses.")
+	nodePortForwardCmd.Flags().StringVar(&portForwardNamespace, "namespace", "default", "The namespace of the pod, when the container is given as POD/CONTAINER.")
+	nodePortForwardCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node running the container. Defaults to the primary control plane.")
+	nodeCmd.AddCommand(nodePortForwardCmd)
+}
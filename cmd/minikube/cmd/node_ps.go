@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	units "github.com/docker/go-units"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/node"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/util"
+)
+
+var (
+	psNamespace string
+	psState     string
+	psOutput    string
+)
+
+// nodeContainer is a container as listed by 'minikube node ps'
+type nodeContainer struct {
+	Node string `json:"node"`
+	cruntime.ContainerInfo
+}
+
+var nodePsCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "List containers running on nodes.",
+	Long:  "List the Kubernetes containers managed by the container runtime of each node, similar to 'crictl ps'.",
+	Example: `minikube node ps
+minikube node ps --node m02 --namespace kube-system --state all -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube node ps [--node name] [--namespace ns] [--state running|paused|all]")
+		}
+		if psOutput != "table" && psOutput != "json" {
+			exit.Message(reason.Usage, "invalid output format: {{.output}}. Valid values: 'table', 'json'", out.V{"output": psOutput})
+		}
+		state, err := cruntime.ParseContainerState(psState)
+		if err != nil {
+			exit.Message(reason.Usage, "invalid state: {{.state}}. Valid values: 'running', 'paused', 'all'", out.V{"state": psState})
+		}
+		opts := cruntime.ListContainersOptions{State: state}
+		if psNamespace != "" {
+			opts.Namespaces = []string{psNamespace}
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		version, err := util.ParseKubernetesVersion(co.Config.KubernetesConfig.KubernetesVersion)
+		if err != nil {
+			exit.Error(reason.InternalNewRuntime, "Failed to parse Kubernetes version", err)
+		}
+		nodes := co.Config.Nodes
+		if nodeName != "" {
+			n, _, err := node.Retrieve(*co.Config, nodeName)
+			if err != nil {
+				exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": nodeName})
+			}
+			nodes = []config.Node{*n}
+		}
+
+		containers := []nodeContainer{}
+		for _, n := range nodes {
+			machineName := config.MachineName(*co.Config, n)
+			host, err := machine.LoadHost(co.API, machineName)
+			if err != nil {
+				exit.Error(reason.GuestLoadHost, "Error getting host", err)
+			}
+			runner, err := machine.CommandRunner(host)
+			if err != nil {
+				exit.Error(reason.InternalCommandRunner, "Failed to get command runner", err)
+			}
+			k8s := config.ForNode(*co.Config, n).KubernetesConfig
+			cr, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: runner, Socket: k8s.CRISocket, KubernetesVersion: version})
+			if err != nil {
+				exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
+			}
+			if !cr.Active() {
+				exit.Message(reason.RuntimeNotRunning, "The {{.runtime}} container runtime is not running on node {{.node}}. Run 'minikube start' first.", out.V{"runtime": cr.Name(), "node": machineName})
+			}
+			list, err := cr.ListContainerInfo(opts)
+			if err != nil {
+				exit.Error(reason.RuntimeListContainers, "Failed to list containers", err)
+			}
+			for _, c := range list {
+				containers = append(containers, nodeContainer{Node: machineName, ContainerInfo: c})
+			}
+		}
+
+		if psOutput == "json" {
+			b, err := json.Marshal(containers)
+			if err != nil {
+				exit.Error(reason.InternalJSONMarshal, "Failed to marshal containers", err)
+			}
+			fmt.Println(string(b))
+			return
+		}
+		renderContainersTable(containers)
+	},
+}
+
+// renderContainersTable renders pretty table for containers list
+func renderContainersTable(containers []nodeContainer) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Node", "Container", "Pod", "Namespace", "State", "Image", "Age"})
+	table.SetAutoFormatHeaders(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("|")
+	for _, c := range containers {
+		age := ""
+		if !c.Created.IsZero() {
+			age = units.HumanDuration(time.Since(c.Created))
+		}
+		table.Append([]string{c.Node, c.Name, c.Pod, c.Namespace, c.State, c.Image, age})
+	}
+	table.Render()
+}
+
+func init() {
+	nodePsCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to list containers on. Defaults to all nodes.")
+	nodePsCmd.Flags().StringVar(&psNamespace, "namespace", "", "Only list containers in this namespace. Defaults to all namespaces.")
+	nodePsCmd.Flags().StringVar(&psState, "state", "running", "Only list containers in this state. One of: running|paused|all")
+	nodePsCmd.Flags().StringVarP(&psOutput, "output", "o", "table", "Format to print stdout in. Options include: [table,json]")
+	nodeCmd.AddCommand(nodePsCmd)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/node"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+)
+
+// interruptedExitCode is the exit code of a command interrupted by the user, as with shells
+const interruptedExitCode = 130
+
+var (
+	diagnosticImage string
+	diagnosticEnv   []string
+)
+
+var nodeRunCmd = &cobra.Command{
+	Use:   "run --image IMAGE -- COMMAND [ARG...]",
+	Short: "Run a one-off container in a node, for diagnostics.",
+	Long:  "Run a one-off container of an image with a command in the container runtime of a node, in the network of the node, and print its output. The image is pulled if the node does not have it, unless the cluster was started with --assume-offline. The container is removed once it exits or the command is interrupted, and minikube exits with the exit code of the container.",
+	Example: `minikube node run --image busybox -- nslookup kubernetes.default
+minikube node run --image busybox --node m02 -- df -h /var
+minikube node run --image curlimages/curl --env NO_PROXY=* -- curl -sS http://10.96.0.1`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if diagnosticImage == "" || len(args) == 0 {
+			exit.Message(reason.Usage, "Usage: minikube node run --image IMAGE [--node name] [--env KEY=VALUE] -- COMMAND [ARG...]")
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		n := co.CP.Node
+		if nodeName != "" {
+			var err error
+			if n, _, err = node.Retrieve(*co.Config, nodeName); err != nil {
+				exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": nodeName})
+			}
+		}
+
+		code, err := machine.RunDiagnosticContainer(interruptContext(), co.API, *co.Config, *n, diagnosticImage, args, cruntime.DiagnosticOptions{Stdout: os.Stdout, Stderr: os.Stderr, Env: diagnosticEnv})
+		var offline *cruntime.ErrOffline
+		switch {
+		case errors.As(err, &offline):
+			exit.Message(reason.InetOffline, "The node is offline and misses the image {{.image}}. Load it with 'minikube image load {{.image}}' first.", out.V{"image": diagnosticImage})
+		case errors.Is(err, context.Canceled):
+			os.Exit(interruptedExitCode)
+		case err != nil:
+			exit.Error(reason.GuestDiagnosticRun, "Failed to run the diagnostic container", err)
+		}
+		if code != 0 {
+			os.Exit(code)
+		}
+	},
+}
+
+func init() {
+	nodeRunCmd.Flags().StringVar(&diagnosticImage, "image", "", "The image of the container, which is pulled if the node does not have it.")
+	nodeRunCmd.Flags().StringArrayVar(&diagnosticEnv, "env", nil, "Environment variables of the container, as KEY=VALUE. Can be repeated.")
+	nodeRunCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to run the container in. Defaults to the primary control plane.")
+	nodeCmd.AddCommand(nodeRunCmd)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/node"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+var (
+	snapshotOutput  string
+	snapshotRestore string
+)
+
+var nodeSnapshotImagesCmd = &cobra.Command{
+	Use:   "snapshot-images",
+	Short: "Save or restore all the images of a node.",
+	Long:  "Save all the images of a node into a single archive with --output, or load the images of such an archive into the nodes with --restore, so that a cluster can be restored on another machine without pulling its images again.",
+	Example: `minikube node snapshot-images --output images.tar
+minikube node snapshot-images --restore images.tar
+minikube node snapshot-images --restore images.tar --node m02`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 || (snapshotOutput == "") == (snapshotRestore == "") {
+			exit.Message(reason.Usage, "Usage: minikube node snapshot-images (--output file | --restore file) [--node name]")
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		ctx := interruptContext()
+		if snapshotOutput != "" {
+			n := co.CP.Node
+			if nodeName != "" {
+				var err error
+				if n, _, err = node.Retrieve(*co.Config, nodeName); err != nil {
+					exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": nodeName})
+				}
+			}
+			if err := machine.SnapshotImages(ctx, co.API, *co.Config, *n, snapshotOutput); err != nil {
+				exit.Error(reason.GuestImageSave, "Failed to save the images", err)
+			}
+			out.Step(style.Success, "Saved the images of {{.node}} to {{.output}}", out.V{"node": config.MachineName(*co.Config, *n), "output": snapshotOutput})
+			return
+		}
+
+		nodes := co.Config.Nodes
+		if nodeName != "" {
+			n, _, err := node.Retrieve(*co.Config, nodeName)
+			if err != nil {
+				exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": nodeName})
+			}
+			nodes = []config.Node{*n}
+		}
+		for _, n := range nodes {
+			if err := machine.RestoreImages(ctx, co.API, *co.Config, n, snapshotRestore); err != nil {
+				exit.Error(reason.GuestImageLoad, "Failed to restore the images", err)
+			}
+			out.Step(style.Success, "Restored the images of {{.input}} into {{.node}}", out.V{"input": snapshotRestore, "node": config.MachineName(*co.Config, n)})
+		}
+	},
+}
+
+func init() {
+	nodeSnapshotImagesCmd.Flags().StringVarP(&snapshotOutput, "output", "o", "", "Save all the images of the node into this archive, which is only replaced once complete.")
+	nodeSnapshotImagesCmd.Flags().StringVar(&snapshotRestore, "restore", "", "Load all the images of this archive, written by --output, into the nodes.")
+	nodeSnapshotImagesCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to save the images of, or to restore them into. Defaults to the primary control plane when saving, and to all nodes when restoring.")
+	nodeCmd.AddCommand(nodeSnapshotImagesCmd)
+}
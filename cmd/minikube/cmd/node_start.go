@@ -21,6 +21,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"k8s.io/minikube/pkg/addons"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/machine"
@@ -62,24 +63,26 @@ var nodeStartCmd = &cobra.Command{
 		}
 
 		s := node.Starter{
-			Runner:         r,
-			PreExists:      p,
-			MachineAPI:     m,
-			Host:           h,
-			Cfg:            cc,
-			Node:           n,
-			ExistingAddons: cc.Addons,
+			Runner:                  r,
+			PreExists:               p,
+			MachineAPI:              m,
+			Host:                    h,
+			Cfg:                     cc,
+			Node:                    n,
+			ExistingAddons:          cc.Addons,
+			PreviousImageRepository: cc.KubernetesConfig.ImageRepository,
 		}
 
 		_, err = node.Start(s, n.ControlPlane)
 		if err != nil {
-			_, err := maybeDeleteAndRetry(cmd, *cc, *n, nil, err)
+			_, err := maybeDeleteAndRetry(cmd, *cc, *n, nil, cc.KubernetesConfig.ImageRepository, err)
 			if err != nil {
 				node.ExitIfFatal(err)
 				exit.Error(reason.GuestNodeStart, "failed to start node", err)
 			}
 		}
 		out.Step(style.Happy, "Successfully started node {{.name}}!", out.V{"name": machineName})
+		addons.WaitPrefetch()
 	},
 }
 
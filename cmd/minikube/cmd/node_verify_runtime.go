@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/docker/machine/libmachine"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/node"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+var fixRuntime bool
+
+var nodeVerifyRuntimeCmd = &cobra.Command{
+	Use:   "verify-runtime",
+	Short: "Compare the configuration of the container runtime of nodes with the profile.",
+	Long:  "Compare the configuration minikube sets for the container runtime of each node (the settings it owns in daemon.json, the cri-docker drop-in, the enabled and masked units and the cgroup driver) with the one in the node, and print how they differ. With --fix, the configuration is set again and the runtime restarted if it changed.",
+	Example: `minikube node verify-runtime
+minikube node verify-runtime --node m02 --fix`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube node verify-runtime [--node name] [--fix]")
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		nodes := co.Config.Nodes
+		if nodeName != "" {
+			n, _, err := node.Retrieve(*co.Config, nodeName)
+			if err != nil {
+				exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": nodeName})
+			}
+			nodes = []config.Node{*n}
+		}
+
+		for _, n := range nodes {
+			machineName := config.MachineName(*co.Config, n)
+			if err := verifyNodeRuntime(co.API, config.ForNode(*co.Config, n), machineName); err != nil {
+				exit.Error(reason.RuntimeVerify, "Failed to verify the configuration of the container runtime", err)
+			}
+		}
+	},
+}
+
+// verifyNodeRuntime prints how the configuration of the runtime of a node differs from the one of the profile, and fixes it if asked to
+func verifyNodeRuntime(api libmachine.API, cc config.ClusterConfig, machineName string) error {
+	host, err := machine.LoadHost(api, machineName)
+	if err != nil {
+		return errors.Wrap(err, "load host")
+	}
+	runner, err := machine.CommandRunner(host)
+	if err != nil {
+		return errors.Wrap(err, "command runner")
+	}
+	drifts, err := node.VerifyRuntime(cc, runner)
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		out.Step(style.Success, "The configuration of the container runtime of {{.node}} is as expected", out.V{"node": machineName})
+		return nil
+	}
+	printRuntimeDrifts(machineName, drifts)
+	if !fixRuntime {
+		return nil
+	}
+
+	out.Step(style.Workaround, "Setting the configuration of the container runtime of {{.node}} again ...", out.V{"node": machineName})
+	if err := node.FixRuntime(cc, runner); err != nil {
+		return errors.Wrap(err, "fix")
+	}
+	drifts, err = node.VerifyRuntime(cc, runner)
+	if err != nil {
+		return err
+	}
+	if len(drifts) != 0 {
+		printRuntimeDrifts(machineName, drifts)
+		return errors.Errorf("%d settings still differ after fixing them", len(drifts))
+	}
+	out.Step(style.Success, "The configuration of the container runtime of {{.node}} is as expected", out.V{"node": machineName})
+	return nil
+}
+
+// printRuntimeDrifts prints each setting which differs, with its expected and actual values
+func printRuntimeDrifts(machineName string, drifts []cruntime.Drift) {
+	out.WarningT("The configuration of the container runtime of {{.node}} differs in {{.count}} settings:", out.V{"node": machineName, "count": len(drifts)})
+	for _, d := range drifts {
+		out.Ln("%s", d.Setting)
+		out.Ln("- %s", driftValue(d.Expected))
+		out.Ln("+ %s", driftValue(d.Actual))
+	}
+}
+
+// driftValue returns how a value of a setting is printed, the empty one being unset
+func driftValue(v string) string {
+	if v == "" {
+		return "<unset>"
+	}
+	return v
+}
+
+func init() {
+	nodeVerifyRuntimeCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to verify the container runtime of. Defaults to all nodes.")
+	nodeVerifyRuntimeCmd.Flags().BoolVar(&fixRuntime, "fix", false, "Set the configuration of the container runtime again where it differs, restarting the runtime if needed.")
+	nodeCmd.AddCommand(nodeVerifyRuntimeCmd)
+}
@@ -38,8 +38,9 @@ import (
 )
 
 var (
-	namespaces    []string
-	allNamespaces bool
+	namespaces        []string
+	allNamespaces     bool
+	excludeNamespaces []string
 )
 
 // pauseCmd represents the docker-pause command
@@ -56,6 +57,10 @@ func runPause(cmd *cobra.Command, args []string) {
 	register.Reg.SetStep(register.Pausing)
 
 	klog.InfoS("namespaces", namespaces, "keys", viper.AllSettings())
+	// the namespaces excluded are among all the others, unless namespaces are selected
+	if len(excludeNamespaces) > 0 && !cmd.Flags().Changed("namespaces") {
+		allNamespaces = true
+	}
 	if allNamespaces {
 		namespaces = nil // all
 	} else if len(namespaces) == 0 {
@@ -83,22 +88,31 @@ func runPause(cmd *cobra.Command, args []string) {
 			exit.Error(reason.InternalCommandRunner, "Failed to get command runner", err)
 		}
 
-		cr, err := cruntime.New(cruntime.Config{Type: co.Config.KubernetesConfig.ContainerRuntime, Runner: r})
+		cr, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(*co.Config, n), Driver: co.Config.Driver, Runner: r})
 		if err != nil {
 			exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
 		}
 
-		uids, err := cluster.Pause(cr, r, namespaces)
+		uids, err := cluster.Pause(cr, r, namespaces, excludeNamespaces)
 		if err != nil {
 			exit.Error(reason.GuestPause, "Pause", err)
 		}
 		ids = append(ids, uids...)
 	}
 
+	// a bare unpause unpauses the namespaces of the last pause
+	co.Config.Paused = &config.PausedNamespaces{Namespaces: namespaces, ExcludeNamespaces: excludeNamespaces}
+	if err := config.SaveProfile(co.Config.Name, co.Config); err != nil {
+		klog.Warningf("unable to save the paused namespaces: %v", err)
+	}
+
 	register.Reg.SetStep(register.Done)
-	if namespaces == nil {
+	switch {
+	case len(excludeNamespaces) > 0:
+		out.Step(style.Unpause, "Paused {{.count}} containers, except in: {{.namespaces}}", out.V{"count": len(ids), "namespaces": strings.Join(excludeNamespaces, ", ")})
+	case namespaces == nil:
 		out.Step(style.Unpause, "Paused {{.count}} containers", out.V{"count": len(ids)})
-	} else {
+	default:
 		out.Step(style.Unpause, "Paused {{.count}} containers in: {{.namespaces}}", out.V{"count": len(ids), "namespaces": strings.Join(namespaces, ", ")})
 	}
 }
@@ -106,5 +120,6 @@ func runPause(cmd *cobra.Command, args []string) {
 func init() {
 	pauseCmd.Flags().StringSliceVarP(&namespaces, "namespaces", "n", constants.DefaultNamespaces, "namespaces to pause")
 	pauseCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "If set, pause all namespaces")
+	pauseCmd.Flags().StringSliceVar(&excludeNamespaces, "exclude-namespaces", nil, "namespaces not to pause, such as those of monitoring agents, even if selected by --namespaces or --all-namespaces. Implies --all-namespaces unless --namespaces is set")
 	pauseCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Format to print stdout in. Options include: [text,json]")
 }
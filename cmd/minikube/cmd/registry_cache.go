@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/registrycache"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+var registryCacheOutput string
+
+// registryCacheCmd represents the registry-cache command
+var registryCacheCmd = &cobra.Command{
+	Use:   "registry-cache",
+	Short: "Manage the registry cache of the host",
+	Long:  "Manage the pull-through cache of Docker Hub enabled by 'minikube start --registry-cache', which is shared by all profiles and kept by 'minikube delete'.",
+}
+
+var registryCacheStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the registry cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := registrycache.Status(registryCacheOCIBinary())
+		if err != nil {
+			exit.Error(reason.HostRegistryCache, "Failed to get the state of the registry cache", err)
+		}
+		if registryCacheOutput == "json" {
+			b, err := json.Marshal(s)
+			if err != nil {
+				exit.Error(reason.InternalJSONMarshal, "Failed to marshal the registry cache state", err)
+			}
+			fmt.Println(string(b))
+			return
+		}
+		switch {
+		case !s.Exists:
+			out.Step(style.Empty, "The registry cache does not exist, start a cluster with --registry-cache to create it")
+		case !s.Running:
+			out.Step(style.Stopped, "The registry cache is stopped, it is started again by 'minikube start'")
+		default:
+			out.Step(style.Running, "The registry cache is running at {{.mirror}}, caching {{.size}}", out.V{"mirror": s.Mirror, "size": s.Size})
+		}
+	},
+}
+
+var registryCachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove the registry cache and the images it holds",
+	Long:  "Remove the registry cache and the images it holds. Clusters started with --registry-cache create it again on their next start.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := registrycache.Prune(registryCacheOCIBinary()); err != nil {
+			exit.Error(reason.HostRegistryCache, "Failed to remove the registry cache", err)
+		}
+		out.Step(style.Deleted, "Removed the registry cache")
+	},
+}
+
+// registryCacheOCIBinary returns the container engine running the registry cache, preferring the one of the current profile
+func registryCacheOCIBinary() string {
+	drvName := ""
+	if cc, err := config.Load(viper.GetString(config.ProfileName)); err == nil {
+		drvName = cc.Driver
+	}
+	ociBin, err := registrycache.OCIBinary(drvName)
+	if err != nil {
+		exit.Message(reason.HostRegistryCache, "{{.error}}", out.V{"error": err})
+	}
+	return ociBin
+}
+
+func init() {
+	registryCacheStatusCmd.Flags().StringVarP(&registryCacheOutput, "output", "o", "text", "Format to print stdout in. Options include: [text,json]")
+	registryCacheCmd.AddCommand(registryCacheStatusCmd)
+	registryCacheCmd.AddCommand(registryCachePruneCmd)
+}
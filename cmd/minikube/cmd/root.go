@@ -244,6 +244,7 @@ func init() {
 				podmanEnvCmd,
 				cacheCmd,
 				imageCmd,
+				registryCacheCmd,
 			},
 		},
 		{
@@ -270,6 +271,7 @@ func init() {
 				kubectlCmd,
 				nodeCmd,
 				cpCmd,
+				benchmarkCmd,
 			},
 		},
 		{
@@ -18,10 +18,15 @@ package cmd
 
 import (
 	"os"
+	"os/exec"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
 
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/machine"
@@ -29,15 +34,26 @@ import (
 	"k8s.io/minikube/pkg/minikube/node"
 	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/util"
 )
 
-var nativeSSHClient bool
+var (
+	nativeSSHClient bool
+	repairImages    bool
+	runtimeCLI      bool
+)
 
 // sshCmd represents the docker-ssh command
 var sshCmd = &cobra.Command{
 	Use:   "ssh",
 	Short: "Log into the minikube environment (for debugging)",
 	Long:  "Log into or run a command on a machine with SSH; similar to 'docker-machine ssh'.",
+	Example: `
+$ minikube ssh -- uptime
+
+$ minikube ssh --runtime -- ps -a
+`,
 	Run: func(cmd *cobra.Command, args []string) {
 		cname := ClusterFlagValue()
 		co := mustload.Running(cname)
@@ -56,17 +72,90 @@ var sshCmd = &cobra.Command{
 			}
 		}
 
+		if repairImages {
+			repairImageStore(co, n)
+			return
+		}
+
+		if runtimeCLI {
+			args = runtimeCLIArgs(*co.Config, *n, args)
+		}
+
 		err = machine.CreateSSHShell(co.API, *co.Config, *n, args, nativeSSHClient)
 		if err != nil {
 			// This is typically due to a non-zero exit code, so no need for flourish.
 			out.ErrLn("ssh: %v", err)
-			// It'd be nice if we could pass up the correct error code here :(
-			os.Exit(1)
+			os.Exit(sshExitCode(err))
 		}
 	},
 }
 
+// runtimeCLIArgs returns the command line running the CLI of the container runtime of a node with args
+func runtimeCLIArgs(cc config.ClusterConfig, n config.Node, args []string) []string {
+	if len(args) == 0 {
+		exit.Message(reason.Usage, "Please provide the arguments of the container runtime CLI, such as: minikube ssh --runtime -- ps")
+	}
+	kv, err := util.ParseKubernetesVersion(cc.KubernetesConfig.KubernetesVersion)
+	if err != nil {
+		exit.Error(reason.Usage, "parsing Kubernetes version", err)
+	}
+	// a node running another runtime than the cluster does not use the CRI socket of the cluster
+	nodeCfg := config.ForNode(cc, n)
+	line, err := cruntime.CLICommand(nodeCfg.KubernetesConfig.ContainerRuntime, nodeCfg.KubernetesConfig.CRISocket, kv, args)
+	if err != nil {
+		exit.Error(reason.Usage, "container runtime CLI", err)
+	}
+	return []string{line}
+}
+
+// sshExitCode returns the exit code of the command run by ssh, or 1 if unknown
+func sshExitCode(err error) int {
+	var sshErr *ssh.ExitError
+	if errors.As(err, &sshErr) {
+		return sshErr.ExitStatus()
+	}
+	var execErr *exec.ExitError
+	if errors.As(err, &execErr) {
+		return execErr.ExitCode()
+	}
+	return 1
+}
+
+// repairImageStore removes dangling image references from the docker reference store of a node
+func repairImageStore(co mustload.ClusterController, n *config.Node) {
+	if co.Config.KubernetesConfig.ContainerRuntime != constants.Docker {
+		exit.Message(reason.Usage, "--repair-images is only supported with the docker container runtime")
+	}
+	host, err := machine.LoadHost(co.API, config.MachineName(*co.Config, *n))
+	if err != nil {
+		exit.Error(reason.GuestLoadHost, "Error getting host", err)
+	}
+	runner, err := machine.CommandRunner(host)
+	if err != nil {
+		exit.Error(reason.InternalCommandRunner, "Failed to get command runner", err)
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: co.Config.KubernetesConfig.ContainerRuntime, Driver: co.Config.Driver, Runner: runner})
+	if err != nil {
+		exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
+	}
+	dr, ok := cr.(*cruntime.Docker)
+	if !ok {
+		exit.Message(reason.Usage, "--repair-images is only supported with the docker container runtime")
+	}
+	result, err := dr.RepairImageStore()
+	if err != nil {
+		exit.Error(reason.RuntimeRepairImages, "Failed to repair image references", err)
+	}
+	if !result.Repaired() {
+		out.Step(style.Check, "No dangling image references found")
+		return
+	}
+	out.Step(style.Check, "Removed {{.removed}} dangling image references", out.V{"removed": len(result.Removed)})
+}
+
 func init() {
 	sshCmd.Flags().BoolVar(&nativeSSHClient, "native-ssh", true, "Use native Golang SSH client (default true). Set to 'false' to use the command line 'ssh' command when accessing the docker machine. Useful for the machine drivers when they will not start with 'Waiting for SSH'.")
 	sshCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to ssh into. Defaults to the primary control plane.")
+	sshCmd.Flags().BoolVar(&runtimeCLI, "runtime", false, "Run the arguments with the CLI of the container runtime of the node: docker, crictl or podman.")
+	sshCmd.Flags().BoolVar(&repairImages, "repair-images", false, "Remove dangling image references from the docker image store of the node instead of opening a shell.")
 }
@@ -25,20 +25,25 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"os/user"
 	"regexp"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/Delta456/box-cli-maker/v2"
 	"github.com/blang/semver/v4"
 	"github.com/docker/go-connections/nat"
+	units "github.com/docker/go-units"
 	"github.com/docker/machine/libmachine/ssh"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
 	"github.com/shirou/gopsutil/v3/cpu"
 	gopshost "github.com/shirou/gopsutil/v3/host"
@@ -49,9 +54,13 @@ import (
 
 	"k8s.io/klog/v2"
 	cmdcfg "k8s.io/minikube/cmd/minikube/cmd/config"
+	"k8s.io/minikube/pkg/addons"
 	"k8s.io/minikube/pkg/drivers/kic/oci"
 	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil"
 	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/cluster"
+	"k8s.io/minikube/pkg/minikube/cni"
+	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/minikube/cruntime"
@@ -60,6 +69,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/driver/auxdriver"
 	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/image"
 	"k8s.io/minikube/pkg/minikube/kubeconfig"
 	"k8s.io/minikube/pkg/minikube/localpath"
 	"k8s.io/minikube/pkg/minikube/machine"
@@ -74,6 +84,7 @@ import (
 	pkgtrace "k8s.io/minikube/pkg/trace"
 
 	"k8s.io/minikube/pkg/minikube/registry"
+	"k8s.io/minikube/pkg/minikube/registrycache"
 	"k8s.io/minikube/pkg/minikube/translate"
 	"k8s.io/minikube/pkg/util"
 	"k8s.io/minikube/pkg/version"
@@ -156,6 +167,7 @@ func runStart(cmd *cobra.Command, args []string) {
 	if viper.GetBool(force) {
 		out.WarningT("minikube skips various validations when --force is supplied; this may lead to unexpected behavior")
 	}
+	warnImageCacheSize()
 
 	// if --registry-mirror specified when run minikube start,
 	// take arg precedence over MINIKUBE_REGISTRY_MIRROR
@@ -266,6 +278,10 @@ func runStart(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	if starter.Cfg.RegistryCache && !viper.GetBool(dryRun) {
+		startRegistryCache(starter.Cfg.Driver)
+	}
+
 	kubeconfig, err := startWithDriver(cmd, starter, existing)
 	if err != nil {
 		node.ExitIfFatal(err)
@@ -275,6 +291,43 @@ func runStart(cmd *cobra.Command, args []string) {
 	if err := showKubectlInfo(kubeconfig, starter.Node.KubernetesVersion, starter.Node.ContainerRuntime, starter.Cfg.Name); err != nil {
 		klog.Errorf("kubectl info: %v", err)
 	}
+	if viper.GetBool(profileRuntimeTimings) && !out.JSON {
+		showRuntimeTimings()
+	}
+	// the cluster is usable meanwhile, and an interrupt stops the prefetch
+	addons.WaitPrefetch()
+}
+
+// startRegistryCache starts the registry cache of the host, or reuses the running one.
+// The nodes pull from Docker Hub directly when the cache is unavailable, so failing to start it is not fatal.
+func startRegistryCache(drvName string) {
+	ociBin, err := registrycache.OCIBinary(drvName)
+	if err == nil {
+		err = registrycache.Ensure(ociBin)
+	}
+	if err != nil {
+		out.WarningT("Unable to start the registry cache, images will be pulled from Docker Hub: {{.error}}", out.V{"error": err})
+		return
+	}
+	out.Step(style.Caching, "Pulling Docker Hub images through the registry cache at {{.mirror}}", out.V{"mirror": registrycache.HostURL()})
+}
+
+// showRuntimeTimings prints the time spent in each phase of configuring the container runtime, longest first
+func showRuntimeTimings() {
+	data := [][]string{}
+	for _, t := range pkgtrace.Timings() {
+		data = append(data, []string{t.Phase, t.Duration.Round(time.Millisecond).String()})
+	}
+	if len(data) == 0 {
+		return
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Phase", "Duration"})
+	table.SetAutoFormatHeaders(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(data)
+	table.Render()
 }
 
 func provisionWithDriver(cmd *cobra.Command, ds registry.DriverState, existing *config.ClusterConfig) (node.Starter, error) {
@@ -342,27 +395,58 @@ func provisionWithDriver(cmd *cobra.Command, ds registry.DriverState, existing *
 		ssh.SetDefaultClient(ssh.External)
 	}
 
+	previousRuntime := ""
+	previousImageRepository := cc.KubernetesConfig.ImageRepository
+	if existing != nil {
+		previousRuntime = existing.KubernetesConfig.ContainerRuntime
+		previousImageRepository = existing.KubernetesConfig.ImageRepository
+	}
+
 	mRunner, preExists, mAPI, host, err := node.Provision(&cc, &n, true, viper.GetBool(deleteOnFailure))
 	if err != nil {
 		return node.Starter{}, err
 	}
 
 	return node.Starter{
-		Runner:         mRunner,
-		PreExists:      preExists,
-		StopK8s:        stopk8s,
-		MachineAPI:     mAPI,
-		Host:           host,
-		ExistingAddons: existingAddons,
-		Cfg:            &cc,
-		Node:           &n,
+		Runner:                  mRunner,
+		PreExists:               preExists,
+		StopK8s:                 stopk8s,
+		MachineAPI:              mAPI,
+		Host:                    host,
+		ExistingAddons:          existingAddons,
+		Cfg:                     &cc,
+		Node:                    &n,
+		PreviousRuntime:         previousRuntime,
+		PreviousImageRepository: previousImageRepository,
+		Context:                 interruptContext(),
 	}, nil
 }
 
+// interruptGrace is how long the phases of the start watching its context get to stop their commands in the node on an interrupt,
+// before minikube exits, as the other phases do not watch it
+const interruptGrace = 5 * time.Second
+
+// interruptContext returns a context which is done on the first interrupt, stopping the commands running in the node,
+// after which minikube exits. The interrupt handler is then removed, so that another interrupt stops minikube at once.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-c
+		signal.Stop(c)
+		out.WarningT("Received {{.name}} signal, stopping the commands running in the node ...", out.V{"name": sig})
+		cancel()
+		time.Sleep(interruptGrace)
+		exit.Message(reason.Interrupted, "Received {{.name}} signal", out.V{"name": sig})
+	}()
+	return ctx
+}
+
 func startWithDriver(cmd *cobra.Command, starter node.Starter, existing *config.ClusterConfig) (*kubeconfig.Settings, error) {
 	kubeconfig, err := node.Start(starter, true)
 	if err != nil {
-		kubeconfig, err = maybeDeleteAndRetry(cmd, *starter.Cfg, *starter.Node, starter.ExistingAddons, err)
+		kubeconfig, err = maybeDeleteAndRetry(cmd, *starter.Cfg, *starter.Node, starter.ExistingAddons, starter.PreviousImageRepository, err)
 		if err != nil {
 			return nil, err
 		}
@@ -391,7 +475,7 @@ func startWithDriver(cmd *cobra.Command, starter node.Starter, existing *config.
 						ContainerRuntime:  starter.Cfg.KubernetesConfig.ContainerRuntime,
 					}
 					out.Ln("") // extra newline for clarity on the command line
-					err := node.Add(starter.Cfg, n, viper.GetBool(deleteOnFailure))
+					err := node.Add(starter.Cfg, n, viper.GetBool(deleteOnFailure), starter.PreviousImageRepository)
 					if err != nil {
 						return nil, errors.Wrap(err, "adding node")
 					}
@@ -399,7 +483,7 @@ func startWithDriver(cmd *cobra.Command, starter node.Starter, existing *config.
 			} else {
 				for _, n := range existing.Nodes {
 					if !n.ControlPlane {
-						err := node.Add(starter.Cfg, n, viper.GetBool(deleteOnFailure))
+						err := node.Add(starter.Cfg, n, viper.GetBool(deleteOnFailure), starter.PreviousImageRepository)
 						if err != nil {
 							return nil, errors.Wrap(err, "adding node")
 						}
@@ -410,10 +494,67 @@ func startWithDriver(cmd *cobra.Command, starter node.Starter, existing *config.
 	}
 
 	pause.RemovePausedFile(starter.Runner)
+	if existing != nil {
+		restorePause(*starter.Cfg)
+	}
 
 	return kubeconfig, nil
 }
 
+// restorePause pauses again the pods of the nodes which were paused before the cluster restarted
+func restorePause(cc config.ClusterConfig) {
+	api, err := machine.NewAPIClient()
+	if err != nil {
+		klog.Warningf("unable to restore the paused pods: %v", err)
+		return
+	}
+	defer api.Close()
+
+	for _, n := range cc.Nodes {
+		m := config.MachineName(cc, n)
+		h, err := machine.LoadHost(api, m)
+		if err != nil {
+			klog.Warningf("unable to load %s to restore its paused pods: %v", m, err)
+			continue
+		}
+		r, err := machine.CommandRunner(h)
+		if err != nil {
+			klog.Warningf("unable to get the command runner of %s to restore its paused pods: %v", m, err)
+			continue
+		}
+		st, err := pause.LoadState(r)
+		if err != nil {
+			klog.Warningf("unable to load the paused pods of %s: %v", m, err)
+			continue
+		}
+		if st == nil {
+			continue
+		}
+		cr, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(cc, n), Driver: cc.Driver, Runner: r})
+		if err != nil {
+			klog.Warningf("unable to get the runtime of %s to restore its paused pods: %v", m, err)
+			continue
+		}
+		ids, missing, err := cluster.RestorePause(cr, r, st)
+		if len(missing) > 0 {
+			names := []string{}
+			for _, p := range missing {
+				names = append(names, p.Namespace+"/"+p.Name)
+			}
+			out.WarningT("{{.count}} pods paused before the restart no longer exist: {{.pods}}", out.V{"count": len(missing), "pods": strings.Join(names, ", ")})
+		}
+		if err != nil {
+			out.WarningT("Unable to pause again the pods paused before the restart: {{.error}}", out.V{"error": err})
+			out.Styled(style.Tip, "To pause them, run: 'minikube pause -p {{.profile}} -n {{.namespaces}}'", out.V{"profile": cc.Name, "namespaces": strings.Join(st.Namespaces(), ",")})
+			continue
+		}
+		if len(ids) > 0 {
+			out.Step(style.Pause, "Paused {{.count}} containers of {{.name}} again, as they were before the restart", out.V{"count": len(ids), "name": m})
+			out.Styled(style.Tip, "To resume them, run: 'minikube unpause -p {{.profile}}'", out.V{"profile": cc.Name})
+		}
+	}
+}
+
 func warnAboutMultiNodeCNI() {
 	out.WarningT("Cluster was created without any CNI, adding a node to it might cause broken networking.")
 }
@@ -437,6 +578,27 @@ func displayVersion(version string) {
 	out.Step(style.Happy, "{{.prefix}}minikube {{.version}} on {{.platform}}", out.V{"prefix": prefix, "version": version, "platform": platform()})
 }
 
+// warnImageCacheSize warns if the image cache of the host holds more than the size set with 'minikube config set image-cache-warn-size'
+func warnImageCacheSize() {
+	limit := viper.GetString(config.ImageCacheWarnSize)
+	if limit == "" {
+		return
+	}
+	maxSize, err := units.RAMInBytes(limit)
+	if err != nil {
+		klog.Warningf("invalid %s %q: %v", config.ImageCacheWarnSize, limit, err)
+		return
+	}
+	size, err := image.CacheSize(detect.ImageCacheDir())
+	if err != nil {
+		klog.Warningf("unable to get the size of the image cache: %v", err)
+		return
+	}
+	if size > maxSize {
+		out.WarningT("The image cache of minikube holds {{.size}}, more than {{.limit}}. To free disk space, run: minikube cache prune --max-size={{.limit}}", out.V{"size": units.BytesSize(float64(size)), "limit": limit})
+	}
+}
+
 // displayEnviron makes the user aware of environment variables that will affect how minikube operates
 func displayEnviron(env []string) {
 	for _, kv := range env {
@@ -514,7 +676,7 @@ func showKubectlInfo(kcs *kubeconfig.Settings, k8sVersion, rtime, machineName st
 	return nil
 }
 
-func maybeDeleteAndRetry(cmd *cobra.Command, existing config.ClusterConfig, n config.Node, existingAddons map[string]bool, originalErr error) (*kubeconfig.Settings, error) {
+func maybeDeleteAndRetry(cmd *cobra.Command, existing config.ClusterConfig, n config.Node, existingAddons map[string]bool, previousImageRepository string, originalErr error) (*kubeconfig.Settings, error) {
 	if viper.GetBool(deleteOnFailure) {
 		out.WarningT("Node {{.name}} failed to start, deleting and trying again.", out.V{"name": n.Name})
 		// Start failed, delete the cluster and try again
@@ -534,13 +696,14 @@ func maybeDeleteAndRetry(cmd *cobra.Command, existing config.ClusterConfig, n co
 		for _, n := range cc.Nodes {
 			r, p, m, h, err := node.Provision(&cc, &n, n.ControlPlane, false)
 			s := node.Starter{
-				Runner:         r,
-				PreExists:      p,
-				MachineAPI:     m,
-				Host:           h,
-				Cfg:            &cc,
-				Node:           &n,
-				ExistingAddons: existingAddons,
+				Runner:                  r,
+				PreExists:               p,
+				MachineAPI:              m,
+				Host:                    h,
+				Cfg:                     &cc,
+				Node:                    &n,
+				ExistingAddons:          existingAddons,
+				PreviousImageRepository: previousImageRepository,
 			}
 			if err != nil {
 				// Ok we failed again, let's bail
@@ -1143,6 +1306,92 @@ func validateCPUCount(drvName string) {
 	}
 }
 
+// validateDockerFeatures exits on malformed docker features, and warns about the ones minikube does not know, or if rtime ignores them
+func validateDockerFeatures(features []string, rtime string) {
+	unknown, err := cruntime.ValidateDockerFeatures(features)
+	if err != nil {
+		exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
+	}
+	if len(unknown) > 0 {
+		out.WarningT("Unknown docker features are passed to the daemon as they are: {{.features}}", out.V{"features": strings.Join(unknown, ", ")})
+	}
+	if rtime != constants.DefaultContainerRuntime && rtime != constants.Docker {
+		out.WarningT("--docker-feature is ignored by the {{.runtime}} container runtime", out.V{"runtime": rtime})
+	}
+}
+
+// validateDockerBridge exits if the docker bridge overlaps the networks of the cluster, or its MTU is invalid, and warns if rtime ignores it
+func validateDockerBridge(cmd *cobra.Command, drvName, rtime string) {
+	if cmd.Flags().Changed(dockerMTU) {
+		if err := cruntime.ValidateDockerMTU(viper.GetInt(dockerMTU)); err != nil {
+			exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
+		}
+	}
+	if cidr := viper.GetString(dockerBridgeCIDR); cidr != "" {
+		others := map[string]string{
+			"service CIDR": viper.GetString(serviceCIDR),
+			"pod CIDR":     cni.DefaultPodCIDR,
+		}
+		if driver.IsKIC(drvName) {
+			// the first network kic tries, unless a subnet is given
+			others["driver network"] = "192.168.49.0/24"
+			if s := viper.GetString(subnet); s != "" {
+				if !strings.Contains(s, "/") {
+					s += "/24"
+				}
+				others["driver network"] = s
+			}
+		}
+		if drvName == driver.VirtualBox {
+			others["driver network"] = viper.GetString(hostOnlyCIDR)
+		}
+		if err := cruntime.ValidateDockerBridge(cidr, others); err != nil {
+			exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
+		}
+	}
+	if rtime != constants.DefaultContainerRuntime && rtime != constants.Docker {
+		out.WarningT("--docker-bridge-cidr and --docker-mtu are ignored by the {{.runtime}} container runtime", out.V{"runtime": rtime})
+	}
+}
+
+// validateGPUs returns an error if the GPUs can not be exposed to the node by the driver, or be used by the container runtime
+func validateGPUs(value, drvName, rtime string) error {
+	if value == "" {
+		return nil
+	}
+	if value != "all" && value != "nvidia" {
+		return errors.Errorf("invalid --gpus value %q, the valid values are all or nvidia", value)
+	}
+	if drvName != driver.Docker && !driver.BareMetal(drvName) && !driver.IsSSH(drvName) {
+		return errors.Errorf("the %s driver can not expose GPUs to the node, --gpus requires the docker driver", drvName)
+	}
+	if rtime != constants.DefaultContainerRuntime && rtime != constants.Docker {
+		return errors.Errorf("the %s container runtime does not support --gpus, use --container-runtime=docker", rtime)
+	}
+	return nil
+}
+
+// validateIPFamilies returns an error if the service or pod networks are invalid, or have an IPv6 network the driver can not carry.
+// Only the none and ssh drivers run Kubernetes on the network of a host, which may have IPv6: the networks the other drivers
+// create for the nodes are IPv4 only. With the docker runtime, the IPv6 network of docker0 must not overlap the IPv6 service network.
+func validateIPFamilies(serviceCIDR, podCIDR, drvName, rtime string) error {
+	_, serviceV6, err := cni.SplitCIDRs(serviceCIDR)
+	if err != nil {
+		return err
+	}
+	_, podV6, err := cni.SplitCIDRs(podCIDR)
+	if err != nil {
+		return err
+	}
+	if (serviceV6 != "" || podV6 != "") && !driver.BareMetal(drvName) && !driver.IsSSH(drvName) {
+		return errors.Errorf("the network of the %s driver can not carry IPv6, IPv6 and dual-stack clusters require the none or ssh driver", drvName)
+	}
+	if podV6 != "" && (rtime == constants.DefaultContainerRuntime || rtime == constants.Docker) {
+		return cruntime.ValidateDockerFixedCIDRv6(podV6, map[string]string{"service CIDR": serviceV6})
+	}
+	return nil
+}
+
 // validateFlags validates the supplied flags against known bad combinations
 func validateFlags(cmd *cobra.Command, drvName string) {
 	if cmd.Flags().Changed(humanReadableDiskSize) {
@@ -1168,10 +1417,26 @@ func validateFlags(cmd *cobra.Command, drvName string) {
 		validateChangedMemoryFlags(drvName)
 	}
 
+	if cmd.Flags().Changed(preloadSource) {
+		validatePreloadSource(viper.GetString(preloadSource))
+	}
+
+	if viper.GetBool(registryCache) && !registrycache.Supported(drvName) {
+		exit.Message(reason.Usage, "The registry cache listens on the loopback of the host, which the nodes of the {{.name}} driver can not reach: use the docker, podman or none driver with --registry-cache", out.V{"name": drvName})
+	}
+
+	if err := download.ValidatePreloadChecksum(viper.GetString(preloadChecksum)); err != nil {
+		exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
+	}
+
 	if cmd.Flags().Changed(listenAddress) {
 		validateListenAddress(viper.GetString(listenAddress))
 	}
 
+	if cmd.Flags().Changed(sshSSHSudo) {
+		validateSSHSudo(drvName, viper.GetString(sshSSHSudo))
+	}
+
 	if cmd.Flags().Changed(imageRepository) {
 		viper.Set(imageRepository, validateImageRepository(viper.GetString(imageRepository)))
 	}
@@ -1183,12 +1448,36 @@ func validateFlags(cmd *cobra.Command, drvName string) {
 
 	}
 
+	rtime := viper.GetString(containerRuntime)
 	if cmd.Flags().Changed(containerRuntime) {
-		err := validateRuntime(viper.GetString(containerRuntime))
+		var err error
+		rtime, err = validateRuntime(rtime)
 		if err != nil {
 			exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
 		}
-		validateCNI(cmd, viper.GetString(containerRuntime))
+		validateCNI(cmd, rtime)
+	}
+
+	if cmd.Flags().Changed(dockerFeature) {
+		validateDockerFeatures(viper.GetStringSlice(dockerFeature), rtime)
+	}
+
+	if viper.GetBool(isolatedBuilder) {
+		if rtime != constants.DefaultContainerRuntime && rtime != constants.Docker {
+			exit.Message(reason.Usage, "The {{.runtime}} container runtime does not support --isolated-builder, use --container-runtime=docker", out.V{"runtime": rtime})
+		}
+	}
+
+	if cmd.Flags().Changed(dockerBridgeCIDR) || cmd.Flags().Changed(dockerMTU) {
+		validateDockerBridge(cmd, drvName, rtime)
+	}
+
+	if err := validateGPUs(viper.GetString(gpus), drvName, rtime); err != nil {
+		exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
+	}
+
+	if err := validateIPFamilies(viper.GetString(serviceCIDR), config.ExtraOptions.Get("pod-network-cidr", bsutil.Kubeadm), drvName, rtime); err != nil {
+		exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
 	}
 
 	if driver.BareMetal(drvName) {
@@ -1197,7 +1486,6 @@ func validateFlags(cmd *cobra.Command, drvName string) {
 		}
 
 		// default container runtime varies, starting with Kubernetes 1.24 - assume that only the default container runtime has been tested
-		rtime := viper.GetString(containerRuntime)
 		if rtime != constants.DefaultContainerRuntime && rtime != defaultRuntime(getKubernetesVersion(nil)) {
 			out.WarningT("Using the '{{.runtime}}' runtime with the 'none' driver is an untested configuration!", out.V{"runtime": rtime})
 		}
@@ -1313,14 +1601,15 @@ func validateDiskSize(diskSize string) error {
 	return nil
 }
 
-// validateRuntime validates the supplied runtime
-func validateRuntime(rtime string) error {
+// validateRuntime validates the supplied runtime, and returns the runtime to configure for it:
+// `cri-o` is spelled `crio`, and `auto` is the default runtime, with consent to fall back to containerd
+func validateRuntime(rtime string) (string, error) {
 	validOptions := cruntime.ValidRuntimes()
 	// `crio` is accepted as an alternative spelling to `cri-o`
 	validOptions = append(validOptions, constants.CRIO)
 
-	if rtime == constants.DefaultContainerRuntime {
-		return nil
+	if rtime == constants.DefaultContainerRuntime || rtime == constants.AutoContainerRuntime {
+		return constants.DefaultContainerRuntime, nil
 	}
 
 	var validRuntime bool
@@ -1328,26 +1617,35 @@ func validateRuntime(rtime string) error {
 		if rtime == option {
 			validRuntime = true
 		}
-
-		// Convert `cri-o` to `crio` as the K8s config uses the `crio` spelling
-		if rtime == "cri-o" {
-			viper.Set(containerRuntime, constants.CRIO)
-		}
-
 	}
 
 	if (rtime == "crio" || rtime == "cri-o") && (strings.HasPrefix(runtime.GOARCH, "ppc64") || detect.RuntimeArch() == "arm" || strings.HasPrefix(detect.RuntimeArch(), "arm/")) {
-		return errors.Errorf("The %s runtime is not compatible with the %s architecture. See https://github.com/cri-o/cri-o/issues/2467 for more details", rtime, runtime.GOARCH)
+		return "", errors.Errorf("The %s runtime is not compatible with the %s architecture. See https://github.com/cri-o/cri-o/issues/2467 for more details", rtime, runtime.GOARCH)
 	}
 
 	if !validRuntime {
-		return errors.Errorf("Invalid Container Runtime: %s. Valid runtimes are: %s", rtime, cruntime.ValidRuntimes())
+		return "", errors.Errorf("Invalid Container Runtime: %s. Valid runtimes are: %s", rtime, cruntime.ValidRuntimes())
 	}
-	return nil
+	// Convert `cri-o` to `crio` as the K8s config uses the `crio` spelling
+	if rtime == "cri-o" {
+		return constants.CRIO, nil
+	}
+	return rtime, nil
+}
+
+// runtimeFallback returns whether --container-runtime=auto consents to fall back to containerd
+func runtimeFallback() bool {
+	return viper.GetString(containerRuntime) == constants.AutoContainerRuntime
 }
 
 func getContainerRuntime(old *config.ClusterConfig) string {
 	paramRuntime := viper.GetString(containerRuntime)
+	switch paramRuntime {
+	case constants.AutoContainerRuntime:
+		paramRuntime = constants.DefaultContainerRuntime
+	case "cri-o":
+		paramRuntime = constants.CRIO
+	}
 
 	// try to load the old version first if the user didn't specify anything
 	if paramRuntime == constants.DefaultContainerRuntime && old != nil {
@@ -1483,6 +1781,35 @@ func validateListenAddress(listenAddr string) {
 	}
 }
 
+// validateSSHSudo validates the --ssh-sudo flag, and asks for the sudo password before the host is touched
+func validateSSHSudo(drvName string, mode string) {
+	e, err := command.ParseElevation(mode)
+	if err != nil {
+		exit.Message(reason.Usage, "Sorry, the --ssh-sudo flag is invalid: {{.err}}", out.V{"err": err})
+	}
+	if drvName != driver.SSH {
+		out.WarningT("The '{{.name}}' driver does not respect the --ssh-sudo flag", out.V{"name": drvName})
+		return
+	}
+	if e != command.ElevationSudoPassword {
+		return
+	}
+	if _, err := command.SudoPassword(); err != nil {
+		exit.Message(reason.Usage, "Unable to get the sudo password of the host: {{.err}}", out.V{"err": err})
+	}
+}
+
+// validatePreloadSource validates that the --preload-source is an http, https or file URL
+func validatePreloadSource(source string) {
+	if source == "" {
+		return
+	}
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "file") {
+		exit.Message(reason.Usage, "Sorry, the URL provided with the --preload-source flag is invalid: {{.url}}. It must start with http://, https:// or file://", out.V{"url": source})
+	}
+}
+
 // This function validates that the --insecure-registry follows one of the following formats:
 // "<ip>[:<port>]" "<hostname>[:<port>]" "<network>/<netmask>"
 func validateInsecureRegistry() {
@@ -1535,7 +1862,10 @@ func createNode(cc config.ClusterConfig, kubeNodeName string, existing *config.C
 		nodes := []config.Node{}
 		for _, n := range existing.Nodes {
 			n.KubernetesVersion = getKubernetesVersion(&cc)
-			n.ContainerRuntime = getContainerRuntime(&cc)
+			// nodes added with their own container runtime keep it, the others follow the cluster
+			if n.ContainerRuntime == "" || n.ContainerRuntime == existing.KubernetesConfig.ContainerRuntime {
+				n.ContainerRuntime = getContainerRuntime(&cc)
+			}
 			nodes = append(nodes, n)
 		}
 		cc.Nodes = nodes
@@ -1660,7 +1990,7 @@ func validateContainerRuntime(old *config.ClusterConfig) {
 		return
 	}
 
-	if err := validateRuntime(old.KubernetesConfig.ContainerRuntime); err != nil {
+	if _, err := validateRuntime(old.KubernetesConfig.ContainerRuntime); err != nil {
 		klog.Errorf("Error parsing old runtime %q: %v", old.KubernetesConfig.ContainerRuntime, err)
 	}
 }
@@ -33,6 +33,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil"
 	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil/kverify"
 	"k8s.io/minikube/pkg/minikube/cni"
+	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/minikube/cruntime"
@@ -40,6 +41,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/download"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/node"
 	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/proxy"
 	"k8s.io/minikube/pkg/minikube/reason"
@@ -117,18 +119,32 @@ const (
 	natNicType              = "nat-nic-type"
 	nodes                   = "nodes"
 	preload                 = "preload"
+	dockerFeature           = "docker-feature"
+	dockerBridgeCIDR        = "docker-bridge-cidr"
+	dockerMTU               = "docker-mtu"
+	gpus                    = "gpus"
+	registryCache           = "registry-cache"
+	assumeOffline           = "assume-offline"
+	preloadAddonImages      = "preload-addon-images"
+	preloadSource           = "preload-source"
+	preloadChecksum         = "preload-checksum"
+	preloadSourceFallback   = "preload-source-fallback"
 	deleteOnFailure         = "delete-on-failure"
 	forceSystemd            = "force-systemd"
+	dockerOnDemand          = "docker-on-demand"
+	isolatedBuilder         = "isolated-builder"
 	kicBaseImage            = "base-image"
 	ports                   = "ports"
 	network                 = "network"
 	subnet                  = "subnet"
 	startNamespace          = "namespace"
 	trace                   = "trace"
+	profileRuntimeTimings   = "profile-runtime-timings"
 	sshIPAddress            = "ssh-ip-address"
 	sshSSHUser              = "ssh-user"
 	sshSSHKey               = "ssh-key"
 	sshSSHPort              = "ssh-port"
+	sshSSHSudo              = "ssh-sudo"
 	defaultSSHUser          = "root"
 	defaultSSHPort          = 22
 	listenAddress           = "listen-address"
@@ -166,7 +182,7 @@ func initMinikubeFlags() {
 	startCmd.Flags().String(kicBaseImage, kic.BaseImage, "The base image to use for docker/podman drivers. Intended for local development.")
 	startCmd.Flags().Bool(keepContext, false, "This will keep the existing kubectl context and will create a minikube context.")
 	startCmd.Flags().Bool(embedCerts, false, "if true, will embed the certs in kubeconfig.")
-	startCmd.Flags().String(containerRuntime, constants.DefaultContainerRuntime, fmt.Sprintf("The container runtime to be used. Valid options: %s (default: auto)", strings.Join(cruntime.ValidRuntimes(), ", ")))
+	startCmd.Flags().String(containerRuntime, constants.DefaultContainerRuntime, fmt.Sprintf("The container runtime to be used. Valid options: %s (default: docker). Use 'auto' for docker, falling back to containerd when the image lacks the cri-dockerd required by Kubernetes 1.24+", strings.Join(cruntime.ValidRuntimes(), ", ")))
 	startCmd.Flags().Bool(createMount, false, "This will start the mount daemon and automatically mount files into minikube.")
 	startCmd.Flags().String(mountString, constants.DefaultMountDir+":/minikube-host", "The argument to pass the minikube mount command on start.")
 	startCmd.Flags().String(mount9PVersion, defaultMount9PVersion, mount9PVersionDescription)
@@ -178,7 +194,8 @@ func initMinikubeFlags() {
 	startCmd.Flags().String(mountTypeFlag, defaultMountType, mountTypeDescription)
 	startCmd.Flags().String(mountUID, defaultMountUID, mountUIDDescription)
 	startCmd.Flags().StringSlice(config.AddonListFlag, nil, "Enable addons. see `minikube addons list` for a list of valid addon names.")
-	startCmd.Flags().String(criSocket, "", "The cri socket path to be used.")
+	startCmd.Flags().StringSlice(preloadAddonImages, nil, "Addons whose images to pull in the background once the control plane is up, without enabling them, so that enabling them later needs no pulls. e.g. --preload-addon-images=ingress,dashboard")
+	startCmd.Flags().String(criSocket, "", "The cri socket path to be used. With the docker runtime, cri-dockerd listens on it, so its directory must exist and be writable on the nodes.")
 	startCmd.Flags().String(networkPlugin, "", "DEPRECATED: Replaced by --cni")
 	startCmd.Flags().Bool(enableDefaultCNI, false, "DEPRECATED: Replaced by --cni=bridge")
 	startCmd.Flags().String(cniFlag, "", "CNI plug-in to use. Valid options: auto, bridge, calico, cilium, flannel, kindnet, or path to a CNI manifest (default: auto)")
@@ -189,12 +206,23 @@ func initMinikubeFlags() {
 	startCmd.Flags().Bool(installAddons, true, "If set, install addons. Defaults to true.")
 	startCmd.Flags().IntP(nodes, "n", 1, "The number of nodes to spin up. Defaults to 1.")
 	startCmd.Flags().Bool(preload, true, "If set, download tarball of preloaded images if available to improve start time. Defaults to true.")
+	startCmd.Flags().String(preloadSource, "", "The base URL of a mirror of the preload tarballs, laid out as the default bucket: <url>/<preload version>/<kubernetes version>/<tarball>. The tarballs are verified with the sha256sum files next to them, named <tarball>.sha256, unless --preload-checksum is set.")
+	startCmd.Flags().String(preloadChecksum, "", "Override the verification of the preload tarball: 'skip', or 'sha256:<value>' to verify it against the given checksum.")
+	startCmd.Flags().Float64(node.PreloadSpaceFactorFlag, cruntime.DefaultPreloadSpaceFactor, "Scale the space and inodes the preload is estimated to need in /var of the node, checked before extracting it. Lower it if the estimate is too conservative, or pass a negative value to skip the check.")
+	startCmd.Flags().Bool(node.SELinuxRelabelFlag, false, "If set, label the directories bind-mounted into the static pods for containers when SELinux enforces on the host of the none driver, instead of failing. Defaults to false.")
+	startCmd.Flags().Bool(node.CRIDockerdSocketGroupFlag, false, "If set, give the docker group of the node read and write access to the socket of cri-dockerd, so that its members run crictl without sudo. Only applies to the docker container runtime. Defaults to false.")
+	startCmd.Flags().Bool(preloadSourceFallback, false, "If set, download the preload tarballs missing from --preload-source from the default bucket. Defaults to false.")
+	startCmd.Flags().Bool(download.LocalPreloadFlag, true, "If set, save the Kubernetes images as a local preload after the first start when no downloadable preload applies (e.g. with --image-repository or release candidates), and load it on the next starts. Defaults to true.")
+	startCmd.Flags().Bool(node.NoAutoRepairFlag, false, "If set, do not restore the Kubernetes images of an existing node from the cached preload when its container runtime lost them. Defaults to false.")
 	startCmd.Flags().Bool(noKubernetes, false, "If set, minikube VM/container will start without starting or configuring Kubernetes. (only works on new clusters)")
 	startCmd.Flags().Bool(deleteOnFailure, false, "If set, delete the current cluster if start fails and try again. Defaults to false.")
-	startCmd.Flags().Bool(forceSystemd, false, "If set, force the container runtime to use systemd as cgroup manager. Defaults to false.")
+	startCmd.Flags().Bool(forceSystemd, false, "If set, force the container runtime to use systemd as cgroup manager. Defaults to true on hosts using cgroup v2 and systemd, false otherwise.")
+	startCmd.Flags().Bool(dockerOnDemand, false, "If set, docker is kept socket activated when using another container runtime, so that it only starts when used, e.g. by 'minikube docker-env'. Defaults to false.")
+	startCmd.Flags().Bool(isolatedBuilder, false, "If set, 'minikube image build' and 'minikube docker-env --builder' use a docker daemon of their own, isolated from the one running Kubernetes, into which the built images are promoted. Requires the docker container runtime. Defaults to false.")
 	startCmd.Flags().StringP(network, "", "", "network to run minikube with. Now it is used by docker/podman and KVM drivers. If left empty, minikube will create a new network.")
 	startCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Format to print stdout in. Options include: [text,json]")
 	startCmd.Flags().StringP(trace, "", "", "Send trace events. Options include: [gcp]")
+	startCmd.Flags().Bool(profileRuntimeTimings, false, "If set, print the time spent in each phase of configuring the container runtime, longest first, once started.")
 	startCmd.Flags().Int(extraDisks, 0, "Number of extra disks created and attached to the minikube VM (currently only implemented for hyperkit and kvm2 drivers)")
 	startCmd.Flags().Duration(certExpiration, constants.DefaultCertExpiration, "Duration until minikube certificate expiration, defaults to three years (26280h).")
 	startCmd.Flags().String(binaryMirror, "", "Location to fetch kubectl, kubelet, & kubeadm binaries from.")
@@ -266,17 +294,24 @@ func initDriverFlags() {
 func initNetworkingFlags() {
 	startCmd.Flags().StringSliceVar(&insecureRegistry, "insecure-registry", nil, "Insecure Docker registries to pass to the Docker daemon.  The default service CIDR range will automatically be added.")
 	startCmd.Flags().StringSliceVar(&registryMirror, "registry-mirror", nil, "Registry mirrors to pass to the Docker daemon")
+	startCmd.Flags().Bool(assumeOffline, false, "If set, fail right away instead of pulling images, for air-gapped machines. The images must be preloaded or cached with 'minikube cache add' on a connected machine. Without it, minikube still assumes the node is offline when it can not connect to the image repository.")
+	startCmd.Flags().Bool(registryCache, false, "If set, pull the images of Docker Hub through a cache running on the host, which is kept by 'minikube delete' and managed with 'minikube registry-cache'.")
 	startCmd.Flags().String(imageRepository, "", "Alternative image repository to pull docker images from. This can be used when you have limited access to gcr.io. Set it to \"auto\" to let minikube decide one for you. For Chinese mainland users, you may use local gcr.io mirrors such as registry.cn-hangzhou.aliyuncs.com/google_containers")
 	startCmd.Flags().String(imageMirrorCountry, "", "Country code of the image mirror to be used. Leave empty to use the global one. For Chinese mainland users, set it to cn.")
 	startCmd.Flags().String(serviceCIDR, constants.DefaultServiceCIDR, "The CIDR to be used for service cluster IPs.")
 	startCmd.Flags().StringArrayVar(&config.DockerEnv, "docker-env", nil, "Environment variables to pass to the Docker daemon. (format: key=value)")
 	startCmd.Flags().StringArrayVar(&config.DockerOpt, "docker-opt", nil, "Specify arbitrary flags to pass to the Docker daemon. (format: key=value)")
+	startCmd.Flags().StringArray(dockerFeature, nil, "Docker daemon features to set in daemon.json, kept for later starts: buildkit, containerd-snapshotter, or ipv6 and fixed-cidr-v6 (format: key=value)")
+	startCmd.Flags().String(dockerBridgeCIDR, "", "The IPv4 CIDR of the docker0 bridge in the node, to avoid conflicts with the networks of the host such as VPNs (docker runtime only). Defaults to 172.17.0.0/16")
+	startCmd.Flags().Int(dockerMTU, 0, "The MTU of the docker0 bridge in the node, for networks with a smaller MTU such as VPNs (docker runtime only)")
+	startCmd.Flags().String(gpus, "", "Allow pods to use your NVIDIA GPUs. Options include: [all,nvidia] (docker driver with the docker container runtime only)")
 
 	// ssh
 	startCmd.Flags().String(sshIPAddress, "", "IP address (ssh driver only)")
 	startCmd.Flags().String(sshSSHUser, defaultSSHUser, "SSH user (ssh driver only)")
 	startCmd.Flags().String(sshSSHKey, "", "SSH key (ssh driver only)")
 	startCmd.Flags().Int(sshSSHPort, defaultSSHPort, "SSH port (ssh driver only)")
+	startCmd.Flags().String(sshSSHSudo, string(command.ElevationSudo), fmt.Sprintf("How to run commands as root on the host: %s. 'password' reads the sudo password from $%s or prompts for it (ssh driver only)", command.Elevations, command.SudoPasswordEnv))
 
 	// socket vmnet
 	startCmd.Flags().String(socketVMnetClientPath, "/opt/socket_vmnet/bin/socket_vmnet_client", "Path to the socket vmnet client binary")
@@ -516,8 +551,19 @@ func generateNewConfigFromFlags(cmd *cobra.Command, k8sVersion string, rtime str
 		NFSSharesRoot:           viper.GetString(nfsSharesRoot),
 		DockerEnv:               config.DockerEnv,
 		DockerOpt:               config.DockerOpt,
+		DockerFeatures:          viper.GetStringSlice(dockerFeature),
+		DockerBridgeCIDR:        viper.GetString(dockerBridgeCIDR),
+		DockerMTU:               viper.GetInt(dockerMTU),
+		GPUs:                    viper.GetString(gpus),
 		InsecureRegistry:        insecureRegistry,
 		RegistryMirror:          registryMirror,
+		RegistryCache:           viper.GetBool(registryCache),
+		AssumeOffline:           viper.GetBool(assumeOffline),
+		PreloadAddonImages:      viper.GetStringSlice(preloadAddonImages),
+		PreloadSource:           viper.GetString(preloadSource),
+		PreloadChecksum:         viper.GetString(preloadChecksum),
+		PreloadChecksumVersion:  k8sVersion,
+		PreloadSourceFallback:   viper.GetBool(preloadSourceFallback),
 		HostOnlyCIDR:            viper.GetString(hostOnlyCIDR),
 		HypervVirtualSwitch:     viper.GetString(hypervVirtualSwitch),
 		HypervUseExternalSwitch: viper.GetBool(hypervUseExternalSwitch),
@@ -540,6 +586,7 @@ func generateNewConfigFromFlags(cmd *cobra.Command, k8sVersion string, rtime str
 		SSHUser:                 viper.GetString(sshSSHUser),
 		SSHKey:                  viper.GetString(sshSSHKey),
 		SSHPort:                 viper.GetInt(sshSSHPort),
+		SSHSudo:                 viper.GetString(sshSSHSudo),
 		ExtraDisks:              viper.GetInt(extraDisks),
 		CertExpiration:          viper.GetDuration(certExpiration),
 		Mount:                   viper.GetBool(createMount),
@@ -569,6 +616,10 @@ func generateNewConfigFromFlags(cmd *cobra.Command, k8sVersion string, rtime str
 			FeatureGates:           viper.GetString(featureGates),
 			ContainerRuntime:       rtime,
 			CRISocket:              viper.GetString(criSocket),
+			DockerOnDemand:         viper.GetBool(dockerOnDemand),
+			IsolatedBuilder:        viper.GetBool(isolatedBuilder),
+			CRISocketGroup:         viper.GetBool(node.CRIDockerdSocketGroupFlag),
+			RuntimeFallback:        runtimeFallback(),
 			NetworkPlugin:          chosenNetworkPlugin,
 			ServiceCIDR:            viper.GetString(serviceCIDR),
 			ImageRepository:        getRepository(cmd, k8sVersion),
@@ -751,10 +802,20 @@ func updateExistingConfigFromFlags(cmd *cobra.Command, existing *config.ClusterC
 	updateStringFromFlag(cmd, &cc.NatNicType, natNicType)
 	updateDurationFromFlag(cmd, &cc.StartHostTimeout, waitTimeout)
 	updateStringSliceFromFlag(cmd, &cc.ExposedPorts, ports)
+	updateStringSliceFromFlag(cmd, &cc.DockerFeatures, dockerFeature)
+	updateStringFromFlag(cmd, &cc.DockerBridgeCIDR, dockerBridgeCIDR)
+	updateIntFromFlag(cmd, &cc.DockerMTU, dockerMTU)
+	updateStringFromFlag(cmd, &cc.GPUs, gpus)
+	updateBoolFromFlag(cmd, &cc.RegistryCache, registryCache)
+	updateBoolFromFlag(cmd, &cc.AssumeOffline, assumeOffline)
+	updateStringSliceFromFlag(cmd, &cc.PreloadAddonImages, preloadAddonImages)
+	updateStringFromFlag(cmd, &cc.PreloadSource, preloadSource)
+	updateBoolFromFlag(cmd, &cc.PreloadSourceFallback, preloadSourceFallback)
 	updateStringFromFlag(cmd, &cc.SSHIPAddress, sshIPAddress)
 	updateStringFromFlag(cmd, &cc.SSHUser, sshSSHUser)
 	updateStringFromFlag(cmd, &cc.SSHKey, sshSSHKey)
 	updateIntFromFlag(cmd, &cc.SSHPort, sshSSHPort)
+	updateStringFromFlag(cmd, &cc.SSHSudo, sshSSHSudo)
 	updateStringFromFlag(cmd, &cc.KubernetesConfig.Namespace, startNamespace)
 	updateStringFromFlag(cmd, &cc.KubernetesConfig.APIServerName, apiServerName)
 	updateStringSliceFromFlag(cmd, &cc.KubernetesConfig.APIServerNames, "apiserver-names")
@@ -762,6 +823,9 @@ func updateExistingConfigFromFlags(cmd *cobra.Command, existing *config.ClusterC
 	updateStringFromFlag(cmd, &cc.KubernetesConfig.FeatureGates, featureGates)
 	updateStringFromFlag(cmd, &cc.KubernetesConfig.ContainerRuntime, containerRuntime)
 	updateStringFromFlag(cmd, &cc.KubernetesConfig.CRISocket, criSocket)
+	updateBoolFromFlag(cmd, &cc.KubernetesConfig.DockerOnDemand, dockerOnDemand)
+	updateBoolFromFlag(cmd, &cc.KubernetesConfig.IsolatedBuilder, isolatedBuilder)
+	updateBoolFromFlag(cmd, &cc.KubernetesConfig.CRISocketGroup, node.CRIDockerdSocketGroupFlag)
 	updateStringFromFlag(cmd, &cc.KubernetesConfig.NetworkPlugin, networkPlugin)
 	updateStringFromFlag(cmd, &cc.KubernetesConfig.ServiceCIDR, serviceCIDR)
 	updateBoolFromFlag(cmd, &cc.KubernetesConfig.ShouldLoadCachedImages, cacheImages)
@@ -786,8 +850,14 @@ func updateExistingConfigFromFlags(cmd *cobra.Command, existing *config.ClusterC
 	if cmd.Flags().Changed(kubernetesVersion) {
 		cc.KubernetesConfig.KubernetesVersion = getKubernetesVersion(existing)
 	}
+	if cmd.Flags().Changed(preloadChecksum) {
+		// a checksum only matches the tarball of one version
+		cc.PreloadChecksum = viper.GetString(preloadChecksum)
+		cc.PreloadChecksumVersion = cc.KubernetesConfig.KubernetesVersion
+	}
 	if cmd.Flags().Changed(containerRuntime) {
 		cc.KubernetesConfig.ContainerRuntime = getContainerRuntime(existing)
+		cc.KubernetesConfig.RuntimeFallback = runtimeFallback()
 	}
 
 	if cmd.Flags().Changed("extra-config") {
@@ -890,7 +960,10 @@ func interpretWaitFlag(cmd cobra.Command) map[string]bool {
 		}
 	}
 
-	waitComponents := kverify.NoComponents
+	waitComponents := map[string]bool{}
+	for k, v := range kverify.NoComponents {
+		waitComponents[k] = v
+	}
 	for _, wc := range waitFlags {
 		seen := false
 		for _, valid := range kverify.AllComponentsList {
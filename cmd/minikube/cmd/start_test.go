@@ -26,6 +26,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil/kverify"
 	cfg "k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/minikube/cruntime"
@@ -431,14 +432,17 @@ func TestValidateDiskSize(t *testing.T) {
 func TestValidateRuntime(t *testing.T) {
 	var tests = []struct {
 		runtime  string
+		want     string
 		errorMsg string
 	}{
 		{
 			runtime:  "cri-o",
+			want:     "crio",
 			errorMsg: "",
 		},
 		{
 			runtime:  "docker",
+			want:     "docker",
 			errorMsg: "",
 		},
 
@@ -449,7 +453,7 @@ func TestValidateRuntime(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.runtime, func(t *testing.T) {
-			got := validateRuntime(test.runtime)
+			rtime, got := validateRuntime(test.runtime)
 			gotError := ""
 			if got != nil {
 				gotError = got.Error()
@@ -457,10 +461,37 @@ func TestValidateRuntime(t *testing.T) {
 			if gotError != test.errorMsg {
 				t.Errorf("ValidateRuntime(runtime=%v): got %v, expected %v", test.runtime, got, test.errorMsg)
 			}
+			if rtime != test.want {
+				t.Errorf("ValidateRuntime(runtime=%v) = %q, expected %q", test.runtime, rtime, test.want)
+			}
 		})
 	}
 }
 
+func TestValidateRuntimeAuto(t *testing.T) {
+	defer viper.Reset()
+	viper.Set(containerRuntime, constants.AutoContainerRuntime)
+	rtime, err := validateRuntime(constants.AutoContainerRuntime)
+	if err != nil {
+		t.Fatalf("validateRuntime(auto): %v", err)
+	}
+	if rtime != constants.DefaultContainerRuntime {
+		t.Errorf("validateRuntime(auto) = %q, want the default runtime", rtime)
+	}
+	if viper.GetString(containerRuntime) != constants.AutoContainerRuntime {
+		t.Errorf("validateRuntime(auto) changed --container-runtime to %q", viper.GetString(containerRuntime))
+	}
+	if !runtimeFallback() {
+		t.Errorf("--container-runtime=auto does not consent to fall back to containerd")
+	}
+	if got := getContainerRuntime(nil); got != constants.Docker {
+		t.Errorf("getContainerRuntime() = %q, want %q", got, constants.Docker)
+	}
+	if got := getContainerRuntime(&cfg.ClusterConfig{KubernetesConfig: cfg.KubernetesConfig{ContainerRuntime: constants.Containerd}}); got != constants.Containerd {
+		t.Errorf("getContainerRuntime(existing) = %q, want the runtime of the existing cluster", got)
+	}
+}
+
 func TestValidatePorts(t *testing.T) {
 	isMicrosoftWSL := detect.IsMicrosoftWSL()
 	type portTest struct {
@@ -638,3 +669,98 @@ func TestValidatePorts(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateIPFamilies(t *testing.T) {
+	var tests = []struct {
+		serviceCIDR string
+		podCIDR     string
+		drvName     string
+		rtime       string
+		wantErr     bool
+	}{
+		{"10.96.0.0/12", "", driver.Docker, "docker", false},
+		{"10.96.0.0/12", "10.244.0.0/16", driver.KVM2, "docker", false},
+		{"10.96.0.0/12,fd00:10:96::/112", "10.244.0.0/16,fd00:10:244::/56", driver.None, "docker", false},
+		{"fd00:10:96::/112", "fd00:10:244::/56", driver.SSH, "docker", false},
+		{"10.96.0.0/12,fd00:10:96::/112", "10.244.0.0/16,fd00:10:244::/56", driver.Docker, "docker", true},
+		{"10.96.0.0/12", "fd00:10:244::/56", driver.KVM2, "docker", true},
+		{"10.96.0.0/12,10.97.0.0/16", "", driver.None, "docker", true},
+		{"10.96.0.0/12", "not-a-cidr", driver.None, "docker", true},
+		// docker0 gets fd00:10:244:ff::/64, the last /64 of the pod network
+		{"fd00:10:244:ff::/112", "fd00:10:244::/56", driver.None, "docker", true},
+		{"fd00:10:244:ff::/112", "fd00:10:244::/56", driver.None, "", true},
+		{"fd00:10:244:ff::/112", "fd00:10:244::/56", driver.None, "containerd", false},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s %s %s %s", test.serviceCIDR, test.podCIDR, test.drvName, test.rtime), func(t *testing.T) {
+			err := validateIPFamilies(test.serviceCIDR, test.podCIDR, test.drvName, test.rtime)
+			if (err != nil) != test.wantErr {
+				t.Errorf("validateIPFamilies(%q, %q, %q, %q) = %v, want error %v", test.serviceCIDR, test.podCIDR, test.drvName, test.rtime, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGPUs(t *testing.T) {
+	var tests = []struct {
+		value   string
+		drvName string
+		runtime string
+		wantErr bool
+	}{
+		{"", driver.VirtualBox, "containerd", false},
+		{"all", driver.Docker, "", false},
+		{"nvidia", driver.Docker, constants.Docker, false},
+		{"all", driver.None, constants.Docker, false},
+		{"1", driver.Docker, constants.Docker, true},
+		{"all", driver.KVM2, constants.Docker, true},
+		{"all", driver.Podman, constants.Docker, true},
+		{"all", driver.Docker, constants.Containerd, true},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s %s %s", test.value, test.drvName, test.runtime), func(t *testing.T) {
+			err := validateGPUs(test.value, test.drvName, test.runtime)
+			if (err != nil) != test.wantErr {
+				t.Errorf("validateGPUs(%q, %q, %q) = %v, want error %v", test.value, test.drvName, test.runtime, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestInterpretWaitFlag(t *testing.T) {
+	var tests = []struct {
+		description string
+		wait        string
+		want        []string
+	}{
+		{"default", "", []string{kverify.APIServerWaitKey, kverify.SystemPodsWaitKey, kverify.RuntimeWaitKey}},
+		{"runtime", "runtime", []string{kverify.RuntimeWaitKey}},
+		{"components", "apiserver,runtime,invalid", []string{kverify.APIServerWaitKey, kverify.RuntimeWaitKey}},
+		{"all", "all", kverify.AllComponentsList},
+		{"none", "none", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			cmd := cobra.Command{}
+			cmd.Flags().StringSlice(waitComponents, kverify.DefaultWaitList, "")
+			if tc.wait != "" {
+				if err := cmd.Flags().Set(waitComponents, tc.wait); err != nil {
+					t.Fatalf("set --wait=%s: %v", tc.wait, err)
+				}
+			}
+			got := interpretWaitFlag(cmd)
+			want := map[string]bool{}
+			for _, c := range tc.want {
+				want[c] = true
+			}
+			for _, c := range kverify.AllComponentsList {
+				if got[c] != want[c] {
+					t.Errorf("--wait=%s: waiting for %s = %v, want %v", tc.wait, c, got[c], want[c])
+				}
+			}
+		})
+	}
+	if kverify.NoComponents[kverify.RuntimeWaitKey] {
+		t.Errorf("interpreting --wait changed the components of 'none'")
+	}
+}
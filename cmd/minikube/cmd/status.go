@@ -36,8 +36,10 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil/kverify"
 	"k8s.io/minikube/pkg/minikube/cluster"
+	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/kubeconfig"
@@ -49,6 +51,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/out/register"
 	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/util"
 	"k8s.io/minikube/pkg/version"
 )
 
@@ -132,15 +135,21 @@ var (
 
 // Status holds string representations of component states
 type Status struct {
-	Name       string
-	Host       string
-	Kubelet    string
-	APIServer  string
-	Kubeconfig string
-	Worker     bool
-	TimeToStop string `json:",omitempty"`
-	DockerEnv  string `json:",omitempty"`
-	PodManEnv  string `json:",omitempty"`
+	Name             string
+	Host             string
+	ContainerRuntime string `json:",omitempty"`
+	Runtime          string `json:",omitempty"`
+	RuntimeReason    string `json:",omitempty"`
+	RuntimeSocket    string `json:",omitempty"`
+	RuntimeVersion   string `json:",omitempty"`
+	RuntimeUpdated   string `json:",omitempty"` // set when the socket and version were read from the profile, as the host is not running
+	Kubelet          string
+	APIServer        string
+	Kubeconfig       string
+	Worker           bool
+	TimeToStop       string `json:",omitempty"`
+	DockerEnv        string `json:",omitempty"`
+	PodManEnv        string `json:",omitempty"`
 }
 
 // ClusterState holds a cluster state representation
@@ -184,6 +193,12 @@ const (
 	defaultStatusFormat          = `{{.Name}}
 type: Control Plane
 host: {{.Host}}
+{{- if .ContainerRuntime }}
+containerRuntime: {{.ContainerRuntime}}
+{{- end }}
+{{- if .Runtime }}
+runtime: {{.Runtime}}{{ if .RuntimeReason }} ({{.RuntimeReason}}){{ end }}
+{{- end }}
 kubelet: {{.Kubelet}}
 apiserver: {{.APIServer}}
 kubeconfig: {{.Kubeconfig}}
@@ -201,6 +216,12 @@ podman-env: {{.PodManEnv}}
 	workerStatusFormat = `{{.Name}}
 type: Worker
 host: {{.Host}}
+{{- if .ContainerRuntime }}
+containerRuntime: {{.ContainerRuntime}}
+{{- end }}
+{{- if .Runtime }}
+runtime: {{.Runtime}}{{ if .RuntimeReason }} ({{.RuntimeReason}}){{ end }}
+{{- end }}
 kubelet: {{.Kubelet}}
 
 `
@@ -324,6 +345,9 @@ func nodeStatus(api libmachine.API, cc config.ClusterConfig, n config.Node) (*St
 		Kubelet:    Nonexistent,
 		Kubeconfig: Nonexistent,
 		Worker:     !controlPlane,
+
+		ContainerRuntime: config.NodeRuntime(cc, n),
+		Runtime:          Nonexistent,
 	}
 
 	hs, err := machine.Status(api, name)
@@ -342,6 +366,8 @@ func nodeStatus(api libmachine.API, cc config.ClusterConfig, n config.Node) (*St
 	if st.Host != state.Running.String() {
 		klog.Infof("host is not running, skipping remaining checks")
 		st.APIServer = st.Host
+		st.Runtime = st.Host
+		recordedRuntime(st, n.Runtime)
 		st.Kubelet = st.Host
 		st.Kubeconfig = st.Host
 		return st, nil
@@ -381,6 +407,8 @@ func nodeStatus(api libmachine.API, cc config.ClusterConfig, n config.Node) (*St
 		st.Host = codeNames[InsufficientStorage]
 	}
 
+	runtimeStatus(cr, cc, n, st)
+
 	stk := kverify.ServiceStatus(cr, "kubelet")
 	st.Kubelet = stk.String()
 	if cc.ScheduledStop != nil {
@@ -430,6 +458,48 @@ func nodeStatus(api libmachine.API, cc config.ClusterConfig, n config.Node) (*St
 	return st, nil
 }
 
+// runtimeStatus fills in the health of the container runtime of a node, along with the reason for it, its socket and version
+func runtimeStatus(runner command.Runner, cc config.ClusterConfig, n config.Node, st *Status) {
+	nc := config.ForNode(cc, n)
+	// the health of the runtime covers the pause image of the Kubernetes version, unless it is unknown
+	kv, err := util.ParseKubernetesVersion(nc.KubernetesConfig.KubernetesVersion)
+	if err != nil {
+		klog.Infof("unable to parse the Kubernetes version %q: %v", nc.KubernetesConfig.KubernetesVersion, err)
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: nc.KubernetesConfig.ContainerRuntime, Driver: nc.Driver, Runner: runner, Socket: nc.KubernetesConfig.CRISocket,
+		KubernetesVersion: kv, ImageRepository: nc.KubernetesConfig.ImageRepository})
+	if err != nil {
+		klog.Errorf("failed to create runtime: %v", err)
+		st.Runtime, st.RuntimeReason = state.Error.String(), err.Error()
+		return
+	}
+	st.RuntimeSocket = cr.SocketPath()
+	h, err := cr.RuntimeHealth()
+	if err != nil {
+		klog.Errorf("failed to get runtime health: %v", err)
+		st.Runtime, st.RuntimeReason = state.Error.String(), err.Error()
+		return
+	}
+	klog.Infof("%s runtime health = %+v", config.MachineName(cc, n), h)
+	st.Runtime, st.RuntimeReason = h.State, h.Reason
+	if h.State != state.Running.String() {
+		return
+	}
+	if st.RuntimeVersion, err = cr.Version(); err != nil {
+		klog.Warningf("failed to get runtime version: %v", err)
+	}
+}
+
+// recordedRuntime fills in the socket and version of the container runtime saved on the last start of a node, with the time they were observed
+func recordedRuntime(st *Status, ri *config.RuntimeInfo) {
+	if ri == nil {
+		return
+	}
+	st.RuntimeSocket = ri.Socket
+	st.RuntimeVersion = ri.Version
+	st.RuntimeUpdated = ri.LastUpdated.Format(time.RFC3339)
+}
+
 func init() {
 	statusCmd.Flags().StringVarP(&statusFormat, "format", "f", defaultStatusFormat,
 		`Go template format string for the status output.  The format for Go templates can be found here: https://golang.org/pkg/text/template/
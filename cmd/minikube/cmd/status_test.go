@@ -19,7 +19,11 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
+
+	"k8s.io/minikube/pkg/minikube/config"
 )
 
 func TestExitCode(t *testing.T) {
@@ -64,6 +68,11 @@ func TestStatusText(t *testing.T) {
 			state: &Status{Name: "minikube", Host: "Stopped", Kubelet: "Stopped", APIServer: "Stopped", Kubeconfig: Misconfigured},
 			want:  "minikube\ntype: Control Plane\nhost: Stopped\nkubelet: Stopped\napiserver: Stopped\nkubeconfig: Misconfigured\n\n\nWARNING: Your kubectl is pointing to stale minikube-vm.\nTo fix the kubectl context, run `minikube update-context`\n",
 		},
+		{
+			name:  "worker runtime",
+			state: &Status{Name: "minikube-m02", Host: "Running", ContainerRuntime: "docker", Runtime: "Crashloop", RuntimeReason: "docker restarted 5 times, last result oom-kill", Kubelet: "Running", Worker: true},
+			want:  "minikube-m02\ntype: Worker\nhost: Running\ncontainerRuntime: docker\nruntime: Crashloop (docker restarted 5 times, last result oom-kill)\nkubelet: Running\n\n",
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -89,6 +98,8 @@ func TestStatusJSON(t *testing.T) {
 		{"ok", &Status{Host: "Running", Kubelet: "Running", APIServer: "Running", Kubeconfig: Configured, TimeToStop: "10m"}},
 		{"paused", &Status{Host: "Running", Kubelet: "Stopped", APIServer: "Paused", Kubeconfig: Configured}},
 		{"down", &Status{Host: "Stopped", Kubelet: "Stopped", APIServer: "Stopped", Kubeconfig: Misconfigured}},
+		{"runtime", &Status{Host: "Running", Runtime: "Running", RuntimeSocket: "/run/containerd/containerd.sock", RuntimeVersion: "1.6.8", Kubelet: "Running", APIServer: "Running", Kubeconfig: Configured}},
+		{"stale runtime", &Status{Host: "Stopped", Runtime: "Stopped", RuntimeSocket: "/var/run/crio/crio.sock", RuntimeVersion: "1.24.1", RuntimeUpdated: "2022-09-01T12:00:00Z", Kubelet: "Stopped", APIServer: "Stopped", Kubeconfig: Configured}},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -102,6 +113,33 @@ func TestStatusJSON(t *testing.T) {
 			if err := json.Unmarshal(b.Bytes(), st); err != nil {
 				t.Errorf("json(%+v) unmarshal error: %v", tc.state, err)
 			}
+			if *st != *tc.state {
+				t.Errorf("json(%+v) round trip = %+v", tc.state, st)
+			}
 		})
 	}
 }
+
+func TestRecordedRuntime(t *testing.T) {
+	st := &Status{Host: "Stopped", Runtime: "Stopped"}
+	recordedRuntime(st, nil)
+	if st.RuntimeSocket != "" || st.RuntimeVersion != "" || st.RuntimeUpdated != "" {
+		t.Errorf("recordedRuntime(nil) = %+v, want no runtime details", st)
+	}
+
+	ri := &config.RuntimeInfo{Name: "crio", Socket: "/var/run/crio/crio.sock", Version: "1.24.1", LastUpdated: time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)}
+	recordedRuntime(st, ri)
+	if st.RuntimeSocket != ri.Socket || st.RuntimeVersion != ri.Version || st.RuntimeUpdated != "2022-09-01T12:00:00Z" {
+		t.Errorf("recordedRuntime(%+v) = %+v", ri, st)
+	}
+
+	var b bytes.Buffer
+	if err := statusJSON([]*Status{st}, &b); err != nil {
+		t.Fatalf("json(%+v) error: %v", st, err)
+	}
+	for _, key := range []string{`"RuntimeSocket":"/var/run/crio/crio.sock"`, `"RuntimeVersion":"1.24.1"`, `"RuntimeUpdated":"2022-09-01T12:00:00Z"`} {
+		if !strings.Contains(b.String(), key) {
+			t.Errorf("json(%+v) = %s, missing %s", st, b.String(), key)
+		}
+	}
+}
@@ -46,8 +46,13 @@ var (
 	keepActive            bool
 	scheduledStopDuration time.Duration
 	cancelScheduledStop   bool
+	stopGC                bool
+	stopGracePeriod       time.Duration
 )
 
+// fastStopTimeout is how long the containers get to stop when they are stopped in bulk before their node
+const fastStopTimeout = 5 * time.Second
+
 // stopCmd represents the stop command
 var stopCmd = &cobra.Command{
 	Use:   "stop",
@@ -61,6 +66,8 @@ func init() {
 	stopCmd.Flags().BoolVar(&keepActive, "keep-context-active", false, "keep the kube-context active after cluster is stopped. Defaults to false.")
 	stopCmd.Flags().DurationVar(&scheduledStopDuration, "schedule", 0*time.Second, "Set flag to stop cluster after a set amount of time (e.g. --schedule=5m)")
 	stopCmd.Flags().BoolVar(&cancelScheduledStop, "cancel-scheduled", false, "cancel any existing scheduled stop requests")
+	stopCmd.Flags().BoolVar(&stopGC, "gc", false, "Remove the Kubernetes containers which stopped more than a day ago from the nodes, before stopping them")
+	stopCmd.Flags().DurationVar(&stopGracePeriod, "grace-period", 0, "If set, stop the containers gracefully, each in turn within this grace period, instead of all at once within 5s so that nodes stop quickly. If negative, let the container runtime stop each container in turn with its own grace period.")
 	stopCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Format to print stdout in. Options include: [text,json]")
 
 	if err := viper.GetViper().BindPFlags(stopCmd.Flags()); err != nil {
@@ -115,14 +122,14 @@ func runStop(cmd *cobra.Command, args []string) {
 
 	stoppedNodes := 0
 	for _, profile := range profilesToStop {
-		stoppedNodes = stopProfile(profile)
+		stoppedNodes = stopProfile(profile, cmd.Flags().Changed("grace-period"))
 	}
 
 	register.Reg.SetStep(register.Done)
 	out.Step(style.Stopped, `{{.count}} node{{if gt .count 1}}s{{end}} stopped.`, out.V{"count": stoppedNodes})
 }
 
-func stopProfile(profile string) int {
+func stopProfile(profile string, graceful bool) int {
 	stoppedNodes := 0
 	register.Reg.SetStep(register.Stopping)
 
@@ -137,7 +144,13 @@ func stopProfile(profile string) int {
 	for _, n := range cc.Nodes {
 		machineName := config.MachineName(*cc, n)
 
+		if stopGC {
+			maybeGarbageCollect(api, config.ForNode(*cc, n), machineName)
+		}
+		prepareStop(api, *cc, n, graceful)
+		start := time.Now()
 		nonexistent := stop(api, machineName)
+		klog.Infof("duration metric: stopped %s in %s", machineName, time.Since(start))
 		if !nonexistent {
 			stoppedNodes++
 		}
@@ -152,6 +165,22 @@ func stopProfile(profile string) int {
 	return stoppedNodes
 }
 
+// prepareStop stops the containers of a node in bulk before stopping it, unless --grace-period asks for the graceful path,
+// where they are stopped in turn within the grace period, or by the runtime itself if it is negative
+func prepareStop(api libmachine.API, cc config.ClusterConfig, n config.Node, graceful bool) {
+	timeout := fastStopTimeout
+	if graceful && stopGracePeriod < 0 {
+		klog.Infof("skipping the bulk stop of the containers, the runtime stops them with their own grace period")
+		return
+	}
+	if graceful {
+		timeout = stopGracePeriod
+	}
+	if err := machine.PrepareStop(api, cc, n, timeout, graceful); err != nil {
+		klog.Warningf("unable to stop the containers of %s in bulk, the runtime stops them in turn: %v", config.MachineName(cc, n), err)
+	}
+}
+
 func stop(api libmachine.API, machineName string) bool {
 	nonexistent := false
 
@@ -42,6 +42,7 @@ var unpauseCmd = &cobra.Command{
 	Use:     "unpause",
 	Aliases: []string{"resume"},
 	Short:   "unpause Kubernetes",
+	Long:    "Unpause the containers of Kubernetes. Without flags, the namespaces the last 'minikube pause' paused are unpaused.",
 	Run: func(cmd *cobra.Command, args []string) {
 		cname := ClusterFlagValue()
 		register.SetEventLogPath(localpath.EventLog(cname))
@@ -51,7 +52,13 @@ var unpauseCmd = &cobra.Command{
 		register.Reg.SetStep(register.Unpausing)
 
 		klog.Infof("namespaces: %v keys: %v", namespaces, viper.AllSettings())
-		if allNamespaces {
+		// a bare unpause reverses the last pause
+		bare := !cmd.Flags().Changed("namespaces") && !cmd.Flags().Changed("all-namespaces") && !cmd.Flags().Changed("exclude-namespaces")
+		if p := co.Config.Paused; bare && p != nil {
+			namespaces, excludeNamespaces = p.Namespaces, p.ExcludeNamespaces
+			klog.Infof("unpausing the namespaces of the last pause: %v, except %v", namespaces, excludeNamespaces)
+		} else if allNamespaces || len(excludeNamespaces) > 0 && !cmd.Flags().Changed("namespaces") {
+			// the namespaces excluded are among all the others, unless namespaces are selected
 			namespaces = nil // all
 		} else {
 			if len(namespaces) == 0 {
@@ -83,23 +90,34 @@ var unpauseCmd = &cobra.Command{
 				exit.Error(reason.InternalCommandRunner, "Failed to get command runner", err)
 			}
 
-			cr, err := cruntime.New(cruntime.Config{Type: co.Config.KubernetesConfig.ContainerRuntime, Runner: r})
+			cr, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(*co.Config, n), Driver: co.Config.Driver, Runner: r})
 			if err != nil {
 				exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
 			}
 
-			uids, err := cluster.Unpause(cr, r, namespaces)
+			uids, err := cluster.Unpause(cr, r, namespaces, excludeNamespaces)
 			if err != nil {
 				exit.Error(reason.GuestUnpause, "Pause", err)
 			}
 			ids = append(ids, uids...)
 		}
 
+		// the containers of the last pause are all unpaused, unless only some of its namespaces were
+		if co.Config.Paused != nil && (bare || (namespaces == nil && len(excludeNamespaces) == 0)) {
+			co.Config.Paused = nil
+			if err := config.SaveProfile(co.Config.Name, co.Config); err != nil {
+				klog.Warningf("unable to forget the paused namespaces: %v", err)
+			}
+		}
+
 		register.Reg.SetStep(register.Done)
 
-		if namespaces == nil {
+		switch {
+		case len(excludeNamespaces) > 0:
+			out.Step(style.Pause, "Unpaused {{.count}} containers, except in: {{.namespaces}}", out.V{"count": len(ids), "namespaces": strings.Join(excludeNamespaces, ", ")})
+		case namespaces == nil:
 			out.Step(style.Pause, "Unpaused {{.count}} containers", out.V{"count": len(ids)})
-		} else {
+		default:
 			out.Step(style.Pause, "Unpaused {{.count}} containers in: {{.namespaces}}", out.V{"count": len(ids), "namespaces": strings.Join(namespaces, ", ")})
 		}
 	},
@@ -108,5 +126,6 @@ var unpauseCmd = &cobra.Command{
 func init() {
 	unpauseCmd.Flags().StringSliceVarP(&namespaces, "namespaces", "n", constants.DefaultNamespaces, "namespaces to unpause")
 	unpauseCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "If set, unpause all namespaces")
+	unpauseCmd.Flags().StringSliceVar(&excludeNamespaces, "exclude-namespaces", nil, "namespaces not to unpause, even if selected by --namespaces or --all-namespaces. Implies --all-namespaces unless --namespaces is set")
 	unpauseCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Format to print stdout in. Options include: [text,json]")
 }
@@ -35,9 +35,11 @@ import (
 	"k8s.io/minikube/pkg/drivers/kic/oci"
 	"k8s.io/minikube/pkg/kapi"
 	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
 	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/machine"
@@ -57,6 +59,16 @@ var Force = false
 // Currently only used for gcp-auth
 var Refresh = false
 
+// Preloading is used when the images of a start come from a preload, which the runtime may still be loading
+// Core addons then wait for their images before applying their manifests
+var Preloading = false
+
+// coreImagesTimeout is how long enabling a core addon waits for its images to be visible to the runtime
+var coreImagesTimeout = time.Minute
+
+// coreImagesInterval is the first interval between the checks of the images of a core addon
+var coreImagesInterval = 250 * time.Millisecond
+
 // ErrSkipThisAddon is a special error that tells us to not error out, but to also not mark the addon as enabled
 var ErrSkipThisAddon = errors.New("skipping this addon")
 
@@ -213,6 +225,16 @@ func EnableOrDisableAddon(cc *config.ClusterConfig, name string, val string) err
 		return nil
 	}
 
+	if enable {
+		refs := assets.ImageRefs(addon, cc, images, customRegistries)
+		if err := checkOfflineImages(cc, runner, refs); err != nil {
+			return errors.Wrapf(err, "enabling %s", name)
+		}
+		if Preloading && addon.IsEnabledByDefault() {
+			waitForCoreImages(cc, runner, refs)
+		}
+	}
+
 	var networkInfo assets.NetworkInfo
 	if len(cc.Nodes) >= 1 {
 		networkInfo.ControlPlaneNodeIP = cc.Nodes[0].IP
@@ -291,6 +313,81 @@ func isAddonAlreadySet(cc *config.ClusterConfig, addon *assets.Addon, enable boo
 	return false
 }
 
+// waitForCoreImages waits until the runtime sees the images of a core addon, which may still be extracted from the preload on start.
+// Applying its manifests before would make its pods fail to pull them at first. The images are pulled as usual on timeout.
+func waitForCoreImages(cc *config.ClusterConfig, runner command.Runner, refs []string) {
+	if len(refs) == 0 {
+		return
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Driver: cc.Driver, Runner: runner, Socket: cc.KubernetesConfig.CRISocket})
+	if err != nil {
+		klog.Warningf("unable to wait for images %v: %v", refs, err)
+		return
+	}
+	if err := waitForImages(cr, refs, coreImagesTimeout); err != nil {
+		klog.Warningf("applying the manifests anyway: %v", err)
+	}
+}
+
+// waitForImages waits until the runtime sees all the images, asking it once for all of them on each try
+func waitForImages(cr cruntime.Manager, refs []string, timeout time.Duration) error {
+	start := time.Now()
+	visible := func() error {
+		exist, err := cr.ImagesExist(refs)
+		if err != nil {
+			return err
+		}
+		missing := []string{}
+		for _, ref := range refs {
+			if !exist[ref] {
+				missing = append(missing, ref)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("images not visible to %s yet: %s", cr.Name(), strings.Join(missing, ", "))
+		}
+		return nil
+	}
+	if err := retry.Expo(visible, coreImagesInterval, timeout); err != nil {
+		return err
+	}
+	klog.Infof("images %v visible to %s after %s", refs, cr.Name(), time.Since(start))
+	return nil
+}
+
+// checkOfflineImages returns an error listing the images which are missing from the node, if it is offline and can not pull them
+func checkOfflineImages(cc *config.ClusterConfig, runner command.Runner, refs []string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Driver: cc.Driver, Runner: runner, Socket: cc.KubernetesConfig.CRISocket})
+	if err != nil {
+		return errors.Wrap(err, "container runtime")
+	}
+	registry := cc.KubernetesConfig.ImageRepository
+	if registry == "" {
+		v, _ := util.ParseKubernetesVersion(cc.KubernetesConfig.KubernetesVersion)
+		registry = images.DefaultKubernetesRepo(v)
+	}
+	if !cruntime.Offline(cc.AssumeOffline, runner, registry) {
+		return nil
+	}
+	exist, err := cr.ImagesExist(refs)
+	if err != nil {
+		return errors.Wrap(err, "checking images")
+	}
+	missing := []string{}
+	for _, ref := range refs {
+		if !exist[ref] {
+			missing = append(missing, ref)
+		}
+	}
+	if len(missing) > 0 {
+		return cruntime.NewErrOffline(missing...)
+	}
+	return nil
+}
+
 // maintain backwards compatibility for ingress and ingress-dns addons with k8s < v1.19 by replacing default addons' images with compatible versions
 func supportLegacyIngress(addon *assets.Addon, cc config.ClusterConfig) error {
 	v, err := util.ParseKubernetesVersion(cc.KubernetesConfig.KubernetesVersion)
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/kapi"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/machine"
+)
+
+// RegistryServiceAddress returns the address of the plain HTTP port of the registry service:
+// its cluster IP, which pods and nodes can both reach, or the node port on nodeIP otherwise.
+func RegistryServiceAddress(svc *core.Service, nodeIP string) (string, error) {
+	for _, p := range svc.Spec.Ports {
+		if p.Name != "http" {
+			continue
+		}
+		if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != core.ClusterIPNone {
+			return net.JoinHostPort(svc.Spec.ClusterIP, strconv.Itoa(int(p.Port))), nil
+		}
+		if p.NodePort != 0 {
+			return net.JoinHostPort(nodeIP, strconv.Itoa(int(p.NodePort))), nil
+		}
+	}
+	return "", fmt.Errorf("service %s/%s has no reachable http port", svc.Namespace, svc.Name)
+}
+
+// allowInsecureRegistry makes the runtimes of the running nodes accept the registry addon over plain HTTP,
+// when the addon is enabled or the cluster starts, so that pushing to it and pulling from it never restarts a runtime
+func allowInsecureRegistry(cc *config.ClusterConfig, name, val string) error {
+	enable, err := strconv.ParseBool(val)
+	if err != nil {
+		return errors.Wrapf(err, "parsing bool: %s", name)
+	}
+	if !enable {
+		return nil
+	}
+	cp, err := config.PrimaryControlPlane(cc)
+	if err != nil {
+		return errors.Wrap(err, "getting primary control plane")
+	}
+	client, err := kapi.Client(cc.Name)
+	if err != nil {
+		return errors.Wrap(err, "get kube-client")
+	}
+	svc, err := client.CoreV1().Services("kube-system").Get(context.Background(), "registry", meta.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "getting the registry service")
+	}
+	addr, err := RegistryServiceAddress(svc, cp.IP)
+	if err != nil {
+		return err
+	}
+
+	api, err := machine.NewAPIClient()
+	if err != nil {
+		return errors.Wrap(err, "machine client")
+	}
+	defer api.Close()
+	for _, n := range cc.Nodes {
+		mName := config.MachineName(*cc, n)
+		if !machine.IsRunning(api, mName) {
+			klog.Infof("%q is not running, skipping the insecure registry %s", mName, addr)
+			continue
+		}
+		host, err := machine.LoadHost(api, mName)
+		if err != nil {
+			return errors.Wrapf(err, "loading %s", mName)
+		}
+		runner, err := machine.CommandRunner(host)
+		if err != nil {
+			return errors.Wrap(err, "command runner")
+		}
+		cr, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(*cc, n), Driver: cc.Driver, Runner: runner})
+		if err != nil {
+			return errors.Wrap(err, "container runtime")
+		}
+		if err := cruntime.EnsureInsecureRegistry(cr, addr); err != nil {
+			return errors.Wrapf(err, "allowing the insecure registry %s on %s", addr, mName)
+		}
+	}
+	return nil
+}
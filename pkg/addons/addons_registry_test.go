@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+)
+
+func TestRegistryServiceAddress(t *testing.T) {
+	ports := []core.ServicePort{{Name: "http", Port: 80, NodePort: 30500}, {Name: "https", Port: 443}}
+	tests := []struct {
+		description string
+		spec        core.ServiceSpec
+		want        string
+		wantErr     bool
+	}{
+		{description: "cluster ip", spec: core.ServiceSpec{ClusterIP: "10.96.12.34", Ports: ports}, want: "10.96.12.34:80"},
+		{description: "headless", spec: core.ServiceSpec{ClusterIP: core.ClusterIPNone, Ports: ports}, want: "192.168.49.2:30500"},
+		{description: "no http port", spec: core.ServiceSpec{ClusterIP: "10.96.12.34", Ports: ports[1:]}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := RegistryServiceAddress(&core.Service{Spec: tc.spec}, "192.168.49.2")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("RegistryServiceAddress() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("RegistryServiceAddress() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -17,13 +17,18 @@ limitations under the License.
 package addons
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"k8s.io/minikube/pkg/minikube/assets"
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/cruntime/cruntimetest"
 	"k8s.io/minikube/pkg/minikube/localpath"
 	"k8s.io/minikube/pkg/minikube/tests"
 )
@@ -137,3 +142,112 @@ func TestStart(t *testing.T) {
 		t.Errorf("expected dashboard to be enabled")
 	}
 }
+
+func TestPrefetchRefs(t *testing.T) {
+	cc := &config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.25.0", ImageRepository: "mirror.local"}}
+	got := prefetchRefs(cc, []string{"dashboard", "unknown", "dashboard"})
+	want := assets.ImageRefs(assets.Addons["dashboard"], cc, assets.Addons["dashboard"].Images, nil)
+	if len(got) != len(want) {
+		t.Fatalf("prefetchRefs() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("prefetchRefs() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPrefetchNames(t *testing.T) {
+	cc := &config.ClusterConfig{
+		PreloadAddonImages: []string{"ingress", "dashboard"},
+		Addons:             map[string]bool{"metrics-server": true, "dashboard": true, "registry": false, "default-storageclass": true},
+	}
+	got := PrefetchNames(cc)
+	want := []string{"ingress", "dashboard", "default-storageclass", "metrics-server"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("PrefetchNames() = %v, want %v", got, want)
+	}
+}
+
+func TestPrefetchImages(t *testing.T) {
+	refs := []string{"a:1", "b:1", "c:1"}
+
+	t.Run("skips existing", func(t *testing.T) {
+		cr := cruntimetest.NewFakeRuntime()
+		cr.Images["b:1"] = "b"
+		pulled, err := prefetchImages(context.Background(), cr, refs)
+		if err != nil {
+			t.Fatalf("prefetchImages() error = %v", err)
+		}
+		if len(pulled) != 2 || pulled[0] != "a:1" || pulled[1] != "c:1" {
+			t.Errorf("prefetchImages() pulled %v, want [a:1 c:1]", pulled)
+		}
+		if calls := cr.Called("ImagesExist"); len(calls) != 1 {
+			t.Errorf("ImagesExist called %d times, want once", len(calls))
+		}
+	})
+
+	t.Run("stops offline", func(t *testing.T) {
+		cr := cruntimetest.NewFakeRuntime()
+		cr.Fail("PullImage", cruntime.NewErrOffline("a:1"))
+		pulled, err := prefetchImages(context.Background(), cr, refs)
+		if _, ok := err.(*cruntime.ErrOffline); !ok {
+			t.Errorf("prefetchImages() error = %v, want offline", err)
+		}
+		if len(pulled) != 0 || len(cr.Called("PullImage")) != 1 {
+			t.Errorf("prefetchImages() pulled %v after %v, want a single attempt", pulled, cr.Called("PullImage"))
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		cr := cruntimetest.NewFakeRuntime()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := prefetchImages(ctx, cr, refs); err != context.Canceled {
+			t.Errorf("prefetchImages() error = %v, want %v", err, context.Canceled)
+		}
+		if calls := cr.Called("PullImage"); len(calls) != 0 {
+			t.Errorf("PullImage called %v after cancel", calls)
+		}
+	})
+}
+
+func TestWaitForImages(t *testing.T) {
+	refs := assets.ImageRefs(assets.Addons["storage-provisioner"], &config.ClusterConfig{}, assets.Addons["storage-provisioner"].Images, nil)
+	if len(refs) == 0 {
+		t.Fatalf("storage-provisioner has no images")
+	}
+
+	t.Run("slow extraction", func(t *testing.T) {
+		cr := cruntimetest.NewFakeRuntime()
+		extracted := make(chan time.Time, 1)
+		go func() {
+			// the preload is still being extracted
+			time.Sleep(300 * time.Millisecond)
+			for _, ref := range refs {
+				if err := cr.PullImage(ref); err != nil {
+					t.Errorf("PullImage(%s): %v", ref, err)
+				}
+			}
+			extracted <- time.Now()
+		}()
+		if err := waitForImages(cr, refs, 10*time.Second); err != nil {
+			t.Fatalf("waitForImages() = %v", err)
+		}
+		applied := time.Now()
+		if at := <-extracted; applied.Before(at) {
+			t.Errorf("applied at %s, before the images were extracted at %s", applied, at)
+		}
+		if calls := cr.Called("ImagesExist"); len(calls) < 2 {
+			t.Errorf("ImagesExist called %d times, want retries until the images appear", len(calls))
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		cr := cruntimetest.NewFakeRuntime()
+		err := waitForImages(cr, refs, 500*time.Millisecond)
+		if err == nil || !strings.Contains(err.Error(), refs[0]) {
+			t.Errorf("waitForImages() = %v, want an error naming %s", err, refs[0])
+		}
+	})
+}
@@ -71,7 +71,7 @@ var Addons = []*Addon{
 	{
 		name:        "gvisor",
 		set:         SetBool,
-		validations: []setFn{IsRuntimeContainerd},
+		validations: []setFn{SupportsUntrustedRuntimeClass},
 		callbacks:   []setFn{EnableOrDisableAddon, verifyAddonStatus},
 	},
 	{
@@ -137,7 +137,7 @@ var Addons = []*Addon{
 	{
 		name:      "registry",
 		set:       SetBool,
-		callbacks: []setFn{EnableOrDisableAddon, verifyAddonStatus},
+		callbacks: []setFn{EnableOrDisableAddon, allowInsecureRegistry, verifyAddonStatus},
 	},
 	{
 		name:      "registry-creds",
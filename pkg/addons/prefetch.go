@@ -0,0 +1,176 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+var (
+	// prefetching tracks the prefetches running in the background, which the start does not wait for
+	prefetching sync.WaitGroup
+	// pendingPrefetches counts the prefetches running in the background
+	pendingPrefetches int32
+)
+
+// PrefetchImages pulls the missing images of the addons in the background, one at a time, so that enabling them later needs no pulls.
+// It never fails, only logging the images it could not pull, and stops when ctx is done or the node is offline.
+func PrefetchImages(ctx context.Context, cc *config.ClusterConfig, cr cruntime.Manager, names []string) {
+	prefetching.Add(1)
+	atomic.AddInt32(&pendingPrefetches, 1)
+	go func() {
+		defer prefetching.Done()
+		defer atomic.AddInt32(&pendingPrefetches, -1)
+		prefetchAddonImages(ctx, cc, cr, names)
+	}()
+}
+
+// WaitPrefetch waits for the prefetches running in the background, which stop early when their context is done.
+func WaitPrefetch() {
+	if atomic.LoadInt32(&pendingPrefetches) > 0 {
+		out.Step(style.Waiting, "Prefetching the images of addons, press Ctrl-C to stop")
+	}
+	prefetching.Wait()
+}
+
+// PrefetchNames returns the addons to prefetch the images of: the ones of --preload-addon-images, and the ones enabled in cc
+func PrefetchNames(cc *config.ClusterConfig) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, name := range cc.PreloadAddonImages {
+		add(name)
+	}
+	enabled := []string{}
+	for name, on := range cc.Addons {
+		if on {
+			enabled = append(enabled, name)
+		}
+	}
+	sort.Strings(enabled)
+	for _, name := range enabled {
+		add(name)
+	}
+	return names
+}
+
+// prefetchAddonImages pulls the missing images of the addons, logging how long it took
+func prefetchAddonImages(ctx context.Context, cc *config.ClusterConfig, cr cruntime.Manager, names []string) {
+	start := time.Now()
+	klog.Infof("prefetchAddonImages start: addons=%v", names)
+	defer func() {
+		klog.Infof("prefetchAddonImages completed in %s", time.Since(start))
+	}()
+
+	pulled, err := prefetchImages(ctx, cr, prefetchRefs(cc, names))
+	if err != nil {
+		klog.Warningf("prefetching addon images: %v", err)
+	}
+	if len(pulled) > 0 {
+		out.Step(style.Waiting, "Prefetched the images of addons: {{.addons}}", out.V{"addons": strings.Join(names, ", ")})
+	}
+}
+
+// prefetchRefs returns the images of the addons, as their manifests would pull them
+func prefetchRefs(cc *config.ClusterConfig, names []string) []string {
+	seen := map[string]bool{}
+	refs := []string{}
+	for _, name := range names {
+		a, ok := assets.Addons[name]
+		if !ok {
+			out.WarningT("Not prefetching the images of the unknown addon {{.name}}", out.V{"name": name})
+			continue
+		}
+		// a copy, as the addons being enabled meanwhile share the original
+		addon := *a
+		// maintain backwards compatibility for ingress and ingress-dns addons with k8s < v1.19
+		if strings.HasPrefix(name, "ingress") {
+			if err := supportLegacyIngress(&addon, *cc); err != nil {
+				klog.Warningf("not prefetching the images of %s: %v", name, err)
+				continue
+			}
+		}
+		images, customRegistries := assets.PersistedImages(&addon, cc)
+		if cc.KubernetesConfig.ImageRepository == constants.AliyunMirror {
+			images, customRegistries = assets.FixAddonImagesAndRegistries(&addon, images, customRegistries)
+		}
+		for _, ref := range assets.ImageRefs(&addon, cc, images, customRegistries) {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// prefetchImages pulls the images which do not exist yet, returning the ones it pulled
+func prefetchImages(ctx context.Context, cr cruntime.Manager, refs []string) ([]string, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	exist, err := cr.ImagesExist(refs)
+	if err != nil {
+		return nil, errors.Wrap(err, "checking images")
+	}
+	pull := cr.PullImage
+	if cm, ok := cr.(cruntime.ContextManager); ok {
+		pull = func(ref string) error { return cm.PullImageContext(ctx, ref) }
+	}
+	missing := []string{}
+	for _, ref := range refs {
+		if !exist[ref] {
+			missing = append(missing, ref)
+		}
+	}
+	pulled := []string{}
+	for i, ref := range missing {
+		if ctx.Err() != nil {
+			return pulled, ctx.Err()
+		}
+		klog.Infof("prefetching addon image %s (%d/%d)", ref, i+1, len(missing))
+		if err := pull(ref); err != nil {
+			if _, ok := errors.Cause(err).(*cruntime.ErrOffline); ok {
+				return pulled, err
+			}
+			klog.Warningf("unable to prefetch %s: %v", ref, err)
+			continue
+		}
+		pulled = append(pulled, ref)
+	}
+	return pulled, nil
+}
@@ -45,14 +45,13 @@ const volumesnapshotsDisabledMsg = `[WARNING] For full functionality, the 'csi-h
 You can enable 'volumesnapshots' addon by running: 'minikube addons enable volumesnapshots'
 `
 
-// IsRuntimeContainerd is a validator which returns an error if the current runtime is not containerd
-func IsRuntimeContainerd(cc *config.ClusterConfig, _, _ string) error {
+// SupportsUntrustedRuntimeClass is a validator which returns an error if the current runtime cannot run untrusted workloads
+func SupportsUntrustedRuntimeClass(cc *config.ClusterConfig, _, _ string) error {
 	r, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime})
 	if err != nil {
 		return err
 	}
-	_, ok := r.(*cruntime.Containerd)
-	if !ok {
+	if !r.Capabilities().SupportsUntrustedRuntimeClass {
 		return fmt.Errorf(containerdOnlyAddonMsg)
 	}
 	return nil
@@ -143,6 +143,12 @@ func (d *Driver) Create() error {
 			ContainerPort: constants.AutoPauseProxyPort,
 		},
 	)
+	if d.NodeConfig.IsolatedBuilder {
+		params.PortMappings = append(params.PortMappings, oci.PortMapping{
+			ListenAddress: listAddr,
+			ContainerPort: constants.BuilderDaemonPort,
+		})
+	}
 
 	exists, err := oci.ContainerExists(d.OCIBinary, params.Name, true)
 	if err != nil {
@@ -177,15 +183,22 @@ func (d *Driver) Create() error {
 		}
 		t := time.Now()
 		klog.Infof("Starting extracting preloaded images to volume ...")
-		// Extract preloaded images to container
-		if err := oci.ExtractTarballToVolume(d.NodeConfig.OCIBinary, download.TarballPath(d.NodeConfig.KubernetesVersion, d.NodeConfig.ContainerRuntime), params.Name, d.NodeConfig.ImageDigest); err != nil {
-			if strings.Contains(err.Error(), "No space left on device") {
-				pErr = oci.ErrInsufficientDockerStorage
-				return
+		// Extract preloaded images to container, falling back to the next cached tarball if the image cannot decompress one
+		for _, tarball := range download.CachedTarballs(d.NodeConfig.KubernetesVersion, d.NodeConfig.ContainerRuntime) {
+			c, err := download.DetectCompression(tarball)
+			if err == nil {
+				err = oci.ExtractTarballToVolume(d.NodeConfig.OCIBinary, tarball, c.TarFlags(), params.Name, d.NodeConfig.ImageDigest)
+			}
+			if err != nil {
+				if strings.Contains(err.Error(), "No space left on device") {
+					pErr = oci.ErrInsufficientDockerStorage
+					return
+				}
+				klog.Infof("Unable to extract preloaded tarball %s to volume: %v", tarball, err)
+				continue
 			}
-			klog.Infof("Unable to extract preloaded tarball to volume: %v", err)
-		} else {
 			klog.Infof("duration metric: took %f seconds to extract preloaded images to volume", time.Since(t).Seconds())
+			return
 		}
 	}()
 	waitForPreload.Wait()
@@ -441,7 +454,7 @@ func (d *Driver) Stop() error {
 		}
 	}
 
-	runtime, err := cruntime.New(cruntime.Config{Type: d.NodeConfig.ContainerRuntime, Runner: d.exec})
+	runtime, err := cruntime.New(cruntime.Config{Type: d.NodeConfig.ContainerRuntime, Driver: d.NodeConfig.OCIBinary, Runner: d.exec})
 	if err != nil { // won't return error because:
 		// even though we can't stop the cotainers inside, we still wanna stop the minikube container itself
 		klog.Errorf("unable to get container runtime: %v", err)
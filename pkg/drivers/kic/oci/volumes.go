@@ -123,8 +123,8 @@ func allVolumesByLabel(ociBin string, label string) ([]string, error) {
 }
 
 // ExtractTarballToVolume runs a docker image imageName which extracts the tarball at tarballPath
-// to the volume named volumeName
-func ExtractTarballToVolume(ociBin string, tarballPath, volumeName, imageName string) error {
+// to the volume named volumeName, decompressing it with the tar flags tarFlags
+func ExtractTarballToVolume(ociBin string, tarballPath string, tarFlags []string, volumeName, imageName string) error {
 	cmdArgs := []string{"run", "--rm", "--entrypoint", "/usr/bin/tar"}
 	// Podman:
 	// when selinux setenforce is enforced, normal mount will lead to file permissions error (-?????????)
@@ -133,7 +133,9 @@ func ExtractTarballToVolume(ociBin string, tarballPath, volumeName, imageName st
 	if ociBin == Podman && runtime.GOOS == "linux" {
 		cmdArgs = append(cmdArgs, "--security-opt", "label=disable")
 	}
-	cmdArgs = append(cmdArgs, "-v", fmt.Sprintf("%s:/preloaded.tar:ro", tarballPath), "-v", fmt.Sprintf("%s:/extractDir", volumeName), imageName, "-I", "lz4", "-xf", "/preloaded.tar", "-C", "/extractDir")
+	cmdArgs = append(cmdArgs, "-v", fmt.Sprintf("%s:/preloaded.tar:ro", tarballPath), "-v", fmt.Sprintf("%s:/extractDir", volumeName), imageName)
+	cmdArgs = append(cmdArgs, tarFlags...)
+	cmdArgs = append(cmdArgs, "-xf", "/preloaded.tar", "-C", "/extractDir")
 	cmd := exec.Command(ociBin, cmdArgs...)
 	if _, err := runCmd(cmd); err != nil {
 		return err
@@ -67,4 +67,5 @@ type Config struct {
 	Subnet            string            // subnet to be used on kic cluster
 	ExtraArgs         []string          // a list of any extra option to pass to oci binary during creation time, for example --expose 8080...
 	ListenAddress     string            // IP Address to listen to
+	IsolatedBuilder   bool              // publish the port of the isolated builder of docker
 }
@@ -30,6 +30,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/kubeconfig"
 	"k8s.io/minikube/pkg/minikube/sysinit"
 	"k8s.io/minikube/pkg/minikube/vmpath"
@@ -62,7 +63,7 @@ type Config struct {
 // NewDriver returns a fully configured None driver
 func NewDriver(c Config) *Driver {
 	runner := command.NewExecRunner(true)
-	runtime, err := cruntime.New(cruntime.Config{Type: c.ContainerRuntime, Runner: runner})
+	runtime, err := cruntime.New(cruntime.Config{Type: c.ContainerRuntime, Driver: driver.None, Runner: runner})
 	// Libraries shouldn't panic, but there is no way for drivers to return error :(
 	if err != nil {
 		klog.Fatalf("unable to create container runtime: %v", err)
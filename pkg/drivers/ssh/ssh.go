@@ -47,6 +47,7 @@ type Driver struct {
 	*pkgdrivers.CommonDriver
 	EnginePort int
 	SSHKey     string
+	SSHSudo    string
 	runtime    cruntime.Manager
 	exec       command.Runner
 }
@@ -102,6 +103,16 @@ func (d *Driver) GetSSHKeyPath() string {
 	return d.SSHKeyPath
 }
 
+// Elevation returns how the commands which need root are run on the host
+func (d *Driver) Elevation() command.Elevation {
+	e, err := command.ParseElevation(d.SSHSudo)
+	if err != nil {
+		klog.Warningf("unable to parse ssh-sudo %q, using sudo: %v", d.SSHSudo, err)
+		return command.ElevationSudo
+	}
+	return e
+}
+
 // PreCreateCheck checks for correct privileges and dependencies
 func (d *Driver) PreCreateCheck() error {
 	if d.SSHKey != "" {
@@ -120,7 +131,7 @@ func (d *Driver) PreCreateCheck() error {
 		}
 	}
 
-	return nil
+	return cruntime.CheckElevation(d.exec)
 }
 
 // Create a host using the driver's config
@@ -19,6 +19,7 @@ package assets
 import (
 	"fmt"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/blang/semver/v4"
@@ -72,6 +73,11 @@ func (a *Addon) Name() string {
 	return a.addonName
 }
 
+// IsEnabledByDefault returns whether an Addon is enabled in the profiles which do not configure it
+func (a *Addon) IsEnabledByDefault() bool {
+	return a.enabled
+}
+
 // IsEnabled checks if an Addon is enabled for the given profile
 func (a *Addon) IsEnabled(cc *config.ClusterConfig) bool {
 	status, ok := cc.Addons[a.Name()]
@@ -789,6 +795,40 @@ func overrideDefaults(def, override map[string]string) map[string]string {
 	return mergeMaps(def, filterKeySpace(def, override))
 }
 
+// PersistedImages selects which images to use based on addon default images and previously persisted images, without the newly requested ones.
+func PersistedImages(addon *Addon, cc *config.ClusterConfig) (images, customRegistries map[string]string) {
+	addonDefaultImages := addon.Images
+	if addonDefaultImages == nil {
+		addonDefaultImages = make(map[string]string)
+	}
+	// filter by images map because registry map may omit default registry.
+	return overrideDefaults(addonDefaultImages, cc.CustomAddonImages), filterKeySpace(addonDefaultImages, cc.CustomAddonRegistries)
+}
+
+// ImageRefs returns the images of an addon, with the registries its manifests use, but without digests which can not be checked locally
+func ImageRefs(addon *Addon, cc *config.ClusterConfig, images, customRegistries map[string]string) []string {
+	refs := []string{}
+	for name, image := range images {
+		// the same precedence as the templates of the manifests
+		registry := addon.Registries[name]
+		if _, ok := cc.CustomAddonImages[name]; ok {
+			registry = ""
+		}
+		if r, ok := customRegistries[name]; ok && r != "" {
+			registry = r
+		} else if cc.KubernetesConfig.ImageRepository != "" {
+			registry = cc.KubernetesConfig.ImageRepository
+		}
+		ref := strings.Split(image, "@")[0]
+		if registry != "" {
+			ref = strings.TrimSuffix(registry, "/") + "/" + ref
+		}
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
 // SelectAndPersistImages selects which images to use based on addon default images, previously persisted images, and newly requested images - which are then persisted for future enables.
 func SelectAndPersistImages(addon *Addon, cc *config.ClusterConfig) (images, customRegistries map[string]string, _ error) {
 	addonDefaultImages := addon.Images
@@ -796,8 +836,8 @@ func SelectAndPersistImages(addon *Addon, cc *config.ClusterConfig) (images, cus
 		addonDefaultImages = make(map[string]string)
 	}
 
-	// Use previously configured custom images.
-	images = overrideDefaults(addonDefaultImages, cc.CustomAddonImages)
+	// Use previously configured custom images and registries.
+	images, customRegistries = PersistedImages(addon, cc)
 	if viper.IsSet(config.AddonImages) {
 		// Parse the AddonImages flag if present.
 		newImages := parseMapString(viper.GetString(config.AddonImages))
@@ -817,8 +857,6 @@ func SelectAndPersistImages(addon *Addon, cc *config.ClusterConfig) (images, cus
 		cc.CustomAddonImages = mergeMaps(cc.CustomAddonImages, newImages)
 	}
 
-	// Use previously configured custom registries.
-	customRegistries = filterKeySpace(addonDefaultImages, cc.CustomAddonRegistries) // filter by images map because registry map may omit default registry.
 	if viper.IsSet(config.AddonRegistries) {
 		// Parse the AddonRegistries flag if present.
 		customRegistries = parseMapString(viper.GetString(config.AddonRegistries))
@@ -16,7 +16,11 @@ limitations under the License.
 
 package assets
 
-import "testing"
+import (
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/config"
+)
 
 // mapsEqual returns true if and only if `a` contains all the same pairs as `b`.
 func mapsEqual(a, b map[string]string) bool {
@@ -142,3 +146,35 @@ func TestOverrideDefautls(t *testing.T) {
 		}
 	}
 }
+
+func TestImageRefs(t *testing.T) {
+	addon := &Addon{
+		Images:     map[string]string{"Dashboard": "kubernetesui/dashboard:v2.7.0@sha256:2e50", "Scraper": "kubernetesui/metrics-scraper:v1.0.8"},
+		Registries: map[string]string{"Dashboard": "docker.io", "Scraper": "docker.io"},
+	}
+	var tests = []struct {
+		description string
+		cc          config.ClusterConfig
+		custom      map[string]string
+		want        []string
+	}{
+		{"defaults", config.ClusterConfig{}, nil, []string{"docker.io/kubernetesui/dashboard:v2.7.0", "docker.io/kubernetesui/metrics-scraper:v1.0.8"}},
+		{"custom registry", config.ClusterConfig{}, map[string]string{"Scraper": "mirror.local:5000/"}, []string{"docker.io/kubernetesui/dashboard:v2.7.0", "mirror.local:5000/kubernetesui/metrics-scraper:v1.0.8"}},
+		{"image repository", config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{ImageRepository: "mirror.local"}}, nil, []string{"mirror.local/kubernetesui/dashboard:v2.7.0", "mirror.local/kubernetesui/metrics-scraper:v1.0.8"}},
+		{"custom image", config.ClusterConfig{CustomAddonImages: map[string]string{"Scraper": "kubernetesui/metrics-scraper:v1.0.8"}}, nil, []string{"docker.io/kubernetesui/dashboard:v2.7.0", "kubernetesui/metrics-scraper:v1.0.8"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			got := ImageRefs(addon, &tc.cc, addon.Images, tc.custom)
+			if len(got) != len(tc.want) {
+				t.Fatalf("ImageRefs() = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("ImageRefs() = %v, want %v", got, tc.want)
+					break
+				}
+			}
+		})
+	}
+}
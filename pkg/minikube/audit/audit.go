@@ -28,6 +28,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/version"
@@ -55,6 +56,9 @@ func args() string {
 	return strings.Join(os.Args[2:], " ")
 }
 
+// commandID is the ID of the running command in the audit log, empty if it is not audited
+var commandID string
+
 // Log details about the executed command.
 func LogCommandStart() (string, error) {
 	if !shouldLog() {
@@ -65,6 +69,7 @@ func LogCommandStart() (string, error) {
 	if err := appendToLog(r); err != nil {
 		return "", err
 	}
+	commandID = r.id
 	return r.id, nil
 }
 
@@ -72,6 +77,12 @@ func LogCommandEnd(id string) error {
 	if id == "" {
 		return nil
 	}
+	logMu.Lock()
+	commandID = ""
+	if err := trimImageLog(); err != nil {
+		klog.Warningf("failed to trim the image log: %v", err)
+	}
+	logMu.Unlock()
 	if err := openAuditLog(); err != nil {
 		return err
 	}
@@ -0,0 +1,312 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/viper"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/localpath"
+	"k8s.io/minikube/pkg/minikube/out/register"
+)
+
+// ImagePreloadCheck is the action of checking whether the images of Kubernetes are preloaded,
+// recorded besides the pull, load and preload-copy actions of register
+const ImagePreloadCheck = "preload-check"
+
+// The sources of the images of an image operation
+const (
+	// ImageFromCache is an image loaded from the cache or the preload of minikube
+	ImageFromCache = "cache"
+	// ImageFromNetwork is an image pulled from its registry
+	ImageFromNetwork = "network"
+)
+
+// imageRowType is the cloud events type of the rows of image operations
+const imageRowType = "io.k8s.sigs.minikube.audit.image"
+
+// maxImageEntries is how many image operations the image log keeps. They are logged apart from the commands,
+// as a start may log hundreds of them, which would push the commands out of the audit log.
+const maxImageEntries = 10000
+
+// imageOverrideFilename overrides the default image log filename, used for testing purposes
+var imageOverrideFilename string
+
+// imageLogPath returns the path of the log of the image operations
+func imageLogPath() string {
+	if imageOverrideFilename != "" {
+		return imageOverrideFilename
+	}
+	return localpath.ImageAuditLog()
+}
+
+// ImageOp is an image operation of a command, such as pulling an image or copying the preload into the node.
+type ImageOp struct {
+	Action   string
+	Image    string
+	Source   string
+	Bytes    int64
+	Duration time.Duration
+}
+
+// imageRow is the log of an image operation of a command.
+type imageRow struct {
+	ImageOp
+	commandID string
+	profile   string
+	time      string
+}
+
+// Type returns the cloud events compatible type of this struct.
+func (e *imageRow) Type() string {
+	return imageRowType
+}
+
+// toMap combines fields into a string map,
+// to be used when converting to JSON Cloud Event format.
+func (e *imageRow) toMap() map[string]string {
+	return map[string]string{
+		"commandID": e.commandID,
+		"profile":   e.profile,
+		"time":      e.time,
+		"action":    e.Action,
+		"image":     e.Image,
+		"source":    e.Source,
+		"bytes":     strconv.FormatInt(e.Bytes, 10),
+		"duration":  fmt.Sprintf("%.3f", e.Duration.Seconds()),
+	}
+}
+
+// imageRowFromMap converts the map values to an image row,
+// to be used when converting from JSON Cloud Event format.
+func imageRowFromMap(m map[string]string) imageRow {
+	b, err := strconv.ParseInt(m["bytes"], 10, 64)
+	if err != nil {
+		klog.Warningf("invalid bytes of image row: %q", m["bytes"])
+	}
+	s, err := strconv.ParseFloat(m["duration"], 64)
+	if err != nil {
+		klog.Warningf("invalid duration of image row: %q", m["duration"])
+	}
+	return imageRow{
+		ImageOp: ImageOp{
+			Action:   m["action"],
+			Image:    m["image"],
+			Source:   m["source"],
+			Bytes:    b,
+			Duration: time.Duration(s * float64(time.Second)),
+		},
+		commandID: m["commandID"],
+		profile:   m["profile"],
+		time:      m["time"],
+	}
+}
+
+// RecordingImages returns whether the image operations of the running command are logged.
+func RecordingImages() bool {
+	logMu.Lock()
+	defer logMu.Unlock()
+	return commandID != ""
+}
+
+// RecordImage logs an image operation of the running command, unless the command is not audited.
+func RecordImage(op ImageOp) {
+	logMu.Lock()
+	defer logMu.Unlock()
+	if commandID == "" {
+		return
+	}
+	r := &imageRow{ImageOp: op, commandID: commandID, profile: viper.GetString(config.ProfileName), time: time.Now().Format(constants.TimeFormat)}
+	if err := appendToImageLog(r); err != nil {
+		klog.Warningf("failed to log image operation: %v", err)
+	}
+}
+
+// appendToImageLog appends the row to the image log, with logMu held.
+func appendToImageLog(r *imageRow) error {
+	bs, err := register.CloudEvent(r, r.toMap()).MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("error marshalling event: %v", err)
+	}
+	f, err := os.OpenFile(imageLogPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open the image log: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(string(bs) + "\n"); err != nil {
+		return fmt.Errorf("unable to write to image log: %v", err)
+	}
+	return nil
+}
+
+// readLogLines returns the lines of a log file, none if it does not exist
+func readLogLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	return lines, s.Err()
+}
+
+// trimImageLog keeps the last maxImageEntries image operations in the image log, with logMu held.
+func trimImageLog() error {
+	lines, err := readLogLines(imageLogPath())
+	if err != nil || len(lines) <= maxImageEntries {
+		return err
+	}
+	lines = lines[len(lines)-maxImageEntries:]
+	return os.WriteFile(imageLogPath(), []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// ImageStart sums the image operations of a start.
+type ImageStart struct {
+	Profile      string
+	StartTime    string
+	Pulls        int
+	PullTime     time.Duration
+	CacheBytes   int64
+	NetworkBytes int64
+	Hits         int
+	Misses       int
+}
+
+// add sums an image operation, where loading from the cache is a hit and pulling a miss
+func (s *ImageStart) add(op ImageOp) {
+	if op.Source == ImageFromCache {
+		s.Hits++
+		s.CacheBytes += op.Bytes
+		return
+	}
+	s.Misses++
+	s.NetworkBytes += op.Bytes
+	if op.Action == register.ImagePull {
+		s.Pulls++
+		s.PullTime += op.Duration
+	}
+}
+
+// ImageReport sums the image operations of the last starts, oldest first.
+type ImageReport struct {
+	Starts []ImageStart
+}
+
+// ImageStats reads the image operations of the last n starts from the log file.
+func ImageStats(lastNStarts int) (*ImageReport, error) {
+	if lastNStarts <= 0 {
+		return nil, fmt.Errorf("last n starts must be 1 or greater")
+	}
+	logs, err := readLogLines(auditPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from audit file: %v", err)
+	}
+	rows, err := logsToRows(logs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert logs to rows: %v", err)
+	}
+	logMu.Lock()
+	logs, err = readLogLines(imageLogPath())
+	logMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from image log: %v", err)
+	}
+	imageRows, err := logsToRows(logs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert image logs to rows: %v", err)
+	}
+
+	starts := map[string]*ImageStart{}
+	order := []string{}
+	for _, r := range rows {
+		if r.command != "start" || r.id == "" {
+			continue
+		}
+		starts[r.id] = &ImageStart{Profile: r.profile, StartTime: r.startTime}
+		order = append(order, r.id)
+	}
+	if len(order) > lastNStarts {
+		order = order[len(order)-lastNStarts:]
+	}
+	for _, r := range imageRows {
+		if r.TypeField != imageRowType {
+			continue
+		}
+		ir := imageRowFromMap(r.Data)
+		if st, ok := starts[ir.commandID]; ok {
+			st.add(ir.ImageOp)
+		}
+	}
+	report := &ImageReport{}
+	for _, id := range order {
+		report.Starts = append(report.Starts, *starts[id])
+	}
+	return report, nil
+}
+
+// Total sums the image operations of all the starts of the report.
+func (ir *ImageReport) Total() ImageStart {
+	t := ImageStart{Profile: "Total"}
+	for _, s := range ir.Starts {
+		t.Pulls += s.Pulls
+		t.PullTime += s.PullTime
+		t.CacheBytes += s.CacheBytes
+		t.NetworkBytes += s.NetworkBytes
+		t.Hits += s.Hits
+		t.Misses += s.Misses
+	}
+	return t
+}
+
+// ASCIITable creates a formatted table of the starts of the report, followed by their total.
+func (ir *ImageReport) ASCIITable() string {
+	c := [][]string{}
+	fields := func(s ImageStart) []string {
+		return []string{s.Profile, s.StartTime, strconv.Itoa(s.Pulls), s.PullTime.Round(time.Millisecond).String(),
+			units.HumanSize(float64(s.CacheBytes)), units.HumanSize(float64(s.NetworkBytes)), strconv.Itoa(s.Hits), strconv.Itoa(s.Misses)}
+	}
+	for _, s := range ir.Starts {
+		c = append(c, fields(s))
+	}
+	b := new(bytes.Buffer)
+	t := tablewriter.NewWriter(b)
+	t.SetHeader([]string{"Profile", "Start Time", "Pulls", "Pull Time", "From Cache", "From Network", "Cache Hits", "Cache Misses"})
+	t.SetFooter(fields(ir.Total()))
+	t.SetAutoFormatHeaders(false)
+	t.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	t.SetCenterSeparator("|")
+	t.AppendBulk(c)
+	t.Render()
+	return b.String()
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/out/register"
+)
+
+func TestImageRow(t *testing.T) {
+	r := &imageRow{
+		ImageOp:   ImageOp{Action: register.ImagePull, Image: "busybox:latest", Source: ImageFromNetwork, Bytes: 4261550, Duration: 1500 * time.Millisecond},
+		commandID: "9b7593cb-fbec-49e5-a3ce-bdc2d0bfb208",
+		profile:   "mini1",
+		time:      "Wed, 03 Feb 2021 15:30:40 MST",
+	}
+
+	t.Run("Type", func(t *testing.T) {
+		if got := r.Type(); got != "io.k8s.sigs.minikube.audit.image" {
+			t.Errorf("Type() = %s; want io.k8s.sigs.minikube.audit.image", got)
+		}
+	})
+
+	t.Run("toMap", func(t *testing.T) {
+		want := map[string]string{
+			"commandID": "9b7593cb-fbec-49e5-a3ce-bdc2d0bfb208",
+			"profile":   "mini1",
+			"time":      "Wed, 03 Feb 2021 15:30:40 MST",
+			"action":    "pull",
+			"image":     "busybox:latest",
+			"source":    "network",
+			"bytes":     "4261550",
+			"duration":  "1.500",
+		}
+		if diff := cmp.Diff(want, r.toMap()); diff != "" {
+			t.Errorf("toMap() diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("roundtrip", func(t *testing.T) {
+		ce := register.CloudEvent(r, r.toMap())
+		bs, err := ce.MarshalJSON()
+		if err != nil {
+			t.Fatalf("failed to marshal event: %v", err)
+		}
+		rows, err := logsToRows([]string{string(bs)})
+		if err != nil {
+			t.Fatalf("failed to convert logs to rows: %v", err)
+		}
+		if rows[0].TypeField != imageRowType {
+			t.Errorf("type = %s; want %s", rows[0].TypeField, imageRowType)
+		}
+		// the command rows are matched by id, which image rows must not have
+		if rows[0].id != "" {
+			t.Errorf("image row has the id %q of a command", rows[0].id)
+		}
+		got := imageRowFromMap(rows[0].Data)
+		if diff := cmp.Diff(*r, got, cmp.AllowUnexported(imageRow{})); diff != "" {
+			t.Errorf("imageRowFromMap() diff (-want +got):\n%s", diff)
+		}
+		if _, err := json.Marshal(rows[0]); err != nil {
+			t.Errorf("failed to marshal the row back: %v", err)
+		}
+	})
+}
+
+func TestImageStats(t *testing.T) {
+	f, err := os.CreateTemp("", "audit.json")
+	if err != nil {
+		t.Fatalf("failed creating temporary file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	auditOverrideFilename = f.Name()
+	defer func() { auditOverrideFilename = "" }()
+	imageOverrideFilename = f.Name() + ".images"
+	defer func() {
+		os.Remove(imageOverrideFilename)
+		imageOverrideFilename = ""
+	}()
+
+	s := `{"data":{"args":"-p mini1","command":"start","endTime":"Wed, 03 Feb 2021 15:33:05 MST","id":"start-1","profile":"mini1","startTime":"Wed, 03 Feb 2021 15:30:33 MST","user":"user1"},"datacontenttype":"application/json","id":"9b7593cb-fbec-49e5-a3ce-bdc2d0bfb208","source":"https://minikube.sigs.k8s.io/","specversion":"1.0","type":"io.k8s.sigs.minikube.audit"}
+{"data":{"args":"--user user2","command":"logs","endTime":"Tue, 02 Feb 2021 16:46:20 MST","id":"logs-1","profile":"minikube","startTime":"Tue, 02 Feb 2021 16:46:00 MST","user":"user2"},"datacontenttype":"application/json","id":"fec03227-2484-48b6-880a-88fd010b5efd","source":"https://minikube.sigs.k8s.io/","specversion":"1.0","type":"io.k8s.sigs.minikube.audit"}
+{"data":{"args":"-p mini1","command":"start","endTime":"Thu, 04 Feb 2021 15:33:05 MST","id":"start-2","profile":"mini1","startTime":"Thu, 04 Feb 2021 15:30:33 MST","user":"user1"},"datacontenttype":"application/json","id":"0d4a17a4-8d9e-4ad0-8d47-8a3c0e2b7e51","source":"https://minikube.sigs.k8s.io/","specversion":"1.0","type":"io.k8s.sigs.minikube.audit"}
+`
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatalf("failed writing to file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("failed closing file: %v", err)
+	}
+
+	ops := map[string][]ImageOp{
+		"start-1": {
+			{Action: ImagePreloadCheck, Source: ImageFromNetwork, Duration: 100 * time.Millisecond},
+			{Action: register.ImagePull, Image: "busybox", Source: ImageFromNetwork, Bytes: 1000, Duration: 2 * time.Second},
+			{Action: register.ImagePull, Image: "nginx", Source: ImageFromNetwork, Bytes: 3000, Duration: 3 * time.Second},
+		},
+		"start-2": {
+			{Action: ImagePreloadCheck, Source: ImageFromCache, Duration: 100 * time.Millisecond},
+			{Action: register.ImagePreloadCopy, Image: "preloaded.tar.lz4", Source: ImageFromCache, Bytes: 5000, Duration: time.Second},
+			{Action: register.ImageLoad, Image: "busybox", Source: ImageFromCache, Bytes: 1000, Duration: time.Second},
+		},
+	}
+	for _, id := range []string{"start-1", "start-2"} {
+		commandID = id
+		for _, op := range ops[id] {
+			RecordImage(op)
+		}
+	}
+	commandID = ""
+
+	t.Run("stats", func(t *testing.T) {
+		r, err := ImageStats(10)
+		if err != nil {
+			t.Fatalf("ImageStats: %v", err)
+		}
+		want := []ImageStart{
+			{Profile: "mini1", StartTime: "Wed, 03 Feb 2021 15:30:33 MST", Pulls: 2, PullTime: 5 * time.Second, NetworkBytes: 4000, Misses: 3},
+			{Profile: "mini1", StartTime: "Thu, 04 Feb 2021 15:30:33 MST", CacheBytes: 6000, Hits: 3},
+		}
+		if diff := cmp.Diff(want, r.Starts); diff != "" {
+			t.Errorf("ImageStats() diff (-want +got):\n%s", diff)
+		}
+		total := r.Total()
+		if total.CacheBytes != 6000 || total.NetworkBytes != 4000 || total.Hits != 3 || total.Misses != 3 {
+			t.Errorf("Total() = %+v", total)
+		}
+		if !strings.Contains(r.ASCIITable(), "Total") {
+			t.Errorf("ASCIITable() has no total:\n%s", r.ASCIITable())
+		}
+	})
+
+	t.Run("last start", func(t *testing.T) {
+		r, err := ImageStats(1)
+		if err != nil {
+			t.Fatalf("ImageStats: %v", err)
+		}
+		if len(r.Starts) != 1 || r.Starts[0].StartTime != "Thu, 04 Feb 2021 15:30:33 MST" {
+			t.Errorf("ImageStats(1) = %+v, want the last start", r.Starts)
+		}
+	})
+
+	t.Run("report", func(t *testing.T) {
+		r, err := Report(10)
+		if err != nil {
+			t.Fatalf("Report: %v", err)
+		}
+		if len(r.rows) != 3 {
+			t.Errorf("report has %d rows, want the 3 commands, which the image operations are logged apart from", len(r.rows))
+		}
+	})
+
+	t.Run("not audited", func(t *testing.T) {
+		RecordImage(ImageOp{Action: register.ImagePull, Source: ImageFromNetwork})
+		r, err := ImageStats(10)
+		if err != nil {
+			t.Fatalf("ImageStats: %v", err)
+		}
+		if r.Total().Pulls != 2 {
+			t.Errorf("an image operation was logged without an audited command")
+		}
+	})
+}
+
+func TestTrimImageLog(t *testing.T) {
+	f, err := os.CreateTemp("", "audit_images.json")
+	if err != nil {
+		t.Fatalf("failed creating temporary file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if err := f.Close(); err != nil {
+		t.Fatalf("failed closing file: %v", err)
+	}
+	imageOverrideFilename = f.Name()
+	defer func() { imageOverrideFilename = "" }()
+
+	for i := 0; i < maxImageEntries+10; i++ {
+		r := &imageRow{ImageOp: ImageOp{Action: register.ImagePull, Image: fmt.Sprintf("image-%d", i), Source: ImageFromNetwork}, commandID: "start-1"}
+		if err := appendToImageLog(r); err != nil {
+			t.Fatalf("appendToImageLog: %v", err)
+		}
+	}
+	if err := trimImageLog(); err != nil {
+		t.Fatalf("trimImageLog: %v", err)
+	}
+	lines, err := readLogLines(f.Name())
+	if err != nil {
+		t.Fatalf("readLogLines: %v", err)
+	}
+	if len(lines) != maxImageEntries {
+		t.Fatalf("image log has %d rows after trimming, want %d", len(lines), maxImageEntries)
+	}
+	rows, err := logsToRows(lines[:1])
+	if err != nil {
+		t.Fatalf("logsToRows: %v", err)
+	}
+	if got := imageRowFromMap(rows[0].Data).Image; got != "image-10" {
+		t.Errorf("the first row left is %s, want image-10", got)
+	}
+}
@@ -19,6 +19,7 @@ package audit
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/localpath"
@@ -31,6 +32,9 @@ var (
 
 	// auditOverrideFilename overrides the default audit log filename, used for testing purposes
 	auditOverrideFilename string
+
+	// logMu serializes the writes to the image log, as image operations are logged concurrently
+	logMu sync.Mutex
 )
 
 // openAuditLog opens the audit log file or creates it if it doesn't exist.
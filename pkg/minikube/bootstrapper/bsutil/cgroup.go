@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bsutil
+
+import (
+	"regexp"
+)
+
+// KubeletConfigFile is where kubeadm saves the kubelet configuration of the node
+const KubeletConfigFile = "/var/lib/kubelet/config.yaml"
+
+// systemdDriver is the cgroup driver forced by --force-systemd
+const systemdDriver = "systemd"
+
+// cgroupDriverRe matches the cgroup driver of a kubelet configuration
+var cgroupDriverRe = regexp.MustCompile(`(?m)^(cgroupDriver:[ \t]*)(\S*)`)
+
+// CgroupDriverFix is how to align the cgroup drivers of the container runtime and kubelet
+type CgroupDriverFix struct {
+	// Driver is the cgroup driver both have to use
+	Driver string
+	// Runtime is whether the container runtime has to be switched to Driver
+	Runtime bool
+	// Kubelet is whether the kubelet configuration has to be switched to Driver
+	Kubelet bool
+}
+
+// KubeletCgroupDriver returns the cgroup driver of a kubelet configuration, or "" if it sets none
+func KubeletCgroupDriver(cfg string) string {
+	m := cgroupDriverRe.FindStringSubmatch(cfg)
+	if m == nil {
+		return ""
+	}
+	return m[2]
+}
+
+// SetKubeletCgroupDriver returns the kubelet configuration with its cgroup driver set to driver
+func SetKubeletCgroupDriver(cfg string, driver string) string {
+	return cgroupDriverRe.ReplaceAllString(cfg, "${1}"+driver)
+}
+
+// FixCgroupDrivers returns how to align the cgroup drivers of the runtime and kubelet, kubelet having none if it is not configured yet.
+// Kubelet follows the runtime, unless systemd is forced, in which case the runtime is switched to systemd first.
+func FixCgroupDrivers(runtimeDriver string, kubeletDriver string, forceSystemd bool) CgroupDriverFix {
+	fix := CgroupDriverFix{Driver: runtimeDriver}
+	if forceSystemd && runtimeDriver != systemdDriver {
+		fix = CgroupDriverFix{Driver: systemdDriver, Runtime: true}
+	}
+	fix.Kubelet = kubeletDriver != "" && kubeletDriver != fix.Driver
+	return fix
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bsutil
+
+import (
+	"testing"
+)
+
+func TestFixCgroupDrivers(t *testing.T) {
+	tests := []struct {
+		runtime, kubelet string
+		force            bool
+		want             CgroupDriverFix
+	}{
+		{runtime: "cgroupfs", kubelet: "cgroupfs", want: CgroupDriverFix{Driver: "cgroupfs"}},
+		{runtime: "cgroupfs", kubelet: "systemd", want: CgroupDriverFix{Driver: "cgroupfs", Kubelet: true}},
+		{runtime: "systemd", kubelet: "cgroupfs", want: CgroupDriverFix{Driver: "systemd", Kubelet: true}},
+		{runtime: "systemd", kubelet: "systemd", want: CgroupDriverFix{Driver: "systemd"}},
+		{runtime: "cgroupfs", kubelet: "cgroupfs", force: true, want: CgroupDriverFix{Driver: "systemd", Runtime: true, Kubelet: true}},
+		{runtime: "cgroupfs", kubelet: "systemd", force: true, want: CgroupDriverFix{Driver: "systemd", Runtime: true}},
+		{runtime: "systemd", kubelet: "cgroupfs", force: true, want: CgroupDriverFix{Driver: "systemd", Kubelet: true}},
+		{runtime: "systemd", kubelet: "systemd", force: true, want: CgroupDriverFix{Driver: "systemd"}},
+		{runtime: "cgroupfs", kubelet: "", want: CgroupDriverFix{Driver: "cgroupfs"}},
+		{runtime: "cgroupfs", kubelet: "", force: true, want: CgroupDriverFix{Driver: "systemd", Runtime: true}},
+	}
+	for _, tc := range tests {
+		got := FixCgroupDrivers(tc.runtime, tc.kubelet, tc.force)
+		if got != tc.want {
+			t.Errorf("FixCgroupDrivers(%q, %q, %t) = %+v, want %+v", tc.runtime, tc.kubelet, tc.force, got, tc.want)
+		}
+	}
+}
+
+func TestKubeletCgroupDriver(t *testing.T) {
+	cfg := `apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+cgroupDriver: systemd
+clusterDomain: "cluster.local"
+`
+	if got := KubeletCgroupDriver(cfg); got != "systemd" {
+		t.Errorf("KubeletCgroupDriver() = %q, want %q", got, "systemd")
+	}
+	want := `apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+cgroupDriver: cgroupfs
+clusterDomain: "cluster.local"
+`
+	if got := SetKubeletCgroupDriver(cfg, "cgroupfs"); got != want {
+		t.Errorf("SetKubeletCgroupDriver() = %q, want %q", got, want)
+	}
+	if got := KubeletCgroupDriver("kind: KubeletConfiguration\n"); got != "" {
+		t.Errorf("KubeletCgroupDriver() without driver = %q, want none", got)
+	}
+}
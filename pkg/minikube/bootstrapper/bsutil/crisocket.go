@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bsutil
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+const (
+	// KubeletFlagsFile is where kubeadm saves the kubelet flags of the node
+	KubeletFlagsFile = "/var/lib/kubelet/kubeadm-flags.env"
+	// CRISocketAnnotation is the node annotation where kubeadm records the CRI socket of the node
+	CRISocketAnnotation = "kubeadm.alpha.kubernetes.io/cri-socket"
+)
+
+// endpointFlagRe matches the kubelet flags pointing at the CRI socket
+var endpointFlagRe = regexp.MustCompile(`(--(?:container-runtime-endpoint|image-service-endpoint)=)[^\s"]+`)
+
+// MigrateKubeletFlags points the CRI endpoints of the kubelet flags saved by kubeadm at socket, returning the flags and whether they changed.
+// Flags saved with dockershim have no endpoint, so one is added unless socket is dockershim's.
+func MigrateKubeletFlags(env string, socket string) (string, bool) {
+	url := cruntime.SocketURL(socket)
+	migrated := endpointFlagRe.ReplaceAllString(env, "${1}"+url)
+	if !endpointFlagRe.MatchString(env) && cruntime.SocketFile(socket) != cruntime.InternalDockerCRISocket {
+		migrated = strings.Replace(env, `KUBELET_KUBEADM_ARGS="`, `KUBELET_KUBEADM_ARGS="--container-runtime-endpoint=`+url+` `, 1)
+	}
+	return migrated, migrated != env
+}
+
+// CRISocketAnnotationValue returns the CRI socket as kubeadm records it on the node objects of a Kubernetes version
+func CRISocketAnnotationValue(socket string, kv semver.Version) string {
+	if kv.GTE(dockershimRemoved) {
+		return cruntime.SocketURL(socket)
+	}
+	return cruntime.SocketFile(socket)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bsutil
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+func TestCRISocketUpgrade(t *testing.T) {
+	tests := []struct {
+		description    string
+		from, to       string
+		env            string
+		wantEnv        string
+		wantAnnotation string
+	}{
+		{
+			description:    "docker 1.23 to 1.24",
+			from:           "1.23.8",
+			to:             "1.24.3",
+			env:            `KUBELET_KUBEADM_ARGS="--network-plugin=cni --pod-infra-container-image=k8s.gcr.io/pause:3.6"`,
+			wantEnv:        `KUBELET_KUBEADM_ARGS="--container-runtime-endpoint=unix:///var/run/cri-dockerd.sock --network-plugin=cni --pod-infra-container-image=k8s.gcr.io/pause:3.6"`,
+			wantAnnotation: "unix:///var/run/cri-dockerd.sock",
+		},
+		{
+			description:    "docker 1.23 with an explicit dockershim socket to 1.24",
+			from:           "1.23.8",
+			to:             "1.24.3",
+			env:            `KUBELET_KUBEADM_ARGS="--container-runtime=remote --container-runtime-endpoint=/var/run/dockershim.sock --pod-infra-container-image=k8s.gcr.io/pause:3.6"`,
+			wantEnv:        `KUBELET_KUBEADM_ARGS="--container-runtime=remote --container-runtime-endpoint=unix:///var/run/cri-dockerd.sock --pod-infra-container-image=k8s.gcr.io/pause:3.6"`,
+			wantAnnotation: "unix:///var/run/cri-dockerd.sock",
+		},
+		{
+			description:    "docker 1.25 to 1.26",
+			from:           "1.25.3",
+			to:             "1.26.0",
+			env:            `KUBELET_KUBEADM_ARGS="--container-runtime=remote --container-runtime-endpoint=unix:///var/run/cri-dockerd.sock --pod-infra-container-image=registry.k8s.io/pause:3.8"`,
+			wantEnv:        `KUBELET_KUBEADM_ARGS="--container-runtime=remote --container-runtime-endpoint=unix:///var/run/cri-dockerd.sock --pod-infra-container-image=registry.k8s.io/pause:3.8"`,
+			wantAnnotation: "unix:///var/run/cri-dockerd.sock",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			from, to := semver.MustParse(tc.from), semver.MustParse(tc.to)
+			old := cruntime.CRISocket("docker", "", from)
+			socket := cruntime.CRISocket("docker", old, to)
+			if got := CRISocketAnnotationValue(socket, to); got != tc.wantAnnotation {
+				t.Errorf("CRISocketAnnotationValue(%s, %s) = %q, want %q", socket, to, got, tc.wantAnnotation)
+			}
+			env, changed := MigrateKubeletFlags(tc.env, socket)
+			if env != tc.wantEnv {
+				t.Errorf("MigrateKubeletFlags() = %s, want %s", env, tc.wantEnv)
+			}
+			if changed != (tc.env != tc.wantEnv) {
+				t.Errorf("MigrateKubeletFlags() changed = %t", changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bsutil
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/util"
+)
+
+// dockershimRemoved is the first Kubernetes version without dockershim, which docker reaches through cri-dockerd from then on
+var dockershimRemoved = semver.MustParse("1.24.0-alpha.0")
+
+var (
+	kubernetesVersionRe = regexp.MustCompile(`(?m)^kubernetesVersion:\s*"?([^\s"]+)"?`)
+	criSocketRe         = regexp.MustCompile(`(?m)^\s*criSocket:\s*"?([^\s"]+)"?`)
+)
+
+// Deployment is a deployment of the cluster
+type Deployment struct {
+	Namespace string
+	Name      string
+}
+
+// dockershimAddonDeployments are the deployments of the addons which show no metrics after leaving dockershim until they restart, by addon
+var dockershimAddonDeployments = map[string][]Deployment{
+	"metrics-server": {{Namespace: "kube-system", Name: "metrics-server"}},
+	"dashboard": {
+		{Namespace: "kubernetes-dashboard", Name: "kubernetes-dashboard"},
+		{Namespace: "kubernetes-dashboard", Name: "dashboard-metrics-scraper"},
+	},
+}
+
+// DockershimMigration is what changes as a cluster of the docker runtime is upgraded from dockershim, before Kubernetes 1.24, to cri-dockerd
+type DockershimMigration struct {
+	// From and To are the Kubernetes versions before and after the upgrade
+	From semver.Version
+	To   semver.Version
+	// OldSocket and NewSocket are the CRI sockets recorded on the node objects before and after the upgrade
+	OldSocket string
+	NewSocket string
+	// Restart are the deployments of the enabled addons which need a restart
+	Restart []Deployment
+}
+
+// PlanDockershimMigration returns the migration of an upgrade from oldConf, the kubeadm configuration the previous start saved on the node,
+// to cfg, newSocket being the CRI socket the runtime serves for the new version. It returns nil if the upgrade does not leave dockershim.
+func PlanDockershimMigration(oldConf string, cfg config.ClusterConfig, newSocket string) (*DockershimMigration, error) {
+	if cfg.KubernetesConfig.ContainerRuntime != "docker" {
+		return nil, nil
+	}
+	m := kubernetesVersionRe.FindStringSubmatch(oldConf)
+	if m == nil {
+		return nil, errors.New("no kubernetesVersion in the previous kubeadm configuration")
+	}
+	from, err := util.ParseKubernetesVersion(m[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the previous Kubernetes version")
+	}
+	to, err := util.ParseKubernetesVersion(cfg.KubernetesConfig.KubernetesVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing the Kubernetes version")
+	}
+	if from.GTE(dockershimRemoved) || to.LT(dockershimRemoved) {
+		return nil, nil
+	}
+
+	// configurations without a socket were written for the default one of docker
+	oldSocket := cruntime.DefaultCRISocket("docker", from)
+	if m := criSocketRe.FindStringSubmatch(oldConf); m != nil {
+		oldSocket = m[1]
+	}
+	mig := &DockershimMigration{
+		From:      from,
+		To:        to,
+		OldSocket: CRISocketAnnotationValue(oldSocket, from),
+		NewSocket: CRISocketAnnotationValue(newSocket, to),
+		Restart:   []Deployment{},
+	}
+	addons := []string{}
+	for name := range dockershimAddonDeployments {
+		if cfg.Addons[name] {
+			addons = append(addons, name)
+		}
+	}
+	sort.Strings(addons)
+	for _, name := range addons {
+		mig.Restart = append(mig.Restart, dockershimAddonDeployments[name]...)
+	}
+	return mig, nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+func TestPlanDockershimMigration(t *testing.T) {
+	tests := []struct {
+		description string
+		// fixture is the kubeadm configuration of the previous start, in testdata
+		fixture   string
+		runtime   string
+		to        string
+		addons    map[string]bool
+		want      *DockershimMigration
+		wantError bool
+	}{
+		{
+			description: "docker 1.23 to 1.24",
+			fixture:     "v1.23/default.yaml",
+			runtime:     "docker",
+			to:          "v1.24.3",
+			addons:      map[string]bool{"metrics-server": true, "dashboard": true, "ingress": true},
+			want: &DockershimMigration{
+				From:      semver.MustParse("1.23.0"),
+				To:        semver.MustParse("1.24.3"),
+				OldSocket: "/var/run/dockershim.sock",
+				NewSocket: "unix:///var/run/cri-dockerd.sock",
+				Restart: []Deployment{
+					{Namespace: "kubernetes-dashboard", Name: "kubernetes-dashboard"},
+					{Namespace: "kubernetes-dashboard", Name: "dashboard-metrics-scraper"},
+					{Namespace: "kube-system", Name: "metrics-server"},
+				},
+			},
+		},
+		{
+			description: "docker 1.23 to 1.25 without addons",
+			fixture:     "v1.23/default.yaml",
+			runtime:     "docker",
+			to:          "v1.25.0",
+			addons:      map[string]bool{"metrics-server": false},
+			want: &DockershimMigration{
+				From:      semver.MustParse("1.23.0"),
+				To:        semver.MustParse("1.25.0"),
+				OldSocket: "/var/run/dockershim.sock",
+				NewSocket: "unix:///var/run/cri-dockerd.sock",
+				Restart:   []Deployment{},
+			},
+		},
+		{
+			description: "docker 1.22 to 1.23",
+			fixture:     "v1.22/default.yaml",
+			runtime:     "docker",
+			to:          "v1.23.0",
+		},
+		{
+			description: "docker 1.24 to 1.25",
+			fixture:     "v1.24/default.yaml",
+			runtime:     "docker",
+			to:          "v1.25.0",
+		},
+		{
+			description: "containerd 1.23 to 1.24",
+			fixture:     "v1.23/containerd.yaml",
+			runtime:     "containerd",
+			to:          "v1.24.3",
+		},
+		{
+			description: "no version",
+			runtime:     "docker",
+			to:          "v1.24.3",
+			wantError:   true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			oldConf := ""
+			if tc.fixture != "" {
+				data, err := os.ReadFile(filepath.Join("testdata", tc.fixture))
+				if err != nil {
+					t.Fatalf("reading fixture: %v", err)
+				}
+				oldConf = string(data)
+			}
+			cfg := config.ClusterConfig{
+				KubernetesConfig: config.KubernetesConfig{KubernetesVersion: tc.to, ContainerRuntime: tc.runtime},
+				Addons:           tc.addons,
+			}
+			got, err := PlanDockershimMigration(oldConf, cfg, cruntime.ExternalDockerCRISocket)
+			if tc.wantError {
+				if err == nil {
+					t.Errorf("PlanDockershimMigration() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PlanDockershimMigration: %v", err)
+			}
+			if tc.want == nil {
+				if got != nil {
+					t.Errorf("PlanDockershimMigration() = %+v, want no migration", got)
+				}
+				return
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("PlanDockershimMigration() returned diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
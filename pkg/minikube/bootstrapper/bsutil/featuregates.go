@@ -67,3 +67,16 @@ func parseFeatureArgs(featureGates string) (map[string]bool, string, error) {
 	componentFeatureArgs = strings.TrimRight(componentFeatureArgs, ",")
 	return kubeadmFeatureArgs, componentFeatureArgs, nil
 }
+
+// FeatureGateEnabled returns whether the feature gates, as given to --feature-gates, enable a feature
+func FeatureGateEnabled(featureGates string, name string) bool {
+	for _, s := range strings.Split(featureGates, ",") {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || strings.TrimSpace(k) != name {
+			continue
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		return err == nil && enabled
+	}
+	return false
+}
@@ -92,3 +92,22 @@ func TestSupport(t *testing.T) {
 	}
 
 }
+
+func TestFeatureGateEnabled(t *testing.T) {
+	tests := []struct {
+		featureGates string
+		want         bool
+	}{
+		{"", false},
+		{"ContainerCheckpoint=true", true},
+		{"EphemeralContainers=true, ContainerCheckpoint = true", true},
+		{"ContainerCheckpoint=false", false},
+		{"ContainerCheckpoint", false},
+		{"ContainerCheckpointing=true", false},
+	}
+	for _, tc := range tests {
+		if got := FeatureGateEnabled(tc.featureGates, "ContainerCheckpoint"); got != tc.want {
+			t.Errorf("FeatureGateEnabled(%q) = %t, want %t", tc.featureGates, got, tc.want)
+		}
+	}
+}
@@ -307,3 +307,39 @@ func TestEtcdExtraArgs(t *testing.T) {
 		t.Errorf("machines mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestGenerateCustomCRISocket(t *testing.T) {
+	const socket = "/run/cri-dockerd-mk1.sock"
+	fcr := command.NewFakeCommandRunner()
+	fcr.SetCommandToOutput(map[string]string{
+		"docker info --format {{.CgroupDriver}}": "systemd\n",
+	})
+	cfg := config.ClusterConfig{
+		Name: "mk",
+		KubernetesConfig: config.KubernetesConfig{
+			KubernetesVersion: constants.DefaultKubernetesVersion,
+			ContainerRuntime:  "docker",
+			CRISocket:         socket,
+		},
+		Nodes: []config.Node{{IP: "1.1.1.1", Name: "mk", ControlPlane: true}},
+	}
+	runtime, err := cruntime.New(cruntime.Config{Type: "docker", Runner: fcr, Socket: cfg.KubernetesConfig.CRISocket})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+
+	kubeadm, err := GenerateKubeadmYAML(cfg, cfg.Nodes[0], runtime)
+	if err != nil {
+		t.Fatalf("GenerateKubeadmYAML: %v", err)
+	}
+	if !strings.Contains(string(kubeadm), "criSocket: "+socket+"\n") {
+		t.Errorf("kubeadm config does not use the CRI socket %s:\n%s", socket, kubeadm)
+	}
+	kubelet, err := NewKubeletConfig(cfg, cfg.Nodes[0], runtime)
+	if err != nil {
+		t.Fatalf("NewKubeletConfig: %v", err)
+	}
+	if !strings.Contains(string(kubelet), "--container-runtime-endpoint="+socket+" ") {
+		t.Errorf("kubelet config does not use the CRI socket %s:\n%s", socket, kubelet)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bsutil
+
+import (
+	"os/exec"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+)
+
+// runtimeEndpointRe matches the CRI endpoint of a kubelet configuration, which kubeadm sets for Kubernetes 1.27 and later
+var runtimeEndpointRe = regexp.MustCompile(`(?m)^(containerRuntimeEndpoint:[ \t]*)(\S*)`)
+
+// KubeletRuntimeChange is a setting of the kubelet configuration saved by kubeadm which changed with the container runtime
+type KubeletRuntimeChange struct {
+	// File is the file of the setting
+	File string
+	// Setting is the name of the setting
+	Setting string
+	From    string
+	To      string
+}
+
+// KubeletRuntime are the kubelet configuration and flags kubeadm saved on a node, empty if not saved yet
+type KubeletRuntime struct {
+	Config string
+	Flags  string
+}
+
+// Reconcile returns the kubelet configuration and flags using the cgroup driver and CRI socket of the runtime, and the settings which changed.
+// The cgroup driver is left as it is if driver is empty, as the runtime could not tell it.
+func (k KubeletRuntime) Reconcile(driver string, socket string) (KubeletRuntime, []KubeletRuntimeChange) {
+	changes := []KubeletRuntimeChange{}
+	if from := KubeletCgroupDriver(k.Config); driver != "" && FixCgroupDrivers(driver, from, false).Kubelet {
+		k.Config = SetKubeletCgroupDriver(k.Config, driver)
+		changes = append(changes, KubeletRuntimeChange{File: KubeletConfigFile, Setting: "cgroupDriver", From: from, To: driver})
+	}
+	if m := runtimeEndpointRe.FindStringSubmatch(k.Config); m != nil && m[2] != cruntime.SocketURL(socket) {
+		k.Config = runtimeEndpointRe.ReplaceAllString(k.Config, "${1}"+cruntime.SocketURL(socket))
+		changes = append(changes, KubeletRuntimeChange{File: KubeletConfigFile, Setting: "containerRuntimeEndpoint", From: m[2], To: cruntime.SocketURL(socket)})
+	}
+	if k.Flags != "" {
+		from := ""
+		if m := endpointFlagRe.FindStringSubmatch(k.Flags); m != nil {
+			from = m[0][len(m[1]):]
+		}
+		if flags, changed := MigrateKubeletFlags(k.Flags, socket); changed {
+			k.Flags = flags
+			changes = append(changes, KubeletRuntimeChange{File: KubeletFlagsFile, Setting: "--container-runtime-endpoint", From: from, To: cruntime.SocketURL(socket)})
+		}
+	}
+	return k, changes
+}
+
+// UpdateKubeletRuntime points the kubelet configuration and flags kubeadm saved on a node at the cgroup driver and CRI socket the runtime has now,
+// which change when systemd is forced or docker moves to cri-dockerd, leaving them stale. It writes the files and restarts kubelet only if they changed,
+// returning the settings which did.
+func UpdateKubeletRuntime(runner command.Runner, driver string, socket string) ([]KubeletRuntimeChange, error) {
+	saved := KubeletRuntime{}
+	if rr, err := runner.RunCmd(exec.Command("sudo", "cat", KubeletConfigFile)); err == nil {
+		saved.Config = rr.Stdout.String()
+	}
+	if rr, err := runner.RunCmd(exec.Command("sudo", "cat", KubeletFlagsFile)); err == nil {
+		saved.Flags = rr.Stdout.String()
+	}
+	updated, changes := saved.Reconcile(driver, socket)
+	if len(changes) == 0 {
+		klog.Infof("kubelet configuration matches the runtime: cgroup driver %q, CRI socket %q", driver, socket)
+		return changes, nil
+	}
+	for _, c := range changes {
+		klog.Infof("updating kubelet %s in %s: %q -> %q", c.Setting, c.File, c.From, c.To)
+	}
+
+	files := []assets.CopyableFile{}
+	if updated.Config != saved.Config {
+		files = append(files, assets.NewMemoryAssetTarget([]byte(updated.Config), KubeletConfigFile, "0644"))
+	}
+	if updated.Flags != saved.Flags {
+		files = append(files, assets.NewMemoryAssetTarget([]byte(updated.Flags), KubeletFlagsFile, "0644"))
+	}
+	for _, f := range files {
+		if err := runner.Copy(f); err != nil {
+			return changes, errors.Wrapf(err, "writing %s", f.GetTargetPath())
+		}
+	}
+	if sm := sysinit.New(runner); sm.Active("kubelet") {
+		if err := sm.Restart("kubelet"); err != nil {
+			return changes, errors.Wrap(err, "restarting kubelet")
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bsutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+const kubeletConfig = `apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+cgroupDriver: cgroupfs
+clusterDomain: "cluster.local"
+`
+
+const kubeletFlags = `KUBELET_KUBEADM_ARGS="--container-runtime=remote --container-runtime-endpoint=unix:///run/containerd/containerd.sock --pod-infra-container-image=registry.k8s.io/pause:3.8"
+`
+
+func TestKubeletRuntimeReconcile(t *testing.T) {
+	tests := []struct {
+		name        string
+		saved       KubeletRuntime
+		driver      string
+		socket      string
+		wantChanges []KubeletRuntimeChange
+	}{
+		{
+			name:   "unchanged",
+			saved:  KubeletRuntime{Config: kubeletConfig, Flags: kubeletFlags},
+			driver: "cgroupfs",
+			socket: "/run/containerd/containerd.sock",
+		},
+		{
+			name:        "systemd forced",
+			saved:       KubeletRuntime{Config: kubeletConfig, Flags: kubeletFlags},
+			driver:      "systemd",
+			socket:      "/run/containerd/containerd.sock",
+			wantChanges: []KubeletRuntimeChange{{File: KubeletConfigFile, Setting: "cgroupDriver", From: "cgroupfs", To: "systemd"}},
+		},
+		{
+			name:   "cgroup driver unknown",
+			saved:  KubeletRuntime{Config: kubeletConfig, Flags: kubeletFlags},
+			socket: "/run/containerd/containerd.sock",
+		},
+		{
+			name:        "docker moved to cri-dockerd",
+			saved:       KubeletRuntime{Config: kubeletConfig, Flags: `KUBELET_KUBEADM_ARGS="--network-plugin=cni"` + "\n"},
+			driver:      "systemd",
+			socket:      "/var/run/cri-dockerd.sock",
+			wantChanges: []KubeletRuntimeChange{{File: KubeletConfigFile, Setting: "cgroupDriver", From: "cgroupfs", To: "systemd"}, {File: KubeletFlagsFile, Setting: "--container-runtime-endpoint", To: "unix:///var/run/cri-dockerd.sock"}},
+		},
+		{
+			name:        "endpoint in the configuration",
+			saved:       KubeletRuntime{Config: kubeletConfig + "containerRuntimeEndpoint: unix:///var/run/cri-dockerd.sock\n"},
+			driver:      "cgroupfs",
+			socket:      "/run/containerd/containerd.sock",
+			wantChanges: []KubeletRuntimeChange{{File: KubeletConfigFile, Setting: "containerRuntimeEndpoint", From: "unix:///var/run/cri-dockerd.sock", To: "unix:///run/containerd/containerd.sock"}},
+		},
+		{
+			name:   "not configured yet",
+			driver: "systemd",
+			socket: "/run/containerd/containerd.sock",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, changes := tc.saved.Reconcile(tc.driver, tc.socket)
+			if diff := cmp.Diff(tc.wantChanges, changes, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Reconcile() changes mismatch (-want +got):\n%s", diff)
+			}
+			if len(changes) == 0 && got != tc.saved {
+				t.Errorf("Reconcile() changed the files without reporting it: %+v", got)
+			}
+			// reconciling again changes nothing
+			if again, changes := got.Reconcile(tc.driver, tc.socket); len(changes) != 0 || again != got {
+				t.Errorf("Reconcile() of the reconciled files = %+v, want no changes", changes)
+			}
+		})
+	}
+}
+
+// kubeletNode is a node whose files are read with 'sudo cat', recording the files written and the kubelet restarts
+type kubeletNode struct {
+	*command.FakeCommandRunner
+	files    map[string]string
+	writes   []string
+	restarts int
+}
+
+func (n *kubeletNode) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
+	rr := &command.RunResult{Args: cmd.Args}
+	c := rr.Command()
+	switch {
+	case strings.HasPrefix(c, "sudo cat "):
+		content, ok := n.files[cmd.Args[2]]
+		if !ok {
+			return rr, fmt.Errorf("%s: no such file", cmd.Args[2])
+		}
+		rr.Stdout.WriteString(content)
+	case strings.Contains(c, "restart") && strings.Contains(c, "kubelet"):
+		n.restarts++
+	}
+	return rr, nil
+}
+
+func (n *kubeletNode) Copy(f assets.CopyableFile) error {
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, f); err != nil {
+		return err
+	}
+	n.files[f.GetTargetPath()] = b.String()
+	n.writes = append(n.writes, f.GetTargetPath())
+	return nil
+}
+
+func TestUpdateKubeletRuntimeCgroupfsToSystemd(t *testing.T) {
+	n := &kubeletNode{FakeCommandRunner: command.NewFakeCommandRunner(), files: map[string]string{KubeletConfigFile: kubeletConfig, KubeletFlagsFile: kubeletFlags}}
+	socket := "/run/containerd/containerd.sock"
+
+	// the first start with systemd forced rewrites the configuration, and the next ones keep it
+	for i := 0; i < 3; i++ {
+		changes, err := UpdateKubeletRuntime(n, "systemd", socket)
+		if err != nil {
+			t.Fatalf("UpdateKubeletRuntime: %v", err)
+		}
+		want := 0
+		if i == 0 {
+			want = 1
+		}
+		if len(changes) != want {
+			t.Errorf("start %d: UpdateKubeletRuntime() = %+v, want %d changes", i, changes, want)
+		}
+	}
+	if diff := cmp.Diff([]string{KubeletConfigFile}, n.writes); diff != "" {
+		t.Errorf("files written mismatch (-want +got):\n%s", diff)
+	}
+	if n.restarts != 1 {
+		t.Errorf("kubelet restarted %d times, want once", n.restarts)
+	}
+	if got := KubeletCgroupDriver(n.files[KubeletConfigFile]); got != "systemd" {
+		t.Errorf("kubelet cgroup driver = %q, want systemd", got)
+	}
+	if n.files[KubeletFlagsFile] != kubeletFlags {
+		t.Errorf("kubelet flags changed:\n%s", n.files[KubeletFlagsFile])
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kverify
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/minikube/pkg/minikube/cruntime/cruntimetest"
+)
+
+func TestComponents(t *testing.T) {
+	for _, c := range AllComponentsList {
+		if !AllComponents[c] {
+			t.Errorf("AllComponents does not wait for %s", c)
+		}
+		if v, ok := NoComponents[c]; !ok || v {
+			t.Errorf("NoComponents[%s] = %v, %v, want false, true", c, v, ok)
+		}
+	}
+	for _, c := range DefaultWaitList {
+		if !DefaultComponents[c] {
+			t.Errorf("DefaultComponents does not wait for %s, which the --wait flag defaults to", c)
+		}
+	}
+	for c := range DefaultComponents {
+		if !ShouldWait(map[string]bool{c: true}) {
+			t.Errorf("ShouldWait does not wait for %s", c)
+		}
+	}
+	if !DefaultComponents[RuntimeWaitKey] {
+		t.Errorf("DefaultComponents does not wait for the runtime")
+	}
+}
+
+func TestRuntimeTimeout(t *testing.T) {
+	var tests = []struct {
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{6 * time.Minute, 72 * time.Second},
+		{time.Minute, 30 * time.Second},
+		{10 * time.Second, 10 * time.Second},
+	}
+	for _, tc := range tests {
+		if got := RuntimeTimeout(tc.timeout); got != tc.want {
+			t.Errorf("RuntimeTimeout(%s) = %s, want %s", tc.timeout, got, tc.want)
+		}
+	}
+}
+
+func TestWaitForRuntime(t *testing.T) {
+	cr := cruntimetest.NewFakeRuntime()
+	if err := WaitForRuntime(cr, time.Minute); err != nil {
+		t.Errorf("WaitForRuntime: %v", err)
+	}
+	if err := cr.Disable(); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if err := WaitForRuntime(cr, time.Second); err == nil {
+		t.Errorf("WaitForRuntime of a disabled runtime succeeded")
+	}
+
+	cr = cruntimetest.NewFakeRuntime()
+	cr.Fail("EnsurePauseImage", fmt.Errorf("offline"))
+	if err := WaitForRuntime(cr, time.Minute); err == nil || !strings.Contains(err.Error(), "pause image") {
+		t.Errorf("WaitForRuntime without the pause image = %v, want an error about the pause image", err)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kverify verifies a running Kubernetes cluster is healthy
+package kverify
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/util/retry"
+)
+
+const (
+	// runtimeTimeoutShare is the fraction of the wait timeout given to the runtime, which recovers within seconds if at all
+	runtimeTimeoutShare = 5
+	// minRuntimeTimeout is the least time to wait for the runtime to be healthy
+	minRuntimeTimeout = 30 * time.Second
+)
+
+// RuntimeTimeout returns the share of the wait timeout given to the container runtime
+func RuntimeTimeout(timeout time.Duration) time.Duration {
+	share := timeout / runtimeTimeoutShare
+	if share < minRuntimeTimeout {
+		share = minRuntimeTimeout
+	}
+	if share > timeout {
+		return timeout
+	}
+	return share
+}
+
+// WaitForRuntime waits for the container runtime to be active and answering on its socket, and to hold the pause image
+func WaitForRuntime(cr cruntime.Manager, timeout time.Duration) error {
+	pStart := time.Now()
+	timeout = RuntimeTimeout(timeout)
+	klog.Infof("waiting %s for %s to be healthy ...", timeout, cr.Name())
+
+	if err := retry.Local(cr.Healthy, timeout); err != nil {
+		return fmt.Errorf("%s is not healthy: %v", cr.Name(), err)
+	}
+	// the image GC of the kubelet may have removed the pause image, which no pod starts without
+	repaired, err := cr.EnsurePauseImage()
+	if err != nil {
+		return fmt.Errorf("%s is missing the pause image: %v", cr.Name(), err)
+	}
+	if repaired {
+		klog.Warningf("restored the missing pause image of %s", cr.Name())
+	}
+
+	klog.Infof("duration metric: took %s WaitForRuntime to wait for %s.", time.Since(pStart), cr.Name())
+	return nil
+}
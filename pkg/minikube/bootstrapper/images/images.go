@@ -130,11 +130,7 @@ func coreDNS(v semver.Version, mirror string) string {
 		cv = findLatestTagFromRepository(fmt.Sprintf(tagURLTemplate, kubernetesRepo(mirror, v), imageName), cv)
 	}
 
-	if mirror == constants.AliyunMirror {
-		imageName = "coredns"
-	}
-
-	return fmt.Sprintf("%s:%s", path.Join(kubernetesRepo(mirror, v), imageName), cv)
+	return fmt.Sprintf("%s:%s", path.Join(kubernetesRepo(mirror, v), mirrorName(mirror, imageName)), cv)
 }
 
 // etcd returns the image used for etcd
@@ -166,11 +162,9 @@ func auxiliary(mirror string) []string {
 // storageProvisioner returns the minikube storage provisioner image
 func storageProvisioner(mirror string) string {
 	cv := version.GetStorageProvisionerVersion()
-	in := "k8s-minikube/storage-provisioner:" + cv
+	in := mirrorName(mirror, "k8s-minikube/storage-provisioner") + ":" + cv
 	if mirror == "" {
 		mirror = "gcr.io"
-	} else if mirror == constants.AliyunMirror {
-		in = "storage-provisioner:" + cv
 	}
 	return path.Join(mirror, in)
 }
@@ -17,7 +17,12 @@ limitations under the License.
 package images
 
 import (
+	"path"
+	"strings"
+
 	"github.com/blang/semver/v4"
+
+	"k8s.io/minikube/pkg/minikube/constants"
 )
 
 // OldDefaultKubernetesRepo is the old default Kubernetes repository
@@ -41,3 +46,36 @@ func DefaultKubernetesRepo(kv semver.Version) string {
 	}
 	return NewDefaultKubernetesRepo
 }
+
+// aliyunNames are the names of images in the Aliyun mirror, which flattens their namespaces
+var aliyunNames = map[string]string{
+	"coredns/coredns":                  "coredns",
+	"k8s-minikube/storage-provisioner": "storage-provisioner",
+}
+
+// mirrorName returns the name within mirror of an image, given without registry nor tag
+func mirrorName(mirror string, name string) string {
+	if n, ok := aliyunNames[name]; ok && mirror == constants.AliyunMirror {
+		return n
+	}
+	return name
+}
+
+// Remap returns the name of image in mirror, following the rules of Kubeadm, so that loaded images match the names in the manifests
+// The registry of image is replaced by mirror, and images already in mirror are returned as they are.
+func Remap(image string, mirror string) string {
+	mirror = strings.TrimSuffix(mirror, "/")
+	if mirror == "" || strings.HasPrefix(image, mirror+"/") {
+		return image
+	}
+	name := image
+	if parts := strings.SplitN(image, "/", 2); len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		name = parts[1]
+	}
+	// without the registry, the first colon starts the tag
+	tag := ""
+	if i := strings.IndexAny(name, ":@"); i != -1 {
+		name, tag = name[:i], name[i:]
+	}
+	return path.Join(mirror, mirrorName(mirror, name)) + tag
+}
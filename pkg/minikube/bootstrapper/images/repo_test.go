@@ -21,6 +21,8 @@ import (
 
 	"github.com/blang/semver/v4"
 	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/minikube/pkg/minikube/constants"
 )
 
 func Test_kubernetesRepo(t *testing.T) {
@@ -59,3 +61,47 @@ func Test_kubernetesRepo(t *testing.T) {
 	}
 
 }
+
+func TestRemap(t *testing.T) {
+	tests := []struct {
+		image  string
+		mirror string
+		want   string
+	}{
+		{"registry.k8s.io/pause:3.8", "", "registry.k8s.io/pause:3.8"},
+		{"registry.k8s.io/pause:3.8", "mirror.local", "mirror.local/pause:3.8"},
+		{"registry.k8s.io/sig-storage/csi-provisioner:v3.3.0", "mirror.local/k8s/", "mirror.local/k8s/sig-storage/csi-provisioner:v3.3.0"},
+		{"localhost:5000/team/app/api:1.0", "mirror.local:5000/k8s", "mirror.local:5000/k8s/team/app/api:1.0"},
+		{"busybox@sha256:abc", "mirror.local:5000", "mirror.local:5000/busybox@sha256:abc"},
+		{"mirror.local:5000/k8s/pause:3.8", "mirror.local:5000/k8s", "mirror.local:5000/k8s/pause:3.8"},
+		{"registry.k8s.io/coredns/coredns:v1.9.3", constants.AliyunMirror, constants.AliyunMirror + "/coredns:v1.9.3"},
+	}
+	for _, tc := range tests {
+		if got := Remap(tc.image, tc.mirror); got != tc.want {
+			t.Errorf("Remap(%q, %q) = %q, want %q", tc.image, tc.mirror, got, tc.want)
+		}
+	}
+}
+
+// TestRemapKubeadm checks that remapping the default images of Kubeadm gives the images Kubeadm uses with a mirror
+func TestRemapKubeadm(t *testing.T) {
+	for _, mirror := range []string{"mirror.local:5000/k8s", constants.AliyunMirror} {
+		for _, version := range []string{"v1.20.0", "v1.24.4", "v1.25.3"} {
+			defaults, err := Kubeadm("", version)
+			if err != nil {
+				t.Fatalf("Kubeadm(%s): %v", version, err)
+			}
+			want, err := Kubeadm(mirror, version)
+			if err != nil {
+				t.Fatalf("Kubeadm(%s, %s): %v", mirror, version, err)
+			}
+			got := []string{}
+			for _, img := range defaults {
+				got = append(got, Remap(img, mirror))
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Remap of Kubeadm %s images to %s mismatch (-want +got):\n%s", version, mirror, diff)
+			}
+		}
+	}
+}
@@ -37,6 +37,17 @@ func (f *FailFastError) Error() string {
 	return f.Err.Error()
 }
 
+// PrePullError is returned when an image of the new Kubernetes version could not be pulled ahead of reconfiguring
+// the control plane, which is left running untouched
+type PrePullError struct {
+	Image string
+	Err   error
+}
+
+func (p *PrePullError) Error() string {
+	return fmt.Sprintf("pre-pulling %s: %v", p.Image, p.Err)
+}
+
 // ErrNoExecLinux is thrown on linux when the kubeadm binaries are mounted in a noexec volume on Linux as seen in https://github.com/kubernetes/minikube/issues/8327#issuecomment-651288459
 // this error could be seen on docker/podman or none driver.
 var ErrNoExecLinux = &FailFastError{errors.New("mounted kubeadm binary is not executable")}
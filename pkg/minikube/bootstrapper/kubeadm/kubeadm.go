@@ -181,7 +181,7 @@ func (k *Bootstrapper) init(cfg config.ClusterConfig) error {
 	}
 
 	extraFlags := bsutil.CreateFlagsFromExtraArgs(cfg.KubernetesConfig.ExtraOptions)
-	r, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Runner: k.c})
+	r, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Driver: cfg.Driver, Runner: k.c})
 	if err != nil {
 		return err
 	}
@@ -372,12 +372,16 @@ func (k *Bootstrapper) applyCNI(cfg config.ClusterConfig, registerStep ...bool)
 
 // unpause unpauses any Kubernetes backplane components
 func (k *Bootstrapper) unpause(cfg config.ClusterConfig) error {
-	cr, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Runner: k.c})
+	cr, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Driver: cfg.Driver, Runner: k.c})
 	if err != nil {
 		return err
 	}
 
 	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Paused, Namespaces: []string{"kube-system"}})
+	if errors.Is(err, cruntime.ErrPauseUntracked) {
+		klog.Warningf("assuming no paused kube-system containers: %v", err)
+		return nil
+	}
 	if err != nil {
 		return errors.Wrap(err, "list paused")
 	}
@@ -413,6 +417,10 @@ func (k *Bootstrapper) StartCluster(cfg config.ClusterConfig) error {
 		if rerr == nil {
 			return nil
 		}
+		// the images could not be pulled, resetting would take the control plane down without fixing it
+		if _, ok := rerr.(*PrePullError); ok {
+			return rerr
+		}
 
 		out.ErrT(style.Embarrassed, "Unable to restart cluster, will reset it: {{.error}}", out.V{"error": rerr})
 		if err := k.DeleteCluster(cfg.KubernetesConfig); err != nil {
@@ -529,7 +537,11 @@ func (k *Bootstrapper) WaitForNode(cfg config.ClusterConfig, n config.Node, time
 		}
 	}
 
-	cr, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Runner: k.c})
+	k8sVersion, err := util.ParseKubernetesVersion(cfg.KubernetesConfig.KubernetesVersion)
+	if err != nil {
+		return errors.Wrap(err, "parsing Kubernetes version")
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Driver: cfg.Driver, Runner: k.c, Socket: cfg.KubernetesConfig.CRISocket, KubernetesVersion: k8sVersion})
 	if err != nil {
 		return errors.Wrapf(err, "create runtme-manager %s", cfg.KubernetesConfig.ContainerRuntime)
 	}
@@ -570,6 +582,13 @@ func (k *Bootstrapper) WaitForNode(cfg config.ClusterConfig, n config.Node, time
 		}
 	}
 
+	// the runtime may crash right after kubeadm is done, which the other components do not notice yet
+	if cfg.VerifyComponents[kverify.RuntimeWaitKey] {
+		if err := kverify.WaitForRuntime(cr, timeout); err != nil {
+			return errors.Wrap(err, "waiting for runtime")
+		}
+	}
+
 	klog.Infof("duration metric: took %s to wait for : %+v ...", time.Since(start), cfg.VerifyComponents)
 
 	if err := kverify.NodePressure(client); err != nil {
@@ -622,6 +641,42 @@ func (k *Bootstrapper) needsReconfigure(conf string, hostname string, port int,
 	return false
 }
 
+// prePullImages brings the missing images of the configured Kubernetes version into the node, extracting them from
+// the local preload when it holds them and pulling the others, reporting the progress of each image
+func (k *Bootstrapper) prePullImages(cfg config.ClusterConfig, cr cruntime.Manager) error {
+	imgs, err := images.Kubeadm(cfg.KubernetesConfig.ImageRepository, cfg.KubernetesConfig.KubernetesVersion)
+	if err != nil {
+		return errors.Wrap(err, "kubeadm images")
+	}
+	exist, err := cr.ImagesExist(imgs)
+	if err != nil {
+		return errors.Wrap(err, "checking images")
+	}
+	missing := []string{}
+	for _, img := range imgs {
+		if !exist[img] {
+			missing = append(missing, img)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	out.Step(style.Pulling, "Pulling the images of Kubernetes {{.version}} before reconfiguring the control plane ...", out.V{"version": cfg.KubernetesConfig.KubernetesVersion})
+	left, err := cruntime.ExtractImagesFromPreload(cr, k.c, cfg, missing)
+	if err != nil {
+		klog.Warningf("unable to extract the images from the local preload, will pull them: %v", err)
+		left = missing
+	}
+	for i, img := range left {
+		out.Infof("Pulling {{.image}} ({{.index}}/{{.total}}) ...", out.V{"image": img, "index": i + 1, "total": len(left)})
+		if err := cr.PullImage(img); err != nil {
+			return &PrePullError{Image: img, Err: err}
+		}
+	}
+	return nil
+}
+
 // restartCluster restarts the Kubernetes cluster configured by kubeadm
 func (k *Bootstrapper) restartControlPlane(cfg config.ClusterConfig) error {
 	klog.Infof("restartCluster start")
@@ -676,6 +731,16 @@ func (k *Bootstrapper) restartControlPlane(cfg config.ClusterConfig) error {
 		return nil
 	}
 
+	cr, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Driver: cfg.Driver, Runner: k.c, Socket: cfg.KubernetesConfig.CRISocket, KubernetesVersion: k8sVersion})
+	if err != nil {
+		return errors.Wrap(err, "runtime")
+	}
+
+	// pull the images of the new version while the control plane still runs, rather than while it is down
+	if err := k.prePullImages(cfg, cr); err != nil {
+		return err
+	}
+
 	if err := k.stopKubeSystem(cfg); err != nil {
 		klog.Warningf("Failed to stop kube-system containers: port conflicts may arise: %v", err)
 	}
@@ -688,6 +753,9 @@ func (k *Bootstrapper) restartControlPlane(cfg config.ClusterConfig) error {
 		return errors.Wrap(err, "clearing stale configs")
 	}
 
+	// the configuration of the previous start tells which version the cluster is upgraded from
+	migration := k.planDockershimMigration(cfg, cr, conf)
+
 	if _, err := k.c.RunCmd(exec.Command("sudo", "cp", conf+".new", conf)); err != nil {
 		return errors.Wrap(err, "cp")
 	}
@@ -713,11 +781,6 @@ func (k *Bootstrapper) restartControlPlane(cfg config.ClusterConfig) error {
 		}
 	}
 
-	cr, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Runner: k.c})
-	if err != nil {
-		return errors.Wrap(err, "runtime")
-	}
-
 	// We must ensure that the apiserver is healthy before proceeding
 	if err := kverify.WaitForAPIServerProcess(cr, k, cfg, k.c, time.Now(), kconst.DefaultControlPlaneTimeout); err != nil {
 		return errors.Wrap(err, "apiserver healthz")
@@ -727,6 +790,11 @@ func (k *Bootstrapper) restartControlPlane(cfg config.ClusterConfig) error {
 		return errors.Wrap(err, "apiserver health")
 	}
 
+	// upgrades across Kubernetes 1.24 move docker from dockershim to cri-dockerd
+	if err := k.migrateCRISocket(cfg, cr, k8sVersion); err != nil {
+		klog.Warningf("unable to migrate the CRI socket: %v", err)
+	}
+
 	// because reboots clear /etc/cni
 	if err := k.applyCNI(cfg); err != nil {
 		return errors.Wrap(err, "apply cni")
@@ -754,6 +822,10 @@ func (k *Bootstrapper) restartControlPlane(cfg config.ClusterConfig) error {
 		return errors.Wrap(err, "addons")
 	}
 
+	if migration != nil {
+		k.migrateDockershimAddons(cfg, migration)
+	}
+
 	// must be called after applyCNI and `kubeadm phase addon all` (ie, coredns redeploy)
 	if cfg.VerifyComponents[kverify.ExtraKey] {
 		// after kubelet is restarted (with 'kubeadm init phase kubelet-start' above),
@@ -824,7 +896,7 @@ func (k *Bootstrapper) GenerateToken(cc config.ClusterConfig) (string, error) {
 	if err != nil {
 		return "", errors.Wrap(err, "parsing Kubernetes version")
 	}
-	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Runner: k.c, Socket: cc.KubernetesConfig.CRISocket, KubernetesVersion: version})
+	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Driver: cc.Driver, Runner: k.c, Socket: cc.KubernetesConfig.CRISocket, KubernetesVersion: version})
 	if err != nil {
 		klog.Errorf("cruntime: %v", err)
 	}
@@ -924,7 +996,7 @@ func (k *Bootstrapper) UpdateCluster(cfg config.ClusterConfig) error {
 	}
 
 	if cfg.KubernetesConfig.ShouldLoadCachedImages {
-		if err := machine.LoadCachedImages(&cfg, k.c, images, detect.ImageCacheDir(), false); err != nil {
+		if err := machine.LoadCachedImages(&cfg, k.c, images, detect.ImageCacheDir(), false, false, cruntime.ImageOutputBuffered); err != nil {
 			out.FailureT("Unable to load cached images: {{.error}}", out.V{"error": err})
 		}
 	}
@@ -992,6 +1064,22 @@ func (k *Bootstrapper) UpdateNode(cfg config.ClusterConfig, n config.Node, r cru
 		return errors.Wrap(err, "resolv.conf")
 	}
 
+	// the runtime was enabled before, so it reports the cgroup driver it runs with now
+	driver, err := r.CGroupDriver()
+	if err != nil {
+		klog.Warningf("unable to get the cgroup driver of %s, not checking the one of kubelet: %v", r.Name(), err)
+		driver = ""
+	}
+	changes, err := bsutil.UpdateKubeletRuntime(k.c, driver, r.SocketPath())
+	if err != nil {
+		return errors.Wrap(err, "updating the runtime of kubelet")
+	}
+	for _, c := range changes {
+		if c.Setting == "cgroupDriver" {
+			out.Step(style.Option, "Switched kubelet from the {{.from}} to the {{.to}} cgroup driver of {{.runtime}}", out.V{"runtime": r.Name(), "from": c.From, "to": c.To})
+		}
+	}
+
 	cp, err := config.PrimaryControlPlane(&cfg)
 	if err != nil {
 		return errors.Wrap(err, "control plane")
@@ -1026,6 +1114,79 @@ func kubectlPath(cfg config.ClusterConfig) string {
 	return path.Join(vmpath.GuestPersistentDir, "binaries", cfg.KubernetesConfig.KubernetesVersion, "kubectl")
 }
 
+// migrateCRISocket points the node annotations at the CRI socket of the runtime, UpdateNode having pointed the kubelet flags at it
+func (k *Bootstrapper) migrateCRISocket(cfg config.ClusterConfig, cr cruntime.Manager, kv semver.Version) error {
+	socket := cr.SocketPath()
+	want := bsutil.CRISocketAnnotationValue(socket, kv)
+	kubeconfig := fmt.Sprintf("--kubeconfig=%s", path.Join(vmpath.GuestPersistentDir, "kubeconfig"))
+	for _, n := range cfg.Nodes {
+		name := config.MachineName(cfg, n)
+		rr, err := k.c.RunCmd(exec.Command("sudo", kubectlPath(cfg), "get", "node", name, kubeconfig,
+			"-o", fmt.Sprintf("jsonpath={.metadata.annotations.%s}", strings.ReplaceAll(bsutil.CRISocketAnnotation, ".", `\.`))))
+		if err != nil {
+			klog.Infof("unable to get the CRI socket of node %s: %v", name, err)
+			continue
+		}
+		if strings.TrimSpace(rr.Stdout.String()) == want {
+			continue
+		}
+		klog.Infof("migrating node %s to CRI socket %s", name, want)
+		if _, err := k.c.RunCmd(exec.Command("sudo", kubectlPath(cfg), "annotate", "node", name, "--overwrite",
+			fmt.Sprintf("%s=%s", bsutil.CRISocketAnnotation, want), kubeconfig)); err != nil {
+			return errors.Wrapf(err, "annotating node %s", name)
+		}
+	}
+	return nil
+}
+
+// planDockershimMigration returns what changes as the cluster leaves dockershim, comparing the kubeadm configuration conf
+// of the previous start to cfg, or nil if it does not
+func (k *Bootstrapper) planDockershimMigration(cfg config.ClusterConfig, cr cruntime.Manager, conf string) *bsutil.DockershimMigration {
+	if cfg.KubernetesConfig.ContainerRuntime != constants.Docker {
+		return nil
+	}
+	rr, err := k.c.RunCmd(exec.Command("sudo", "cat", conf))
+	if err != nil {
+		klog.Infof("unable to read the previous kubeadm configuration: %v", err)
+		return nil
+	}
+	m, err := bsutil.PlanDockershimMigration(rr.Stdout.String(), cfg, cr.SocketPath())
+	if err != nil {
+		klog.Warningf("unable to tell whether the cluster leaves dockershim: %v", err)
+		return nil
+	}
+	if m != nil {
+		klog.Infof("upgrading from Kubernetes %s to %s moves docker from dockershim to cri-dockerd", m.From, m.To)
+	}
+	return m
+}
+
+// migrateDockershimAddons restarts the deployments of the addons which show no metrics once the cluster left dockershim,
+// migrateCRISocket having annotated the nodes, and tells the user what changed
+func (k *Bootstrapper) migrateDockershimAddons(cfg config.ClusterConfig, m *bsutil.DockershimMigration) {
+	kubeconfig := fmt.Sprintf("--kubeconfig=%s", path.Join(vmpath.GuestPersistentDir, "kubeconfig"))
+	restarted := []bsutil.Deployment{}
+	failed := []bsutil.Deployment{}
+	for _, d := range m.Restart {
+		if _, err := k.c.RunCmd(exec.Command("sudo", kubectlPath(cfg), "rollout", "restart", "deployment", d.Name, "-n", d.Namespace, kubeconfig)); err != nil {
+			klog.Warningf("unable to restart deployment %s/%s: %v", d.Namespace, d.Name, err)
+			failed = append(failed, d)
+			continue
+		}
+		restarted = append(restarted, d)
+	}
+
+	out.Step(style.Notice, "Kubernetes {{.new}} runs docker through cri-dockerd instead of dockershim, which Kubernetes {{.old}} used:", out.V{"old": m.From, "new": m.To})
+	out.Infof("The CRI socket of the nodes is now {{.new}}, instead of {{.old}}", out.V{"old": m.OldSocket, "new": m.NewSocket})
+	out.Infof("The metrics of the kubelet and cAdvisor label the containers as cri-dockerd reports them, so dashboards and alerts using the dockershim labels need updating")
+	for _, d := range restarted {
+		out.Infof("Restarted deployment {{.namespace}}/{{.name}} to collect the metrics again", out.V{"namespace": d.Namespace, "name": d.Name})
+	}
+	for _, d := range failed {
+		out.Infof("Unable to restart deployment {{.namespace}}/{{.name}}, run 'kubectl rollout restart deployment {{.name}} -n {{.namespace}}' if it shows no metrics", out.V{"namespace": d.Namespace, "name": d.Name})
+	}
+}
+
 // applyNodeLabels applies minikube labels to all the nodes
 // but it's currently called only from kubeadm.StartCluster (via kubeadm.init) where there's only one - first node
 func (k *Bootstrapper) applyNodeLabels(cfg config.ClusterConfig) error {
@@ -1112,7 +1273,7 @@ func (k *Bootstrapper) elevateKubeSystemPrivileges(cfg config.ClusterConfig) err
 // stopKubeSystem stops all the containers in the kube-system to prevent #8740 when doing hot upgrade
 func (k *Bootstrapper) stopKubeSystem(cfg config.ClusterConfig) error {
 	klog.Info("stopping kube-system containers ...")
-	cr, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Runner: k.c})
+	cr, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Driver: cfg.Driver, Runner: k.c})
 	if err != nil {
 		return errors.Wrap(err, "new cruntime")
 	}
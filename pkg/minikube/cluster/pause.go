@@ -23,16 +23,23 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/out"
 	pkgpause "k8s.io/minikube/pkg/minikube/pause"
 	"k8s.io/minikube/pkg/minikube/sysinit"
 	"k8s.io/minikube/pkg/util/retry"
 )
 
-// Pause pauses a Kubernetes cluster, retrying if necessary
-func Pause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]string, error) {
+// restoreTimeout is how long RestorePause waits for the paused pods to restart
+var restoreTimeout = 30 * time.Second
+
+// Pause pauses the containers of the namespaces of a Kubernetes cluster, or of all of them if nil, except those of the excluded namespaces, retrying if necessary
+func Pause(cr cruntime.Manager, r command.Runner, namespaces []string, exclude []string) ([]string, error) {
+	if !cr.Capabilities().SupportsPause {
+		return nil, errors.Errorf("pausing containers is not supported by the %s runtime", cr.Name())
+	}
 	var ids []string
 	tryPause := func() (err error) {
-		ids, err = pause(cr, r, namespaces)
+		ids, err = pause(cr, r, namespaces, exclude)
 		return err
 	}
 
@@ -43,7 +50,7 @@ func Pause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]string
 }
 
 // pause pauses a Kubernetes cluster
-func pause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]string, error) {
+func pause(cr cruntime.Manager, r command.Runner, namespaces []string, exclude []string) ([]string, error) {
 	ids := []string{}
 
 	// Disable the kubelet so it does not attempt to restart paused pods
@@ -54,7 +61,7 @@ func pause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]string
 		return ids, errors.Wrap(err, "kubelet disable --now")
 	}
 
-	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Running, Namespaces: namespaces})
+	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Running, Namespaces: namespaces, ExcludeNamespaces: exclude})
 	if err != nil {
 		return ids, errors.Wrap(err, "list running")
 	}
@@ -68,18 +75,22 @@ func pause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]string
 		return ids, errors.Wrap(err, "pausing containers")
 	}
 
-	if doesNamespaceContainKubeSystem(namespaces) {
+	if err := recordPaused(cr, r, namespaces, exclude); err != nil {
+		klog.Warningf("unable to record the paused pods, they will not be paused again after a restart: %v", err)
+	}
+
+	if doesNamespaceContainKubeSystem(namespaces, exclude) {
 		pkgpause.CreatePausedFile(r)
 	}
 
 	return ids, nil
 }
 
-// Unpause unpauses a Kubernetes cluster, retrying if necessary
-func Unpause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]string, error) {
+// Unpause unpauses the containers of the namespaces of a Kubernetes cluster, or of all of them if nil, except those of the excluded namespaces, retrying if necessary
+func Unpause(cr cruntime.Manager, r command.Runner, namespaces []string, exclude []string) ([]string, error) {
 	var ids []string
 	tryUnpause := func() (err error) {
-		ids, err = unpause(cr, r, namespaces)
+		ids, err = unpause(cr, r, namespaces, exclude)
 		return err
 	}
 
@@ -90,8 +101,12 @@ func Unpause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]stri
 }
 
 // unpause unpauses a Kubernetes cluster
-func unpause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]string, error) {
-	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Paused, Namespaces: namespaces})
+func unpause(cr cruntime.Manager, r command.Runner, namespaces []string, exclude []string) ([]string, error) {
+	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Paused, Namespaces: namespaces, ExcludeNamespaces: exclude})
+	if errors.Is(err, cruntime.ErrPauseUntracked) {
+		out.WarningT("Unable to tell which containers of the {{.runtime}} runtime are paused, as runc cannot list them and minikube has no record of pausing them", out.V{"runtime": cr.Name()})
+		err = nil
+	}
 	if err != nil {
 		return ids, errors.Wrap(err, "list paused")
 	}
@@ -108,16 +123,119 @@ func unpause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]stri
 		return ids, errors.Wrap(err, "kubelet start")
 	}
 
-	if doesNamespaceContainKubeSystem(namespaces) {
+	if err := forgetPaused(r, namespaces, exclude); err != nil {
+		klog.Warningf("unable to forget the unpaused pods: %v", err)
+	}
+
+	if doesNamespaceContainKubeSystem(namespaces, exclude) {
 		pkgpause.RemovePausedFile(r)
 	}
 
 	return ids, nil
 }
 
+// recordPaused adds the pods of the paused containers of the namespaces, except the excluded ones, to the paused state of the node
+func recordPaused(cr cruntime.Manager, r command.Runner, namespaces []string, exclude []string) error {
+	infos, err := cr.ListContainerInfo(cruntime.ListContainersOptions{State: cruntime.Paused, Namespaces: namespaces, ExcludeNamespaces: exclude})
+	if err != nil {
+		return errors.Wrap(err, "list paused")
+	}
+	st, err := pkgpause.LoadState(r)
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		st = &pkgpause.State{}
+	}
+	for _, c := range infos {
+		if c.PodUID != "" {
+			st.Add(pkgpause.Pod{UID: c.PodUID, Name: c.Pod, Namespace: c.Namespace})
+		}
+	}
+	return pkgpause.SaveState(r, st)
+}
+
+// forgetPaused removes the pods of the namespaces, except the excluded ones, from the paused state of the node
+func forgetPaused(r command.Runner, namespaces []string, exclude []string) error {
+	st, err := pkgpause.LoadState(r)
+	if err != nil || st == nil {
+		return err
+	}
+	st.Remove(namespaces, exclude)
+	return pkgpause.SaveState(r, st)
+}
+
+// RestorePause pauses again the pods of st, paused before the node restarted, finding their new containers by pod UID.
+// It returns the IDs of the paused containers, and the pods which no longer exist, which are forgotten.
+func RestorePause(cr cruntime.Manager, r command.Runner, st *pkgpause.State) ([]string, []pkgpause.Pod, error) {
+	var ids []string
+	var kept, missing []pkgpause.Pod
+	findPods := func() error {
+		var cids []string
+		var found, lost []pkgpause.Pod
+		for _, p := range st.Pods {
+			running, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Running, PodUID: p.UID})
+			if err != nil {
+				return errors.Wrapf(err, "list containers of pod %s", p.Name)
+			}
+			paused, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Paused, PodUID: p.UID})
+			if errors.Is(err, cruntime.ErrPauseUntracked) {
+				paused, err = nil, nil
+			}
+			if err != nil {
+				return errors.Wrapf(err, "list containers of pod %s", p.Name)
+			}
+			if len(running) == 0 && len(paused) == 0 {
+				lost = append(lost, p)
+				continue
+			}
+			found = append(found, p)
+			cids = append(cids, running...)
+		}
+		ids, kept, missing = cids, found, lost
+		if len(lost) > 0 {
+			return errors.Errorf("%d paused pods have not restarted", len(lost))
+		}
+		return nil
+	}
+	// the kubelet restarts the pods one after the other, while the deleted pods never come back
+	if err := retry.Expo(findPods, time.Second, restoreTimeout); err != nil && len(missing) == 0 {
+		return nil, nil, err
+	}
+
+	if len(ids) > 0 {
+		if err := sysinit.New(r).DisableNow("kubelet"); err != nil {
+			return nil, missing, errors.Wrap(err, "kubelet disable --now")
+		}
+		if err := cr.PauseContainers(ids); err != nil {
+			return nil, missing, errors.Wrap(err, "pausing containers")
+		}
+	}
+	for _, p := range kept {
+		if p.Namespace == "kube-system" {
+			pkgpause.CreatePausedFile(r)
+			break
+		}
+	}
+	// the state now also holds the containers paused again
+	cur, err := pkgpause.LoadState(r)
+	if err != nil || cur == nil {
+		cur = &pkgpause.State{}
+	}
+	cur.Pods = kept
+	if err := pkgpause.SaveState(r, cur); err != nil {
+		klog.Warningf("unable to forget the pods which no longer exist: %v", err)
+	}
+	return ids, missing, nil
+}
+
 // CheckIfPaused checks if the Kubernetes cluster is paused
 func CheckIfPaused(cr cruntime.Manager, namespaces []string) (bool, error) {
 	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Paused, Namespaces: namespaces})
+	if errors.Is(err, cruntime.ErrPauseUntracked) {
+		klog.Warningf("assuming no paused containers: %v", err)
+		return false, nil
+	}
 	if err != nil {
 		return true, errors.Wrap(err, "list paused")
 	}
@@ -129,17 +247,7 @@ func CheckIfPaused(cr cruntime.Manager, namespaces []string) (bool, error) {
 	return false, nil
 }
 
-// doesNamespaceContainKubeSystem returns true if kube-system is contained in the namespace list
-// This is used to only mark the apiserver as paused/unpaused when the kube-system namespace is specified
-func doesNamespaceContainKubeSystem(namespaces []string) bool {
-	// nil slice indicates all namespaces
-	if namespaces == nil {
-		return true
-	}
-	for _, ns := range namespaces {
-		if ns == "kube-system" {
-			return true
-		}
-	}
-	return false
+// doesNamespaceContainKubeSystem returns true if kube-system is contained in the namespace list, and not excluded
+func doesNamespaceContainKubeSystem(namespaces []string, exclude []string) bool {
+	return cruntime.ListContainersOptions{Namespaces: namespaces, ExcludeNamespaces: exclude}.SelectsNamespace("kube-system")
 }
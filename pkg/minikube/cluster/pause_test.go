@@ -0,0 +1,257 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/cruntime/cruntimetest"
+	"k8s.io/minikube/pkg/minikube/out"
+	pkgpause "k8s.io/minikube/pkg/minikube/pause"
+	"k8s.io/minikube/pkg/minikube/tests"
+)
+
+// pauseRunner returns a runner accepting the commands run to pause and unpause the kubelet
+func pauseRunner() *command.FakeCommandRunner {
+	r := command.NewFakeCommandRunner()
+	r.SetCommandToOutput(map[string]string{
+		"systemctl --version":                              "systemd 245",
+		"sudo systemctl is-active --quiet service kubelet": "",
+		"sudo systemctl disable --now kubelet":             "",
+		"sudo systemctl daemon-reload":                     "",
+		"sudo systemctl start kubelet":                     "",
+		"touch paused":                                     "",
+		"rm -f paused":                                     "",
+	})
+	return r
+}
+
+func TestPauseUnpause(t *testing.T) {
+	cr := cruntimetest.NewFakeRuntime()
+	cr.AddContainer("a", cruntimetest.FakeContainer{Name: "kube-apiserver", Namespace: "kube-system"})
+	cr.AddContainer("b", cruntimetest.FakeContainer{Name: "nginx", Namespace: "default"})
+	r := pauseRunner()
+
+	ids, err := pause(cr, r, []string{"kube-system"}, nil)
+	if err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a"}, ids); diff != "" {
+		t.Errorf("paused containers mismatch (-want +got):\n%s", diff)
+	}
+	if cr.Containers["b"].State != cruntimetest.StateRunning {
+		t.Errorf("container of another namespace is %s", cr.Containers["b"].State)
+	}
+
+	paused, err := CheckIfPaused(cr, nil)
+	if err != nil || !paused {
+		t.Errorf("CheckIfPaused() = %v, %v, want paused", paused, err)
+	}
+
+	ids, err = unpause(cr, r, nil, nil)
+	if err != nil {
+		t.Fatalf("unpause: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a"}, ids); diff != "" {
+		t.Errorf("unpaused containers mismatch (-want +got):\n%s", diff)
+	}
+	if paused, _ := CheckIfPaused(cr, nil); paused {
+		t.Errorf("CheckIfPaused() after unpause = true")
+	}
+}
+
+func TestPauseFailure(t *testing.T) {
+	cr := cruntimetest.NewFakeRuntime()
+	cr.AddContainer("a", cruntimetest.FakeContainer{Name: "kube-apiserver", Namespace: "kube-system"})
+	cr.Fail("PauseContainers", errors.New("paused already"))
+
+	if _, err := pause(cr, pauseRunner(), nil, nil); err == nil {
+		t.Fatalf("pause succeeded with a failing runtime")
+	}
+	if cr.Containers["a"].State != cruntimetest.StateRunning {
+		t.Errorf("container is %s after a failed pause", cr.Containers["a"].State)
+	}
+
+	cr.Fail("ListContainers", errors.New("runtime is down"))
+	if paused, err := CheckIfPaused(cr, nil); err == nil || !paused {
+		t.Errorf("CheckIfPaused() = %v, %v, want paused and an error", paused, err)
+	}
+}
+
+func TestUnpauseUntracked(t *testing.T) {
+	cr := cruntimetest.NewFakeRuntime()
+	cr.AddContainer("a", cruntimetest.FakeContainer{Name: "nginx", Namespace: "default"})
+	cr.Fail("ListContainers", errors.Wrap(cruntime.ErrPauseUntracked, "runc"))
+	f := tests.NewFakeFile()
+	out.SetErrFile(f)
+
+	ids, err := unpause(cr, pauseRunner(), []string{"default"}, nil)
+	if err != nil {
+		t.Fatalf("unpause: %v", err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("unpause() = %v, want no containers", ids)
+	}
+	if !strings.Contains(f.String(), "Unable to tell which containers") {
+		t.Errorf("unpause did not warn about the untracked paused state, got: %q", f.String())
+	}
+
+	if paused, err := CheckIfPaused(cr, nil); err != nil || paused {
+		t.Errorf("CheckIfPaused() = %v, %v, want not paused", paused, err)
+	}
+}
+
+// savedState returns the paused state written to the runner
+func savedState(t *testing.T, r *command.FakeCommandRunner) pkgpause.State {
+	t.Helper()
+	data, err := r.GetFileToContents(assets.MemorySource)
+	if err != nil {
+		t.Fatalf("paused state not written: %v", err)
+	}
+	var st pkgpause.State
+	if err := json.Unmarshal([]byte(data), &st); err != nil {
+		t.Fatalf("paused state: %v", err)
+	}
+	return st
+}
+
+func TestPauseRecordsPods(t *testing.T) {
+	cr := cruntimetest.NewFakeRuntime()
+	cr.AddContainer("a", cruntimetest.FakeContainer{Name: "kube-apiserver", Pod: "kube-apiserver-minikube", PodUID: "uid-a", Namespace: "kube-system"})
+	cr.AddContainer("b", cruntimetest.FakeContainer{Name: "nginx", Pod: "nginx", PodUID: "uid-b", Namespace: "default"})
+	r := pauseRunner()
+
+	if _, err := pause(cr, r, []string{"default"}, nil); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	want := pkgpause.State{Pods: []pkgpause.Pod{{UID: "uid-b", Name: "nginx", Namespace: "default"}}}
+	if diff := cmp.Diff(want, savedState(t, r)); diff != "" {
+		t.Errorf("paused state mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPauseExcludeNamespaces(t *testing.T) {
+	cr := cruntimetest.NewFakeRuntime()
+	cr.AddContainer("a", cruntimetest.FakeContainer{Name: "kube-apiserver", Pod: "kube-apiserver-minikube", PodUID: "uid-a", Namespace: "kube-system"})
+	cr.AddContainer("b", cruntimetest.FakeContainer{Name: "nginx", Pod: "nginx", PodUID: "uid-b", Namespace: "default"})
+	cr.AddContainer("c", cruntimetest.FakeContainer{Name: "agent", Pod: "agent", PodUID: "uid-c", Namespace: "monitoring"})
+	r := pauseRunner()
+
+	ids, err := pause(cr, r, nil, []string{"monitoring"})
+	if err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a", "b"}, ids); diff != "" {
+		t.Errorf("paused containers mismatch (-want +got):\n%s", diff)
+	}
+	if cr.Containers["c"].State != cruntimetest.StateRunning {
+		t.Errorf("container of an excluded namespace is %s", cr.Containers["c"].State)
+	}
+	want := pkgpause.State{Pods: []pkgpause.Pod{{UID: "uid-a", Name: "kube-apiserver-minikube", Namespace: "kube-system"}, {UID: "uid-b", Name: "nginx", Namespace: "default"}}}
+	if diff := cmp.Diff(want, savedState(t, r), cmpopts.SortSlices(func(a, b pkgpause.Pod) bool { return a.UID < b.UID })); diff != "" {
+		t.Errorf("paused state mismatch (-want +got):\n%s", diff)
+	}
+
+	ids, err = unpause(cr, r, []string{"kube-system", "default"}, []string{"kube-system"})
+	if err != nil {
+		t.Fatalf("unpause: %v", err)
+	}
+	if diff := cmp.Diff([]string{"b"}, ids); diff != "" {
+		t.Errorf("unpaused containers mismatch (-want +got):\n%s", diff)
+	}
+	if cr.Containers["a"].State != cruntimetest.StatePaused {
+		t.Errorf("container of an excluded namespace is %s", cr.Containers["a"].State)
+	}
+}
+
+func TestStateRemoveExcluded(t *testing.T) {
+	pods := []pkgpause.Pod{{UID: "a", Namespace: "kube-system"}, {UID: "b", Namespace: "default"}, {UID: "c", Namespace: "monitoring"}}
+	var tests = []struct {
+		name       string
+		namespaces []string
+		exclude    []string
+		want       []string
+	}{
+		{"all", nil, nil, []string{}},
+		{"namespaces", []string{"default"}, nil, []string{"a", "c"}},
+		{"all but excluded", nil, []string{"monitoring"}, []string{"c"}},
+		{"namespaces but excluded", []string{"default", "monitoring"}, []string{"monitoring"}, []string{"a", "c"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			st := pkgpause.State{Pods: append([]pkgpause.Pod{}, pods...)}
+			st.Remove(tc.namespaces, tc.exclude)
+			got := []string{}
+			for _, p := range st.Pods {
+				got = append(got, p.UID)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Remove() kept mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRestorePause(t *testing.T) {
+	defer func(d time.Duration) { restoreTimeout = d }(restoreTimeout)
+	restoreTimeout = 10 * time.Millisecond
+
+	// the containers were recreated by the restart, with new IDs
+	cr := cruntimetest.NewFakeRuntime()
+	cr.AddContainer("a2", cruntimetest.FakeContainer{Name: "kube-apiserver", PodUID: "uid-a", Namespace: "kube-system"})
+	cr.AddContainer("b2", cruntimetest.FakeContainer{Name: "nginx", PodUID: "uid-b", Namespace: "default"})
+	cr.AddContainer("c", cruntimetest.FakeContainer{Name: "redis", PodUID: "uid-c", Namespace: "default"})
+	r := pauseRunner()
+
+	apiserver := pkgpause.Pod{UID: "uid-a", Name: "kube-apiserver-minikube", Namespace: "kube-system"}
+	nginx := pkgpause.Pod{UID: "uid-b", Name: "nginx", Namespace: "default"}
+	deleted := pkgpause.Pod{UID: "uid-d", Name: "deleted", Namespace: "default"}
+	st := &pkgpause.State{Pods: []pkgpause.Pod{apiserver, nginx, deleted}}
+
+	ids, missing, err := RestorePause(cr, r, st)
+	if err != nil {
+		t.Fatalf("RestorePause: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a2", "b2"}, ids); diff != "" {
+		t.Errorf("paused containers mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]pkgpause.Pod{deleted}, missing); diff != "" {
+		t.Errorf("missing pods mismatch (-want +got):\n%s", diff)
+	}
+	if cr.Containers["c"].State != cruntimetest.StateRunning {
+		t.Errorf("container of a pod which was not paused is %s", cr.Containers["c"].State)
+	}
+	want := pkgpause.State{Pods: []pkgpause.Pod{apiserver, nginx}}
+	if diff := cmp.Diff(want, savedState(t, r)); diff != "" {
+		t.Errorf("paused state mismatch, the deleted pod should be forgotten (-want +got):\n%s", diff)
+	}
+
+	// the pods already paused are kept, without being paused twice
+	ids, missing, err = RestorePause(cr, r, &want)
+	if err != nil || len(ids) != 0 || len(missing) != 0 {
+		t.Errorf("RestorePause() of paused pods = %v, %v, %v, want none paused and none missing", ids, missing, err)
+	}
+}
@@ -45,7 +45,14 @@ var bridgeConf = template.Must(template.New("bridge").Parse(`
       "hairpinMode": true,
       "ipam": {
           "type": "host-local",
+{{- if .PodCIDRv6}}
+          "ranges": [
+              [{"subnet": "{{.PodCIDR}}"}],
+              [{"subnet": "{{.PodCIDRv6}}"}]
+          ]
+{{- else}}
           "subnet": "{{.PodCIDR}}"
+{{- end}}
       }
     },
     {
@@ -69,7 +76,15 @@ func (c Bridge) String() string {
 }
 
 func (c Bridge) netconf() (assets.CopyableFile, error) {
-	input := &tmplInput{PodCIDR: DefaultPodCIDR}
+	v4, v6, err := SplitCIDRs(PodCIDR(c.cc))
+	if err != nil {
+		return nil, errors.Wrap(err, "pod CIDR")
+	}
+	input := &tmplInput{PodCIDR: v4, PodCIDRv6: v6}
+	if v4 == "" {
+		// single-stack IPv6
+		input.PodCIDR, input.PodCIDRv6 = v6, ""
+	}
 
 	b := bytes.Buffer{}
 	if err := bridgeConf.Execute(&b, input); err != nil {
@@ -105,3 +120,8 @@ func (c Bridge) Apply(r Runner) error {
 func (c Bridge) CIDR() string {
 	return DefaultPodCIDR
 }
+
+// RuntimeSettings returns the runtime settings for the bridge plugin, installed in the standard directories
+func (c Bridge) RuntimeSettings() CNIRuntimeSettings {
+	return defaultRuntimeSettings()
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/config"
+)
+
+func TestBridgeNetconf(t *testing.T) {
+	var tests = []struct {
+		description string
+		podCIDR     string
+		want        string
+	}{
+		{"default", "", `{"type":"host-local","subnet":"10.244.0.0/16"}`},
+		{"single-stack IPv4", "10.100.0.0/16", `{"type":"host-local","subnet":"10.100.0.0/16"}`},
+		{"single-stack IPv6", "fd00:10:244::/56", `{"type":"host-local","subnet":"fd00:10:244::/56"}`},
+		{"dual-stack", "10.244.0.0/16,fd00:10:244::/56", `{"type":"host-local","ranges":[[{"subnet":"10.244.0.0/16"}],[{"subnet":"fd00:10:244::/56"}]]}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			cc := config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{CNI: "bridge"}}
+			if tc.podCIDR != "" {
+				if err := cc.KubernetesConfig.ExtraOptions.Set("kubeadm.pod-network-cidr=" + tc.podCIDR); err != nil {
+					t.Fatalf("ExtraOptions.Set: %v", err)
+				}
+			}
+			f, err := Bridge{cc: cc}.netconf()
+			if err != nil {
+				t.Fatalf("netconf: %v", err)
+			}
+			data, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatalf("read netconf: %v", err)
+			}
+			var conf struct {
+				Plugins []struct {
+					IPAM json.RawMessage `json:"ipam"`
+				} `json:"plugins"`
+			}
+			if err := json.Unmarshal(data, &conf); err != nil {
+				t.Fatalf("netconf is not valid JSON: %v\n%s", err, data)
+			}
+			var got, want interface{}
+			if err := json.Unmarshal(conf.Plugins[0].IPAM, &got); err != nil {
+				t.Fatalf("ipam: %v", err)
+			}
+			if err := json.Unmarshal([]byte(tc.want), &want); err != nil {
+				t.Fatalf("want: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ipam mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSplitCIDRs(t *testing.T) {
+	var tests = []struct {
+		cidrs   string
+		v4      string
+		v6      string
+		wantErr bool
+	}{
+		{"10.244.0.0/16", "10.244.0.0/16", "", false},
+		{"fd00:10:244::/56", "", "fd00:10:244::/56", false},
+		{"fd00:10:244::/56, 10.244.0.0/16", "10.244.0.0/16", "fd00:10:244::/56", false},
+		{"", "", "", false},
+		{"10.244.0.0/16,10.245.0.0/16", "", "", true},
+		{"10.244.0.0", "", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.cidrs, func(t *testing.T) {
+			v4, v6, err := SplitCIDRs(tc.cidrs)
+			if (err != nil) != tc.wantErr || v4 != tc.v4 || v6 != tc.v6 {
+				t.Errorf("SplitCIDRs(%q) = %q, %q, %v, want %q, %q (error %v)", tc.cidrs, v4, v6, err, tc.v4, tc.v6, tc.wantErr)
+			}
+		})
+	}
+}
@@ -94,3 +94,8 @@ func (c Calico) CIDR() string {
 	// Calico docs specify 192.168.0.0/16 - but we do this for compatibility with other CNI's.
 	return DefaultPodCIDR
 }
+
+// RuntimeSettings returns the runtime settings for the plugins installed by calico in the standard directories
+func (c Calico) RuntimeSettings() CNIRuntimeSettings {
+	return defaultRuntimeSettings()
+}
@@ -808,6 +808,11 @@ func (c Cilium) CIDR() string {
 	return DefaultPodCIDR
 }
 
+// RuntimeSettings returns the runtime settings for the plugin installed by cilium in the standard directories
+func (c Cilium) RuntimeSettings() CNIRuntimeSettings {
+	return defaultRuntimeSettings()
+}
+
 // GenerateCiliumYAML generates the .yaml file
 func GenerateCiliumYAML() ([]byte, error) {
 
@@ -20,8 +20,10 @@ package cni
 import (
 	"context"
 	"fmt"
+	"net"
 	"os/exec"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/blang/semver/v4"
@@ -46,6 +48,10 @@ const (
 	// CustomConfDir is the custom CNI Config Directory path used to avoid conflicting CNI configs
 	// ref: https://github.com/kubernetes/minikube/issues/10984 and https://github.com/kubernetes/minikube/pull/11106
 	CustomConfDir = "/etc/cni/net.mk"
+	// DefaultBinDir is the default directory of the CNI plugin binaries
+	DefaultBinDir = "/opt/cni/bin"
+	// DefaultCacheDir is the default directory of the CNI plugins cache
+	DefaultCacheDir = "/var/lib/cni/cache"
 )
 
 var (
@@ -75,6 +81,26 @@ type Manager interface {
 
 	// String representation
 	String() string
+
+	// RuntimeSettings returns how the container runtime has to run the CNI plugins
+	RuntimeSettings() CNIRuntimeSettings
+}
+
+// CNIRuntimeSettings are the settings of the network plugin of the container runtime, used by cri-dockerd
+type CNIRuntimeSettings struct {
+	// NetworkPlugin is "cni" for the runtime to run the CNI plugins, or empty to leave the pod network to the CNI
+	NetworkPlugin string
+	// BinDir is the directory of the CNI plugin binaries
+	BinDir string
+	// ConfDir is the directory of the CNI configurations
+	ConfDir string
+	// CacheDir is the directory of the CNI plugins cache
+	CacheDir string
+}
+
+// defaultRuntimeSettings returns the settings running the CNI plugins from the standard directories
+func defaultRuntimeSettings() CNIRuntimeSettings {
+	return CNIRuntimeSettings{NetworkPlugin: "cni", BinDir: DefaultBinDir, ConfDir: DefaultConfDir, CacheDir: DefaultCacheDir}
 }
 
 // tmplInputs are inputs to CNI templates
@@ -83,6 +109,8 @@ type tmplInput struct {
 	PodCIDR      string
 	DefaultRoute string
 	CNIConfDir   string
+	// PodCIDRv6 is the IPv6 pod network of dual-stack clusters, whose IPv4 one is PodCIDR
+	PodCIDRv6 string
 }
 
 // New returns a new CNI manager
@@ -94,6 +122,17 @@ func New(cc *config.ClusterConfig) (Manager, error) {
 
 	klog.Infof("Creating CNI manager for %q", cc.KubernetesConfig.CNI)
 
+	cnm, err := newManager(cc)
+
+	if err := configureCNI(cc, cnm); err != nil {
+		klog.Errorf("unable to set CNI Config Directory: %v", err)
+	}
+
+	return cnm, err
+}
+
+// newManager returns the CNI manager selected by the cluster config
+func newManager(cc *config.ClusterConfig) (Manager, error) {
 	var cnm Manager
 	var err error
 	switch cc.KubernetesConfig.CNI {
@@ -114,12 +153,85 @@ func New(cc *config.ClusterConfig) (Manager, error) {
 	default:
 		cnm, err = NewCustom(*cc, cc.KubernetesConfig.CNI)
 	}
+	return cnm, err
+}
 
-	if err := configureCNI(cc, cnm); err != nil {
-		klog.Errorf("unable to set CNI Config Directory: %v", err)
+// ConfFiles returns the names of the configuration files that the CNI of the cluster writes to its configuration directory
+func ConfFiles(cc config.ClusterConfig) []string {
+	if IsDisabled(cc) {
+		return nil
+	}
+	cnm, err := newManager(&cc)
+	if err != nil {
+		return nil
 	}
+	switch cnm.(type) {
+	case Bridge:
+		return []string{"1-k8s.conflist"}
+	case KindNet:
+		return []string{"10-kindnet.conflist"}
+	case Flannel:
+		return []string{"10-flannel.conflist"}
+	case Calico:
+		return []string{"10-calico.conflist", "calico-kubeconfig"}
+	case Cilium:
+		return []string{"05-cilium.conf"}
+	}
+	return nil
+}
 
-	return cnm, err
+// RuntimeSettings returns the network plugin settings of the container runtime for the CNI of the cluster
+func RuntimeSettings(cc config.ClusterConfig) CNIRuntimeSettings {
+	if cc.KubernetesConfig.NetworkPlugin != "" && cc.KubernetesConfig.NetworkPlugin != "cni" {
+		return CNIRuntimeSettings{NetworkPlugin: cc.KubernetesConfig.NetworkPlugin}
+	}
+	cnm, err := newManager(&cc)
+	if err != nil {
+		klog.Warningf("unable to get the CNI manager, using the default CNI settings: %v", err)
+		return defaultRuntimeSettings()
+	}
+	if _, ok := cnm.(Disabled); ok && cc.KubernetesConfig.NetworkPlugin == "cni" {
+		// --network-plugin=cni without a CNI, which the user provides
+		return defaultRuntimeSettings()
+	}
+	return cnm.RuntimeSettings()
+}
+
+// PodCIDR returns the pod network of the cluster: the one given with --extra-config=kubeadm.pod-network-cidr, or the one of its CNI.
+// Dual-stack clusters have an IPv4 and an IPv6 network, separated by a comma.
+func PodCIDR(cc config.ClusterConfig) string {
+	if cidr := cc.KubernetesConfig.ExtraOptions.Get("pod-network-cidr", "kubeadm"); cidr != "" {
+		return cidr
+	}
+	cnm, err := newManager(&cc)
+	if err != nil {
+		return DefaultPodCIDR
+	}
+	return cnm.CIDR()
+}
+
+// SplitCIDRs returns the IPv4 and the IPv6 networks of comma separated CIDRs, either of which is empty if there is none
+func SplitCIDRs(cidrs string) (string, string, error) {
+	var v4, v6 string
+	for _, cidr := range strings.Split(cidrs, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return "", "", fmt.Errorf("%q is not a valid CIDR", cidr)
+		}
+		family := &v6
+		if ip.To4() != nil {
+			family = &v4
+		}
+		if *family != "" {
+			return "", "", fmt.Errorf("%q has more than one network of the same IP family", cidrs)
+		}
+		*family = cidr
+	}
+	return v4, v6, nil
 }
 
 // IsDisabled checks if CNI is disabled
@@ -142,7 +254,7 @@ func chooseDefault(cc config.ClusterConfig) Manager {
 	// For backwards compatibility with older profiles using --enable-default-cni
 	if cc.KubernetesConfig.EnableDefaultCNI {
 		klog.Infof("EnableDefaultCNI is true, recommending bridge")
-		return Bridge{}
+		return Bridge{cc: cc}
 	}
 
 	if driver.BareMetal(cc.Driver) {
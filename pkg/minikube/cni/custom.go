@@ -69,3 +69,8 @@ func (c Custom) Apply(r Runner) error {
 func (c Custom) CIDR() string {
 	return DefaultPodCIDR
 }
+
+// RuntimeSettings returns the runtime settings for a custom CNI, expected to use the standard directories
+func (c Custom) RuntimeSettings() CNIRuntimeSettings {
+	return defaultRuntimeSettings()
+}
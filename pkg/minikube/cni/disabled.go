@@ -51,3 +51,8 @@ func (c Disabled) CIDR() string {
 	// Even without any CNI we want our nodes to have spec.PodCIDR set.
 	return DefaultPodCIDR
 }
+
+// RuntimeSettings returns no network plugin, as there is no CNI to run
+func (c Disabled) RuntimeSettings() CNIRuntimeSettings {
+	return CNIRuntimeSettings{}
+}
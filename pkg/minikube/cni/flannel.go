@@ -702,3 +702,8 @@ func (c Flannel) Apply(r Runner) error {
 func (c Flannel) CIDR() string {
 	return DefaultPodCIDR
 }
+
+// RuntimeSettings returns the runtime settings for the plugins installed by flannel in the standard directories
+func (c Flannel) RuntimeSettings() CNIRuntimeSettings {
+	return defaultRuntimeSettings()
+}
@@ -163,8 +163,8 @@ func (c KindNet) String() string {
 // manifest returns a Kubernetes manifest for a CNI
 func (c KindNet) manifest() (assets.CopyableFile, error) {
 	input := &tmplInput{
-		DefaultRoute: "0.0.0.0/0", // assumes IPv4
-		PodCIDR:      DefaultPodCIDR,
+		DefaultRoute: "0.0.0.0/0",   // assumes IPv4
+		PodCIDR:      PodCIDR(c.cc), // kindnet takes both networks of dual-stack clusters
 		ImageName:    images.KindNet(c.cc.KubernetesConfig.ImageRepository),
 		CNIConfDir:   ConfDir,
 	}
@@ -195,3 +195,10 @@ func (c KindNet) Apply(r Runner) error {
 func (c KindNet) CIDR() string {
 	return DefaultPodCIDR
 }
+
+// RuntimeSettings returns the runtime settings for kindnet, whose configuration is kept apart from the other CNIs
+func (c KindNet) RuntimeSettings() CNIRuntimeSettings {
+	s := defaultRuntimeSettings()
+	s.ConfDir = CustomConfDir
+	return s
+}
@@ -19,6 +19,7 @@ package command
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -29,6 +30,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/assets"
 )
 
@@ -64,6 +66,9 @@ type Runner interface {
 	// not all implementors are guaranteed to handle all the properties of cmd.
 	RunCmd(cmd *exec.Cmd) (*RunResult, error)
 
+	// RunCmdContext runs a cmd like RunCmd, stopping it when ctx is done
+	RunCmdContext(ctx context.Context, cmd *exec.Cmd) (*RunResult, error)
+
 	// StartCmd starts a cmd of exec.Cmd type.
 	// This func in non-blocking, use WaitCmd to block until complete.
 	// Not all implementors are guaranteed to handle all the properties of cmd.
@@ -211,3 +216,44 @@ func writeFile(dst string, f assets.CopyableFile, perms os.FileMode) error {
 	}
 	return w.Close()
 }
+
+// stopGracePeriod is how long a cancelled command has to exit after being interrupted, before it is killed
+const stopGracePeriod = 5 * time.Second
+
+// runContext runs a local command, interrupting it when ctx is done, and killing it if it does not exit in time
+func runContext(ctx context.Context, cmd *exec.Cmd) error {
+	if ctx.Done() == nil {
+		return cmd.Run()
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	klog.Infof("stopping %v: %v", cmd.Args, ctx.Err())
+	// sudo passes interrupts on to the command, which it can not do with kills
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		klog.Infof("unable to interrupt %v: %v", cmd.Args, err)
+		_ = cmd.Process.Kill()
+	}
+	select {
+	case <-done:
+	case <-time.After(stopGracePeriod):
+		_ = cmd.Process.Kill()
+		<-done
+	}
+	return ctx.Err()
+}
+
+// cancelled returns the error of a command stopped as ctx is done
+func cancelled(ctx context.Context, rr *RunResult) error {
+	return errors.Wrapf(ctx.Err(), "%s", rr.Command())
+}
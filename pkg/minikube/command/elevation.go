@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/kballard/go-shellquote"
+	"golang.org/x/term"
+)
+
+// SudoPasswordEnv is the environment variable holding the password of sudo on the hosts of the ssh driver
+const SudoPasswordEnv = "MINIKUBE_SSH_SUDO_PASSWORD"
+
+// Elevation is how a runner runs the commands which request root privileges by starting with sudo, as in 'sudo systemctl restart docker'
+type Elevation string
+
+const (
+	// ElevationSudo runs them with sudo as they are, which must not ask for a password
+	ElevationSudo Elevation = "sudo"
+	// ElevationSudoPassword runs them with sudo, which is given the password from SudoPassword
+	ElevationSudoPassword Elevation = "password"
+	// ElevationDoas runs them with doas, which must permit the user without a password
+	ElevationDoas Elevation = "doas"
+	// ElevationNone runs them without sudo, as the user is root already
+	ElevationNone Elevation = "none"
+)
+
+// Elevations are the supported elevations
+var Elevations = []Elevation{ElevationSudo, ElevationSudoPassword, ElevationDoas, ElevationNone}
+
+// ParseElevation returns the elevation named s, the default one if empty
+func ParseElevation(s string) (Elevation, error) {
+	if s == "" {
+		return ElevationSudo, nil
+	}
+	for _, e := range Elevations {
+		if string(e) == s {
+			return e, nil
+		}
+	}
+	names := []string{}
+	for _, e := range Elevations {
+		names = append(names, string(e))
+	}
+	return "", fmt.Errorf("invalid sudo mode %q, valid ones are: %s", s, strings.Join(names, ", "))
+}
+
+// Elevator is implemented by the drivers whose hosts elevate the privileges of commands in another way than passwordless sudo
+type Elevator interface {
+	Elevation() Elevation
+}
+
+// sudoRe matches the command lines which run sudo
+var sudoRe = regexp.MustCompile(`(^|[^\w/.-])sudo($|\s)`)
+
+// skipSudoOptions drops the options of sudo from the arguments of the sudo shell function, remembering whether -s asked for a shell
+const skipSudoOptions = `local shell=; while [ $# -gt 0 ] && [ "${1#-}" != "$1" ]; do [ "$1" = -s ] && shell=1; shift; done; `
+
+// sudoFunctions are the shell functions replacing sudo, which the nested bash scripts inherit.
+// They run the commands through env, which sets the variables given before them as sudo does, as in 'sudo KEY=value command'.
+var sudoFunctions = map[Elevation]string{
+	ElevationNone: `sudo() { ` + skipSudoOptions + `if [ -n "$shell" ]; then /bin/bash -c "$*"; else env "$@"; fi; }; export -f sudo; `,
+	ElevationDoas: `sudo() { ` + skipSudoOptions + `if [ -n "$shell" ]; then doas -n /bin/bash -c "$*"; else doas -n env "$@"; fi; }; export -f sudo; `,
+	// the password is the first line of stdin, which only the askpass helper holds, so that it is never on a command line
+	// nor in the environment of the commands. The helper prints its own lines from the third one on, and is removed on exit.
+	ElevationSudoPassword: `IFS= read -r password; ` +
+		`SUDO_ASKPASS=$(mktemp "$HOME/.minikube-askpass.XXXXXX") && trap 'rm -f "$SUDO_ASKPASS"' EXIT && chmod 700 "$SUDO_ASKPASS" && ` +
+		`printf '#!/bin/sh\nexec tail -n +3 "$0"\n%s\n' "$password" >"$SUDO_ASKPASS" || exit 1; unset password; export SUDO_ASKPASS; ` +
+		`sudo() { command sudo -A "$@"; }; export -f sudo; `,
+}
+
+// elevate returns the command line running line with the elevation, through bash so that the sudo of the scripts is elevated as well,
+// and whether the password of sudo has to be the first line of its stdin
+func elevate(e Elevation, line string) (string, bool) {
+	fn, ok := sudoFunctions[e]
+	if !ok || !sudoRe.MatchString(line) {
+		return line, false
+	}
+	return shellquote.Join("/bin/bash", "-c", fn+line), e == ElevationSudoPassword
+}
+
+var (
+	sudoPasswordOnce sync.Once
+	sudoPassword     string
+	errSudoPassword  error
+)
+
+// SudoPassword returns the password of sudo from the SudoPasswordEnv environment variable, or else asks for it once if stdin is a terminal
+func SudoPassword() (string, error) {
+	sudoPasswordOnce.Do(func() {
+		if p, ok := os.LookupEnv(SudoPasswordEnv); ok {
+			sudoPassword = p
+			return
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			errSudoPassword = fmt.Errorf("sudo needs a password: set it in the %s environment variable", SudoPasswordEnv)
+			return
+		}
+		fmt.Fprint(os.Stderr, "Password of sudo on the host: ")
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			errSudoPassword = fmt.Errorf("reading the password of sudo: %v", err)
+			return
+		}
+		sudoPassword = string(b)
+	})
+	return sudoPassword, errSudoPassword
+}
+
+// elevatedStdin returns the stdin of an elevated command: the password of sudo as its first line if needed, followed by stdin
+func elevatedStdin(password bool, stdin io.Reader) (io.Reader, error) {
+	if !password {
+		return stdin, nil
+	}
+	p, err := SudoPassword()
+	if err != nil {
+		return nil, err
+	}
+	if stdin == nil {
+		return strings.NewReader(p + "\n"), nil
+	}
+	return io.MultiReader(strings.NewReader(p+"\n"), stdin), nil
+}
+
+// Sudo returns the command running args as root. The runners elevate it with the Elevation of their host.
+func Sudo(args ...string) *exec.Cmd {
+	return exec.Command("sudo", args...)
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kballard/go-shellquote"
+)
+
+func TestParseElevation(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Elevation
+		wantErr bool
+	}{
+		{"", ElevationSudo, false},
+		{"sudo", ElevationSudo, false},
+		{"password", ElevationSudoPassword, false},
+		{"doas", ElevationDoas, false},
+		{"none", ElevationNone, false},
+		{"su", "", true},
+	}
+	for _, tc := range tests {
+		got, err := ParseElevation(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseElevation(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("ParseElevation(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestElevate(t *testing.T) {
+	tests := []struct {
+		name         string
+		e            Elevation
+		line         string
+		wrapped      bool
+		wantPassword bool
+	}{
+		{"sudo", ElevationSudo, "sudo systemctl restart docker", false, false},
+		{"sudo not root", ElevationNone, "docker version", false, false},
+		{"sudo path", ElevationNone, "ls /etc/sudoers.d/sudo", false, false},
+		{"none", ElevationNone, "sudo systemctl restart docker", true, false},
+		{"none script", ElevationNone, "/bin/bash -c \"sudo mkdir -p /x && sudo cp a /x\"", true, false},
+		{"doas", ElevationDoas, "sudo -E crictl ps", true, false},
+		{"password", ElevationSudoPassword, "sudo systemctl restart docker", true, true},
+		{"password not root", ElevationSudoPassword, "uname -a", false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, password := elevate(tc.e, tc.line)
+			if password != tc.wantPassword {
+				t.Errorf("elevate(%q, %q) password = %v, want %v", tc.e, tc.line, password, tc.wantPassword)
+			}
+			if !tc.wrapped {
+				if got != tc.line {
+					t.Errorf("elevate(%q, %q) = %q, want it unchanged", tc.e, tc.line, got)
+				}
+				return
+			}
+			args, err := shellquote.Split(got)
+			if err != nil {
+				t.Fatalf("split %q: %v", got, err)
+			}
+			want := []string{"/bin/bash", "-c", sudoFunctions[tc.e] + tc.line}
+			if strings.Join(args, "\x00") != strings.Join(want, "\x00") {
+				t.Errorf("elevate(%q, %q) = %q, want %q", tc.e, tc.line, args, want)
+			}
+		})
+	}
+}
+
+func TestElevateNoneRuns(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash is not available on windows")
+	}
+	if _, err := exec.LookPath("/bin/bash"); err != nil {
+		t.Skip("bash is not available")
+	}
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"sudo echo hello", "hello"},
+		{"sudo -E echo hello", "hello"},
+		{"sudo -s 'echo a && echo b'", "a\nb"},
+		{"/bin/bash -c 'sudo echo nested'", "nested"},
+		{"sudo GREETING=hello printenv GREETING", "hello"},
+	}
+	for _, tc := range tests {
+		line, _ := elevate(ElevationNone, tc.line)
+		out, err := exec.Command("/bin/sh", "-c", line).CombinedOutput()
+		if err != nil {
+			t.Fatalf("running %q: %v: %s", line, err, out)
+		}
+		if got := strings.TrimSpace(string(out)); got != tc.want {
+			t.Errorf("running %q = %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestElevatePasswordAskpass(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash is not available on windows")
+	}
+	if _, err := exec.LookPath("/bin/bash"); err != nil {
+		t.Skip("bash is not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+	// the line mentions sudo to be elevated, without running it
+	line, password := elevate(ElevationSudoPassword, `: sudo ; env; echo "$SUDO_ASKPASS"; "$SUDO_ASKPASS"`)
+	if !password {
+		t.Fatalf("elevate(%q) does not take the password", ElevationSudoPassword)
+	}
+	c := exec.Command("/bin/sh", "-c", line)
+	c.Stdin = strings.NewReader("s3 cret%s\ndata\n")
+	out, err := c.CombinedOutput()
+	if err != nil {
+		t.Fatalf("running %q: %v: %s", line, err, out)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if got := lines[len(lines)-1]; got != "s3 cret%s" {
+		t.Errorf("askpass printed %q, want the password", got)
+	}
+	if n := strings.Count(string(out), "s3 cret"); n != 1 {
+		t.Errorf("the password is in the environment of the commands: %s", out)
+	}
+	askpass := lines[len(lines)-2]
+	if _, err := os.Stat(askpass); !os.IsNotExist(err) {
+		t.Errorf("the askpass helper %s is left behind: %v", askpass, err)
+	}
+}
+
+func TestElevatedStdin(t *testing.T) {
+	t.Setenv(SudoPasswordEnv, "s3cret")
+	r, err := elevatedStdin(false, nil)
+	if err != nil || r != nil {
+		t.Errorf("elevatedStdin(false, nil) = %v, %v, want nil", r, err)
+	}
+
+	r, err = elevatedStdin(true, strings.NewReader("data"))
+	if err != nil {
+		t.Fatalf("elevatedStdin(true) error: %v", err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	if string(b) != "s3cret\ndata" {
+		t.Errorf("elevatedStdin(true) = %q, want the password line before the data", b)
+	}
+}
+
+func TestSudo(t *testing.T) {
+	c := Sudo("systemctl", "restart", "docker")
+	if got := strings.Join(c.Args, " "); got != "sudo systemctl restart docker" {
+		t.Errorf("Sudo() = %q", got)
+	}
+}
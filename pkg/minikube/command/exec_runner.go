@@ -18,6 +18,7 @@ package command
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -47,6 +48,11 @@ func NewExecRunner(sudo bool) Runner {
 
 // RunCmd implements the Command Runner interface to run a exec.Cmd object
 func (e *execRunner) RunCmd(cmd *exec.Cmd) (*RunResult, error) {
+	return e.RunCmdContext(context.Background(), cmd)
+}
+
+// RunCmdContext implements the Command Runner interface to run a exec.Cmd object until ctx is done
+func (e *execRunner) RunCmdContext(ctx context.Context, cmd *exec.Cmd) (*RunResult, error) {
 	rr := &RunResult{Args: cmd.Args}
 	klog.Infof("Run: %v", rr.Command())
 
@@ -73,9 +79,12 @@ func (e *execRunner) RunCmd(cmd *exec.Cmd) (*RunResult, error) {
 	cmd.Stderr = errb
 
 	start := time.Now()
-	err := cmd.Run()
+	err := runContext(ctx, cmd)
 	elapsed := time.Since(start)
 
+	if ctx.Err() != nil {
+		return rr, cancelled(ctx, rr)
+	}
 	if exitError, ok := err.(*exec.ExitError); ok {
 		rr.ExitCode = exitError.ExitCode()
 	}
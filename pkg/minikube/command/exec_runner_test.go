@@ -0,0 +1,47 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestExecRunnerRunCmdContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep is not available on windows")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	rr, err := NewExecRunner(false).RunCmdContext(ctx, exec.Command("sleep", "30"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunCmdContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > stopGracePeriod {
+		t.Errorf("RunCmdContext() took %s to return after being cancelled", elapsed)
+	}
+	if rr.Command() != "sleep 30" {
+		t.Errorf("RunCmdContext() ran %q", rr.Command())
+	}
+}
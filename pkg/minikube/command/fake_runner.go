@@ -18,6 +18,7 @@ package command
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os/exec"
@@ -47,6 +48,14 @@ func NewFakeCommandRunner() *FakeCommandRunner {
 	return &FakeCommandRunner{}
 }
 
+// RunCmdContext implements the Command Runner interface, the fake commands completing at once
+func (f *FakeCommandRunner) RunCmdContext(ctx context.Context, cmd *exec.Cmd) (*RunResult, error) {
+	if ctx.Err() != nil {
+		return &RunResult{Args: cmd.Args}, ctx.Err()
+	}
+	return f.RunCmd(cmd)
+}
+
 // RunCmd implements the Command Runner interface to run a exec.Cmd object
 func (f *FakeCommandRunner) RunCmd(cmd *exec.Cmd) (*RunResult, error) {
 	rr := &RunResult{Args: cmd.Args}
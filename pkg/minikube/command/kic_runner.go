@@ -18,6 +18,7 @@ package command
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -52,6 +53,12 @@ func NewKICRunner(containerNameOrID string, oci string) Runner {
 }
 
 func (k *kicRunner) RunCmd(cmd *exec.Cmd) (*RunResult, error) {
+	return k.RunCmdContext(context.Background(), cmd)
+}
+
+// RunCmdContext runs a command in the container, stopping it when ctx is done.
+// Stopping the docker client does not stop the command it runs, so cancellable commands record their pid to be stopped with.
+func (k *kicRunner) RunCmdContext(ctx context.Context, cmd *exec.Cmd) (*RunResult, error) {
 	args := []string{
 		"exec",
 		// run with privileges so we can remount etc..
@@ -79,6 +86,12 @@ func (k *kicRunner) RunCmd(cmd *exec.Cmd) (*RunResult, error) {
 		k.nameOrID, // ... against the container
 	)
 
+	pidFile := ""
+	if ctx.Done() != nil {
+		pidFile = fmt.Sprintf("/tmp/minikube-cmd-%d.pid", time.Now().UnixNano())
+		args = append(args, "/bin/sh", "-c", fmt.Sprintf(`echo $$ > %s; exec "$@"`, pidFile), "sh")
+	}
+
 	args = append(
 		args,
 		cmd.Args...,
@@ -115,8 +128,14 @@ func (k *kicRunner) RunCmd(cmd *exec.Cmd) (*RunResult, error) {
 
 	start := time.Now()
 
-	err := oc.Run()
+	err := runContext(ctx, oc)
 	elapsed := time.Since(start)
+	if pidFile != "" {
+		k.stopRemote(pidFile, ctx.Err() != nil)
+	}
+	if ctx.Err() != nil {
+		return rr, cancelled(ctx, rr)
+	}
 	if err == nil {
 		// Reduce log spam
 		if elapsed > (1 * time.Second) {
@@ -131,6 +150,18 @@ func (k *kicRunner) RunCmd(cmd *exec.Cmd) (*RunResult, error) {
 
 }
 
+// stopRemote interrupts the command whose pid is in pidFile if it was cancelled, along with its children, and removes pidFile
+func (k *kicRunner) stopRemote(pidFile string, stop bool) {
+	script := fmt.Sprintf("rm -f %s", pidFile)
+	if stop {
+		script = fmt.Sprintf(`pid=$(cat %s) && sudo pkill -INT -P "$pid"; sudo kill -INT "$pid"; %s`, pidFile, script)
+	}
+	c := oci.PrefixCmd(exec.Command(k.ociBin, "exec", "--privileged", k.nameOrID, "/bin/sh", "-c", script))
+	if out, err := c.CombinedOutput(); err != nil {
+		klog.Warningf("unable to stop the command of %s: %v: %s", pidFile, err, out)
+	}
+}
+
 func (k *kicRunner) StartCmd(cmd *exec.Cmd) (*StartedCmd, error) {
 	return nil, fmt.Errorf("kicRunner does not support StartCmd - you could be the first to add it")
 }
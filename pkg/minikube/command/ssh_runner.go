@@ -19,6 +19,7 @@ package command
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os/exec"
@@ -151,7 +152,24 @@ func (s *SSHRunner) Remove(f assets.CopyableFile) error {
 	}
 
 	defer sess.Close()
-	return sess.Run(fmt.Sprintf("sudo rm %s", dst))
+	cmd, stdin, err := s.elevate(fmt.Sprintf("sudo rm %s", dst))
+	if err != nil {
+		return err
+	}
+	sess.Stdin = stdin
+	return sess.Run(cmd)
+}
+
+// elevate returns the command line running line with the elevation of the host,
+// and the stdin to give it, which holds the password of sudo if needed
+func (s *SSHRunner) elevate(line string) (string, io.Reader, error) {
+	e, ok := s.d.(Elevator)
+	if !ok {
+		return line, nil, nil
+	}
+	line, password := elevate(e.Elevation(), line)
+	stdin, err := elevatedStdin(password, nil)
+	return line, stdin, err
 }
 
 // teeSSH runs an SSH command, streaming stdout, stderr to logs
@@ -187,6 +205,11 @@ func teeSSH(s *ssh.Session, cmd string, outB io.Writer, errB io.Writer) error {
 
 // RunCmd implements the Command Runner interface to run a exec.Cmd object
 func (s *SSHRunner) RunCmd(cmd *exec.Cmd) (*RunResult, error) {
+	return s.RunCmdContext(context.Background(), cmd)
+}
+
+// RunCmdContext implements the Command Runner interface to run a exec.Cmd object until ctx is done
+func (s *SSHRunner) RunCmdContext(ctx context.Context, cmd *exec.Cmd) (*RunResult, error) {
 	if cmd.Stdin != nil {
 		return nil, fmt.Errorf("SSHRunner does not support stdin - you could be the first to add it")
 	}
@@ -224,9 +247,34 @@ func (s *SSHRunner) RunCmd(cmd *exec.Cmd) (*RunResult, error) {
 		}
 	}()
 
-	err = teeSSH(sess, shellquote.Join(cmd.Args...), outb, errb)
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				// sshd passes signals on since OpenSSH 7.9, and closing the session stops older ones from waiting
+				if err := sess.Signal(ssh.SIGINT); err != nil {
+					klog.Infof("unable to interrupt %s: %v", rr.Command(), err)
+				}
+				_ = sess.Close()
+			case <-stop:
+			}
+		}()
+	}
+
+	line, stdin, err := s.elevate(shellquote.Join(cmd.Args...))
+	if err != nil {
+		return rr, err
+	}
+	sess.Stdin = stdin
+	err = teeSSH(sess, line, outb, errb)
 	elapsed := time.Since(start)
 
+	if ctx.Err() != nil {
+		return rr, cancelled(ctx, rr)
+	}
+
 	if exitError, ok := err.(*exec.ExitError); ok {
 		rr.ExitCode = exitError.ExitCode()
 	}
@@ -306,9 +354,14 @@ func (s *SSHRunner) StartCmd(cmd *exec.Cmd) (*StartedCmd, error) {
 		return sc, errors.Wrap(err, "NewSession")
 	}
 
+	line, stdin, err := s.elevate(shellquote.Join(cmd.Args...))
+	if err != nil {
+		return sc, err
+	}
+	sess.Stdin = stdin
 	s.s = sess
 
-	err = teeSSHStart(s.s, shellquote.Join(cmd.Args...), outb, errb, &wg)
+	err = teeSSHStart(s.s, line, outb, errb, &wg)
 
 	return sc, err
 }
@@ -376,6 +429,18 @@ func (s *SSHRunner) Copy(f assets.CopyableFile) error {
 		}
 	}()
 
+	scp := fmt.Sprintf("sudo test -d %s && sudo scp -t %s", f.GetTargetDir(), f.GetTargetDir())
+	mtime, err := f.GetModTime()
+	if err != nil {
+		klog.Infof("error getting modtime for %s: %v", dst, err)
+	} else if mtime != (time.Time{}) {
+		scp += fmt.Sprintf(" && sudo touch -d \"%s\" %s", mtime.Format(layout), dst)
+	}
+	cmd, password, err := s.elevate(scp)
+	if err != nil {
+		return err
+	}
+
 	w, err := sess.StdinPipe()
 	if err != nil {
 		return errors.Wrap(err, "StdinPipe")
@@ -387,6 +452,11 @@ func (s *SSHRunner) Copy(f assets.CopyableFile) error {
 
 	g.Go(func() error {
 		defer w.Close()
+		if password != nil {
+			if _, err := io.Copy(w, password); err != nil {
+				return errors.Wrap(err, "writing the password of sudo")
+			}
+		}
 		header := fmt.Sprintf("C%s %d %s\n", f.GetPermissions(), f.GetLength(), f.GetTargetName())
 		fmt.Fprint(w, header)
 		if f.GetLength() == 0 {
@@ -406,14 +476,7 @@ func (s *SSHRunner) Copy(f assets.CopyableFile) error {
 		return nil
 	})
 
-	scp := fmt.Sprintf("sudo test -d %s && sudo scp -t %s", f.GetTargetDir(), f.GetTargetDir())
-	mtime, err := f.GetModTime()
-	if err != nil {
-		klog.Infof("error getting modtime for %s: %v", dst, err)
-	} else if mtime != (time.Time{}) {
-		scp += fmt.Sprintf(" && sudo touch -d \"%s\" %s", mtime.Format(layout), dst)
-	}
-	out, err := sess.CombinedOutput(scp)
+	out, err := sess.CombinedOutput(cmd)
 	if err != nil {
 		return fmt.Errorf("%s: %s\noutput: %s", scp, err, out)
 	}
@@ -449,6 +512,11 @@ func (s *SSHRunner) CopyFrom(f assets.CopyableFile) error {
 	klog.Infof("scp %s --> %s (%d bytes)", dst, src, length)
 	f.SetLength(length)
 
+	scp, password, err := s.elevate(fmt.Sprintf("sudo scp -f %s", f.GetTargetPath()))
+	if err != nil {
+		return err
+	}
+
 	r, err := sess.StdoutPipe()
 	if err != nil {
 		return errors.Wrap(err, "StdoutPipe")
@@ -465,6 +533,11 @@ func (s *SSHRunner) CopyFrom(f assets.CopyableFile) error {
 	g.Go(func() error {
 		defer w.Close()
 		br := bufio.NewReader(r)
+		if password != nil {
+			if _, err := io.Copy(w, password); err != nil {
+				return errors.Wrap(err, "writing the password of sudo")
+			}
+		}
 		fmt.Fprint(w, "\x00")
 		b, err := br.ReadBytes('\n')
 		if err != nil {
@@ -491,10 +564,9 @@ func (s *SSHRunner) CopyFrom(f assets.CopyableFile) error {
 		return nil
 	})
 
-	scp := fmt.Sprintf("sudo scp -f %s", f.GetTargetPath())
 	err = sess.Start(scp)
 	if err != nil {
-		return fmt.Errorf("%s: %s", scp, err)
+		return fmt.Errorf("sudo scp -f %s: %s", f.GetTargetPath(), err)
 	}
 	return g.Wait()
 }
@@ -56,6 +56,8 @@ const (
 	EmbedCerts = "EmbedCerts"
 	// MaxAuditEntries is the maximum number of audit entries to retain
 	MaxAuditEntries = "MaxAuditEntries"
+	// ImageCacheWarnSize is the size of the image cache of the host from which start warns about it
+	ImageCacheWarnSize = "image-cache-warn-size"
 )
 
 var (
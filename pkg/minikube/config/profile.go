@@ -336,3 +336,23 @@ func MachineName(cc ClusterConfig, n Node) string {
 	}
 	return fmt.Sprintf("%s-%s", cc.Name, n.Name)
 }
+
+// NodeRuntime returns the container runtime of a node, which defaults to the one of the cluster
+func NodeRuntime(cc ClusterConfig, n Node) string {
+	if n.ContainerRuntime != "" {
+		return n.ContainerRuntime
+	}
+	return cc.KubernetesConfig.ContainerRuntime
+}
+
+// ForNode returns the cluster config as seen by a node, using the container runtime of the node
+func ForNode(cc ClusterConfig, n Node) ClusterConfig {
+	rt := NodeRuntime(cc, n)
+	if rt == cc.KubernetesConfig.ContainerRuntime {
+		return cc
+	}
+	cc.KubernetesConfig.ContainerRuntime = rt
+	// a custom CRI socket belongs to the runtime of the cluster
+	cc.KubernetesConfig.CRISocket = ""
+	return cc
+}
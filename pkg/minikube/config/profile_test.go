@@ -19,7 +19,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -346,3 +348,111 @@ func TestGetPrimaryControlPlane(t *testing.T) {
 		}()
 	}
 }
+
+func TestForNode(t *testing.T) {
+	cc := ClusterConfig{KubernetesConfig: KubernetesConfig{ContainerRuntime: "containerd", CRISocket: "/run/custom.sock"}}
+
+	var tests = []struct {
+		description string
+		runtime     string
+		wantRuntime string
+		wantSocket  string
+	}{
+		{"default", "", "containerd", "/run/custom.sock"},
+		{"same", "containerd", "containerd", "/run/custom.sock"},
+		{"other", "docker", "docker", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			n := Node{Name: "m02", ContainerRuntime: tc.runtime}
+			if got := NodeRuntime(cc, n); got != tc.wantRuntime {
+				t.Errorf("NodeRuntime() = %q, want %q", got, tc.wantRuntime)
+			}
+			got := ForNode(cc, n)
+			if got.KubernetesConfig.ContainerRuntime != tc.wantRuntime || got.KubernetesConfig.CRISocket != tc.wantSocket {
+				t.Errorf("ForNode() runtime %q and socket %q, want %q and %q", got.KubernetesConfig.ContainerRuntime, got.KubernetesConfig.CRISocket, tc.wantRuntime, tc.wantSocket)
+			}
+		})
+	}
+	if cc.KubernetesConfig.ContainerRuntime != "containerd" || cc.KubernetesConfig.CRISocket != "/run/custom.sock" {
+		t.Errorf("ForNode() modified the cluster config: %+v", cc.KubernetesConfig)
+	}
+}
+
+func TestSaveProfileRuntime(t *testing.T) {
+	miniDir := t.TempDir()
+	updated := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	cc := &ClusterConfig{
+		Name: "runtime",
+		Nodes: []Node{
+			{Name: "", ControlPlane: true, Runtime: &RuntimeInfo{Name: "containerd", Socket: "/run/containerd/containerd.sock", Version: "1.6.8", LastUpdated: updated}},
+			{Name: "m02", Worker: true},
+		},
+	}
+	if err := SaveProfile(cc.Name, cc, miniDir); err != nil {
+		t.Fatalf("SaveProfile() error: %v", err)
+	}
+
+	data, err := os.ReadFile(profileFilePath(cc.Name, miniDir))
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if got := strings.Count(string(data), `"Runtime"`); got != 1 {
+		t.Errorf("config.json has %d Runtime fields, want 1 as it is omitted for nodes never started:\n%s", got, data)
+	}
+
+	p, err := LoadProfile(cc.Name, miniDir)
+	if err != nil {
+		t.Fatalf("LoadProfile() error: %v", err)
+	}
+	got := p.Config.Nodes[0].Runtime
+	if got == nil {
+		t.Fatalf("LoadProfile() lost the runtime of the control plane")
+	}
+	want := cc.Nodes[0].Runtime
+	if got.Name != want.Name || got.Socket != want.Socket || got.Version != want.Version || !got.LastUpdated.Equal(want.LastUpdated) {
+		t.Errorf("LoadProfile() runtime = %+v, want %+v", got, want)
+	}
+	if p.Config.Nodes[1].Runtime != nil {
+		t.Errorf("LoadProfile() runtime of m02 = %+v, want nil", p.Config.Nodes[1].Runtime)
+	}
+}
+
+func TestSaveProfilePaused(t *testing.T) {
+	miniDir := t.TempDir()
+	var tests = []struct {
+		name   string
+		paused *PausedNamespaces
+	}{
+		{"not paused", nil},
+		{"all namespaces", &PausedNamespaces{}},
+		{"included and excluded", &PausedNamespaces{Namespaces: []string{"kube-system", "monitoring"}, ExcludeNamespaces: []string{"monitoring"}}},
+		{"all but excluded", &PausedNamespaces{ExcludeNamespaces: []string{"monitoring"}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cc := &ClusterConfig{Name: "paused", Nodes: []Node{{ControlPlane: true}}, Paused: tc.paused}
+			if err := SaveProfile(cc.Name, cc, miniDir); err != nil {
+				t.Fatalf("SaveProfile() error: %v", err)
+			}
+			p, err := LoadProfile(cc.Name, miniDir)
+			if err != nil {
+				t.Fatalf("LoadProfile() error: %v", err)
+			}
+			got := p.Config.Paused
+			if (got == nil) != (tc.paused == nil) {
+				t.Fatalf("LoadProfile() paused = %+v, want %+v", got, tc.paused)
+			}
+			if got == nil {
+				return
+			}
+			// nil namespaces are all of them, which must not load as none
+			if (got.Namespaces == nil) != (tc.paused.Namespaces == nil) || strings.Join(got.Namespaces, ",") != strings.Join(tc.paused.Namespaces, ",") {
+				t.Errorf("LoadProfile() paused namespaces = %#v, want %#v", got.Namespaces, tc.paused.Namespaces)
+			}
+			if strings.Join(got.ExcludeNamespaces, ",") != strings.Join(tc.paused.ExcludeNamespaces, ",") {
+				t.Errorf("LoadProfile() excluded namespaces = %v, want %v", got.ExcludeNamespaces, tc.paused.ExcludeNamespaces)
+			}
+		})
+	}
+}
@@ -49,7 +49,14 @@ type ClusterConfig struct {
 	ContainerVolumeMounts   []string // Only used by container drivers: Docker, Podman
 	InsecureRegistry        []string
 	RegistryMirror          []string
-	HostOnlyCIDR            string // Only used by the virtualbox driver
+	RegistryCache           bool     // Pull Docker Hub images through the registry cache of the host
+	AssumeOffline           bool     // Fail instead of pulling images, which must be preloaded or cached
+	PreloadAddonImages      []string // Addons whose images are pulled in the background during start, without enabling them
+	PreloadSource           string   // Base URL of a mirror of the preload tarballs, empty for the default bucket
+	PreloadChecksum         string   // "skip" or "sha256:<value>" to override the verification of the preload tarballs
+	PreloadChecksumVersion  string   // Kubernetes version of the preload tarball a "sha256:<value>" PreloadChecksum verifies
+	PreloadSourceFallback   bool     // Download the preload tarballs missing from PreloadSource from the default bucket
+	HostOnlyCIDR            string   // Only used by the virtualbox driver
 	HypervVirtualSwitch     string
 	HypervUseExternalSwitch bool
 	HypervExternalAdapter   string
@@ -60,6 +67,10 @@ type ClusterConfig struct {
 	KVMNUMACount            int    // Only used by the KVM2 driver
 	APIServerPort           int
 	DockerOpt               []string // Each entry is formatted as KEY=VALUE.
+	DockerFeatures          []string // Each entry is formatted as KEY=VALUE, merged into daemon.json.
+	DockerBridgeCIDR        string   // The network of the docker0 bridge in the node, chosen by docker if empty
+	DockerMTU               int      // The MTU of the docker0 bridge in the node, chosen by docker if 0
+	GPUs                    string   // The GPUs exposed to the node, as "all", docker driver and runtime only
 	DisableDriverMounts     bool     // Only used by virtualbox
 	NFSShare                []string
 	NFSSharesRoot           string
@@ -73,6 +84,7 @@ type ClusterConfig struct {
 	SSHUser                 string // Only used by ssh driver
 	SSHKey                  string // Only used by ssh driver
 	SSHPort                 int    // Only used by ssh driver
+	SSHSudo                 string // Only used by ssh driver
 	KubernetesConfig        KubernetesConfig
 	Nodes                   []Node
 	Addons                  map[string]bool
@@ -104,6 +116,8 @@ type ClusterConfig struct {
 	CustomQemuFirmwarePath  string
 	SocketVMnetClientPath   string
 	SocketVMnetPath         string
+	// Paused are the namespaces of the last 'minikube pause', which a bare 'minikube unpause' unpauses
+	Paused *PausedNamespaces `json:",omitempty"`
 }
 
 // KubernetesConfig contains the parameters used to configure the VM Kubernetes.
@@ -117,6 +131,11 @@ type KubernetesConfig struct {
 	DNSDomain           string
 	ContainerRuntime    string
 	CRISocket           string
+	DockerOnDemand      bool   // keep docker socket activated when using another container runtime
+	IsolatedBuilder     bool   // build images in a dockerd of their own, apart from the one running Kubernetes
+	CRISocketGroup      bool   // give the docker group access to the socket of cri-dockerd
+	RuntimeFallback     bool   // use containerd when the image lacks the cri-dockerd required by the docker runtime
+	RuntimeFallbackFrom string // the runtime which was replaced by ContainerRuntime, as the image lacked its dependencies
 	NetworkPlugin       string
 	FeatureGates        string // https://kubernetes.io/docs/reference/command-line-tools-reference/feature-gates/
 	ServiceCIDR         string // the subnet which Kubernetes services will be deployed to
@@ -147,6 +166,15 @@ type Node struct {
 	ContainerRuntime  string
 	ControlPlane      bool
 	Worker            bool
+	Runtime           *RuntimeInfo `json:",omitempty"` // the container runtime observed on the last successful start
+}
+
+// RuntimeInfo describes the container runtime of a node, for tools which read the profile instead of querying the node
+type RuntimeInfo struct {
+	Name        string
+	Socket      string
+	Version     string
+	LastUpdated time.Time // when the values were observed, they may be stale once the node is stopped
 }
 
 // VersionedExtraOption holds information on flags to apply to a specific range
@@ -174,6 +202,14 @@ type VersionedExtraOption struct {
 	GreaterThanOrEqual semver.Version
 }
 
+// PausedNamespaces are the namespaces 'minikube pause' paused the containers of
+type PausedNamespaces struct {
+	// Namespaces are the paused namespaces, or all of them if nil
+	Namespaces []string
+	// ExcludeNamespaces are the namespaces which were left running
+	ExcludeNamespaces []string `json:",omitempty"`
+}
+
 // ScheduledStopConfig contains information around scheduled stop
 // not yet used, will be used to show status of scheduled stop
 type ScheduledStopConfig struct {
@@ -45,6 +45,8 @@ const (
 	DefaultClusterName = "minikube"
 	// DockerDaemonPort is the port Docker daemon listening inside a minikube node (vm or container).
 	DockerDaemonPort = 2376
+	// BuilderDaemonPort is the port the isolated builder Docker daemon listens on inside a minikube node, when enabled
+	BuilderDaemonPort = 2377
 	// APIServerPort is the default API server port
 	APIServerPort = 8443
 	// AutoPauseProxyPort is the port to be used as a reverse proxy for apiserver port
@@ -62,6 +64,8 @@ const (
 	Docker = "docker"
 	// DefaultContainerRuntime is our default container runtime
 	DefaultContainerRuntime = ""
+	// AutoContainerRuntime is docker, falling back to containerd when the image lacks cri-dockerd
+	AutoContainerRuntime = "auto"
 
 	// APIServerName is the default API server name
 	APIServerName = "minikubeCA"
@@ -83,6 +87,8 @@ const (
 	// MinikubeActiveDockerdEnv holds the docker daemon which user's shell is pointing at
 	// value would be profile or empty if pointing to the user's host daemon.
 	MinikubeActiveDockerdEnv = "MINIKUBE_ACTIVE_DOCKERD"
+	// ContainerRuntimeEndpointEnv points crictl at a CRI socket
+	ContainerRuntimeEndpointEnv = "CONTAINER_RUNTIME_ENDPOINT"
 	// PodmanVarlinkBridgeEnv is used for podman settings
 	PodmanVarlinkBridgeEnv = "PODMAN_VARLINK_BRIDGE"
 	// PodmanContainerHostEnv is used for podman settings
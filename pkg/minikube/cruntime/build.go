@@ -0,0 +1,302 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// BuildOptions are the options of BuildImage
+type BuildOptions struct {
+	// File is the path of the Dockerfile within the build context, if not the default one
+	File string
+	// Tag is the name of the built image
+	Tag string
+	// Push is whether to push the built image, which requires a tag
+	Push bool
+	// Env are the environment variables of the build, as key=value
+	Env []string
+	// Opts are flags passed as-is to the builder, as key=value without the leading dashes
+	Opts []string
+	// CacheFrom are the caches to import layers from, as image references or type=registry,ref=REF[,key=value...] specs
+	CacheFrom []string
+	// CacheTo are the caches to export layers to, in the same format as CacheFrom
+	CacheTo []string
+	// Stdout and Stderr receive the output of the builder, which goes to os.Stdout and os.Stderr if nil
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// streams returns the writers of the output and errors of the builder
+func (o BuildOptions) streams() (io.Writer, io.Writer) {
+	stdout, stderr := o.Stdout, o.Stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	return stdout, stderr
+}
+
+// The builders of the runtimes, which import and export caches differently
+const (
+	// builderDocker is the classic builder of docker, which can only import caches from images
+	builderDocker = "docker"
+	// builderBuildx is the BuildKit builder of docker
+	builderBuildx = "buildx"
+	// builderBuildctl is the BuildKit client used with containerd
+	builderBuildctl = "buildctl"
+	// builderPodman is the builder used with CRI-O
+	builderPodman = "podman"
+)
+
+// AddonCacheRegistry is the registry of the cache references pointing at the registry addon, like addon/app:cache
+const AddonCacheRegistry = "addon"
+
+// insecureCacheAttr marks a registry cache served over plain HTTP
+const insecureCacheAttr = "registry.insecure=true"
+
+// cacheSpec is a registry build cache
+type cacheSpec struct {
+	// Ref is the image holding the cache
+	Ref string
+	// Attrs are the other attributes of the cache, like mode=max, as key=value
+	Attrs []string
+}
+
+// parseCacheSpec parses an image reference, or a type=registry,ref=REF[,key=value...] spec
+func parseCacheSpec(s string) (cacheSpec, error) {
+	if !strings.Contains(s, "=") {
+		return cacheSpec{Ref: s}, nil
+	}
+	var c cacheSpec
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return c, fmt.Errorf("invalid build cache %q: %q is not key=value", s, kv)
+		}
+		switch k {
+		case "type":
+			if v != "registry" {
+				return c, fmt.Errorf("invalid build cache %q: only type=registry caches are supported", s)
+			}
+		case "ref":
+			c.Ref = v
+		default:
+			c.Attrs = append(c.Attrs, kv)
+		}
+	}
+	if c.Ref == "" {
+		return c, fmt.Errorf("invalid build cache %q: missing ref", s)
+	}
+	return c, nil
+}
+
+// String returns the BuildKit spec of the cache
+func (c cacheSpec) String() string {
+	return strings.Join(append([]string{"type=registry", "ref=" + c.Ref}, c.Attrs...), ",")
+}
+
+// insecure returns whether the cache registry is served over plain HTTP
+func (c cacheSpec) insecure() bool {
+	for _, a := range c.Attrs {
+		if a == insecureCacheAttr {
+			return true
+		}
+	}
+	return false
+}
+
+// imageRef returns the reference of the cache, for the builders which only take references
+func (c cacheSpec) imageRef(builder string) (string, error) {
+	for _, a := range c.Attrs {
+		if a != insecureCacheAttr {
+			return "", fmt.Errorf("the %s builder only takes image references as build caches, not %q", builder, a)
+		}
+	}
+	return c.Ref, nil
+}
+
+// buildCacheArgs returns the flags of a builder importing and exporting the caches of opts
+func buildCacheArgs(builder string, opts BuildOptions) ([]string, error) {
+	args := []string{}
+	for _, s := range opts.CacheFrom {
+		c, err := parseCacheSpec(s)
+		if err != nil {
+			return nil, err
+		}
+		switch builder {
+		case builderBuildx:
+			args = append(args, "--cache-from", c.String())
+		case builderBuildctl:
+			args = append(args, "--import-cache", c.String())
+		default:
+			ref, err := c.imageRef(builder)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, "--cache-from", ref)
+		}
+	}
+	for _, s := range opts.CacheTo {
+		c, err := parseCacheSpec(s)
+		if err != nil {
+			return nil, err
+		}
+		switch builder {
+		case builderBuildx:
+			args = append(args, "--cache-to", c.String())
+		case builderBuildctl:
+			args = append(args, "--export-cache", c.String())
+		case builderDocker:
+			return nil, fmt.Errorf("the classic docker builder cannot export build caches (--cache-to %s): install buildx in the node, or only use --cache-from", s)
+		default:
+			ref, err := c.imageRef(builder)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, "--cache-to", ref)
+		}
+	}
+	return args, nil
+}
+
+// insecureCacheRegistries returns the plain HTTP registries of the caches of opts
+func insecureCacheRegistries(opts BuildOptions) []string {
+	regs := []string{}
+	for _, s := range append(append([]string{}, opts.CacheFrom...), opts.CacheTo...) {
+		c, err := parseCacheSpec(s)
+		if err != nil || !c.insecure() {
+			continue
+		}
+		if host, _, ok := strings.Cut(c.Ref, "/"); ok {
+			regs = append(regs, host)
+		}
+	}
+	return regs
+}
+
+// ValidateCaches returns an error if one of the cache specs cannot be parsed
+func ValidateCaches(specs []string) error {
+	for _, s := range specs {
+		if _, err := parseCacheSpec(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// needsBuildKit returns whether the caches of opts can only be handled by BuildKit, as the classic builders only import caches from image references
+func needsBuildKit(opts BuildOptions) bool {
+	if len(opts.CacheTo) > 0 {
+		return true
+	}
+	for _, s := range opts.CacheFrom {
+		c, err := parseCacheSpec(s)
+		if err != nil {
+			return false
+		}
+		if _, err := c.imageRef(builderDocker); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// PrepareBuild fails if the builder of the runtime cannot handle the caches of opts, before anything is transferred for the build.
+// The classic builders pull the caches through the runtime, which must already accept their plain HTTP registries.
+func PrepareBuild(r Manager, opts BuildOptions) error {
+	builder := ""
+	switch rt := r.(type) {
+	case *Docker:
+		builder = rt.builder(opts)
+	case *Containerd:
+		builder = builderBuildctl
+	case *CRIO:
+		builder = builderPodman
+	default:
+		if len(opts.CacheFrom) == 0 && len(opts.CacheTo) == 0 {
+			return nil
+		}
+		return fmt.Errorf("%s cannot import or export build caches", r.Name())
+	}
+	if _, err := buildCacheArgs(builder, opts); err != nil {
+		return err
+	}
+	if builder == builderBuildx || builder == builderBuildctl {
+		return nil
+	}
+	for _, addr := range insecureCacheRegistries(opts) {
+		ok, err := AcceptsInsecureRegistry(r, addr)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%s does not accept the insecure cache registry %s: enable the registry addon, or restart minikube with --insecure-registry=%s", r.Name(), addr, addr)
+		}
+	}
+	return nil
+}
+
+// UsesCacheRegistry returns whether one of the cache specs is in the registry
+func UsesCacheRegistry(specs []string, registry string) bool {
+	for _, s := range specs {
+		if c, err := parseCacheSpec(s); err == nil && strings.HasPrefix(c.Ref, registry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// RetagCaches points the cache specs in the registry at the plain HTTP registry at addr (host:port), leaving the others unchanged
+func RetagCaches(specs []string, registry string, addr string) ([]string, error) {
+	retagged := []string{}
+	for _, s := range specs {
+		c, err := parseCacheSpec(s)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(c.Ref, registry+"/") {
+			c.Ref = addr + "/" + strings.TrimPrefix(c.Ref, registry+"/")
+			if !c.insecure() {
+				c.Attrs = append(c.Attrs, insecureCacheAttr)
+			}
+			s = c.String()
+		}
+		retagged = append(retagged, s)
+	}
+	return retagged, nil
+}
+
+// builder returns the builder of docker for opts: buildx if the caches need BuildKit and it is installed in the node, the classic builder otherwise
+func (r *Docker) builder(opts BuildOptions) string {
+	if !needsBuildKit(opts) {
+		return builderDocker
+	}
+	if _, err := r.Runner.RunCmd(exec.Command("docker", "buildx", "version")); err != nil {
+		klog.Infof("buildx is unavailable, using the classic builder: %v", err)
+		return builderDocker
+	}
+	return builderBuildx
+}
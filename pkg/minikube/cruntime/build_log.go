@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	// buildStepRes match the lines starting a step of a build, as printed by the classic builder of docker and by podman,
+	// and the lines of the BuildKit steps, which are numbered as #N
+	buildStepRes = []*regexp.Regexp{
+		regexp.MustCompile(`^Step (\d+/\d+) :`),
+		regexp.MustCompile(`^STEP (\d+(?:/\d+)?):`),
+		regexp.MustCompile(`^(#\d+) `),
+	}
+	// buildImageIDRes match the lines printing the ID of the built image, as printed by BuildKit with docker and with containerd,
+	// by the classic builder of docker, and by podman as its last line
+	buildImageIDRes = []*regexp.Regexp{
+		regexp.MustCompile(`writing image (sha256:[0-9a-f]{64})`),
+		regexp.MustCompile(`exporting config (sha256:[0-9a-f]{64})`),
+		regexp.MustCompile(`^Successfully built ([0-9a-f]{12,64})$`),
+		regexp.MustCompile(`^([0-9a-f]{64})$`),
+	}
+)
+
+// BuildLog splits the output of a build into lines, tracking the step of the build each line belongs to, and the ID of the built image
+type BuildLog struct {
+	// OnLine is called with each line of the output, the stream it was written to, and the step it belongs to, empty before the first step.
+	// It is called with the log locked, so it must not call the methods of the log.
+	OnLine func(stream, step, line string)
+
+	mu      sync.Mutex
+	step    string
+	imageID string
+	streams []*buildStream
+}
+
+// Stream returns the writer of a stream of the output of the builder, such as its standard output
+func (b *BuildLog) Stream(name string) io.Writer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := &buildStream{log: b, name: name}
+	b.streams = append(b.streams, s)
+	return s
+}
+
+// Flush reports the last lines of the streams, which did not end with a newline
+func (b *BuildLog) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, s := range b.streams {
+		if s.buf.Len() > 0 {
+			b.line(s.name, s.buf.String())
+			s.buf.Reset()
+		}
+	}
+}
+
+// ImageID returns the ID of the built image, empty if the builder did not print it
+func (b *BuildLog) ImageID() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.imageID
+}
+
+// line reports a line of the output, with b.mu held
+func (b *BuildLog) line(stream, line string) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if step := parseBuildStep(line); step != "" {
+		b.step = step
+	}
+	if id := parseBuildImageID(line); id != "" {
+		b.imageID = id
+	}
+	if b.OnLine != nil {
+		b.OnLine(stream, b.step, line)
+	}
+}
+
+// buildStream is a stream of the output of the builder, buffering the line being written
+type buildStream struct {
+	log  *BuildLog
+	name string
+	buf  bytes.Buffer
+}
+
+func (s *buildStream) Write(p []byte) (int, error) {
+	s.log.mu.Lock()
+	defer s.log.mu.Unlock()
+	s.buf.Write(p)
+	for {
+		i := bytes.IndexByte(s.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(s.buf.Next(i + 1))
+		s.log.line(s.name, strings.TrimSuffix(line, "\n"))
+	}
+}
+
+// parseBuildStep returns the step of the build started by a line of its output, or "" if it does not start one
+func parseBuildStep(line string) string {
+	for _, re := range buildStepRes {
+		if m := re.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// parseBuildImageID returns the ID of the built image printed by a line of the output of the build, or "" if it does not print it
+func parseBuildImageID(line string) string {
+	line = strings.TrimSpace(line)
+	for _, re := range buildImageIDRes {
+		if m := re.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
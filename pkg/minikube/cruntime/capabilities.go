@@ -0,0 +1,33 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+// Capabilities describes what a runtime supports, so that features do not need to check its name
+type Capabilities struct {
+	// SupportsUntrustedRuntimeClass is true if the runtime can run pods of the untrusted workload runtime class (gvisor)
+	SupportsUntrustedRuntimeClass bool
+	// SupportsBuild is true if images can be built in the runtime with BuildImage
+	SupportsBuild bool
+	// SupportsPause is true if the containers of the runtime can be paused with PauseContainers
+	SupportsPause bool
+	// SnapshotterName is the name of the storage driver or snapshotter of the image store
+	SnapshotterName string
+	// CRISocket is the path of the CRI socket the kubelet talks to, empty if the kubelet talks to the runtime directly
+	CRISocket string
+	// NativeBuildkit is true if the runtime builds images with its own BuildKit, without a separate daemon
+	NativeBuildkit bool
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// cgroupControllers only exists on hosts using the unified (v2) cgroup hierarchy
+const cgroupControllers = "/sys/fs/cgroup/cgroup.controllers"
+
+// DetectCgroupVersion returns the version of the cgroup hierarchy of the host: 1 or 2
+func DetectCgroupVersion(cr CommandRunner) (int, error) {
+	rr, err := cr.RunCmd(exec.Command("stat", "-c", "%n", cgroupControllers))
+	if err == nil {
+		return 2, nil
+	}
+	if rr != nil && rr.ExitCode > 0 {
+		return 1, nil
+	}
+	return 0, errors.Wrap(err, "detecting cgroup version")
+}
+
+// ChooseForceSystemd decides whether the container runtime has to use systemd as cgroup manager.
+// requested is the value of --force-systemd if the user set it, and nil otherwise.
+// It returns the decision along with the reason for it.
+func ChooseForceSystemd(requested *bool, cgroupVersion int, initSystem string) (bool, string) {
+	if requested != nil {
+		return *requested, fmt.Sprintf("--force-systemd=%t was requested", *requested)
+	}
+	if cgroupVersion == 2 && initSystem == "systemd" {
+		return true, "the host uses cgroup v2 and systemd, which kubelet expects as cgroup driver"
+	}
+	return false, fmt.Sprintf("the host uses cgroup v%d and %s", cgroupVersion, initSystem)
+}
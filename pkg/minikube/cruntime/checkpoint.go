@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// CheckpointFeatureGate is the feature gate of kubelet enabling the checkpointing of containers
+const CheckpointFeatureGate = "ContainerCheckpoint"
+
+// ErrUnsupported is returned by the experimental operations a runtime does not support
+type ErrUnsupported struct {
+	// Runtime is the name of the runtime
+	Runtime string
+	// Operation is what the runtime does not support
+	Operation string
+}
+
+func (e *ErrUnsupported) Error() string {
+	return fmt.Sprintf("%s does not support %s", e.Runtime, e.Operation)
+}
+
+// checkpointCRIContainer checkpoints a running container through the CRI into a tarball at destPath on the node
+func checkpointCRIContainer(cr CommandRunner, name string, id string, destPath string) error {
+	klog.Infof("Checkpointing container %s into %s", id, destPath)
+	c := command.Sudo(getCrictlPath(cr), "checkpoint", "--export="+destPath, id)
+	rr, err := cr.RunCmd(c)
+	if err != nil {
+		if rr != nil && strings.Contains(rr.Stderr.String(), "No help topic for 'checkpoint'") {
+			return &ErrUnsupported{Runtime: name, Operation: "checkpointing containers with this crictl, which is older than 1.25"}
+		}
+		return errors.Wrap(err, "crictl checkpoint")
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/blang/semver/v4"
+	"github.com/kballard/go-shellquote"
+	"k8s.io/minikube/pkg/minikube/reason"
+)
+
+// runtimeCLIs return the command line tool managing the containers of a runtime from within a node, given the CRI socket
+var runtimeCLIs = map[string]func(socket string) []string{
+	"docker": func(string) []string {
+		return []string{"docker"}
+	},
+	"containerd": func(socket string) []string {
+		return []string{"sudo", "crictl", "--runtime-endpoint", SocketURL(socket)}
+	},
+	"crio": func(string) []string {
+		return []string{"sudo", "podman"}
+	},
+}
+
+// CLICommand returns the shell command line running the CLI of a runtime with args, such as 'sudo podman ps' for CRI-O.
+// socket is the CRI socket of the node, or empty for the default one.
+func CLICommand(runtime string, socket string, kv semver.Version, args []string) (string, error) {
+	if runtime == "" {
+		runtime = "docker"
+	}
+	if runtime == "cri-o" {
+		runtime = "crio"
+	}
+	cli, ok := runtimeCLIs[runtime]
+	if !ok {
+		return "", fmt.Errorf("no command line tool known for the %s runtime", runtime)
+	}
+	if socket == "" {
+		socket = DefaultCRISocket(runtime, kv)
+	}
+	return shellquote.Join(append(cli(socket), args...)...), nil
+}
+
+// cliIssues are the known causes of failed docker and crictl commands, found in their output.
+// The first match wins, so the causes which are reported along with others come first.
+var cliIssues = []struct {
+	kind   *reason.Kind
+	regexp *regexp.Regexp
+}{
+	{&reason.RuntimeNoSpace, regexp.MustCompile(`(?i)no space left on device`)},
+	{&reason.RuntimeSocketPermission, regexp.MustCompile(`(?i)permission denied while trying to connect to the docker daemon socket|dial unix \S+: connect: permission denied`)},
+	{&reason.RuntimeDaemonNotRunning, regexp.MustCompile(`(?i)cannot connect to the docker daemon|is the docker daemon running|dial unix \S+: connect: (connection refused|no such file or directory)`)},
+	{&reason.RuntimeAuthRequired, regexp.MustCompile(`(?i)authentication required|no basic auth credentials|unauthorized:|401 unauthorized`)},
+	{&reason.RuntimeImageNotFound, regexp.MustCompile(`(?i)manifest unknown|manifest for \S+ not found|repository does not exist|failed to resolve reference "[^"]*": \S+ not found|no such image`)},
+	{&reason.RuntimeClockSkew, regexp.MustCompile(`(?i)x509: certificate has expired or is not yet valid|certificate is not yet valid`)},
+	{&reason.RuntimeNetworkTimeout, regexp.MustCompile(`(?i)i/o timeout|tls handshake timeout|client\.timeout exceeded|request canceled while waiting for connection`)},
+}
+
+// ErrCLI is the error returned when a command of the CLI of a runtime failed for a known cause
+type ErrCLI struct {
+	// Kind is the known cause of the failure, with the advice to fix it
+	Kind *reason.Kind
+	Err  error
+}
+
+func (e *ErrCLI) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the command
+func (e *ErrCLI) Unwrap() error {
+	return e.Err
+}
+
+// KnownIssue returns the known cause of the failure, which lets the reason package show its advice
+func (e *ErrCLI) KnownIssue() *reason.Kind {
+	return e.Kind
+}
+
+// classifyCLIError returns err as an ErrCLI if the output of the docker or crictl command it holds matches a known cause,
+// and err itself otherwise
+func classifyCLIError(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, ci := range cliIssues {
+		if ci.regexp.MatchString(err.Error()) {
+			return &ErrCLI{Kind: ci.kind, Err: err}
+		}
+	}
+	return err
+}
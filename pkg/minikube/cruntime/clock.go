@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/driver"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+)
+
+// clockSkewThreshold is the clock skew of the node from which it is resynced, as the certificates of registries may be only minutes old
+const clockSkewThreshold = 30 * time.Second
+
+// timeServices are the services keeping the clock of the node in sync, restarted in order to resync it
+var timeServices = []string{"chronyd", "systemd-timesyncd"}
+
+// clockChecked is whether a failed pull already checked the clock of the node, which is done once per command
+var clockChecked struct {
+	sync.Mutex
+	done bool
+}
+
+// ClockSkew returns how far the clock of the node is ahead of the one of the host, negative if it is behind.
+// date prints whole seconds, so that a skew of less than a second is not significant.
+func ClockSkew(cr CommandRunner) (time.Duration, error) {
+	before := time.Now()
+	rr, err := cr.RunCmd(exec.Command("date", "+%s"))
+	after := time.Now()
+	if err != nil {
+		return 0, errors.Wrap(err, "date")
+	}
+	return clockSkew(before, after, rr.Stdout.String())
+}
+
+// clockSkew returns the skew of the clock of the node which printed its time in seconds as output, between the host times before and after
+func clockSkew(before, after time.Time, output string) (time.Duration, error) {
+	secs, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected date output: %q", output)
+	}
+	host := before.Add(after.Sub(before) / 2)
+	// the node time is truncated to the second, so it is compared with the middle of its second
+	node := time.Unix(secs, int64(time.Second/2))
+	return node.Sub(host).Round(time.Second), nil
+}
+
+// clockSkewed returns whether a skew breaks the validation of certificates
+func clockSkewed(skew time.Duration) bool {
+	return skew > clockSkewThreshold || skew < -clockSkewThreshold
+}
+
+// resyncsClock returns whether minikube resyncs the clock of the nodes of the driver drv, which only drifts in the VMs
+// suspended with their host: the clocks of the other hosts are left to their owners
+func resyncsClock(drv string) bool {
+	return drv != "" && driver.IsVM(drv) && !driver.IsSSH(drv)
+}
+
+// syncClock resyncs the clock of the node of the driver drv if it is skewed, from its hardware clock or else by restarting
+// its time service, returning the skew it found. The clocks of the nodes which are not VMs are only reported.
+func syncClock(cr CommandRunner, init sysinit.Manager, drv string) (time.Duration, error) {
+	skew, err := ClockSkew(cr)
+	if err != nil || !clockSkewed(skew) {
+		return skew, err
+	}
+	if !resyncsClock(drv) {
+		out.WarningT("The clock of the node is skewed by {{.skew}}, which breaks pulling images: resync the clock of its host", out.V{"skew": skew})
+		return skew, fmt.Errorf("the clock of the node is skewed by %s, which is left to the owner of its host", skew)
+	}
+	klog.Warningf("the clock of the node is skewed by %s, resyncing it", skew)
+	if _, err := cr.RunCmd(command.Sudo("hwclock", "-s")); err != nil {
+		klog.Infof("unable to set the clock from the hardware clock: %v", err)
+	} else if now, err := ClockSkew(cr); err == nil && !clockSkewed(now) {
+		klog.Infof("resynced the clock of the node from its hardware clock")
+		return skew, nil
+	}
+	for _, svc := range timeServices {
+		if init == nil || !init.Active(svc) {
+			continue
+		}
+		if err := init.Restart(svc); err != nil {
+			klog.Warningf("unable to restart %s: %v", svc, err)
+			continue
+		}
+		if now, err := ClockSkew(cr); err == nil && !clockSkewed(now) {
+			klog.Infof("resynced the clock of the node by restarting %s", svc)
+			return skew, nil
+		}
+	}
+	return skew, fmt.Errorf("unable to resync the clock of the node, which is skewed by %s", skew)
+}
+
+// isClockSkewError returns whether err is a failed validation of a certificate, which a skewed clock of the node causes
+func isClockSkewError(err error) bool {
+	var cerr *ErrCLI
+	return errors.As(err, &cerr) && cerr.Kind.ID == reason.RuntimeClockSkew.ID
+}
+
+// pullWithClockCheck runs pull, and if it fails validating a certificate, resyncs the clock of the node of the driver drv
+// and retries it once. The clock is checked once per command, so that the pulls of the images of a cluster do not all check it.
+func pullWithClockCheck(cr CommandRunner, init sysinit.Manager, drv string, pull func() error) error {
+	err := pull()
+	if !isClockSkewError(err) {
+		return err
+	}
+	clockChecked.Lock()
+	defer clockChecked.Unlock()
+	if clockChecked.done {
+		return err
+	}
+	clockChecked.done = true
+	skew, serr := syncClock(cr, init, drv)
+	if serr != nil {
+		klog.Warningf("checking the clock of the node: %v", serr)
+		return err
+	}
+	if !clockSkewed(skew) {
+		klog.Infof("the clock of the node is in sync (skew %s), not retrying the pull", skew)
+		return err
+	}
+	klog.Infof("retrying the pull after resyncing the clock of the node, which was skewed by %s", skew)
+	return pull()
+}
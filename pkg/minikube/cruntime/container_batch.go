@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// containerBatchSize is how many container IDs at most are passed to a command, keeping below the limits of argv
+const containerBatchSize = 100
+
+// missingContainerRe matches the errors of docker, crictl and runc about containers which do not exist
+var missingContainerRe = regexp.MustCompile(`(?i)no such container|code = NotFound|container .*not found|does not exist`)
+
+// containerResult is what an operation did to a container
+type containerResult int
+
+const (
+	// containerDone is a container the operation succeeded on
+	containerDone containerResult = iota
+	// containerMissing is a container which no longer exists, which the operation skipped
+	containerMissing
+	// containerFailed is an existing container the operation failed on
+	containerFailed
+)
+
+// containerResults are the results of an operation, by container ID
+type containerResults map[string]containerResult
+
+// with returns the sorted IDs of the containers with the result
+func (r containerResults) with(result containerResult) []string {
+	ids := []string{}
+	for id, res := range r {
+		if res == result {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// runOnContainers runs the command built by command on the containers, batchSize of them at a time.
+// The containers which no longer exist are skipped, and it only fails if the command failed on existing containers.
+func runOnContainers(cr CommandRunner, ids []string, batchSize int, command func(ids []string) *exec.Cmd) (containerResults, error) {
+	results := containerResults{}
+	var failure error
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+		rr, err := cr.RunCmd(command(batch))
+		if err == nil {
+			for _, id := range batch {
+				results[id] = containerDone
+			}
+			continue
+		}
+		stderr := ""
+		if rr != nil {
+			stderr = rr.Stderr.String()
+		}
+		for id, res := range batchResults(batch, stderr) {
+			results[id] = res
+			if res == containerFailed {
+				failure = err
+			}
+		}
+	}
+	if missing := results.with(containerMissing); len(missing) > 0 {
+		klog.V(3).Infof("skipped the containers which no longer exist: %v", missing)
+	}
+	if failure != nil {
+		return results, errors.Wrapf(failure, "containers %v", results.with(containerFailed))
+	}
+	return results, nil
+}
+
+// batchResults attributes the errors in the output of a failed command to the containers of its batch.
+// docker and crictl carry on past the containers they fail on, so the containers no error names succeeded,
+// unless no error names any container, or the command ran on a single container.
+func batchResults(batch []string, stderr string) containerResults {
+	results := containerResults{}
+	attributed := false
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		res := containerFailed
+		if missingContainerRe.MatchString(line) {
+			res = containerMissing
+		}
+		named := false
+		for _, id := range batch {
+			if strings.Contains(line, id) {
+				named = true
+				if results[id] != containerFailed {
+					results[id] = res
+				}
+			}
+		}
+		if !named && len(batch) == 1 && results[batch[0]] != containerFailed {
+			named = true
+			results[batch[0]] = res
+		}
+		attributed = attributed || named
+	}
+	for _, id := range batch {
+		if _, ok := results[id]; ok {
+			continue
+		}
+		if attributed {
+			results[id] = containerDone
+		} else {
+			results[id] = containerFailed
+		}
+	}
+	return results
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// ContainerCopyRoot is where the files copied into containers are staged on the node
+const ContainerCopyRoot = "/var/lib/minikube/cp"
+
+// ownerRe matches the owners of the copied files, as UID or UID:GID
+var ownerRe = regexp.MustCompile(`^([0-9]+)(?::([0-9]+))?$`)
+
+// CopyOptions are the options of CopyToContainer
+type CopyOptions struct {
+	// Mode is the octal permissions of the copied file, such as 0644, or "" to keep those of the source
+	Mode string
+	// Owner is the numeric owner of the copied file within the container, as UID or UID:GID, or "" for root
+	Owner string
+}
+
+// Validate returns an error if the mode or the owner is malformed
+func (o CopyOptions) Validate() error {
+	if o.Mode != "" {
+		if _, err := strconv.ParseUint(o.Mode, 8, 32); err != nil {
+			return fmt.Errorf("invalid mode %q: it must be octal, such as 0644", o.Mode)
+		}
+	}
+	if o.Owner != "" && !ownerRe.MatchString(o.Owner) {
+		return fmt.Errorf("invalid owner %q: it must be numeric, as UID or UID:GID", o.Owner)
+	}
+	return nil
+}
+
+// tarCommand returns the command archiving the file src of the node to stdout, with the mode and owner of o
+func (o CopyOptions) tarCommand(src string) string {
+	args := []string{"sudo", "tar", "-C", path.Dir(src), "-cf", "-"}
+	if m := ownerRe.FindStringSubmatch(o.Owner); m != nil {
+		group := m[2]
+		if group == "" {
+			group = m[1]
+		}
+		args = append(args, "--numeric-owner", "--owner="+m[1], "--group="+group)
+	}
+	if o.Mode != "" {
+		args = append(args, "--mode="+o.Mode)
+	}
+	return shellquote.Join(append(args, path.Base(src))...)
+}
+
+// copyIntoDocker copies the file src of the node into the directory dir of a docker container, whether running or not
+func copyIntoDocker(cr CommandRunner, c ContainerInfo, src string, dir string, o CopyOptions) error {
+	klog.Infof("Copying %s into %s:%s", src, c.ID, dir)
+	// archive mode keeps the owner of the archive, instead of the root of the container
+	cmd := fmt.Sprintf("%s | docker cp -a - %s", o.tarCommand(src), shellquote.Join(c.ID+":"+dir))
+	if _, err := cr.RunCmd(exec.Command("/bin/bash", "-c", cmd)); err != nil {
+		return errors.Wrap(err, "docker cp")
+	}
+	return nil
+}
+
+// copyIntoCRI copies the file src of the node into the directory dir of a container of a CRI runtime.
+// It extracts the file with the tar of running containers, and into the root filesystem of the others if rootfs is set,
+// which mounts it in the given directory.
+func copyIntoCRI(cr CommandRunner, name string, c ContainerInfo, src string, dir string, o CopyOptions, rootfs func(mnt string) error) error {
+	klog.Infof("Copying %s into %s:%s", src, c.ID, dir)
+	switch c.State {
+	case Running.String():
+		cmd := fmt.Sprintf("%s | %s", o.tarCommand(src), shellquote.Join("sudo", getCrictlPath(cr), "exec", "-i", c.ID, "tar", "-xf", "-", "-C", dir))
+		if _, err := cr.RunCmd(exec.Command("/bin/bash", "-c", cmd)); err != nil {
+			return errors.Wrap(err, "crictl exec")
+		}
+		return nil
+	case Paused.String():
+		return fmt.Errorf("container %s is paused, unpause it to copy into it", c.ID)
+	}
+	if rootfs == nil {
+		return &ErrUnsupported{Runtime: name, Operation: "copying into containers which are not running"}
+	}
+
+	mnt := path.Join(ContainerCopyRoot, "rootfs-"+c.ID)
+	if _, err := cr.RunCmd(command.Sudo("mkdir", "-p", mnt)); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := cr.RunCmd(command.Sudo("rmdir", mnt)); err != nil {
+			klog.Warningf("unable to remove %s: %v", mnt, err)
+		}
+	}()
+	if err := rootfs(mnt); err != nil {
+		return errors.Wrapf(err, "mounting the root filesystem of %s", c.ID)
+	}
+	defer func() {
+		if _, err := cr.RunCmd(command.Sudo("umount", mnt)); err != nil {
+			klog.Warningf("unable to unmount %s: %v", mnt, err)
+		}
+	}()
+	cmd := fmt.Sprintf("%s | %s", o.tarCommand(src), shellquote.Join("sudo", "tar", "-xf", "-", "-C", path.Join(mnt, dir)))
+	if _, err := cr.RunCmd(exec.Command("/bin/bash", "-c", cmd)); err != nil {
+		return errors.Wrap(err, "extracting into the root filesystem")
+	}
+	return nil
+}
+
+// mountContainerdSnapshot mounts the snapshot of a container of containerd, which is its root filesystem, at mnt
+func mountContainerdSnapshot(cr CommandRunner, id string) func(string) error {
+	return func(mnt string) error {
+		// ctr prints the mount commands of the snapshot, as supported by its snapshotter
+		cmd := fmt.Sprintf("sudo ctr -n=k8s.io snapshots mounts %s %s | sudo sh -e", mnt, id)
+		if _, err := cr.RunCmd(exec.Command("/bin/bash", "-c", cmd)); err != nil {
+			return errors.Wrap(err, "ctr snapshots mounts")
+		}
+		return nil
+	}
+}
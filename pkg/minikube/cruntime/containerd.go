@@ -18,10 +18,12 @@ package cruntime
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"net/url"
 	"os"
 	"os/exec"
@@ -38,6 +40,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/download"
+	"k8s.io/minikube/pkg/minikube/out/register"
 	"k8s.io/minikube/pkg/minikube/style"
 	"k8s.io/minikube/pkg/minikube/sysinit"
 )
@@ -62,6 +65,19 @@ type Containerd struct {
 	KubernetesVersion semver.Version
 	Init              sysinit.Manager
 	InsecureRegistry  []string
+	CleanupNetwork    bool
+	CNIConfigs        []string
+	DockerOnDemand    bool
+	// RegistryMirrors are the mirrors of Docker Hub, tried in order before it
+	RegistryMirrors []string
+	// Offline makes pulling images fail instead of waiting for unreachable registries, and preloads mandatory
+	Offline bool
+	// PreloadFactor scales the estimated space needed to extract the preload, skipping the check if negative
+	PreloadFactor float64
+	// ImageOutput is how the output of the commands pulling and loading images reaches the user
+	ImageOutput ImageOutput
+	// Driver is the driver of the node, the clock of the hosts which are not VMs is left to their owners
+	Driver string
 }
 
 // Name is a human readable name for containerd
@@ -109,7 +125,7 @@ func (r *Containerd) SocketPath() string {
 	if r.Socket != "" {
 		return r.Socket
 	}
-	return "/run/containerd/containerd.sock"
+	return ContainerdCRISocket
 }
 
 // Active returns if containerd is active on the host
@@ -117,12 +133,62 @@ func (r *Containerd) Active() bool {
 	return r.Init.Active("containerd")
 }
 
+// RuntimeHealth reports the health of containerd
+func (r *Containerd) RuntimeHealth() (*Health, error) {
+	h, err := runtimeHealth(r.Runner, []healthUnit{{unit: "containerd", process: "containerd"}}, func() error {
+		_, err := r.Version()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	checkPauseImage(h, r, pauseImage(r.KubernetesVersion, r.ImageRepository))
+	return h, nil
+}
+
+// EnsurePauseImage restores the pause image if it is missing, loaded from the local preload or pulled, and pins it again
+func (r *Containerd) EnsurePauseImage() (bool, error) {
+	name := pauseImage(r.KubernetesVersion, r.ImageRepository)
+	repaired, err := ensurePauseImage(r, name, pauseFromPreload(r, r.Runner, "containerd", r.KubernetesVersion, r.ImageRepository), r.PullImage)
+	if repaired {
+		if err := pinContainerdImage(r, name); err != nil {
+			klog.Warningf("unable to pin the pause image %s: %v", name, err)
+		}
+	}
+	return repaired, err
+}
+
+// Healthy returns an error if containerd is not active, or if its socket does not answer
+func (r *Containerd) Healthy() error {
+	return servicesHealthy(r.Init, []string{"containerd"}, func() error {
+		return criResponsive(r.Runner, r.SocketPath())
+	})
+}
+
+// Events returns the incidents logged by containerd, along with the OOM kills and the state changes of its unit
+func (r *Containerd) Events(since time.Duration) ([]RuntimeEvent, error) {
+	return runtimeEvents(r.Runner, []string{"containerd.service"}, since, func() ([]RuntimeEvent, error) {
+		return journalEvents(r.Runner, "containerd", []string{"-u", "containerd"}, since, containerdPatterns)
+	})
+}
+
 // Available returns an error if it is not possible to use this runtime on a host
 func (r *Containerd) Available() error {
 	c := exec.Command("which", "containerd")
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "check containerd availability")
 	}
+	return CheckElevation(r.Runner)
+}
+
+// containerdFunctional returns an error if the containerd of the node is missing or can not run
+func containerdFunctional(cr CommandRunner) error {
+	if _, err := cr.RunCmd(exec.Command("which", "containerd")); err != nil {
+		return errors.Wrap(err, "check containerd availability")
+	}
+	if _, err := cr.RunCmd(exec.Command("containerd", "--version")); err != nil {
+		return errors.Wrap(err, "containerd version")
+	}
 	return nil
 }
 
@@ -143,33 +209,66 @@ func generateContainerdConfig(cr CommandRunner, imageRepository string, kv semve
 	}
 
 	for _, registry := range insecureRegistry {
-		addr := registry
-		if strings.HasPrefix(strings.ToLower(registry), "http://") || strings.HasPrefix(strings.ToLower(registry), "https://") {
-			i := strings.Index(addr, "//")
-			addr = addr[i+2:]
-		} else {
-			registry = "http://" + registry
+		if err := addContainerdInsecureRegistry(cr, registry); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		t, err := template.New("hosts.toml").Parse(containerdInsecureRegistryTemplate)
-		if err != nil {
-			return errors.Wrap(err, "unable to parse insecure registry template")
-		}
-		opts := struct {
-			InsecureRegistry string
-		}{
-			InsecureRegistry: registry,
-		}
-		var b bytes.Buffer
-		if err := t.Execute(&b, opts); err != nil {
-			return errors.Wrap(err, "unable to create insecure registry template")
+// containerdMirrorsConfig returns the hosts.toml of docker.io, pulling through the mirrors before falling back to Docker Hub
+func containerdMirrorsConfig(mirrors []string) string {
+	var b strings.Builder
+	b.WriteString("server = \"https://registry-1.docker.io\"\n")
+	for _, m := range mirrors {
+		if !strings.Contains(m, "://") {
+			m = "https://" + m
 		}
-		regRootPath := path.Join(containerdMirrorsRoot, addr)
+		fmt.Fprintf(&b, "\n[host.%q]\n  capabilities = [\"pull\", \"resolve\"]\n", m)
+	}
+	return b.String()
+}
 
-		c := exec.Command("/bin/bash", "-c", fmt.Sprintf("sudo mkdir -p %s && printf %%s \"%s\" | base64 -d | sudo tee %s", regRootPath, base64.StdEncoding.EncodeToString(b.Bytes()), path.Join(regRootPath, "hosts.toml")))
-		if _, err := cr.RunCmd(c); err != nil {
-			return errors.Wrap(err, "unable to generate insecure registry cfg")
-		}
+// configureContainerdMirrors makes containerd pull the images of Docker Hub through the mirrors
+func configureContainerdMirrors(cr CommandRunner, mirrors []string) error {
+	if len(mirrors) == 0 {
+		return nil
+	}
+	ma := assets.NewMemoryAssetTarget([]byte(containerdMirrorsConfig(mirrors)), path.Join(containerdMirrorsRoot, "docker.io", "hosts.toml"), "0644")
+	if err := cr.Copy(ma); err != nil {
+		return errors.Wrap(err, "writing registry mirrors")
+	}
+	return nil
+}
+
+// addContainerdInsecureRegistry configures containerd to reach the registry over plain HTTP, skipping TLS verification
+func addContainerdInsecureRegistry(cr CommandRunner, registry string) error {
+	addr := registry
+	if strings.HasPrefix(strings.ToLower(registry), "http://") || strings.HasPrefix(strings.ToLower(registry), "https://") {
+		i := strings.Index(addr, "//")
+		addr = addr[i+2:]
+	} else {
+		registry = "http://" + registry
+	}
+
+	t, err := template.New("hosts.toml").Parse(containerdInsecureRegistryTemplate)
+	if err != nil {
+		return errors.Wrap(err, "unable to parse insecure registry template")
+	}
+	opts := struct {
+		InsecureRegistry string
+	}{
+		InsecureRegistry: registry,
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, opts); err != nil {
+		return errors.Wrap(err, "unable to create insecure registry template")
+	}
+	regRootPath := path.Join(containerdMirrorsRoot, addr)
+
+	c := exec.Command("/bin/bash", "-c", fmt.Sprintf("sudo mkdir -p %s && printf %%s \"%s\" | base64 -d | sudo tee %s", regRootPath, base64.StdEncoding.EncodeToString(b.Bytes()), path.Join(regRootPath, "hosts.toml")))
+	if _, err := cr.RunCmd(c); err != nil {
+		return errors.Wrap(err, "unable to generate insecure registry cfg")
 	}
 	return nil
 }
@@ -187,7 +286,7 @@ func (r *Containerd) Enable(disOthers, forceSystemd, inUserNamespace bool) error
 		}
 	}
 	if disOthers {
-		if err := disableOthers(r, r.Runner); err != nil {
+		if err := disableOthers(r, r.Runner, disableOptions{CleanupNetwork: r.CleanupNetwork, CNIConfigs: r.CNIConfigs, DockerOnDemand: r.DockerOnDemand, ForceSystemd: forceSystemd}); err != nil {
 			klog.Warningf("disableOthers: %v", err)
 		}
 	}
@@ -197,12 +296,32 @@ func (r *Containerd) Enable(disOthers, forceSystemd, inUserNamespace bool) error
 	if err := generateContainerdConfig(r.Runner, r.ImageRepository, r.KubernetesVersion, forceSystemd, r.InsecureRegistry, inUserNamespace); err != nil {
 		return err
 	}
+	if err := configureContainerdMirrors(r.Runner, r.RegistryMirrors); err != nil {
+		return err
+	}
 	if err := enableIPForwarding(r.Runner); err != nil {
 		return err
 	}
 
 	// Otherwise, containerd will fail API requests with 'Unimplemented'
-	return r.Init.Restart("containerd")
+	if err := r.Init.Restart("containerd"); err != nil {
+		return err
+	}
+	// the image GC of the kubelet spares the pinned images, such as the pause image which no pod starts without
+	if name := pauseImage(r.KubernetesVersion, r.ImageRepository); name != "" {
+		if err := pinContainerdImage(r, name); err != nil {
+			klog.Warningf("unable to pin the pause image %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// EnableContext is Enable, killing its commands in the host once ctx is done
+func (r *Containerd) EnableContext(ctx context.Context, disOthers, forceSystemd, inUserNamespace bool) error {
+	p := *r
+	return runPhase(ctx, "containerd.enable", &p.Runner, &p.Init, func() error {
+		return p.Enable(disOthers, forceSystemd, inUserNamespace)
+	})
 }
 
 // Disable idempotently disables containerd on a host
@@ -212,6 +331,9 @@ func (r *Containerd) Disable() error {
 
 // ImageExists checks if image exists based on image name and optionally image sha
 func (r *Containerd) ImageExists(name string, sha string) bool {
+	if _, ok := digestRef(name); ok {
+		return criImageExists(r.Runner, name, sha)
+	}
 	c := exec.Command("/bin/bash", "-c", fmt.Sprintf("sudo ctr -n=k8s.io images check | grep %s", name))
 	rr, err := r.Runner.RunCmd(c)
 	if err != nil {
@@ -223,51 +345,151 @@ func (r *Containerd) ImageExists(name string, sha string) bool {
 	return true
 }
 
+// ImagesExist returns which of the images exist
+func (r *Containerd) ImagesExist(names []string) (map[string]bool, error) {
+	return criImagesExist(r.Runner, names)
+}
+
+// ImageID returns the ID of an image
+func (r *Containerd) ImageID(name string) (string, error) {
+	return criImageID(r.Runner, name)
+}
+
 // ListImages lists images managed by this container runtime
-func (r *Containerd) ListImages(ListImagesOptions) ([]ListImage, error) {
-	return listCRIImages(r.Runner)
+func (r *Containerd) ListImages(opts ListImagesOptions) ([]ListImage, error) {
+	return listCRIImages(r.Runner, opts)
+}
+
+// ImageHistory returns the layers of an image, newest first
+func (r *Containerd) ImageHistory(name string) ([]LayerInfo, error) {
+	return containerdImageHistory(r.Runner, name)
+}
+
+// ImageScanTarget scans the images in the k8s.io namespace of containerd
+func (r *Containerd) ImageScanTarget() ImageScanTarget {
+	socket := ContainerdCRISocket
+	if r.Socket != "" {
+		socket = SocketFile(r.Socket)
+	}
+	return ImageScanTarget{Source: "containerd", Env: []string{"CONTAINERD_ADDRESS=" + socket, "CONTAINERD_NAMESPACE=k8s.io"}}
+}
+
+// Capabilities returns what containerd supports
+func (r *Containerd) Capabilities() Capabilities {
+	return Capabilities{
+		SupportsUntrustedRuntimeClass: true,
+		SupportsBuild:                 true,
+		SupportsPause:                 true,
+		SnapshotterName:               "overlayfs",
+		CRISocket:                     r.SocketPath(),
+	}
 }
 
 // LoadImage loads an image into this runtime
-func (r *Containerd) LoadImage(path string) error {
+func (r *Containerd) LoadImage(path string) ([]string, error) {
 	klog.Infof("Loading image: %s", path)
-	c := exec.Command("sudo", "ctr", "-n=k8s.io", "images", "import", path)
-	if _, err := r.Runner.RunCmd(c); err != nil {
-		return errors.Wrapf(err, "ctr images import")
-	}
-	return nil
+	var refs []string
+	err := trackImage(path, register.ImageLoad, func() string { return fileSize(r.Runner, path) }, func() error {
+		c := command.Sudo("ctr", "-n=k8s.io", "images", "import", path)
+		streamImageOutput(r.ImageOutput, c, path, register.ImageLoad)
+		rr, err := r.Runner.RunCmd(c)
+		if err != nil {
+			return errors.Wrapf(err, "ctr images import")
+		}
+		refs = parseLoadedImages(rr.Stdout.String())
+		return nil
+	})
+	return refs, err
 }
 
 // PullImage pulls an image into this runtime
 func (r *Containerd) PullImage(name string) error {
-	return pullCRIImage(r.Runner, name)
+	if r.Offline {
+		return NewErrOffline(name)
+	}
+	return pullWithClockCheck(r.Runner, r.Init, r.Driver, func() error { return pullCRIImage(r.Runner, name, r.ImageOutput) })
 }
 
 // SaveImage save an image from this runtime
 func (r *Containerd) SaveImage(name string, path string) error {
 	klog.Infof("Saving image %s: %s", name, path)
-	c := exec.Command("sudo", "ctr", "-n=k8s.io", "images", "export", path, name)
+	c := command.Sudo("ctr", "-n=k8s.io", "images", "export", path, name)
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrapf(err, "ctr images export")
 	}
 	return nil
 }
 
+// ExportImageStore saves all the tagged images of the k8s.io namespace into the archive at path
+func (r *Containerd) ExportImageStore(path string) error {
+	refs, err := imageStoreRefs(r)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Exporting %d images: %s", len(refs), path)
+	return trackImage(path, register.ImageStoreExport, func() string { return fileSize(r.Runner, path) }, func() error {
+		c := command.Sudo(append([]string{"ctr", "-n=k8s.io", "images", "export", path}, refs...)...)
+		if _, err := r.Runner.RunCmd(c); err != nil {
+			return errors.Wrap(err, "ctr images export")
+		}
+		return nil
+	})
+}
+
+// ImportImageStore loads all the images of the archive at path into the k8s.io namespace
+func (r *Containerd) ImportImageStore(path string) error {
+	klog.Infof("Importing images: %s", path)
+	return trackImage(path, register.ImageStoreImport, func() string { return fileSize(r.Runner, path) }, func() error {
+		c := command.Sudo("ctr", "-n=k8s.io", "images", "import", path)
+		if _, err := r.Runner.RunCmd(c); err != nil {
+			return errors.Wrap(err, "ctr images import")
+		}
+		return nil
+	})
+}
+
 // RemoveImage removes a image
 func (r *Containerd) RemoveImage(name string) error {
-	return removeCRIImage(r.Runner, name)
+	return removeCRIImage(r.Runner, name, false)
+}
+
+// ForceRemoveImage removes an image with all of its references
+func (r *Containerd) ForceRemoveImage(name string) error {
+	return removeCRIImage(r.Runner, name, true)
 }
 
 // TagImage tags an image in this runtime
 func (r *Containerd) TagImage(source string, target string) error {
 	klog.Infof("Tagging image %s: %s", source, target)
-	c := exec.Command("sudo", "ctr", "-n=k8s.io", "images", "tag", source, target)
+	if ref, ok := digestRef(source); ok {
+		// ctr only takes image names, which for digests are fully qualified and without tags
+		if _, err := criImageRef(r.Runner, ref); err != nil {
+			return errors.Wrap(err, "ctr images tag")
+		}
+		source = normalizeImageRef(ref)
+	}
+	// like docker tag, replace the target if it exists
+	c := command.Sudo("ctr", "-n=k8s.io", "images", "tag", "--force", source, target)
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrapf(err, "ctr images tag")
 	}
 	return nil
 }
 
+// RetagImages tags each source image as its target with ctr in one script, untagging the targets it created if any fails
+func (r *Containerd) RetagImages(mapping map[string]string) error {
+	return retagImages(r, r.Runner, retagCommands{
+		script: bashScript,
+		// ctr only takes fully qualified names, and fails on existing targets unless forced
+		tag: func(source, target string) string {
+			return fmt.Sprintf("sudo ctr -n=k8s.io images tag --force %s %s", normalizeImageRef(source), normalizeImageRef(target))
+		},
+		untag: func(target string) string {
+			return fmt.Sprintf("sudo ctr -n=k8s.io images rm %s", normalizeImageRef(target))
+		},
+	}, mapping)
+}
+
 func gitClone(cr CommandRunner, src string) (string, error) {
 	// clone to a temporary directory
 	rr, err := cr.RunCmd(exec.Command("mktemp", "-d"))
@@ -320,7 +542,12 @@ func downloadRemote(cr CommandRunner, src string) (string, error) {
 }
 
 // BuildImage builds an image into this runtime
-func (r *Containerd) BuildImage(src string, file string, tag string, push bool, env []string, opts []string) error {
+func (r *Containerd) BuildImage(src string, opts BuildOptions) error {
+	cacheArgs, err := buildCacheArgs(builderBuildctl, opts)
+	if err != nil {
+		return err
+	}
+	file, tag := opts.File, opts.Tag
 	// download url if not already present
 	dir, err := downloadRemote(r.Runner, src)
 	if err != nil {
@@ -333,7 +560,7 @@ func (r *Containerd) BuildImage(src string, file string, tag string, push bool,
 		// copy to standard path for Dockerfile
 		df := path.Join(dir, "Dockerfile")
 		if file != df {
-			cmd := exec.Command("sudo", "cp", "-f", file, df)
+			cmd := command.Sudo("cp", "-f", file, df)
 			if _, err := r.Runner.RunCmd(cmd); err != nil {
 				return err
 			}
@@ -347,7 +574,7 @@ func (r *Containerd) BuildImage(src string, file string, tag string, push bool,
 			tag += ":latest"
 		}
 		extra = fmt.Sprintf(",name=%s", tag)
-		if push {
+		if opts.Push {
 			extra += ",push=true"
 		}
 	}
@@ -356,15 +583,15 @@ func (r *Containerd) BuildImage(src string, file string, tag string, push bool,
 		"--local", fmt.Sprintf("context=%s", dir),
 		"--local", fmt.Sprintf("dockerfile=%s", dir),
 		"--output", fmt.Sprintf("type=image%s", extra)}
-	for _, opt := range opts {
+	args = append(args, cacheArgs...)
+	for _, opt := range opts.Opts {
 		args = append(args, "--"+opt)
 	}
-	c := exec.Command("sudo", args...)
+	c := command.Sudo(args...)
 	e := os.Environ()
-	e = append(e, env...)
+	e = append(e, opts.Env...)
 	c.Env = e
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
+	c.Stdout, c.Stderr = opts.streams()
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "buildctl build")
 	}
@@ -374,7 +601,8 @@ func (r *Containerd) BuildImage(src string, file string, tag string, push bool,
 // PushImage pushes an image
 func (r *Containerd) PushImage(name string) error {
 	klog.Infof("Pushing image %s: %s", name)
-	c := exec.Command("sudo", "ctr", "-n=k8s.io", "images", "push", name)
+	// the hosts directory holds the insecure registries
+	c := command.Sudo("ctr", "-n=k8s.io", "images", "push", "--hosts-dir", containerdMirrorsRoot, name)
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrapf(err, "ctr images push")
 	}
@@ -385,6 +613,9 @@ func (r *Containerd) PushImage(name string) error {
 func (r *Containerd) CGroupDriver() (string, error) {
 	info, err := getCRIInfo(r.Runner)
 	if err != nil {
+		if !r.Active() {
+			return "", &ErrDaemonNotRunning{Runtime: r.Name(), Err: err}
+		}
 		return "", err
 	}
 	if info["config"] == nil {
@@ -419,6 +650,32 @@ func (r *Containerd) ListContainers(o ListContainersOptions) ([]string, error) {
 	return listCRIContainers(r.Runner, containerdNamespaceRoot, o)
 }
 
+// ListContainerInfo returns details of the containers managed by this container runtime
+func (r *Containerd) ListContainerInfo(o ListContainersOptions) ([]ContainerInfo, error) {
+	return listCRIContainerInfo(r.Runner, containerdNamespaceRoot, o)
+}
+
+// PortMappings returns the ports a container, given by its ID or a prefix of it, exposes or publishes on the node
+func (r *Containerd) PortMappings(id string) ([]PortMapping, error) {
+	return criPortMappings(r.Runner, id)
+}
+
+// CheckpointContainer checkpoints a running container into a tarball on the host of the runtime (experimental)
+func (r *Containerd) CheckpointContainer(id string, destPath string) error {
+	return checkpointCRIContainer(r.Runner, r.Name(), id, destPath)
+}
+
+// CopyToContainer copies the file src of the node into the directory dir of a container, keeping its name.
+// It copies into the snapshot of the containers which are not running.
+func (r *Containerd) CopyToContainer(c ContainerInfo, src string, dir string, o CopyOptions) error {
+	return copyIntoCRI(r.Runner, r.Name(), c, src, dir, o, mountContainerdSnapshot(r.Runner, c.ID))
+}
+
+// GarbageCollect removes the containers and pod sandboxes which stopped longer ago than the given age
+func (r *Containerd) GarbageCollect(olderThan time.Duration) (int, error) {
+	return garbageCollectCRI(r.Runner, olderThan)
+}
+
 // PauseContainers pauses a running container based on ID
 func (r *Containerd) PauseContainers(ids []string) error {
 	return pauseCRIContainers(r.Runner, containerdNamespaceRoot, ids)
@@ -439,11 +696,26 @@ func (r *Containerd) StopContainers(ids []string) error {
 	return stopCRIContainers(r.Runner, ids)
 }
 
+// PrepareStop stops all the running containers at once within timeout, or each in turn within it, then containerd
+func (r *Containerd) PrepareStop(timeout time.Duration, inTurn bool) (int, error) {
+	return prepareStop(r, r.Init, inTurn, func(ids []string) error { return stopCRIContainersWithin(r.Runner, ids, timeout) }, "containerd")
+}
+
 // ContainerLogCmd returns the command to retrieve the log for a container based on ID
 func (r *Containerd) ContainerLogCmd(id string, len int, follow bool) string {
 	return criContainerLogCmd(r.Runner, id, len, follow)
 }
 
+// StreamContainerLogs writes the log of a container to w, until the log ends or ctx is done
+func (r *Containerd) StreamContainerLogs(ctx context.Context, id string, w io.Writer, opts StreamLogsOptions) error {
+	return streamContainerLogs(ctx, r.Runner, r.ContainerLogCmd(id, opts.Lines, opts.Follow), w)
+}
+
+// RunDiagnosticContainer runs a one-off container of img with cmd in the network of the host, returning its exit code
+func (r *Containerd) RunDiagnosticContainer(ctx context.Context, img string, cmd []string, o DiagnosticOptions) (int, error) {
+	return runCRIDiagnosticContainer(ctx, r, r.Runner, img, cmd, o)
+}
+
 // SystemLogCmd returns the command to retrieve system logs
 func (r *Containerd) SystemLogCmd(len int) string {
 	return fmt.Sprintf("sudo journalctl -u containerd -n %d", len)
@@ -451,8 +723,19 @@ func (r *Containerd) SystemLogCmd(len int) string {
 
 // Preload preloads the container runtime with k8s images
 func (r *Containerd) Preload(cc config.ClusterConfig) error {
+	return r.PreloadContext(context.Background(), cc)
+}
+
+// PreloadContext is Preload, killing its commands in the host once ctx is done
+func (r *Containerd) PreloadContext(ctx context.Context, cc config.ClusterConfig) error {
+	p := *r
+	return runPhase(ctx, "containerd.preload", &p.Runner, &p.Init, func() error { return p.preload(cc) })
+}
+
+// preload copies the preload tarball of the images of Kubernetes into the host, and extracts it
+func (r *Containerd) preload(cc config.ClusterConfig) error {
 	if !download.PreloadExists(cc.KubernetesConfig.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver) {
-		return nil
+		return missingPreload(r.Offline, cc)
 	}
 
 	k8sVersion := cc.KubernetesConfig.KubernetesVersion
@@ -463,21 +746,19 @@ func (r *Containerd) Preload(cc config.ClusterConfig) error {
 	if err != nil {
 		return errors.Wrap(err, "getting images")
 	}
-	if containerdImagesPreloaded(r.Runner, images) {
+	if r.preloadedWithAliases(images) {
 		klog.Info("Images already preloaded, skipping extraction")
 		return nil
 	}
 
-	tarballPath := download.TarballPath(k8sVersion, cRuntime)
+	tarballPath, compression, err := preloadTarball(r.Runner, k8sVersion, cRuntime)
+	if err != nil {
+		return err
+	}
 	targetDir := "/"
-	targetName := "preloaded.tar.lz4"
+	targetName := NodeTempName("preloaded" + compression.Extension())
 	dest := path.Join(targetDir, targetName)
 
-	c := exec.Command("which", "lz4")
-	if _, err := r.Runner.RunCmd(c); err != nil {
-		return NewErrISOFeature("lz4")
-	}
-
 	// Copy over tarball into host
 	fa, err := assets.NewFileAsset(tarballPath, targetDir, targetName, "0644")
 	if err != nil {
@@ -489,15 +770,21 @@ func (r *Containerd) Preload(cc config.ClusterConfig) error {
 		}
 	}()
 
+	if err := checkPreloadSpace(r.Runner, "/var", fa.GetLength(), r.PreloadFactor); err != nil {
+		return err
+	}
+
 	t := time.Now()
-	if err := r.Runner.Copy(fa); err != nil {
+	if err := trackImage(tarballPath, register.ImagePreloadCopy, func() string { return fmt.Sprintf("%d", fa.GetLength()) }, func() error {
+		return r.Runner.Copy(newProgressFile(fa, tarballPath, register.ImagePreloadCopy, r.ImageOutput))
+	}); err != nil {
 		return errors.Wrap(err, "copying file")
 	}
 	klog.Infof("Took %f seconds to copy over tarball", time.Since(t).Seconds())
 
 	t = time.Now()
 	// extract the tarball to /var in the VM
-	if rr, err := r.Runner.RunCmd(exec.Command("sudo", "tar", "-I", "lz4", "-C", "/var", "-xf", dest)); err != nil {
+	if rr, err := r.Runner.RunCmd(tarExtractCmd(compression, "/var", dest)); err != nil {
 		return errors.Wrapf(err, "extracting tarball: %s", rr.Output())
 	}
 	klog.Infof("Took %f seconds t extract the tarball", time.Since(t).Seconds())
@@ -507,17 +794,72 @@ func (r *Containerd) Preload(cc config.ClusterConfig) error {
 		klog.Infof("error removing tarball: %v", err)
 	}
 
-	return r.Restart()
+	if err := r.Restart(); err != nil {
+		return err
+	}
+	if !r.preloadedWithAliases(images) {
+		klog.Infof("preload does not hold all the images of Kubernetes %s", k8sVersion)
+	}
+	return nil
 }
 
-// Restart restarts Docker on a host
+// preloadedWithAliases tags the missing images from their registry alias, and returns true if all images have been preloaded
+func (r *Containerd) preloadedWithAliases(images []string) bool {
+	if tags, err := containerdImageTags(r.Runner); err == nil {
+		want := []string{}
+		for _, i := range images {
+			want = append(want, addRepoTagToImageName(i))
+		}
+		if err := retagAliases(r, want, tags); err != nil {
+			klog.Warningf("unable to tag preloaded images: %v", err)
+		}
+	}
+	return containerdImagesPreloaded(r.Runner, images)
+}
+
+// containerdImageTags returns the names of the images of containerd
+func containerdImageTags(runner command.Runner) ([]string, error) {
+	rr, err := runner.RunCmd(command.Sudo("crictl", "images", "--output", "json"))
+	if err != nil {
+		return nil, err
+	}
+	var jsonImages crictlImages
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &jsonImages); err != nil {
+		return nil, errors.Wrap(err, "crictl images")
+	}
+	tags := []string{}
+	for _, ji := range jsonImages.Images {
+		tags = append(tags, ji.RepoTags...)
+	}
+	return tags, nil
+}
+
+// ApplyPendingRestart does nothing, as containerd restarts right away for the images and settings to apply
+func (r *Containerd) ApplyPendingRestart() error {
+	return nil
+}
+
+// ApplyPendingRestartContext does nothing, as ApplyPendingRestart
+func (r *Containerd) ApplyPendingRestartContext(context.Context) error {
+	return nil
+}
+
+// Restart restarts containerd on a host
 func (r *Containerd) Restart() error {
 	return r.Init.Restart("containerd")
 }
 
+// RestartContext is Restart, killing its commands in the host once ctx is done
+func (r *Containerd) RestartContext(ctx context.Context) error {
+	p := *r
+	return runPhase(ctx, "containerd.restart", &p.Runner, &p.Init, p.Restart)
+}
+
 // containerdImagesPreloaded returns true if all images have been preloaded
-func containerdImagesPreloaded(runner command.Runner, images []string) bool {
-	rr, err := runner.RunCmd(exec.Command("sudo", "crictl", "images", "--output", "json"))
+func containerdImagesPreloaded(runner command.Runner, images []string) (preloaded bool) {
+	start := time.Now()
+	defer func() { recordPreloadCheck(start, preloaded) }()
+	rr, err := runner.RunCmd(command.Sudo("crictl", "images", "--output", "json"))
 	if err != nil {
 		return false
 	}
@@ -558,3 +900,8 @@ func containerdImagesPreloaded(runner command.Runner, images []string) bool {
 func (r *Containerd) ImagesPreloaded(images []string) bool {
 	return containerdImagesPreloaded(r.Runner, images)
 }
+
+// Purge removes the Kubernetes containers and the images of o, as minikube leaves the settings of containerd in place
+func (r *Containerd) Purge(o PurgeOptions) error {
+	return purgeKubernetes(r, o)
+}
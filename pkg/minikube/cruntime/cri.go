@@ -23,11 +23,14 @@ import (
 	"html/template"
 	"os/exec"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/out/register"
 )
 
 // container maps to 'runc list -f json'
@@ -47,6 +50,42 @@ type crictlImages struct {
 	} `json:"images"`
 }
 
+// crictlContainers maps to 'crictl ps --output json'
+type crictlContainers struct {
+	Containers []struct {
+		ID       string `json:"id"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Image struct {
+			Image string `json:"image"`
+		} `json:"image"`
+		PodSandboxID string            `json:"podSandboxId"`
+		State        string            `json:"state"`
+		CreatedAt    string            `json:"createdAt"`
+		Labels       map[string]string `json:"labels"`
+		Annotations  map[string]string `json:"annotations"`
+	} `json:"containers"`
+}
+
+// crictlPods maps to the output of 'crictl pods --output json'
+type crictlPods struct {
+	Items []struct {
+		ID        string            `json:"id"`
+		State     string            `json:"state"`
+		CreatedAt string            `json:"createdAt"`
+		Labels    map[string]string `json:"labels"`
+	} `json:"items"`
+}
+
+// crictlInspect maps to the output of 'crictl inspect --output json'
+type crictlInspect struct {
+	Status struct {
+		ID         string `json:"id"`
+		FinishedAt string `json:"finishedAt"`
+	} `json:"status"`
+}
+
 // crictlList returns the output of 'crictl ps' in an efficient manner
 func crictlList(cr CommandRunner, root string, o ListContainersOptions) (*command.RunResult, error) {
 	klog.Infof("listing CRI containers in root %s: %+v", root, o)
@@ -57,10 +96,13 @@ func crictlList(cr CommandRunner, root string, o ListContainersOptions) (*comman
 	if o.Name != "" {
 		baseCmd = append(baseCmd, fmt.Sprintf("--name=%s", o.Name))
 	}
+	if o.PodUID != "" {
+		baseCmd = append(baseCmd, fmt.Sprintf("--label=%s=%s", podUIDLabel, o.PodUID))
+	}
 
 	// shortcut for all namespaces
 	if len(o.Namespaces) == 0 {
-		return cr.RunCmd(exec.Command("sudo", baseCmd...))
+		return cr.RunCmd(command.Sudo(baseCmd...))
 	}
 
 	// Gather containers for all namespaces without causing extraneous shells to be launched
@@ -70,11 +112,15 @@ func crictlList(cr CommandRunner, root string, o ListContainersOptions) (*comman
 		cmds = append(cmds, cmd)
 	}
 
-	return cr.RunCmd(exec.Command("sudo", "-s", "eval", strings.Join(cmds, "; ")))
+	return cr.RunCmd(command.Sudo("-s", "eval", strings.Join(cmds, "; ")))
 }
 
 // listCRIContainers returns a list of containers
 func listCRIContainers(cr CommandRunner, root string, o ListContainersOptions) ([]string, error) {
+	o, ok := selectNamespaces(o)
+	if !ok {
+		return nil, nil
+	}
 	rr, err := crictlList(cr, root, o)
 	if err != nil {
 		return nil, errors.Wrap(err, "crictl list")
@@ -91,6 +137,16 @@ func listCRIContainers(cr CommandRunner, root string, o ListContainersOptions) (
 		}
 	}
 
+	if len(o.ExcludeNamespaces) > 0 {
+		if ids, err = excludeCRINamespaces(cr, ids, o); err != nil {
+			return nil, err
+		}
+		seen = map[string]bool{}
+		for _, id := range ids {
+			seen[id] = true
+		}
+	}
+
 	if len(ids) == 0 {
 		return nil, nil
 	}
@@ -99,6 +155,62 @@ func listCRIContainers(cr CommandRunner, root string, o ListContainersOptions) (
 	}
 
 	// crictl does not understand paused pods
+	cs, err := runcList(cr, root)
+	if err != nil {
+		klog.Warningf("runc list failed, falling back to the record of paused containers: %v", err)
+		return filterPausedState(cr, ids, o)
+	}
+
+	if len(cs) == 0 {
+		klog.Warningf("list returned 0 containers, but ps returned %d, falling back to the record of paused containers", len(ids))
+		return filterPausedState(cr, ids, o)
+	}
+
+	klog.Infof("list returned %d containers", len(cs))
+	var fids []string
+	for _, c := range cs {
+		klog.Infof("container: %+v", c)
+		if !seen[c.ID] {
+			klog.Infof("skipping %s - not in ps", c.ID)
+			continue
+		}
+		if o.State != All && o.State.String() != c.Status {
+			klog.Infof("skipping %s: state = %q, want %q", c, c.Status, o.State)
+			continue
+		}
+		fids = append(fids, c.ID)
+	}
+	return fids, nil
+}
+
+// excludeCRINamespaces leaves out the containers of the namespaces o excludes, by their namespace label,
+// as the label filters of crictl only select equal values
+func excludeCRINamespaces(cr CommandRunner, ids []string, o ListContainersOptions) ([]string, error) {
+	rr, err := cr.RunCmd(command.Sudo("crictl", "ps", "-a", "--output", "json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl ps")
+	}
+	var cs crictlContainers
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &cs); err != nil {
+		return nil, errors.Wrap(err, "crictl ps output")
+	}
+	excluded := map[string]bool{}
+	for _, c := range cs.Containers {
+		if !o.SelectsNamespace(c.Labels["io.kubernetes.pod.namespace"]) {
+			excluded[c.ID] = true
+		}
+	}
+	kept := []string{}
+	for _, id := range ids {
+		if !excluded[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept, nil
+}
+
+// runcList returns the output of 'runc list', which knows about paused containers
+func runcList(cr CommandRunner, root string) ([]container, error) {
 	cs := []container{}
 	args := []string{"runc"}
 	if root != "" {
@@ -106,7 +218,7 @@ func listCRIContainers(cr CommandRunner, root string, o ListContainersOptions) (
 	}
 
 	args = append(args, "list", "-f", "json")
-	rr, err = cr.RunCmd(exec.Command("sudo", args...))
+	rr, err := cr.RunCmd(command.Sudo(args...))
 	if err != nil {
 		return nil, errors.Wrap(err, "runc")
 	}
@@ -116,26 +228,69 @@ func listCRIContainers(cr CommandRunner, root string, o ListContainersOptions) (
 	if err := d.Decode(&cs); err != nil {
 		return nil, err
 	}
+	return cs, nil
+}
 
-	if len(cs) == 0 {
-		return nil, fmt.Errorf("list returned 0 containers, but ps returned %d", len(ids))
+// listCRIContainerInfo returns details of the containers matching the options
+func listCRIContainerInfo(cr CommandRunner, root string, o ListContainersOptions) ([]ContainerInfo, error) {
+	ids, err := listCRIContainers(cr, root, o)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	wanted := map[string]bool{}
+	for _, id := range ids {
+		wanted[id] = true
 	}
 
-	klog.Infof("list returned %d containers", len(cs))
-	var fids []string
-	for _, c := range cs {
-		klog.Infof("container: %+v", c)
-		if !seen[c.ID] {
-			klog.Infof("skipping %s - not in ps", c.ID)
-			continue
+	rr, err := cr.RunCmd(command.Sudo("crictl", "ps", "-a", "--output", "json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl ps")
+	}
+	var jsonContainers crictlContainers
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &jsonContainers); err != nil {
+		return nil, errors.Wrap(err, "crictl ps output")
+	}
+
+	// crictl reports paused containers as running, so ask runc as well
+	paused := map[string]bool{}
+	if cs, err := runcList(cr, root); err != nil {
+		klog.Warningf("unable to determine paused containers: %v", err)
+	} else {
+		for _, c := range cs {
+			if c.Status == Paused.String() {
+				paused[c.ID] = true
+			}
 		}
-		if o.State != All && o.State.String() != c.Status {
-			klog.Infof("skipping %s: state = %q, want %q", c, c.Status, o.State)
+	}
+
+	result := []ContainerInfo{}
+	for _, c := range jsonContainers.Containers {
+		if !wanted[c.ID] {
 			continue
 		}
-		fids = append(fids, c.ID)
+		state := strings.ToLower(strings.TrimPrefix(c.State, "CONTAINER_"))
+		if paused[c.ID] {
+			state = Paused.String()
+		}
+		var created time.Time
+		if ns, err := strconv.ParseInt(c.CreatedAt, 10, 64); err == nil {
+			created = time.Unix(0, ns)
+		}
+		result = append(result, ContainerInfo{
+			ID:        c.ID,
+			Name:      c.Metadata.Name,
+			Pod:       c.Labels["io.kubernetes.pod.name"],
+			PodUID:    c.Labels[podUIDLabel],
+			Namespace: c.Labels["io.kubernetes.pod.namespace"],
+			State:     state,
+			Image:     c.Image.Image,
+			Created:   created,
+		})
 	}
-	return fids, nil
+	return result, nil
 }
 
 // pauseContainers pauses a list of containers
@@ -145,12 +300,13 @@ func pauseCRIContainers(cr CommandRunner, root string, ids []string) error {
 		baseArgs = append(baseArgs, "--root", root)
 	}
 	baseArgs = append(baseArgs, "pause")
-	for _, id := range ids {
-		args := baseArgs
-		args = append(args, id)
-		if _, err := cr.RunCmd(exec.Command("sudo", args...)); err != nil {
-			return errors.Wrap(err, "runc")
-		}
+	// runc takes a single container
+	results, err := runOnContainers(cr, ids, 1, func(ids []string) *exec.Cmd {
+		return command.Sudo(append(baseArgs, ids...)...)
+	})
+	recordPausedContainers(cr, results.with(containerDone), true)
+	if err != nil {
+		return errors.Wrap(err, "runc")
 	}
 	return nil
 }
@@ -165,19 +321,20 @@ func getCrictlPath(cr CommandRunner) string {
 	return strings.Split(rr.Stdout.String(), "\n")[0]
 }
 
-// unpauseCRIContainers pauses a list of containers
+// unpauseCRIContainers unpauses a list of containers
 func unpauseCRIContainers(cr CommandRunner, root string, ids []string) error {
 	args := []string{"runc"}
 	if root != "" {
 		args = append(args, "--root", root)
 	}
 	args = append(args, "resume")
-	cargs := args
-	for _, id := range ids {
-		cargs := append(cargs, id)
-		if _, err := cr.RunCmd(exec.Command("sudo", cargs...)); err != nil {
-			return errors.Wrap(err, "runc")
-		}
+	results, err := runOnContainers(cr, ids, 1, func(ids []string) *exec.Cmd {
+		return command.Sudo(append(args, ids...)...)
+	})
+	// the containers which no longer exist are not paused either
+	recordPausedContainers(cr, append(results.with(containerDone), results.with(containerMissing)...), false)
+	if err != nil {
+		return errors.Wrap(err, "runc")
 	}
 	return nil
 }
@@ -190,34 +347,104 @@ func killCRIContainers(cr CommandRunner, ids []string) error {
 	klog.Infof("Killing containers: %s", ids)
 
 	crictl := getCrictlPath(cr)
-	args := append([]string{crictl, "rm"}, ids...)
-	c := exec.Command("sudo", args...)
-	if _, err := cr.RunCmd(c); err != nil {
+	if _, err := runOnContainers(cr, ids, containerBatchSize, func(ids []string) *exec.Cmd {
+		return command.Sudo(append([]string{crictl, "rm"}, ids...)...)
+	}); err != nil {
 		return errors.Wrap(err, "crictl")
 	}
 	return nil
 }
 
 // pullCRIImage pulls image using crictl
-func pullCRIImage(cr CommandRunner, name string) error {
+func pullCRIImage(cr CommandRunner, name string, output ImageOutput) error {
 	klog.Infof("Pulling image: %s", name)
 
 	crictl := getCrictlPath(cr)
-	args := append([]string{crictl, "pull"}, name)
-	c := exec.Command("sudo", args...)
-	if _, err := cr.RunCmd(c); err != nil {
-		return errors.Wrap(err, "crictl")
+	return trackImage(name, register.ImagePull, func() string { return criImageSize(cr, name) }, func() error {
+		args := append([]string{crictl, "pull"}, name)
+		c := command.Sudo(args...)
+		streamImageOutput(output, c, name, register.ImagePull)
+		if _, err := cr.RunCmd(c); err != nil {
+			return classifyCLIError(errors.Wrap(err, "crictl"))
+		}
+		return nil
+	})
+}
+
+// crictlImage maps to the status of 'crictl inspecti --output json'
+type crictlImage struct {
+	Status struct {
+		ID       string   `json:"id"`
+		RepoTags []string `json:"repoTags"`
+		Size     string   `json:"size"`
+	} `json:"status"`
+	Info struct {
+		ImageSpec struct {
+			RootFS struct {
+				DiffIDs []string `json:"diff_ids"`
+			} `json:"rootfs"`
+			History []struct {
+				Created    time.Time `json:"created"`
+				CreatedBy  string    `json:"created_by"`
+				EmptyLayer bool      `json:"empty_layer"`
+			} `json:"history"`
+		} `json:"imageSpec"`
+	} `json:"info"`
+}
+
+// inspectCRIImage returns the status of an image using crictl
+func inspectCRIImage(cr CommandRunner, name string) (*crictlImage, error) {
+	crictl := getCrictlPath(cr)
+	rr, err := cr.RunCmd(command.Sudo(crictl, "inspecti", "--output", "json", name))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl inspecti")
 	}
-	return nil
+	var img crictlImage
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &img); err != nil {
+		return nil, errors.Wrap(err, "crictl inspecti output")
+	}
+	return &img, nil
+}
+
+// criImageID returns the ID of an image using crictl
+func criImageID(cr CommandRunner, name string) (string, error) {
+	img, err := inspectCRIImage(cr, name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(img.Status.ID, "sha256:"), nil
+}
+
+// criImageSize returns the size in bytes of an image using crictl, or "" if unknown
+func criImageSize(cr CommandRunner, name string) string {
+	img, err := inspectCRIImage(cr, name)
+	if err != nil {
+		klog.Warningf("unable to get size of %s: %v", name, err)
+		return ""
+	}
+	return img.Status.Size
 }
 
-// removeCRIImage remove image using crictl
-func removeCRIImage(cr CommandRunner, name string) error {
+// removeCRIImage remove image using crictl, by its ID if the name has a digest.
+// crictl removes all the references of an image, so without force it only removes an image by its digest if it has no other tags.
+func removeCRIImage(cr CommandRunner, name string, force bool) error {
 	klog.Infof("Removing image: %s", name)
 
+	ref := name
+	if d, ok := digestRef(name); ok || force {
+		img, err := inspectCRIImage(cr, d)
+		if err != nil {
+			return errors.Wrapf(err, "resolving %s", name)
+		}
+		if !force && len(img.Status.RepoTags) > 1 {
+			return errors.Errorf("unable to remove %s, which is referenced by the tags %s: use --force to untag all of them", name, strings.Join(img.Status.RepoTags, ", "))
+		}
+		ref = img.Status.ID
+	}
+
 	crictl := getCrictlPath(cr)
-	args := append([]string{crictl, "rmi"}, name)
-	c := exec.Command("sudo", args...)
+	args := append([]string{crictl, "rmi"}, ref)
+	c := command.Sudo(args...)
 	if _, err := cr.RunCmd(c); err != nil {
 		return errors.Wrap(err, "crictl")
 	}
@@ -226,23 +453,41 @@ func removeCRIImage(cr CommandRunner, name string) error {
 
 // stopCRIContainers stops containers using crictl
 func stopCRIContainers(cr CommandRunner, ids []string) error {
+	return stopCRIContainersWithin(cr, ids, 0)
+}
+
+// stopCRIContainersWithin stops containers using crictl, killing them after timeout, or after the default timeout of the runtime if 0.
+// The batches of containers share the timeout, so that all of them are stopped within it.
+func stopCRIContainersWithin(cr CommandRunner, ids []string, timeout time.Duration) error {
 	if len(ids) == 0 {
 		return nil
 	}
 	klog.Infof("Stopping containers: %s", ids)
 
 	crictl := getCrictlPath(cr)
-	args := append([]string{crictl, "stop"}, ids...)
-	c := exec.Command("sudo", args...)
-	if _, err := cr.RunCmd(c); err != nil {
+	deadline := time.Now().Add(timeout)
+	if _, err := runOnContainers(cr, ids, containerBatchSize, func(ids []string) *exec.Cmd {
+		args := []string{crictl, "stop"}
+		if timeout > 0 {
+			args = append(args, "--timeout", stopSeconds(time.Until(deadline)))
+		}
+		return command.Sudo(append(args, ids...)...)
+	}); err != nil {
 		return errors.Wrap(err, "crictl")
 	}
 	return nil
 }
 
+// crictlConfigFile is the configuration of crictl
+const crictlConfigFile = "/etc/crictl.yaml"
+
 // populateCRIConfig sets up /etc/crictl.yaml
 func populateCRIConfig(cr CommandRunner, socket string) error {
-	cPath := "/etc/crictl.yaml"
+	return writeCRIConfig(cr, crictlConfigFile, socket)
+}
+
+// writeCRIConfig writes the configuration of crictl at cPath, for the runtime serving socket
+func writeCRIConfig(cr CommandRunner, cPath string, socket string) error {
 	tmpl := `runtime-endpoint: unix://{{.Socket}}
 image-endpoint: unix://{{.Socket}}
 `
@@ -265,7 +510,7 @@ image-endpoint: unix://{{.Socket}}
 // getCRIInfo returns current information
 func getCRIInfo(cr CommandRunner) (map[string]interface{}, error) {
 	args := []string{"crictl", "info"}
-	c := exec.Command("sudo", args...)
+	c := command.Sudo(args...)
 	rr, err := cr.RunCmd(c)
 	if err != nil {
 		return nil, errors.Wrap(err, "get cri info")
@@ -279,12 +524,37 @@ func getCRIInfo(cr CommandRunner) (map[string]interface{}, error) {
 	return jsonMap, nil
 }
 
-// listCRIImages lists images using crictl
-func listCRIImages(cr CommandRunner) ([]ListImage, error) {
-	c := exec.Command("sudo", "crictl", "images", "--output", "json")
+// listCRIImages lists the images matching opts using crictl, with their platforms
+func listCRIImages(cr CommandRunner, opts ListImagesOptions) ([]ListImage, error) {
+	images, err := crictlImageList(cr, opts)
+	if err != nil {
+		return nil, err
+	}
+	// crictl images does not print the platforms, so inspect all the images left at once
+	ids := []string{}
+	for _, img := range images {
+		ids = append(ids, img.ID)
+	}
+	platforms, err := criImagePlatforms(cr, ids)
+	if err != nil {
+		if opts.Platform != "" {
+			return nil, err
+		}
+		klog.Warningf("unable to get the platforms of the images: %v", err)
+	}
+	setPlatforms(images, platforms)
+	if opts.Platform == "" {
+		return images, nil
+	}
+	return FilterImages(images, ListImagesOptions{Platform: opts.Platform})
+}
+
+// crictlImageList lists the images matching the reference and dangling filters of opts using crictl, without their platforms
+func crictlImageList(cr CommandRunner, opts ListImagesOptions) ([]ListImage, error) {
+	c := command.Sudo("crictl", "images", "--output", "json")
 	rr, err := cr.RunCmd(c)
 	if err != nil {
-		return nil, errors.Wrapf(err, "crictl images")
+		return nil, classifyCLIError(errors.Wrapf(err, "crictl images"))
 	}
 
 	var jsonImages crictlImages
@@ -303,7 +573,8 @@ func listCRIImages(cr CommandRunner) ([]ListImage, error) {
 			Size:        img.Size,
 		})
 	}
-	return images, nil
+	// crictl can not filter images, so filter them here
+	return FilterImages(images, ListImagesOptions{Reference: opts.Reference, Dangling: opts.Dangling})
 }
 
 // criContainerLogCmd returns the command to retrieve the log for a container based on ID
@@ -335,3 +606,101 @@ func addRepoTagToImageName(imgName string) string {
 	} // else it already has repo name dont add anything
 	return imgName
 }
+
+// criFinishedAt returns when the given containers exited
+func criFinishedAt(cr CommandRunner, ids []string) (map[string]time.Time, error) {
+	args := append([]string{getCrictlPath(cr), "inspect", "--output", "json"}, ids...)
+	rr, err := cr.RunCmd(command.Sudo(args...))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl inspect")
+	}
+	finished := map[string]time.Time{}
+	// one document is written per container
+	d := json.NewDecoder(&rr.Stdout)
+	for d.More() {
+		var c crictlInspect
+		if err := d.Decode(&c); err != nil {
+			return nil, errors.Wrap(err, "crictl inspect output")
+		}
+		t, err := time.Parse(time.RFC3339Nano, c.Status.FinishedAt)
+		if err != nil {
+			klog.Warningf("unable to parse finish time %q of %s: %v", c.Status.FinishedAt, c.Status.ID, err)
+			continue
+		}
+		finished[c.Status.ID] = t
+	}
+	return finished, nil
+}
+
+// criGCCandidates returns the containers and pod sandboxes of a CRI runtime
+func criGCCandidates(cr CommandRunner) ([]gcCandidate, error) {
+	crictl := getCrictlPath(cr)
+	rr, err := cr.RunCmd(command.Sudo(crictl, "ps", "-a", "--output", "json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl ps")
+	}
+	var cs crictlContainers
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &cs); err != nil {
+		return nil, errors.Wrap(err, "crictl ps output")
+	}
+	candidates := []gcCandidate{}
+	exited := []string{}
+	for _, c := range cs.Containers {
+		running := c.State != "CONTAINER_EXITED"
+		candidates = append(candidates, gcCandidate{ID: c.ID, Sandbox: c.PodSandboxID, Running: running, Diagnostic: c.Labels[DiagnosticLabel] == "true"})
+		if !running {
+			exited = append(exited, c.ID)
+		}
+	}
+	if len(exited) > 0 {
+		finished, err := criFinishedAt(cr, exited)
+		if err != nil {
+			return nil, err
+		}
+		for i := range candidates {
+			candidates[i].Stopped = finished[candidates[i].ID]
+		}
+	}
+
+	rr, err = cr.RunCmd(command.Sudo(crictl, "pods", "--output", "json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl pods")
+	}
+	var pods crictlPods
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &pods); err != nil {
+		return nil, errors.Wrap(err, "crictl pods output")
+	}
+	for _, p := range pods.Items {
+		var created time.Time
+		if ns, err := strconv.ParseInt(p.CreatedAt, 10, 64); err == nil {
+			created = time.Unix(0, ns)
+		}
+		candidates = append(candidates, gcCandidate{ID: p.ID, IsSandbox: true, Running: p.State == "SANDBOX_READY", Stopped: created, Diagnostic: p.Labels[DiagnosticLabel] == "true"})
+	}
+	return candidates, nil
+}
+
+// garbageCollectCRI removes the containers and pod sandboxes which stopped longer ago than the given age
+func garbageCollectCRI(cr CommandRunner, olderThan time.Duration) (int, error) {
+	candidates, err := criGCCandidates(cr)
+	if err != nil {
+		return 0, err
+	}
+	containers, sandboxes := collectable(candidates, time.Now().Add(-olderThan))
+	crictl := getCrictlPath(cr)
+	if len(containers) > 0 {
+		klog.Infof("removing %d exited containers: %v", len(containers), containers)
+		args := append([]string{crictl, "rm"}, containers...)
+		if _, err := cr.RunCmd(command.Sudo(args...)); err != nil {
+			return 0, errors.Wrap(err, "crictl rm")
+		}
+	}
+	if len(sandboxes) > 0 {
+		klog.Infof("removing %d stopped pod sandboxes: %v", len(sandboxes), sandboxes)
+		args := append([]string{crictl, "rmp"}, sandboxes...)
+		if _, err := cr.RunCmd(command.Sudo(args...)); err != nil {
+			return len(containers), errors.Wrap(err, "crictl rmp")
+		}
+	}
+	return len(containers) + len(sandboxes), nil
+}
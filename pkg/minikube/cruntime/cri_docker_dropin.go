@@ -0,0 +1,211 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// mergedExecStartMarker comments out the ExecStart overrides of the drop-ins of users, once merged into criDockerServiceConfFile.
+// They are merged again as the drop-ins are synced, or as the network plugin changes.
+var mergedExecStartMarker = "# merged by minikube into " + path.Base(criDockerServiceConfFile) + ": "
+
+// criDockerFlags are the flags of cri-dockerd which minikube sets, and which override those of the users
+var criDockerFlags = map[string]bool{
+	"--container-runtime-endpoint": true,
+	"--network-plugin":             true,
+	"--cni-bin-dir":                true,
+	"--cni-cache-dir":              true,
+	"--cni-conf-dir":               true,
+	"--hairpin-mode":               true,
+	"--ipv6-dual-stack":            true,
+}
+
+// criDockerDropIn is a drop-in of the cri-docker service
+type criDockerDropIn struct {
+	path    string
+	content string
+}
+
+// listCRIDockerDropIns returns the drop-ins of the cri-docker service other than criDockerServiceConfFile, in the order systemd applies them
+func listCRIDockerDropIns(cr CommandRunner, pc PathConfig) ([]criDockerDropIn, error) {
+	conf := pc.systemdPath(criDockerServiceConfFile)
+	dir := path.Dir(conf)
+	rr, err := cr.RunCmd(command.Sudo("find", dir, "-maxdepth", "1", "-type", "f", "-name", "*.conf"))
+	if err != nil {
+		// the directory does not exist before the first start
+		klog.Infof("unable to list the drop-ins in %s: %v", dir, err)
+		return nil, nil
+	}
+	paths := strings.Fields(rr.Stdout.String())
+	sort.Slice(paths, func(i, j int) bool { return path.Base(paths[i]) < path.Base(paths[j]) })
+	dropIns := []criDockerDropIn{}
+	for _, p := range paths {
+		if p == conf {
+			continue
+		}
+		rr, err := cr.RunCmd(command.Sudo("cat", p))
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", p)
+		}
+		dropIns = append(dropIns, criDockerDropIn{path: p, content: rr.Stdout.String()})
+	}
+	return dropIns, nil
+}
+
+// execStarts returns the ExecStart settings of a drop-in, with those minikube merged before, joining the continued lines.
+// The empty ones reset the commands set before.
+func execStarts(content string) []string {
+	settings := []string{}
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		l := strings.TrimSpace(strings.TrimPrefix(lines[i], mergedExecStartMarker))
+		if !strings.HasPrefix(l, "ExecStart=") {
+			continue
+		}
+		for strings.HasSuffix(l, "\\") && i+1 < len(lines) {
+			i++
+			l = strings.TrimSuffix(l, "\\") + " " + strings.TrimSpace(strings.TrimPrefix(lines[i], mergedExecStartMarker))
+		}
+		settings = append(settings, strings.TrimSpace(strings.TrimPrefix(l, "ExecStart=")))
+	}
+	return settings
+}
+
+// disableExecStarts comments out the ExecStart settings of a drop-in with mergedExecStartMarker, returning whether there were any
+func disableExecStarts(content string) (string, bool) {
+	lines := strings.Split(content, "\n")
+	changed := false
+	continued := false
+	for i, l := range lines {
+		if continued || strings.HasPrefix(strings.TrimSpace(l), "ExecStart=") {
+			continued = strings.HasSuffix(l, "\\")
+			lines[i] = mergedExecStartMarker + l
+			changed = true
+		}
+	}
+	return strings.Join(lines, "\n"), changed
+}
+
+// splitFlags groups the arguments of a command by flag, each with the values following it
+func splitFlags(args []string) [][]string {
+	groups := [][]string{}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") || len(groups) == 0 {
+			groups = append(groups, []string{arg})
+			continue
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], arg)
+	}
+	return groups
+}
+
+// flagName returns the name of a flag given as --name=value or --name
+func flagName(flag string) string {
+	return strings.SplitN(flag, "=", 2)[0]
+}
+
+// mergeCRIDockerExecStart appends to the ExecStart of conf, the drop-in of minikube, the flags of the ExecStart settings of users
+// which minikube does not set, a later flag of a name replacing an earlier one. It returns the merged drop-in and the flags it dropped.
+func mergeCRIDockerExecStart(conf []byte, settings []string) ([]byte, []string) {
+	names := []string{}
+	flags := map[string][]string{}
+	dropped := []string{}
+	for _, s := range settings {
+		args := strings.Fields(s)
+		if len(args) == 0 {
+			// a reset, which minikube applies itself
+			continue
+		}
+		if bin := strings.TrimLeft(args[0], "-@:+!"); path.Base(bin) != "cri-dockerd" {
+			klog.Warningf("the cri-docker drop-ins run %s, ignoring it for cri-dockerd", bin)
+		}
+		for _, group := range splitFlags(args[1:]) {
+			name := flagName(group[0])
+			if criDockerFlags[name] {
+				dropped = append(dropped, strings.Join(group, " "))
+				continue
+			}
+			if _, ok := flags[name]; !ok {
+				names = append(names, name)
+			}
+			flags[name] = group
+		}
+	}
+	if len(names) == 0 {
+		return conf, dropped
+	}
+	extra := []string{}
+	for _, name := range names {
+		extra = append(extra, strings.Join(flags[name], " "))
+	}
+	merged := bytes.TrimRight(conf, "\n")
+	return append(merged, []byte(" "+strings.Join(extra, " "))...), dropped
+}
+
+// reconcileCRIDockerDropIns removes the drop-ins of older versions of minikube, and merges the ExecStart settings of the drop-ins of users
+// into conf, disabling them so that cri-dockerd is started by a single ExecStart. It returns the drop-in of minikube and whether
+// other drop-ins changed.
+func reconcileCRIDockerDropIns(cr CommandRunner, paths PathConfig, conf []byte) ([]byte, bool, error) {
+	dropIns, err := listCRIDockerDropIns(cr, paths)
+	if err != nil {
+		return nil, false, err
+	}
+	changed := false
+	settings := []string{}
+	for _, d := range dropIns {
+		// users may write drop-ins as minikube does, so only those where older versions wrote them are theirs
+		if d.path == paths.systemdPath(legacyCRIDockerServiceConfFile) && strings.HasPrefix(d.content, criDockerServiceConfHeader) {
+			klog.Infof("removing %s, written by an older version and replaced by %s", d.path, paths.systemdPath(criDockerServiceConfFile))
+			if _, err := cr.RunCmd(command.Sudo("rm", "-f", d.path)); err != nil {
+				return nil, false, errors.Wrapf(err, "removing %s", d.path)
+			}
+			changed = true
+			continue
+		}
+		s := execStarts(d.content)
+		if len(s) == 0 {
+			continue
+		}
+		settings = append(settings, s...)
+		content, overrides := disableExecStarts(d.content)
+		if !overrides {
+			continue
+		}
+		klog.Warningf("%s overrides the ExecStart of cri-docker, merging it into %s: %v", d.path, paths.systemdPath(criDockerServiceConfFile), s)
+		if err := cr.Copy(assets.NewMemoryAssetTarget([]byte(content), d.path, "0644")); err != nil {
+			return nil, false, errors.Wrapf(err, "disabling the ExecStart of %s", d.path)
+		}
+		changed = true
+	}
+	merged, dropped := mergeCRIDockerExecStart(conf, settings)
+	if len(dropped) > 0 {
+		klog.Warningf("dropped the flags of the cri-docker drop-ins which minikube sets: %v", dropped)
+	}
+	if len(merged) != len(conf) {
+		klog.Infof("merged the flags of the cri-docker drop-ins: %s", merged[len(conf):])
+	}
+	return merged, changed, nil
+}
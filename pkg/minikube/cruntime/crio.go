@@ -17,8 +17,10 @@ limitations under the License.
 package cruntime
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
@@ -35,6 +37,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/download"
+	"k8s.io/minikube/pkg/minikube/out/register"
 	"k8s.io/minikube/pkg/minikube/style"
 	"k8s.io/minikube/pkg/minikube/sysinit"
 )
@@ -51,6 +54,17 @@ type CRIO struct {
 	ImageRepository   string
 	KubernetesVersion semver.Version
 	Init              sysinit.Manager
+	CleanupNetwork    bool
+	CNIConfigs        []string
+	DockerOnDemand    bool
+	// Offline makes pulling images fail instead of waiting for unreachable registries, and preloads mandatory
+	Offline bool
+	// PreloadFactor scales the estimated space needed to extract the preload, skipping the check if negative
+	PreloadFactor float64
+	// ImageOutput is how the output of the commands pulling and loading images reaches the user
+	ImageOutput ImageOutput
+	// Driver is the driver of the node, the clock of the hosts which are not VMs is left to their owners
+	Driver string
 }
 
 // generateCRIOConfig sets up /etc/crio/crio.conf
@@ -61,6 +75,10 @@ func generateCRIOConfig(cr CommandRunner, imageRepository string, kv semver.Vers
 	if _, err := cr.RunCmd(c); err != nil {
 		return errors.Wrap(err, "generateCRIOConfig")
 	}
+	// the image GC of the kubelet spares the pinned images, such as the pause image which no pod starts without
+	if err := pinCRIOImage(cr, pauseImage); err != nil {
+		return err
+	}
 
 	if cni.Network != "" {
 		klog.Infof("Updating CRIO to use the custom CNI network %q", cni.Network)
@@ -93,8 +111,13 @@ func (r *CRIO) Style() style.Enum {
 
 // Version retrieves the current version of this runtime
 func (r *CRIO) Version() (string, error) {
+	return crioVersion(r.Runner)
+}
+
+// crioVersion retrieves the version of the CRI-O installed in the host of cr
+func crioVersion(cr CommandRunner) (string, error) {
 	c := exec.Command("crio", "--version")
-	rr, err := r.Runner.RunCmd(c)
+	rr, err := cr.RunCmd(c)
 	if err != nil {
 		return "", errors.Wrap(err, "crio version")
 	}
@@ -110,7 +133,40 @@ func (r *CRIO) SocketPath() string {
 	if r.Socket != "" {
 		return r.Socket
 	}
-	return "/var/run/crio/crio.sock"
+	return CRIOCRISocket
+}
+
+// RuntimeHealth reports the health of crio
+func (r *CRIO) RuntimeHealth() (*Health, error) {
+	h, err := runtimeHealth(r.Runner, []healthUnit{{unit: "crio", process: "crio"}}, func() error {
+		_, err := r.Version()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	checkPauseImage(h, r, pauseImage(r.KubernetesVersion, r.ImageRepository))
+	return h, nil
+}
+
+// EnsurePauseImage restores the pause image if it is missing, loaded from the local preload or pulled
+func (r *CRIO) EnsurePauseImage() (bool, error) {
+	return ensurePauseImage(r, pauseImage(r.KubernetesVersion, r.ImageRepository),
+		pauseFromPreload(r, r.Runner, "crio", r.KubernetesVersion, r.ImageRepository), r.PullImage)
+}
+
+// Healthy returns an error if crio is not active, or if its socket does not answer
+func (r *CRIO) Healthy() error {
+	return servicesHealthy(r.Init, []string{"crio"}, func() error {
+		return criResponsive(r.Runner, r.SocketPath())
+	})
+}
+
+// Events returns the incidents logged by CRI-O, along with the OOM kills and the state changes of its unit
+func (r *CRIO) Events(since time.Duration) ([]RuntimeEvent, error) {
+	return runtimeEvents(r.Runner, []string{"crio.service"}, since, func() ([]RuntimeEvent, error) {
+		return journalEvents(r.Runner, "crio", []string{"-u", "crio"}, since, crioPatterns)
+	})
 }
 
 // Available returns an error if it is not possible to use this runtime on a host
@@ -119,7 +175,7 @@ func (r *CRIO) Available() error {
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrapf(err, "check crio available")
 	}
-	return nil
+	return CheckElevation(r.Runner)
 }
 
 // Active returns if CRIO is active on the host
@@ -132,15 +188,15 @@ func (r *CRIO) Active() bool {
 func enableIPForwarding(cr CommandRunner) error {
 	// The bridge-netfilter module enables iptables rules to work on Linux bridges
 	// NOTE: br_netfilter isn't available in WSL2, but forwarding works fine there anyways
-	c := exec.Command("sudo", "sysctl", "net.bridge.bridge-nf-call-iptables")
+	c := command.Sudo("sysctl", "net.bridge.bridge-nf-call-iptables")
 	if rr, err := cr.RunCmd(c); err != nil {
 		klog.Infof("couldn't verify netfilter by %q which might be okay. error: %v", rr.Command(), err)
-		c = exec.Command("sudo", "modprobe", "br_netfilter")
+		c = command.Sudo("modprobe", "br_netfilter")
 		if _, err := cr.RunCmd(c); err != nil {
 			klog.Warningf("%q failed, which may be ok: %v", rr.Command(), err)
 		}
 	}
-	c = exec.Command("sudo", "sh", "-c", "echo 1 > /proc/sys/net/ipv4/ip_forward")
+	c = command.Sudo("sh", "-c", "echo 1 > /proc/sys/net/ipv4/ip_forward")
 	if _, err := cr.RunCmd(c); err != nil {
 		return errors.Wrapf(err, "ip_forward")
 	}
@@ -161,7 +217,7 @@ Environment="_CRIO_ROOTLESS=1"
 	}
 	for target, content := range files {
 		targetDir := filepath.Dir(target)
-		c := exec.Command("sudo", "mkdir", "-p", targetDir)
+		c := command.Sudo("mkdir", "-p", targetDir)
 		if _, err := r.Runner.RunCmd(c); err != nil {
 			return errors.Wrapf(err, "failed to create directory %q", targetDir)
 		}
@@ -187,7 +243,7 @@ Environment="_CRIO_ROOTLESS=1"
 // Enable idempotently enables CRIO on a host
 func (r *CRIO) Enable(disOthers, forceSystemd, inUserNamespace bool) error {
 	if disOthers {
-		if err := disableOthers(r, r.Runner); err != nil {
+		if err := disableOthers(r, r.Runner, disableOptions{CleanupNetwork: r.CleanupNetwork, CNIConfigs: r.CNIConfigs, DockerOnDemand: r.DockerOnDemand, ForceSystemd: forceSystemd}); err != nil {
 			klog.Warningf("disableOthers: %v", err)
 		}
 	}
@@ -222,6 +278,14 @@ func (r *CRIO) Enable(disOthers, forceSystemd, inUserNamespace bool) error {
 	return r.Init.Start("crio")
 }
 
+// EnableContext is Enable, killing its commands in the host once ctx is done
+func (r *CRIO) EnableContext(ctx context.Context, disOthers, forceSystemd, inUserNamespace bool) error {
+	p := *r
+	return runPhase(ctx, "crio.enable", &p.Runner, &p.Init, func() error {
+		return p.Enable(disOthers, forceSystemd, inUserNamespace)
+	})
+}
+
 // Disable idempotently disables CRIO on a host
 func (r *CRIO) Disable() error {
 	return r.Init.ForceStop("crio")
@@ -229,8 +293,11 @@ func (r *CRIO) Disable() error {
 
 // ImageExists checks if image exists based on image name and optionally image sha
 func (r *CRIO) ImageExists(name string, sha string) bool {
+	if _, ok := digestRef(name); ok {
+		return criImageExists(r.Runner, name, sha)
+	}
 	// expected output looks like [NAME@sha256:SHA]
-	c := exec.Command("sudo", "podman", "image", "inspect", "--format", "{{.Id}}", name)
+	c := command.Sudo("podman", "image", "inspect", "--format", "{{.Id}}", name)
 	rr, err := r.Runner.RunCmd(c)
 	if err != nil {
 		return false
@@ -241,78 +308,184 @@ func (r *CRIO) ImageExists(name string, sha string) bool {
 	return true
 }
 
+// ImagesExist returns which of the images exist
+func (r *CRIO) ImagesExist(names []string) (map[string]bool, error) {
+	return criImagesExist(r.Runner, names)
+}
+
+// ImageID returns the ID of an image
+func (r *CRIO) ImageID(name string) (string, error) {
+	return criImageID(r.Runner, name)
+}
+
 // ListImages returns a list of images managed by this container runtime
-func (r *CRIO) ListImages(ListImagesOptions) ([]ListImage, error) {
-	return listCRIImages(r.Runner)
+func (r *CRIO) ListImages(opts ListImagesOptions) ([]ListImage, error) {
+	return listCRIImages(r.Runner, opts)
+}
+
+// ImageHistory returns the layers of an image, newest first
+func (r *CRIO) ImageHistory(name string) ([]LayerInfo, error) {
+	rr, err := r.Runner.RunCmd(command.Sudo("podman", "history", "--no-trunc", "--format", "json", name))
+	if err != nil {
+		return nil, errors.Wrap(err, "podman history")
+	}
+	steps, err := parsePodmanHistory(rr.Stdout.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	diffIDs, err := inspectRootFSLayers(r.Runner, name, "sudo", "podman")
+	if err != nil {
+		return nil, err
+	}
+	return layersFromHistory(steps, diffIDs), nil
+}
+
+// ImageScanTarget scans the images of CRI-O from an archive, as the podman socket is not enabled
+func (r *CRIO) ImageScanTarget() ImageScanTarget {
+	return ImageScanTarget{Archive: true}
+}
+
+// Capabilities returns what CRI-O supports
+func (r *CRIO) Capabilities() Capabilities {
+	return Capabilities{
+		SupportsBuild:   true,
+		SupportsPause:   true,
+		SnapshotterName: "overlay",
+		CRISocket:       r.SocketPath(),
+	}
 }
 
 // LoadImage loads an image into this runtime
-func (r *CRIO) LoadImage(path string) error {
+func (r *CRIO) LoadImage(path string) ([]string, error) {
 	klog.Infof("Loading image: %s", path)
-	c := exec.Command("sudo", "podman", "load", "-i", path)
-	if _, err := r.Runner.RunCmd(c); err != nil {
-		return errors.Wrap(err, "crio load image")
-	}
-	return nil
+	var refs []string
+	err := trackImage(path, register.ImageLoad, func() string { return fileSize(r.Runner, path) }, func() error {
+		c := command.Sudo("podman", "load", "-i", path)
+		streamImageOutput(r.ImageOutput, c, path, register.ImageLoad)
+		rr, err := r.Runner.RunCmd(c)
+		if err != nil {
+			return errors.Wrap(err, "crio load image")
+		}
+		refs = parseLoadedImages(rr.Stdout.String())
+		return nil
+	})
+	return refs, err
 }
 
 // PullImage pulls an image
 func (r *CRIO) PullImage(name string) error {
-	return pullCRIImage(r.Runner, name)
+	if r.Offline {
+		return NewErrOffline(name)
+	}
+	return pullWithClockCheck(r.Runner, r.Init, r.Driver, func() error { return pullCRIImage(r.Runner, name, r.ImageOutput) })
 }
 
 // SaveImage saves an image from this runtime
 func (r *CRIO) SaveImage(name string, path string) error {
 	klog.Infof("Saving image %s: %s", name, path)
-	c := exec.Command("sudo", "podman", "save", name, "-o", path)
+	c := command.Sudo("podman", "save", name, "-o", path)
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "crio save image")
 	}
 	return nil
 }
 
+// ExportImageStore saves all the tagged images of CRI-O into a single archive at path
+func (r *CRIO) ExportImageStore(path string) error {
+	refs, err := imageStoreRefs(r)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Exporting %d images: %s", len(refs), path)
+	return trackImage(path, register.ImageStoreExport, func() string { return fileSize(r.Runner, path) }, func() error {
+		// podman saves only the first image unless asked for an archive of many
+		c := command.Sudo(append([]string{"podman", "save", "--multi-image-archive", "-o", path}, refs...)...)
+		if _, err := r.Runner.RunCmd(c); err != nil {
+			return errors.Wrap(err, "podman save")
+		}
+		return nil
+	})
+}
+
+// ImportImageStore loads all the images of the archive at path into CRI-O
+func (r *CRIO) ImportImageStore(path string) error {
+	klog.Infof("Importing images: %s", path)
+	return trackImage(path, register.ImageStoreImport, func() string { return fileSize(r.Runner, path) }, func() error {
+		c := command.Sudo("podman", "load", "-i", path)
+		if _, err := r.Runner.RunCmd(c); err != nil {
+			return errors.Wrap(err, "podman load")
+		}
+		return nil
+	})
+}
+
 // RemoveImage removes a image
 func (r *CRIO) RemoveImage(name string) error {
-	return removeCRIImage(r.Runner, name)
+	return removeCRIImage(r.Runner, name, false)
+}
+
+// ForceRemoveImage removes an image with all of its references
+func (r *CRIO) ForceRemoveImage(name string) error {
+	return removeCRIImage(r.Runner, name, true)
 }
 
 // TagImage tags an image in this runtime
 func (r *CRIO) TagImage(source string, target string) error {
 	klog.Infof("Tagging image %s: %s", source, target)
-	c := exec.Command("sudo", "podman", "tag", source, target)
+	source, err := criImageRef(r.Runner, source)
+	if err != nil {
+		return errors.Wrap(err, "crio tag image")
+	}
+	c := command.Sudo("podman", "tag", source, target)
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "crio tag image")
 	}
 	return nil
 }
 
+// RetagImages tags each source image as its target with podman in one script, untagging the targets it created if any fails
+func (r *CRIO) RetagImages(mapping map[string]string) error {
+	return retagImages(r, r.Runner, retagCommands{
+		script: bashScript,
+		tag: func(source, target string) string {
+			return fmt.Sprintf("sudo podman tag %s %s", source, target)
+		},
+		untag: func(target string) string {
+			return fmt.Sprintf("sudo podman untag %s", target)
+		},
+	}, mapping)
+}
+
 // BuildImage builds an image into this runtime
-func (r *CRIO) BuildImage(src string, file string, tag string, push bool, env []string, opts []string) error {
+func (r *CRIO) BuildImage(src string, opts BuildOptions) error {
 	klog.Infof("Building image: %s", src)
+	cacheArgs, err := buildCacheArgs(builderPodman, opts)
+	if err != nil {
+		return err
+	}
 	args := []string{"podman", "build"}
-	if file != "" {
-		args = append(args, "-f", file)
+	if opts.File != "" {
+		args = append(args, "-f", opts.File)
 	}
-	if tag != "" {
-		args = append(args, "-t", tag)
+	if opts.Tag != "" {
+		args = append(args, "-t", opts.Tag)
 	}
+	args = append(args, cacheArgs...)
 	args = append(args, src)
-	for _, opt := range opts {
+	for _, opt := range opts.Opts {
 		args = append(args, "--"+opt)
 	}
-	c := exec.Command("sudo", args...)
+	c := command.Sudo(args...)
 	e := os.Environ()
-	e = append(e, env...)
+	e = append(e, opts.Env...)
 	c.Env = e
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
+	c.Stdout, c.Stderr = opts.streams()
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "crio build image")
 	}
-	if tag != "" && push {
-		c := exec.Command("sudo", "podman", "push", tag)
-		c.Stdout = os.Stdout
-		c.Stderr = os.Stderr
+	if opts.Tag != "" && opts.Push {
+		c := command.Sudo("podman", "push", opts.Tag)
+		c.Stdout, c.Stderr = opts.streams()
 		if _, err := r.Runner.RunCmd(c); err != nil {
 			return errors.Wrap(err, "crio push image")
 		}
@@ -323,7 +496,7 @@ func (r *CRIO) BuildImage(src string, file string, tag string, push bool, env []
 // PushImage pushes an image
 func (r *CRIO) PushImage(name string) error {
 	klog.Infof("Pushing image %s", name)
-	c := exec.Command("sudo", "podman", "push", name)
+	c := command.Sudo("podman", "push", name)
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "crio push image")
 	}
@@ -335,6 +508,9 @@ func (r *CRIO) CGroupDriver() (string, error) {
 	c := exec.Command("crio", "config")
 	rr, err := r.Runner.RunCmd(c)
 	if err != nil {
+		if !r.Active() {
+			return "", &ErrDaemonNotRunning{Runtime: r.Name(), Err: err}
+		}
 		return "", err
 	}
 	cgroupManager := "systemd" // default
@@ -365,6 +541,31 @@ func (r *CRIO) ListContainers(o ListContainersOptions) ([]string, error) {
 	return listCRIContainers(r.Runner, "", o)
 }
 
+// ListContainerInfo returns details of the containers managed by this container runtime
+func (r *CRIO) ListContainerInfo(o ListContainersOptions) ([]ContainerInfo, error) {
+	return listCRIContainerInfo(r.Runner, "", o)
+}
+
+// PortMappings returns the ports a container, given by its ID or a prefix of it, exposes or publishes on the node
+func (r *CRIO) PortMappings(id string) ([]PortMapping, error) {
+	return criPortMappings(r.Runner, id)
+}
+
+// CheckpointContainer checkpoints a running container into a tarball on the host of the runtime (experimental)
+func (r *CRIO) CheckpointContainer(id string, destPath string) error {
+	return checkpointCRIContainer(r.Runner, r.Name(), id, destPath)
+}
+
+// CopyToContainer copies the file src of the node into the directory dir of a running container, keeping its name
+func (r *CRIO) CopyToContainer(c ContainerInfo, src string, dir string, o CopyOptions) error {
+	return copyIntoCRI(r.Runner, r.Name(), c, src, dir, o, nil)
+}
+
+// GarbageCollect removes the containers and pod sandboxes which stopped longer ago than the given age
+func (r *CRIO) GarbageCollect(olderThan time.Duration) (int, error) {
+	return garbageCollectCRI(r.Runner, olderThan)
+}
+
 // PauseContainers pauses a running container based on ID
 func (r *CRIO) PauseContainers(ids []string) error {
 	return pauseCRIContainers(r.Runner, "", ids)
@@ -385,11 +586,26 @@ func (r *CRIO) StopContainers(ids []string) error {
 	return stopCRIContainers(r.Runner, ids)
 }
 
+// PrepareStop stops all the running containers at once within timeout, or each in turn within it, then CRI-O
+func (r *CRIO) PrepareStop(timeout time.Duration, inTurn bool) (int, error) {
+	return prepareStop(r, r.Init, inTurn, func(ids []string) error { return stopCRIContainersWithin(r.Runner, ids, timeout) }, "crio")
+}
+
 // ContainerLogCmd returns the command to retrieve the log for a container based on ID
 func (r *CRIO) ContainerLogCmd(id string, len int, follow bool) string {
 	return criContainerLogCmd(r.Runner, id, len, follow)
 }
 
+// StreamContainerLogs writes the log of a container to w, until the log ends or ctx is done
+func (r *CRIO) StreamContainerLogs(ctx context.Context, id string, w io.Writer, opts StreamLogsOptions) error {
+	return streamContainerLogs(ctx, r.Runner, r.ContainerLogCmd(id, opts.Lines, opts.Follow), w)
+}
+
+// RunDiagnosticContainer runs a one-off container of img with cmd in the network of the host, returning its exit code
+func (r *CRIO) RunDiagnosticContainer(ctx context.Context, img string, cmd []string, o DiagnosticOptions) (int, error) {
+	return runCRIDiagnosticContainer(ctx, r, r.Runner, img, cmd, o)
+}
+
 // SystemLogCmd returns the command to retrieve system logs
 func (r *CRIO) SystemLogCmd(len int) string {
 	return fmt.Sprintf("sudo journalctl -u crio -n %d", len)
@@ -397,8 +613,19 @@ func (r *CRIO) SystemLogCmd(len int) string {
 
 // Preload preloads the container runtime with k8s images
 func (r *CRIO) Preload(cc config.ClusterConfig) error {
+	return r.PreloadContext(context.Background(), cc)
+}
+
+// PreloadContext is Preload, killing its commands in the host once ctx is done
+func (r *CRIO) PreloadContext(ctx context.Context, cc config.ClusterConfig) error {
+	p := *r
+	return runPhase(ctx, "crio.preload", &p.Runner, &p.Init, func() error { return p.preload(cc) })
+}
+
+// preload copies the preload tarball of the images of Kubernetes into the host, and extracts it
+func (r *CRIO) preload(cc config.ClusterConfig) error {
 	if !download.PreloadExists(cc.KubernetesConfig.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver) {
-		return nil
+		return missingPreload(r.Offline, cc)
 	}
 
 	k8sVersion := cc.KubernetesConfig.KubernetesVersion
@@ -414,16 +641,14 @@ func (r *CRIO) Preload(cc config.ClusterConfig) error {
 		return nil
 	}
 
-	tarballPath := download.TarballPath(k8sVersion, cRuntime)
+	tarballPath, compression, err := preloadTarball(r.Runner, k8sVersion, cRuntime)
+	if err != nil {
+		return err
+	}
 	targetDir := "/"
-	targetName := "preloaded.tar.lz4"
+	targetName := NodeTempName("preloaded" + compression.Extension())
 	dest := path.Join(targetDir, targetName)
 
-	c := exec.Command("which", "lz4")
-	if _, err := r.Runner.RunCmd(c); err != nil {
-		return NewErrISOFeature("lz4")
-	}
-
 	// Copy over tarball into host
 	fa, err := assets.NewFileAsset(tarballPath, targetDir, targetName, "0644")
 	if err != nil {
@@ -435,15 +660,21 @@ func (r *CRIO) Preload(cc config.ClusterConfig) error {
 		}
 	}()
 
+	if err := checkPreloadSpace(r.Runner, "/var", fa.GetLength(), r.PreloadFactor); err != nil {
+		return err
+	}
+
 	t := time.Now()
-	if err := r.Runner.Copy(fa); err != nil {
+	if err := trackImage(tarballPath, register.ImagePreloadCopy, func() string { return fmt.Sprintf("%d", fa.GetLength()) }, func() error {
+		return r.Runner.Copy(newProgressFile(fa, tarballPath, register.ImagePreloadCopy, r.ImageOutput))
+	}); err != nil {
 		return errors.Wrap(err, "copying file")
 	}
 	klog.Infof("Took %f seconds to copy over tarball", time.Since(t).Seconds())
 
 	t = time.Now()
 	// extract the tarball to /var in the VM
-	if rr, err := r.Runner.RunCmd(exec.Command("sudo", "tar", "-I", "lz4", "-C", "/var", "-xf", dest)); err != nil {
+	if rr, err := r.Runner.RunCmd(tarExtractCmd(compression, "/var", dest)); err != nil {
 		return errors.Wrapf(err, "extracting tarball: %s", rr.Output())
 	}
 	klog.Infof("Took %f seconds t extract the tarball", time.Since(t).Seconds())
@@ -457,8 +688,10 @@ func (r *CRIO) Preload(cc config.ClusterConfig) error {
 }
 
 // crioImagesPreloaded returns true if all images have been preloaded
-func crioImagesPreloaded(runner command.Runner, images []string) bool {
-	rr, err := runner.RunCmd(exec.Command("sudo", "crictl", "images", "--output", "json"))
+func crioImagesPreloaded(runner command.Runner, images []string) (preloaded bool) {
+	start := time.Now()
+	defer func() { recordPreloadCheck(start, preloaded) }()
+	rr, err := runner.RunCmd(command.Sudo("crictl", "images", "--output", "json"))
 	if err != nil {
 		return false
 	}
@@ -499,3 +732,18 @@ func crioImagesPreloaded(runner command.Runner, images []string) bool {
 func (r *CRIO) ImagesPreloaded(images []string) bool {
 	return crioImagesPreloaded(r.Runner, images)
 }
+
+// ApplyPendingRestart does nothing, as CRI-O restarts right away for its settings to apply
+func (r *CRIO) ApplyPendingRestart() error {
+	return nil
+}
+
+// ApplyPendingRestartContext does nothing, as ApplyPendingRestart
+func (r *CRIO) ApplyPendingRestartContext(context.Context) error {
+	return nil
+}
+
+// Purge removes the Kubernetes containers and the images of o, as minikube leaves the settings of CRI-O in place
+func (r *CRIO) Purge(o PurgeOptions) error {
+	return purgeKubernetes(r, o)
+}
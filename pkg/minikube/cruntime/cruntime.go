@@ -18,20 +18,36 @@ limitations under the License.
 package cruntime
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"math"
 	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/blang/semver/v4"
+	"github.com/kballard/go-shellquote"
 	"github.com/pkg/errors"
 	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/audit"
+	"k8s.io/minikube/pkg/minikube/cni"
 	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/out/register"
 	"k8s.io/minikube/pkg/minikube/style"
 	"k8s.io/minikube/pkg/minikube/sysinit"
+	"k8s.io/minikube/pkg/trace"
 )
 
+// podUIDLabel is the label holding the UID of the pod of a Kubernetes container
+const podUIDLabel = "io.kubernetes.pod.uid"
+
 // ContainerState is the run state of a container
 type ContainerState int
 
@@ -48,6 +64,16 @@ func (cs ContainerState) String() string {
 	return [...]string{"all", "running", "paused"}[cs]
 }
 
+// ParseContainerState returns the ContainerState matching the given name
+func ParseContainerState(name string) (ContainerState, error) {
+	for _, cs := range []ContainerState{All, Running, Paused} {
+		if strings.EqualFold(cs.String(), name) {
+			return cs, nil
+		}
+	}
+	return All, fmt.Errorf("unknown container state: %q", name)
+}
+
 // ValidRuntimes lists the supported container runtimes
 func ValidRuntimes() []string {
 	return []string{"docker", "cri-o", "containerd"}
@@ -58,6 +84,8 @@ type CommandRunner interface {
 	// RunCmd is a blocking method that runs a command
 	// Use this if you don't need to stream stdout and stderr in real-time
 	RunCmd(cmd *exec.Cmd) (*command.RunResult, error)
+	// RunCmdContext runs a command like RunCmd, stopping it when ctx is done
+	RunCmdContext(ctx context.Context, cmd *exec.Cmd) (*command.RunResult, error)
 	// StartCmd is a non-blocking method that starts a command
 	// Use WaitCmd to block until the command is complete
 	// Use this if you need to stream stdout and/or stderr in real-time
@@ -88,6 +116,14 @@ type Manager interface {
 	Active() bool
 	// Available returns an error if it is not possible to use this runtime on a host
 	Available() error
+	// RuntimeHealth reports whether the runtime on a host is running, degraded or crash looping
+	RuntimeHealth() (*Health, error)
+	// EnsurePauseImage restores the pause image of Kubernetes if it is missing, returning whether it did
+	EnsurePauseImage() (bool, error)
+	// Healthy returns an error if the services of the runtime are not active, or its socket does not answer
+	Healthy() error
+	// Events returns the incidents of the runtime and its host in the last period, such as OOM kills, restarts and image deletions, oldest first
+	Events(time.Duration) ([]RuntimeEvent, error)
 	// Style is an associated StyleEnum for Name()
 	Style() style.Enum
 
@@ -98,30 +134,64 @@ type Manager interface {
 	// SocketPath returns the path to the socket file for a given runtime
 	SocketPath() string
 
-	// Load an image idempotently into the runtime on a host
-	LoadImage(string) error
+	// Load an image idempotently into the runtime on a host, returning the references of the images loaded
+	LoadImage(string) ([]string, error)
 	// Pull an image to the runtime from the container registry
 	PullImage(string) error
 	// Build an image idempotently into the runtime on a host
-	BuildImage(string, string, string, bool, []string, []string) error
+	BuildImage(string, BuildOptions) error
 	// Save an image from the runtime on a host
 	SaveImage(string, string) error
 	// Tag an image
 	TagImage(string, string) error
+	// RetagImages tags each source image of the mapping as its target at once, untagging the targets it created if any fails
+	RetagImages(map[string]string) error
 	// Push an image from the runtime to the container registry
 	PushImage(string) error
 
 	// ImageExists takes image name and optionally image sha to check if an image exists
 	ImageExists(string, string) bool
+	// ImagesExist returns which of the images exist, asking the runtime once for all of them
+	ImagesExist([]string) (map[string]bool, error)
+	// ImageID returns the ID of an image, without the digest algorithm prefix
+	ImageID(string) (string, error)
 	// ListImages returns a list of images managed by this container runtime
 	ListImages(ListImagesOptions) ([]ListImage, error)
+	// ImageHistory returns the layers of an image, newest first
+	ImageHistory(string) ([]LayerInfo, error)
+	// ImageScanTarget tells an image scanner how to reach the images of this runtime
+	ImageScanTarget() ImageScanTarget
+	// Capabilities returns what this runtime supports
+	Capabilities() Capabilities
+
+	// ExportImageStore saves all the tagged images of the runtime on a host into a single archive
+	ExportImageStore(string) error
+	// ImportImageStore loads all the images of an archive written by ExportImageStore
+	ImportImageStore(string) error
 
 	// RemoveImage remove image based on name
 	RemoveImage(string) error
+	// ForceRemoveImage removes the image a name refers to, untagging all of its references
+	ForceRemoveImage(string) error
 
 	// ListContainers returns a list of containers managed by this container runtime
 	ListContainers(ListContainersOptions) ([]string, error)
-	// KillContainers removes containers based on ID
+	// ListContainerInfo returns details of the containers managed by this container runtime
+	ListContainerInfo(ListContainersOptions) ([]ContainerInfo, error)
+	// CheckpointContainer checkpoints a running container into a tarball on the host of the runtime (experimental)
+	CheckpointContainer(id string, destPath string) error
+	// PortMappings returns the ports a container, given by its ID or a prefix of it, exposes or publishes on the node,
+	// with its address in the node
+	PortMappings(id string) ([]PortMapping, error)
+	// CopyToContainer copies the file src of the node into the directory dir of a container, keeping its name
+	CopyToContainer(c ContainerInfo, src string, dir string, o CopyOptions) error
+	// GarbageCollect removes the Kubernetes containers and pod sandboxes which stopped longer ago than the given age
+	GarbageCollect(time.Duration) (int, error)
+	// PrepareStop stops all the running containers at once within the given timeout, or each in turn within it if inTurn is set,
+	// then the runtime services, so that stopping the node does not wait for the runtime to stop them. It returns how many containers it stopped.
+	PrepareStop(timeout time.Duration, inTurn bool) (int, error)
+	// KillContainers removes containers based on ID. Like the other operations on containers,
+	// it skips the containers which no longer exist, only failing on the existing ones.
 	KillContainers([]string) error
 	// StopContainers stops containers based on ID
 	StopContainers([]string) error
@@ -131,12 +201,47 @@ type Manager interface {
 	UnpauseContainers([]string) error
 	// ContainerLogCmd returns the command to retrieve the log for a container based on ID
 	ContainerLogCmd(string, int, bool) string
+	// StreamContainerLogs writes the log of a container to the writer, until the log ends or the context is done
+	StreamContainerLogs(context.Context, string, io.Writer, StreamLogsOptions) error
+	// RunDiagnosticContainer runs a one-off container of an image with a command in the network of the host, pulling the image if needed.
+	// It streams the output of the container and returns its exit code, removing the container even if the context is done.
+	RunDiagnosticContainer(context.Context, string, []string, DiagnosticOptions) (int, error)
 	// SystemLogCmd returns the command to return the system logs
 	SystemLogCmd(int) string
 	// Preload preloads the container runtime with k8s images
 	Preload(config.ClusterConfig) error
 	// ImagesPreloaded returns true if all images have been preloaded
 	ImagesPreloaded([]string) bool
+	// Purge removes what Kubernetes and minikube left in the runtime of a host, which is not deleted with the cluster
+	Purge(PurgeOptions) error
+	// ApplyPendingRestart restarts the services whose restart was deferred with DeferRestarts, once,
+	// and restarts them right away from then on
+	ApplyPendingRestart() error
+}
+
+// ContextManager is implemented by the runtimes whose long running operations stop when their context is done
+type ContextManager interface {
+	// PreloadContext is Preload, stopping when ctx is done
+	PreloadContext(context.Context, config.ClusterConfig) error
+	// PullImageContext is PullImage, stopping when ctx is done
+	PullImageContext(context.Context, string) error
+	// LoadImageContext is LoadImage, stopping when ctx is done
+	LoadImageContext(context.Context, string) ([]string, error)
+	// BuildImageContext is BuildImage, stopping when ctx is done
+	BuildImageContext(context.Context, string, BuildOptions) error
+	// RestartContext is Restart, stopping when ctx is done
+	RestartContext(context.Context) error
+}
+
+// PhaseManager is implemented by the runtimes whose Enable and Preload kill their commands in the host once their context is done,
+// so that a hung restart or extraction does not outlast the --wait-timeout of a start
+type PhaseManager interface {
+	// EnableContext is Enable, killing its commands once ctx is done
+	EnableContext(context.Context, bool, bool, bool) error
+	// PreloadContext is Preload, killing its commands once ctx is done
+	PreloadContext(context.Context, config.ClusterConfig) error
+	// ApplyPendingRestartContext is ApplyPendingRestart, killing its commands once ctx is done
+	ApplyPendingRestartContext(context.Context) error
 }
 
 // Config is runtime configuration
@@ -153,6 +258,57 @@ type Config struct {
 	KubernetesVersion semver.Version
 	// InsecureRegistry list of insecure registries
 	InsecureRegistry []string
+	// AdoptRunning keeps an already running runtime instead of restarting it, when possible
+	AdoptRunning bool
+	// ForceRestart restarts an adopted runtime even if it disrupts running containers
+	ForceRestart bool
+	// CleanupNetwork removes the CNI and bridge state left behind by other runtimes, when switching runtimes
+	CleanupNetwork bool
+	// CNIConfigs are the CNI configuration files owned by the selected CNI, which are kept on cleanup
+	CNIConfigs []string
+	// DockerOnDemand keeps docker socket activated when another runtime is selected, instead of masking it
+	DockerOnDemand bool
+	// DockerIsolatedBuilder runs the image builds of docker in a dockerd of their own, apart from the one of Kubernetes
+	DockerIsolatedBuilder bool
+	// PreloadSpaceFactor scales the estimated space needed to extract the preload, skipping the check if negative
+	PreloadSpaceFactor float64
+	// ImageOutput is how the output of the commands pulling and loading images reaches the user, buffered by default
+	ImageOutput ImageOutput
+	// ForceSystemd is whether the runtime will be enabled with systemd as cgroup manager, for the settings written before Enable
+	ForceSystemd bool
+	// BareMetal is whether the runtime runs on a host minikube does not set up, as with the none driver
+	BareMetal bool
+	// Driver is the driver of the node, which decides what minikube may change in the host of the runtime
+	Driver string
+	// SELinuxRelabel labels the directories of the static pods for containers, if SELinux enforces on a bare metal host
+	SELinuxRelabel bool
+	// RestartTimeout is how long to wait for a restarted runtime to answer, defaults to DefaultRestartTimeout
+	RestartTimeout time.Duration
+	// DeferRestarts makes Preload, Enable and ConfigureNetworkPlugin leave the restart of docker to ApplyPendingRestart,
+	// so that it restarts once for all of them. The other runtimes restart right away.
+	DeferRestarts bool
+	// DockerFeatures are the daemon features to merge into the daemon.json of docker, formatted as key=value
+	DockerFeatures []string
+	// RegistryMirrors are the mirrors of Docker Hub the runtime pulls through, if not empty
+	RegistryMirrors []string
+	// DockerBridgeCIDR is the network of the docker0 bridge, if not empty
+	DockerBridgeCIDR string
+	// DockerMTU is the MTU of the docker0 bridge, if not 0
+	DockerMTU int
+	// PodCIDR is the pod network of the cluster, with an IPv4 and an IPv6 network separated by a comma when dual-stack
+	PodCIDR string
+	// GPUs are the GPUs exposed to the node, as "all", if not empty
+	GPUs string
+	// Offline makes pulling images fail instead of waiting for unreachable registries, and preloads mandatory
+	Offline bool
+	// CNI are the network plugin settings of the CNI of the cluster, for the runtimes which run the CNI plugins themselves
+	CNI *cni.CNIRuntimeSettings
+	// FallbackToContainerd uses containerd instead of docker when the image lacks the cri-dockerd required by Kubernetes 1.24+
+	FallbackToContainerd bool
+	// DockerGroupUser is the user of the node added to the docker group, to run docker without sudo, none if empty
+	DockerGroupUser string
+	// CRIDockerdSocketGroup gives the docker group access to the socket of cri-dockerd, for its members to run crictl without sudo
+	CRIDockerdSocketGroup bool
 }
 
 // ListContainersOptions are the options to use for listing containers
@@ -163,10 +319,113 @@ type ListContainersOptions struct {
 	Name string
 	// Namespaces is the namespaces to look into
 	Namespaces []string
+	// ExcludeNamespaces are the namespaces not to look into, even if in Namespaces
+	ExcludeNamespaces []string
+	// PodUID is the UID of the pod to look into, as container IDs change when a pod restarts
+	PodUID string
+}
+
+// SelectsNamespace returns whether the containers of namespace ns are looked into
+func (o ListContainersOptions) SelectsNamespace(ns string) bool {
+	if containsString(o.ExcludeNamespaces, ns) {
+		return false
+	}
+	return len(o.Namespaces) == 0 || containsString(o.Namespaces, ns)
+}
+
+// selectNamespaces removes the excluded namespaces from those to look into, returning false if none is left.
+// The excluded namespaces are kept only when looking into all of them: neither the name filters of docker
+// nor the label filters of crictl can exclude values, so the containers of the listing are left out by namespace instead.
+func selectNamespaces(o ListContainersOptions) (ListContainersOptions, bool) {
+	if len(o.ExcludeNamespaces) == 0 || len(o.Namespaces) == 0 {
+		return o, true
+	}
+	included := []string{}
+	for _, ns := range o.Namespaces {
+		if !containsString(o.ExcludeNamespaces, ns) {
+			included = append(included, ns)
+		}
+	}
+	o.Namespaces = included
+	o.ExcludeNamespaces = nil
+	return o, len(included) > 0
+}
+
+// containsString returns whether s is one of ss
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PurgeOptions are the options of Purge
+type PurgeOptions struct {
+	// Images are the images to remove, such as the Kubernetes images, which are kept if empty
+	Images []string
+}
+
+// ContainerInfo describes a container along with its Kubernetes metadata
+type ContainerInfo struct {
+	ID        string    `json:"id" yaml:"id"`
+	Name      string    `json:"name" yaml:"name"`
+	Pod       string    `json:"pod" yaml:"pod"`
+	PodUID    string    `json:"podUID" yaml:"podUID"`
+	Namespace string    `json:"namespace" yaml:"namespace"`
+	State     string    `json:"state" yaml:"state"`
+	Image     string    `json:"image" yaml:"image"`
+	Created   time.Time `json:"created" yaml:"created"`
+}
+
+// gcCandidate is a Kubernetes container or pod sandbox considered for garbage collection
+type gcCandidate struct {
+	ID string
+	// Sandbox is the ID of the pod sandbox of a container
+	Sandbox   string
+	IsSandbox bool
+	Running   bool
+	// Stopped is when a container exited, or when a sandbox was created, as sandboxes do not record when they stopped
+	Stopped time.Time
+	// Diagnostic is whether it was left behind by RunDiagnosticContainer, which is collected as soon as it stopped
+	Diagnostic bool
+}
+
+// collectable returns the containers and pod sandboxes which stopped before the cutoff, and the stopped diagnostic ones.
+// Running containers are never collected, and neither are sandboxes which are ready or still have containers left.
+func collectable(candidates []gcCandidate, cutoff time.Time) (containers []string, sandboxes []string) {
+	expired := func(c gcCandidate) bool {
+		return c.Diagnostic || !c.Stopped.IsZero() && c.Stopped.Before(cutoff)
+	}
+	kept := map[string]bool{}
+	for _, c := range candidates {
+		if c.IsSandbox {
+			continue
+		}
+		if !c.Running && expired(c) {
+			containers = append(containers, c.ID)
+			continue
+		}
+		kept[c.Sandbox] = true
+	}
+	for _, c := range candidates {
+		if !c.IsSandbox || c.Running || kept[c.ID] || !expired(c) {
+			continue
+		}
+		sandboxes = append(sandboxes, c.ID)
+	}
+	return containers, sandboxes
 }
 
 // ListImagesOptions are the options to use for listing images
 type ListImagesOptions struct {
+	// Reference is a glob the references of the images must match, as registry.k8s.io/*
+	Reference string
+	// Dangling lists only the images without tags if true, or only the tagged ones if false
+	Dangling *bool
+	// Platform lists only the images of a platform, as linux/amd64, ignoring the variant if it has none
+	Platform string
 }
 
 type ListImage struct {
@@ -174,11 +433,96 @@ type ListImage struct {
 	RepoDigests []string `json:"repoDigests" yaml:"repoDigests"`
 	RepoTags    []string `json:"repoTags" yaml:"repoTags"`
 	Size        string   `json:"size" yaml:"size"`
+	// SharedSize is the number of bytes shared with other images, if the runtime reports it
+	SharedSize string `json:"sharedSize,omitempty" yaml:"sharedSize,omitempty"`
+	// OS, Architecture and Variant are the platform of the image, empty if unknown
+	OS           string `json:"os,omitempty" yaml:"os,omitempty"`
+	Architecture string `json:"architecture,omitempty" yaml:"architecture,omitempty"`
+	Variant      string `json:"variant,omitempty" yaml:"variant,omitempty"`
+}
+
+// stopSeconds returns timeout in the whole seconds the runtime CLIs take, rounded up, and 0 once it passed
+func stopSeconds(timeout time.Duration) string {
+	if timeout < 0 {
+		timeout = 0
+	}
+	return strconv.Itoa(int(math.Ceil(timeout.Seconds())))
+}
+
+// prepareStop stops the running containers of a runtime with stopContainers in a single call, or a call per container if inTurn is set,
+// then its services in order
+func prepareStop(r Manager, init sysinit.Manager, inTurn bool, stopContainers func([]string) error, services ...string) (int, error) {
+	ids, err := r.ListContainers(ListContainersOptions{State: Running})
+	if err != nil {
+		return 0, errors.Wrap(err, "list running containers")
+	}
+	batches := [][]string{ids}
+	if inTurn {
+		batches = [][]string{}
+		for _, id := range ids {
+			batches = append(batches, []string{id})
+		}
+	}
+	for _, b := range batches {
+		if err := stopContainers(b); err != nil {
+			return 0, errors.Wrap(err, "stop containers")
+		}
+	}
+	for _, svc := range services {
+		if err := init.Stop(svc); err != nil {
+			return len(ids), errors.Wrapf(err, "stop %s", svc)
+		}
+	}
+	return len(ids), nil
+}
+
+// imageStoreRefs returns the sorted tags of the images of a runtime, to export all of them at once
+func imageStoreRefs(r Manager) ([]string, error) {
+	tagged := false
+	images, err := r.ListImages(ListImagesOptions{Dangling: &tagged})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing images")
+	}
+	refs := []string{}
+	seen := map[string]bool{}
+	for _, img := range images {
+		for _, tag := range img.RepoTags {
+			if !seen[tag] {
+				seen[tag] = true
+				refs = append(refs, tag)
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return nil, errors.Errorf("%s has no images to export", r.Name())
+	}
+	sort.Strings(refs)
+	return refs, nil
 }
 
 // ErrContainerRuntimeNotRunning is thrown when container runtime is not running
 var ErrContainerRuntimeNotRunning = errors.New("container runtime is not running")
 
+// ErrDaemonNotRunning is the error returned when a runtime can not be queried because its daemon is not running
+type ErrDaemonNotRunning struct {
+	Runtime string
+	Err     error
+}
+
+func (e *ErrDaemonNotRunning) Error() string {
+	return fmt.Sprintf("%s daemon is not running: %v", e.Runtime, e.Err)
+}
+
+// Unwrap returns the error returned by the query
+func (e *ErrDaemonNotRunning) Unwrap() error {
+	return e.Err
+}
+
+// Is makes ErrDaemonNotRunning match ErrContainerRuntimeNotRunning
+func (e *ErrDaemonNotRunning) Is(target error) bool {
+	return target == ErrContainerRuntimeNotRunning
+}
+
 // ErrServiceVersion is the error returned when disk image has incompatible version of service
 type ErrServiceVersion struct {
 	// Service is the name of the incompatible service
@@ -209,12 +553,27 @@ func New(c Config) (Manager, error) {
 
 	switch c.Type {
 	case "", "docker":
+		if c.FallbackToContainerd && c.Runner != nil && c.KubernetesVersion.GTE(dockershimRemoved) && !CRIDockerdInstalled(c.Runner) {
+			if err := containerdFunctional(c.Runner); err != nil {
+				return nil, errors.Wrap(err, "cri-dockerd is missing, and containerd can not replace it")
+			}
+			klog.Infof("cri-dockerd is missing, falling back to the containerd runtime")
+			c.Type = "containerd"
+			// a custom CRI socket belongs to cri-dockerd
+			c.Socket = ""
+			return New(c)
+		}
 		sp := c.Socket
 		cs := ""
 		// There is no more dockershim socket, in Kubernetes version 1.24 and beyond
-		if sp == "" && c.KubernetesVersion.GTE(semver.MustParse("1.24.0-alpha.0")) {
-			sp = ExternalDockerCRISocket
-			cs = "cri-docker.socket"
+		if sp != "" || c.KubernetesVersion.GTE(dockershimRemoved) {
+			sp = CRISocket("docker", sp, c.KubernetesVersion)
+		}
+		if SocketFile(sp) == ExternalDockerCRISocket {
+			cs = criDockerService + ".socket"
+		} else if customDockerCRISocket(sp) {
+			// cri-dockerd listens on the custom socket itself, rather than on the one of cri-docker.socket
+			cs = criDockerService + ".service"
 		}
 		return &Docker{
 			Socket:            sp,
@@ -224,6 +583,29 @@ func New(c Config) (Manager, error) {
 			Init:              sm,
 			UseCRI:            (sp != ""), // !dockershim
 			CRIService:        cs,
+			AdoptRunning:      c.AdoptRunning,
+			ForceRestart:      c.ForceRestart,
+			CleanupNetwork:    c.CleanupNetwork,
+			CNIConfigs:        c.CNIConfigs,
+			RestartTimeout:    c.RestartTimeout,
+			Features:          c.DockerFeatures,
+			RegistryMirrors:   c.RegistryMirrors,
+			BridgeCIDR:        c.DockerBridgeCIDR,
+			MTU:               c.DockerMTU,
+			PodCIDR:           c.PodCIDR,
+			GPUs:              c.GPUs,
+			Offline:           c.Offline,
+			CNI:               c.CNI,
+			IsolatedBuilder:   c.DockerIsolatedBuilder,
+			PreloadFactor:     c.PreloadSpaceFactor,
+			ImageOutput:       c.ImageOutput,
+			ForceSystemd:      c.ForceSystemd,
+			BareMetal:         c.BareMetal,
+			Driver:            c.Driver,
+			SELinuxRelabel:    c.SELinuxRelabel,
+			GroupUser:         c.DockerGroupUser,
+			CRISocketGroup:    c.CRIDockerdSocketGroup,
+			restarts:          newRestartSession(c.DeferRestarts),
 		}, nil
 	case "crio", "cri-o":
 		return &CRIO{
@@ -232,6 +614,13 @@ func New(c Config) (Manager, error) {
 			ImageRepository:   c.ImageRepository,
 			KubernetesVersion: c.KubernetesVersion,
 			Init:              sm,
+			CleanupNetwork:    c.CleanupNetwork,
+			CNIConfigs:        c.CNIConfigs,
+			DockerOnDemand:    c.DockerOnDemand,
+			Offline:           c.Offline,
+			PreloadFactor:     c.PreloadSpaceFactor,
+			ImageOutput:       c.ImageOutput,
+			Driver:            c.Driver,
 		}, nil
 	case "containerd":
 		return &Containerd{
@@ -241,20 +630,62 @@ func New(c Config) (Manager, error) {
 			KubernetesVersion: c.KubernetesVersion,
 			Init:              sm,
 			InsecureRegistry:  c.InsecureRegistry,
+			CleanupNetwork:    c.CleanupNetwork,
+			CNIConfigs:        c.CNIConfigs,
+			DockerOnDemand:    c.DockerOnDemand,
+			RegistryMirrors:   c.RegistryMirrors,
+			Offline:           c.Offline,
+			PreloadFactor:     c.PreloadSpaceFactor,
+			ImageOutput:       c.ImageOutput,
+			Driver:            c.Driver,
 		}, nil
 	default:
 		return nil, fmt.Errorf("unknown runtime type: %q", c.Type)
 	}
 }
 
+// StreamLogsOptions are the options to use for streaming the log of a container
+type StreamLogsOptions struct {
+	// Lines is how many of the last lines to write first, or all of them if 0
+	Lines int
+	// Follow keeps writing the new lines, until the container exits
+	Follow bool
+}
+
+// streamContainerLogs runs the command printing the log of a container, writing its output to w as it comes
+func streamContainerLogs(ctx context.Context, cr CommandRunner, cmd string, w io.Writer) error {
+	c := exec.Command("/bin/bash", "-c", cmd)
+	c.Stdout = w
+	c.Stderr = w
+	if _, err := cr.RunCmdContext(ctx, c); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return errors.Wrap(err, "container logs")
+	}
+	return nil
+}
+
 // ContainerStatusCommand works across container runtimes with good formatting
 func ContainerStatusCommand() string {
 	// Fallback to 'docker ps' if it fails (none driver)
 	return "sudo `which crictl || echo crictl` ps -a || sudo docker ps -a"
 }
 
+// disableOptions are the options for disabling the runtimes other than the selected one
+type disableOptions struct {
+	// CleanupNetwork removes the network state left behind by the other runtimes
+	CleanupNetwork bool
+	// CNIConfigs are the CNI configuration files kept on cleanup
+	CNIConfigs []string
+	// DockerOnDemand stops docker but keeps it socket activated, instead of masking it
+	DockerOnDemand bool
+	// ForceSystemd is whether the node uses systemd as cgroup manager
+	ForceSystemd bool
+}
+
 // disableOthers disables all other runtimes except for me.
-func disableOthers(me Manager, cr CommandRunner) error {
+func disableOthers(me Manager, cr CommandRunner, o disableOptions) error {
 	// valid values returned by manager.Name()
 	runtimes := []string{"containerd", "crio", "docker"}
 	for _, name := range runtimes {
@@ -280,7 +711,11 @@ func disableOthers(me Manager, cr CommandRunner) error {
 			continue
 		}
 
-		if err = r.Disable(); err != nil {
+		if d, ok := r.(*Docker); ok && o.DockerOnDemand {
+			if err = d.disableOnDemand(o.ForceSystemd); err != nil {
+				klog.Warningf("disable on demand failed: %v", err)
+			}
+		} else if err = r.Disable(); err != nil {
 			klog.Warningf("disable failed: %v", err)
 		}
 
@@ -289,9 +724,131 @@ func disableOthers(me Manager, cr CommandRunner) error {
 			return fmt.Errorf("%s is still active", r.Name())
 		}
 	}
+	if o.CleanupNetwork {
+		return cleanupNetwork(cr, o.CNIConfigs)
+	}
 	return nil
 }
 
+// Switched returns whether a cluster previously running the given runtime is switching to another one
+func Switched(previous, current string) bool {
+	normalize := func(name string) string {
+		if name == "cri-o" {
+			return "crio"
+		}
+		return name
+	}
+	// new clusters have no previous runtime to clean up after
+	if previous == "" || current == "" {
+		return false
+	}
+	return normalize(previous) != normalize(current)
+}
+
+// runtimeBridges are the bridges created by the default CNI configurations of the container runtimes
+var runtimeBridges = []string{"cni0", "cni-podman0"}
+
+// staleCNIConfigs returns the CNI configuration files which are not owned by the selected CNI
+func staleCNIConfigs(files []string, keep []string) []string {
+	stale := []string{}
+	for _, f := range files {
+		name := filepath.Base(f)
+		// loopback is required by every runtime, and disabled configs are not loaded
+		if strings.Contains(name, "loopback") || strings.HasPrefix(name, "DISABLED-") {
+			continue
+		}
+		owned := false
+		for _, k := range keep {
+			if name == k {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			stale = append(stale, f)
+		}
+	}
+	return stale
+}
+
+// cleanupNetwork removes the CNI configs, bridges, cache and iptables rules left behind by a previous runtime
+func cleanupNetwork(cr CommandRunner, cniConfigs []string) error {
+	rr, err := cr.RunCmd(command.Sudo("find", CNIConfDir, "-maxdepth", "1", "-type", "f"))
+	if err != nil {
+		return errors.Wrap(err, "list cni configs")
+	}
+	for _, f := range staleCNIConfigs(strings.Fields(rr.Stdout.String()), cniConfigs) {
+		klog.Infof("removing stale CNI config %s", f)
+		if _, err := cr.RunCmd(command.Sudo("rm", "-f", f)); err != nil {
+			return errors.Wrapf(err, "remove %s", f)
+		}
+	}
+
+	for _, br := range runtimeBridges {
+		if _, err := cr.RunCmd(exec.Command("ip", "link", "show", br)); err != nil {
+			continue
+		}
+		klog.Infof("removing stale bridge %s", br)
+		if _, err := cr.RunCmd(command.Sudo("ip", "link", "delete", br)); err != nil {
+			klog.Warningf("unable to remove bridge %s: %v", br, err)
+		}
+	}
+
+	klog.Infof("removing %s", CNICacheDir)
+	if _, err := cr.RunCmd(command.Sudo("rm", "-rf", CNICacheDir)); err != nil {
+		return errors.Wrap(err, "remove cni cache")
+	}
+
+	// the portmap and bridge plugins add their rules to chains named CNI-*, the other rules of the host are kept
+	for _, table := range cniTables {
+		rr, err := cr.RunCmd(command.Sudo("iptables", "-t", table, "-S"))
+		if err != nil {
+			klog.Warningf("unable to list the iptables rules of the %s table: %v", table, err)
+			continue
+		}
+		for _, args := range cniChainCommands(table, rr.Stdout.String()) {
+			if _, err := cr.RunCmd(command.Sudo(append([]string{"iptables"}, args...)...)); err != nil {
+				klog.Warningf("unable to remove CNI iptables rules: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// cniTables are the iptables tables which the CNI plugins add their chains to
+var cniTables = []string{"nat", "filter"}
+
+// cniChainCommands returns the iptables arguments removing the chains named CNI-* of table, listed as rules by 'iptables -S':
+// the rules of the other chains jumping to them are deleted, then the chains are flushed and deleted by name.
+// The other rules are left alone, even if they mention CNI-.
+func cniChainCommands(table string, rules string) [][]string {
+	var jumps, flushes, deletes [][]string
+	for _, line := range strings.Split(rules, "\n") {
+		fields, err := shellquote.Split(line)
+		if err != nil || len(fields) < 2 {
+			continue
+		}
+		switch {
+		case fields[0] == "-N" && strings.HasPrefix(fields[1], "CNI-"):
+			flushes = append(flushes, []string{"-t", table, "-F", fields[1]})
+			deletes = append(deletes, []string{"-t", table, "-X", fields[1]})
+		case fields[0] == "-A" && !strings.HasPrefix(fields[1], "CNI-") && jumpsToCNIChain(fields):
+			jumps = append(jumps, append([]string{"-t", table, "-D"}, fields[1:]...))
+		}
+	}
+	return append(append(jumps, flushes...), deletes...)
+}
+
+// jumpsToCNIChain returns whether the target of the rule of fields is a chain named CNI-*
+func jumpsToCNIChain(fields []string) bool {
+	for i := 0; i+1 < len(fields); i++ {
+		if (fields[i] == "-j" || fields[i] == "-g") && strings.HasPrefix(fields[i+1], "CNI-") {
+			return true
+		}
+	}
+	return false
+}
+
 var requiredContainerdVersion = semver.MustParse("1.4.0")
 
 // compatibleWithVersion checks if current version of "runtime" is compatible with version "v"
@@ -351,3 +908,122 @@ func ConfigureNetworkPlugin(r Manager, cr CommandRunner, networkPlugin string) e
 	}
 	return dockerConfigureNetworkPlugin(*dm, cr, networkPlugin)
 }
+
+// trackImage runs an image operation, reporting its start and finish when JSON output is enabled,
+// and logging how long it took to the audit log
+func trackImage(artifact, action string, size func() string, fn func() error) error {
+	source := imageSource(action)
+	record := source != "" && audit.RecordingImages()
+	if !out.JSON && !record {
+		return fn()
+	}
+	start := time.Now()
+	if out.JSON {
+		register.PrintImageProgress(artifact, action, register.ImageStarted, 0)
+	}
+	err := fn()
+	elapsed := time.Since(start)
+	data := map[string]string{}
+	if err != nil {
+		data["error"] = err.Error()
+	} else if s := size(); s != "" {
+		data["size"] = s
+	}
+	if record && err == nil {
+		b, _ := strconv.ParseInt(data["size"], 10, 64)
+		audit.RecordImage(audit.ImageOp{Action: action, Image: artifact, Source: source, Bytes: b, Duration: elapsed})
+	}
+	if out.JSON {
+		register.PrintImageProgress(artifact, action, register.ImageFinished, elapsed, data)
+	}
+	return err
+}
+
+// imageSource returns where the images of an image operation come from, or "" if it does not bring images into the node
+func imageSource(action string) string {
+	switch action {
+	case register.ImagePull:
+		return audit.ImageFromNetwork
+	case register.ImageLoad, register.ImagePreloadCopy:
+		return audit.ImageFromCache
+	}
+	return ""
+}
+
+// recordPreloadCheck logs to the audit log whether the images of Kubernetes were found preloaded, sparing their pulls
+func recordPreloadCheck(start time.Time, preloaded bool) {
+	source := audit.ImageFromNetwork
+	if preloaded {
+		source = audit.ImageFromCache
+	}
+	audit.RecordImage(audit.ImageOp{Action: audit.ImagePreloadCheck, Source: source, Duration: time.Since(start)})
+}
+
+// timePhase starts timing a phase of the runtime with pkg/trace, and returns the function ending it,
+// which logs the time spent and reports it as a JSON event
+func timePhase(phase string) func() {
+	stop := trace.Time(phase)
+	return func() {
+		d := stop()
+		klog.Infof("%s took %s", phase, d)
+		if out.JSON {
+			register.PrintTiming(phase, d)
+			return
+		}
+		register.RecordTiming(phase, d)
+	}
+}
+
+// fileSize returns the size in bytes of a file on the host, or "" if unknown
+func fileSize(cr CommandRunner, path string) string {
+	rr, err := cr.RunCmd(command.Sudo("stat", "-c", "%s", path))
+	if err != nil {
+		klog.Warningf("unable to get size of %s: %v", path, err)
+		return ""
+	}
+	return strings.TrimSpace(rr.Stdout.String())
+}
+
+// progressFile reports how much of a file has been read, to track the progress of copies
+type progressFile struct {
+	assets.CopyableFile
+	artifact string
+	action   string
+	read     int64
+	start    time.Time
+	last     time.Time
+	// summarize prints the progress on a line every imageSummaryInterval, instead of as JSON events
+	summarize bool
+}
+
+// newProgressFile wraps a file to report copy progress when JSON output is enabled, or the image output of the runtime is shown
+func newProgressFile(f assets.CopyableFile, artifact, action string, mode ImageOutput) assets.CopyableFile {
+	summarize := mode == ImageOutputTerminal || mode == ImageOutputSummary
+	if !out.JSON && !summarize {
+		return f
+	}
+	return &progressFile{CopyableFile: f, artifact: artifact, action: action, start: time.Now(), last: time.Now(), summarize: !out.JSON}
+}
+
+func (p *progressFile) Read(b []byte) (int, error) {
+	n, err := p.CopyableFile.Read(b)
+	p.read += int64(n)
+	total := p.GetLength()
+	if total <= 0 {
+		return n, err
+	}
+	progress := float64(p.read) / float64(total)
+	if p.summarize {
+		if t := time.Now(); t.Sub(p.last) > imageSummaryInterval || progress == 1 {
+			out.Styled(style.Copying, "{{.artifact}}: {{.percent}}% ({{.elapsed}})", out.V{"artifact": p.artifact, "percent": int(progress * 100), "elapsed": time.Since(p.start).Round(time.Second)})
+			p.last = t
+		}
+		return n, err
+	}
+	// print progress every second so user isn't overwhelmed with events
+	if t := time.Now(); t.Sub(p.last) > time.Second || progress == 1 {
+		register.PrintImageProgress(p.artifact, p.action, register.ImageInProgress, time.Since(p.start), map[string]string{"progress": fmt.Sprintf("%v", progress)})
+		p.last = t
+	}
+	return n, err
+}
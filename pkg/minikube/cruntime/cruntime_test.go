@@ -18,18 +18,37 @@ package cruntime
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/cni"
 	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/download"
+	"k8s.io/minikube/pkg/minikube/driver"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/out/register"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/trace"
 )
 
 func TestName(t *testing.T) {
@@ -85,6 +104,218 @@ func TestDefaultDockerSocketPath(t *testing.T) {
 	}
 }
 
+func TestCGroupDriverDaemonNotRunning(t *testing.T) {
+	for _, running := range []bool{false, true} {
+		t.Run(fmt.Sprintf("running=%t", running), func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			delete(runner.dockerInfo, "{{.CgroupDriver}}")
+			if running {
+				runner.services["docker"] = SvcRunning
+			}
+			r, err := New(Config{Type: "docker", Runner: runner})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			_, err = r.CGroupDriver()
+			if err == nil {
+				t.Fatalf("CGroupDriver() succeeded, want an error")
+			}
+			var notRunning *ErrDaemonNotRunning
+			if got := errors.As(err, &notRunning); got == running {
+				t.Errorf("CGroupDriver() error %q is ErrDaemonNotRunning: %t, want %t", err, got, !running)
+			}
+			if got := errors.Is(err, ErrContainerRuntimeNotRunning); got == running {
+				t.Errorf("CGroupDriver() error %q is ErrContainerRuntimeNotRunning: %t, want %t", err, got, !running)
+			}
+		})
+	}
+}
+
+func TestCRIOCGroupDriverDaemonNotRunning(t *testing.T) {
+	for _, running := range []bool{false, true} {
+		t.Run(fmt.Sprintf("running=%t", running), func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.failOn = "crio config"
+			if running {
+				runner.services["crio"] = SvcRunning
+			}
+			r, err := New(Config{Type: "crio", Runner: runner})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			_, err = r.CGroupDriver()
+			if err == nil {
+				t.Fatalf("CGroupDriver() succeeded, want an error")
+			}
+			var notRunning *ErrDaemonNotRunning
+			if got := errors.As(err, &notRunning); got == running {
+				t.Errorf("CGroupDriver() error %q is ErrDaemonNotRunning: %t, want %t", err, got, !running)
+			}
+		})
+	}
+}
+
+func TestDetectCgroupVersion(t *testing.T) {
+	for _, want := range []int{1, 2} {
+		t.Run(fmt.Sprintf("v%d", want), func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.cgroupV1 = want == 1
+			got, err := DetectCgroupVersion(runner)
+			if err != nil {
+				t.Fatalf("DetectCgroupVersion: %v", err)
+			}
+			if got != want {
+				t.Errorf("DetectCgroupVersion() = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestChooseForceSystemd(t *testing.T) {
+	yes, no := true, false
+	var tests = []struct {
+		name      string
+		requested *bool
+		version   int
+		init      string
+		want      bool
+	}{
+		{"v1", nil, 1, "systemd", false},
+		{"v2", nil, 2, "systemd", true},
+		{"v2 openrc", nil, 2, "OpenRC", false},
+		{"v1 requested", &yes, 1, "systemd", true},
+		{"v1 requested openrc", &yes, 1, "OpenRC", true},
+		{"v2 overridden", &no, 2, "systemd", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, why := ChooseForceSystemd(tc.requested, tc.version, tc.init)
+			if got != tc.want {
+				t.Errorf("ChooseForceSystemd() = %t (%s), want %t", got, why, tc.want)
+			}
+			if why == "" {
+				t.Errorf("ChooseForceSystemd() returned no reason")
+			}
+		})
+	}
+}
+
+func TestDockerOSProfile(t *testing.T) {
+	var tests = []struct {
+		os         string
+		version    string
+		socket     string
+		loadCmd    []string
+		saveCmd    []string
+		daemonJSON string
+	}{
+		{
+			os:         "linux",
+			version:    "1.23.0",
+			socket:     InternalDockerCRISocket,
+			loadCmd:    []string{"/bin/bash", "-c", "sudo cat /tmp/img.tar | docker load"},
+			saveCmd:    []string{"/bin/bash", "-c", "docker save busybox | sudo tee /tmp/img.tar >/dev/null"},
+			daemonJSON: "/etc/docker/daemon.json",
+		},
+		{
+			os:         "linux",
+			version:    "1.24.6",
+			socket:     ExternalDockerCRISocket,
+			loadCmd:    []string{"/bin/bash", "-c", "sudo cat /tmp/img.tar | docker load"},
+			saveCmd:    []string{"/bin/bash", "-c", "docker save busybox | sudo tee /tmp/img.tar >/dev/null"},
+			daemonJSON: "/etc/docker/daemon.json",
+		},
+		{
+			os:         "windows",
+			version:    "1.23.0",
+			socket:     "npipe:////./pipe/dockershim",
+			loadCmd:    []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", "docker load -i '/tmp/img.tar'"},
+			saveCmd:    []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", "docker save -o '/tmp/img.tar' 'busybox'"},
+			daemonJSON: `C:\ProgramData\docker\config/daemon.json`,
+		},
+		{
+			os:         "windows",
+			version:    "1.24.6",
+			socket:     "npipe:////./pipe/cri-dockerd",
+			loadCmd:    []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", "docker load -i '/tmp/img.tar'"},
+			saveCmd:    []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", "docker save -o '/tmp/img.tar' 'busybox'"},
+			daemonJSON: `C:\ProgramData\docker\config/daemon.json`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.os+"-"+tc.version, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.os = tc.os
+			cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse(tc.version)})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if got := cr.SocketPath(); got != tc.socket {
+				t.Errorf("SocketPath() = %q, want %q", got, tc.socket)
+			}
+			r := cr.(*Docker)
+			if r.OS != tc.os {
+				t.Errorf("detected OS %q, want %q", r.OS, tc.os)
+			}
+
+			runner.cmds = []string{}
+			if _, err := r.LoadImage("/tmp/img.tar"); err != nil {
+				t.Fatalf("LoadImage: %v", err)
+			}
+			if diff := cmp.Diff(tc.loadCmd, runner.cmds); diff != "" {
+				t.Errorf("LoadImage commands diff (-want +got):\n%s", diff)
+			}
+
+			runner.cmds = []string{}
+			if err := r.SaveImage("busybox", "/tmp/img.tar"); err != nil {
+				t.Fatalf("SaveImage: %v", err)
+			}
+			if diff := cmp.Diff(tc.saveCmd, runner.cmds); diff != "" {
+				t.Errorf("SaveImage commands diff (-want +got):\n%s", diff)
+			}
+
+			if _, err := r.writeDaemonConfig(true); err != nil {
+				t.Fatalf("writeDaemonConfig: %v", err)
+			}
+			if diff := cmp.Diff([]string{tc.daemonJSON}, runner.copied); diff != "" {
+				t.Errorf("writeDaemonConfig copied diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDockerOSFromDriver(t *testing.T) {
+	var tests = []struct {
+		driver string
+		os     string
+		want   string
+		probed bool
+	}{
+		{driver: driver.KVM2, os: "windows", want: "linux"},
+		{driver: driver.Docker, os: "windows", want: "linux"},
+		{driver: driver.None, os: "windows", want: "linux"},
+		{driver: driver.SSH, os: "windows", want: "windows", probed: true},
+		{os: "windows", want: "windows", probed: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.driver+"-"+tc.os, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.os = tc.os
+			cr, err := New(Config{Type: "docker", Runner: runner, Driver: tc.driver})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			runner.cmds = []string{}
+			if got := cr.(*Docker).osProfile().OS; got != tc.want {
+				t.Errorf("osProfile().OS = %q, want %q", got, tc.want)
+			}
+			if probed := len(runner.cmds) > 0; probed != tc.probed {
+				t.Errorf("probed the OS = %v (%q), want %v", probed, runner.cmds, tc.probed)
+			}
+		})
+	}
+}
+
 func TestImageExists(t *testing.T) {
 	var tests = []struct {
 		runtime string
@@ -117,6 +348,41 @@ func TestImageExists(t *testing.T) {
 	}
 }
 
+func TestImageID(t *testing.T) {
+	var tests = []struct {
+		runtime string
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"docker", "missing-image", "", true},
+		{"docker", "available-image", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", false},
+		{"containerd", "missing-image", "", true},
+		{"containerd", "available-image", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", false},
+		{"crio", "available-image", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", false},
+	}
+	for _, tc := range tests {
+		runner := NewFakeRunner(t)
+		runner.images = map[string]string{
+			"available-image": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		}
+		t.Run(tc.runtime+"/"+tc.name, func(t *testing.T) {
+			r, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+
+			got, err := r.ImageID(tc.name)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ImageID(%s) error = %v, wantErr %v", tc.name, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ImageID(%s) = %q, want %q", tc.name, got, tc.want)
+			}
+		})
+	}
+}
+
 func TestCGroupDriver(t *testing.T) {
 	var tests = []struct {
 		runtime string
@@ -190,25 +456,94 @@ const (
 type FakeRunner struct {
 	cmds       []string
 	services   map[string]serviceState
+	masked     map[string]bool
 	containers map[string]string
 	images     map[string]string
-	t          *testing.T
+	dockerInfo map[string]string
+	os         string
+	copied     []string
+	cgroupV1   bool
+	// unready is how many more times the runtime fails to answer before it is ready
+	unready int
+	// failOn makes the commands containing it fail
+	failOn string
+	// history is the commands run, one per entry
+	history []string
+	// needReload are the services whose unit files changed since the last daemon-reload
+	needReload map[string]bool
+	// blockOn makes the commands containing it run until they are cancelled, which they signal on blocked
+	blockOn string
+	blocked chan struct{}
+	// delay is how long each command takes
+	delay time.Duration
+	// files are the contents of the copied files, by target path
+	files map[string]string
+	// bridge is the address of docker0, which does not exist if empty
+	bridge string
+	// curlExitCode is the exit code of curl, which succeeds if 0
+	curlExitCode int
+	// selinux is the mode printed by getenforce, which is not installed if empty
+	selinux string
+	// skew is how far the clock of the node is ahead of the host, failing the pulls if skewed until hwclock resyncs it
+	skew time.Duration
+	// dockerdLegacy makes dockerd fail on --validate, as before dockerd 23.0
+	dockerdLegacy bool
+	// dockerdInvalid is the error dockerd validating its configuration prints, which is valid if empty
+	dockerdInvalid string
+	// dockerdVersion is the version of dockerd, 18.06.2-ce if empty
+	dockerdVersion string
+	// buildx is whether the buildx plugin of docker is installed
+	buildx bool
+	// crictlLegacy makes crictl older than 1.25, without the checkpoint command
+	crictlLegacy bool
+	// containerdVersion is the version of containerd, 1.2.0 if empty
+	containerdVersion string
+	// crioVersion is the version of CRI-O, 1.13.0 if empty
+	crioVersion string
+	// diagnosticExitCode is the exit code of the diagnostic containers
+	diagnosticExitCode int
+	// paused are the IDs of the paused containers
+	paused map[string]bool
+	// busy are the IDs of the containers which the runtime fails to stop, remove, pause or unpause
+	busy map[string]bool
+	// df is the output of df, by its first argument
+	df map[string]string
+	// platforms are the os/arch/variant of the images by ID, which have no architecture if missing
+	platforms map[string]string
+	// inspected are the outputs of 'docker container inspect' and 'crictl inspectp', by container or pod sandbox ID
+	inspected map[string]string
+	// annotations are the annotations of the CRI containers as JSON, by container ID
+	annotations map[string]string
+	// groups are the members of the groups of the host, which exist if present
+	groups map[string][]string
+	// sockets are the group and the octal mode of the sockets, by path, which do not exist if missing
+	sockets map[string]string
+	t       *testing.T
 }
 
 // NewFakeRunner returns a CommandRunner which emulates a systemd host
 func NewFakeRunner(t *testing.T) *FakeRunner {
 	return &FakeRunner{
 		services:   map[string]serviceState{},
+		masked:     map[string]bool{},
 		cmds:       []string{},
 		t:          t,
 		containers: map[string]string{},
 		images:     map[string]string{},
+		dockerInfo: map[string]string{
+			"{{.CgroupDriver}}":       "cgroupfs",
+			"{{.LiveRestoreEnabled}}": "false",
+		},
+		groups:  map[string][]string{"docker": {"docker"}},
+		sockets: map[string]string{"/var/run/docker.sock": "docker 660", ExternalDockerCRISocket: "root 660"},
 	}
 }
 
 func buffer(s string, err error) (*command.RunResult, error) {
 	rr := &command.RunResult{}
 	if err != nil {
+		// failing commands print their error
+		rr.Stderr.WriteString(s)
 		return rr, err
 	}
 	var buf bytes.Buffer
@@ -225,6 +560,14 @@ func buffer(s string, err error) (*command.RunResult, error) {
 func (f *FakeRunner) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
 	xargs := cmd.Args
 	f.cmds = append(f.cmds, xargs...)
+	f.history = append(f.history, strings.Join(xargs, " "))
+	time.Sleep(f.delay)
+	if len(xargs) == 3 && xargs[0] == "/bin/bash" && xargs[1] == "-c" && (strings.HasPrefix(xargs[2], "set -e\n") || strings.HasPrefix(xargs[2], "rc=0\n")) {
+		return f.script(xargs)
+	}
+	if f.failOn != "" && strings.Contains(strings.Join(xargs, " "), f.failOn) {
+		return &command.RunResult{Args: xargs, ExitCode: 1}, fmt.Errorf("injected failure: %s", f.failOn)
+	}
 	root := false
 	bin, args := xargs[0], xargs[1:]
 	f.t.Logf("bin=%s args=%v", bin, args)
@@ -232,6 +575,12 @@ func (f *FakeRunner) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
 		root = true
 		bin, args = xargs[1], xargs[2:]
 	}
+	if clockSkewed(f.skew) && len(args) > 0 && (bin == "docker" || strings.HasSuffix(bin, "crictl")) && args[0] == "pull" {
+		return &command.RunResult{Args: xargs, ExitCode: 1}, fmt.Errorf("Error response from daemon: Get \"https://registry-1.docker.io/v2/\": x509: certificate has expired or is not yet valid")
+	}
+	if bin == "docker" && len(args) > 0 && args[0] == "run" && f.diagnosticExitCode != 0 {
+		return &command.RunResult{Args: xargs, ExitCode: f.diagnosticExitCode}, fmt.Errorf("docker run: exit status %d", f.diagnosticExitCode)
+	}
 	switch bin {
 	case "systemctl":
 		return buffer(f.systemctl(args, root))
@@ -247,12 +596,203 @@ func (f *FakeRunner) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
 		return buffer(f.crio(args, root))
 	case "containerd":
 		return buffer(f.containerd(args, root))
+	case "ctr":
+		return buffer(f.ctr(args, root))
+	case "runc":
+		return buffer(f.runc(args))
+	case "stat":
+		if len(args) == 3 && args[1] == "%G %a" {
+			return buffer(f.stat(args[2]))
+		}
+		if f.cgroupV1 && args[len(args)-1] == cgroupControllers {
+			return &command.RunResult{ExitCode: 1}, fmt.Errorf("stat: cannot stat '%s': No such file or directory", cgroupControllers)
+		}
+		return buffer("", nil)
+	case "uname":
+		if f.os == "windows" {
+			return buffer("", fmt.Errorf("uname: command not found"))
+		}
+		if len(args) > 0 && args[0] == "-m" {
+			return buffer("aarch64\n", nil)
+		}
+		return buffer("Linux", nil)
+	case "powershell":
+		return buffer(f.powershell(args, root))
+	case "journalctl":
+		return buffer("dockerd[1234]: failed to start daemon: error initializing graphdriver", nil)
+	case "cat":
+		return buffer(f.files[args[0]], nil)
+	case "mount":
+		if len(args) == 3 && args[0] == "--bind" {
+			f.files["/proc/mounts"] += fmt.Sprintf("/dev/sda1 %s ext4 rw 0 0\n", args[2])
+		}
+		return buffer("", nil)
+	case "df":
+		return buffer(f.df[args[0]], nil)
+	case "date":
+		return buffer(strconv.FormatInt(time.Now().Add(f.skew).Unix(), 10), nil)
+	case "hwclock":
+		f.skew = 0
+		return buffer("", nil)
+	case "systemd-run":
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "--unit=") {
+				f.services[strings.TrimPrefix(arg, "--unit=")] = SvcRunning
+			}
+		}
+		return buffer("", nil)
+	case "getenforce":
+		if f.selinux == "" {
+			return buffer("", fmt.Errorf("getenforce: command not found"))
+		}
+		return buffer(f.selinux, nil)
+	case "rm":
+		delete(f.files, args[len(args)-1])
+		return buffer("", nil)
+	case "test":
+		if len(args) == 2 && args[0] == "-f" {
+			if _, ok := f.files[args[1]]; !ok {
+				return &command.RunResult{Args: xargs, ExitCode: 1}, fmt.Errorf("test: exit status 1")
+			}
+		}
+		return buffer("", nil)
+	case "find":
+		return buffer(f.find(args))
+	case "getent", "id", "groupadd", "usermod":
+		return buffer(f.group(bin, args))
+	case "chgrp", "chmod":
+		return buffer(f.chown(bin, args))
+	case "ip":
+		return buffer(f.ip(args))
+	case "dockerd":
+		return f.dockerd(xargs)
+	case "curl":
+		if f.curlExitCode != 0 {
+			return &command.RunResult{Args: xargs, ExitCode: f.curlExitCode}, fmt.Errorf("curl: exit status %d", f.curlExitCode)
+		}
+		return buffer("", nil)
 	default:
 		rr := &command.RunResult{}
 		return rr, nil
 	}
 }
 
+// script runs the commands of a script written by bashScript one at a time, so that each of them may fail
+func (f *FakeRunner) script(xargs []string) (*command.RunResult, error) {
+	lines := strings.Split(xargs[2], "\n")
+	stopOnError := lines[0] == "set -e"
+	var failed error
+	for _, l := range lines[1:] {
+		if l == "exit $rc" {
+			continue
+		}
+		args := strings.Fields(strings.TrimSuffix(l, " || rc=1"))
+		if _, err := f.RunCmd(exec.Command(args[0], args[1:]...)); err != nil {
+			failed = err
+			if stopOnError {
+				break
+			}
+		}
+	}
+	if failed != nil {
+		return &command.RunResult{Args: xargs, ExitCode: 1}, failed
+	}
+	return &command.RunResult{Args: xargs}, nil
+}
+
+// group emulates looking up and changing the groups of the host
+func (f *FakeRunner) group(bin string, args []string) (string, error) {
+	switch bin {
+	case "getent":
+		if _, ok := f.groups[args[1]]; !ok {
+			return "", fmt.Errorf("getent: exit status 2")
+		}
+		return fmt.Sprintf("%s:x:999:%s", args[1], strings.Join(f.groups[args[1]], ",")), nil
+	case "id":
+		names := []string{"users"}
+		for g, members := range f.groups {
+			if containsString(members, args[1]) {
+				names = append(names, g)
+			}
+		}
+		return strings.Join(names, " "), nil
+	case "groupadd":
+		f.groups[args[len(args)-1]] = nil
+	case "usermod":
+		g, user := args[1], args[2]
+		if _, ok := f.groups[g]; !ok {
+			return "", fmt.Errorf("usermod: group '%s' does not exist", g)
+		}
+		f.groups[g] = append(f.groups[g], user)
+	}
+	return "", nil
+}
+
+// stat emulates printing the group and the octal mode of a socket
+func (f *FakeRunner) stat(path string) (string, error) {
+	s, ok := f.sockets[path]
+	if !ok {
+		return "", fmt.Errorf("stat: cannot statx '%s': No such file or directory", path)
+	}
+	return s, nil
+}
+
+// chown emulates changing the group or giving the group read and write access of a socket
+func (f *FakeRunner) chown(bin string, args []string) (string, error) {
+	path := args[len(args)-1]
+	fields := strings.Fields(f.sockets[path])
+	if len(fields) != 2 {
+		return "", fmt.Errorf("%s: cannot access '%s': No such file or directory", bin, path)
+	}
+	if bin == "chgrp" {
+		fields[0] = args[0]
+	} else {
+		mode, _ := strconv.ParseUint(fields[1], 8, 32)
+		fields[1] = strconv.FormatUint(mode|0o060, 8)
+	}
+	f.sockets[path] = strings.Join(fields, " ")
+	return "", nil
+}
+
+// dockerd emulates validating the configuration of dockerd
+func (f *FakeRunner) dockerd(xargs []string) (*command.RunResult, error) {
+	rr := &command.RunResult{Args: xargs}
+	if len(xargs) == 2 && xargs[1] == "--version" {
+		v := f.dockerdVersion
+		if v == "" {
+			v = "18.06.2-ce"
+		}
+		rr.Stdout.WriteString(fmt.Sprintf("Docker version %s, build 6d37f41\n", v))
+		return rr, nil
+	}
+	switch {
+	case f.dockerdLegacy:
+		rr.ExitCode = 125
+		rr.Stderr.WriteString("unknown flag: --validate")
+	case f.dockerdInvalid != "":
+		rr.ExitCode = 1
+		rr.Stderr.WriteString(f.dockerdInvalid)
+	default:
+		rr.Stdout.WriteString("configuration OK")
+		return rr, nil
+	}
+	return rr, fmt.Errorf("dockerd: exit status %d", rr.ExitCode)
+}
+
+func (f *FakeRunner) RunCmdContext(ctx context.Context, cmd *exec.Cmd) (*command.RunResult, error) {
+	line := strings.Join(cmd.Args, " ")
+	if f.blockOn == "" || !strings.Contains(line, f.blockOn) {
+		return f.RunCmd(cmd)
+	}
+	f.history = append(f.history, line)
+	if f.blocked != nil {
+		close(f.blocked)
+		f.blocked = nil
+	}
+	<-ctx.Done()
+	return &command.RunResult{Args: cmd.Args}, errors.Wrap(ctx.Err(), line)
+}
+
 func (f *FakeRunner) StartCmd(cmd *exec.Cmd) (*command.StartedCmd, error) {
 	return &command.StartedCmd{}, nil
 }
@@ -261,7 +801,20 @@ func (f *FakeRunner) WaitCmd(sc *command.StartedCmd) (*command.RunResult, error)
 	return &command.RunResult{}, nil
 }
 
-func (f *FakeRunner) Copy(assets.CopyableFile) error {
+func (f *FakeRunner) Copy(file assets.CopyableFile) error {
+	target := file.GetTargetDir() + "/" + file.GetTargetName()
+	if readOnlyMount(f.files["/proc/mounts"], target) {
+		return fmt.Errorf("%s: read-only file system", target)
+	}
+	f.copied = append(f.copied, target)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	if f.files == nil {
+		f.files = map[string]string{}
+	}
+	f.files[target] = string(data)
 	return nil
 }
 
@@ -277,46 +830,151 @@ func (f *FakeRunner) ReadableFile(sourcePath string) (assets.ReadableFile, error
 	return nil, nil
 }
 
+// find is a fake implementation of finding the files of a directory named as the pattern of -name
+func (f *FakeRunner) find(args []string) (string, error) {
+	pattern := "*"
+	for i, arg := range args {
+		if arg == "-name" && i+1 < len(args) {
+			pattern = args[i+1]
+		}
+	}
+	paths := []string{}
+	for p := range f.files {
+		if ok, _ := path.Match(pattern, path.Base(p)); ok && path.Dir(p) == args[0] {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, "\n"), nil
+}
+
+// ip is a fake implementation of showing and deleting the docker0 bridge
+func (f *FakeRunner) ip(args []string) (string, error) {
+	if f.bridge == "" {
+		return "", fmt.Errorf("Device \"docker0\" does not exist.")
+	}
+	if args[0] == "link" && args[1] == "delete" {
+		f.bridge = ""
+		return "", nil
+	}
+	return fmt.Sprintf("3: docker0    inet %s scope global docker0\\       valid_lft forever preferred_lft forever", f.bridge), nil
+}
+
 func (f *FakeRunner) dockerPs(args []string) (string, error) {
 	// ps -a --filter="name=apiserver" --format="{{.ID}}"
+	status := ""
+	if args[1] == "--filter" && strings.HasPrefix(args[2], "status=") {
+		status = strings.TrimPrefix(args[2], "status=")
+		args = append([]string{args[0], "-a"}, args[3:]...)
+	}
 	if args[1] == "-a" && strings.HasPrefix(args[2], "--filter") {
 		filter := strings.Split(args[2], `r=`)[1]
 		fname := strings.Split(filter, "=")[1]
 		ids := []string{}
 		f.t.Logf("fake docker: Looking for containers matching %q", fname)
 		for id, cname := range f.containers {
-			if strings.Contains(cname, fname) {
+			if strings.Contains(cname, fname) && f.inState(id, status) {
 				ids = append(ids, id)
 			}
 		}
 		f.t.Logf("fake docker: Found containers: %v", ids)
+		if args[len(args)-1] == "{{json .}}" {
+			lines := []string{}
+			for _, id := range ids {
+				lines = append(lines, fmt.Sprintf(`{"ID":%q,"Image":"busybox","State":"running","CreatedAt":"2022-10-17 10:00:00 +0000 UTC","Labels":"io.kubernetes.container.name=%s,io.kubernetes.pod.name=%s-pod,io.kubernetes.pod.namespace=default"}`, id, f.containers[id], f.containers[id]))
+			}
+			return strings.Join(lines, "\n"), nil
+		}
 		return strings.Join(ids, "\n"), nil
 	}
 	return "", nil
 }
 
-func (f *FakeRunner) dockerStop(args []string) (string, error) {
-	ids := strings.Split(args[1], " ")
+// inState returns whether the container is in the status, which is any status if empty.
+// The fake containers which are not paused are running.
+func (f *FakeRunner) inState(id string, status string) bool {
+	switch status {
+	case "paused":
+		return f.paused[id]
+	case "running":
+		return !f.paused[id]
+	}
+	return true
+}
+
+// eachContainer runs op on each of the containers, carrying on past the containers it fails on as docker and crictl do,
+// and printing an error for each of those
+func (f *FakeRunner) eachContainer(ids []string, op func(id string)) (string, error) {
+	errs := []string{}
 	for _, id := range ids {
-		f.t.Logf("fake docker: Stopping id %q", id)
-		if f.containers[id] == "" {
-			return "", fmt.Errorf("no such container")
+		switch {
+		case f.containers[id] == "":
+			errs = append(errs, fmt.Sprintf("Error response from daemon: No such container: %s", id))
+		case f.busy[id]:
+			errs = append(errs, fmt.Sprintf("Error response from daemon: container %s: device or resource busy", id))
+		default:
+			op(id)
 		}
-		delete(f.containers, id)
+	}
+	if len(errs) > 0 {
+		return strings.Join(errs, "\n"), fmt.Errorf("exit status 1")
 	}
 	return "", nil
 }
 
+// setPaused pauses or resumes the containers
+func (f *FakeRunner) setPaused(ids []string, paused bool) (string, error) {
+	if f.paused == nil {
+		f.paused = map[string]bool{}
+	}
+	return f.eachContainer(ids, func(id string) {
+		if paused {
+			f.paused[id] = true
+		} else {
+			delete(f.paused, id)
+		}
+	})
+}
+
+// runc is a fake implementation of runc, which knows about the paused containers
+func (f *FakeRunner) runc(args []string) (string, error) {
+	if args[0] == "--root" {
+		args = args[2:]
+	}
+	switch args[0] {
+	case "list":
+		cs := []string{}
+		for id := range f.containers {
+			status := "running"
+			if f.paused[id] {
+				status = "paused"
+			}
+			cs = append(cs, fmt.Sprintf(`{"id":%q,"status":%q}`, id, status))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(cs, ",")), nil
+	case "pause", "resume":
+		return f.setPaused(args[1:], args[0] == "pause")
+	}
+	return "", fmt.Errorf("unknown runc command: %v", args)
+}
+
+func (f *FakeRunner) dockerStop(args []string) (string, error) {
+	ids := args[1:]
+	if ids[0] == "-t" {
+		ids = ids[2:]
+	}
+	return f.eachContainer(ids, func(id string) {
+		f.t.Logf("fake docker: Stopping id %q", id)
+		delete(f.containers, id)
+	})
+}
+
 func (f *FakeRunner) dockerRm(args []string) (string, error) {
 	// Skip "-f" argument
-	for _, id := range args[2:] {
+	return f.eachContainer(args[2:], func(id string) {
 		f.t.Logf("fake docker: Removing id %q", id)
-		if f.containers[id] == "" {
-			return "", fmt.Errorf("no such container")
-		}
 		delete(f.containers, id)
-	}
-	return "", nil
+	})
 }
 
 func (f *FakeRunner) dockerInspect(args []string) (string, error) {
@@ -331,9 +989,19 @@ func (f *FakeRunner) dockerInspect(args []string) (string, error) {
 }
 
 func (f *FakeRunner) dockerRmi(args []string) (string, error) {
-	// Skip "-f" argument
+	force := false
 	for _, id := range args[1:] {
+		if id == "-f" || id == "--force" {
+			force = true
+			continue
+		}
 		f.t.Logf("fake docker: Removing id %q", id)
+		if strings.HasPrefix(id, "sha256:") {
+			if err := f.removeImageID(id, force); err != nil {
+				return "", err
+			}
+			continue
+		}
 		if f.images[id] == "" {
 			return "", fmt.Errorf("no such image")
 		}
@@ -342,8 +1010,65 @@ func (f *FakeRunner) dockerRmi(args []string) (string, error) {
 	return "", nil
 }
 
+// imageNames returns the names of the images with the ID, which may have the sha256: prefix
+func (f *FakeRunner) imageNames(id string) []string {
+	names := []string{}
+	for name, i := range f.images {
+		if i == strings.TrimPrefix(id, "sha256:") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// imageTags returns the names of the images with the ID which are not digests
+func (f *FakeRunner) imageTags(id string) []string {
+	tags := []string{}
+	for _, name := range f.imageNames(id) {
+		if !strings.Contains(name, "@") {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// removeImageID removes all the names of the image with the ID, which must be forced if it has several tags
+func (f *FakeRunner) removeImageID(id string, force bool) error {
+	names := f.imageNames(id)
+	if len(names) == 0 {
+		return fmt.Errorf("no such image")
+	}
+	if len(f.imageTags(id)) > 1 && !force {
+		return fmt.Errorf("conflict: unable to delete %s (must be forced) - image is referenced in multiple repositories", id)
+	}
+	for _, name := range names {
+		delete(f.images, name)
+	}
+	return nil
+}
+
+// tagImage tags the image the source names, by name or ID, or an image of its own if it does not exist
+func (f *FakeRunner) tagImage(source string, target string) {
+	if id, ok := f.images[source]; ok {
+		f.images[target] = id
+		return
+	}
+	for name, id := range f.images {
+		if normalizeImageRef(name) == source || id == strings.TrimPrefix(source, "sha256:") {
+			f.images[target] = id
+			return
+		}
+	}
+	f.images[target] = target
+}
+
 // docker is a fake implementation of docker
 func (f *FakeRunner) docker(args []string, _ bool) (string, error) {
+	// the daemons of the node share the fake containers and images
+	if args[0] == "--host" {
+		args = args[2:]
+	}
 	switch cmd := args[0]; cmd {
 	case "ps":
 		return f.dockerPs(args)
@@ -351,12 +1076,19 @@ func (f *FakeRunner) docker(args []string, _ bool) (string, error) {
 	case "stop":
 		return f.dockerStop(args)
 
+	case "pause", "unpause":
+		return f.setPaused(args[1:], cmd == "pause")
+
 	case "rm":
 		return f.dockerRm(args)
 
 	case "version":
 
 		if args[1] == "--format" && args[2] == "{{.Server.Version}}" {
+			if f.unready > 0 {
+				f.unready--
+				return "", fmt.Errorf("Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?")
+			}
 			return "18.06.2-ce", nil
 		}
 
@@ -365,16 +1097,52 @@ func (f *FakeRunner) docker(args []string, _ bool) (string, error) {
 			return f.dockerInspect(args[1:])
 		}
 
+	case "container":
+		if args[1] == "inspect" {
+			// docker container inspect --format {{json .}} CONTAINER
+			if out, ok := f.inspected[args[4]]; ok {
+				return out, nil
+			}
+			return "", fmt.Errorf("Error: No such container: %s", args[4])
+		}
+
 	case "rmi":
 		return f.dockerRmi(args)
 
+	case "pull":
+		if _, ok := f.images[args[1]]; !ok {
+			f.images[args[1]] = "pulled"
+		}
+		return "", nil
+
+	case "images":
+		names := []string{}
+		for name := range f.images {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return strings.Join(names, "\n"), nil
+
+	case "tag":
+		f.tagImage(args[1], args[2])
+		return "", nil
+
+	case "buildx":
+		if args[1] == "version" && !f.buildx {
+			return "", fmt.Errorf("docker: 'buildx' is not a docker command")
+		}
+
 	case "inspect":
 		return f.dockerInspect(args)
 
 	case "info":
 
-		if args[1] == "--format" && args[2] == "{{.CgroupDriver}}" {
-			return "cgroupfs", nil
+		if args[1] == "--format" {
+			v, ok := f.dockerInfo[args[2]]
+			if !ok {
+				return "", fmt.Errorf("Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?")
+			}
+			return v, nil
 		}
 	}
 	return "", nil
@@ -395,6 +1163,24 @@ func (f *FakeRunner) podman(args []string, _ bool) (string, error) {
 			return "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", nil
 		}
 
+	case "tag":
+		f.tagImage(args[1], args[2])
+
+	case "load":
+		return "Loaded image(s): docker.io/library/busybox:latest", nil
+	}
+	return "", nil
+}
+
+// ctr is a fake implementation of ctr
+func (f *FakeRunner) ctr(args []string, _ bool) (string, error) { //nolint (result 1 (error) is always nil)
+	// ctr -n=k8s.io images tag --force SOURCE TARGET
+	if len(args) == 6 && args[1] == "images" && args[2] == "tag" && args[3] == "--force" {
+		f.tagImage(args[4], args[5])
+	}
+	// ctr -n=k8s.io images import PATH
+	if len(args) == 4 && args[1] == "images" && args[2] == "import" {
+		return "unpacking docker.io/library/busybox:latest (sha256:7b3ccabffc97de872a30dfd234fd972a66d247c8cfc69b0550f276481852627c)...done", nil
 	}
 	return "", nil
 }
@@ -402,7 +1188,11 @@ func (f *FakeRunner) podman(args []string, _ bool) (string, error) {
 // crio is a fake implementation of crio
 func (f *FakeRunner) crio(args []string, _ bool) (string, error) { //nolint (result 1 (error) is always nil)
 	if args[0] == "--version" {
-		return "crio version 1.13.0", nil
+		v := f.crioVersion
+		if v == "" {
+			v = "1.13.0"
+		}
+		return "crio version " + v, nil
 	}
 	if args[0] == "config" {
 		return "# Cgroup management implementation used for the runtime.\ncgroup_manager = \"cgroupfs\"\n", nil
@@ -413,7 +1203,11 @@ func (f *FakeRunner) crio(args []string, _ bool) (string, error) { //nolint (res
 // containerd is a fake implementation of containerd
 func (f *FakeRunner) containerd(args []string, _ bool) (string, error) {
 	if args[0] == "--version" {
-		return "containerd github.com/containerd/containerd v1.2.0 c4446665cb9c30056f4998ed953e6d4ff22c7c39", nil
+		v := f.containerdVersion
+		if v == "" {
+			v = "1.2.0"
+		}
+		return fmt.Sprintf("containerd github.com/containerd/containerd v%s c4446665cb9c30056f4998ed953e6d4ff22c7c39", v), nil
 	}
 	if args[0] != "--version" { // doing this to suppress lint "result 1 (error) is always nil"
 		return "", fmt.Errorf("unknown args[0]")
@@ -421,10 +1215,31 @@ func (f *FakeRunner) containerd(args []string, _ bool) (string, error) {
 	return "", nil
 }
 
+// powershell is a fake implementation of powershell, which only exists on windows hosts
+func (f *FakeRunner) powershell(args []string, _ bool) (string, error) {
+	if f.os != "windows" {
+		return "", fmt.Errorf("powershell: command not found")
+	}
+	if args[len(args)-1] == "[System.Environment]::OSVersion.Platform" {
+		return "Win32NT\r\n", nil
+	}
+	return "", nil
+}
+
 // crictl is a fake implementation of crictl
 func (f *FakeRunner) crictl(args []string, _ bool) (string, error) {
 	f.t.Logf("crictl args: %s", args)
 	switch cmd := args[0]; cmd {
+	case "pull":
+		if _, ok := f.images[args[1]]; !ok {
+			f.images[args[1]] = "pulled"
+		}
+		return "", nil
+	case "checkpoint":
+		if f.crictlLegacy {
+			return "No help topic for 'checkpoint'", fmt.Errorf("exit status 3")
+		}
+		return "", nil
 	case "info":
 		return `{
 		  "status": {
@@ -434,18 +1249,57 @@ func (f *FakeRunner) crictl(args []string, _ bool) (string, error) {
 		  },
 		  "golang": "go1.11.13"
 		}`, nil
+	case "images":
+		names := []string{}
+		for _, name := range f.images {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		is := []string{}
+		for _, name := range names {
+			is = append(is, fmt.Sprintf(`{"id":%q,"repoTags":[%q],"repoDigests":[],"size":"0"}`, name, name))
+		}
+		return fmt.Sprintf(`{"images":[%s]}`, strings.Join(is, ",")), nil
 	case "ps":
 		fmt.Printf("args %d: %v\n", len(args), args)
-		if len(args) != 4 {
-			f.t.Logf("crictl all")
-			ids := []string{}
-			for id := range f.containers {
-				ids = append(ids, id)
+		if args[len(args)-2] == "--output" && args[len(args)-1] == "json" {
+			// crictl ps -a --id PREFIX --output json
+			prefix := ""
+			if args[2] == "--id" {
+				prefix = args[3]
+			}
+			cs := []string{}
+			for id, cname := range f.containers {
+				if !strings.HasPrefix(id, prefix) {
+					continue
+				}
+				annotations, ok := f.annotations[id]
+				if !ok {
+					annotations = "{}"
+				}
+				cs = append(cs, fmt.Sprintf(`{"id":%q,"metadata":{"name":%q},"image":{"image":"busybox"},"podSandboxId":"%s-pod","state":"CONTAINER_RUNNING","createdAt":"1666000800000000000","labels":{"io.kubernetes.pod.name":"%s-pod","io.kubernetes.pod.namespace":"default"},"annotations":%s}`, id, cname, id, cname, annotations))
+			}
+			return fmt.Sprintf(`{"containers":[%s]}`, strings.Join(cs, ",")), nil
+		}
+		if len(args) != 4 {
+			f.t.Logf("crictl all")
+			ids := []string{}
+			for id := range f.containers {
+				ids = append(ids, id)
 			}
 			f.t.Logf("fake crictl: Found containers: %v", ids)
 			return strings.Join(ids, "\n"), nil
 		}
 
+		// crictl ps --quiet --state running, which includes the paused containers
+		if args[2] == "--state" {
+			ids := []string{}
+			for id := range f.containers {
+				ids = append(ids, id)
+			}
+			return strings.Join(ids, "\n"), nil
+		}
+
 		// crictl ps -a --name=apiserver --state=Running --quiet
 		if args[1] == "-a" && strings.HasPrefix(args[3], "--name") {
 			fname := strings.Split(args[3], "=")[1]
@@ -461,30 +1315,65 @@ func (f *FakeRunner) crictl(args []string, _ bool) (string, error) {
 			return strings.Join(ids, "\n"), nil
 		}
 	case "stop":
-		for _, id := range args[1:] {
+		ids := args[1:]
+		if ids[0] == "--timeout" {
+			ids = ids[2:]
+		}
+		return f.eachContainer(ids, func(id string) {
 			f.t.Logf("fake crictl: Stopping id %q", id)
-			if f.containers[id] == "" {
-				return "", fmt.Errorf("no such container")
-			}
 			delete(f.containers, id)
-		}
+		})
 	case "rm":
-		for _, id := range args[1:] {
+		return f.eachContainer(args[1:], func(id string) {
 			f.t.Logf("fake crictl: Removing id %q", id)
-			if f.containers[id] == "" {
-				return "", fmt.Errorf("no such container")
-			}
 			delete(f.containers, id)
-
-		}
+		})
 	case "rmi":
 		for _, id := range args[1:] {
 			f.t.Logf("fake crictl: Removing id %q", id)
+			if strings.HasPrefix(id, "sha256:") {
+				// crictl removes all the references of an image
+				if err := f.removeImageID(id, true); err != nil {
+					return "", err
+				}
+				continue
+			}
 			if f.images[id] == "" {
 				return "", fmt.Errorf("no such image")
 			}
 			delete(f.images, id)
 		}
+	case "inspectp":
+		// crictl inspectp --output json POD
+		if out, ok := f.inspected[args[3]]; ok {
+			return out, nil
+		}
+		return "", fmt.Errorf("pod sandbox %q not found", args[3])
+	case "runp":
+		return "pod1", nil
+	case "create":
+		return "ctr1", nil
+	case "inspect":
+		if args[1] == "--output" && args[2] == "go-template" {
+			return fmt.Sprintf("CONTAINER_EXITED %d", f.diagnosticExitCode), nil
+		}
+	case "inspecti":
+		// crictl inspecti --output json IMAGE...
+		docs := []string{}
+		for _, name := range args[3:] {
+			image, ok := f.images[name]
+			if !ok {
+				return "", fmt.Errorf("no such image")
+			}
+			tags, _ := json.Marshal(f.imageTags(image))
+			info := "{}"
+			if p, ok := f.platforms[image]; ok {
+				parts := strings.SplitN(p+"//", "/", 4)
+				info = fmt.Sprintf(`{"imageSpec":{"os":%q,"architecture":%q,"variant":%q}}`, parts[0], parts[1], parts[2])
+			}
+			docs = append(docs, fmt.Sprintf(`{"status":{"id":"sha256:%s","repoTags":%s,"size":"1024"},"info":%s}`, image, tags, info))
+		}
+		return strings.Join(docs, "\n"), nil
 	}
 	return "", nil
 }
@@ -499,9 +1388,25 @@ func (f *FakeRunner) systemctl(args []string, root bool) (string, error) { // no
 	}
 
 	if action == "daemon-reload" {
+		f.needReload = nil
 		return "ok", nil
 	}
 
+	// systemctl show --property=NeedDaemonReload docker
+	if action == "show" {
+		svc := strings.TrimSuffix(args[len(args)-1], ".service")
+		if strings.Contains(strings.Join(args, " "), "ActiveState") {
+			if f.services[svc] == SvcRunning {
+				return "ActiveState=active\nSubState=running\nResult=success\nNRestarts=0", nil
+			}
+			return "ActiveState=inactive\nSubState=dead\nResult=success\nNRestarts=0", nil
+		}
+		if f.needReload[svc] {
+			return "NeedDaemonReload=yes", nil
+		}
+		return "NeedDaemonReload=no", nil
+	}
+
 	var svcs []string
 	if len(args) > 0 {
 		svcs = args[1:]
@@ -511,6 +1416,11 @@ func (f *FakeRunner) systemctl(args []string, root bool) (string, error) { // no
 	if svcs[0] == "-f" {
 		svcs = svcs[1:]
 	}
+	now := false
+	if svcs[0] == "--now" {
+		now = true
+		svcs = svcs[1:]
+	}
 
 	out := ""
 
@@ -548,6 +1458,10 @@ func (f *FakeRunner) systemctl(args []string, root bool) (string, error) { // no
 			}
 			f.services[svc] = SvcRestarted
 			f.t.Logf("fake systemctl: SvcRestarted %s", svc)
+			// docker 24+ stores its images in containerd once restarted with the feature
+			if svc == "docker" && strings.Contains(f.files["/etc/docker/daemon.json"], `"containerd-snapshotter": true`) {
+				f.dockerInfo["{{json .DriverStatus}}"] = `[["driver-type","io.containerd.snapshotter.v1"]]`
+			}
 		case "is-active":
 			f.t.Logf("fake systemctl: %s is-status: %v", svc, state)
 			if state == SvcRunning {
@@ -565,9 +1479,22 @@ func (f *FakeRunner) systemctl(args []string, root bool) (string, error) { // no
 			}
 			return out, fmt.Errorf("%s cat unimplemented", svc)
 		case "enable":
+			if now {
+				f.services[svc] = SvcRunning
+			}
 		case "disable":
+			if now {
+				f.services[svc] = SvcExited
+			}
+		case "is-enabled":
+			if f.masked[svc] {
+				return "masked", nil
+			}
+			return "enabled", nil
 		case "mask":
+			f.masked[svc] = true
 		case "unmask":
+			delete(f.masked, svc)
 			f.t.Logf("fake systemctl: %s %s: %v", svc, action, state)
 		default:
 			return out, fmt.Errorf("unimplemented fake action: %q", action)
@@ -625,6 +1552,79 @@ var allServices = map[string]serviceState{
 	"containerd":    SvcRunning,
 }
 
+func TestPrepareStop(t *testing.T) {
+	runner := NewFakeRunner(t)
+	for k, v := range defaultServices {
+		runner.services[k] = v
+	}
+	runner.services["docker.socket"] = SvcRunning
+	runner.containers = map[string]string{
+		"abc0": "k8s_apiserver",
+		"fgh1": "k8s_coredns",
+	}
+	cr, err := New(Config{Type: "docker", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	stopped, err := cr.PrepareStop(4500*time.Millisecond, false)
+	if err != nil {
+		t.Fatalf("PrepareStop: %v", err)
+	}
+	if stopped != 2 {
+		t.Errorf("PrepareStop stopped %d containers, want 2", stopped)
+	}
+	if len(runner.containers) != 0 {
+		t.Errorf("PrepareStop left containers running: %v", runner.containers)
+	}
+	// the containers are stopped in a single call, before docker
+	var stops []string
+	for _, h := range runner.history {
+		if strings.HasPrefix(h, "docker stop") || strings.HasPrefix(h, "sudo systemctl stop") {
+			stops = append(stops, h)
+		}
+	}
+	if len(stops) > 0 && stops[0] == "docker stop -t 5 fgh1 abc0" {
+		// the fake lists the containers in any order
+		stops[0] = "docker stop -t 5 abc0 fgh1"
+	}
+	want := []string{"docker stop -t 5 abc0 fgh1", "sudo systemctl stop docker.socket", "sudo systemctl stop docker.service"}
+	if diff := cmp.Diff(want, stops); diff != "" {
+		t.Errorf("PrepareStop commands diff (-want +got):\n%s", diff)
+	}
+	if runner.services["docker"] != SvcExited {
+		t.Errorf("docker is %v after PrepareStop, want it stopped", runner.services["docker"])
+	}
+
+	// the graceful path stops each container in turn within the grace period
+	runner.containers = map[string]string{"abc0": "k8s_apiserver", "fgh1": "k8s_coredns"}
+	runner.history = nil
+	if _, err := cr.PrepareStop(30*time.Second, true); err != nil {
+		t.Fatalf("PrepareStop in turn: %v", err)
+	}
+	for _, want := range []string{"docker stop -t 30 abc0", "docker stop -t 30 fgh1"} {
+		if !containsString(runner.history, want) {
+			t.Errorf("PrepareStop in turn did not run %q: %v", want, runner.history)
+		}
+	}
+}
+
+func TestStopSeconds(t *testing.T) {
+	var tests = []struct {
+		timeout time.Duration
+		want    string
+	}{
+		{4500 * time.Millisecond, "5"},
+		{5 * time.Second, "5"},
+		// the batches after the deadline kill their containers right away
+		{-time.Second, "0"},
+	}
+	for _, tc := range tests {
+		if got := stopSeconds(tc.timeout); got != tc.want {
+			t.Errorf("stopSeconds(%s) = %s, want %s", tc.timeout, got, tc.want)
+		}
+	}
+}
+
 func TestDisable(t *testing.T) {
 	var tests = []struct {
 		runtime string
@@ -656,6 +1656,52 @@ func TestDisable(t *testing.T) {
 	}
 }
 
+func TestDockerRestart(t *testing.T) {
+	var tests = []struct {
+		name      string
+		version   string
+		unready   int
+		timeout   time.Duration
+		criDocker serviceState
+		restarted []string
+		wantErr   string
+	}{
+		{"ready", "1.23.0", 0, time.Minute, SvcExited, []string{"docker"}, ""},
+		{"delayed", "1.23.0", 2, time.Minute, SvcExited, []string{"docker"}, ""},
+		{"cri-docker", "1.24.6", 1, time.Minute, SvcRunning, []string{"docker", "cri-docker"}, ""},
+		{"cri-docker stopped", "1.24.6", 0, time.Minute, SvcExited, []string{"docker"}, ""},
+		{"timeout", "1.23.0", 1000, 300 * time.Millisecond, SvcExited, []string{"docker"}, "failed to start daemon"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.services["docker"] = SvcRunning
+			runner.services["cri-docker"] = tc.criDocker
+			runner.unready = tc.unready
+			cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse(tc.version), RestartTimeout: tc.timeout})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			err = cr.(*Docker).Restart()
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("Restart: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("Restart() error = %v, want it to contain %q", err, tc.wantErr)
+			}
+			restarted := []string{}
+			for i, arg := range runner.cmds {
+				if arg == "restart" && i+1 < len(runner.cmds) {
+					restarted = append(restarted, runner.cmds[i+1])
+				}
+			}
+			if diff := cmp.Diff(tc.restarted, restarted); diff != "" {
+				t.Errorf("restarted services diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestEnable(t *testing.T) {
 	var tests = []struct {
 		runtime  string
@@ -712,6 +1758,41 @@ func TestEnable(t *testing.T) {
 	}
 }
 
+func TestEnableDockerFeaturesUnchanged(t *testing.T) {
+	var tests = []struct {
+		description string
+		features    []string
+		want        serviceState
+	}{
+		{description: "no features", want: SvcRestarted},
+		{description: "unchanged features", features: []string{"buildkit=true"}, want: SvcRunning},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.services["docker"] = SvcRunning
+			cr, err := New(Config{Type: "docker", Runner: runner, DockerFeatures: tc.features})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			if err := cr.Enable(true, false, false); err != nil {
+				t.Fatalf("Enable: %v", err)
+			}
+			if got := runner.services["docker"]; got != SvcRestarted {
+				t.Errorf("docker state after writing daemon.json = %v, want %v", got, SvcRestarted)
+			}
+			// the second start finds daemon.json as the first one wrote it
+			runner.services["docker"] = SvcRunning
+			if err := cr.Enable(true, false, false); err != nil {
+				t.Fatalf("Enable: %v", err)
+			}
+			if got := runner.services["docker"]; got != tc.want {
+				t.Errorf("docker state = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestContainerFunctions(t *testing.T) {
 	var tests = []struct {
 		runtime string
@@ -797,3 +1878,5177 @@ func TestContainerFunctions(t *testing.T) {
 		})
 	}
 }
+
+func TestPausedContainers(t *testing.T) {
+	var tests = []struct {
+		description string
+		runtime     string
+		prefix      string
+		// runcFails makes 'runc list' fail, so that the record of paused containers answers instead
+		runcFails bool
+	}{
+		{"docker", "docker", "k8s_", false},
+		{"containerd", "containerd", "", false},
+		{"containerd without runc list", "containerd", "", true},
+		{"crio without runc list", "crio", "", true},
+	}
+
+	sortSlices := cmpopts.SortSlices(func(a, b string) bool { return a < b })
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.containers = map[string]string{
+				"abc0": tc.prefix + "apiserver",
+				"fgh1": tc.prefix + "coredns",
+				"xyz2": tc.prefix + "storage",
+			}
+			if tc.runcFails {
+				runner.failOn = "list -f json"
+			}
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+
+			if err := cr.PauseContainers([]string{"abc0", "fgh1"}); err != nil {
+				t.Fatalf("PauseContainers: %v", err)
+			}
+			got, err := cr.ListContainers(ListContainersOptions{State: Paused})
+			if err != nil {
+				t.Fatalf("ListContainers(Paused): %v", err)
+			}
+			if diff := cmp.Diff(got, []string{"abc0", "fgh1"}, sortSlices); diff != "" {
+				t.Errorf("ListContainers(Paused) unexpected results, diff (-got + want): %s", diff)
+			}
+			got, err = cr.ListContainers(ListContainersOptions{State: Running})
+			if err != nil {
+				t.Fatalf("ListContainers(Running): %v", err)
+			}
+			if diff := cmp.Diff(got, []string{"xyz2"}); diff != "" {
+				t.Errorf("ListContainers(Running) unexpected results, diff (-got + want): %s", diff)
+			}
+
+			if err := cr.UnpauseContainers([]string{"fgh1"}); err != nil {
+				t.Fatalf("UnpauseContainers: %v", err)
+			}
+			got, err = cr.ListContainers(ListContainersOptions{State: Paused})
+			if err != nil {
+				t.Fatalf("ListContainers(Paused): %v", err)
+			}
+			if diff := cmp.Diff(got, []string{"abc0"}); diff != "" {
+				t.Errorf("ListContainers(Paused) after unpause unexpected results, diff (-got + want): %s", diff)
+			}
+		})
+	}
+}
+
+func TestPausedContainersUntracked(t *testing.T) {
+	runner := NewFakeRunner(t)
+	runner.containers = map[string]string{"abc0": "apiserver"}
+	runner.failOn = "list -f json"
+	cr, err := New(Config{Type: "containerd", Runner: runner})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if _, err := cr.ListContainers(ListContainersOptions{State: Paused}); !errors.Is(err, ErrPauseUntracked) {
+		t.Errorf("ListContainers(Paused) = %v, want %v", err, ErrPauseUntracked)
+	}
+	got, err := cr.ListContainers(ListContainersOptions{State: Running})
+	if err != nil {
+		t.Fatalf("ListContainers(Running): %v", err)
+	}
+	if diff := cmp.Diff(got, []string{"abc0"}); diff != "" {
+		t.Errorf("ListContainers(Running) unexpected results, diff (-got + want): %s", diff)
+	}
+}
+
+func TestBatchResults(t *testing.T) {
+	var tests = []struct {
+		description string
+		batch       []string
+		stderr      string
+		want        containerResults
+	}{
+		{
+			description: "docker missing",
+			batch:       []string{"abc0", "fgh1", "xyz2"},
+			stderr:      "Error response from daemon: No such container: fgh1\n",
+			want:        containerResults{"abc0": containerDone, "fgh1": containerMissing, "xyz2": containerDone},
+		},
+		{
+			description: "docker missing and busy",
+			batch:       []string{"abc0", "fgh1", "xyz2"},
+			stderr:      "Error: No such container: abc0\nError response from daemon: cannot remove container \"/xyz2\": could not kill: device or resource busy\n",
+			want:        containerResults{"abc0": containerMissing, "fgh1": containerDone, "xyz2": containerFailed},
+		},
+		{
+			description: "crictl not found",
+			batch:       []string{"abc0", "fgh1"},
+			stderr:      `E0101 00:00:00.000000    1234 remote_runtime.go:343] "RemoveContainer from runtime service failed" err="rpc error: code = NotFound desc = an error occurred when try to find container \"abc0\": not found" containerID="abc0"`,
+			want:        containerResults{"abc0": containerMissing, "fgh1": containerDone},
+		},
+		{
+			description: "runc does not exist",
+			batch:       []string{"abc0"},
+			stderr:      "time=\"2023-01-01T00:00:00Z\" level=error msg=\"container does not exist\"\n",
+			want:        containerResults{"abc0": containerMissing},
+		},
+		{
+			description: "daemon down",
+			batch:       []string{"abc0", "fgh1"},
+			stderr:      "Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?\n",
+			want:        containerResults{"abc0": containerFailed, "fgh1": containerFailed},
+		},
+		{
+			description: "no output",
+			batch:       []string{"abc0"},
+			want:        containerResults{"abc0": containerFailed},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, batchResults(tc.batch, tc.stderr)); diff != "" {
+				t.Errorf("batchResults() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestContainerOperationsMissing(t *testing.T) {
+	type operation func(cr Manager, ids []string) error
+	kill := func(cr Manager, ids []string) error { return cr.KillContainers(ids) }
+	stop := func(cr Manager, ids []string) error { return cr.StopContainers(ids) }
+	pause := func(cr Manager, ids []string) error { return cr.PauseContainers(ids) }
+	unpause := func(cr Manager, ids []string) error { return cr.UnpauseContainers(ids) }
+
+	var tests = []struct {
+		description string
+		runtime     string
+		op          operation
+		busy        string
+		// left are the containers left once the operation completed, with the paused ones suffixed by "+paused"
+		left    []string
+		wantErr bool
+	}{
+		{description: "docker kill", runtime: "docker", op: kill, left: []string{"xyz2"}},
+		{description: "docker kill busy", runtime: "docker", op: kill, busy: "fgh1", left: []string{"fgh1", "xyz2"}, wantErr: true},
+		{description: "docker stop", runtime: "docker", op: stop, left: []string{"xyz2"}},
+		{description: "docker pause", runtime: "docker", op: pause, left: []string{"abc0+paused", "fgh1+paused", "xyz2"}},
+		{description: "docker unpause busy", runtime: "docker", op: unpause, busy: "abc0", left: []string{"abc0", "fgh1", "xyz2"}, wantErr: true},
+		{description: "containerd kill", runtime: "containerd", op: kill, left: []string{"xyz2"}},
+		{description: "containerd stop busy", runtime: "containerd", op: stop, busy: "abc0", left: []string{"abc0", "xyz2"}, wantErr: true},
+		{description: "containerd pause", runtime: "containerd", op: pause, left: []string{"abc0+paused", "fgh1+paused", "xyz2"}},
+		{description: "crio kill", runtime: "crio", op: kill, left: []string{"xyz2"}},
+		{description: "crio pause busy", runtime: "crio", op: pause, busy: "fgh1", left: []string{"abc0+paused", "fgh1", "xyz2"}, wantErr: true},
+		{description: "crio unpause", runtime: "crio", op: unpause, left: []string{"abc0", "fgh1", "xyz2"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.containers = map[string]string{"abc0": "apiserver", "fgh1": "coredns", "xyz2": "storage"}
+			if tc.busy != "" {
+				runner.busy = map[string]bool{tc.busy: true}
+			}
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+
+			// gone0 was removed since it was listed
+			err = tc.op(cr, []string{"abc0", "gone0", "fgh1"})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("operation error = %v, want error %v", err, tc.wantErr)
+			}
+			if err != nil && (!strings.Contains(err.Error(), tc.busy) || strings.Contains(err.Error(), "gone0")) {
+				t.Errorf("operation error %q should only name the busy container %s", err, tc.busy)
+			}
+			left := []string{}
+			for id := range runner.containers {
+				if runner.paused[id] {
+					id += "+paused"
+				}
+				left = append(left, id)
+			}
+			sort.Strings(left)
+			if diff := cmp.Diff(tc.left, left); diff != "" {
+				t.Errorf("containers left mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunOnContainersBatches(t *testing.T) {
+	runner := NewFakeRunner(t)
+	ids := []string{}
+	for i := 0; i < 2*containerBatchSize+1; i++ {
+		id := fmt.Sprintf("c%03d", i)
+		runner.containers[id] = "pod"
+		ids = append(ids, id)
+	}
+	cr, err := New(Config{Type: "docker", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	runner.history = nil
+	if err := cr.KillContainers(ids); err != nil {
+		t.Fatalf("KillContainers: %v", err)
+	}
+	if len(runner.history) != 3 {
+		t.Errorf("KillContainers ran %d commands, want 3", len(runner.history))
+	}
+	if len(runner.containers) != 0 {
+		t.Errorf("KillContainers left %d containers", len(runner.containers))
+	}
+}
+
+func TestDockerPsArgsNamespaces(t *testing.T) {
+	var tests = []struct {
+		name       string
+		namespaces []string
+		exclude    []string
+		wantFilter string
+		wantNone   bool
+		wantLeft   []string
+	}{
+		{name: "all", wantFilter: "--filter=name=k8s_"},
+		{name: "included", namespaces: []string{"kube-system", "monitoring"}, wantFilter: "--filter=name=k8s_.*_(kube-system|monitoring)_"},
+		{name: "included minus excluded", namespaces: []string{"kube-system", "monitoring"}, exclude: []string{"monitoring", "default"}, wantFilter: "--filter=name=k8s_.*_(kube-system)_"},
+		{name: "all included excluded", namespaces: []string{"monitoring"}, exclude: []string{"monitoring"}, wantNone: true},
+		{name: "all but excluded", exclude: []string{"monitoring"}, wantFilter: "--filter=name=k8s_", wantLeft: []string{"monitoring"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o, ok := selectNamespaces(ListContainersOptions{State: Paused, Namespaces: tc.namespaces, ExcludeNamespaces: tc.exclude})
+			if ok == tc.wantNone {
+				t.Fatalf("selectNamespaces() = %v, want %v", ok, !tc.wantNone)
+			}
+			if !ok {
+				return
+			}
+			if diff := cmp.Diff(tc.wantLeft, o.ExcludeNamespaces, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("excluded namespaces left mismatch (-want +got):\n%s", diff)
+			}
+			want := []string{"ps", "--filter", "status=paused", tc.wantFilter}
+			if diff := cmp.Diff(want, dockerPsArgs(o)); diff != "" {
+				t.Errorf("dockerPsArgs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSelectsNamespace(t *testing.T) {
+	o := ListContainersOptions{Namespaces: []string{"kube-system", "monitoring"}, ExcludeNamespaces: []string{"monitoring"}}
+	for ns, want := range map[string]bool{"kube-system": true, "monitoring": false, "default": false} {
+		if got := o.SelectsNamespace(ns); got != want {
+			t.Errorf("SelectsNamespace(%q) = %v, want %v", ns, got, want)
+		}
+	}
+	o = ListContainersOptions{ExcludeNamespaces: []string{"monitoring"}}
+	for ns, want := range map[string]bool{"kube-system": true, "monitoring": false, "": true} {
+		if got := o.SelectsNamespace(ns); got != want {
+			t.Errorf("SelectsNamespace(%q) with all namespaces = %v, want %v", ns, got, want)
+		}
+	}
+}
+
+func TestListContainersExcludeNamespaces(t *testing.T) {
+	var tests = []struct {
+		runtime string
+		prefix  string
+	}{
+		{"docker", "k8s_"},
+		{"containerd", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.runtime, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.containers = map[string]string{"abc0": tc.prefix + "apiserver"}
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			// the containers of the fake runner are in the default namespace
+			got, err := cr.ListContainers(ListContainersOptions{State: All, ExcludeNamespaces: []string{"kube-system"}})
+			if err != nil {
+				t.Fatalf("ListContainers: %v", err)
+			}
+			if diff := cmp.Diff([]string{"abc0"}, got); diff != "" {
+				t.Errorf("ListContainers() excluding kube-system mismatch (-want +got):\n%s", diff)
+			}
+			got, err = cr.ListContainers(ListContainersOptions{State: All, ExcludeNamespaces: []string{"default"}})
+			if err != nil {
+				t.Fatalf("ListContainers: %v", err)
+			}
+			if len(got) != 0 {
+				t.Errorf("ListContainers() excluding default = %v, want none", got)
+			}
+			runner.history = nil
+			got, err = cr.ListContainers(ListContainersOptions{State: All, Namespaces: []string{"default"}, ExcludeNamespaces: []string{"default"}})
+			if err != nil || len(got) != 0 || len(runner.history) != 0 {
+				t.Errorf("ListContainers() of excluded namespaces = %v, %v, ran %v, want none without running commands", got, err, runner.history)
+			}
+		})
+	}
+}
+
+func TestListContainerInfo(t *testing.T) {
+	var tests = []struct {
+		runtime string
+		prefix  string
+	}{
+		{"docker", "k8s_"},
+		{"containerd", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.runtime, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.containers = map[string]string{
+				"abc0": tc.prefix + "apiserver",
+			}
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			got, err := cr.ListContainerInfo(ListContainersOptions{})
+			if err != nil {
+				t.Fatalf("ListContainerInfo: %v", err)
+			}
+			want := []ContainerInfo{{
+				ID:        "abc0",
+				Name:      tc.prefix + "apiserver",
+				Pod:       tc.prefix + "apiserver-pod",
+				Namespace: "default",
+				State:     "running",
+				Image:     "busybox",
+				Created:   time.Unix(1666000800, 0),
+			}}
+			if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })); diff != "" {
+				t.Errorf("ListContainerInfo() unexpected results, diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDockerPsArgsPodUID(t *testing.T) {
+	got := dockerPsArgs(ListContainersOptions{State: Running, PodUID: "uid-a"})
+	want := []string{"ps", "--filter", "status=running", "--filter=label=io.kubernetes.pod.uid=uid-a", "--filter=name=k8s_"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("dockerPsArgs() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestImageProgressEvents(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	register.SetOutputFile(buf)
+	defer register.SetOutputFile(os.Stdout)
+	out.SetJSON(true)
+	defer out.SetJSON(false)
+
+	runner := NewFakeRunner(t)
+	cr, err := New(Config{Type: "docker", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	if _, err := cr.LoadImage("/var/lib/minikube/images/busybox_latest"); err != nil {
+		t.Fatalf("LoadImage: %v", err)
+	}
+	if err := cr.PullImage("busybox:latest"); err != nil {
+		t.Fatalf("PullImage: %v", err)
+	}
+
+	type event struct {
+		Type string            `json:"type"`
+		Data map[string]string `json:"data"`
+	}
+	got := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid JSON event %q: %v", line, err)
+		}
+		if e.Type != "io.k8s.sigs.minikube.image.progress" {
+			t.Errorf("unexpected event type: %s", e.Type)
+		}
+		if _, ok := e.Data["elapsed"]; !ok {
+			t.Errorf("event %v is missing elapsed time", e.Data)
+		}
+		got = append(got, fmt.Sprintf("%s %s %s", e.Data["action"], e.Data["status"], e.Data["artifact"]))
+	}
+	want := []string{
+		"load started /var/lib/minikube/images/busybox_latest",
+		"load finished /var/lib/minikube/images/busybox_latest",
+		"pull started busybox:latest",
+		"pull finished busybox:latest",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("image events diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestImageProgressEventsTextOutput(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	register.SetOutputFile(buf)
+	defer register.SetOutputFile(os.Stdout)
+
+	runner := NewFakeRunner(t)
+	cr, err := New(Config{Type: "containerd", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(containerd): %v", err)
+	}
+	if _, err := cr.LoadImage("/var/lib/minikube/images/busybox_latest"); err != nil {
+		t.Fatalf("LoadImage: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no JSON events with text output, got %s", buf.String())
+	}
+}
+
+func TestSelectImageOutput(t *testing.T) {
+	var tests = []struct {
+		json, quiet, terminal bool
+		want                  ImageOutput
+	}{
+		{json: true, terminal: true, want: ImageOutputEvents},
+		{json: true, quiet: true, want: ImageOutputEvents},
+		{quiet: true, terminal: true, want: ImageOutputDiscard},
+		{terminal: true, want: ImageOutputTerminal},
+		{want: ImageOutputSummary},
+	}
+	for _, tc := range tests {
+		if got := SelectImageOutput(tc.json, tc.quiet, tc.terminal); got != tc.want {
+			t.Errorf("SelectImageOutput(json=%v, quiet=%v, terminal=%v) = %v, want %v", tc.json, tc.quiet, tc.terminal, got, tc.want)
+		}
+	}
+}
+
+func TestStreamImageOutput(t *testing.T) {
+	c := exec.Command("docker", "pull", "busybox")
+	streamImageOutput(ImageOutputBuffered, c, "busybox", register.ImagePull)
+	if c.Stdout != nil || c.Stderr != nil {
+		t.Errorf("buffered output set the streams of the command: %v, %v", c.Stdout, c.Stderr)
+	}
+
+	c = exec.Command("docker", "pull", "busybox")
+	streamImageOutput(ImageOutputDiscard, c, "busybox", register.ImagePull)
+	if c.Stdout != io.Discard || c.Stderr != io.Discard {
+		t.Errorf("discarded output went to %v, %v", c.Stdout, c.Stderr)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	register.SetOutputFile(buf)
+	defer register.SetOutputFile(os.Stdout)
+	c = exec.Command("docker", "pull", "busybox")
+	streamImageOutput(ImageOutputEvents, c, "busybox", register.ImagePull)
+	fmt.Fprint(c.Stdout, "latest: Pulling from library/busybox\r\n")
+	fmt.Fprint(c.Stdout, "a1b2c3: Downloading 1MB/2MB\ra1b2c3: Download complete\n")
+
+	type event struct {
+		Data map[string]string `json:"data"`
+	}
+	got := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid JSON event %q: %v", line, err)
+		}
+		got = append(got, fmt.Sprintf("%s %s %s", e.Data["action"], e.Data["status"], e.Data["line"]))
+	}
+	want := []string{
+		"pull progress latest: Pulling from library/busybox",
+		"pull progress a1b2c3: Downloading 1MB/2MB",
+		"pull progress a1b2c3: Download complete",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("image output events diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestEnableAdoptRunningDocker(t *testing.T) {
+	var tests = []struct {
+		name         string
+		cgroupDriver string
+		liveRestore  string
+		forceSystemd bool
+		forceRestart bool
+		wantErr      bool
+		want         serviceState
+	}{
+		{"compatible", "cgroupfs", "true", false, false, false, SvcRunning},
+		{"compatible systemd", "systemd", "true", true, false, false, SvcRunning},
+		{"cgroup driver mismatch", "cgroupfs", "true", true, false, true, SvcRunning},
+		{"live-restore disabled", "systemd", "false", false, false, false, SvcRestarted},
+		{"live-restore disabled with cgroup driver mismatch", "cgroupfs", "false", true, false, true, SvcRunning},
+		{"forced restart", "cgroupfs", "true", true, true, false, SvcRestarted},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.services["docker"] = SvcRunning
+			runner.dockerInfo["{{.CgroupDriver}}"] = tc.cgroupDriver
+			runner.dockerInfo["{{.LiveRestoreEnabled}}"] = tc.liveRestore
+			cr, err := New(Config{Type: "docker", Runner: runner, AdoptRunning: true, ForceRestart: tc.forceRestart})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			err = cr.Enable(false, tc.forceSystemd, false)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Enable() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr && !strings.Contains(err.Error(), "--force") {
+				t.Errorf("Enable() error %q does not mention --force", err)
+			}
+			if got := runner.services["docker"]; got != tc.want {
+				t.Errorf("docker service state = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEnableAdoptRunningDockerDaemonConfig(t *testing.T) {
+	var tests = []struct {
+		name         string
+		cgroupDriver string
+		liveRestore  string
+		forceSystemd bool
+		features     []string
+		forceRestart bool
+		wantErr      bool
+		want         serviceState
+		wantSystemd  bool
+	}{
+		{name: "daemon.json changed", cgroupDriver: "cgroupfs", liveRestore: "true", features: []string{"buildkit=true"}, wantErr: true, want: SvcRunning},
+		{name: "daemon.json changed without live-restore", cgroupDriver: "cgroupfs", liveRestore: "false", features: []string{"buildkit=true"}, wantErr: true, want: SvcRunning},
+		{name: "daemon.json changed with --force", cgroupDriver: "cgroupfs", liveRestore: "true", features: []string{"buildkit=true"}, forceRestart: true, want: SvcRestarted},
+		{name: "systemd set in another form", cgroupDriver: "systemd", liveRestore: "false", forceSystemd: true, want: SvcRestarted},
+		{name: "systemd with --force", cgroupDriver: "cgroupfs", liveRestore: "true", forceSystemd: true, forceRestart: true, want: SvcRestarted, wantSystemd: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.services["docker"] = SvcRunning
+			runner.dockerInfo["{{.CgroupDriver}}"] = tc.cgroupDriver
+			runner.dockerInfo["{{.LiveRestoreEnabled}}"] = tc.liveRestore
+			cr, err := New(Config{Type: "docker", Runner: runner, AdoptRunning: true, ForceRestart: tc.forceRestart, DockerFeatures: tc.features})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			err = cr.Enable(false, tc.forceSystemd, false)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Enable() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr && (!strings.Contains(err.Error(), "daemon.json changed") || !strings.Contains(err.Error(), "--force")) {
+				t.Errorf("Enable() error %q does not ask for --force as daemon.json changed", err)
+			}
+			if got := runner.services["docker"]; got != tc.want {
+				t.Errorf("docker service state = %v, want %v", got, tc.want)
+			}
+			if systemd := strings.Contains(runner.files["/etc/docker/daemon.json"], "native.cgroupdriver=systemd"); systemd != tc.wantSystemd {
+				t.Errorf("daemon.json with the systemd cgroup driver = %v, want %v", systemd, tc.wantSystemd)
+			}
+		})
+	}
+}
+
+func TestSwitched(t *testing.T) {
+	var tests = []struct {
+		previous string
+		current  string
+		want     bool
+	}{
+		{"", "docker", false},
+		{"docker", "docker", false},
+		{"cri-o", "crio", false},
+		{"containerd", "docker", true},
+		{"docker", "crio", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.previous+"->"+tc.current, func(t *testing.T) {
+			if got := Switched(tc.previous, tc.current); got != tc.want {
+				t.Errorf("Switched(%q, %q) = %v, want %v", tc.previous, tc.current, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCNIChainCommands(t *testing.T) {
+	rules := `-P PREROUTING ACCEPT
+-P POSTROUTING ACCEPT
+-N CNI-HOSTPORT-DNAT
+-N CNI-a1b2c3
+-N DOCKER
+-A PREROUTING -m addrtype --dst-type LOCAL -j CNI-HOSTPORT-DNAT
+-A PREROUTING -m addrtype --dst-type LOCAL -j DOCKER
+-A POSTROUTING -s 10.244.0.2/32 -m comment --comment "name: \"bridge\" id: \"a1b2c3\"" -j CNI-a1b2c3
+-A POSTROUTING -s 192.168.1.0/24 -m comment --comment "user rule, not CNI-managed" -j MASQUERADE
+-A CNI-a1b2c3 -d 10.244.0.0/16 -j ACCEPT
+-A CNI-a1b2c3 ! -d 224.0.0.0/4 -j MASQUERADE
+`
+	want := [][]string{
+		{"-t", "nat", "-D", "PREROUTING", "-m", "addrtype", "--dst-type", "LOCAL", "-j", "CNI-HOSTPORT-DNAT"},
+		{"-t", "nat", "-D", "POSTROUTING", "-s", "10.244.0.2/32", "-m", "comment", "--comment", `name: "bridge" id: "a1b2c3"`, "-j", "CNI-a1b2c3"},
+		{"-t", "nat", "-F", "CNI-HOSTPORT-DNAT"},
+		{"-t", "nat", "-F", "CNI-a1b2c3"},
+		{"-t", "nat", "-X", "CNI-HOSTPORT-DNAT"},
+		{"-t", "nat", "-X", "CNI-a1b2c3"},
+	}
+	if diff := cmp.Diff(want, cniChainCommands("nat", rules)); diff != "" {
+		t.Errorf("cniChainCommands() returned diff (-want +got):\n%s", diff)
+	}
+	if got := cniChainCommands("filter", "-P INPUT ACCEPT\n-A INPUT -p tcp --dport 22 -j ACCEPT\n"); len(got) != 0 {
+		t.Errorf("cniChainCommands() of a table without CNI chains = %v", got)
+	}
+}
+
+func TestStaleCNIConfigs(t *testing.T) {
+	files := []string{
+		"/etc/cni/net.d/10-containerd-net.conflist",
+		"/etc/cni/net.d/87-podman-bridge.conflist",
+		"/etc/cni/net.d/100-crio-bridge.conf",
+		"/etc/cni/net.d/200-loopback.conf",
+		"/etc/cni/net.d/DISABLED-10-flannel.conflist",
+		"/etc/cni/net.d/1-k8s.conflist",
+	}
+	var tests = []struct {
+		name string
+		keep []string
+		want []string
+	}{
+		{"bridge", []string{"1-k8s.conflist"}, []string{
+			"/etc/cni/net.d/10-containerd-net.conflist",
+			"/etc/cni/net.d/87-podman-bridge.conflist",
+			"/etc/cni/net.d/100-crio-bridge.conf",
+		}},
+		{"custom", nil, []string{
+			"/etc/cni/net.d/10-containerd-net.conflist",
+			"/etc/cni/net.d/87-podman-bridge.conflist",
+			"/etc/cni/net.d/100-crio-bridge.conf",
+			"/etc/cni/net.d/1-k8s.conflist",
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := staleCNIConfigs(files, tc.keep)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("staleCNIConfigs() returned diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDisableOthersDockerOnDemand(t *testing.T) {
+	var tests = []struct {
+		name       string
+		runtime    string
+		onDemand   bool
+		wantDocker serviceState
+		wantSocket serviceState
+		wantMasked bool
+	}{
+		{"masked", "containerd", false, SvcExited, SvcExited, true},
+		{"on-demand", "containerd", true, SvcExited, SvcRunning, false},
+		{"on-demand crio", "crio", true, SvcExited, SvcRunning, false},
+		{"enabled", "docker", true, SvcRestarted, SvcExited, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			for k, v := range defaultServices {
+				runner.services[k] = v
+			}
+			runner.services["docker.socket"] = SvcExited
+			cr, err := New(Config{Type: tc.runtime, Runner: runner, DockerOnDemand: tc.onDemand})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			if err := cr.Enable(true, true, false); err != nil {
+				t.Fatalf("Enable(%s): %v", tc.runtime, err)
+			}
+			if got := runner.services["docker"]; got != tc.wantDocker {
+				t.Errorf("docker service state = %v, want %v", got, tc.wantDocker)
+			}
+			if got := runner.services["docker.socket"]; got != tc.wantSocket {
+				t.Errorf("docker socket state = %v, want %v", got, tc.wantSocket)
+			}
+			if got := runner.masked["docker"]; got != tc.wantMasked {
+				t.Errorf("docker service masked = %v, want %v", got, tc.wantMasked)
+			}
+		})
+	}
+}
+
+func TestCollectable(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	recent := now.Add(-time.Hour)
+	candidates := []gcCandidate{
+		// a running pod, with a container that restarted long ago
+		{ID: "sandbox-running", IsSandbox: true, Running: true, Stopped: old},
+		{ID: "app", Sandbox: "sandbox-running", Running: true},
+		{ID: "app-previous", Sandbox: "sandbox-running", Stopped: old},
+		// a deleted pod, whose container exited recently
+		{ID: "sandbox-recent", IsSandbox: true, Stopped: old},
+		{ID: "job", Sandbox: "sandbox-recent", Stopped: recent},
+		// a deleted pod, long gone
+		{ID: "sandbox-old", IsSandbox: true, Stopped: old},
+		{ID: "job-old", Sandbox: "sandbox-old", Stopped: old},
+		// a container that never ran
+		{ID: "created", Sandbox: "sandbox-old"},
+		// a sandbox without any container left
+		{ID: "sandbox-empty", IsSandbox: true, Stopped: old},
+		{ID: "sandbox-new", IsSandbox: true, Stopped: recent},
+		// a diagnostic container left behind, which does not wait for the age
+		{ID: "sandbox-diagnostic", IsSandbox: true, Stopped: recent, Diagnostic: true},
+		{ID: "nslookup", Sandbox: "sandbox-diagnostic", Stopped: recent, Diagnostic: true},
+		// a diagnostic container still running
+		{ID: "sandbox-diagnosing", IsSandbox: true, Running: true, Stopped: recent, Diagnostic: true},
+		{ID: "dig", Sandbox: "sandbox-diagnosing", Running: true, Diagnostic: true},
+	}
+	containers, sandboxes := collectable(candidates, now.Add(-24*time.Hour))
+	if diff := cmp.Diff([]string{"app-previous", "job-old", "nslookup"}, containers); diff != "" {
+		t.Errorf("collectable containers diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"sandbox-empty", "sandbox-diagnostic"}, sandboxes); diff != "" {
+		t.Errorf("collectable sandboxes diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseDockerGCCandidates(t *testing.T) {
+	output := strings.Join([]string{
+		"abc\trunning\t0001-01-01T00:00:00Z\tpodsandbox\t",
+		"def\texited\t2022-10-17T10:00:00.123456789Z\tcontainer\tabc",
+		"ghi\tcreated\t0001-01-01T00:00:00Z\tcontainer\tabc",
+		"",
+	}, "\n")
+	finished := time.Date(2022, 10, 17, 10, 0, 0, 123456789, time.UTC)
+	want := []gcCandidate{
+		{ID: "abc", IsSandbox: true, Running: true},
+		{ID: "def", Sandbox: "abc", Stopped: finished},
+		{ID: "ghi", Sandbox: "abc", Running: true},
+	}
+	if diff := cmp.Diff(want, parseDockerGCCandidates(output), cmp.AllowUnexported(gcCandidate{})); diff != "" {
+		t.Errorf("parseDockerGCCandidates diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDockerRegistryConfigInsecure(t *testing.T) {
+	var rc dockerRegistryConfig
+	if err := json.Unmarshal([]byte(`{"InsecureRegistryCIDRs":["10.96.0.0/12","127.0.0.0/8"],"IndexConfigs":{"docker.io":{"Secure":true},"registry.local:5000":{"Secure":false}}}`), &rc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	var tests = []struct {
+		addr string
+		want bool
+	}{
+		{"10.96.0.5:80", true},
+		{"127.0.0.1:5000", true},
+		{"registry.local:5000", true},
+		{"192.168.49.2:5000", false},
+		{"docker.io", false},
+		{"registry.example.com:443", false},
+	}
+	for _, tc := range tests {
+		if got := rc.insecure(tc.addr); got != tc.want {
+			t.Errorf("insecure(%q) = %t, want %t", tc.addr, got, tc.want)
+		}
+	}
+}
+
+func TestClassifyHealth(t *testing.T) {
+	running := UnitHealth{Unit: "docker", ActiveState: "active", SubState: "running", Result: "success"}
+	restarted := UnitHealth{Unit: "docker", ActiveState: "active", SubState: "running", Result: "success", Restarts: 1}
+	looping := UnitHealth{Unit: "docker", ActiveState: "activating", SubState: "auto-restart", Result: "oom-kill", Restarts: 7}
+	stopped := UnitHealth{Unit: "cri-docker", ActiveState: "inactive", SubState: "dead", Result: "success"}
+	var tests = []struct {
+		description string
+		units       []UnitHealth
+		ooms        int
+		responsive  bool
+		want        string
+	}{
+		{"running", []UnitHealth{running}, 0, true, HealthRunning},
+		{"restarted", []UnitHealth{restarted}, 0, true, HealthDegraded},
+		{"oom killed child", []UnitHealth{running}, 2, true, HealthDegraded},
+		{"unresponsive", []UnitHealth{running}, 0, false, HealthDegraded},
+		{"crash loop", []UnitHealth{looping}, 7, false, HealthCrashloop},
+		{"crash loop wins over stopped", []UnitHealth{stopped, looping}, 0, false, HealthCrashloop},
+		{"stopped", []UnitHealth{running, stopped}, 0, true, HealthStopped},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			got, reason := classifyHealth(tc.units, tc.ooms, tc.responsive)
+			if got != tc.want {
+				t.Errorf("classifyHealth() = %s (%s), want %s", got, reason, tc.want)
+			}
+			if got != HealthRunning && reason == "" {
+				t.Errorf("classifyHealth() = %s without a reason", got)
+			}
+		})
+	}
+	if !oomRe.MatchString("Out of memory: Killed process 1234 (dockerd) total-vm:1843612kB, anon-rss:61124kB") {
+		t.Errorf("kernel OOM line does not match")
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	host := time.Unix(1700000000, 0)
+	var tests = []struct {
+		description string
+		before      time.Time
+		after       time.Time
+		output      string
+		want        time.Duration
+		wantErr     bool
+	}{
+		{description: "in sync", before: host, after: host.Add(200 * time.Millisecond), output: "1700000000\n", want: 0},
+		{description: "slow command", before: host, after: host.Add(3 * time.Second), output: "1700000001", want: 0},
+		{description: "ahead", before: host, after: host, output: "1700003600", want: time.Hour},
+		{description: "behind after suspend", before: host, after: host, output: "1699913600", want: -24 * time.Hour},
+		{description: "garbage", before: host, after: host, output: "Mon Jan  1 00:00:00 UTC 2024", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := clockSkew(tc.before, tc.after, tc.output)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("clockSkew() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			// the node prints whole seconds, so that the skew is known within a second
+			if d := got - tc.want; d > time.Second || d < -time.Second {
+				t.Errorf("clockSkew() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+	if clockSkewed(10*time.Second) || !clockSkewed(-time.Hour) {
+		t.Errorf("clockSkewed() does not use the threshold of %s", clockSkewThreshold)
+	}
+}
+
+func TestRuntimeHealthClockSkew(t *testing.T) {
+	for _, skew := range []time.Duration{0, time.Hour} {
+		runner := NewFakeRunner(t)
+		runner.skew = skew
+		runner.services["containerd"] = SvcRunning
+		cr, err := New(Config{Type: "containerd", Runner: runner})
+		if err != nil {
+			t.Fatalf("New(containerd): %v", err)
+		}
+		h, err := cr.RuntimeHealth()
+		if err != nil {
+			t.Fatalf("RuntimeHealth: %v", err)
+		}
+		if d := h.ClockSkew - skew; d > time.Second || d < -time.Second {
+			t.Errorf("ClockSkew = %s, want %s", h.ClockSkew, skew)
+		}
+		if got := strings.Contains(h.Reason, "clock skewed"); got != clockSkewed(skew) {
+			t.Errorf("RuntimeHealth() with a skew of %s = %s (%s)", skew, h.State, h.Reason)
+		}
+	}
+}
+
+func TestPullWithClockCheck(t *testing.T) {
+	var tests = []struct {
+		description string
+		runtime     string
+		driver      string
+		skew        time.Duration
+		failOn      string
+		wantErr     bool
+		wantPulls   int
+	}{
+		{description: "docker in sync", runtime: "docker", driver: driver.KVM2, wantPulls: 1},
+		{description: "docker resynced", runtime: "docker", driver: driver.KVM2, skew: -48 * time.Hour, wantPulls: 2},
+		{description: "containerd resynced", runtime: "containerd", driver: driver.HyperKit, skew: 2 * time.Hour, wantPulls: 2},
+		{description: "crio resynced", runtime: "crio", driver: driver.VirtualBox, skew: 2 * time.Hour, wantPulls: 2},
+		{description: "resync failed", runtime: "docker", driver: driver.KVM2, skew: -48 * time.Hour, failOn: "hwclock", wantErr: true, wantPulls: 1},
+		{description: "ssh host not resynced", runtime: "docker", driver: driver.SSH, skew: -48 * time.Hour, wantErr: true, wantPulls: 1},
+		{description: "bare metal host not resynced", runtime: "containerd", driver: driver.None, skew: 2 * time.Hour, wantErr: true, wantPulls: 1},
+		{description: "unknown host not resynced", runtime: "crio", skew: 2 * time.Hour, wantErr: true, wantPulls: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			clockChecked.done = false
+			defer func() { clockChecked.done = false }()
+			runner := NewFakeRunner(t)
+			runner.skew = tc.skew
+			runner.failOn = tc.failOn
+			cr, err := New(Config{Type: tc.runtime, Runner: runner, Driver: tc.driver})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			err = cr.PullImage("busybox:latest")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("PullImage() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr && !isClockSkewError(err) {
+				t.Errorf("PullImage() = %v, want the clock skew error of the pull", err)
+			}
+			pulls := 0
+			for _, c := range runner.history {
+				if strings.Contains(c, " pull busybox:latest") {
+					pulls++
+				}
+			}
+			if pulls != tc.wantPulls {
+				t.Errorf("PullImage() pulled %d times, want %d:\n%s", pulls, tc.wantPulls, strings.Join(runner.history, "\n"))
+			}
+			if !resyncsClock(tc.driver) {
+				for _, c := range runner.history {
+					if strings.Contains(c, "hwclock") || strings.Contains(c, "systemctl restart") {
+						t.Errorf("PullImage() resynced the clock of a %q host: %s", tc.driver, c)
+					}
+				}
+			}
+
+			// the clock is checked once per command
+			runner.skew = tc.skew
+			before := len(runner.history)
+			err = cr.PullImage("busybox:latest")
+			if clockSkewed(tc.skew) != (err != nil) {
+				t.Errorf("second PullImage() = %v", err)
+			}
+			for _, c := range runner.history[before:] {
+				if strings.Contains(c, "hwclock") {
+					t.Errorf("second PullImage() checked the clock again: %s", c)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	var tests = []struct {
+		description string
+		runtime     string
+		services    map[string]serviceState
+		failOn      string
+		wantErr     bool
+	}{
+		{"docker", "docker", map[string]serviceState{"docker": SvcRunning, "cri-docker": SvcRunning}, "", false},
+		{"cri-dockerd crashed", "docker", map[string]serviceState{"docker": SvcRunning, "cri-docker": SvcExited}, "", true},
+		{"docker not answering", "docker", map[string]serviceState{"docker": SvcRunning, "cri-docker": SvcRunning}, "crictl", true},
+		{"containerd", "containerd", map[string]serviceState{"containerd": SvcRunning}, "", false},
+		{"containerd stopped", "containerd", map[string]serviceState{"containerd": SvcExited}, "", true},
+		{"crio", "crio", map[string]serviceState{"crio": SvcRunning}, "", false},
+		{"crio not answering", "crio", map[string]serviceState{"crio": SvcRunning}, "crictl", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.services = tc.services
+			runner.failOn = tc.failOn
+			cr, err := New(Config{Type: tc.runtime, Runner: runner, KubernetesVersion: semver.MustParse("1.25.0")})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			err = cr.Healthy()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Healthy() = %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDockerDeferredRestart(t *testing.T) {
+	t.Setenv("MINIKUBE_HOME", t.TempDir())
+	viper.Set("preload", true)
+	defer viper.Set("preload", nil)
+	tarball := download.TarballPath("v1.26.1", "docker")
+	if err := os.MkdirAll(filepath.Dir(tarball), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tarball, []byte{0x04, 0x22, 0x4d, 0x18}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cc := config.ClusterConfig{Driver: "kvm2", KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.26.1", ContainerRuntime: "docker"}}
+
+	for _, deferred := range []bool{false, true} {
+		t.Run(fmt.Sprintf("deferred=%v", deferred), func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			for k, v := range defaultServices {
+				runner.services[k] = v
+			}
+			runner.services["cri-docker"] = SvcRunning
+			runner.services["cri-docker.socket"] = SvcRunning
+			cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.26.1"), PreloadSpaceFactor: -1, DeferRestarts: deferred})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			if err := cr.Preload(cc); err != nil {
+				t.Fatalf("Preload: %v", err)
+			}
+			if err := ConfigureNetworkPlugin(cr, runner, "cni"); err != nil {
+				t.Fatalf("ConfigureNetworkPlugin: %v", err)
+			}
+			if err := cr.Enable(true, true, false); err != nil {
+				t.Fatalf("Enable: %v", err)
+			}
+			restarts := func() int {
+				n := 0
+				for _, h := range runner.history {
+					if h == "sudo systemctl restart docker" {
+						n++
+					}
+				}
+				return n
+			}
+			before := restarts()
+			if err := cr.ApplyPendingRestart(); err != nil {
+				t.Fatalf("ApplyPendingRestart: %v", err)
+			}
+			if deferred && (before != 0 || restarts() != 1) {
+				t.Errorf("docker restarted %d times before ApplyPendingRestart and %d times in all, want once by ApplyPendingRestart", before, restarts())
+			}
+			if !deferred && (before < 2 || restarts() != before) {
+				t.Errorf("docker restarted %d times before ApplyPendingRestart and %d times in all, want each step to restart it", before, restarts())
+			}
+
+			// once applied, the steps restart docker right away again
+			total := restarts()
+			if err := cr.Enable(false, true, false); err != nil {
+				t.Fatalf("Enable: %v", err)
+			}
+			if got := restarts() - total; got != 1 {
+				t.Errorf("Enable after ApplyPendingRestart restarted docker %d times, want once", got)
+			}
+		})
+	}
+}
+
+func TestCheckElevation(t *testing.T) {
+	runner := NewFakeRunner(t)
+	if err := CheckElevation(runner); err != nil {
+		t.Errorf("CheckElevation() = %v, want no error", err)
+	}
+	runner.failOn = "sudo true"
+	if err := CheckElevation(runner); err == nil {
+		t.Errorf("CheckElevation() = nil, want an error when sudo fails")
+	}
+}
+
+func TestMergeDaemonConfig(t *testing.T) {
+	systemd := `{
+  "exec-opts": [
+    "native.cgroupdriver=systemd"
+  ],
+  "log-driver": "json-file",
+  "log-opts": {
+    "max-size": "100m"
+  },
+  "storage-driver": "overlay2"
+}`
+	var tests = []struct {
+		description  string
+		current      string
+		forceSystemd bool
+		features     []string
+		mirrors      []string
+		bridgeIP     string
+		mtu          int
+		nvidia       bool
+		want         string
+	}{
+		{
+			description:  "systemd only",
+			forceSystemd: true,
+			want:         systemd,
+		},
+		{
+			description:  "features merged with systemd keys",
+			forceSystemd: true,
+			features:     []string{"buildkit=true", "containerd-snapshotter=false", "ipv6=true", "fixed-cidr-v6=fd00:dead:beef::/48"},
+			want: `{
+  "exec-opts": [
+    "native.cgroupdriver=systemd"
+  ],
+  "features": {
+    "buildkit": true,
+    "containerd-snapshotter": false
+  },
+  "fixed-cidr-v6": "fd00:dead:beef::/48",
+  "ipv6": true,
+  "log-driver": "json-file",
+  "log-opts": {
+    "max-size": "100m"
+  },
+  "storage-driver": "overlay2"
+}`,
+		},
+		{
+			description: "features kept from the current file, and systemd keys written before",
+			current:     systemd[:len(systemd)-1] + `,"features":{"buildkit":false,"other":true},"insecure-registries":["10.0.0.1:5000"]}`,
+			features:    []string{"buildkit=1", "unknown-feature=on"},
+			want: `{
+  "exec-opts": [
+    "native.cgroupdriver=systemd"
+  ],
+  "features": {
+    "buildkit": true,
+    "other": true,
+    "unknown-feature": "on"
+  },
+  "insecure-registries": [
+    "10.0.0.1:5000"
+  ],
+  "log-driver": "json-file",
+  "log-opts": {
+    "max-size": "100m"
+  },
+  "storage-driver": "overlay2"
+}`,
+		},
+		{
+			description: "bridge settings",
+			current:     `{"bip":"172.17.0.1/16","ipv6":true}`,
+			bridgeIP:    "10.200.0.1/24",
+			mtu:         1400,
+			want: `{
+  "bip": "10.200.0.1/24",
+  "ipv6": true,
+  "mtu": 1400
+}`,
+		},
+		{
+			description: "registry mirrors replace the current ones",
+			current:     `{"registry-mirrors":["https://mirror.gcr.io"],"ipv6":true}`,
+			mirrors:     []string{"http://host.minikube.internal:5000", "https://mirror.gcr.io"},
+			want: `{
+  "ipv6": true,
+  "registry-mirrors": [
+    "http://host.minikube.internal:5000",
+    "https://mirror.gcr.io"
+  ]
+}`,
+		},
+		{
+			description: "nvidia runtime added to the current runtimes",
+			current:     `{"runtimes":{"runsc":{"path":"/usr/local/bin/runsc"}},"default-runtime":"runc"}`,
+			nvidia:      true,
+			want: `{
+  "default-runtime": "nvidia",
+  "runtimes": {
+    "nvidia": {
+      "args": [],
+      "path": "nvidia-container-runtime"
+    },
+    "runsc": {
+      "path": "/usr/local/bin/runsc"
+    }
+  }
+}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			settings := daemonSettings{forceSystemd: tc.forceSystemd, features: tc.features, mirrors: tc.mirrors, bridgeIP: tc.bridgeIP, mtu: tc.mtu, nvidia: tc.nvidia}
+			got, err := mergeDaemonConfig([]byte(tc.current), settings)
+			if err != nil {
+				t.Fatalf("mergeDaemonConfig: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("mergeDaemonConfig diff (-want +got):\n%s", diff)
+			}
+			// merging again gives the same content, so that the daemon is not restarted for nothing
+			again, err := mergeDaemonConfig(got, settings)
+			if err != nil {
+				t.Fatalf("mergeDaemonConfig: %v", err)
+			}
+			if string(again) != string(got) {
+				t.Errorf("merging twice changed daemon.json:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestValidateDockerFeatures(t *testing.T) {
+	unknown, err := ValidateDockerFeatures([]string{"buildkit=true", "ipv6=false", "fixed-cidr-v6=2001:db8:1::/64", "cdi=true"})
+	if err != nil {
+		t.Fatalf("ValidateDockerFeatures: %v", err)
+	}
+	if diff := cmp.Diff([]string{"cdi"}, unknown); diff != "" {
+		t.Errorf("unknown features diff (-want +got):\n%s", diff)
+	}
+	for _, invalid := range []string{"buildkit", "buildkit=maybe", "fixed-cidr-v6=10.0.0.0/8", "=true"} {
+		if _, err := ValidateDockerFeatures([]string{invalid}); err == nil {
+			t.Errorf("ValidateDockerFeatures(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestCRISocket(t *testing.T) {
+	var tests = []struct {
+		runtime string
+		socket  string
+		version string
+		want    string
+	}{
+		{"docker", "", "1.23.8", InternalDockerCRISocket},
+		{"docker", "", "1.24.3", ExternalDockerCRISocket},
+		{"docker", InternalDockerCRISocket, "1.23.8", InternalDockerCRISocket},
+		{"docker", InternalDockerCRISocket, "1.24.3", ExternalDockerCRISocket},
+		{"docker", "unix://" + InternalDockerCRISocket, "1.24.3", ExternalDockerCRISocket},
+		{"docker", ExternalDockerCRISocket, "1.25.3", ExternalDockerCRISocket},
+		{"docker", ExternalDockerCRISocket, "1.26.0", ExternalDockerCRISocket},
+		{"docker", ExternalDockerCRISocket, "1.23.8", ExternalDockerCRISocket},
+		{"containerd", "", "1.26.0", ContainerdCRISocket},
+		{"crio", "/var/run/custom.sock", "1.26.0", "/var/run/custom.sock"},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s %s %s", tc.runtime, tc.socket, tc.version), func(t *testing.T) {
+			if got := CRISocket(tc.runtime, tc.socket, semver.MustParse(tc.version)); got != tc.want {
+				t.Errorf("CRISocket() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	var tests = []struct {
+		name string
+		r    Manager
+		want Capabilities
+	}{
+		{"docker", &Docker{Runner: NewFakeRunner(t)},
+			Capabilities{SupportsBuild: true, SupportsPause: true, SnapshotterName: "overlay2", NativeBuildkit: true}},
+		{"docker UseCRI", &Docker{Runner: NewFakeRunner(t), UseCRI: true},
+			Capabilities{SupportsBuild: true, SupportsPause: true, SnapshotterName: "overlay2", CRISocket: InternalDockerCRISocket, NativeBuildkit: true}},
+		{"docker UseCRI cri-dockerd", &Docker{Runner: NewFakeRunner(t), UseCRI: true, Socket: ExternalDockerCRISocket},
+			Capabilities{SupportsBuild: true, SupportsPause: true, SnapshotterName: "overlay2", CRISocket: ExternalDockerCRISocket, NativeBuildkit: true}},
+		{"containerd", &Containerd{Runner: NewFakeRunner(t)},
+			Capabilities{SupportsUntrustedRuntimeClass: true, SupportsBuild: true, SupportsPause: true, SnapshotterName: "overlayfs", CRISocket: ContainerdCRISocket}},
+		{"crio", &CRIO{Runner: NewFakeRunner(t)},
+			Capabilities{SupportsBuild: true, SupportsPause: true, SnapshotterName: "overlay", CRISocket: CRIOCRISocket}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tc.r.Capabilities()); diff != "" {
+				t.Errorf("Capabilities() unexpected diff: (-want +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestParseDockerHistory(t *testing.T) {
+	out := `{"Comment":"","CreatedAt":"2022-08-09T17:19:53Z","CreatedBy":"/bin/sh -c #(nop)  CMD [\"sh\"]","CreatedSince":"2 months ago","ID":"sha256:9d5226e6ce3f","Size":"0"}
+{"Comment":"","CreatedAt":"2022-08-09T17:19:53Z","CreatedBy":"/bin/sh -c #(nop) ADD file:6f2d0 in / ","CreatedSince":"2 months ago","ID":"<missing>","Size":"4859342"}
+`
+	steps, err := parseDockerHistory(out)
+	if err != nil {
+		t.Fatalf("parseDockerHistory: %v", err)
+	}
+	layers := layersFromHistory(steps, []string{"sha256:aaa"})
+	want := []LayerInfo{
+		{CreatedBy: `/bin/sh -c #(nop)  CMD ["sh"]`, Created: time.Date(2022, 8, 9, 17, 19, 53, 0, time.UTC)},
+		{Digest: "sha256:aaa", Size: 4859342, CreatedBy: "/bin/sh -c #(nop) ADD file:6f2d0 in / ", Created: time.Date(2022, 8, 9, 17, 19, 53, 0, time.UTC)},
+	}
+	if diff := cmp.Diff(want, layers); diff != "" {
+		t.Errorf("layersFromHistory() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseDockerImageSizes(t *testing.T) {
+	// captured from docker 20.10.18 in the kic node
+	images := `{"Containers":"N/A","CreatedAt":"2022-08-23 18:05:43 +0000 UTC","CreatedSince":"2 months ago","Digest":"\u003cnone\u003e","ID":"sha256:4d2edfd10d3e3a42e4f1e37ad5a06fde8ea8d3e4cc9a2b4c5f4a5bc4a5f3a1c2","Repository":"registry.k8s.io/kube-apiserver","SharedSize":"N/A","Size":"128MB","Tag":"v1.25.0","UniqueSize":"N/A","VirtualSize":"128.4MB"}
+{"Containers":"N/A","CreatedAt":"2022-10-14 21:22:38 +0000 UTC","CreatedSince":"3 days ago","Digest":"\u003cnone\u003e","ID":"sha256:b3c2f0b5d2d8c9a8f8c0ad6c0e5c7a57a7e7f07d4e1f3b8b5ea0d6f6c4d2d1e0","Repository":"gcr.io/k8s-minikube/kicbase","SharedSize":"N/A","Size":"1.12GB","Tag":"v0.0.35","UniqueSize":"N/A","VirtualSize":"1.125GB"}
+`
+	inspect := `sha256:4d2edfd10d3e3a42e4f1e37ad5a06fde8ea8d3e4cc9a2b4c5f4a5bc4a5f3a1c2 128420567 linux/amd64/
+sha256:b3c2f0b5d2d8c9a8f8c0ad6c0e5c7a57a7e7f07d4e1f3b8b5ea0d6f6c4d2d1e0 1124877932 linux/arm64/v8
+sha256:c0ffee0000000000000000000000000000000000000000000000000000000000 2048 //
+`
+	df := `{"BuildCache":[],"Containers":[],"Images":[{"Containers":"1","CreatedAt":"2022-08-23 18:05:43 +0000 UTC","CreatedSince":"2 months ago","Digest":"\u003cnone\u003e","ID":"4d2edfd10d3e","Repository":"registry.k8s.io/kube-apiserver","SharedSize":"78.69MB","Size":"128.4MB","Tag":"v1.25.0","UniqueSize":"49.73MB","VirtualSize":"128.4MB"},{"Containers":"0","CreatedAt":"2022-10-14 21:22:38 +0000 UTC","CreatedSince":"3 days ago","Digest":"\u003cnone\u003e","ID":"b3c2f0b5d2d8","Repository":"gcr.io/k8s-minikube/kicbase","SharedSize":"N/A","Size":"1.125GB","Tag":"v0.0.35","UniqueSize":"N/A","VirtualSize":"1.125GB"}],"Volumes":[]}`
+
+	list, err := parseDockerImages(images)
+	if err != nil {
+		t.Fatalf("parseDockerImages: %v", err)
+	}
+	// the rounded sizes are only a fallback
+	if len(list) != 2 || list[0].Size != "128000000" || list[1].Size != "1120000000" {
+		t.Errorf("parseDockerImages() = %+v", list)
+	}
+
+	sizes, platforms, err := parseDockerImageInspect(inspect)
+	if err != nil {
+		t.Fatalf("parseDockerImageInspect: %v", err)
+	}
+	want := map[string]int64{
+		"4d2edfd10d3e3a42e4f1e37ad5a06fde8ea8d3e4cc9a2b4c5f4a5bc4a5f3a1c2": 128420567,
+		"b3c2f0b5d2d8c9a8f8c0ad6c0e5c7a57a7e7f07d4e1f3b8b5ea0d6f6c4d2d1e0": 1124877932,
+		"c0ffee0000000000000000000000000000000000000000000000000000000000": 2048,
+	}
+	if diff := cmp.Diff(want, sizes); diff != "" {
+		t.Errorf("parseDockerImageInspect() sizes mismatch (-want +got):\n%s", diff)
+	}
+	wantPlatforms := map[string]imagePlatform{
+		"4d2edfd10d3e3a42e4f1e37ad5a06fde8ea8d3e4cc9a2b4c5f4a5bc4a5f3a1c2": {OS: "linux", Architecture: "amd64"},
+		"b3c2f0b5d2d8c9a8f8c0ad6c0e5c7a57a7e7f07d4e1f3b8b5ea0d6f6c4d2d1e0": {OS: "linux", Architecture: "arm64", Variant: "v8"},
+		"c0ffee0000000000000000000000000000000000000000000000000000000000": {},
+	}
+	if diff := cmp.Diff(wantPlatforms, platforms); diff != "" {
+		t.Errorf("parseDockerImageInspect() platforms mismatch (-want +got):\n%s", diff)
+	}
+
+	shared, err := parseDockerSharedSizes(df)
+	if err != nil {
+		t.Fatalf("parseDockerSharedSizes: %v", err)
+	}
+	if diff := cmp.Diff(map[string]int64{"4d2edfd10d3e": 78690000}, shared); diff != "" {
+		t.Errorf("parseDockerSharedSizes() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, _, err := parseDockerImageInspect("sha256:abc 1.12GB linux/amd64/"); err == nil {
+		t.Errorf("parseDockerImageInspect() expected an error for a human readable size")
+	}
+}
+
+func TestLayersFromHistoryMismatch(t *testing.T) {
+	steps := []historyStep{{LayerInfo: LayerInfo{Size: 10}}, {LayerInfo: LayerInfo{Size: 20}}}
+	for _, l := range layersFromHistory(steps, []string{"sha256:aaa"}) {
+		if l.Digest != "" {
+			t.Errorf("expected no digest when the layers do not match the diff IDs, got %q", l.Digest)
+		}
+	}
+}
+
+func TestUniqueLayersSize(t *testing.T) {
+	base := LayerInfo{Digest: "sha256:base", Size: 100}
+	a := []LayerInfo{{Digest: "sha256:a", Size: 10}, {CreatedBy: "CMD", Size: 0}, base}
+	b := []LayerInfo{{Digest: "sha256:b", Size: 20}, base}
+	if got := UniqueLayersSize(a, b); got != 130 {
+		t.Errorf("UniqueLayersSize() = %d, want 130", got)
+	}
+}
+
+func TestContainerdLayerSizes(t *testing.T) {
+	diffIDs := []string{"sha256:aaa", "sha256:bbb"}
+	chains := chainIDs(diffIDs)
+	if chains[0] != "sha256:aaa" || len(chains) != 2 || chains[1] == "sha256:bbb" {
+		t.Fatalf("chainIDs() = %v", chains)
+	}
+	out := "KEY                      SIZE      INODES\n" +
+		chains[0] + "  7.3 MiB   521\n" +
+		chains[1] + "  8.0 KiB   2\n"
+	usage := parseSnapshotsUsage(out)
+	if usage[chains[0]] != 7654604 || usage[chains[1]] != 8*1024 {
+		t.Errorf("parseSnapshotsUsage() = %v", usage)
+	}
+}
+
+func TestEnableDockerRollback(t *testing.T) {
+	var tests = []struct {
+		failOn string
+		// wantUndo are the commands rolling back, in order
+		wantUndo []string
+		// wantRestored are the files restored
+		wantRestored []string
+	}{
+		{
+			failOn:       "systemctl start cri-docker.socket",
+			wantUndo:     []string{"sudo systemctl stop docker", "sudo systemctl mask docker.service", "sudo systemctl unmask containerd", "sudo systemctl start containerd", "sudo systemctl unmask crio", "sudo systemctl start crio"},
+			wantRestored: []string{"/etc/docker/daemon.json", "/etc/crictl.yaml"},
+		},
+		{
+			failOn:       "systemctl restart docker",
+			wantUndo:     []string{"sudo systemctl stop docker", "sudo systemctl mask docker.service", "sudo systemctl unmask containerd", "sudo systemctl start containerd", "sudo systemctl unmask crio", "sudo systemctl start crio"},
+			wantRestored: []string{"/etc/docker/daemon.json", "/etc/crictl.yaml"},
+		},
+		{
+			failOn:       "systemctl unmask docker.service",
+			wantUndo:     []string{"sudo systemctl mask docker.service", "sudo systemctl unmask containerd", "sudo systemctl start containerd", "sudo systemctl unmask crio", "sudo systemctl start crio"},
+			wantRestored: []string{"/etc/crictl.yaml"},
+		},
+		{
+			failOn:       "tee /etc/crictl.yaml",
+			wantUndo:     []string{"sudo systemctl unmask containerd", "sudo systemctl start containerd", "sudo systemctl unmask crio", "sudo systemctl start crio"},
+			wantRestored: []string{"/etc/crictl.yaml"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.failOn, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			for k, v := range map[string]serviceState{"docker": SvcExited, "containerd": SvcRunning, "crio": SvcRunning, "crio-shutdown": SvcExited, "cri-docker.socket": SvcExited} {
+				runner.services[k] = v
+			}
+			runner.masked["docker"] = true
+			cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.0")})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			runner.failOn = tc.failOn
+			err = cr.Enable(true, false, false)
+			if err == nil {
+				t.Fatalf("Enable() succeeded despite failing %q", tc.failOn)
+			}
+			if !strings.Contains(err.Error(), "injected failure") || !strings.Contains(err.Error(), "rolled back") {
+				t.Errorf("Enable() error %q does not wrap the failure and the rollback", err)
+			}
+
+			failed := -1
+			for i, c := range runner.history {
+				if strings.Contains(c, tc.failOn) {
+					failed = i
+				}
+			}
+			undo := []string{}
+			for _, c := range runner.history[failed+1:] {
+				if strings.HasPrefix(c, "sudo systemctl") && c != "sudo systemctl daemon-reload" {
+					undo = append(undo, c)
+				}
+			}
+			if diff := cmp.Diff(tc.wantUndo, undo); diff != "" {
+				t.Errorf("rollback commands (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantRestored, runner.copied); diff != "" {
+				t.Errorf("restored files (-want +got):\n%s", diff)
+			}
+			for _, svc := range []string{"containerd", "crio"} {
+				if runner.services[svc] != SvcRunning {
+					t.Errorf("%s was not restarted: %v", svc, runner.services[svc])
+				}
+			}
+		})
+	}
+}
+
+func TestEnableDockerGPUs(t *testing.T) {
+	runner := NewFakeRunner(t)
+	for k, v := range map[string]serviceState{"docker": SvcExited, "cri-docker.socket": SvcExited} {
+		runner.services[k] = v
+	}
+	cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.0"), GPUs: "all"})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	if err := cr.Enable(true, false, false); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	if !strings.Contains(runner.files["/etc/docker/daemon.json"], `"default-runtime": "nvidia"`) {
+		t.Errorf("nvidia is not the default runtime of daemon.json:\n%s", runner.files["/etc/docker/daemon.json"])
+	}
+
+	runner.failOn = "which nvidia-container-runtime"
+	err = cr.Enable(true, false, false)
+	if err == nil || !strings.Contains(err.Error(), "nvidia-container-toolkit") {
+		t.Errorf("Enable() without the NVIDIA container toolkit = %v, want an error naming it", err)
+	}
+}
+
+func TestEnableDockerSELinux(t *testing.T) {
+	var tests = []struct {
+		mode      string
+		bareMetal bool
+		relabel   bool
+		want      selinuxAction
+	}{
+		{mode: SELinuxEnforcing, bareMetal: true, want: selinuxFail},
+		{mode: SELinuxEnforcing, bareMetal: true, relabel: true, want: selinuxRelabel},
+		{mode: SELinuxEnforcing, want: selinuxIgnore},
+		{mode: SELinuxEnforcing, relabel: true, want: selinuxIgnore},
+		{mode: SELinuxPermissive, bareMetal: true, want: selinuxIgnore},
+		{mode: SELinuxPermissive, bareMetal: true, relabel: true, want: selinuxIgnore},
+		{mode: SELinuxPermissive, want: selinuxIgnore},
+		{mode: SELinuxDisabled, bareMetal: true, want: selinuxIgnore},
+		{mode: SELinuxDisabled, bareMetal: true, relabel: true, want: selinuxIgnore},
+		{mode: SELinuxDisabled, want: selinuxIgnore},
+		{mode: "", bareMetal: true, want: selinuxIgnore},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s/baremetal=%v/relabel=%v", tc.mode, tc.bareMetal, tc.relabel), func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.selinux = tc.mode
+			for k, v := range map[string]serviceState{"docker": SvcExited, "cri-docker.socket": SvcExited} {
+				runner.services[k] = v
+			}
+			cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.0"), BareMetal: tc.bareMetal, SELinuxRelabel: tc.relabel})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			err = cr.Enable(false, false, false)
+			history := strings.Join(runner.history, "\n")
+			if got := strings.Contains(history, "getenforce"); got != tc.bareMetal {
+				t.Errorf("getenforce run = %v, want %v", got, tc.bareMetal)
+			}
+			daemonJSON := runner.files["/etc/docker/daemon.json"]
+			switch tc.want {
+			case selinuxFail:
+				serr, ok := err.(*ErrSELinux)
+				if !ok {
+					t.Fatalf("Enable() = %v, want an ErrSELinux", err)
+				}
+				if cmds := strings.Join(serr.Commands(), "\n"); !strings.Contains(cmds, "semanage fcontext -a -t container_file_t '/var/lib/minikube/etcd(/.*)?'") {
+					t.Errorf("Commands() = %s, want the semanage rule of the etcd data", cmds)
+				}
+				if last := runner.history[len(runner.history)-1]; last != "getenforce" {
+					t.Errorf("Enable() ran %q after getenforce, want it to fail before changing anything", last)
+				}
+			case selinuxRelabel:
+				if err != nil {
+					t.Fatalf("Enable: %v", err)
+				}
+				for _, want := range []string{"sudo restorecon -R /var/lib/minikube/certs", "sudo restorecon -R /var/lib/minikube/etcd"} {
+					if !strings.Contains(history, want) {
+						t.Errorf("Enable() did not run %q:\n%s", want, history)
+					}
+				}
+				if !strings.Contains(daemonJSON, `"selinux-enabled": true`) {
+					t.Errorf("selinux-enabled is not set in daemon.json:\n%s", daemonJSON)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("Enable: %v", err)
+				}
+				if strings.Contains(history, "restorecon") || strings.Contains(daemonJSON, "selinux-enabled") {
+					t.Errorf("Enable() configured SELinux, want it left alone:\n%s\n%s", history, daemonJSON)
+				}
+			}
+			if got := selinuxDecision(tc.mode, tc.bareMetal, tc.relabel); got != tc.want {
+				t.Errorf("selinuxDecision(%q, %v, %v) = %v, want %v", tc.mode, tc.bareMetal, tc.relabel, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLabelSELinuxDirs(t *testing.T) {
+	runner := NewFakeRunner(t)
+	runner.failOn = "which semanage"
+	if err := labelSELinuxDirs(runner); err != nil {
+		t.Fatalf("labelSELinuxDirs: %v", err)
+	}
+	history := strings.Join(runner.history, "\n")
+	if want := "sudo chcon -R -t container_file_t /var/lib/minikube/certs"; !strings.Contains(history, want) {
+		t.Errorf("labelSELinuxDirs() without semanage did not run %q:\n%s", want, history)
+	}
+	if strings.Contains(history, "restorecon") {
+		t.Errorf("labelSELinuxDirs() without semanage ran restorecon:\n%s", history)
+	}
+}
+
+func TestValidateDaemonConfig(t *testing.T) {
+	var tests = []struct {
+		description string
+		legacy      bool
+		invalid     string
+		daemonJSON  string
+		wantErr     []string
+	}{
+		{description: "validated by dockerd", daemonJSON: `{"mtu":1400}`},
+		{description: "rejected by dockerd", invalid: "unable to configure the Docker daemon: mtu: invalid value", daemonJSON: `{"mtu":"big","foo":1}`, wantErr: []string{"mtu: invalid value", "unknown keys: foo"}},
+		{description: "known keys", legacy: true, daemonJSON: `{"mtu":1400,"features":{"buildkit":true}}`},
+		{description: "legacy keys", legacy: true, daemonJSON: `{"cluster-store":"consul://10.0.0.1:8500","cpu-rt-period":1000000,"graph":"/var/lib/docker"}`},
+		{description: "unknown keys", legacy: true, daemonJSON: `{"mtu":1400,"log-opt":{},"bips":"10.0.0.1/24"}`, wantErr: []string{"bips, log-opt"}},
+		{description: "malformed JSON", legacy: true, daemonJSON: `{"mtu":1400`, wantErr: []string{"not valid JSON"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.dockerdLegacy = tc.legacy
+			runner.dockerdInvalid = tc.invalid
+			runner.files = map[string]string{"/etc/docker/daemon.json": tc.daemonJSON}
+			r := &Docker{Runner: runner, OS: "linux"}
+			err := r.validateDaemonConfig()
+			if len(tc.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("validateDaemonConfig: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validateDaemonConfig succeeded, want an error with %q", tc.wantErr)
+			}
+			for _, want := range tc.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("validateDaemonConfig() = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEnableDockerInvalidConfig(t *testing.T) {
+	runner := NewFakeRunner(t)
+	runner.services["docker"] = SvcRunning
+	runner.files = map[string]string{"/etc/docker/daemon.json": `{"debug":true}`}
+	runner.dockerdInvalid = "unable to configure the Docker daemon: mtu: invalid value"
+	cr, err := New(Config{Type: "docker", Runner: runner, DockerMTU: 1400})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	err = cr.Enable(false, false, false)
+	if err == nil || !strings.Contains(err.Error(), "mtu: invalid value") {
+		t.Fatalf("Enable() = %v, want the validation error", err)
+	}
+	if got := runner.files["/etc/docker/daemon.json"]; got != `{"debug":true}` {
+		t.Errorf("daemon.json was not restored: %s", got)
+	}
+	// the rollback restarts docker with the restored daemon.json
+	for _, c := range runner.history {
+		if strings.HasPrefix(c, "sudo dockerd --validate") {
+			break
+		}
+		if c == "sudo systemctl restart docker" {
+			t.Errorf("docker was restarted with daemon.json before validating it")
+		}
+	}
+}
+
+func TestRollbackIncomplete(t *testing.T) {
+	rb := &rollback{}
+	undone := []string{}
+	_ = rb.run("first", func() error { return nil }, func() error {
+		undone = append(undone, "first")
+		return nil
+	})
+	_ = rb.run("second", func() error { return nil }, func() error { return fmt.Errorf("boom") })
+	err := rb.fail(rb.run("third", func() error { return fmt.Errorf("failed") }, nil))
+	if !strings.Contains(err.Error(), "rollback incomplete (second: boom)") || !strings.Contains(err.Error(), "third: failed") {
+		t.Errorf("fail() = %q", err)
+	}
+	if diff := cmp.Diff([]string{"first"}, undone); diff != "" {
+		t.Errorf("undone steps (-want +got):\n%s", diff)
+	}
+}
+
+func TestCLICommand(t *testing.T) {
+	var tests = []struct {
+		runtime string
+		socket  string
+		args    []string
+		want    string
+	}{
+		{"docker", "", []string{"ps", "-a"}, "docker ps -a"},
+		{"", "", []string{"exec", "-it", "abc", "sh"}, "docker exec -it abc sh"},
+		{"containerd", "", []string{"ps"}, "sudo crictl --runtime-endpoint unix:///run/containerd/containerd.sock ps"},
+		{"containerd", "/var/run/custom.sock", []string{"images"}, "sudo crictl --runtime-endpoint unix:///var/run/custom.sock images"},
+		{"crio", "", []string{"ps", "--format", "{{.Names}} {{.Status}}"}, "sudo podman ps --format '{{.Names}} {{.Status}}'"},
+		{"cri-o", "", []string{"images"}, "sudo podman images"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.want, func(t *testing.T) {
+			got, err := CLICommand(tc.runtime, tc.socket, semver.MustParse("1.25.3"), tc.args)
+			if err != nil {
+				t.Fatalf("CLICommand: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("CLICommand() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+	if _, err := CLICommand("rkt", "", semver.MustParse("1.25.3"), nil); err == nil {
+		t.Errorf("CLICommand(rkt) succeeded, want error")
+	}
+}
+
+func TestClassifyCLIError(t *testing.T) {
+	var tests = []struct {
+		name   string
+		stderr string
+		want   *reason.Kind
+	}{
+		{"no space", "write /var/lib/docker/tmp/GetImageBlob123: no space left on device", &reason.RuntimeNoSpace},
+		{"no space in layer", "failed to register layer: Error processing tar file(exit status 1): write /usr/lib/libc.so: No space left on device", &reason.RuntimeNoSpace},
+		{"authentication required", "Error response from daemon: Head \"https://registry.example.com/v2/app/manifests/v1\": unauthorized: authentication required", &reason.RuntimeAuthRequired},
+		{"no credentials", "Error response from daemon: Get \"https://123.dkr.ecr.us-east-1.amazonaws.com/v2/app/manifests/v1\": no basic auth credentials", &reason.RuntimeAuthRequired},
+		{"crictl unauthorized", `FATA[0001] pulling image: rpc error: code = Unknown desc = failed to pull and unpack image "ghcr.io/org/app:v1": failed to resolve reference "ghcr.io/org/app:v1": failed to authorize: failed to fetch anonymous token: unexpected status: 401 Unauthorized`, &reason.RuntimeAuthRequired},
+		{"manifest unknown", "Error response from daemon: manifest for busybox:nope not found: manifest unknown: manifest unknown", &reason.RuntimeImageNotFound},
+		{"repository does not exist", "Error response from daemon: pull access denied for nope, repository does not exist or may require 'docker login': denied: requested access to the resource is denied", &reason.RuntimeImageNotFound},
+		{"crictl not found", `FATA[0002] pulling image: rpc error: code = NotFound desc = failed to pull and unpack image "docker.io/library/busybox:nope": failed to resolve reference "docker.io/library/busybox:nope": docker.io/library/busybox:nope: not found`, &reason.RuntimeImageNotFound},
+		{"daemon not running", "Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?", &reason.RuntimeDaemonNotRunning},
+		{"crictl daemon not running", `FATA[0000] validate service connection: CRI v1 image API is not implemented for endpoint "unix:///run/containerd/containerd.sock": rpc error: code = Unavailable desc = connection error: desc = "transport: Error while dialing dial unix /run/containerd/containerd.sock: connect: no such file or directory"`, &reason.RuntimeDaemonNotRunning},
+		{"socket permission", "Got permission denied while trying to connect to the Docker daemon socket at unix:///var/run/docker.sock: Get \"http://%2Fvar%2Frun%2Fdocker.sock/v1.24/images/json\": dial unix /var/run/docker.sock: connect: permission denied", &reason.RuntimeSocketPermission},
+		{"network timeout", "Error response from daemon: Get \"https://registry-1.docker.io/v2/\": net/http: request canceled while waiting for connection (Client.Timeout exceeded while awaiting headers)", &reason.RuntimeNetworkTimeout},
+		{"dial timeout", "Error response from daemon: Get \"https://registry-1.docker.io/v2/\": dial tcp 54.236.113.205:443: i/o timeout", &reason.RuntimeNetworkTimeout},
+		{"tls handshake timeout", "Error response from daemon: Get \"https://k8s.gcr.io/v2/\": net/http: TLS handshake timeout", &reason.RuntimeNetworkTimeout},
+		{"unknown", "Error response from daemon: invalid reference format", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := errors.Wrap(fmt.Errorf("docker pull: Process exited with status 1\nstdout:\n\nstderr:\n%s", tc.stderr), "pull image docker")
+			got := classifyCLIError(err)
+			var cerr *ErrCLI
+			if !errors.As(got, &cerr) {
+				if tc.want != nil {
+					t.Fatalf("classifyCLIError(%q) = %v, want an ErrCLI of %s", tc.stderr, got, tc.want.ID)
+				}
+				if got != err {
+					t.Errorf("classifyCLIError(%q) = %v, want the error unchanged", tc.stderr, got)
+				}
+				return
+			}
+			if tc.want == nil {
+				t.Fatalf("classifyCLIError(%q) = %s, want the error unchanged", tc.stderr, cerr.Kind.ID)
+			}
+			if cerr.Kind.ID != tc.want.ID {
+				t.Errorf("classifyCLIError(%q) = %s, want %s", tc.stderr, cerr.Kind.ID, tc.want.ID)
+			}
+			if got.Error() != err.Error() {
+				t.Errorf("classifyCLIError changed the message to %q, want %q", got.Error(), err.Error())
+			}
+			if !errors.Is(got, errors.Cause(err)) {
+				t.Errorf("classifyCLIError(%q) does not wrap the error of the command", tc.stderr)
+			}
+		})
+	}
+	if err := classifyCLIError(nil); err != nil {
+		t.Errorf("classifyCLIError(nil) = %v, want nil", err)
+	}
+}
+
+func TestDockerCancel(t *testing.T) {
+	var tests = []struct {
+		name    string
+		blockOn string
+		unready int
+		run     func(context.Context, ContextManager) error
+		wantMsg string
+	}{
+		{"pull", "docker pull", 0, func(ctx context.Context, cm ContextManager) error { return cm.PullImageContext(ctx, "busybox") }, "pull image docker"},
+		{"load", "docker load", 0, func(ctx context.Context, cm ContextManager) error {
+			_, err := cm.LoadImageContext(ctx, "/tmp/busybox.tar")
+			return err
+		}, "loadimage docker"},
+		{"build", "docker build", 0, func(ctx context.Context, cm ContextManager) error {
+			return cm.BuildImageContext(ctx, "/tmp/build", BuildOptions{Tag: "app"})
+		}, "buildimage docker"},
+		{"restart", "", 1000, func(ctx context.Context, cm ContextManager) error { return cm.RestartContext(ctx) }, "waiting for docker"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.services["docker"] = SvcRunning
+			runner.blockOn = tc.blockOn
+			runner.unready = tc.unready
+			runner.blocked = make(chan struct{})
+			blocked := runner.blocked
+			if tc.blockOn == "" {
+				close(blocked)
+			}
+			cr, err := New(Config{Type: "docker", Runner: runner, RestartTimeout: time.Minute})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			cm, ok := cr.(ContextManager)
+			if !ok {
+				t.Fatalf("docker does not implement ContextManager")
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				<-blocked
+				time.Sleep(50 * time.Millisecond)
+				cancel()
+			}()
+			start := time.Now()
+			err = tc.run(ctx, cm)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("error = %v, want context.Canceled", err)
+			}
+			if !strings.Contains(err.Error(), tc.wantMsg) {
+				t.Errorf("error %q does not mention %q", err, tc.wantMsg)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("took %s to return after being cancelled", elapsed)
+			}
+		})
+	}
+}
+
+func TestDockerTimings(t *testing.T) {
+	trace.ResetTimings()
+	defer trace.ResetTimings()
+
+	runner := NewFakeRunner(t)
+	runner.delay = time.Millisecond
+	for _, svc := range []string{"docker", "cri-docker.socket", "cri-docker"} {
+		runner.services[svc] = SvcExited
+	}
+	cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.0")})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	if err := cr.Enable(false, true, false); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	if err := ConfigureNetworkPlugin(cr, runner, "cni"); err != nil {
+		t.Fatalf("ConfigureNetworkPlugin: %v", err)
+	}
+
+	got := map[string]time.Duration{}
+	for _, tm := range trace.Timings() {
+		got[tm.Phase] = tm.Duration
+	}
+	for _, phase := range []string{"docker.enable", "docker.force-systemd", "docker.restart", "docker.configure-network-plugin"} {
+		d, ok := got[phase]
+		if !ok {
+			t.Errorf("no timing recorded for %s, got %v", phase, got)
+			continue
+		}
+		if d <= 0 {
+			t.Errorf("timing of %s = %s, want a positive duration", phase, d)
+		}
+	}
+	if got["docker.enable"] < got["docker.restart"] {
+		t.Errorf("docker.enable took %s, less than the docker.restart it includes (%s)", got["docker.enable"], got["docker.restart"])
+	}
+}
+
+func TestValidateDockerBridge(t *testing.T) {
+	others := map[string]string{"service CIDR": "10.96.0.0/12", "pod CIDR": "10.244.0.0/16", "driver network": "192.168.49.0/24", "unset": ""}
+	var tests = []struct {
+		cidr    string
+		wantErr string
+	}{
+		{"172.30.0.0/16", ""},
+		{"10.200.0.0/24", ""},
+		{"10.100.0.0/24", "overlaps the service CIDR"},
+		{"10.244.5.0/24", "overlaps the pod CIDR"},
+		{"10.0.0.0/8", "overlaps the pod CIDR"},
+		{"192.168.0.0/16", "overlaps the driver network"},
+		{"2001:db8::/64", "not an IPv4 CIDR"},
+		{"10.200.0.0/31", "too small"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.cidr, func(t *testing.T) {
+			err := ValidateDockerBridge(tc.cidr, others)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateDockerBridge(%q) = %v, want no error", tc.cidr, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("ValidateDockerBridge(%q) = %v, want an error containing %q", tc.cidr, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDockerBridgeIP(t *testing.T) {
+	for cidr, want := range map[string]string{"10.200.0.0/24": "10.200.0.1/24", "10.200.0.5/24": "10.200.0.5/24"} {
+		got, err := DockerBridgeIP(cidr)
+		if err != nil {
+			t.Fatalf("DockerBridgeIP(%q): %v", cidr, err)
+		}
+		if got != want {
+			t.Errorf("DockerBridgeIP(%q) = %q, want %q", cidr, got, want)
+		}
+	}
+}
+
+func TestDockerBridgeRecreated(t *testing.T) {
+	runner := NewFakeRunner(t)
+	runner.services["docker"] = SvcRunning
+	runner.bridge = "172.17.0.1/16"
+	cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.23.8"), DockerBridgeCIDR: "10.200.0.0/24", DockerMTU: 1400})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	if err := cr.Enable(false, false, false); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	daemonJSON := runner.files["/etc/docker/daemon.json"]
+	if !strings.Contains(daemonJSON, `"bip": "10.200.0.1/24"`) || !strings.Contains(daemonJSON, `"mtu": 1400`) {
+		t.Errorf("daemon.json does not hold the bridge settings:\n%s", daemonJSON)
+	}
+	if runner.bridge != "" {
+		t.Errorf("the stale docker0 %s was not deleted", runner.bridge)
+	}
+
+	// docker recreated the bridge with the configured address, so enabling again changes nothing
+	runner.bridge = "10.200.0.1/24"
+	runner.copied = nil
+	runner.history = nil
+	if err := cr.Enable(false, false, false); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	if len(runner.copied) != 0 {
+		t.Errorf("unchanged bridge settings copied %v", runner.copied)
+	}
+	for _, cmd := range runner.history {
+		if strings.Contains(cmd, "ip link delete") {
+			t.Errorf("unchanged bridge settings ran %q", cmd)
+		}
+	}
+}
+
+func TestOffline(t *testing.T) {
+	defer func() { offlineProbes = map[string]offlineProbe{} }()
+	var tests = []struct {
+		description string
+		assume      bool
+		exitCode    int
+		want        bool
+		probes      int
+	}{
+		{"assumed", true, 0, true, 0},
+		{"reachable", false, 0, false, 1},
+		{"connection refused", false, 7, true, 1},
+		{"timed out", false, 28, true, 1},
+		{"tls error", false, 35, false, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			offlineProbes = map[string]offlineProbe{}
+			runner := NewFakeRunner(t)
+			runner.curlExitCode = tc.exitCode
+			for i := 0; i < 2; i++ {
+				if got := Offline(tc.assume, runner, "registry.k8s.io"); got != tc.want {
+					t.Errorf("Offline() = %v, want %v", got, tc.want)
+				}
+			}
+			probes := 0
+			for _, cmd := range runner.history {
+				if strings.HasPrefix(cmd, "curl") {
+					probes++
+				}
+			}
+			if probes != tc.probes {
+				t.Errorf("probed %d times, want %d", probes, tc.probes)
+			}
+		})
+	}
+}
+
+func TestOfflineProbeExpires(t *testing.T) {
+	defer func() { offlineProbes = map[string]offlineProbe{} }()
+	offlineProbes = map[string]offlineProbe{}
+	runner := NewFakeRunner(t)
+	runner.curlExitCode = 7
+	if !Offline(false, runner, "registry.k8s.io") {
+		t.Fatalf("Offline() = false for an unreachable registry")
+	}
+	// the registry became reachable after the result expired
+	offlineProbes["registry.k8s.io"] = offlineProbe{offline: true, at: time.Now().Add(-offlineProbeTTL)}
+	runner.curlExitCode = 0
+	if Offline(false, runner, "registry.k8s.io") {
+		t.Errorf("Offline() = true, want the expired result probed again")
+	}
+}
+
+func TestOfflineRuntime(t *testing.T) {
+	cc := config.ClusterConfig{Driver: "kvm2", KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.25.3"}}
+	for _, runtime := range []string{"docker", "containerd", "crio"} {
+		t.Run(runtime, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			cc.KubernetesConfig.ContainerRuntime = runtime
+			for _, offline := range []bool{false, true} {
+				cr, err := New(Config{Type: runtime, Runner: runner, KubernetesVersion: semver.MustParse("1.25.3"), Offline: offline})
+				if err != nil {
+					t.Fatalf("New(%s): %v", runtime, err)
+				}
+				runner.history = nil
+				err = cr.PullImage("busybox")
+				var offlineErr *ErrOffline
+				if offline != errors.As(err, &offlineErr) {
+					t.Errorf("PullImage() offline=%v returned %v", offline, err)
+				}
+				if offline && len(runner.history) != 0 {
+					t.Errorf("PullImage() ran %v while offline", runner.history)
+				}
+
+				// without preload, the images can not be loaded while offline
+				err = cr.Preload(cc)
+				if offline != errors.As(err, &offlineErr) {
+					t.Errorf("Preload() offline=%v returned %v", offline, err)
+				}
+				if offline && !strings.Contains(offlineErr.Error(), download.TarballName("v1.25.3", runtime)) {
+					t.Errorf("Preload() error %q does not name the preload", offlineErr)
+				}
+			}
+		})
+	}
+}
+
+func TestAliasRetags(t *testing.T) {
+	// flavor returns the images of a preload, which hold the images of a Kubernetes version in a registry
+	flavor := func(version, registry string) []string {
+		imgs, err := images.Kubeadm("", version)
+		if err != nil {
+			t.Fatalf("Kubeadm(%s): %v", version, err)
+		}
+		out := []string{}
+		for _, i := range imgs {
+			if alias := registryAlias(i); alias != "" && !strings.HasPrefix(i, registry+"/") {
+				i = alias
+			}
+			out = append(out, i)
+		}
+		return out
+	}
+	var tests = []struct {
+		version  string
+		registry string
+		retagged bool
+	}{
+		{"v1.24.0", images.OldDefaultKubernetesRepo, false},
+		{"v1.24.0", images.NewDefaultKubernetesRepo, true},
+		{"v1.25.0", images.NewDefaultKubernetesRepo, false},
+		{"v1.25.0", images.OldDefaultKubernetesRepo, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version+"/"+tc.registry, func(t *testing.T) {
+			want, err := images.Kubeadm("", tc.version)
+			if err != nil {
+				t.Fatalf("Kubeadm(%s): %v", tc.version, err)
+			}
+			present := flavor(tc.version, tc.registry)
+			retags := aliasRetags(want, present)
+			if !tc.retagged {
+				if len(retags) != 0 {
+					t.Errorf("aliasRetags() = %v, want none", retags)
+				}
+				return
+			}
+			if len(retags) == 0 {
+				t.Fatalf("aliasRetags() returned no tags")
+			}
+			have := map[string]bool{}
+			for _, p := range present {
+				have[p] = true
+			}
+			for _, r := range retags {
+				if !have[r.source] || !strings.HasPrefix(r.source, tc.registry+"/") {
+					t.Errorf("retag %v is not from the preloaded %s images", r, tc.registry)
+				}
+				have[r.target] = true
+			}
+			for _, w := range want {
+				if !have[w] {
+					t.Errorf("%s does not resolve after retagging", w)
+				}
+			}
+		})
+	}
+}
+
+func TestDockerPreloadedWithAliases(t *testing.T) {
+	runner := NewFakeRunner(t)
+	want := []string{"k8s.gcr.io/kube-apiserver:v1.24.0", "k8s.gcr.io/pause:3.7", "gcr.io/k8s-minikube/storage-provisioner:v5"}
+	for _, i := range []string{"registry.k8s.io/kube-apiserver:v1.24.0", "registry.k8s.io/pause:3.7", "gcr.io/k8s-minikube/storage-provisioner:v5"} {
+		runner.images[i] = i
+	}
+	cr, err := New(Config{Type: "docker", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	d := cr.(*Docker)
+	if dockerImagesPreloaded(runner, want) {
+		t.Fatalf("images preloaded before tagging the aliases")
+	}
+	if !d.preloadedWithAliases(want) {
+		t.Errorf("images not preloaded after tagging the aliases: %v", runner.images)
+	}
+	for _, c := range []string{"docker tag registry.k8s.io/kube-apiserver:v1.24.0 k8s.gcr.io/kube-apiserver:v1.24.0", "docker tag registry.k8s.io/pause:3.7 k8s.gcr.io/pause:3.7"} {
+		found := false
+		for _, h := range runner.history {
+			found = found || h == c
+		}
+		if !found {
+			t.Errorf("%q was not run, got %v", c, runner.history)
+		}
+	}
+}
+
+func TestContainerdMirrorsConfig(t *testing.T) {
+	got := containerdMirrorsConfig([]string{"http://host.minikube.internal:5000", "mirror.gcr.io"})
+	want := `server = "https://registry-1.docker.io"
+
+[host."http://host.minikube.internal:5000"]
+  capabilities = ["pull", "resolve"]
+
+[host."https://mirror.gcr.io"]
+  capabilities = ["pull", "resolve"]
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("containerdMirrorsConfig diff (-want +got):\n%s", diff)
+	}
+
+	runner := NewFakeRunner(t)
+	if err := configureContainerdMirrors(runner, nil); err != nil || len(runner.copied) != 0 {
+		t.Errorf("configureContainerdMirrors(nil) = %v, copied %v, want nothing written", err, runner.copied)
+	}
+	if err := configureContainerdMirrors(runner, []string{"http://host.minikube.internal:5000"}); err != nil {
+		t.Fatalf("configureContainerdMirrors: %v", err)
+	}
+	if diff := cmp.Diff([]string{"/etc/containerd/certs.d/docker.io/hosts.toml"}, runner.copied); diff != "" {
+		t.Errorf("copied files diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCRIDockerServiceConf(t *testing.T) {
+	const prefix = "[Service]\nExecStart=\nExecStart=/usr/bin/cri-dockerd --container-runtime-endpoint fd:// "
+	standard := "--network-plugin=cni --cni-bin-dir=/opt/cni/bin --cni-cache-dir=/var/lib/cni/cache --cni-conf-dir=/etc/cni/net.d --hairpin-mode=promiscuous-bridge"
+	var tests = []struct {
+		cni           string
+		networkPlugin string
+		want          string
+	}{
+		{"bridge", "cni", standard},
+		{"calico", "cni", standard},
+		{"cilium", "cni", standard},
+		{"flannel", "cni", standard},
+		{"kindnet", "cni", "--network-plugin=cni --cni-bin-dir=/opt/cni/bin --cni-cache-dir=/var/lib/cni/cache --cni-conf-dir=/etc/cni/net.mk --hairpin-mode=promiscuous-bridge"},
+		{"false", "", "--network-plugin="},
+		{"false", "cni", standard},
+		{"bridge", "kubenet", "--network-plugin=kubenet"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.cni+"-"+tc.networkPlugin, func(t *testing.T) {
+			cc := config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{CNI: tc.cni, NetworkPlugin: tc.networkPlugin, ContainerRuntime: "docker", KubernetesVersion: "v1.24.1"}}
+			s := cni.RuntimeSettings(cc)
+			got, err := criDockerServiceConf(criDockerActivatedEndpoint, tc.networkPlugin, &s, false)
+			if err != nil {
+				t.Fatalf("criDockerServiceConf: %v", err)
+			}
+			if diff := cmp.Diff(prefix+tc.want, string(got)); diff != "" {
+				t.Errorf("cri-docker drop-in mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDockerIPFamilies(t *testing.T) {
+	var tests = []struct {
+		description string
+		podCIDR     string
+		// dockerdVersion is the version of dockerd, 18.06.2-ce if empty
+		dockerdVersion string
+		// daemon are the IPv6 settings of daemon.json, none if empty
+		daemon    map[string]interface{}
+		dualStack bool
+	}{
+		{description: "single-stack IPv4", podCIDR: "10.244.0.0/16"},
+		{
+			description: "single-stack IPv6",
+			podCIDR:     "fd00:10:244::/56",
+			daemon:      map[string]interface{}{"ipv6": true, "fixed-cidr-v6": "fd00:10:244:ff::/64", "ip6tables": true, "experimental": true},
+		},
+		{
+			description: "dual-stack",
+			podCIDR:     "10.244.0.0/16,fd00:10:244::/56",
+			daemon:      map[string]interface{}{"ipv6": true, "fixed-cidr-v6": "fd00:10:244:ff::/64", "ip6tables": true, "experimental": true},
+			dualStack:   true,
+		},
+		{
+			description:    "dual-stack with docker 27",
+			podCIDR:        "10.244.0.0/16,fd00:10:244::/56",
+			dockerdVersion: "27.1.1",
+			daemon:         map[string]interface{}{"ipv6": true, "fixed-cidr-v6": "fd00:10:244:ff::/64", "ip6tables": true},
+			dualStack:      true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.services["cri-docker"] = SvcRunning
+			runner.dockerdVersion = tc.dockerdVersion
+			cr, err := New(Config{Type: "docker", Runner: runner, PodCIDR: tc.podCIDR, KubernetesVersion: semver.MustParse("1.24.1")})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+
+			settings, err := cr.(*Docker).daemonSettings(false)
+			if err != nil {
+				t.Fatalf("daemonSettings: %v", err)
+			}
+			merged, err := mergeDaemonConfig(nil, settings)
+			if err != nil {
+				t.Fatalf("mergeDaemonConfig: %v", err)
+			}
+			got := map[string]interface{}{}
+			if err := json.Unmarshal(merged, &got); err != nil {
+				t.Fatalf("daemon.json: %v", err)
+			}
+			want := tc.daemon
+			if want == nil {
+				want = map[string]interface{}{}
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("daemon.json mismatch (-want +got):\n%s", diff)
+			}
+
+			if err := ConfigureNetworkPlugin(cr, runner, "cni"); err != nil {
+				t.Fatalf("ConfigureNetworkPlugin: %v", err)
+			}
+			if got := strings.Contains(runner.files[criDockerServiceConfFile], " --ipv6-dual-stack"); got != tc.dualStack {
+				t.Errorf("cri-docker drop-in enables dual-stack = %v, want %v:\n%s", got, tc.dualStack, runner.files[criDockerServiceConfFile])
+			}
+		})
+	}
+}
+
+func TestDockerFixedCIDRv6(t *testing.T) {
+	var tests = []struct {
+		podCIDR string
+		want    string
+		wantErr bool
+	}{
+		{"fd00:10:244::/56", "fd00:10:244:ff::/64", false},
+		{"fd00::/48", "fd00:0:0:ffff::/64", false},
+		{"fd00:10:244::/64", "fd00:10:244::/64", false},
+		{"fd00:10:244::/112", "fd00:10:244::/112", false},
+		{"10.244.0.0/16", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.podCIDR, func(t *testing.T) {
+			got, err := dockerFixedCIDRv6(tc.podCIDR)
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Errorf("dockerFixedCIDRv6(%q) = %q, %v, want %q (error %v)", tc.podCIDR, got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCustomDockerCRISocket(t *testing.T) {
+	const socket = "/run/cri-dockerd-mk1.sock"
+	runner := NewFakeRunner(t)
+	runner.services["cri-docker"] = SvcRunning
+	cr, err := New(Config{Type: "docker", Runner: runner, Socket: socket, KubernetesVersion: semver.MustParse("1.24.1")})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	if got := cr.SocketPath(); got != socket {
+		t.Errorf("SocketPath() = %q, want %q", got, socket)
+	}
+	if got := cr.KubeletOptions()["container-runtime-endpoint"]; got != socket {
+		t.Errorf("KubeletOptions() container-runtime-endpoint = %q, want %q", got, socket)
+	}
+	if got := cr.(*Docker).CRIService; got != "cri-docker.service" {
+		t.Errorf("CRIService = %q, want cri-docker.service, which listens on the custom socket itself", got)
+	}
+
+	if err := ConfigureNetworkPlugin(cr, runner, "cni"); err != nil {
+		t.Fatalf("ConfigureNetworkPlugin: %v", err)
+	}
+	if !strings.Contains(runner.files[criDockerServiceConfFile], "--container-runtime-endpoint unix://"+socket+" ") {
+		t.Errorf("cri-docker drop-in does not serve %s:\n%s", socket, runner.files[criDockerServiceConfFile])
+	}
+	if !strings.Contains(strings.Join(runner.history, "\n"), "sudo test -d /run -a -w /run") {
+		t.Errorf("ConfigureNetworkPlugin did not check the directory of the socket, ran %v", runner.history)
+	}
+
+	runner.failOn = "sudo test -d"
+	err = ConfigureNetworkPlugin(cr, runner, "cni")
+	if err == nil || !strings.Contains(err.Error(), "/run of the CRI socket") {
+		t.Errorf("ConfigureNetworkPlugin with an unwritable socket directory = %v, want an error naming it", err)
+	}
+}
+
+func TestConfigureNetworkPluginChangedCNI(t *testing.T) {
+	runner := NewFakeRunner(t)
+	runner.services["cri-docker"] = SvcRunning
+	configure := func(name string) {
+		cc := config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{CNI: name, NetworkPlugin: "cni", ContainerRuntime: "docker", KubernetesVersion: "v1.24.1"}}
+		s := cni.RuntimeSettings(cc)
+		cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.1"), CNI: &s})
+		if err != nil {
+			t.Fatalf("New(docker): %v", err)
+		}
+		runner.services["cri-docker"] = SvcRunning
+		if err := ConfigureNetworkPlugin(cr, runner, "cni"); err != nil {
+			t.Fatalf("ConfigureNetworkPlugin: %v", err)
+		}
+	}
+
+	configure("bridge")
+	if runner.services["cri-docker"] != SvcRestarted {
+		t.Errorf("cri-docker is %v after writing the drop-in, want restarted", runner.services["cri-docker"])
+	}
+	configure("bridge")
+	if runner.services["cri-docker"] != SvcRunning {
+		t.Errorf("cri-docker is %v with an unchanged drop-in, want running", runner.services["cri-docker"])
+	}
+	configure("kindnet")
+	if runner.services["cri-docker"] != SvcRestarted {
+		t.Errorf("cri-docker is %v after changing the CNI, want restarted", runner.services["cri-docker"])
+	}
+	if conf := runner.files[criDockerServiceConfFile]; !strings.Contains(conf, "--cni-conf-dir=/etc/cni/net.mk") {
+		t.Errorf("cri-docker drop-in was not regenerated for kindnet:\n%s", conf)
+	}
+}
+
+func TestNormalizeImageRef(t *testing.T) {
+	var tests = []struct {
+		ref  string
+		want string
+	}{
+		{"busybox", "docker.io/library/busybox:latest"},
+		{"busybox:1.35", "docker.io/library/busybox:1.35"},
+		{"docker.io/library/busybox:1.35", "docker.io/library/busybox:1.35"},
+		{"index.docker.io/kubernetesui/dashboard:v2.6.0", "docker.io/kubernetesui/dashboard:v2.6.0"},
+		{"kubernetesui/dashboard", "docker.io/kubernetesui/dashboard:latest"},
+		{"registry.k8s.io/pause:3.7", "registry.k8s.io/pause:3.7"},
+		{"localhost:5000/app", "localhost:5000/app:latest"},
+		{"localhost/app:1", "localhost/app:1"},
+		{"myregistry:443/team/app", "myregistry:443/team/app:latest"},
+		{"Invalid/Image", "Invalid/Image"},
+		{"gcr.io/k8s-minikube/storage-provisioner:v5@sha256:18eb69d1418e854ad5a19e399310e52808a8321e4c441c1dddad8977a0d7a944", "gcr.io/k8s-minikube/storage-provisioner@sha256:18eb69d1418e854ad5a19e399310e52808a8321e4c441c1dddad8977a0d7a944"},
+	}
+	for _, tc := range tests {
+		if got := normalizeImageRef(tc.ref); got != tc.want {
+			t.Errorf("normalizeImageRef(%q) = %q, want %q", tc.ref, got, tc.want)
+		}
+	}
+}
+
+func TestParseLoadedImages(t *testing.T) {
+	var tests = []struct {
+		description string
+		out         string
+		want        []string
+	}{
+		{"docker", "Loaded image: busybox:latest\n", []string{"busybox:latest"}},
+		{"docker several", "Loaded image: busybox:latest\nLoaded image: registry.k8s.io/pause:3.7\n", []string{"busybox:latest", "registry.k8s.io/pause:3.7"}},
+		{"docker untagged", "Loaded image ID: sha256:beae173ccac6ad749f76713cf4440fe3d21d1043fe616dfbe30775815d1d0f6a\n", []string{"sha256:beae173ccac6ad749f76713cf4440fe3d21d1043fe616dfbe30775815d1d0f6a"}},
+		{"podman", "Getting image source signatures\nCopying blob 01fd6df81c8e done\nCopying config beae173cca done\nWriting manifest to image destination\nStoring signatures\nLoaded image(s): docker.io/library/busybox:latest,registry.k8s.io/pause:3.7\n", []string{"docker.io/library/busybox:latest", "registry.k8s.io/pause:3.7"}},
+		{"podman 4", "Loaded image: docker.io/library/busybox:latest\n", []string{"docker.io/library/busybox:latest"}},
+		{"ctr", "unpacking docker.io/library/busybox:latest (sha256:7b3ccabffc97de872a30dfd234fd972a66d247c8cfc69b0550f276481852627c)...done\n", []string{"docker.io/library/busybox:latest"}},
+		{"ctr several", "unpacking docker.io/library/busybox:latest (sha256:7b3ccabffc97de872a30dfd234fd972a66d247c8cfc69b0550f276481852627c)...done\nunpacking registry.k8s.io/pause:3.7 (sha256:bb6ed397957e9ca7c65ada0db5c5d1c707c9c8afc80a94acbe69f3ae76988f0c)...done\n", []string{"docker.io/library/busybox:latest", "registry.k8s.io/pause:3.7"}},
+		{"empty", "", []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, parseLoadedImages(tc.out)); diff != "" {
+				t.Errorf("parseLoadedImages() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImagesExist(t *testing.T) {
+	names := []string{"busybox", "docker.io/library/nginx:1.23", "registry.k8s.io/pause:3.7", "registry.k8s.io/etcd:3.5.3-0", "kubernetesui/dashboard:v2.6.0"}
+	want := map[string]bool{"busybox": true, "docker.io/library/nginx:1.23": true, "registry.k8s.io/pause:3.7": true, "registry.k8s.io/etcd:3.5.3-0": false, "kubernetesui/dashboard:v2.6.0": false}
+	for _, runtime := range []string{"docker", "crio", "containerd"} {
+		t.Run(runtime, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			for _, name := range []string{"docker.io/library/busybox:latest", "nginx:1.23", "registry.k8s.io/pause:3.7", "kubernetesui/dashboard:v2.5.1"} {
+				runner.images[name] = name
+			}
+			cr, err := New(Config{Type: runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", runtime, err)
+			}
+			runner.history = nil
+			got, err := cr.ImagesExist(names)
+			if err != nil {
+				t.Fatalf("ImagesExist: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ImagesExist() mismatch (-want +got):\n%s", diff)
+			}
+			if len(runner.history) != 1 {
+				t.Errorf("ImagesExist ran %d commands for %d images, want 1: %v", len(runner.history), len(names), runner.history)
+			}
+		})
+	}
+}
+
+func TestSyncedDropInPreserved(t *testing.T) {
+	const userDropIn = "/etc/systemd/system/cri-docker.service.d/20-limits.conf"
+	runner := NewFakeRunner(t)
+	runner.services["docker"] = SvcRunning
+	runner.files = map[string]string{
+		// written by an older version
+		legacyCRIDockerServiceConfFile: criDockerServiceConfHeader + "cni",
+		// synced from ~/.minikube/files before the runtime is enabled
+		userDropIn: "[Service]\nLimitNOFILE=1048576\n",
+	}
+	runner.needReload = map[string]bool{"cri-docker": true}
+
+	start := func() {
+		runner.services["cri-docker"] = SvcRunning
+		runner.services["cri-docker.socket"] = SvcRunning
+		cr, err := New(Config{Type: "docker", Runner: runner, Socket: "/var/run/cri-dockerd.sock", KubernetesVersion: semver.MustParse("1.24.1")})
+		if err != nil {
+			t.Fatalf("New(docker): %v", err)
+		}
+		if err := ConfigureNetworkPlugin(cr, runner, "cni"); err != nil {
+			t.Fatalf("ConfigureNetworkPlugin: %v", err)
+		}
+		if err := cr.Enable(false, false, false); err != nil {
+			t.Fatalf("Enable: %v", err)
+		}
+	}
+
+	start()
+	if _, ok := runner.files[legacyCRIDockerServiceConfFile]; ok {
+		t.Errorf("legacy drop-in %s was kept", legacyCRIDockerServiceConfFile)
+	}
+	if !strings.HasPrefix(runner.files[criDockerServiceConfFile], criDockerServiceConfHeader) {
+		t.Errorf("%s = %q, want the network plugin drop-in", criDockerServiceConfFile, runner.files[criDockerServiceConfFile])
+	}
+	if runner.services["cri-docker"] != SvcRestarted {
+		t.Errorf("cri-docker is %v after its drop-ins changed, want restarted", runner.services["cri-docker"])
+	}
+	if runner.files[userDropIn] != "[Service]\nLimitNOFILE=1048576\n" {
+		t.Errorf("user drop-in was changed to %q", runner.files[userDropIn])
+	}
+
+	// the user changes the drop-in, which is synced again by the second start
+	runner.files[userDropIn] = "[Service]\nLimitNOFILE=65536\n"
+	runner.needReload = map[string]bool{"cri-docker": true}
+	start()
+	if runner.services["cri-docker"] != SvcRestarted {
+		t.Errorf("cri-docker is %v after a synced drop-in changed, want restarted", runner.services["cri-docker"])
+	}
+	if runner.files[userDropIn] != "[Service]\nLimitNOFILE=65536\n" {
+		t.Errorf("user drop-in was changed to %q", runner.files[userDropIn])
+	}
+
+	start()
+	if runner.services["cri-docker"] != SvcRunning {
+		t.Errorf("cri-docker is %v with unchanged drop-ins, want running", runner.services["cri-docker"])
+	}
+}
+
+func TestMergeCRIDockerExecStart(t *testing.T) {
+	conf, err := criDockerServiceConf(criDockerActivatedEndpoint, "cni", nil, false)
+	if err != nil {
+		t.Fatalf("criDockerServiceConf: %v", err)
+	}
+	execStart := string(conf[strings.LastIndex(string(conf), "\n")+1:])
+	tests := []struct {
+		name        string
+		dropIns     []string
+		wantFlags   string
+		wantDropped []string
+	}{
+		{
+			name:    "no override",
+			dropIns: []string{"[Service]\nLimitNOFILE=1048576\n"},
+		},
+		{
+			name:        "reset and override",
+			dropIns:     []string{"[Service]\nExecStart=\nExecStart=/usr/bin/cri-dockerd --container-runtime-endpoint fd:// --network-plugin=kubenet --log-level=debug\n"},
+			wantFlags:   " --log-level=debug",
+			wantDropped: []string{"--container-runtime-endpoint fd://", "--network-plugin=kubenet"},
+		},
+		{
+			name:      "override without reset",
+			dropIns:   []string{"[Service]\nExecStart=/usr/bin/cri-dockerd --pod-infra-container-image registry.k8s.io/pause:3.9 --hairpin-mode=none\n"},
+			wantFlags: " --pod-infra-container-image registry.k8s.io/pause:3.9", wantDropped: []string{"--hairpin-mode=none"},
+		},
+		{
+			name: "later drop-in replaces a flag",
+			dropIns: []string{
+				"[Service]\nExecStart=\nExecStart=/usr/bin/cri-dockerd --log-level=debug --cri-dockerd-root-directory=/var/lib/cri\n",
+				"[Service]\nExecStart=\nExecStart=/usr/bin/cri-dockerd --log-level=info\n",
+			},
+			wantFlags: " --log-level=info --cri-dockerd-root-directory=/var/lib/cri",
+		},
+		{
+			name:      "continued lines",
+			dropIns:   []string{"[Service]\nExecStart=\nExecStart=/usr/bin/cri-dockerd \\\n  --log-level=debug \\\n  --network-plugin=cni\n"},
+			wantFlags: " --log-level=debug", wantDropped: []string{"--network-plugin=cni"},
+		},
+		{
+			name:      "merged before",
+			dropIns:   []string{"[Service]\n" + mergedExecStartMarker + "ExecStart=\n" + mergedExecStartMarker + "ExecStart=/usr/bin/cri-dockerd --log-level=debug\n"},
+			wantFlags: " --log-level=debug",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := []string{}
+			for _, d := range tc.dropIns {
+				settings = append(settings, execStarts(d)...)
+			}
+			merged, dropped := mergeCRIDockerExecStart(conf, settings)
+			got := string(merged[strings.LastIndex(string(merged), "\n")+1:])
+			if want := execStart + tc.wantFlags; got != want {
+				t.Errorf("merged ExecStart = %q, want %q", got, want)
+			}
+			if diff := cmp.Diff(tc.wantDropped, dropped, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("dropped flags mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCRIDockerDropInConflicts(t *testing.T) {
+	const (
+		userDropIn = "/etc/systemd/system/cri-docker.service.d/20-debug.conf"
+		userConf   = "[Service]\nExecStart=\nExecStart=/usr/bin/cri-dockerd --container-runtime-endpoint fd:// --log-level=debug\nLimitNOFILE=1048576\n"
+	)
+	runner := NewFakeRunner(t)
+	runner.services["cri-docker"] = SvcRunning
+	runner.files = map[string]string{
+		legacyCRIDockerServiceConfFile: criDockerServiceConfHeader + "cni",
+		userDropIn:                     userConf,
+	}
+	cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.1")})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	if err := ConfigureNetworkPlugin(cr, runner, "cni"); err != nil {
+		t.Fatalf("ConfigureNetworkPlugin: %v", err)
+	}
+
+	if _, ok := runner.files[legacyCRIDockerServiceConfFile]; ok {
+		t.Errorf("drop-in %s of an older version was kept", legacyCRIDockerServiceConfFile)
+	}
+	conf := runner.files[criDockerServiceConfFile]
+	if !strings.HasSuffix(conf, " --hairpin-mode=promiscuous-bridge --log-level=debug") {
+		t.Errorf("%s = %q, want the flags of %s merged", criDockerServiceConfFile, conf, userDropIn)
+	}
+	if strings.Count(conf, "--container-runtime-endpoint") != 1 {
+		t.Errorf("%s = %q, want a single endpoint", criDockerServiceConfFile, conf)
+	}
+	user := runner.files[userDropIn]
+	if diff := cmp.Diff(execStarts(userConf), execStarts(user)); diff != "" {
+		t.Errorf("the ExecStart of %s was lost (-want +got):\n%s", userDropIn, diff)
+	}
+	for _, l := range strings.Split(user, "\n") {
+		if strings.HasPrefix(l, "ExecStart=") {
+			t.Errorf("%s still overrides ExecStart: %q", userDropIn, user)
+		}
+	}
+	if !strings.Contains(user, "\nLimitNOFILE=1048576\n") {
+		t.Errorf("%s lost its other settings: %q", userDropIn, user)
+	}
+	reload, restart := -1, -1
+	for i, c := range runner.history {
+		switch c {
+		case "sudo systemctl daemon-reload":
+			reload = i
+		case "sudo systemctl restart cri-docker":
+			restart = i
+		}
+	}
+	if restart == -1 || reload == -1 || reload > restart {
+		t.Errorf("want a daemon-reload before restarting cri-docker, got %v", runner.history)
+	}
+
+	// the drop-in of the user is synced again by the next start
+	runner.files[userDropIn] = userConf
+	runner.history = nil
+	if err := ConfigureNetworkPlugin(cr, runner, "cni"); err != nil {
+		t.Fatalf("ConfigureNetworkPlugin: %v", err)
+	}
+	if runner.files[criDockerServiceConfFile] != conf {
+		t.Errorf("%s = %q after syncing %s again, want %q", criDockerServiceConfFile, runner.files[criDockerServiceConfFile], userDropIn, conf)
+	}
+
+	runner.history = nil
+	if err := ConfigureNetworkPlugin(cr, runner, "cni"); err != nil {
+		t.Fatalf("ConfigureNetworkPlugin: %v", err)
+	}
+	for _, c := range runner.history {
+		if c == "sudo systemctl restart cri-docker" {
+			t.Errorf("cri-docker was restarted with unchanged drop-ins")
+		}
+	}
+}
+
+func TestMatchReference(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		ref     string
+		want    bool
+	}{
+		{"registry.k8s.io/*", "registry.k8s.io/pause:3.7", true},
+		{"registry.k8s.io/*", "registry.k8s.io/coredns/coredns:v1.9.3", false},
+		{"registry.k8s.io/*/*", "registry.k8s.io/coredns/coredns:v1.9.3", true},
+		{"registry.k8s.io/pause:3.*", "registry.k8s.io/pause:3.7", true},
+		{"busybox", "docker.io/library/busybox:latest", true},
+		{"busybox:*", "docker.io/library/busybox:1.35", true},
+		{"kubernetesui/*", "docker.io/kubernetesui/dashboard:v2.6.0", true},
+		{"docker.io/library/*", "busybox:latest", true},
+		{"localhost:5000/*", "localhost:5000/app:1.0", true},
+		{"localhost:5000/app", "localhost:5000/app:1.0", true},
+		{"localhost:5000/app:1.*", "localhost:5000/app:1.0", true},
+		{"localhost:*/app", "localhost:5000/app:1.0", true},
+		{"localhost:5000/*", "localhost:5001/app:1.0", false},
+		{"localhost:5000", "localhost:5000/app:1.0", false},
+		{"localhost*", "localhost:5000/app:1.0", false},
+		{"registry.k8s.io/pause", "registry.k8s.io/pause@sha256:bb6ed397957e9ca7c65ada0db5c5d1c707c9c8afc80a94acbe69f3ae76988f0c", true},
+	}
+	for _, tc := range tests {
+		if got := matchReference(tc.pattern, tc.ref); got != tc.want {
+			t.Errorf("matchReference(%q, %q) = %t, want %t", tc.pattern, tc.ref, got, tc.want)
+		}
+	}
+}
+
+func TestFilterImages(t *testing.T) {
+	images := []ListImage{
+		{ID: "1", RepoTags: []string{"registry.k8s.io/pause:3.7", "localhost:5000/pause:3.7"}},
+		{ID: "2", RepoTags: []string{"docker.io/library/busybox:latest"}, RepoDigests: []string{"docker.io/library/busybox@sha256:abc"}},
+		{ID: "3", RepoDigests: []string{"localhost:5000/app@sha256:def"}},
+	}
+	yes, no := true, false
+	var tests = []struct {
+		description string
+		opts        ListImagesOptions
+		want        []ListImage
+		wantErr     bool
+	}{
+		{description: "no filter", want: images},
+		{description: "registry", opts: ListImagesOptions{Reference: "registry.k8s.io/*"}, want: []ListImage{{ID: "1", RepoTags: []string{"registry.k8s.io/pause:3.7"}}}},
+		{description: "registry port", opts: ListImagesOptions{Reference: "localhost:5000/*"}, want: []ListImage{{ID: "1", RepoTags: []string{"localhost:5000/pause:3.7"}}, images[2]}},
+		{description: "familiar name", opts: ListImagesOptions{Reference: "busybox"}, want: images[1:2]},
+		{description: "dangling", opts: ListImagesOptions{Dangling: &yes}, want: images[2:]},
+		{description: "not dangling", opts: ListImagesOptions{Dangling: &no}, want: images[:2]},
+		{description: "dangling reference", opts: ListImagesOptions{Reference: "localhost:5000/*", Dangling: &yes}, want: images[2:]},
+		{description: "no match", opts: ListImagesOptions{Reference: "quay.io/*"}, want: []ListImage{}},
+		{description: "bad pattern", opts: ListImagesOptions{Reference: "registry.k8s.io/[pause"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := FilterImages(images, tc.opts)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("FilterImages() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); !tc.wantErr && diff != "" {
+				t.Errorf("FilterImages() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterImagesPlatform(t *testing.T) {
+	images := []ListImage{
+		{ID: "1", OS: "linux", Architecture: "amd64"},
+		{ID: "2", OS: "linux", Architecture: "arm64"},
+		{ID: "3", OS: "linux", Architecture: "arm", Variant: "v7"},
+		{ID: "4"},
+	}
+	var tests = []struct {
+		platform string
+		want     []ListImage
+	}{
+		{platform: "linux/amd64", want: images[:1]},
+		{platform: "linux/arm64/v8", want: images[1:2]},
+		{platform: "linux/arm", want: images[2:3]},
+		{platform: "linux/arm/v6", want: []ListImage{}},
+		{platform: "windows/amd64", want: []ListImage{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.platform, func(t *testing.T) {
+			got, err := FilterImages(images, ListImagesOptions{Platform: tc.platform})
+			if err != nil {
+				t.Fatalf("FilterImages: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("FilterImages() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDockerImagesArgs(t *testing.T) {
+	dangling := true
+	got, err := dockerImagesArgs(ListImagesOptions{Reference: "localhost:5000/*", Dangling: &dangling})
+	if err != nil {
+		t.Fatalf("dockerImagesArgs: %v", err)
+	}
+	want := []string{"images", "--no-trunc", "--format", "{{json .}}", "--filter", "reference=localhost:5000/*", "--filter", "dangling=true"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("dockerImagesArgs() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := dockerImagesArgs(ListImagesOptions{Reference: "["}); err == nil {
+		t.Errorf("dockerImagesArgs accepted an invalid pattern")
+	}
+}
+
+func TestListImagesReference(t *testing.T) {
+	for _, runtime := range []string{"crio", "containerd"} {
+		t.Run(runtime, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			for _, name := range []string{"registry.k8s.io/pause:3.7", "localhost:5000/app:1.0", "docker.io/library/busybox:latest"} {
+				runner.images[name] = name
+			}
+			cr, err := New(Config{Type: runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", runtime, err)
+			}
+			got, err := cr.ListImages(ListImagesOptions{Reference: "localhost:5000/*"})
+			if err != nil {
+				t.Fatalf("ListImages: %v", err)
+			}
+			want := []ListImage{{ID: "localhost:5000/app:1.0", RepoTags: []string{"localhost:5000/app:1.0"}, RepoDigests: []string{}, Size: "0"}}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ListImages() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListImagesPlatform(t *testing.T) {
+	for _, runtime := range []string{"crio", "containerd"} {
+		t.Run(runtime, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			for _, name := range []string{"registry.k8s.io/pause:3.7", "localhost:5000/app:1.0", "localhost:5000/scratch:1.0"} {
+				runner.images[name] = name
+			}
+			runner.platforms = map[string]string{
+				"registry.k8s.io/pause:3.7": "linux/amd64",
+				"localhost:5000/app:1.0":    "linux/arm64/v8",
+			}
+			cr, err := New(Config{Type: runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", runtime, err)
+			}
+			images, err := cr.ListImages(ListImagesOptions{})
+			if err != nil {
+				t.Fatalf("ListImages: %v", err)
+			}
+			got := map[string]string{}
+			for _, img := range images {
+				got[img.ID] = img.Platform()
+			}
+			want := map[string]string{
+				"registry.k8s.io/pause:3.7":  "linux/amd64",
+				"localhost:5000/app:1.0":     "linux/arm64/v8",
+				"localhost:5000/scratch:1.0": "",
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ListImages() platforms mismatch (-want +got):\n%s", diff)
+			}
+			inspections := 0
+			for _, cmd := range runner.history {
+				if strings.Contains(cmd, "inspecti") {
+					inspections++
+				}
+			}
+			if inspections != 1 {
+				t.Errorf("ListImages() inspected the images %d times, want 1: %v", inspections, runner.history)
+			}
+
+			images, err = cr.ListImages(ListImagesOptions{Platform: "linux/arm64"})
+			if err != nil {
+				t.Fatalf("ListImages: %v", err)
+			}
+			if len(images) != 1 || images[0].ID != "localhost:5000/app:1.0" {
+				t.Errorf("ListImages(linux/arm64) = %+v, want localhost:5000/app:1.0", images)
+			}
+		})
+	}
+}
+
+func TestParseCRIImagePlatforms(t *testing.T) {
+	containerd := `{"status":{"id":"sha256:aaa"},"info":{"imageSpec":{"os":"linux","architecture":"arm64","variant":"v8"}}}
+{"status":{"id":"sha256:bbb"},"info":{"imageSpec":{"rootfs":{"type":"layers"}}}}`
+	crio := `{"status":{"id":"ccc"},"info":{"info":"{\"imageSpec\":{\"os\":\"linux\",\"architecture\":\"amd64\"}}"}}`
+	got, err := parseCRIImagePlatforms([]byte(containerd + "\n" + crio))
+	if err != nil {
+		t.Fatalf("parseCRIImagePlatforms: %v", err)
+	}
+	want := map[string]imagePlatform{
+		"aaa": {OS: "linux", Architecture: "arm64", Variant: "v8"},
+		"bbb": {},
+		"ccc": {OS: "linux", Architecture: "amd64"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseCRIImagePlatforms() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := parseCRIImagePlatforms([]byte(`{"status":`)); err == nil {
+		t.Errorf("parseCRIImagePlatforms() expected an error for truncated output")
+	}
+}
+
+func TestImagePlatform(t *testing.T) {
+	var tests = []struct {
+		img     ListImage
+		want    string
+		foreign bool
+	}{
+		{img: ListImage{OS: "linux", Architecture: "amd64"}, want: "linux/amd64"},
+		{img: ListImage{OS: "linux", Architecture: "arm", Variant: "v7"}, want: "linux/arm/v7", foreign: true},
+		{img: ListImage{Architecture: "arm64"}, want: "linux/arm64", foreign: true},
+		{img: ListImage{}, want: ""},
+	}
+	for _, tc := range tests {
+		if got := tc.img.Platform(); got != tc.want {
+			t.Errorf("Platform(%+v) = %q, want %q", tc.img, got, tc.want)
+		}
+		if got := tc.img.ForeignArch("amd64"); got != tc.foreign {
+			t.Errorf("ForeignArch(%+v) = %t, want %t", tc.img, got, tc.foreign)
+		}
+	}
+	for _, p := range []string{"linux", "linux/", "/amd64", "linux/arm/v7/x"} {
+		if err := ValidatePlatform(p); err == nil {
+			t.Errorf("ValidatePlatform(%q) expected an error", p)
+		}
+	}
+	if err := ValidatePlatform("linux/arm/v7"); err != nil {
+		t.Errorf("ValidatePlatform(linux/arm/v7): %v", err)
+	}
+}
+
+func TestNodeArch(t *testing.T) {
+	runner := NewFakeRunner(t)
+	got, err := NodeArch(runner)
+	if err != nil {
+		t.Fatalf("NodeArch: %v", err)
+	}
+	if got != "arm64" {
+		t.Errorf("NodeArch() = %q, want arm64", got)
+	}
+}
+
+func TestImageArchiveCommands(t *testing.T) {
+	const (
+		name = `example.com/it's/my "app":v1`
+		path = "/tmp/my images/ärchive.tar"
+	)
+	var tests = []struct {
+		runtime string
+		os      string
+		loadCmd []string
+		saveCmd []string
+		loaded  []string
+	}{
+		{
+			runtime: "docker",
+			os:      "linux",
+			loadCmd: []string{"/bin/bash", "-c", `sudo cat '/tmp/my images/ärchive.tar' | docker load`},
+			saveCmd: []string{"/bin/bash", "-c", `docker save 'example.com/it'\''s/my "app":v1' | sudo tee '/tmp/my images/ärchive.tar' >/dev/null`},
+			loaded:  []string{},
+		},
+		{
+			runtime: "docker",
+			os:      "windows",
+			loadCmd: []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", `docker load -i '/tmp/my images/ärchive.tar'`},
+			saveCmd: []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", `docker save -o '/tmp/my images/ärchive.tar' 'example.com/it''s/my "app":v1'`},
+			loaded:  []string{},
+		},
+		{
+			runtime: "containerd",
+			os:      "linux",
+			loadCmd: []string{"sudo", "ctr", "-n=k8s.io", "images", "import", path},
+			saveCmd: []string{"sudo", "ctr", "-n=k8s.io", "images", "export", path, name},
+			loaded:  []string{"docker.io/library/busybox:latest"},
+		},
+		{
+			runtime: "crio",
+			os:      "linux",
+			loadCmd: []string{"sudo", "podman", "load", "-i", path},
+			saveCmd: []string{"sudo", "podman", "save", name, "-o", path},
+			loaded:  []string{"docker.io/library/busybox:latest"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.runtime+"-"+tc.os, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.os = tc.os
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			if r, ok := cr.(*Docker); ok {
+				// detect the OS before recording the commands
+				r.osProfile()
+			}
+
+			runner.cmds = []string{}
+			loaded, err := cr.LoadImage(path)
+			if err != nil {
+				t.Fatalf("LoadImage: %v", err)
+			}
+			if diff := cmp.Diff(tc.loaded, loaded); diff != "" {
+				t.Errorf("LoadImage loaded images diff (-want +got):\n%s", diff)
+			}
+			// the first command gets the size of the archive to report progress
+			if diff := cmp.Diff(tc.loadCmd, runner.cmds[len(runner.cmds)-len(tc.loadCmd):]); diff != "" {
+				t.Errorf("LoadImage commands diff (-want +got):\n%s", diff)
+			}
+
+			runner.cmds = []string{}
+			if err := cr.SaveImage(name, path); err != nil {
+				t.Fatalf("SaveImage: %v", err)
+			}
+			if diff := cmp.Diff(tc.saveCmd, runner.cmds); diff != "" {
+				t.Errorf("SaveImage commands diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPowershellQuote(t *testing.T) {
+	var tests = []struct {
+		s    string
+		want string
+	}{
+		{"busybox", "'busybox'"},
+		{`C:\Users\my user\img.tar`, `'C:\Users\my user\img.tar'`},
+		{"it's", "'it''s'"},
+		{"it’s", "'it’’s'"},
+		{"$env:TEMP\\ü.tar", "'$env:TEMP\\ü.tar'"},
+	}
+	for _, tc := range tests {
+		if got := powershellQuote(tc.s); got != tc.want {
+			t.Errorf("powershellQuote(%q) = %q, want %q", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestExportImageStore(t *testing.T) {
+	var tests = []struct {
+		runtime   string
+		exportCmd string
+		importCmd string
+	}{
+		{
+			runtime:   "containerd",
+			exportCmd: "sudo ctr -n=k8s.io images export /tmp/store.tar docker.io/library/busybox:latest registry.k8s.io/pause:3.7",
+			importCmd: "sudo ctr -n=k8s.io images import /tmp/store.tar",
+		},
+		{
+			runtime:   "crio",
+			exportCmd: "sudo podman save --multi-image-archive -o /tmp/store.tar docker.io/library/busybox:latest registry.k8s.io/pause:3.7",
+			importCmd: "sudo podman load -i /tmp/store.tar",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.runtime, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			for _, name := range []string{"registry.k8s.io/pause:3.7", "docker.io/library/busybox:latest"} {
+				runner.images[name] = name
+			}
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+
+			runner.history = nil
+			if err := cr.ExportImageStore("/tmp/store.tar"); err != nil {
+				t.Fatalf("ExportImageStore: %v", err)
+			}
+			if got := runner.history[len(runner.history)-1]; got != tc.exportCmd {
+				t.Errorf("ExportImageStore ran %q, want %q", got, tc.exportCmd)
+			}
+
+			runner.history = nil
+			if err := cr.ImportImageStore("/tmp/store.tar"); err != nil {
+				t.Fatalf("ImportImageStore: %v", err)
+			}
+			if got := runner.history[len(runner.history)-1]; got != tc.importCmd {
+				t.Errorf("ImportImageStore ran %q, want %q", got, tc.importCmd)
+			}
+		})
+	}
+}
+
+func TestExportImageStoreEmpty(t *testing.T) {
+	runner := NewFakeRunner(t)
+	cr, err := New(Config{Type: "containerd", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(containerd): %v", err)
+	}
+	if err := cr.ExportImageStore("/tmp/store.tar"); err == nil {
+		t.Errorf("ExportImageStore succeeded without images")
+	}
+}
+
+func TestDockerSavePipeline(t *testing.T) {
+	var tests = []struct {
+		os   string
+		want string
+	}{
+		{"linux", "docker save busybox:latest 'my app:v1' | sudo tee /tmp/store.tar >/dev/null"},
+		{"windows", "docker save -o '/tmp/store.tar' 'busybox:latest' 'my app:v1'"},
+	}
+	for _, tc := range tests {
+		if got := dockerProfiles[tc.os].SavePipeline("/tmp/store.tar", "busybox:latest", "my app:v1"); got != tc.want {
+			t.Errorf("SavePipeline(%s) = %q, want %q", tc.os, got, tc.want)
+		}
+	}
+}
+
+func TestDockerPurge(t *testing.T) {
+	const (
+		daemonJSON = "/etc/docker/daemon.json"
+		userDropIn = "/etc/systemd/system/cri-docker.service.d/20-limits.conf"
+	)
+	tests := []struct {
+		name        string
+		images      []string
+		noBackup    bool
+		wantImages  []string
+		wantDaemon  string
+		wantRestart bool
+	}{
+		{
+			name:        "restore",
+			images:      []string{"registry.k8s.io/pause:3.9", "registry.k8s.io/etcd:3.5.6-0"},
+			wantImages:  []string{"nginx:latest"},
+			wantDaemon:  `{"debug": true}`,
+			wantRestart: true,
+		},
+		{
+			name:        "keep images",
+			wantImages:  []string{"nginx:latest", "registry.k8s.io/pause:3.9"},
+			wantDaemon:  `{"debug": true}`,
+			wantRestart: true,
+		},
+		{
+			name:       "daemon.json of the user",
+			noBackup:   true,
+			wantImages: []string{"nginx:latest", "registry.k8s.io/pause:3.9"},
+			wantDaemon: `{"exec-opts": ["native.cgroupdriver=systemd"]}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.services["docker"] = SvcRunning
+			runner.containers = map[string]string{
+				"k1": "k8s_kube-apiserver_kube-apiserver-minikube_kube-system_0",
+				"k2": "k8s_POD_etcd-minikube_kube-system_0",
+				"u1": "my-nginx",
+				"u2": "postgres",
+			}
+			runner.images = map[string]string{"nginx:latest": "1", "registry.k8s.io/pause:3.9": "2"}
+			runner.files = map[string]string{
+				daemonJSON:                      `{"exec-opts": ["native.cgroupdriver=systemd"]}`,
+				criDockerServiceConfFile:        criDockerServiceConfHeader + "cni",
+				userDropIn:                      "[Service]\nLimitNOFILE=1048576\n",
+				daemonJSON + ".minikube-backup": `{"debug": true}`,
+			}
+			if tc.noBackup {
+				delete(runner.files, criDockerServiceConfFile)
+				runner.failOn = "test -e"
+			}
+			cr, err := New(Config{Type: "docker", Runner: runner})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			if err := cr.Purge(PurgeOptions{Images: tc.images}); err != nil {
+				t.Fatalf("Purge: %v", err)
+			}
+
+			if diff := cmp.Diff(map[string]string{"u1": "my-nginx", "u2": "postgres"}, runner.containers); diff != "" {
+				t.Errorf("containers diff (-want +got):\n%s", diff)
+			}
+			imgs := []string{}
+			for name := range runner.images {
+				imgs = append(imgs, name)
+			}
+			sort.Strings(imgs)
+			if diff := cmp.Diff(tc.wantImages, imgs); diff != "" {
+				t.Errorf("images diff (-want +got):\n%s", diff)
+			}
+			for _, c := range runner.history {
+				for _, user := range []string{"u1", "u2", "nginx"} {
+					if strings.Contains(c, user) {
+						t.Errorf("Purge ran %q, touching %s of the user", c, user)
+					}
+				}
+			}
+			if got := runner.files[daemonJSON]; got != tc.wantDaemon {
+				t.Errorf("daemon.json = %q, want %q", got, tc.wantDaemon)
+			}
+			if _, ok := runner.files[daemonJSON+".minikube-backup"]; ok && !tc.noBackup {
+				t.Errorf("the backup of daemon.json was kept")
+			}
+			if _, ok := runner.files[criDockerServiceConfFile]; ok {
+				t.Errorf("drop-in %s was kept", criDockerServiceConfFile)
+			}
+			if runner.files[userDropIn] != "[Service]\nLimitNOFILE=1048576\n" {
+				t.Errorf("user drop-in was changed to %q", runner.files[userDropIn])
+			}
+			restarts := 0
+			for _, c := range runner.history {
+				if c == "sudo systemctl restart docker" {
+					restarts++
+				}
+			}
+			if tc.wantRestart && restarts != 1 {
+				t.Errorf("docker was restarted %d times, want once", restarts)
+			}
+			if !tc.wantRestart && restarts != 0 {
+				t.Errorf("docker was restarted %d times with unchanged settings", restarts)
+			}
+		})
+	}
+}
+
+func TestDockerDaemonConfigBackup(t *testing.T) {
+	const daemonJSON = "/etc/docker/daemon.json"
+	runner := NewFakeRunner(t)
+	runner.files = map[string]string{daemonJSON: `{"debug": true}`}
+	// the backup does not exist yet
+	runner.failOn = "test -e"
+	r := &Docker{Runner: runner, Features: []string{"buildkit=true"}}
+	if _, err := r.writeDaemonConfig(false); err != nil {
+		t.Fatalf("writeDaemonConfig: %v", err)
+	}
+	if got := runner.files[daemonJSON+".minikube-backup"]; got != `{"debug": true}` {
+		t.Errorf("backup = %q, want the daemon.json of the user", got)
+	}
+
+	// the backup exists, and keeps the daemon.json of the user
+	runner.failOn = ""
+	r.Features = []string{"buildkit=false"}
+	if _, err := r.writeDaemonConfig(false); err != nil {
+		t.Fatalf("writeDaemonConfig: %v", err)
+	}
+	if got := runner.files[daemonJSON+".minikube-backup"]; got != `{"debug": true}` {
+		t.Errorf("backup = %q after a second write, want the daemon.json of the user", got)
+	}
+}
+
+func TestDaemonEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		socket   string
+		onDemand bool
+		want     DaemonEndpoint
+	}{
+		{
+			name:    "dockershim",
+			version: "1.23.0",
+			want:    DaemonEndpoint{Host: "tcp://192.168.49.2:2376", TLSDir: "/certs", BuildTarget: true},
+		},
+		{
+			name:    "cri-dockerd",
+			version: "1.24.1",
+			want:    DaemonEndpoint{Host: "tcp://192.168.49.2:2376", TLSDir: "/certs", BuildTarget: true, CRISocket: "unix:///var/run/cri-dockerd.sock"},
+		},
+		{
+			name:    "custom socket",
+			version: "1.24.1",
+			socket:  "/run/minikube/cri-dockerd.sock",
+			want:    DaemonEndpoint{Host: "tcp://192.168.49.2:2376", TLSDir: "/certs", BuildTarget: true, CRISocket: "unix:///run/minikube/cri-dockerd.sock"},
+		},
+		{
+			name:     "on demand",
+			version:  "1.24.1",
+			onDemand: true,
+			want:     DaemonEndpoint{Host: "tcp://192.168.49.2:2376", TLSDir: "/certs"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cr, err := New(Config{Type: "docker", Runner: NewFakeRunner(t), Socket: tc.socket, KubernetesVersion: semver.MustParse(tc.version)})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			r := cr.(*Docker)
+			r.OnDemand = tc.onDemand
+			if diff := cmp.Diff(tc.want, r.DaemonEndpoint("tcp://192.168.49.2:2376", "/certs")); diff != "" {
+				t.Errorf("DaemonEndpoint() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFallbackToContainerd(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		fallback bool
+		failOn   string
+		want     string
+		wantErr  bool
+	}{
+		{name: "cri-dockerd installed", version: "1.24.1", fallback: true, want: "Docker"},
+		{name: "no consent", version: "1.24.1", failOn: "which cri-dockerd", want: "Docker"},
+		{name: "dockershim", version: "1.23.0", fallback: true, failOn: "which cri-dockerd", want: "Docker"},
+		{name: "cri-dockerd missing", version: "1.24.1", fallback: true, failOn: "which cri-dockerd", want: "containerd"},
+		// fails both "which cri-dockerd" and "which containerd"
+		{name: "containerd missing", version: "1.24.1", fallback: true, failOn: "which c", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.failOn = tc.failOn
+			cr, err := New(Config{Type: "docker", Runner: runner, Socket: "/run/minikube/cri-dockerd.sock", KubernetesVersion: semver.MustParse(tc.version), FallbackToContainerd: tc.fallback})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if cr.Name() != tc.want {
+				t.Errorf("New() = %s, want %s", cr.Name(), tc.want)
+			}
+			if tc.want == "containerd" && cr.SocketPath() != "/run/containerd/containerd.sock" {
+				t.Errorf("SocketPath() = %s, the custom socket of cri-dockerd was kept", cr.SocketPath())
+			}
+		})
+	}
+}
+
+func TestImageDigests(t *testing.T) {
+	const digest = "sha256:2e500d29e9d5f4a086b908eb8dfe7ecac57d2ab09d65b24f588b1d449841ef93"
+	// busybox:1.36 was pulled by its digest, so both name the same image
+	images := func() map[string]string {
+		return map[string]string{"busybox:1.36": "b1", "busybox@" + digest: "b1", "alpine:3.17": "a1"}
+	}
+	var refs = []struct {
+		description string
+		name        string
+		id          string
+		gone        []string
+	}{
+		{"tag", "alpine:3.17", "a1", []string{"alpine:3.17"}},
+		{"digest", "busybox@" + digest, "b1", []string{"busybox:1.36", "busybox@" + digest}},
+		{"tag and digest", "busybox:1.36@" + digest, "b1", []string{"busybox:1.36", "busybox@" + digest}},
+	}
+	for _, runtime := range []string{"docker", "containerd", "crio"} {
+		for _, ref := range refs {
+			newRuntime := func(t *testing.T) (Manager, *FakeRunner) {
+				runner := NewFakeRunner(t)
+				runner.images = images()
+				cr, err := New(Config{Type: runtime, Runner: runner})
+				if err != nil {
+					t.Fatalf("New(%s): %v", runtime, err)
+				}
+				return cr, runner
+			}
+			t.Run(runtime+" ImageExists "+ref.description, func(t *testing.T) {
+				cr, _ := newRuntime(t)
+				if !cr.ImageExists(ref.name, "") {
+					t.Errorf("ImageExists(%s) = false, want true", ref.name)
+				}
+			})
+			t.Run(runtime+" TagImage "+ref.description, func(t *testing.T) {
+				cr, runner := newRuntime(t)
+				if err := cr.TagImage(ref.name, "copy:1"); err != nil {
+					t.Fatalf("TagImage(%s): %v", ref.name, err)
+				}
+				if got := runner.images["copy:1"]; got != ref.id {
+					t.Errorf("TagImage(%s) tagged %q, want %q", ref.name, got, ref.id)
+				}
+			})
+			t.Run(runtime+" RemoveImage "+ref.description, func(t *testing.T) {
+				cr, runner := newRuntime(t)
+				if err := cr.RemoveImage(ref.name); err != nil {
+					t.Fatalf("RemoveImage(%s): %v", ref.name, err)
+				}
+				for _, name := range ref.gone {
+					if _, ok := runner.images[name]; ok {
+						t.Errorf("RemoveImage(%s) left %s", ref.name, name)
+					}
+				}
+			})
+		}
+
+		t.Run(runtime+" missing digest", func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.images = images()
+			cr, err := New(Config{Type: runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", runtime, err)
+			}
+			missing := "busybox@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+			if cr.ImageExists(missing, "") {
+				t.Errorf("ImageExists(%s) = true, want false", missing)
+			}
+			if err := cr.TagImage(missing, "copy:1"); err == nil {
+				t.Errorf("TagImage(%s) succeeded, want error", missing)
+			}
+			if err := cr.RemoveImage(missing); err == nil {
+				t.Errorf("RemoveImage(%s) succeeded, want error", missing)
+			}
+		})
+
+		t.Run(runtime+" force", func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.images = images()
+			runner.images["mirror.local/busybox:1.36"] = "b1"
+			cr, err := New(Config{Type: runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", runtime, err)
+			}
+			if err := cr.RemoveImage("busybox@" + digest); err == nil {
+				t.Errorf("RemoveImage of an image with several tags succeeded, want error")
+			}
+			if err := cr.ForceRemoveImage("busybox@" + digest); err != nil {
+				t.Fatalf("ForceRemoveImage: %v", err)
+			}
+			if len(runner.images) != 1 || runner.images["alpine:3.17"] == "" {
+				t.Errorf("ForceRemoveImage left %v, want only alpine:3.17", runner.images)
+			}
+		})
+	}
+}
+
+func TestPhaseTimeout(t *testing.T) {
+	var tests = []struct {
+		runtime string
+		blockOn string
+		delay   time.Duration
+	}{
+		{"docker", "systemctl restart docker", 0},
+		{"containerd", "systemctl restart containerd", 0},
+		{"crio", "systemctl start crio", 0},
+		// each command is slow, rather than one of them hanging
+		{"docker", "", 30 * time.Millisecond},
+	}
+	for _, tc := range tests {
+		t.Run(tc.runtime+" "+tc.blockOn, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			for k, v := range defaultServices {
+				runner.services[k] = v
+			}
+			runner.blockOn = tc.blockOn
+			runner.delay = tc.delay
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			pm, ok := cr.(PhaseManager)
+			if !ok {
+				t.Fatalf("%s does not implement PhaseManager", tc.runtime)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err = pm.EnableContext(ctx, true, false, false)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("EnableContext took %s to return after its deadline", elapsed)
+			}
+			var perr *ErrPhaseTimeout
+			if !errors.As(err, &perr) {
+				t.Fatalf("EnableContext() = %v, want an ErrPhaseTimeout", err)
+			}
+			if perr.Phase != tc.runtime+".enable" {
+				t.Errorf("ErrPhaseTimeout.Phase = %q, want %s.enable", perr.Phase, tc.runtime)
+			}
+			if tc.blockOn != "" && !strings.Contains(perr.Command, tc.blockOn) {
+				t.Errorf("ErrPhaseTimeout.Command = %q, want the hung %q", perr.Command, tc.blockOn)
+			}
+			if perr.Elapsed < 200*time.Millisecond {
+				t.Errorf("ErrPhaseTimeout.Elapsed = %s, before the deadline", perr.Elapsed)
+			}
+			// the runtime runs its commands without the deadline once the phase is over
+			if err := cr.Enable(true, false, false); err != nil && tc.blockOn == "" {
+				t.Errorf("Enable() after the phase timed out: %v", err)
+			}
+		})
+	}
+}
+
+func TestDockerPendingRestartTimeout(t *testing.T) {
+	runner := NewFakeRunner(t)
+	for k, v := range defaultServices {
+		runner.services[k] = v
+	}
+	cr, err := New(Config{Type: "docker", Runner: runner, DeferRestarts: true})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	if err := cr.Enable(true, true, false); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	runner.blockOn = "systemctl restart docker"
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err = cr.(PhaseManager).ApplyPendingRestartContext(ctx)
+	var perr *ErrPhaseTimeout
+	if !errors.As(err, &perr) {
+		t.Fatalf("ApplyPendingRestartContext() = %v, want an ErrPhaseTimeout", err)
+	}
+	if perr.Phase != "docker.restart" || !strings.Contains(perr.Command, runner.blockOn) {
+		t.Errorf("ErrPhaseTimeout = %q in %q, want the hung %q in docker.restart", perr.Command, perr.Phase, runner.blockOn)
+	}
+}
+
+func TestDockerPendingCRIDockerRestartTimeout(t *testing.T) {
+	runner := NewFakeRunner(t)
+	runner.services["docker"] = SvcRunning
+	runner.services["cri-docker"] = SvcRunning
+	runner.services["cri-docker.socket"] = SvcRunning
+	cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.6"), DeferRestarts: true, RestartTimeout: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	// changed drop-ins of cri-dockerd only restart it, not docker
+	if err := cr.(*Docker).enableCRIService(true); err != nil {
+		t.Fatalf("enableCRIService: %v", err)
+	}
+	runner.failOn = "crictl version"
+	err = cr.ApplyPendingRestart()
+	if err == nil || !strings.Contains(err.Error(), "cri-docker did not answer within 300ms") {
+		t.Errorf("ApplyPendingRestart() = %v, want cri-docker not to answer within the restart timeout", err)
+	}
+}
+
+func TestPhaseKeepsRunner(t *testing.T) {
+	runner := NewFakeRunner(t)
+	for k, v := range defaultServices {
+		runner.services[k] = v
+	}
+	runner.blockOn = "systemctl unmask docker.service"
+	blocked := make(chan struct{})
+	runner.blocked = blocked
+	cr, err := New(Config{Type: "docker", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- cr.(PhaseManager).EnableContext(ctx, true, false, false)
+	}()
+	<-blocked
+	// the other users of the runtime do not get the runner of the phase, which is killed with it
+	if d := cr.(*Docker); d.Runner != runner {
+		t.Errorf("the runtime runs with %T during a phase, want its own runner", d.Runner)
+	}
+	cancel()
+	<-done
+}
+
+func TestPhaseNoDeadline(t *testing.T) {
+	runner := NewFakeRunner(t)
+	for k, v := range defaultServices {
+		runner.services[k] = v
+	}
+	cr, err := New(Config{Type: "containerd", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(containerd): %v", err)
+	}
+	if err := cr.(PhaseManager).EnableContext(context.Background(), true, false, false); err != nil {
+		t.Errorf("EnableContext() = %v", err)
+	}
+	if c := cr.(*Containerd); c.Runner != runner {
+		t.Errorf("EnableContext left the runner of the phase: %T", c.Runner)
+	}
+}
+
+func TestPullVerifiedImage(t *testing.T) {
+	const pinned = "example.com/app@sha256:2e500d29e9d5f4a086b908eb8dfe7ecac57d2ab09d65b24f588b1d449841ef93"
+	for _, runtime := range []string{"docker", "containerd", "crio"} {
+		t.Run(runtime, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			// the verified image is there already, keeping its ID as it is pulled
+			runner.images = map[string]string{pinned: "a1"}
+			cr, err := New(Config{Type: runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", runtime, err)
+			}
+			if err := PullVerifiedImage(cr, "example.com/app:v1", pinned); err != nil {
+				t.Fatalf("PullVerifiedImage: %v", err)
+			}
+			pulled := false
+			for _, h := range runner.history {
+				pulled = pulled || strings.HasSuffix(h, "pull "+pinned)
+			}
+			if !pulled {
+				t.Errorf("PullVerifiedImage did not pull %s: %v", pinned, runner.history)
+			}
+			if got := runner.images["example.com/app:v1"]; got != "a1" {
+				t.Errorf("PullVerifiedImage tagged %q, want the verified image", got)
+			}
+			// the short names are tagged as the fully qualified names the kubelet resolves
+			if err := PullVerifiedImage(cr, "app:v1", pinned); err != nil {
+				t.Fatalf("PullVerifiedImage: %v", err)
+			}
+			if got := runner.images["docker.io/library/app:v1"]; got != "a1" {
+				t.Errorf("PullVerifiedImage tagged %q as docker.io/library/app:v1, want the verified image", got)
+			}
+			if err := PullVerifiedImage(cr, "example.com/app:v1", "example.com/app:v1"); err == nil {
+				t.Errorf("PullVerifiedImage succeeded without a digest")
+			}
+		})
+	}
+}
+
+func TestStreamContainerLogs(t *testing.T) {
+	for _, runtime := range []string{"docker", "containerd", "crio"} {
+		t.Run(runtime, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.blockOn = "--follow"
+			runner.blocked = make(chan struct{})
+			cr, err := New(Config{Type: runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", runtime, err)
+			}
+			want := cr.ContainerLogCmd("a1", 5, true)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() {
+				done <- cr.StreamContainerLogs(ctx, "a1", io.Discard, StreamLogsOptions{Lines: 5, Follow: true})
+			}()
+			<-runner.blocked
+			cancel()
+			if err := <-done; err != context.Canceled {
+				t.Errorf("StreamContainerLogs() = %v, want %v", err, context.Canceled)
+			}
+			if got := runner.history[len(runner.history)-1]; !strings.HasSuffix(got, want) {
+				t.Errorf("StreamContainerLogs() ran %q, want %q", got, want)
+			}
+
+			if err := cr.StreamContainerLogs(context.Background(), "a1", io.Discard, StreamLogsOptions{Lines: 5}); err != nil {
+				t.Errorf("StreamContainerLogs() without following = %v", err)
+			}
+		})
+	}
+}
+
+func TestBuildCache(t *testing.T) {
+	from := []string{"10.96.0.5:80/app:cache"}
+	to := []string{"type=registry,ref=10.96.0.5:80/app:cache,mode=max"}
+	var tests = []struct {
+		name    string
+		runtime string
+		buildx  bool
+		opts    BuildOptions
+		want    string
+		wantErr string
+	}{
+		{"docker", "docker", false, BuildOptions{Tag: "app", CacheFrom: from},
+			"docker build -t app --cache-from 10.96.0.5:80/app:cache /tmp/build", ""},
+		{"buildx without BuildKit caches", "docker", true, BuildOptions{Tag: "app", CacheFrom: from},
+			"docker build -t app --cache-from 10.96.0.5:80/app:cache /tmp/build", ""},
+		{"docker cache-to", "docker", false, BuildOptions{Tag: "app", CacheFrom: from, CacheTo: to},
+			"", "the classic docker builder cannot export build caches"},
+		{"buildx", "docker", true, BuildOptions{Tag: "app", CacheFrom: from, CacheTo: to},
+			"docker buildx build --load -t app --cache-from type=registry,ref=10.96.0.5:80/app:cache --cache-to type=registry,ref=10.96.0.5:80/app:cache,mode=max /tmp/build", ""},
+		{"containerd", "containerd", false, BuildOptions{Tag: "app", CacheFrom: from, CacheTo: to},
+			"--output type=image,name=app:latest --import-cache type=registry,ref=10.96.0.5:80/app:cache --export-cache type=registry,ref=10.96.0.5:80/app:cache,mode=max", ""},
+		{"crio", "crio", false, BuildOptions{Tag: "app", CacheFrom: from, CacheTo: []string{"type=registry,ref=10.96.0.5:80/app:cache"}},
+			"sudo podman build -t app --cache-from 10.96.0.5:80/app:cache --cache-to 10.96.0.5:80/app:cache /tmp/build", ""},
+		{"crio attributes", "crio", false, BuildOptions{Tag: "app", CacheTo: to},
+			"", "the podman builder only takes image references as build caches"},
+		{"local cache", "containerd", false, BuildOptions{Tag: "app", CacheTo: []string{"type=local,dest=/tmp/cache"}},
+			"", "only type=registry caches are supported"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.buildx = tc.buildx
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			prepErr := PrepareBuild(cr, tc.opts)
+			err = cr.BuildImage("/tmp/build", tc.opts)
+			if tc.wantErr != "" {
+				for _, err := range []error{prepErr, err} {
+					if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+						t.Errorf("building = %v, want an error containing %q", err, tc.wantErr)
+					}
+				}
+				for _, c := range runner.history {
+					if strings.Contains(c, " build ") {
+						t.Errorf("ran %q despite the unsupported caches", c)
+					}
+				}
+				return
+			}
+			if prepErr != nil {
+				t.Fatalf("PrepareBuild() = %v", prepErr)
+			}
+			if err != nil {
+				t.Fatalf("BuildImage() = %v", err)
+			}
+			found := false
+			for _, c := range runner.history {
+				found = found || strings.Contains(c, tc.want)
+			}
+			if !found {
+				t.Errorf("BuildImage() ran %q, want %q", runner.history, tc.want)
+			}
+		})
+	}
+}
+
+func TestPrepareBuildInsecureCache(t *testing.T) {
+	opts := BuildOptions{Tag: "app", CacheFrom: []string{"type=registry,ref=10.96.0.5:80/app:cache,registry.insecure=true"}}
+	var tests = []struct {
+		name     string
+		registry string
+		wantErr  string
+	}{
+		{"accepted", `{"InsecureRegistryCIDRs":["10.96.0.0/12"]}`, ""},
+		{"not accepted", `{"InsecureRegistryCIDRs":["127.0.0.0/8"]}`, "does not accept the insecure cache registry 10.96.0.5:80"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.dockerInfo["{{json .RegistryConfig}}"] = tc.registry
+			cr, err := New(Config{Type: "docker", Runner: runner})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			err = PrepareBuild(cr, opts)
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("PrepareBuild() = %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("PrepareBuild() = %v, want an error containing %q", err, tc.wantErr)
+			}
+			for _, c := range runner.history {
+				if strings.Contains(c, "daemon.json") || strings.Contains(c, "restart") {
+					t.Errorf("PrepareBuild() ran %q, want no change to the runtime", c)
+				}
+			}
+		})
+	}
+}
+
+func TestBuilderUnit(t *testing.T) {
+	unit, err := builderUnit(builderSettings{Socket: BuilderSocket, Port: 2377, ConfigDir: "/etc/docker", ConfigFile: builderConfigFile, Namespace: builderNamespace})
+	if err != nil {
+		t.Fatalf("builderUnit: %v", err)
+	}
+	for _, want := range []string{
+		"--host unix:///var/run/docker-builder.sock --host tcp://0.0.0.0:2377",
+		"--tlscacert /etc/docker/ca.pem --tlscert /etc/docker/server.pem --tlskey /etc/docker/server-key.pem",
+		"--config-file /etc/docker-builder/daemon.json --data-root /var/lib/docker-builder --exec-root /var/run/docker-builder",
+		"--containerd-namespace moby-builder --containerd-plugins-namespace moby-builder-plugins",
+		"OOMScoreAdjust=500",
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(string(unit), want) {
+			t.Errorf("builderUnit() lacks %q:\n%s", want, unit)
+		}
+	}
+}
+
+func TestEnableIsolatedBuilder(t *testing.T) {
+	var tests = []struct {
+		description   string
+		features      []string
+		wantNamespace string
+	}{
+		{"own image store", nil, "--containerd-namespace moby-builder "},
+		{"containerd image store", []string{"containerd-snapshotter=true"}, "--containerd-namespace moby "},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			for k, v := range map[string]serviceState{"docker": SvcExited, "cri-docker.socket": SvcExited, BuilderService: SvcExited} {
+				runner.services[k] = v
+			}
+			cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.0"), DockerFeatures: tc.features, DockerIsolatedBuilder: true})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			if err := cr.Enable(true, false, false); err != nil {
+				t.Fatalf("Enable: %v", err)
+			}
+			if unit := runner.files[builderUnitFile]; !strings.Contains(unit, tc.wantNamespace) {
+				t.Errorf("the unit of the builder lacks %q:\n%s", tc.wantNamespace, unit)
+			}
+			if conf := runner.files[builderConfigFile]; !strings.Contains(conf, `"network-host": true`) {
+				t.Errorf("the daemon.json of the builder does not allow host networking:\n%s", conf)
+			}
+			if runner.services[BuilderService] != SvcRestarted {
+				t.Errorf("the builder is %v after its unit was installed, want it restarted", runner.services[BuilderService])
+			}
+
+			// unchanged settings keep the running builder
+			runner.services[BuilderService] = SvcRunning
+			if err := cr.Enable(true, false, false); err != nil {
+				t.Fatalf("Enable again: %v", err)
+			}
+			if runner.services[BuilderService] != SvcRunning {
+				t.Errorf("the builder is %v after an Enable without changes, want it running", runner.services[BuilderService])
+			}
+
+			// the builder stops once the cluster no longer uses it
+			cr.(*Docker).IsolatedBuilder = false
+			if err := cr.Enable(true, false, false); err != nil {
+				t.Fatalf("Enable without the builder: %v", err)
+			}
+			if runner.services[BuilderService] != SvcExited {
+				t.Errorf("the builder is %v after an Enable without it, want it stopped", runner.services[BuilderService])
+			}
+		})
+	}
+}
+
+func TestBuildImageIsolatedBuilder(t *testing.T) {
+	var tests = []struct {
+		description string
+		driver      string
+		want        []string
+	}{
+		{"promoted with save and load", "", []string{
+			"docker --host unix:///var/run/docker-builder.sock build -t app /tmp/build --network host",
+			"docker --host unix:///var/run/docker-builder.sock info --format {{json .DriverStatus}}",
+			"/bin/bash -c docker --host unix:///var/run/docker-builder.sock save app | docker load",
+		}},
+		{"shared containerd image store", `[["driver-type","io.containerd.snapshotter.v1"]]`, []string{
+			"docker --host unix:///var/run/docker-builder.sock build -t app /tmp/build --network host",
+			"docker --host unix:///var/run/docker-builder.sock info --format {{json .DriverStatus}}",
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			if tc.driver != "" {
+				runner.dockerInfo = map[string]string{"{{json .DriverStatus}}": tc.driver}
+			}
+			cr, err := New(Config{Type: "docker", Runner: runner, DockerIsolatedBuilder: true})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			if err := cr.BuildImage("/tmp/build", BuildOptions{Tag: "app"}); err != nil {
+				t.Fatalf("BuildImage: %v", err)
+			}
+			var got []string
+			for _, c := range runner.history {
+				if strings.Contains(c, "docker-builder.sock") || strings.Contains(c, "docker load") {
+					got = append(got, c)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("BuildImage() commands mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDockerPreloadStore(t *testing.T) {
+	var tests = []struct {
+		description string
+		driver      string
+		features    []string
+		systemd     bool
+		want        bool
+		restarted   bool
+	}{
+		{description: "overlay2"},
+		{description: "containerd image store", driver: `[["driver-type","io.containerd.snapshotter.v1"]]`, want: true},
+		{description: "feature disabled", features: []string{"containerd-snapshotter=false"}},
+		{description: "feature enabled", features: []string{"containerd-snapshotter=true"}, want: true, restarted: true},
+		{description: "feature enabled with systemd", features: []string{"containerd-snapshotter=true"}, systemd: true, want: true, restarted: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.services["docker"] = SvcRunning
+			if tc.driver != "" {
+				runner.dockerInfo["{{json .DriverStatus}}"] = tc.driver
+			}
+			cr, err := New(Config{Type: "docker", Runner: runner, DockerFeatures: tc.features, ForceSystemd: tc.systemd})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			got, err := cr.(*Docker).preloadStore(context.Background())
+			if err != nil {
+				t.Fatalf("preloadStore: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("preloadStore() = %v, want %v", got, tc.want)
+			}
+			if restarted := runner.services["docker"] == SvcRestarted; restarted != tc.restarted {
+				t.Errorf("docker restarted = %v, want %v", restarted, tc.restarted)
+			}
+			if tc.restarted {
+				systemd := strings.Contains(runner.files["/etc/docker/daemon.json"], "native.cgroupdriver=systemd")
+				if systemd != tc.systemd {
+					t.Errorf("daemon.json with the systemd cgroup driver = %v, want %v", systemd, tc.systemd)
+				}
+			}
+		})
+	}
+}
+
+func TestDockerImportPreload(t *testing.T) {
+	runner := NewFakeRunner(t)
+	runner.images = map[string]string{"registry.k8s.io/pause:3.9": "abc", "registry.k8s.io/etcd:3.5.6-0": "def"}
+	cr, err := New(Config{Type: "docker", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	runner.history = nil
+	if err := cr.(*Docker).importPreload(context.Background(), download.CompressionLZ4, "/preloaded.tar.lz4"); err != nil {
+		t.Fatalf("importPreload: %v", err)
+	}
+	want := []string{
+		"sudo mkdir -p /var/lib/minikube/preload-docker",
+		"sudo tar -I lz4 -C /var/lib/minikube/preload-docker -xf /preloaded.tar.lz4",
+		"sudo systemd-run --unit=docker-preload --collect /usr/bin/dockerd --host unix:///var/run/docker-preload.sock --data-root /var/lib/minikube/preload-docker/lib/docker --exec-root /var/run/docker-preload --pidfile /var/run/docker-preload.pid --storage-driver overlay2 --bridge none --iptables=false --ip-masq=false",
+		"docker --host unix:///var/run/docker-preload.sock version --format {{.Server.Version}}",
+		"docker --host unix:///var/run/docker-preload.sock images --format {{.Repository}}:{{.Tag}}",
+		"/bin/bash -c set -o pipefail; docker --host unix:///var/run/docker-preload.sock save registry.k8s.io/etcd:3.5.6-0 registry.k8s.io/pause:3.9 | docker load",
+		"sudo systemctl stop docker-preload",
+		"sudo rm -rf /var/lib/minikube/preload-docker",
+	}
+	if diff := cmp.Diff(want, runner.history); diff != "" {
+		t.Errorf("importPreload() commands mismatch (-want +got):\n%s", diff)
+	}
+	if runner.services["docker-preload"] != SvcExited {
+		t.Errorf("the transient daemon is still %v", runner.services["docker-preload"])
+	}
+}
+func TestDockerPauseImageRepair(t *testing.T) {
+	kv := semver.MustParse("1.25.3")
+	pause := images.Pause(kv, "")
+	var tests = []struct {
+		description string
+		images      map[string]string
+		offline     bool
+		repaired    bool
+		pulled      bool
+		missing     bool
+	}{
+		{description: "present", images: map[string]string{pause: "abc"}},
+		{description: "pulled", images: map[string]string{}, repaired: true, pulled: true},
+		{description: "retagged", images: map[string]string{strings.Replace(pause, "registry.k8s.io", "k8s.gcr.io", 1): "abc"}, repaired: true},
+		{description: "offline", images: map[string]string{}, offline: true, missing: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.services["docker"] = SvcRunning
+			runner.services["cri-docker"] = SvcRunning
+			runner.images = tc.images
+			cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: kv, Offline: tc.offline})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			h, err := cr.RuntimeHealth()
+			if err != nil {
+				t.Fatalf("RuntimeHealth: %v", err)
+			}
+			if h.PauseImage != pause {
+				t.Errorf("PauseImage = %q, want %q", h.PauseImage, pause)
+			}
+			if h.PauseImageRepaired != tc.repaired {
+				t.Errorf("PauseImageRepaired = %v, want %v", h.PauseImageRepaired, tc.repaired)
+			}
+			if h.PauseImageMissing != tc.missing {
+				t.Errorf("PauseImageMissing = %v, want %v", h.PauseImageMissing, tc.missing)
+			}
+			if tc.missing && h.State != HealthDegraded {
+				t.Errorf("State = %v, want %v", h.State, HealthDegraded)
+			}
+			pulled := false
+			for _, c := range runner.history {
+				if strings.HasSuffix(c, "pull "+pause) {
+					pulled = true
+				}
+			}
+			if pulled != tc.pulled {
+				t.Errorf("pulled %s = %v, want %v: %q", pause, pulled, tc.pulled, runner.history)
+			}
+		})
+	}
+}
+
+func TestContainerdPinPauseImage(t *testing.T) {
+	pause := "registry.k8s.io/pause:3.8"
+	label := fmt.Sprintf("sudo ctr -n=k8s.io images label %s %s", pause, containerdPinnedLabel)
+	for _, tc := range []struct {
+		version string
+		pinned  bool
+	}{{version: "1.7.2", pinned: true}, {version: "1.6.15"}} {
+		t.Run(tc.version, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.containerdVersion = tc.version
+			runner.images = map[string]string{pause: "abc"}
+			cr, err := New(Config{Type: "containerd", Runner: runner})
+			if err != nil {
+				t.Fatalf("New(containerd): %v", err)
+			}
+			if err := pinContainerdImage(cr.(*Containerd), pause); err != nil {
+				t.Fatalf("pinContainerdImage: %v", err)
+			}
+			pinned := false
+			for _, c := range runner.history {
+				if c == label {
+					pinned = true
+				}
+			}
+			if pinned != tc.pinned {
+				t.Errorf("containerd %s pinned %s = %v, want %v: %q", tc.version, pause, pinned, tc.pinned, runner.history)
+			}
+		})
+	}
+}
+
+func TestCRIOPinPauseImage(t *testing.T) {
+	kv := semver.MustParse("1.25.3")
+	for _, tc := range []struct {
+		version string
+		pinned  bool
+	}{{version: "1.28.1", pinned: true}, {version: "1.24.6"}} {
+		t.Run(tc.version, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.crioVersion = tc.version
+			if err := generateCRIOConfig(runner, "", kv); err != nil {
+				t.Fatalf("generateCRIOConfig: %v", err)
+			}
+			got, pinned := runner.files[crioPinnedImagesFile]
+			if pinned != tc.pinned {
+				t.Fatalf("CRI-O %s wrote %s = %v, want %v", tc.version, crioPinnedImagesFile, pinned, tc.pinned)
+			}
+			want := fmt.Sprintf("pinned_images = [\n\t%q,\n]", images.Pause(kv, ""))
+			if pinned && !strings.Contains(got, want) {
+				t.Errorf("%s = %q, want it to contain %q", crioPinnedImagesFile, got, want)
+			}
+		})
+	}
+}
+
+func TestCopyOptions(t *testing.T) {
+	var tests = []struct {
+		o       CopyOptions
+		want    string
+		invalid bool
+	}{
+		{o: CopyOptions{}, want: "sudo tar -C /var/lib/minikube/cp/staged -cf - a.txt"},
+		{o: CopyOptions{Mode: "0600", Owner: "1000"}, want: "sudo tar -C /var/lib/minikube/cp/staged -cf - --numeric-owner --owner=1000 --group=1000 --mode=0600 a.txt"},
+		{o: CopyOptions{Owner: "1000:2000"}, want: "sudo tar -C /var/lib/minikube/cp/staged -cf - --numeric-owner --owner=1000 --group=2000 a.txt"},
+		{o: CopyOptions{Mode: "0900"}, invalid: true},
+		{o: CopyOptions{Mode: "u+x"}, invalid: true},
+		{o: CopyOptions{Owner: "docker"}, invalid: true},
+		{o: CopyOptions{Owner: "1000:"}, invalid: true},
+	}
+	for _, tc := range tests {
+		err := tc.o.Validate()
+		if tc.invalid {
+			if err == nil {
+				t.Errorf("%+v.Validate() = nil, want an error", tc.o)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v.Validate() = %v", tc.o, err)
+		}
+		if got := tc.o.tarCommand("/var/lib/minikube/cp/staged/a.txt"); got != tc.want {
+			t.Errorf("%+v.tarCommand() = %q, want %q", tc.o, got, tc.want)
+		}
+	}
+}
+
+func TestCopyToContainer(t *testing.T) {
+	tar := "sudo tar -C /var/lib/minikube/cp/staged -cf - --mode=0600 a.txt"
+	mnt := "/var/lib/minikube/cp/rootfs-abc"
+	var tests = []struct {
+		runtime string
+		state   string
+		dir     string
+		want    []string
+		wantErr string
+	}{
+		{runtime: "docker", state: "exited", want: []string{"/bin/bash -c " + tar + " | docker cp -a - abc:/etc/app"}},
+		{runtime: "docker", state: "exited", dir: "/etc/my app;", want: []string{"/bin/bash -c " + tar + " | docker cp -a - 'abc:/etc/my app;'"}},
+		{runtime: "containerd", state: "running", want: []string{"which crictl", "/bin/bash -c " + tar + " | sudo /usr/bin/crictl exec -i abc tar -xf - -C /etc/app"}},
+		{runtime: "containerd", state: "running", dir: "/etc/my app;", want: []string{"which crictl", "/bin/bash -c " + tar + " | sudo /usr/bin/crictl exec -i abc tar -xf - -C '/etc/my app;'"}},
+		{runtime: "containerd", state: "exited", want: []string{
+			"sudo mkdir -p " + mnt,
+			"/bin/bash -c sudo ctr -n=k8s.io snapshots mounts " + mnt + " abc | sudo sh -e",
+			"/bin/bash -c " + tar + " | sudo tar -xf - -C " + mnt + "/etc/app",
+			"sudo umount " + mnt,
+			"sudo rmdir " + mnt,
+		}},
+		{runtime: "containerd", state: "paused", wantErr: "unpause it"},
+		{runtime: "crio", state: "running", want: []string{"which crictl", "/bin/bash -c " + tar + " | sudo /usr/bin/crictl exec -i abc tar -xf - -C /etc/app"}},
+		{runtime: "crio", state: "exited", wantErr: "does not support copying into containers which are not running"},
+	}
+	for _, tc := range tests {
+		if tc.dir == "" {
+			tc.dir = "/etc/app"
+		}
+		t.Run(tc.runtime+"-"+tc.state+tc.dir, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			runner.history = nil
+			err = cr.CopyToContainer(ContainerInfo{ID: "abc", State: tc.state}, "/var/lib/minikube/cp/staged/a.txt", tc.dir, CopyOptions{Mode: "0600"})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("CopyToContainer() = %v, want an error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CopyToContainer: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, runner.history); diff != "" {
+				t.Errorf("CopyToContainer() commands mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRetagCaches(t *testing.T) {
+	specs := []string{"addon/app:cache", "type=registry,ref=addon/app:cache,mode=max", "ghcr.io/org/app:cache"}
+	if !UsesCacheRegistry(specs, AddonCacheRegistry) {
+		t.Errorf("UsesCacheRegistry(%q) = false, want true", specs)
+	}
+	if UsesCacheRegistry(specs[2:], AddonCacheRegistry) {
+		t.Errorf("UsesCacheRegistry(%q) = true, want false", specs[2:])
+	}
+	got, err := RetagCaches(specs, AddonCacheRegistry, "10.96.0.5:80")
+	if err != nil {
+		t.Fatalf("RetagCaches() = %v", err)
+	}
+	want := []string{
+		"type=registry,ref=10.96.0.5:80/app:cache,registry.insecure=true",
+		"type=registry,ref=10.96.0.5:80/app:cache,mode=max,registry.insecure=true",
+		"ghcr.io/org/app:cache",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RetagCaches() mismatch (-want +got):\n%s", diff)
+	}
+	if args, err := buildCacheArgs(builderDocker, BuildOptions{CacheFrom: got[:1]}); err != nil || strings.Join(args, " ") != "--cache-from 10.96.0.5:80/app:cache" {
+		t.Errorf("buildCacheArgs() of an insecure cache = %q, %v", args, err)
+	}
+}
+
+func TestCheckpointContainer(t *testing.T) {
+	var tests = []struct {
+		runtime string
+		legacy  bool
+		want    string
+	}{
+		{"containerd", false, "sudo /usr/bin/crictl checkpoint --export=/var/lib/minikube/checkpoints/c.tar abc123"},
+		{"crio", false, "sudo /usr/bin/crictl checkpoint --export=/var/lib/minikube/checkpoints/c.tar abc123"},
+		{"containerd", true, ""},
+		{"docker", false, ""},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s legacy=%t", tc.runtime, tc.legacy), func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.crictlLegacy = tc.legacy
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			err = cr.CheckpointContainer("abc123", "/var/lib/minikube/checkpoints/c.tar")
+			if tc.want == "" {
+				var unsupported *ErrUnsupported
+				if !errors.As(err, &unsupported) || unsupported.Runtime != cr.Name() {
+					t.Errorf("CheckpointContainer() = %v, want an ErrUnsupported of %s", err, tc.runtime)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckpointContainer() = %v", err)
+			}
+			if got := runner.history[len(runner.history)-1]; got != tc.want {
+				t.Errorf("CheckpointContainer() ran %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPortMappings(t *testing.T) {
+	var tests = []struct {
+		description string
+		runtime     string
+		containers  map[string]string
+		inspected   map[string]string
+		annotations map[string]string
+		id          string
+		want        []PortMapping
+	}{
+		{
+			description: "docker published and exposed",
+			runtime:     "docker",
+			inspected: map[string]string{
+				"web": `{"Config":{"ExposedPorts":{"80/tcp":{},"443/tcp":{},"53/udp":{}}},"HostConfig":{"NetworkMode":"bridge"},` +
+					`"NetworkSettings":{"IPAddress":"172.17.0.2","Ports":{"80/tcp":[{"HostIp":"::","HostPort":"8080"},{"HostIp":"0.0.0.0","HostPort":"8080"}],"443/tcp":null,"53/udp":null}}}`,
+			},
+			id: "web",
+			want: []PortMapping{
+				{Protocol: "udp", ContainerIP: "172.17.0.2", ContainerPort: 53},
+				{Protocol: "tcp", ContainerIP: "172.17.0.2", ContainerPort: 80, HostIP: "0.0.0.0", HostPort: 8080},
+				{Protocol: "tcp", ContainerIP: "172.17.0.2", ContainerPort: 443},
+			},
+		},
+		{
+			description: "docker user-defined network",
+			runtime:     "docker",
+			inspected: map[string]string{
+				"db": `{"Config":{"ExposedPorts":{"5432/tcp":{}}},"HostConfig":{"NetworkMode":"backend"},` +
+					`"NetworkSettings":{"Ports":{"5432/tcp":null},"Networks":{"backend":{"IPAddress":"172.18.0.3"}}}}`,
+			},
+			id:   "db",
+			want: []PortMapping{{Protocol: "tcp", ContainerIP: "172.18.0.3", ContainerPort: 5432}},
+		},
+		{
+			description: "docker container of a pod",
+			runtime:     "docker",
+			inspected: map[string]string{
+				"nginx":   `{"Config":{"Labels":{"annotation.io.kubernetes.container.ports":"[{\"name\":\"http\",\"containerPort\":80,\"protocol\":\"TCP\"}]"}},"HostConfig":{"NetworkMode":"container:sandbox"},"NetworkSettings":{}}`,
+				"sandbox": `{"HostConfig":{"NetworkMode":"bridge"},"NetworkSettings":{"IPAddress":"10.244.0.5","Ports":{"443/tcp":[{"HostIp":"","HostPort":"30443"}]}}}`,
+			},
+			id: "nginx",
+			want: []PortMapping{
+				{Protocol: "tcp", ContainerIP: "10.244.0.5", ContainerPort: 80},
+				{Protocol: "tcp", ContainerIP: "10.244.0.5", ContainerPort: 443, HostPort: 30443},
+			},
+		},
+		{
+			description: "docker host network",
+			runtime:     "docker",
+			inspected: map[string]string{
+				"agent": `{"Config":{"ExposedPorts":{"9100/tcp":{}}},"HostConfig":{"NetworkMode":"host"},"NetworkSettings":{"Networks":{"host":{}}}}`,
+			},
+			id:   "agent",
+			want: []PortMapping{{Protocol: "tcp", ContainerPort: 9100}},
+		},
+		{
+			description: "containerd",
+			runtime:     "containerd",
+			containers:  map[string]string{"abc123": "nginx", "def456": "etcd"},
+			annotations: map[string]string{"abc123": `{"io.kubernetes.container.ports":"[{\"containerPort\":80,\"protocol\":\"TCP\"},{\"containerPort\":53,\"protocol\":\"UDP\"}]"}`},
+			inspected: map[string]string{
+				"abc123-pod": `{"status":{"network":{"ip":"10.244.0.7"},"linux":{"namespaces":{"options":{"network":"POD"}}}},` +
+					`"info":{"config":{"port_mappings":[{"container_port":80,"host_port":8080},{"protocol":1,"container_port":53,"host_port":5353,"host_ip":"127.0.0.1"}]}}}`,
+			},
+			id: "abc",
+			want: []PortMapping{
+				{Protocol: "udp", ContainerIP: "10.244.0.7", ContainerPort: 53, HostIP: "127.0.0.1", HostPort: 5353},
+				{Protocol: "tcp", ContainerIP: "10.244.0.7", ContainerPort: 80, HostPort: 8080},
+			},
+		},
+		{
+			description: "crio host network",
+			runtime:     "crio",
+			containers:  map[string]string{"abc123": "nginx"},
+			inspected: map[string]string{
+				"abc123-pod": `{"status":{"network":{"ip":"192.168.49.2"},"linux":{"namespaces":{"options":{"network":"NODE"}}}},` +
+					`"info":{"runtimeSpec":{"annotations":{"io.kubernetes.cri-o.PortMappings":"[{\"hostPort\":443,\"containerPort\":443,\"protocol\":\"TCP\",\"hostIP\":\"\"}]"}}}}`,
+			},
+			id:   "abc123",
+			want: []PortMapping{{Protocol: "tcp", ContainerPort: 443, HostPort: 443}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.inspected = tc.inspected
+			runner.annotations = tc.annotations
+			for id, name := range tc.containers {
+				runner.containers[id] = name
+			}
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			got, err := cr.PortMappings(tc.id)
+			if err != nil {
+				t.Fatalf("PortMappings(%s) = %v", tc.id, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("PortMappings(%s) mismatch (-want +got):\n%s", tc.id, diff)
+			}
+		})
+	}
+}
+
+func TestPortMappingsMissingContainer(t *testing.T) {
+	for _, rt := range []string{"docker", "containerd", "crio"} {
+		t.Run(rt, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.containers["abc123"] = "nginx"
+			runner.containers["abd456"] = "etcd"
+			cr, err := New(Config{Type: rt, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", rt, err)
+			}
+			for _, id := range []string{"missing", "ab"} {
+				if got, err := cr.PortMappings(id); err == nil {
+					t.Errorf("PortMappings(%s) = %v, want an error", id, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNodeTempName(t *testing.T) {
+	pid := os.Getpid()
+	var tests = []struct {
+		name string
+		want string
+	}{
+		{"preloaded.tar.lz4", fmt.Sprintf("preloaded-%d.tar.lz4", pid)},
+		{"local-preload.tar", fmt.Sprintf("local-preload-%d.tar", pid)},
+		{"/var/lib/minikube/local-preload", fmt.Sprintf("/var/lib/minikube/local-preload-%d", pid)},
+		{"registry.k8s.io_pause_3.7", fmt.Sprintf("registry.k8s.io_pause_3.7-%d", pid)},
+	}
+	for _, tc := range tests {
+		if got := NodeTempName(tc.name); got != tc.want {
+			t.Errorf("NodeTempName(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExtractImagesFromPreload(t *testing.T) {
+	t.Setenv("MINIKUBE_HOME", t.TempDir())
+	cc := config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.24.1", ContainerRuntime: "docker"}}
+	held := "registry.k8s.io/pause:3.7"
+	pulled := "registry.k8s.io/kube-apiserver:v1.24.1"
+
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/"+download.LocalPreloadArchiveName(held), []byte("archive"), 0o644); err != nil {
+		t.Fatalf("writing archive: %v", err)
+	}
+	if err := download.WriteLocalPreload("v1.24.1", "docker", "", dir, download.CompressionNone); err != nil {
+		t.Fatalf("WriteLocalPreload: %v", err)
+	}
+
+	runner := NewFakeRunner(t)
+	cr, err := New(Config{Type: "docker", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	missing, err := ExtractImagesFromPreload(cr, runner, cc, []string{held, pulled})
+	if err != nil {
+		t.Fatalf("ExtractImagesFromPreload: %v", err)
+	}
+	if diff := cmp.Diff([]string{pulled}, missing); diff != "" {
+		t.Errorf("ExtractImagesFromPreload missing diff (-want +got):\n%s", diff)
+	}
+	// only the archive of the held image is extracted and loaded
+	archive := download.LocalPreloadArchiveName(held)
+	for _, want := range []string{
+		"sudo tar -C " + NodeTempName(localPreloadExtractDir) + " -xf /" + NodeTempName(localPreloadTargetName) + " " + archive,
+		NodeTempName(localPreloadExtractDir) + "/" + archive,
+	} {
+		found := false
+		for _, h := range runner.history {
+			if strings.Contains(h, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("ExtractImagesFromPreload did not run %q: %v", want, runner.history)
+		}
+	}
+}
+
+func TestRetagImages(t *testing.T) {
+	mapping := map[string]string{
+		"registry.k8s.io/kube-apiserver:v1.25.0": "mirror.example.com/kube-apiserver:v1.25.0",
+		"registry.k8s.io/pause:3.8":              "mirror.example.com/pause:3.8",
+	}
+	var tests = []struct {
+		description string
+		failOn      string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			description: "all tagged",
+			want:        []string{"mirror.example.com/kube-apiserver:v1.25.0", "mirror.example.com/pause:3.8", "mirror.example.com/pause:3.9", "registry.k8s.io/kube-apiserver:v1.25.0", "registry.k8s.io/pause:3.8"},
+		},
+		{
+			// the tag of kube-apiserver is rolled back, and the tag existing before is kept
+			description: "rolled back",
+			failOn:      "docker tag registry.k8s.io/pause:3.8",
+			want:        []string{"mirror.example.com/pause:3.9", "registry.k8s.io/kube-apiserver:v1.25.0", "registry.k8s.io/pause:3.8"},
+			wantErr:     true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.failOn = tc.failOn
+			runner.images = map[string]string{
+				"registry.k8s.io/kube-apiserver:v1.25.0": "aaa",
+				"registry.k8s.io/pause:3.8":              "bbb",
+				"mirror.example.com/pause:3.9":           "ccc",
+			}
+			cr, err := New(Config{Type: "docker", Runner: runner})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			err = cr.RetagImages(mapping)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("RetagImages() error = %v, want error: %v", err, tc.wantErr)
+			}
+			got := []string{}
+			for name := range runner.images {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("images after RetagImages() diff (-want +got):\n%s", diff)
+			}
+			// all the tags run in one invocation
+			scripts := 0
+			for _, h := range runner.history {
+				if strings.HasPrefix(h, "/bin/bash -c set -e") {
+					scripts++
+				}
+			}
+			if scripts != 1 {
+				t.Errorf("RetagImages() ran %d tag scripts, want 1: %v", scripts, runner.history)
+			}
+		})
+	}
+}
+
+func TestContainerdRetagImagesReplacesTargets(t *testing.T) {
+	runner := NewFakeRunner(t)
+	cr, err := New(Config{Type: "containerd", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(containerd): %v", err)
+	}
+	// the target may already exist, from an earlier retag of the same repository
+	_ = cr.RetagImages(map[string]string{"registry.k8s.io/pause:3.8": "mirror.example.com/pause:3.8"})
+	want := "sudo ctr -n=k8s.io images tag --force registry.k8s.io/pause:3.8 mirror.example.com/pause:3.8"
+	for _, h := range runner.history {
+		if strings.Contains(h, want) {
+			return
+		}
+	}
+	t.Errorf("RetagImages() ran %q, want %q", runner.history, want)
+}
+
+func TestRetagImagesInvalid(t *testing.T) {
+	runner := NewFakeRunner(t)
+	cr, err := New(Config{Type: "docker", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	if err := cr.RetagImages(map[string]string{"busybox": "Mirror/busybox; rm -rf /"}); err == nil {
+		t.Errorf("RetagImages() of an invalid reference succeeded")
+	}
+	for _, h := range runner.history {
+		if strings.Contains(h, "docker") {
+			t.Errorf("RetagImages() of an invalid reference ran %q", h)
+		}
+	}
+}
+
+// eventSummaries returns the type and subject of each event, to compare them
+func eventSummaries(events []RuntimeEvent) []string {
+	s := []string{}
+	for _, e := range events {
+		s = append(s, fmt.Sprintf("%s %s %s", e.Source, e.Type, e.Subject))
+	}
+	return s
+}
+
+func readFixture(t *testing.T, name string) string {
+	b, err := os.ReadFile("testdata/events/" + name)
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	return string(b)
+}
+
+func TestParseDockerEvents(t *testing.T) {
+	events, err := parseDockerEvents(readFixture(t, "docker.json"))
+	if err != nil {
+		t.Fatalf("parseDockerEvents: %v", err)
+	}
+	id := "sha256:221177c6082a88ea4f6240ab2450d540955ac6f4d5454f0e15751b653ebda165"
+	// starts and successful exits are not incidents
+	want := []string{
+		"docker image-pull registry.k8s.io/pause",
+		"docker oom k8s_nginx_nginx_default_1",
+		"docker container-die k8s_nginx_nginx_default_1",
+		"docker image-delete " + id,
+		"docker image-delete " + id,
+		"docker daemon-reload minikube",
+	}
+	if diff := cmp.Diff(want, eventSummaries(events)); diff != "" {
+		t.Errorf("parseDockerEvents() diff (-want +got):\n%s", diff)
+	}
+	if got := events[2].Message; got != "container die (exit code 137)" {
+		t.Errorf("parseDockerEvents() message = %q", got)
+	}
+	if got := events[0].Time; !got.Equal(time.Unix(0, 1666030810112233445)) {
+		t.Errorf("parseDockerEvents() time = %v", got)
+	}
+	if _, err := parseDockerEvents("{not json"); err == nil {
+		t.Errorf("parseDockerEvents() of invalid output succeeded")
+	}
+}
+
+func TestParseJournalEvents(t *testing.T) {
+	var tests = []struct {
+		fixture  string
+		source   string
+		patterns []journalPattern
+		want     []string
+	}{
+		{"kernel.txt", "kernel", kernelOOMPatterns, []string{"kernel oom nginx", "kernel oom dockerd"}},
+		{"kubelet.txt", "kubelet", kubeletGCPatterns, []string{"kubelet image-delete sha256:221177c6082a88ea4f6240ab2450d540955ac6f4d5454f0e15751b653ebda165"}},
+		{"containerd.txt", "containerd", containerdPatterns, []string{
+			"containerd image-pull registry.k8s.io/pause:3.6",
+			"containerd oom 5f1c0b1e2a3d9c8b7a6f5e4d3c2b1a09",
+			"containerd image-delete registry.k8s.io/pause:3.6",
+		}},
+		{"crio.txt", "crio", crioPatterns, []string{
+			"crio image-pull registry.k8s.io/pause@sha256:3d380ca8864549e74af4b29c10f9cb0956236dfb01c40ca076fb6c37253234db",
+			"crio image-delete registry.k8s.io/pause:3.6",
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.fixture, func(t *testing.T) {
+			events := parseJournalEvents(readFixture(t, tc.fixture), tc.source, tc.patterns)
+			if diff := cmp.Diff(tc.want, eventSummaries(events)); diff != "" {
+				t.Errorf("parseJournalEvents() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	events := parseJournalEvents(readFixture(t, "kernel.txt"), "kernel", kernelOOMPatterns)
+	if got := events[0].Time; !got.Equal(time.Unix(1666030860, 412399000)) {
+		t.Errorf("parseJournalEvents() time = %v", got)
+	}
+	if !strings.HasPrefix(events[0].Message, "Memory cgroup out of memory") {
+		t.Errorf("parseJournalEvents() message = %q", events[0].Message)
+	}
+}
+
+func TestParseUnitEvents(t *testing.T) {
+	events := parseUnitEvents(readFixture(t, "systemd.json"))
+	// the messages of systemd about no unit are left out
+	want := []string{
+		"systemd oom docker.service",
+		"systemd unit docker.service",
+		"systemd oom docker.service",
+		"systemd unit docker.service",
+		"systemd unit docker.service",
+	}
+	if diff := cmp.Diff(want, eventSummaries(events)); diff != "" {
+		t.Errorf("parseUnitEvents() diff (-want +got):\n%s", diff)
+	}
+	if got := events[4].Time; !got.Equal(time.UnixMicro(1666031205000000)) {
+		t.Errorf("parseUnitEvents() time = %v", got)
+	}
+}
+
+func TestSortEvents(t *testing.T) {
+	events := append(parseUnitEvents(readFixture(t, "systemd.json")), parseJournalEvents(readFixture(t, "kernel.txt"), "kernel", kernelOOMPatterns)...)
+	SortEvents(events)
+	for i := 1; i < len(events); i++ {
+		if events[i].Time.Before(events[i-1].Time) {
+			t.Fatalf("SortEvents() left %v before %v", events[i-1], events[i])
+		}
+	}
+	// the kernel killed dockerd before systemd noticed
+	if got := eventSummaries(events)[1]; got != "kernel oom dockerd" {
+		t.Errorf("SortEvents() second event = %q, want the OOM kill of dockerd", got)
+	}
+}
+
+func TestPreloadTarball(t *testing.T) {
+	magics := map[download.Compression][]byte{
+		download.CompressionLZ4:  {0x04, 0x22, 0x4d, 0x18},
+		download.CompressionZstd: {0x28, 0xb5, 0x2f, 0xfd},
+	}
+	var tests = []struct {
+		description string
+		cached      []download.Compression
+		failOn      string
+		want        download.Compression
+		wantErr     bool
+	}{
+		{"zstd preferred", []download.Compression{download.CompressionLZ4, download.CompressionZstd}, "", download.CompressionZstd, false},
+		{"fallback to lz4", []download.Compression{download.CompressionLZ4, download.CompressionZstd}, "which zstd", download.CompressionLZ4, false},
+		{"lz4 only", []download.Compression{download.CompressionLZ4}, "", download.CompressionLZ4, false},
+		{"no decompressor", []download.Compression{download.CompressionZstd}, "which zstd", "", true},
+		{"none cached", nil, "", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			t.Setenv("MINIKUBE_HOME", t.TempDir())
+			for _, c := range tc.cached {
+				p := download.CompressedTarballPath("v1.24.1", "docker", c)
+				if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, magics[c], 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			runner := NewFakeRunner(t)
+			runner.failOn = tc.failOn
+			p, c, err := preloadTarball(runner, "v1.24.1", "docker")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("preloadTarball() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if c != tc.want {
+				t.Errorf("preloadTarball() compression = %q, want %q", c, tc.want)
+			}
+			if err == nil && p != download.CompressedTarballPath("v1.24.1", "docker", tc.want) {
+				t.Errorf("preloadTarball() = %s, want the %s tarball", p, tc.want)
+			}
+			if tc.failOn != "" && len(tc.cached) == 1 {
+				if _, ok := err.(*ErrISOFeature); !ok {
+					t.Errorf("preloadTarball() error = %v, want an ErrISOFeature", err)
+				}
+			}
+		})
+	}
+}
+
+const (
+	coreutilsDF = `IInodes  IFree    Avail
+ 3276800 3100000 41943040
+`
+	busyboxDFSpace = `Filesystem           1024-blocks    Used Available Capacity Mounted on
+/dev/mapper/a-very-long-name-of-a-volume-group
+                      51475068  9531860  41943040  19% /var
+`
+	busyboxDFInodes = `Filesystem              Inodes      Used Available Capacity Mounted on
+/dev/sda1              3276800    176800   3100000   5% /var
+`
+)
+
+func TestParseDF(t *testing.T) {
+	want := diskFree{Inodes: 3276800, FreeInodes: 3100000, FreeBytes: 41943040 * 1024}
+	got, err := parseCoreutilsDF(coreutilsDF)
+	if err != nil || got != want {
+		t.Errorf("parseCoreutilsDF() = %+v, %v, want %+v", got, err, want)
+	}
+	got, err = parseBusyboxDF(busyboxDFSpace, busyboxDFInodes)
+	if err != nil || got != want {
+		t.Errorf("parseBusyboxDF() = %+v, %v, want %+v", got, err, want)
+	}
+	// btrfs does not limit the inodes
+	got, err = parseCoreutilsDF("IInodes IFree Avail\n      0     0  1024\n")
+	if err != nil || got != (diskFree{FreeBytes: 1024 * 1024}) {
+		t.Errorf("parseCoreutilsDF(btrfs) = %+v, %v", got, err)
+	}
+	for _, out := range []string{"", "IInodes IFree Avail", "IInodes IFree Avail\n - - -"} {
+		if _, err := parseCoreutilsDF(out); err == nil {
+			t.Errorf("parseCoreutilsDF(%q) succeeded, want an error", out)
+		}
+	}
+}
+
+func TestParseMountOptions(t *testing.T) {
+	mounts := `overlay / overlay rw,relatime,lowerdir=/a 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+/dev/sda1 /var ext4 rw,noexec,relatime 0 0
+/dev/sda1 /var ext4 rw,relatime 0 0
+/dev/sdb1 /variant ext4 ro 0 0
+`
+	var tests = []struct {
+		dir  string
+		want string
+	}{
+		{"/var", "rw,relatime"},
+		{"/var/lib/docker", "rw,relatime"},
+		{"/variant/x", "ro"},
+		{"/tmp", "rw,relatime,lowerdir=/a"},
+	}
+	for _, tc := range tests {
+		if got := strings.Join(parseMountOptions(mounts, tc.dir), ","); got != tc.want {
+			t.Errorf("parseMountOptions(%s) = %s, want %s", tc.dir, got, tc.want)
+		}
+	}
+}
+
+func TestCheckPreloadSpace(t *testing.T) {
+	// a 400MiB tarball needs 800MiB and 6400 inodes
+	size := 400 * 1024 * 1024
+	var tests = []struct {
+		description string
+		mounts      string
+		df          map[string]string
+		failOn      string
+		factor      float64
+		wantErr     bool
+	}{
+		{"enough space", "", map[string]string{"--output=itotal,iavail,avail": "I F A\n 100000 100000 1048576\n"}, "", 0, false},
+		{"not enough space", "", map[string]string{"--output=itotal,iavail,avail": "I F A\n 100000 100000 512000\n"}, "", 0, true},
+		{"not enough inodes", "", map[string]string{"--output=itotal,iavail,avail": "I F A\n 100000 6000 1048576\n"}, "", 0, true},
+		{"no inode limit", "", map[string]string{"--output=itotal,iavail,avail": "I F A\n 0 0 1048576\n"}, "", 0, false},
+		{"lower factor", "", map[string]string{"--output=itotal,iavail,avail": "I F A\n 100000 100000 512000\n"}, "", 0.5, false},
+		{"skipped", "/dev/sda1 /var ext4 rw,noexec 0 0", nil, "", -1, false},
+		{"busybox", "", map[string]string{"-Pk": busyboxDFSpace, "-Pi": busyboxDFInodes}, "--output", 0, false},
+		{"unknown", "", nil, "", 0, false},
+		{"noexec", "/dev/sda1 /var ext4 rw,noexec 0 0", nil, "", 0, true},
+		{"read-only", "/dev/sda1 / ext4 ro 0 0", nil, "", 0, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.files = map[string]string{"/proc/mounts": tc.mounts}
+			runner.df = tc.df
+			runner.failOn = tc.failOn
+			err := checkPreloadSpace(runner, "/var", size, tc.factor)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("checkPreloadSpace() = %v, wantErr %v", err, tc.wantErr)
+			}
+			if _, ok := err.(*ErrPreloadSpace); err != nil && !ok {
+				t.Errorf("checkPreloadSpace() = %v, want an ErrPreloadSpace", err)
+			}
+		})
+	}
+}
+
+func TestRunDiagnosticContainer(t *testing.T) {
+	defer func(name func() string) { diagnosticName = name }(diagnosticName)
+	diagnosticName = func() string { return "minikube-diagnostic-1" }
+
+	var tests = []struct {
+		runtime string
+		run     string
+		cleanup []string
+	}{
+		{"docker", "docker run --rm --name minikube-diagnostic-1 --network host --label io.x-k8s.minikube.diagnostic=true --env A=1 busybox nslookup kubernetes.default", []string{"docker rm -f minikube-diagnostic-1"}},
+		{"containerd", "sudo /usr/bin/crictl logs --follow ctr1", []string{"sudo /usr/bin/crictl rmp --force pod1", "sudo rm -rf /tmp/minikube-diagnostics/minikube-diagnostic-1"}},
+		{"crio", "sudo /usr/bin/crictl logs --follow ctr1", []string{"sudo /usr/bin/crictl rmp --force pod1", "sudo rm -rf /tmp/minikube-diagnostics/minikube-diagnostic-1"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.runtime, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.diagnosticExitCode = 3
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			code, err := cr.RunDiagnosticContainer(context.Background(), "busybox", []string{"nslookup", "kubernetes.default"}, DiagnosticOptions{Env: []string{"A=1"}})
+			if err != nil {
+				t.Fatalf("RunDiagnosticContainer: %v", err)
+			}
+			if code != 3 {
+				t.Errorf("RunDiagnosticContainer() = %d, want the exit code of the container 3", code)
+			}
+			for _, want := range append([]string{tc.run}, tc.cleanup...) {
+				found := false
+				for _, h := range runner.history {
+					found = found || h == want
+				}
+				if !found {
+					t.Errorf("RunDiagnosticContainer did not run %q: %v", want, runner.history)
+				}
+			}
+			if tc.runtime != "docker" {
+				pod := runner.files["/tmp/minikube-diagnostics/minikube-diagnostic-1/pod.json"]
+				for _, want := range []string{`"namespace":"minikube-diagnostics"`, `"io.x-k8s.minikube.diagnostic":"true"`, `"network":2`} {
+					if !strings.Contains(pod, want) {
+						t.Errorf("pod sandbox configuration %s does not contain %s", pod, want)
+					}
+				}
+			}
+
+			// the container is removed when interrupted
+			runner.history = nil
+			runner.blockOn = tc.run
+			runner.blocked = make(chan struct{})
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() {
+				_, err := cr.RunDiagnosticContainer(ctx, "busybox", []string{"nslookup", "kubernetes.default"}, DiagnosticOptions{Env: []string{"A=1"}})
+				done <- err
+			}()
+			<-runner.blocked
+			cancel()
+			if err := <-done; err != context.Canceled {
+				t.Errorf("RunDiagnosticContainer() = %v when interrupted, want %v", err, context.Canceled)
+			}
+			for _, want := range tc.cleanup {
+				found := false
+				for _, h := range runner.history {
+					found = found || h == want
+				}
+				if !found {
+					t.Errorf("RunDiagnosticContainer did not run %q when interrupted: %v", want, runner.history)
+				}
+			}
+		})
+	}
+}
+
+func TestRunDiagnosticContainerOffline(t *testing.T) {
+	runner := NewFakeRunner(t)
+	cr, err := New(Config{Type: "docker", Runner: runner, Offline: true})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	_, err = cr.RunDiagnosticContainer(context.Background(), "busybox", []string{"true"}, DiagnosticOptions{})
+	if _, ok := err.(*ErrOffline); !ok {
+		t.Errorf("RunDiagnosticContainer() = %v, want an ErrOffline for the missing image", err)
+	}
+	for _, h := range runner.history {
+		if strings.HasPrefix(h, "docker run") {
+			t.Errorf("RunDiagnosticContainer ran %q while offline", h)
+		}
+	}
+}
+
+func TestOwnedDaemonConfig(t *testing.T) {
+	owned, err := ownedDaemonConfig(daemonSettings{mirrors: []string{"https://mirror.example"}, mtu: 1400, nvidia: true, features: []string{"buildkit=true", "other=on"}})
+	if err != nil {
+		t.Fatalf("ownedDaemonConfig: %v", err)
+	}
+	got := map[string]string{}
+	for k, v := range owned {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal %s: %v", k, err)
+		}
+		got[k] = string(b)
+	}
+	want := map[string]string{
+		"registry-mirrors":  `["https://mirror.example"]`,
+		"mtu":               `1400`,
+		"runtimes.nvidia":   `{"args":[],"path":"nvidia-container-runtime"}`,
+		"default-runtime":   `"nvidia"`,
+		"features.buildkit": `true`,
+		"features.other":    `"on"`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ownedDaemonConfig() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ownedDaemonConfig(daemonSettings{features: []string{"buildkit"}}); err == nil {
+		t.Errorf("ownedDaemonConfig(buildkit) succeeded, want an error")
+	}
+}
+
+func TestCompareRuntimeState(t *testing.T) {
+	dropIn := "[Service]\nExecStart=\nExecStart=/usr/bin/cri-dockerd --network-plugin=cni"
+	expected := RuntimeState{
+		DaemonConfig:    map[string]string{"exec-opts": `["native.cgroupdriver=systemd"]`, "features.buildkit": `true`},
+		CRIDockerDropIn: dropIn,
+		Units:           expectedDockerUnits("cri-docker.socket"),
+		CgroupDriver:    "systemd",
+	}
+	live := func(edit func(s *RuntimeState)) RuntimeState {
+		s := RuntimeState{
+			DaemonConfig:    map[string]string{"exec-opts": `["native.cgroupdriver=systemd"]`, "features.buildkit": `true`},
+			CRIDockerDropIn: dropIn,
+			Units:           map[string]string{"docker.service": "disabled", "docker.socket": "enabled", "cri-docker.socket": "enabled"},
+			CgroupDriver:    "systemd",
+		}
+		edit(&s)
+		return s
+	}
+	tests := []struct {
+		name string
+		live RuntimeState
+		want []Drift
+	}{
+		{
+			name: "as expected",
+			live: live(func(s *RuntimeState) {}),
+			want: []Drift{},
+		},
+		{
+			name: "user flags merged into the drop-in",
+			live: live(func(s *RuntimeState) { s.CRIDockerDropIn += " --log-level=debug" }),
+			want: []Drift{},
+		},
+		{
+			name: "daemon.json edited",
+			live: live(func(s *RuntimeState) {
+				s.DaemonConfig["features.buildkit"] = `false`
+				delete(s.DaemonConfig, "exec-opts")
+			}),
+			want: []Drift{
+				{Setting: "daemon.json exec-opts", Expected: `["native.cgroupdriver=systemd"]`},
+				{Setting: "daemon.json features.buildkit", Expected: `true`, Actual: `false`},
+			},
+		},
+		{
+			name: "drop-in replaced",
+			live: live(func(s *RuntimeState) {
+				s.CRIDockerDropIn = "[Service]\nExecStart=\nExecStart=/usr/bin/cri-dockerd --network-plugin=kubenet"
+			}),
+			want: []Drift{{Setting: criDockerServiceConfFile, Expected: "/usr/bin/cri-dockerd --network-plugin=cni", Actual: "/usr/bin/cri-dockerd --network-plugin=kubenet"}},
+		},
+		{
+			name: "units masked and disabled",
+			live: live(func(s *RuntimeState) {
+				s.Units["docker.service"] = "masked"
+				s.Units["cri-docker.socket"] = "disabled"
+			}),
+			want: []Drift{
+				{Setting: "unit cri-docker.socket", Expected: "enabled", Actual: "disabled"},
+				{Setting: "unit docker.service", Expected: unitUnmasked, Actual: "masked"},
+			},
+		},
+		{
+			name: "cgroup driver",
+			live: live(func(s *RuntimeState) { s.CgroupDriver = "cgroupfs" }),
+			want: []Drift{{Setting: "cgroup driver", Expected: "systemd", Actual: "cgroupfs"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CompareRuntimeState(expected, tc.live)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("CompareRuntimeState() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDockerExpectedState(t *testing.T) {
+	r := &Docker{KubernetesVersion: semver.MustParse("1.24.0"), CRIService: "cri-docker.socket", RegistryMirrors: []string{"https://mirror.example"}}
+	got, err := r.ExpectedState(StateOptions{ForceSystemd: true, NetworkPlugin: "cni"})
+	if err != nil {
+		t.Fatalf("ExpectedState: %v", err)
+	}
+	if got.DaemonConfig["exec-opts"] != `["native.cgroupdriver=systemd"]` || got.DaemonConfig["registry-mirrors"] != `["https://mirror.example"]` {
+		t.Errorf("DaemonConfig = %v, want the systemd settings and the mirrors", got.DaemonConfig)
+	}
+	if !strings.Contains(execStartLine(got.CRIDockerDropIn), "--network-plugin=cni") {
+		t.Errorf("CRIDockerDropIn = %q, want the cni network plugin", got.CRIDockerDropIn)
+	}
+	if diff := cmp.Diff(expectedDockerUnits("cri-docker.socket"), got.Units); diff != "" {
+		t.Errorf("Units mismatch (-want +got):\n%s", diff)
+	}
+	if got.CgroupDriver != "systemd" {
+		t.Errorf("CgroupDriver = %q, want systemd", got.CgroupDriver)
+	}
+
+	r.KubernetesVersion = semver.MustParse("1.23.0")
+	got, err = r.ExpectedState(StateOptions{NetworkPlugin: "cni"})
+	if err != nil {
+		t.Fatalf("ExpectedState: %v", err)
+	}
+	if got.CRIDockerDropIn != "" || got.CgroupDriver != "" || len(got.DaemonConfig) != 1 {
+		t.Errorf("ExpectedState(1.23.0) = %+v, want only the mirrors", got)
+	}
+}
+
+func TestDockerLiveState(t *testing.T) {
+	runner := NewFakeRunner(t)
+	for _, svc := range []string{"docker", "docker.socket", "cri-docker.socket"} {
+		runner.services[svc] = SvcRunning
+	}
+	runner.masked["docker"] = true
+	runner.files = map[string]string{
+		"/etc/docker/daemon.json": `{"exec-opts":["native.cgroupdriver=systemd"],"features":{"buildkit":false},"insecure-registries":["10.0.0.1:5000"]}`,
+		criDockerServiceConfFile:  "[Service]\nExecStart=\nExecStart=/usr/bin/cri-dockerd --network-plugin=kubenet",
+	}
+	cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.0")})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	v := cr.(DriftVerifier)
+	expected, err := v.ExpectedState(StateOptions{ForceSystemd: true, NetworkPlugin: "cni"})
+	if err != nil {
+		t.Fatalf("ExpectedState: %v", err)
+	}
+	expected.DaemonConfig["features.buildkit"] = `true`
+	live, err := v.LiveState(expected)
+	if err != nil {
+		t.Fatalf("LiveState: %v", err)
+	}
+	got := []string{}
+	for _, d := range CompareRuntimeState(expected, live) {
+		got = append(got, d.Setting)
+	}
+	want := []string{
+		criDockerServiceConfFile,
+		"cgroup driver",
+		"daemon.json features.buildkit",
+		"daemon.json log-driver",
+		"daemon.json log-opts",
+		"daemon.json storage-driver",
+		"unit docker.service",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("drifts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolvePathConfig(t *testing.T) {
+	var tests = []struct {
+		description string
+		mounts      string
+		want        PathConfig
+	}{
+		{
+			description: "writable",
+			mounts:      "overlay / overlay rw,relatime 0 0\n",
+			want:        DefaultPathConfig,
+		},
+		{
+			description: "unknown",
+			want:        DefaultPathConfig,
+		},
+		{
+			description: "read-only root with writable /var",
+			mounts:      "/dev/root / ext4 ro,relatime 0 0\n/dev/sda1 /var ext4 rw,relatime 0 0\ntmpfs /run tmpfs rw 0 0\n",
+			want: PathConfig{
+				ConfigDir:        "/var/lib/minikube/etc/docker",
+				BuilderConfigDir: "/var/lib/minikube/etc/docker-builder",
+				SystemdDir:       "/run/systemd/system",
+				PreloadDir:       "/var",
+				PreloadRoot:      "/var",
+				Binds:            []BindMount{{Source: "/var/lib/minikube/etc/docker", Target: "/etc/docker"}},
+			},
+		},
+		{
+			description: "read-only etc with writable /etc/docker",
+			mounts:      "/dev/root / ext4 ro,relatime 0 0\n/dev/sda1 /var ext4 rw,relatime 0 0\n/dev/sda1 /etc/docker ext4 rw,relatime 0 0\n",
+			want: PathConfig{
+				ConfigDir:        "/etc/docker",
+				BuilderConfigDir: "/var/lib/minikube/etc/docker-builder",
+				SystemdDir:       "/run/systemd/system",
+				PreloadDir:       "/var",
+				PreloadRoot:      "/var",
+			},
+		},
+		{
+			description: "read-only root and /var",
+			mounts:      "/dev/root / ext4 ro 0 0\n/dev/sda1 /var ext4 ro 0 0\ntmpfs /run tmpfs rw 0 0\n",
+			want: PathConfig{
+				ConfigDir:        "/run/minikube/etc/docker",
+				BuilderConfigDir: "/run/minikube/etc/docker-builder",
+				SystemdDir:       "/run/systemd/system",
+				PreloadDir:       "/var",
+				PreloadRoot:      "/var",
+				Binds:            []BindMount{{Source: "/run/minikube/etc/docker", Target: "/etc/docker"}},
+			},
+		},
+		{
+			description: "ostree",
+			mounts:      "/dev/vda4 / xfs ro,relatime 0 0\n/dev/vda4 /etc xfs rw,relatime 0 0\n/dev/vda4 /usr xfs ro,relatime 0 0\n/dev/vda4 /var xfs rw,relatime 0 0\n",
+			want: PathConfig{
+				ConfigDir:        "/etc/docker",
+				BuilderConfigDir: "/etc/docker-builder",
+				SystemdDir:       "/etc/systemd/system",
+				CRIConfigFile:    "/etc/crictl.yaml",
+				PreloadDir:       "/var",
+				PreloadRoot:      "/var",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, resolvePathConfig(tc.mounts)); diff != "" {
+				t.Errorf("resolvePathConfig() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPathConfigSystemdPath(t *testing.T) {
+	p := PathConfig{SystemdDir: "/run/systemd/system"}
+	if got, want := p.systemdPath(criDockerServiceConfFile), "/run/systemd/system/cri-docker.service.d/05-minikube-cni.conf"; got != want {
+		t.Errorf("systemdPath(%s) = %s, want %s", criDockerServiceConfFile, got, want)
+	}
+	if got := DefaultPathConfig.systemdPath(builderUnitFile); got != builderUnitFile {
+		t.Errorf("systemdPath(%s) = %s with the default paths", builderUnitFile, got)
+	}
+}
+
+func TestEnableDockerReadOnlyRoot(t *testing.T) {
+	var tests = []struct {
+		description string
+		mounts      string
+		daemonJSON  string
+		bind        string
+	}{
+		{
+			description: "writable /etc/docker",
+			mounts:      "/dev/root / ext4 ro 0 0\n/dev/sda1 /var ext4 rw 0 0\n/dev/sda1 /etc/docker ext4 rw 0 0\ntmpfs /run tmpfs rw 0 0\n",
+			daemonJSON:  "/etc/docker/daemon.json",
+		},
+		{
+			description: "read-only /etc/docker",
+			mounts:      "/dev/root / ext4 ro 0 0\n/dev/sda1 /var ext4 rw 0 0\ntmpfs /run tmpfs rw 0 0\n",
+			daemonJSON:  "/var/lib/minikube/etc/docker/daemon.json",
+			bind:        "sudo mount --bind /var/lib/minikube/etc/docker /etc/docker",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			runner.files = map[string]string{"/proc/mounts": tc.mounts}
+			for k, v := range map[string]serviceState{"docker": SvcExited, "cri-docker.socket": SvcExited, "cri-docker": SvcExited} {
+				runner.services[k] = v
+			}
+			cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.1")})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			// the files written to the read-only filesystems fail to copy
+			if err := cr.Enable(true, true, false); err != nil {
+				t.Fatalf("Enable: %v", err)
+			}
+			if err := ConfigureNetworkPlugin(cr, runner, "cni"); err != nil {
+				t.Fatalf("ConfigureNetworkPlugin: %v", err)
+			}
+			if !strings.Contains(runner.files[tc.daemonJSON], `"exec-opts"`) {
+				t.Errorf("daemon.json was not written to %s: %v", tc.daemonJSON, runner.copied)
+			}
+			if dropIn := "/run/systemd/system/cri-docker.service.d/05-minikube-cni.conf"; !strings.Contains(runner.files[dropIn], "--network-plugin=cni") {
+				t.Errorf("the cri-docker drop-in was not written to %s: %v", dropIn, runner.copied)
+			}
+			for _, c := range runner.history {
+				if strings.Contains(c, crictlConfigFile) {
+					t.Errorf("wrote the read-only %s: %s", crictlConfigFile, c)
+				}
+			}
+			if tc.bind != "" && !containsString(runner.history, tc.bind) {
+				t.Errorf("%s was not run: %v", tc.bind, runner.history)
+			}
+			// restarting does not mount over the directories again
+			runner.history = nil
+			if err := cr.Enable(true, true, false); err != nil {
+				t.Fatalf("Enable again: %v", err)
+			}
+			for _, c := range runner.history {
+				if strings.Contains(c, "mount --bind") || strings.Contains(c, "cp -an") {
+					t.Errorf("mounted over %s again: %s", DefaultPathConfig.ConfigDir, c)
+				}
+			}
+		})
+	}
+}
+
+// groupCommands returns the commands of history setting up the docker group and the access to the sockets
+func groupCommands(history []string) []string {
+	cmds := []string{}
+	for _, h := range history {
+		c := strings.TrimPrefix(h, "sudo ")
+		for _, prefix := range []string{"getent ", "id ", "groupadd ", "usermod ", "stat -c %G %a ", "chgrp ", "chmod g+rw "} {
+			if strings.HasPrefix(c, prefix) {
+				cmds = append(cmds, h)
+			}
+		}
+	}
+	return cmds
+}
+
+func TestEnableDockerGroup(t *testing.T) {
+	var tests = []struct {
+		description string
+		user        string
+		bareMetal   bool
+		driver      string
+		criSocket   bool
+		groups      map[string][]string
+		sockets     map[string]string
+		want        []string
+	}{
+		{
+			description: "configured",
+			user:        "docker",
+			want: []string{
+				"getent group docker",
+				"id -nG docker",
+				"sudo stat -c %G %a /var/run/docker.sock",
+			},
+		},
+		{
+			description: "missing group",
+			user:        "docker",
+			groups:      map[string][]string{},
+			sockets:     map[string]string{"/var/run/docker.sock": "root 660"},
+			want: []string{
+				"getent group docker",
+				"sudo groupadd --system docker",
+				"id -nG docker",
+				"sudo usermod -aG docker docker",
+				"sudo stat -c %G %a /var/run/docker.sock",
+				"sudo chgrp docker /var/run/docker.sock",
+			},
+		},
+		{
+			description: "user not in the group",
+			user:        "docker",
+			groups:      map[string][]string{"docker": {}},
+			want: []string{
+				"getent group docker",
+				"id -nG docker",
+				"sudo usermod -aG docker docker",
+				"sudo stat -c %G %a /var/run/docker.sock",
+			},
+		},
+		{
+			description: "root",
+			user:        "root",
+			want: []string{
+				"getent group docker",
+				"sudo stat -c %G %a /var/run/docker.sock",
+			},
+		},
+		{
+			description: "cri-dockerd socket",
+			user:        "docker",
+			criSocket:   true,
+			sockets:     map[string]string{"/var/run/docker.sock": "docker 600", ExternalDockerCRISocket: "root 600"},
+			want: []string{
+				"getent group docker",
+				"id -nG docker",
+				"sudo stat -c %G %a /var/run/docker.sock",
+				"sudo chmod g+rw /var/run/docker.sock",
+				"sudo stat -c %G %a /var/run/cri-dockerd.sock",
+				"sudo chgrp docker /var/run/cri-dockerd.sock",
+				"sudo chmod g+rw /var/run/cri-dockerd.sock",
+			},
+		},
+		{
+			description: "bare metal",
+			user:        "",
+			bareMetal:   true,
+			groups:      map[string][]string{},
+			want:        []string{},
+		},
+		{
+			description: "ssh",
+			user:        "",
+			driver:      driver.SSH,
+			criSocket:   true,
+			groups:      map[string][]string{},
+			want:        []string{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := NewFakeRunner(t)
+			for k, v := range defaultServices {
+				runner.services[k] = v
+			}
+			runner.services["cri-docker"] = SvcRunning
+			runner.services["cri-docker.socket"] = SvcRunning
+			if tc.groups != nil {
+				runner.groups = tc.groups
+			}
+			if tc.sockets != nil {
+				runner.sockets = tc.sockets
+			}
+			cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.1"), BareMetal: tc.bareMetal, Driver: tc.driver, DockerGroupUser: tc.user, CRIDockerdSocketGroup: tc.criSocket})
+			if err != nil {
+				t.Fatalf("New(docker): %v", err)
+			}
+			if err := cr.Enable(true, false, false); err != nil {
+				t.Fatalf("Enable: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, groupCommands(runner.history)); diff != "" {
+				t.Errorf("Enable() ran unexpected commands (-want +got):\n%s", diff)
+			}
+
+			// once set up, enabling docker again only checks the group and the sockets
+			runner.history = nil
+			if err := cr.Enable(true, false, false); err != nil {
+				t.Fatalf("Enable: %v", err)
+			}
+			for _, c := range groupCommands(runner.history) {
+				for _, change := range []string{"groupadd", "usermod", "chgrp", "chmod"} {
+					if strings.Contains(c, change) {
+						t.Errorf("enabling docker again ran %q", c)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDockerSocketAccessAfterDeferredRestart(t *testing.T) {
+	runner := NewFakeRunner(t)
+	for k, v := range defaultServices {
+		runner.services[k] = v
+	}
+	runner.services["cri-docker"] = SvcRunning
+	runner.services["cri-docker.socket"] = SvcRunning
+	runner.sockets["/var/run/docker.sock"] = "root 660"
+	cr, err := New(Config{Type: "docker", Runner: runner, KubernetesVersion: semver.MustParse("1.24.1"), DockerGroupUser: "docker", DeferRestarts: true})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+	if err := cr.Enable(true, false, false); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	if runner.sockets["/var/run/docker.sock"] != "root 660" {
+		t.Errorf("the socket of docker changed to %q before docker restarted", runner.sockets["/var/run/docker.sock"])
+	}
+	if err := cr.ApplyPendingRestart(); err != nil {
+		t.Fatalf("ApplyPendingRestart: %v", err)
+	}
+	if got := runner.sockets["/var/run/docker.sock"]; got != "docker 660" {
+		t.Errorf("the socket of docker is %q once restarted, want \"docker 660\"", got)
+	}
+}
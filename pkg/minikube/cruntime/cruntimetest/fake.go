@@ -0,0 +1,883 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cruntimetest provides an in-memory container runtime, for the tests of the code using cruntime.Manager
+package cruntimetest
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+// Container states of a FakeContainer
+const (
+	StateRunning = "running"
+	StatePaused  = "paused"
+	StateExited  = "exited"
+)
+
+// FakeContainer is a container of a FakeRuntime
+type FakeContainer struct {
+	Name      string
+	Pod       string
+	PodUID    string
+	Namespace string
+	Image     string
+	// State is one of StateRunning, StatePaused or StateExited
+	State   string
+	Created time.Time
+	// Ports are the ports the container exposes or publishes
+	Ports []cruntime.PortMapping
+}
+
+// FakeDiagnostic is what a diagnostic container of a FakeRuntime prints and exits with
+type FakeDiagnostic struct {
+	Output   string
+	ExitCode int
+}
+
+// FakeRuntime is a cruntime.Manager keeping its images and containers in memory, and recording the calls made to it
+type FakeRuntime struct {
+	mu sync.Mutex
+
+	// RuntimeName is returned by Name
+	RuntimeName string
+	// RuntimeVersion is returned by Version
+	RuntimeVersion string
+	// Images are the IDs of the images, by name
+	Images map[string]string
+	// Archives are the names of the images loaded by LoadImage, by archive path
+	Archives map[string]string
+	// Stores are the names of the images in the archives written by ExportImageStore, by archive path
+	Stores map[string][]string
+	// PreloadedImages are the images added by Preload
+	PreloadedImages []string
+	// Containers are the containers, by ID
+	Containers map[string]*FakeContainer
+	// Logs are the lines of the logs of the containers, by ID
+	Logs map[string][]string
+	// Checkpoints are the IDs of the containers checkpointed by CheckpointContainer, by tarball path
+	Checkpoints map[string]string
+	// Copies are the files copied into containers by CopyToContainer, as ID:DIR, by source path
+	Copies map[string]string
+	// Diagnostics are the results of the diagnostic containers of RunDiagnosticContainer, by image
+	Diagnostics map[string]FakeDiagnostic
+	// RuntimeEvents are returned by Events, when they are within its period
+	RuntimeEvents []cruntime.RuntimeEvent
+	// Caps is returned by Capabilities
+	Caps cruntime.Capabilities
+	// Errors are returned by the methods named by their keys, instead of calling them
+	Errors map[string]error
+	// Calls are the calls made, as the method name followed by the arguments
+	Calls []string
+
+	active bool
+}
+
+// NewFakeRuntime returns an active FakeRuntime without images or containers
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{
+		RuntimeName:    "fake",
+		RuntimeVersion: "1.0.0",
+		Images:         map[string]string{},
+		Archives:       map[string]string{},
+		Stores:         map[string][]string{},
+		Containers:     map[string]*FakeContainer{},
+		Logs:           map[string][]string{},
+		Checkpoints:    map[string]string{},
+		Copies:         map[string]string{},
+		Diagnostics:    map[string]FakeDiagnostic{},
+		Errors:         map[string]error{},
+		Caps:           cruntime.Capabilities{SupportsBuild: true, SupportsPause: true},
+		active:         true,
+	}
+}
+
+var _ cruntime.Manager = &FakeRuntime{}
+
+// Fail makes the method fail with err, until Fail is called again with a nil err
+func (f *FakeRuntime) Fail(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.Errors, method)
+		return
+	}
+	f.Errors[method] = err
+}
+
+// Called returns the calls made to method, without the method name
+func (f *FakeRuntime) Called(method string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := []string{}
+	for _, c := range f.Calls {
+		if c == method || strings.HasPrefix(c, method+" ") {
+			calls = append(calls, strings.TrimSpace(strings.TrimPrefix(c, method)))
+		}
+	}
+	return calls
+}
+
+// AddImage adds an image, with an ID derived from its name
+func (f *FakeRuntime) AddImage(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Images[name] = imageID(name)
+	return f.Images[name]
+}
+
+// AddContainer adds a container, running unless state is set
+func (f *FakeRuntime) AddContainer(id string, c FakeContainer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c.State == "" {
+		c.State = StateRunning
+	}
+	f.Containers[id] = &c
+}
+
+// call records a call and returns the error injected for the method, the lock must be held
+func (f *FakeRuntime) call(method string, args ...interface{}) error {
+	c := method
+	for _, a := range args {
+		c += fmt.Sprintf(" %v", a)
+	}
+	f.Calls = append(f.Calls, c)
+	return f.Errors[method]
+}
+
+// imageID returns a stable image ID for name
+func imageID(name string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(name)))
+}
+
+// Name is a human readable name for the runtime
+func (f *FakeRuntime) Name() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("Name")
+	return f.RuntimeName
+}
+
+// Version retrieves the current version of the runtime
+func (f *FakeRuntime) Version() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Version"); err != nil {
+		return "", err
+	}
+	return f.RuntimeVersion, nil
+}
+
+// Enable activates the runtime
+func (f *FakeRuntime) Enable(disOthers, forceSystemd, inUserNamespace bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Enable", disOthers, forceSystemd, inUserNamespace); err != nil {
+		return err
+	}
+	f.active = true
+	return nil
+}
+
+// Disable deactivates the runtime
+func (f *FakeRuntime) Disable() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Disable"); err != nil {
+		return err
+	}
+	f.active = false
+	return nil
+}
+
+// Active returns whether the runtime is enabled
+func (f *FakeRuntime) Active() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("Active")
+	return f.active
+}
+
+// Available returns the error injected for Available
+func (f *FakeRuntime) Available() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.call("Available")
+}
+
+// RuntimeHealth reports the runtime as running while it is active, and stopped otherwise
+func (f *FakeRuntime) RuntimeHealth() (*cruntime.Health, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("RuntimeHealth"); err != nil {
+		return nil, err
+	}
+	if !f.active {
+		return &cruntime.Health{State: cruntime.HealthStopped, Reason: "disabled"}, nil
+	}
+	return &cruntime.Health{State: cruntime.HealthRunning, Responsive: true}, nil
+}
+
+// EnsurePauseImage does nothing, the fake runtime not knowing its Kubernetes version
+func (f *FakeRuntime) EnsurePauseImage() (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return false, f.call("EnsurePauseImage")
+}
+
+// Healthy returns an error while the runtime is disabled
+func (f *FakeRuntime) Healthy() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Healthy"); err != nil {
+		return err
+	}
+	if !f.active {
+		return fmt.Errorf("%s is disabled", f.RuntimeName)
+	}
+	return nil
+}
+
+// Events returns the RuntimeEvents within the period, oldest first
+func (f *FakeRuntime) Events(since time.Duration) ([]cruntime.RuntimeEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Events", since); err != nil {
+		return nil, err
+	}
+	events := []cruntime.RuntimeEvent{}
+	for _, e := range f.RuntimeEvents {
+		if time.Since(e.Time) <= since {
+			events = append(events, e)
+		}
+	}
+	cruntime.SortEvents(events)
+	return events, nil
+}
+
+// Style is the style for Name
+func (f *FakeRuntime) Style() style.Enum {
+	return style.Empty
+}
+
+// CGroupDriver returns the cgroupfs driver
+func (f *FakeRuntime) CGroupDriver() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("CGroupDriver"); err != nil {
+		return "", err
+	}
+	return "cgroupfs", nil
+}
+
+// KubeletOptions returns the options pointing the kubelet at SocketPath
+func (f *FakeRuntime) KubeletOptions() map[string]string {
+	return map[string]string{
+		"container-runtime":          "remote",
+		"container-runtime-endpoint": f.SocketPath(),
+	}
+}
+
+// SocketPath returns the path to the socket of the runtime
+func (f *FakeRuntime) SocketPath() string {
+	return "/var/run/fake.sock"
+}
+
+// LoadImage adds the image of the archive at path, as set in Archives
+func (f *FakeRuntime) LoadImage(path string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("LoadImage", path); err != nil {
+		return nil, err
+	}
+	name, ok := f.Archives[path]
+	if !ok {
+		return []string{}, nil
+	}
+	f.Images[name] = imageID(name)
+	return []string{name}, nil
+}
+
+// PullImage adds the image
+func (f *FakeRuntime) PullImage(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("PullImage", name); err != nil {
+		return err
+	}
+	if _, ok := f.Images[name]; !ok {
+		f.Images[name] = imageID(name)
+	}
+	return nil
+}
+
+// BuildImage adds the image named by tag
+func (f *FakeRuntime) BuildImage(src string, opts cruntime.BuildOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("BuildImage", src, opts.File, opts.Tag, opts.Push); err != nil {
+		return err
+	}
+	if opts.Tag != "" {
+		f.Images[opts.Tag] = imageID(src + opts.Tag)
+	}
+	return nil
+}
+
+// SaveImage saves an existing image to path, recording it in Archives
+func (f *FakeRuntime) SaveImage(name string, path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("SaveImage", name, path); err != nil {
+		return err
+	}
+	if _, ok := f.Images[name]; !ok {
+		return errors.Errorf("no such image: %s", name)
+	}
+	f.Archives[path] = name
+	return nil
+}
+
+// ExportImageStore records the names of all the images as the archive at path
+func (f *FakeRuntime) ExportImageStore(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ExportImageStore", path); err != nil {
+		return err
+	}
+	if len(f.Images) == 0 {
+		return errors.Errorf("%s has no images to export", f.RuntimeName)
+	}
+	names := []string{}
+	for name := range f.Images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	f.Stores[path] = names
+	return nil
+}
+
+// ImportImageStore adds the images of the archive at path
+func (f *FakeRuntime) ImportImageStore(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ImportImageStore", path); err != nil {
+		return err
+	}
+	names, ok := f.Stores[path]
+	if !ok {
+		return errors.Errorf("no such archive: %s", path)
+	}
+	for _, name := range names {
+		f.Images[name] = imageID(name)
+	}
+	return nil
+}
+
+// TagImage gives the ID of source to target
+func (f *FakeRuntime) TagImage(source string, target string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("TagImage", source, target); err != nil {
+		return err
+	}
+	id, ok := f.Images[source]
+	if !ok {
+		return errors.Errorf("no such image: %s", source)
+	}
+	f.Images[target] = id
+	return nil
+}
+
+// RetagImages gives the ID of each source to its target, tagging none of them if any source does not exist
+func (f *FakeRuntime) RetagImages(mapping map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("RetagImages", mapping); err != nil {
+		return err
+	}
+	for source := range mapping {
+		if _, ok := f.Images[source]; !ok {
+			return errors.Errorf("no such image: %s", source)
+		}
+	}
+	for source, target := range mapping {
+		f.Images[target] = f.Images[source]
+	}
+	return nil
+}
+
+// PushImage checks that the image exists
+func (f *FakeRuntime) PushImage(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("PushImage", name); err != nil {
+		return err
+	}
+	if _, ok := f.Images[name]; !ok {
+		return errors.Errorf("no such image: %s", name)
+	}
+	return nil
+}
+
+// ImageExists returns whether the image exists, with the ID sha if set
+func (f *FakeRuntime) ImageExists(name string, sha string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ImageExists", name, sha)
+	id, ok := f.Images[name]
+	return ok && (sha == "" || strings.TrimPrefix(sha, "sha256:") == id)
+}
+
+// ImagesExist returns which of the images exist
+func (f *FakeRuntime) ImagesExist(names []string) (map[string]bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ImagesExist", names); err != nil {
+		return nil, err
+	}
+	exist := map[string]bool{}
+	for _, name := range names {
+		_, exist[name] = f.Images[name]
+	}
+	return exist, nil
+}
+
+// ImageID returns the ID of the image
+func (f *FakeRuntime) ImageID(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ImageID", name); err != nil {
+		return "", err
+	}
+	id, ok := f.Images[name]
+	if !ok {
+		return "", errors.Errorf("no such image: %s", name)
+	}
+	return id, nil
+}
+
+// ListImages returns the images matching opts, with the names sharing an ID grouped together
+func (f *FakeRuntime) ListImages(opts cruntime.ListImagesOptions) ([]cruntime.ListImage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ListImages"); err != nil {
+		return nil, err
+	}
+	byID := map[string]*cruntime.ListImage{}
+	ids := []string{}
+	for name, id := range f.Images {
+		if _, ok := byID[id]; !ok {
+			byID[id] = &cruntime.ListImage{ID: id, Size: "0"}
+			ids = append(ids, id)
+		}
+		byID[id].RepoTags = append(byID[id].RepoTags, name)
+	}
+	sort.Strings(ids)
+	images := []cruntime.ListImage{}
+	for _, id := range ids {
+		sort.Strings(byID[id].RepoTags)
+		images = append(images, *byID[id])
+	}
+	return cruntime.FilterImages(images, opts)
+}
+
+// ImageHistory returns a single empty layer for an existing image
+func (f *FakeRuntime) ImageHistory(name string) ([]cruntime.LayerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ImageHistory", name); err != nil {
+		return nil, err
+	}
+	id, ok := f.Images[name]
+	if !ok {
+		return nil, errors.Errorf("no such image: %s", name)
+	}
+	return []cruntime.LayerInfo{{Digest: "sha256:" + id}}, nil
+}
+
+// ImageScanTarget tells scanners to scan the archives saved by SaveImage
+func (f *FakeRuntime) ImageScanTarget() cruntime.ImageScanTarget {
+	return cruntime.ImageScanTarget{Archive: true}
+}
+
+// Capabilities returns Caps
+func (f *FakeRuntime) Capabilities() cruntime.Capabilities {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Caps
+}
+
+// RemoveImage removes the image, failing like the real runtimes if it does not exist
+func (f *FakeRuntime) RemoveImage(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("RemoveImage", name); err != nil {
+		return err
+	}
+	if _, ok := f.Images[name]; !ok {
+		return errors.Errorf("Error: No such image: %s", name)
+	}
+	delete(f.Images, name)
+	return nil
+}
+
+// ForceRemoveImage removes the image with all the names sharing its ID
+func (f *FakeRuntime) ForceRemoveImage(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ForceRemoveImage", name); err != nil {
+		return err
+	}
+	id, ok := f.Images[name]
+	if !ok {
+		return errors.Errorf("Error: No such image: %s", name)
+	}
+	for n, i := range f.Images {
+		if i == id {
+			delete(f.Images, n)
+		}
+	}
+	return nil
+}
+
+// matches returns whether c is selected by o
+func matches(c *FakeContainer, o cruntime.ListContainersOptions) bool {
+	switch o.State {
+	case cruntime.Running:
+		if c.State != StateRunning {
+			return false
+		}
+	case cruntime.Paused:
+		if c.State != StatePaused {
+			return false
+		}
+	}
+	if o.Name != "" && !strings.Contains(c.Name, o.Name) {
+		return false
+	}
+	if o.PodUID != "" && c.PodUID != o.PodUID {
+		return false
+	}
+	return o.SelectsNamespace(c.Namespace)
+}
+
+// listContainers returns the sorted IDs of the containers selected by o, the lock must be held
+func (f *FakeRuntime) listContainers(o cruntime.ListContainersOptions) []string {
+	ids := []string{}
+	for id, c := range f.Containers {
+		if matches(c, o) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ListContainers returns the IDs of the containers selected by o
+func (f *FakeRuntime) ListContainers(o cruntime.ListContainersOptions) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ListContainers", o.State, o.Name, o.Namespaces); err != nil {
+		return nil, err
+	}
+	return f.listContainers(o), nil
+}
+
+// ListContainerInfo returns the containers selected by o
+func (f *FakeRuntime) ListContainerInfo(o cruntime.ListContainersOptions) ([]cruntime.ContainerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ListContainerInfo", o.State, o.Name, o.Namespaces); err != nil {
+		return nil, err
+	}
+	infos := []cruntime.ContainerInfo{}
+	for _, id := range f.listContainers(o) {
+		c := f.Containers[id]
+		infos = append(infos, cruntime.ContainerInfo{ID: id, Name: c.Name, Pod: c.Pod, PodUID: c.PodUID, Namespace: c.Namespace, State: c.State, Image: c.Image, Created: c.Created})
+	}
+	return infos, nil
+}
+
+// CheckpointContainer records the checkpoint of a running container in Checkpoints
+func (f *FakeRuntime) CheckpointContainer(id string, destPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("CheckpointContainer", id, destPath); err != nil {
+		return err
+	}
+	c, ok := f.Containers[id]
+	if !ok || c.State != StateRunning {
+		return fmt.Errorf("container %s is not running", id)
+	}
+	f.Checkpoints[destPath] = id
+	return nil
+}
+
+// PortMappings returns the ports of a container, given by its ID or a prefix of it
+func (f *FakeRuntime) PortMappings(id string) ([]cruntime.PortMapping, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("PortMappings", id); err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for cid := range f.Containers {
+		if strings.HasPrefix(cid, id) {
+			ids = append(ids, cid)
+		}
+	}
+	if len(ids) != 1 {
+		return nil, errors.Errorf("no such container: %s", id)
+	}
+	return append([]cruntime.PortMapping{}, f.Containers[ids[0]].Ports...), nil
+}
+
+// CopyToContainer records the file copied into a container in Copies
+func (f *FakeRuntime) CopyToContainer(c cruntime.ContainerInfo, src string, dir string, o cruntime.CopyOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("CopyToContainer", c.ID, src, dir, o); err != nil {
+		return err
+	}
+	if _, ok := f.Containers[c.ID]; !ok {
+		return fmt.Errorf("no such container: %s", c.ID)
+	}
+	f.Copies[src] = c.ID + ":" + dir
+	return nil
+}
+
+// GarbageCollect removes the exited containers created longer ago than age
+func (f *FakeRuntime) GarbageCollect(age time.Duration) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("GarbageCollect", age); err != nil {
+		return 0, err
+	}
+	removed := 0
+	for id, c := range f.Containers {
+		if c.State == StateExited && time.Since(c.Created) >= age {
+			delete(f.Containers, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// PrepareStop stops the running containers, then the runtime
+func (f *FakeRuntime) PrepareStop(timeout time.Duration, inTurn bool) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("PrepareStop", timeout, inTurn); err != nil {
+		return 0, err
+	}
+	ids := f.listContainers(cruntime.ListContainersOptions{State: cruntime.Running})
+	if err := f.setState(ids, StateExited); err != nil {
+		return 0, err
+	}
+	f.active = false
+	return len(ids), nil
+}
+
+// setState sets the state of the containers, which must all exist, the lock must be held
+func (f *FakeRuntime) setState(ids []string, state string) error {
+	for _, id := range ids {
+		if _, ok := f.Containers[id]; !ok {
+			return errors.Errorf("no such container: %s", id)
+		}
+	}
+	for _, id := range ids {
+		f.Containers[id].State = state
+	}
+	return nil
+}
+
+// KillContainers removes the containers
+func (f *FakeRuntime) KillContainers(ids []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("KillContainers", ids); err != nil {
+		return err
+	}
+	if err := f.setState(ids, StateExited); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		delete(f.Containers, id)
+	}
+	return nil
+}
+
+// StopContainers marks the containers as exited
+func (f *FakeRuntime) StopContainers(ids []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("StopContainers", ids); err != nil {
+		return err
+	}
+	return f.setState(ids, StateExited)
+}
+
+// PauseContainers marks the containers as paused
+func (f *FakeRuntime) PauseContainers(ids []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("PauseContainers", ids); err != nil {
+		return err
+	}
+	return f.setState(ids, StatePaused)
+}
+
+// UnpauseContainers marks the containers as running
+func (f *FakeRuntime) UnpauseContainers(ids []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("UnpauseContainers", ids); err != nil {
+		return err
+	}
+	return f.setState(ids, StateRunning)
+}
+
+// ContainerLogCmd returns a command printing the log of a container
+func (f *FakeRuntime) ContainerLogCmd(id string, length int, follow bool) string {
+	cmd := fmt.Sprintf("fake logs --tail %d %s", length, id)
+	if follow {
+		cmd += " --follow"
+	}
+	return cmd
+}
+
+// StreamContainerLogs writes the lines of the log of the container, and if following, waits until it no longer runs or ctx is done
+func (f *FakeRuntime) StreamContainerLogs(ctx context.Context, id string, w io.Writer, opts cruntime.StreamLogsOptions) error {
+	f.mu.Lock()
+	if err := f.call("StreamContainerLogs", id, opts.Lines, opts.Follow); err != nil {
+		f.mu.Unlock()
+		return err
+	}
+	lines := f.Logs[id]
+	if opts.Lines > 0 && len(lines) > opts.Lines {
+		lines = lines[len(lines)-opts.Lines:]
+	}
+	f.mu.Unlock()
+
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(w, l); err != nil {
+			return err
+		}
+	}
+	for opts.Follow {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+		f.mu.Lock()
+		c, ok := f.Containers[id]
+		running := ok && c.State == StateRunning
+		f.mu.Unlock()
+		if !running {
+			return nil
+		}
+	}
+	return nil
+}
+
+// RunDiagnosticContainer pulls img if it is missing, then writes the output of its diagnostic container and returns its exit code
+func (f *FakeRuntime) RunDiagnosticContainer(ctx context.Context, img string, cmd []string, o cruntime.DiagnosticOptions) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("RunDiagnosticContainer", img, strings.Join(cmd, " ")); err != nil {
+		return -1, err
+	}
+	if _, ok := f.Images[img]; !ok {
+		if err := f.call("PullImage", img); err != nil {
+			return -1, err
+		}
+		f.Images[img] = imageID(img)
+	}
+	d := f.Diagnostics[img]
+	if o.Stdout != nil {
+		if _, err := io.WriteString(o.Stdout, d.Output); err != nil {
+			return -1, err
+		}
+	}
+	return d.ExitCode, ctx.Err()
+}
+
+// SystemLogCmd returns a command printing the log of the runtime
+func (f *FakeRuntime) SystemLogCmd(length int) string {
+	return fmt.Sprintf("sudo journalctl -u fake -n %d", length)
+}
+
+// Preload adds PreloadedImages, unless an error is injected for Preload
+func (f *FakeRuntime) Preload(cc config.ClusterConfig) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Preload", cc.KubernetesConfig.KubernetesVersion); err != nil {
+		return err
+	}
+	for _, name := range f.PreloadedImages {
+		f.Images[name] = imageID(name)
+	}
+	return nil
+}
+
+// ImagesPreloaded returns whether all the images exist
+func (f *FakeRuntime) ImagesPreloaded(images []string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.call("ImagesPreloaded", images)
+	for _, name := range images {
+		if _, ok := f.Images[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyPendingRestart records the call, as the fake runtime does not restart
+func (f *FakeRuntime) ApplyPendingRestart() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.call("ApplyPendingRestart")
+}
+
+// Purge removes all the containers, which are all Kubernetes ones, and the images of o
+func (f *FakeRuntime) Purge(o cruntime.PurgeOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Purge", o.Images); err != nil {
+		return err
+	}
+	f.Containers = map[string]*FakeContainer{}
+	for _, name := range o.Images {
+		delete(f.Images, name)
+	}
+	return nil
+}
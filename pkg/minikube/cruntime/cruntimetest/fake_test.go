@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntimetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+func TestFakeRuntimeImages(t *testing.T) {
+	f := NewFakeRuntime()
+	id := f.AddImage("busybox:latest")
+	if err := f.TagImage("busybox:latest", "example.com/busybox:latest"); err != nil {
+		t.Fatalf("TagImage: %v", err)
+	}
+	if !f.ImageExists("example.com/busybox:latest", "sha256:"+id) {
+		t.Errorf("tagged image does not exist with ID %s", id)
+	}
+	images, err := f.ListImages(cruntime.ListImagesOptions{})
+	if err != nil {
+		t.Fatalf("ListImages: %v", err)
+	}
+	want := []cruntime.ListImage{{ID: id, RepoTags: []string{"busybox:latest", "example.com/busybox:latest"}, Size: "0"}}
+	if diff := cmp.Diff(want, images); diff != "" {
+		t.Errorf("ListImages() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := f.SaveImage("busybox:latest", "/tmp/busybox.tar"); err != nil {
+		t.Fatalf("SaveImage: %v", err)
+	}
+	if err := f.RemoveImage("busybox:latest"); err != nil {
+		t.Fatalf("RemoveImage: %v", err)
+	}
+	if err := f.RemoveImage("busybox:latest"); err == nil {
+		t.Errorf("RemoveImage of a missing image succeeded")
+	}
+	if _, err := f.LoadImage("/tmp/busybox.tar"); err != nil {
+		t.Fatalf("LoadImage: %v", err)
+	}
+	if !f.ImagesPreloaded([]string{"busybox:latest", "example.com/busybox:latest"}) {
+		t.Errorf("loaded image is missing")
+	}
+}
+
+func TestFakeRuntimeContainers(t *testing.T) {
+	f := NewFakeRuntime()
+	f.AddContainer("a", FakeContainer{Name: "kube-apiserver", Namespace: "kube-system"})
+	f.AddContainer("b", FakeContainer{Name: "nginx", Namespace: "default"})
+	f.AddContainer("c", FakeContainer{Name: "etcd", Namespace: "kube-system", State: StateExited})
+
+	var tests = []struct {
+		name string
+		opts cruntime.ListContainersOptions
+		want []string
+	}{
+		{"all", cruntime.ListContainersOptions{}, []string{"a", "b", "c"}},
+		{"running", cruntime.ListContainersOptions{State: cruntime.Running}, []string{"a", "b"}},
+		{"namespace", cruntime.ListContainersOptions{Namespaces: []string{"kube-system"}}, []string{"a", "c"}},
+		{"excluded namespace", cruntime.ListContainersOptions{ExcludeNamespaces: []string{"kube-system"}}, []string{"b"}},
+		{"name", cruntime.ListContainersOptions{Name: "nginx"}, []string{"b"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := f.ListContainers(tc.opts)
+			if err != nil {
+				t.Fatalf("ListContainers: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ListContainers() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if err := f.PauseContainers([]string{"a", "missing"}); err == nil {
+		t.Errorf("PauseContainers of a missing container succeeded")
+	}
+	if f.Containers["a"].State != StateRunning {
+		t.Errorf("failed PauseContainers changed the state to %s", f.Containers["a"].State)
+	}
+	if err := f.PauseContainers([]string{"a"}); err != nil {
+		t.Fatalf("PauseContainers: %v", err)
+	}
+	if got, _ := f.ListContainers(cruntime.ListContainersOptions{State: cruntime.Paused}); !cmp.Equal(got, []string{"a"}) {
+		t.Errorf("paused containers = %v, want [a]", got)
+	}
+	if n, err := f.GarbageCollect(0); err != nil || n != 1 {
+		t.Errorf("GarbageCollect() = %d, %v, want 1 container removed", n, err)
+	}
+}
+
+func TestFakeRuntimeFail(t *testing.T) {
+	f := NewFakeRuntime()
+	want := errors.New("unable to pull")
+	f.Fail("PullImage", want)
+	if err := f.PullImage("busybox"); err != want {
+		t.Errorf("PullImage() = %v, want %v", err, want)
+	}
+	if f.ImageExists("busybox", "") {
+		t.Errorf("failed PullImage added the image")
+	}
+	f.Fail("PullImage", nil)
+	if err := f.PullImage("busybox"); err != nil {
+		t.Errorf("PullImage() after clearing the failure: %v", err)
+	}
+	if diff := cmp.Diff([]string{"busybox", "busybox"}, f.Called("PullImage")); diff != "" {
+		t.Errorf("PullImage calls mismatch (-want +got):\n%s", diff)
+	}
+}
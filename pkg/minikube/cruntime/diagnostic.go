@@ -0,0 +1,257 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+const (
+	// DiagnosticLabel marks the containers and pod sandboxes of RunDiagnosticContainer
+	DiagnosticLabel = "io.x-k8s.minikube.diagnostic"
+	// DiagnosticNamespace is the namespace of the pod sandboxes of diagnostic containers, apart from the pods of Kubernetes
+	DiagnosticNamespace = "minikube-diagnostics"
+	// diagnosticRoot is where the configurations of the pod sandboxes and containers of CRI runtimes are written in the node
+	diagnosticRoot = "/tmp/minikube-diagnostics"
+)
+
+// diagnosticPollInterval is how often the state of a diagnostic container is checked once its output ended
+var diagnosticPollInterval = 500 * time.Millisecond
+
+// diagnosticName returns a name for a diagnostic container, unique on the node
+var diagnosticName = func() string {
+	return fmt.Sprintf("minikube-diagnostic-%d", time.Now().UnixNano())
+}
+
+// DiagnosticOptions are the options of RunDiagnosticContainer
+type DiagnosticOptions struct {
+	// Stdout and Stderr receive the output of the container as it runs
+	Stdout io.Writer
+	Stderr io.Writer
+	// Env are the environment variables of the container, as KEY=VALUE
+	Env []string
+}
+
+// pullDiagnosticImage pulls the image of a diagnostic container if the runtime does not have it, which fails when offline
+func pullDiagnosticImage(m Manager, img string) error {
+	if m.ImageExists(img, "") {
+		return nil
+	}
+	klog.Infof("pulling diagnostic image %s", img)
+	return m.PullImage(img)
+}
+
+// diagnosticExitCode returns the exit code of the command running a diagnostic container, or an error if it did not run
+// The exit codes in runtimeCodes are failures of the runtime itself, not of the container.
+func diagnosticExitCode(ctx context.Context, rr *command.RunResult, err error, runtimeCodes ...int) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	if ctx.Err() != nil {
+		return -1, ctx.Err()
+	}
+	if rr == nil || rr.ExitCode <= 0 {
+		return -1, err
+	}
+	for _, c := range runtimeCodes {
+		if rr.ExitCode == c {
+			return -1, err
+		}
+	}
+	return rr.ExitCode, nil
+}
+
+// runDockerDiagnosticContainer runs a diagnostic container with docker run, removing it even if ctx is done
+func runDockerDiagnosticContainer(ctx context.Context, m Manager, cr CommandRunner, img string, cmd []string, o DiagnosticOptions) (int, error) {
+	if err := pullDiagnosticImage(m, img); err != nil {
+		return -1, err
+	}
+	name := diagnosticName()
+	// interrupting docker run leaves the container running
+	defer func() {
+		if _, err := cr.RunCmd(exec.Command("docker", "rm", "-f", name)); err != nil {
+			klog.Infof("diagnostic container %s is gone already: %v", name, err)
+		}
+	}()
+	args := []string{"run", "--rm", "--name", name, "--network", "host", "--label", DiagnosticLabel + "=true"}
+	for _, e := range o.Env {
+		args = append(args, "--env", e)
+	}
+	args = append(append(args, img), cmd...)
+	c := exec.Command("docker", args...)
+	c.Stdout = o.Stdout
+	c.Stderr = o.Stderr
+	rr, err := cr.RunCmdContext(ctx, c)
+	// docker run exits with 125 when docker itself fails
+	return diagnosticExitCode(ctx, rr, errors.Wrap(err, "docker run"), 125)
+}
+
+// criKeyValue is an environment variable of a CRI container configuration
+type criKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// criMetadata is the metadata of a CRI pod sandbox or container configuration
+type criMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	UID       string `json:"uid,omitempty"`
+}
+
+// criPodConfig is the pod sandbox configuration read by crictl runp
+type criPodConfig struct {
+	Metadata     criMetadata       `json:"metadata"`
+	Labels       map[string]string `json:"labels"`
+	LogDirectory string            `json:"log_directory"`
+	Linux        json.RawMessage   `json:"linux"`
+}
+
+// criContainerConfig is the container configuration read by crictl create
+type criContainerConfig struct {
+	Metadata criMetadata       `json:"metadata"`
+	Image    map[string]string `json:"image"`
+	Command  []string          `json:"command"`
+	Envs     []criKeyValue     `json:"envs,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	LogPath  string            `json:"log_path"`
+}
+
+// criHostNetwork is the linux configuration of a pod sandbox in the network namespace of the node
+var criHostNetwork = json.RawMessage(`{"security_context":{"namespace_options":{"network":2}}}`)
+
+// diagnosticConfigs returns the pod sandbox and container configurations of a diagnostic container for crictl
+func diagnosticConfigs(name string, img string, cmd []string, env []string) ([]byte, []byte, error) {
+	labels := map[string]string{DiagnosticLabel: "true"}
+	pod, err := json.Marshal(criPodConfig{
+		Metadata:     criMetadata{Name: name, Namespace: DiagnosticNamespace, UID: name},
+		Labels:       labels,
+		LogDirectory: path.Join(diagnosticRoot, name),
+		Linux:        criHostNetwork,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	envs := []criKeyValue{}
+	for _, e := range env {
+		k, v, _ := strings.Cut(e, "=")
+		envs = append(envs, criKeyValue{Key: k, Value: v})
+	}
+	container, err := json.Marshal(criContainerConfig{
+		Metadata: criMetadata{Name: "diagnostic"},
+		Image:    map[string]string{"image": img},
+		Command:  cmd,
+		Envs:     envs,
+		Labels:   labels,
+		LogPath:  "diagnostic.log",
+	})
+	return pod, container, err
+}
+
+// runCRIDiagnosticContainer runs a diagnostic container in a pod sandbox of its own with crictl,
+// removing the sandbox even if ctx is done
+func runCRIDiagnosticContainer(ctx context.Context, m Manager, cr CommandRunner, img string, cmd []string, o DiagnosticOptions) (int, error) {
+	if err := pullDiagnosticImage(m, img); err != nil {
+		return -1, err
+	}
+	name := diagnosticName()
+	podConfig, containerConfig, err := diagnosticConfigs(name, img, cmd, o.Env)
+	if err != nil {
+		return -1, errors.Wrap(err, "diagnostic container configuration")
+	}
+	dir := path.Join(diagnosticRoot, name)
+	defer func() {
+		if _, err := cr.RunCmd(command.Sudo("rm", "-rf", dir)); err != nil {
+			klog.Warningf("unable to remove %s: %v", dir, err)
+		}
+	}()
+	podFile := path.Join(dir, "pod.json")
+	containerFile := path.Join(dir, "container.json")
+	for f, b := range map[string][]byte{podFile: podConfig, containerFile: containerConfig} {
+		if err := cr.Copy(assets.NewMemoryAssetTarget(b, f, "0644")); err != nil {
+			return -1, errors.Wrapf(err, "copying %s", f)
+		}
+	}
+
+	crictl := getCrictlPath(cr)
+	rr, err := cr.RunCmd(command.Sudo(crictl, "runp", podFile))
+	if err != nil {
+		return -1, errors.Wrap(err, "crictl runp")
+	}
+	pod := strings.TrimSpace(rr.Stdout.String())
+	defer func() {
+		if _, err := cr.RunCmd(command.Sudo(crictl, "rmp", "--force", pod)); err != nil {
+			klog.Warningf("unable to remove the diagnostic pod sandbox %s: %v", pod, err)
+		}
+	}()
+	rr, err = cr.RunCmd(command.Sudo(crictl, "create", pod, containerFile, podFile))
+	if err != nil {
+		return -1, errors.Wrap(err, "crictl create")
+	}
+	id := strings.TrimSpace(rr.Stdout.String())
+	if _, err := cr.RunCmd(command.Sudo(crictl, "start", id)); err != nil {
+		return -1, errors.Wrap(err, "crictl start")
+	}
+
+	// the logs end along with the container
+	c := command.Sudo(crictl, "logs", "--follow", id)
+	c.Stdout = o.Stdout
+	c.Stderr = o.Stderr
+	if _, err := cr.RunCmdContext(ctx, c); err != nil {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		return -1, errors.Wrap(err, "crictl logs")
+	}
+	return criExitCode(ctx, cr, crictl, id)
+}
+
+// criExitCode waits for a CRI container to exit and returns its exit code
+func criExitCode(ctx context.Context, cr CommandRunner, crictl string, id string) (int, error) {
+	for {
+		rr, err := cr.RunCmd(command.Sudo(crictl, "inspect", "--output", "go-template", "--template", "{{.status.state}} {{.status.exitCode}}", id))
+		if err != nil {
+			return -1, errors.Wrap(err, "crictl inspect")
+		}
+		state, code, _ := strings.Cut(strings.TrimSpace(rr.Stdout.String()), " ")
+		if state == "CONTAINER_EXITED" {
+			n, err := strconv.Atoi(code)
+			if err != nil {
+				return -1, errors.Wrapf(err, "exit code of %s", id)
+			}
+			return n, nil
+		}
+		select {
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		case <-time.After(diagnosticPollInterval):
+		}
+	}
+}
@@ -18,11 +18,15 @@ package cruntime
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -39,15 +43,20 @@ import (
 	"k8s.io/minikube/pkg/minikube/docker"
 	"k8s.io/minikube/pkg/minikube/download"
 	"k8s.io/minikube/pkg/minikube/image"
+	"k8s.io/minikube/pkg/minikube/out/register"
 	"k8s.io/minikube/pkg/minikube/style"
 	"k8s.io/minikube/pkg/minikube/sysinit"
+	"k8s.io/minikube/pkg/util/retry"
 )
 
 // KubernetesContainerPrefix is the prefix of each Kubernetes container
 const KubernetesContainerPrefix = "k8s_"
 
-const InternalDockerCRISocket = "/var/run/dockershim.sock"
-const ExternalDockerCRISocket = "/var/run/cri-dockerd.sock"
+// criDockerService is the service of cri-dockerd, started through CRIService
+const criDockerService = "cri-docker"
+
+// DefaultRestartTimeout is how long to wait for a restarted runtime to answer
+const DefaultRestartTimeout = 2 * time.Minute
 
 // ErrISOFeature is the error returned when disk image is missing features
 type ErrISOFeature struct {
@@ -74,6 +83,86 @@ type Docker struct {
 	Init              sysinit.Manager
 	UseCRI            bool
 	CRIService        string
+	AdoptRunning      bool
+	ForceRestart      bool
+	CleanupNetwork    bool
+	CNIConfigs        []string
+	RestartTimeout    time.Duration
+	// Features are the daemon features to merge into daemon.json, formatted as key=value
+	Features []string
+	// RegistryMirrors are written to daemon.json, as docker refuses them both there and as flags
+	RegistryMirrors []string
+	// BridgeCIDR is the network of the docker0 bridge, docker chooses it if empty
+	BridgeCIDR string
+	// MTU is the MTU of the docker0 bridge, docker chooses it if 0
+	MTU int
+	// PodCIDR is the pod network of the cluster, enabling IPv6 in docker and cri-dockerd if it has an IPv6 network
+	PodCIDR string
+	// GPUs makes the NVIDIA container runtime the default one, if not empty
+	GPUs string
+	// Offline makes pulling images fail instead of waiting for unreachable registries, and preloads mandatory
+	Offline bool
+	// CNI are the network plugin settings of cri-dockerd, the standard CNI directories are used if nil
+	CNI *cni.CNIRuntimeSettings
+	// OnDemand is whether docker is socket activated next to another runtime of Kubernetes, which does not run its images
+	OnDemand bool
+	// IsolatedBuilder runs the image builds in a dockerd of their own, promoting the built images into the one of Kubernetes
+	IsolatedBuilder bool
+	// PreloadFactor scales the estimated space needed to extract the preload, skipping the check if negative
+	PreloadFactor float64
+	// ImageOutput is how the output of the commands pulling and loading images reaches the user
+	ImageOutput ImageOutput
+	// ForceSystemd is whether docker will be enabled with systemd as cgroup manager, kept by the daemon.json written before Enable
+	ForceSystemd bool
+	// BareMetal is whether docker runs on a host minikube does not set up, the SELinux of which is checked
+	BareMetal bool
+	// Driver is the driver of the node, the users, groups and clock of the hosts which are not VMs are left to their owners
+	Driver string
+	// SELinuxRelabel labels the directories bind-mounted into the static pods for containers, if SELinux enforces on a bare metal host
+	SELinuxRelabel bool
+	// GroupUser is the user of the node added to the docker group, to run docker without sudo, none if empty
+	GroupUser string
+	// CRISocketGroup gives the docker group access to the socket of cri-dockerd, which only root reaches otherwise
+	CRISocketGroup bool
+	// OS is the operating system of the docker host, known from the Driver or detected through the Runner if empty
+	OS      string
+	profile *dockerOSProfile
+	// Paths are where docker writes in the node, resolved from the mounts of the node if nil
+	Paths *PathConfig
+	// selinux is whether SELinux enforces on the host, so that docker labels the containers
+	selinux bool
+	// restarts are the restarts deferred to ApplyPendingRestart, which are run right away if nil
+	restarts *restartSession
+}
+
+// DaemonEndpoint describes how clients outside of the node reach the docker daemon
+type DaemonEndpoint struct {
+	// Host is the URL of the daemon, as DOCKER_HOST expects it
+	Host string
+	// TLSDir is the directory of the client certificates, empty if the daemon is not reached with TLS, as over SSH
+	TLSDir string
+	// BuildTarget is whether Kubernetes runs the images built by the daemon
+	BuildTarget bool
+	// CRISocket is the URL of the socket of cri-dockerd in the node, empty if Kubernetes does not reach docker through cri-dockerd
+	CRISocket string
+}
+
+// TLSFiles returns the paths of the CA certificate, and of the client certificate and key, to reach the daemon with,
+// which are empty if the daemon is not reached with TLS
+func (e DaemonEndpoint) TLSFiles() (ca, cert, key string) {
+	if e.TLSDir == "" {
+		return "", "", ""
+	}
+	return filepath.Join(e.TLSDir, "ca.pem"), filepath.Join(e.TLSDir, "cert.pem"), filepath.Join(e.TLSDir, "key.pem")
+}
+
+// DaemonEndpoint returns the endpoint of the daemon reached at host, with the client certificates in tlsDir if not empty
+func (r *Docker) DaemonEndpoint(host, tlsDir string) DaemonEndpoint {
+	e := DaemonEndpoint{Host: host, TLSDir: tlsDir, BuildTarget: !r.OnDemand}
+	if r.UseCRI && !r.OnDemand {
+		e.CRISocket = SocketURL(r.SocketPath())
+	}
+	return e
 }
 
 // Name is a human readable name for Docker
@@ -99,10 +188,77 @@ func (r *Docker) Version() (string, error) {
 
 // SocketPath returns the path to the socket file for Docker
 func (r *Docker) SocketPath() string {
-	if r.Socket != "" {
-		return r.Socket
+	switch r.Socket {
+	case "":
+		return r.osProfile().InternalCRISocket
+	case ExternalDockerCRISocket:
+		return r.osProfile().ExternalCRISocket
+	}
+	return r.Socket
+}
+
+// RuntimeHealth reports the health of dockerd, and of cri-dockerd if used
+func (r *Docker) RuntimeHealth() (*Health, error) {
+	units := []healthUnit{{unit: "docker", process: "dockerd"}}
+	if r.CRIService != "" {
+		units = append(units, healthUnit{unit: criDockerService, process: "cri-dockerd"})
+	}
+	h, err := runtimeHealth(r.Runner, units, func() error {
+		_, err := r.Version()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// cri-dockerd does not report the pause image as pinned, so the image GC of the kubelet may remove it
+	if h.Responsive {
+		repaired, err := r.EnsurePauseImage()
+		if err != nil {
+			klog.Warningf("unable to repair the pause image: %v", err)
+		}
+		h.PauseImageRepaired = repaired
+	}
+	checkPauseImage(h, r, pauseImage(r.KubernetesVersion, r.ImageRepository))
+	return h, nil
+}
+
+// EnsurePauseImage restores the pause image if it is missing: tagged from its registry alias, loaded from the local preload, or pulled
+func (r *Docker) EnsurePauseImage() (bool, error) {
+	retag := func(name string) error {
+		tags, err := dockerImageTags(r.Runner)
+		if err != nil {
+			return err
+		}
+		return retagAliases(r, []string{image.TrimDockerIO(name)}, tags)
+	}
+	return ensurePauseImage(r, pauseImage(r.KubernetesVersion, r.ImageRepository), retag,
+		pauseFromPreload(r, r.Runner, "docker", r.KubernetesVersion, r.ImageRepository), r.PullImage)
+}
+
+// Healthy returns an error if dockerd, or cri-dockerd if used, is not active, or if the socket of Kubernetes does not answer
+func (r *Docker) Healthy() error {
+	services := []string{"docker"}
+	if r.CRIService != "" {
+		services = append(services, criDockerService)
 	}
-	return InternalDockerCRISocket
+	return servicesHealthy(r.Init, services, func() error {
+		if r.UseCRI {
+			return criResponsive(r.Runner, r.SocketPath())
+		}
+		_, err := r.Version()
+		return err
+	})
+}
+
+// Events returns the incidents reported by docker, along with the OOM kills and the state changes of its units
+func (r *Docker) Events(since time.Duration) ([]RuntimeEvent, error) {
+	units := []string{"docker.service"}
+	if r.CRIService != "" {
+		units = append(units, criDockerService+".service")
+	}
+	return runtimeEvents(r.Runner, units, since, func() ([]RuntimeEvent, error) {
+		return dockerEvents(r.Runner, since)
+	})
 }
 
 // Available returns an error if it is not possible to use this runtime on a host
@@ -116,8 +272,16 @@ func (r *Docker) Available() error {
 			return err
 		}
 	}
-	_, err := exec.LookPath("docker")
-	return err
+	if _, err := exec.LookPath("docker"); err != nil {
+		return err
+	}
+	return CheckElevation(r.Runner)
+}
+
+// CRIDockerdInstalled returns whether the cri-dockerd required by Kubernetes 1.24+ is installed on the node
+func CRIDockerdInstalled(cr CommandRunner) bool {
+	_, err := cr.RunCmd(exec.Command("which", "cri-dockerd"))
+	return err == nil
 }
 
 // Active returns if docker is active on the host
@@ -131,17 +295,112 @@ func (r *Docker) Enable(disOthers, forceSystemd, inUserNamespace bool) error {
 		return errors.New("inUserNamespace must not be true for docker")
 	}
 
+	defer timePhase("docker.enable")()
+	rb := &rollback{}
+	if err := r.enable(rb, disOthers, forceSystemd); err != nil {
+		return rb.fail(err)
+	}
+	return nil
+}
+
+// EnableContext is Enable, killing its commands in the host once ctx is done
+func (r *Docker) EnableContext(ctx context.Context, disOthers, forceSystemd, inUserNamespace bool) error {
+	return r.inPhase(ctx, "docker.enable", func(p *Docker) error {
+		return p.Enable(disOthers, forceSystemd, inUserNamespace)
+	})
+}
+
+// inPhase runs a phase on a copy of r, so that the other users of r keep a runner which the end of the phase does not kill,
+// and keeps what the phase learned about the node
+func (r *Docker) inPhase(ctx context.Context, phase string, f func(p *Docker) error) error {
+	p := *r
+	err := runPhase(ctx, phase, &p.Runner, &p.Init, func() error { return f(&p) })
+	r.OS, r.profile, r.Paths, r.selinux, r.restarts = p.OS, p.profile, p.Paths, p.selinux, p.restarts
+	return err
+}
+
+// enable runs the steps of Enable, recording in rb how to undo them
+func (r *Docker) enable(rb *rollback, disOthers, forceSystemd bool) error {
+	if err := r.configureSELinux(); err != nil {
+		return err
+	}
+
 	if disOthers {
-		if err := disableOthers(r, r.Runner); err != nil {
-			klog.Warningf("disableOthers: %v", err)
+		others := activeOthers(r, r.Runner)
+		err := rb.run("disabling other runtimes", func() error {
+			if err := disableOthers(r, r.Runner, disableOptions{CleanupNetwork: r.CleanupNetwork, CNIConfigs: r.CNIConfigs}); err != nil {
+				klog.Warningf("disableOthers: %v", err)
+			}
+			return nil
+		}, func() error {
+			for _, svc := range others {
+				if err := r.Init.Unmask(svc); err != nil {
+					return err
+				}
+				if err := r.Init.Start(svc); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 	}
 
-	if err := populateCRIConfig(r.Runner, r.SocketPath()); err != nil {
+	// restarting docker reloads all the unit files, so whether those of cri-docker changed is checked first
+	reloadCRI := r.CRIService != "" && r.Init.NeedsReload(criDockerService)
+
+	paths := r.pathConfig()
+	if err := rb.run("mounting writable settings directories", r.mountPaths, nil); err != nil {
 		return err
 	}
+	if paths.CRIConfigFile != "" {
+		crictlConf := backupFile(r.Runner, paths.CRIConfigFile)
+		if err := rb.run("configuring crictl", func() error { return writeCRIConfig(r.Runner, paths.CRIConfigFile, r.SocketPath()) }, crictlConf); err != nil {
+			return err
+		}
+	} else {
+		klog.Warningf("%s is read-only, so that crictl looks for the socket of docker itself", crictlConfigFile)
+	}
 
-	if err := r.Init.Unmask("docker.service"); err != nil {
+	if r.manageGroup() {
+		if err := r.configureDockerGroup(); err != nil {
+			klog.Warningf("unable to set up the %s group: %v", dockerGroup, err)
+		}
+	}
+
+	dockerActive := r.Active()
+	// stale is set when the running daemon differs from its configuration, so that it has to be restarted even if daemon.json is unchanged
+	stale := false
+	// systemd is whether daemon.json has to set the systemd cgroup driver, which an adopted daemon may already run with
+	systemd := forceSystemd
+	if r.AdoptRunning && dockerActive {
+		var reasons []string
+		reasons, systemd = r.restartReasons(forceSystemd)
+		switch {
+		case len(reasons) == 0 && r.liveRestore():
+			klog.Infof("adopting running docker daemon without restarting it")
+			return r.enableServices(rb, reloadCRI)
+		case len(reasons) == 0:
+			// without live-restore, docker is configured as on the other drivers, as nothing requires keeping it
+			klog.Infof("live-restore is disabled, configuring the running docker daemon")
+		case !r.ForceRestart:
+			return fmt.Errorf("restarting the running docker daemon would stop its containers (%s), use --force to restart it anyway", strings.Join(reasons, ", "))
+		default:
+			klog.Warningf("restarting the running docker daemon: %s", strings.Join(reasons, ", "))
+			stale = true
+		}
+	}
+
+	masked := r.serviceMasked("docker.service")
+	err := rb.run("unmasking docker", func() error { return r.Init.Unmask("docker.service") }, func() error {
+		if masked {
+			return r.Init.Mask("docker.service")
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
@@ -149,31 +408,257 @@ func (r *Docker) Enable(disOthers, forceSystemd, inUserNamespace bool) error {
 		klog.ErrorS(err, "Failed to enable", "service", "docker.socket")
 	}
 
-	if forceSystemd {
-		if err := r.forceSystemd(); err != nil {
+	if r.GPUs != "" {
+		if err := rb.run("configuring GPUs", r.configureGPU, nil); err != nil {
 			return err
 		}
 	}
 
-	if err := r.Init.Restart("docker"); err != nil {
+	// daemon.json is restored before docker is restarted or stopped again, so both are undone in a single step
+	daemonJSON := backupFile(r.Runner, r.daemonConfigFile())
+	err = rb.run("configuring docker", func() error {
+		phase := "docker.daemon-config"
+		if forceSystemd {
+			phase = "docker.force-systemd"
+		}
+		done := timePhase(phase)
+		changed, err := r.writeDaemonConfig(systemd)
+		done()
+		if err != nil {
+			return err
+		}
+		if changed {
+			if err := r.validateDaemonConfig(); err != nil {
+				return err
+			}
+			if err := r.removeStaleBridge(); err != nil {
+				return err
+			}
+		}
+		// a running docker already has the features of an unchanged daemon.json, and restarting it would stop its containers
+		if len(r.Features) > 0 && !changed && dockerActive && !stale {
+			klog.Infof("daemon.json with the docker features is unchanged, keeping the running docker daemon")
+			return nil
+		}
+		return r.restarts.restartLater("docker", func() error {
+			defer timePhase("docker.restart")()
+			return r.Init.Restart("docker")
+		}, nil)
+	}, func() error {
+		if err := daemonJSON(); err != nil {
+			return err
+		}
+		if dockerActive {
+			return r.Init.Restart("docker")
+		}
+		return r.Init.Stop("docker")
+	})
+	if err != nil {
 		return err
 	}
 
-	if r.CRIService != "" {
-		if err := r.Init.Enable(r.CRIService); err != nil {
+	return r.enableServices(rb, reloadCRI)
+}
+
+// configureSELinux checks the SELinux mode of a bare metal host, labeling the directories bind-mounted into the static pods
+// if it enforces and SELinuxRelabel is set, and failing with an ErrSELinux before changing anything otherwise
+func (r *Docker) configureSELinux() error {
+	mode := SELinuxDisabled
+	if r.BareMetal {
+		mode = selinuxMode(r.Runner)
+	}
+	switch selinuxDecision(mode, r.BareMetal, r.SELinuxRelabel) {
+	case selinuxFail:
+		return &ErrSELinux{Dirs: selinuxDirs}
+	case selinuxRelabel:
+		klog.Infof("SELinux is enforcing, labeling %s for containers", strings.Join(selinuxDirs, ", "))
+		if err := labelSELinuxDirs(r.Runner); err != nil {
 			return err
 		}
-		if err := r.Init.Start(r.CRIService); err != nil {
+		r.selinux = true
+	default:
+		klog.Infof("SELinux is %s on the docker host, leaving it alone", strings.ToLower(mode))
+		r.selinux = false
+	}
+	return nil
+}
+
+// enableServices enables the services next to dockerd: cri-dockerd, if used, and the isolated builder, which is stopped if unused,
+// then gives the docker group access to their sockets
+func (r *Docker) enableServices(rb *rollback, reloadCRI bool) error {
+	if err := rb.run("enabling cri-docker", func() error { return r.enableCRIService(reloadCRI) }, nil); err != nil {
+		return err
+	}
+	if r.IsolatedBuilder {
+		if err := rb.run("enabling the isolated builder", r.enableBuilder, r.disableBuilder); err != nil {
 			return err
 		}
+	} else if err := r.disableBuilder(); err != nil {
+		return err
+	}
+	// the sockets are checked once docker and cri-dockerd restarted, in case their units created them again
+	if r.manageGroup() {
+		r.restarts.afterRestarts(r.configureSocketAccess)
 	}
+	return nil
+}
 
+// serviceMasked returns whether a service is masked, so that enabling docker can mask it again on rollback
+func (r *Docker) serviceMasked(svc string) bool {
+	// is-enabled fails for masked services, but still prints their state
+	rr, _ := r.Runner.RunCmd(command.Sudo("systemctl", "is-enabled", svc))
+	return rr != nil && strings.TrimSpace(rr.Stdout.String()) == "masked"
+}
+
+// enableCRIService enables and starts the cri-dockerd service, if used, restarting it if reload as its unit files changed
+func (r *Docker) enableCRIService(reload bool) error {
+	if r.CRIService == "" {
+		return nil
+	}
+	if err := r.Init.Enable(r.CRIService); err != nil {
+		return err
+	}
+	if err := r.Init.Start(r.CRIService); err != nil {
+		return err
+	}
+	// drop-ins synced from the minikube home only apply once cri-dockerd restarts
+	if reload && r.Init.Active(criDockerService) {
+		klog.Infof("%s unit files changed, restarting it", criDockerService)
+		return r.restarts.restartLater(criDockerService, func() error { return r.restartCRIDocker(context.Background()) }, nil)
+	}
 	return nil
 }
 
+// restartReasons returns why the running docker daemon can not be adopted without a restart, and whether daemon.json
+// has to set the systemd cgroup driver, which it does not for a daemon already running with it, set in another form
+func (r *Docker) restartReasons(forceSystemd bool) ([]string, bool) {
+	reasons := []string{}
+	systemd := forceSystemd
+	if forceSystemd {
+		driver, err := r.CGroupDriver()
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("unable to determine cgroup driver: %v", err))
+		} else if driver != "systemd" {
+			reasons = append(reasons, fmt.Sprintf("cgroup driver is %q, but \"systemd\" is required", driver))
+		} else {
+			systemd = false
+		}
+	}
+	// any change of daemon.json only applies as docker restarts
+	if data, err := r.pendingDaemonConfig(systemd); err != nil {
+		reasons = append(reasons, fmt.Sprintf("unable to determine the changes of daemon.json: %v", err))
+	} else if data != nil {
+		reasons = append(reasons, "daemon.json changed")
+	}
+	if r.Init.NeedsReload("docker") {
+		reasons = append(reasons, "docker unit files changed")
+	}
+	klog.Infof("docker restart reasons: %v", reasons)
+	return reasons, systemd
+}
+
+// liveRestore returns whether the running docker daemon keeps its containers running as it restarts
+func (r *Docker) liveRestore() bool {
+	rr, err := r.Runner.RunCmd(exec.Command("docker", "info", "--format", "{{.LiveRestoreEnabled}}"))
+	if err != nil {
+		klog.Warningf("unable to determine live-restore: %v", err)
+		return false
+	}
+	return strings.TrimSpace(rr.Stdout.String()) == "true"
+}
+
 // Restart restarts Docker on a host
 func (r *Docker) Restart() error {
-	return r.Init.Restart("docker")
+	return r.RestartContext(context.Background())
+}
+
+// RestartContext restarts Docker on a host, killing its commands in the host once ctx is done
+func (r *Docker) RestartContext(ctx context.Context) error {
+	return r.inPhase(ctx, "docker.restart", func(p *Docker) error { return p.restart(ctx) })
+}
+
+// restartTimeout returns how long to wait for a restarted docker or cri-dockerd to answer
+func (r *Docker) restartTimeout() time.Duration {
+	if r.RestartTimeout == 0 {
+		return DefaultRestartTimeout
+	}
+	return r.RestartTimeout
+}
+
+// restart restarts Docker on a host, no longer waiting for it to answer once ctx is done
+func (r *Docker) restart(ctx context.Context) error {
+	if err := r.Init.Restart("docker"); err != nil {
+		return err
+	}
+	if err := waitReady(ctx, r.Runner, "docker", r.restartTimeout(), func() error {
+		_, err := r.Version()
+		return err
+	}); err != nil {
+		return err
+	}
+	// cri-dockerd keeps the connection it opened to dockerd, which broke as dockerd restarted
+	if r.CRIService == "" || !r.Init.Active(criDockerService) {
+		return nil
+	}
+	return r.restartCRIDocker(ctx)
+}
+
+// restartCRIDocker restarts cri-dockerd, waiting for it to answer within the RestartTimeout, or until ctx is done
+func (r *Docker) restartCRIDocker(ctx context.Context) error {
+	if err := r.Init.Restart(criDockerService); err != nil {
+		return err
+	}
+	crictl := getCrictlPath(r.Runner)
+	return waitReady(ctx, r.Runner, criDockerService, r.restartTimeout(), func() error {
+		_, err := r.Runner.RunCmdContext(ctx, command.Sudo(crictl, "version"))
+		return err
+	})
+}
+
+// ApplyPendingRestart restarts docker, which restarts cri-dockerd as well, or cri-dockerd alone,
+// once for all the steps which deferred their restart since New, and restarts them right away from then on
+func (r *Docker) ApplyPendingRestart() error {
+	return r.ApplyPendingRestartContext(context.Background())
+}
+
+// ApplyPendingRestartContext is ApplyPendingRestart, killing the commands of the restarts in the host once ctx is done
+func (r *Docker) ApplyPendingRestartContext(ctx context.Context) error {
+	s := r.restarts
+	r.restarts = nil
+	if s == nil || len(s.pending) == 0 {
+		return nil
+	}
+	klog.Infof("applying the deferred restarts of %s", strings.Join(s.pending, ", "))
+	var err error
+	if s.requires("docker") {
+		err = r.RestartContext(ctx)
+	} else {
+		err = r.inPhase(ctx, criDockerService+".restart", func(p *Docker) error { return p.restartCRIDocker(ctx) })
+	}
+	if err != nil {
+		return err
+	}
+	for _, f := range s.after {
+		f()
+	}
+	return nil
+}
+
+// waitReady waits for a restarted service to answer, returning its last logs if it does not within the timeout
+func waitReady(ctx context.Context, cr CommandRunner, svc string, timeout time.Duration, ready func() error) error {
+	start := time.Now()
+	if err := retry.ExpoContext(ctx, ready, 250*time.Millisecond, timeout); err != nil {
+		if ctx.Err() != nil {
+			return errors.Wrapf(ctx.Err(), "waiting for %s", svc)
+		}
+		rr, lerr := cr.RunCmd(command.Sudo("journalctl", "--no-pager", "-u", svc, "-n", "25"))
+		if lerr != nil {
+			return errors.Wrapf(err, "%s did not answer within %s (unable to read its logs: %v)", svc, timeout, lerr)
+		}
+		return errors.Wrapf(err, "%s did not answer within %s, last logs:\n%s", svc, timeout, strings.TrimSpace(rr.Stdout.String()))
+	}
+	klog.Infof("%s answered %s after restarting", svc, time.Since(start))
+	return nil
 }
 
 // Disable idempotently disables Docker on a host
@@ -201,8 +686,34 @@ func (r *Docker) Disable() error {
 	return r.Init.Mask("docker.service")
 }
 
+// disableOnDemand stops docker, but keeps docker.socket listening so that systemd starts docker again on its first use
+func (r *Docker) disableOnDemand(forceSystemd bool) error {
+	if r.CRIService != "" {
+		if err := r.Init.Stop(r.CRIService); err != nil {
+			return err
+		}
+		if err := r.Init.Disable(r.CRIService); err != nil {
+			return err
+		}
+	}
+	// dockerd reads daemon.json when it gets activated, so its cgroup manager still has to match the node
+	if _, err := r.writeDaemonConfig(forceSystemd); err != nil {
+		return err
+	}
+	klog.Info("stopping docker service, keeping it socket activated ...")
+	if err := r.Init.Unmask("docker.service"); err != nil {
+		return err
+	}
+	if err := r.Init.ForceStop("docker.service"); err != nil {
+		return err
+	}
+	return r.Init.EnableNow("docker.socket")
+}
+
 // ImageExists checks if image exists based on image name and optionally image sha
 func (r *Docker) ImageExists(name string, sha string) bool {
+	// docker resolves digests, but not along with tags
+	name, _ = digestRef(name)
 	// expected output looks like [SHA_ALGO:SHA]
 	c := exec.Command("docker", "image", "inspect", "--format", "{{.Id}}", name)
 	rr, err := r.Runner.RunCmd(c)
@@ -215,23 +726,109 @@ func (r *Docker) ImageExists(name string, sha string) bool {
 	return true
 }
 
+// ImagesExist returns which of the images exist
+func (r *Docker) ImagesExist(names []string) (map[string]bool, error) {
+	return dockerImagesExist(r.Runner, names)
+}
+
+// ImageID returns the ID of an image
+func (r *Docker) ImageID(name string) (string, error) {
+	rr, err := r.Runner.RunCmd(exec.Command("docker", "image", "inspect", "--format", "{{.Id}}", name))
+	if err != nil {
+		return "", errors.Wrap(err, "docker image inspect")
+	}
+	return strings.TrimPrefix(strings.TrimSpace(rr.Stdout.String()), "sha256:"), nil
+}
+
 // ListImages returns a list of images managed by this container runtime
-func (r *Docker) ListImages(ListImagesOptions) ([]ListImage, error) {
-	c := exec.Command("docker", "images", "--no-trunc", "--format", "{{json .}}")
-	rr, err := r.Runner.RunCmd(c)
+func (r *Docker) ListImages(opts ListImagesOptions) ([]ListImage, error) {
+	args, err := dockerImagesArgs(opts)
+	if err != nil {
+		return nil, err
+	}
+	rr, err := r.Runner.RunCmd(exec.Command("docker", args...))
+	if err != nil {
+		return nil, classifyCLIError(errors.Wrapf(err, "docker images"))
+	}
+	result, err := parseDockerImages(rr.Stdout.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	// docker images rounds sizes to three significant digits, so ask for the exact ones, along with the platforms
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, img := range result {
+		if !seen[img.ID] {
+			seen[img.ID] = true
+			ids = append(ids, img.ID)
+		}
+	}
+	rr, err = r.Runner.RunCmd(exec.Command("docker", append([]string{"image", "inspect", "--format", dockerImageInspectFormat}, ids...)...))
+	if err != nil {
+		return nil, classifyCLIError(errors.Wrap(err, "docker image inspect"))
+	}
+	sizes, platforms, err := parseDockerImageInspect(rr.Stdout.String())
+	if err != nil {
+		return nil, err
+	}
+	setPlatforms(result, platforms)
+
+	shared := map[string]int64{}
+	rr, err = r.Runner.RunCmd(exec.Command("docker", "system", "df", "-v", "--format", "{{json .}}"))
 	if err != nil {
-		return nil, errors.Wrapf(err, "docker images")
+		klog.Warningf("unable to get shared image sizes: %v", err)
+	} else if shared, err = parseDockerSharedSizes(rr.Stdout.String()); err != nil {
+		klog.Warningf("unable to parse shared image sizes: %v", err)
+	}
+
+	for i, img := range result {
+		if size, ok := sizes[img.ID]; ok {
+			result[i].Size = strconv.FormatInt(size, 10)
+		}
+		for id, size := range shared {
+			if strings.HasPrefix(img.ID, id) {
+				result[i].SharedSize = strconv.FormatInt(size, 10)
+				break
+			}
+		}
+	}
+	if opts.Platform == "" {
+		return result, nil
+	}
+	return FilterImages(result, ListImagesOptions{Platform: opts.Platform})
+}
+
+// dockerImagesArgs returns the arguments of 'docker images' listing the images matching opts
+func dockerImagesArgs(opts ListImagesOptions) ([]string, error) {
+	args := []string{"images", "--no-trunc", "--format", "{{json .}}"}
+	if opts.Reference != "" {
+		if err := validateReferencePattern(opts.Reference); err != nil {
+			return nil, err
+		}
+		args = append(args, "--filter", "reference="+opts.Reference)
+	}
+	if opts.Dangling != nil {
+		args = append(args, "--filter", fmt.Sprintf("dangling=%t", *opts.Dangling))
 	}
+	return args, nil
+}
+
+// parseDockerImages parses the output of 'docker images --no-trunc --format "{{json .}}"'
+// The sizes are only as precise as the rounded human readable ones docker prints.
+func parseDockerImages(out string) ([]ListImage, error) {
 	type dockerImage struct {
 		ID         string `json:"ID"`
 		Repository string `json:"Repository"`
 		Tag        string `json:"Tag"`
 		Size       string `json:"Size"`
 	}
-	images := strings.Split(rr.Stdout.String(), "\n")
 	result := []ListImage{}
-	for _, img := range images {
-		if img == "" {
+	for _, img := range strings.Split(out, "\n") {
+		if strings.TrimSpace(img) == "" {
 			continue
 		}
 
@@ -244,57 +841,241 @@ func (r *Docker) ListImages(ListImagesOptions) ([]ListImage, error) {
 			return nil, errors.Wrap(err, "Image size convert problem")
 		}
 
-		repoTag := fmt.Sprintf("%s:%s", jsonImage.Repository, jsonImage.Tag)
+		// dangling images have no tags, as in the CRI
+		repoTags := []string{}
+		if jsonImage.Repository != "<none>" && jsonImage.Tag != "<none>" {
+			repoTags = append(repoTags, normalizeImageRef(fmt.Sprintf("%s:%s", jsonImage.Repository, jsonImage.Tag)))
+		}
 		result = append(result, ListImage{
 			ID:          strings.TrimPrefix(jsonImage.ID, "sha256:"),
 			RepoDigests: []string{},
-			RepoTags:    []string{addDockerIO(repoTag)},
+			RepoTags:    repoTags,
 			Size:        fmt.Sprintf("%d", size),
 		})
 	}
 	return result, nil
 }
 
+// dockerImageInspectFormat prints the exact size and the os/architecture/variant of an image
+const dockerImageInspectFormat = "{{.Id}} {{.Size}} {{.Os}}/{{.Architecture}}/{{.Variant}}"
+
+// parseDockerImageInspect parses the output of 'docker image inspect' with dockerImageInspectFormat, returning the sizes in bytes and the platforms by image ID
+func parseDockerImageInspect(out string) (map[string]int64, map[string]imagePlatform, error) {
+	sizes := map[string]int64{}
+	platforms := map[string]imagePlatform{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, nil, fmt.Errorf("unexpected image inspect line: %q", line)
+		}
+		id := strings.TrimPrefix(fields[0], "sha256:")
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "parsing image size %q", fields[1])
+		}
+		sizes[id] = size
+		// images imported from a tarball of a root filesystem may have no architecture
+		p := strings.SplitN(fields[2], "/", 3)
+		if len(p) != 3 {
+			return nil, nil, fmt.Errorf("unexpected image platform %q", fields[2])
+		}
+		platforms[id] = imagePlatform{OS: p[0], Architecture: p[1], Variant: p[2]}
+	}
+	return sizes, platforms, nil
+}
+
+// parseDockerSharedSizes parses the output of 'docker system df -v --format "{{json .}}"', returning the size shared with other images by (short) image ID
+// docker only prints the shared sizes rounded, so these are approximate.
+func parseDockerSharedSizes(out string) (map[string]int64, error) {
+	var df struct {
+		Images []struct {
+			ID         string `json:"ID"`
+			SharedSize string `json:"SharedSize"`
+		} `json:"Images"`
+	}
+	if err := json.Unmarshal([]byte(out), &df); err != nil {
+		return nil, errors.Wrap(err, "parsing docker system df")
+	}
+	shared := map[string]int64{}
+	for _, img := range df.Images {
+		if img.SharedSize == "" || img.SharedSize == "N/A" {
+			continue
+		}
+		size, err := units.FromHumanSize(img.SharedSize)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing shared size %q", img.SharedSize)
+		}
+		shared[strings.TrimPrefix(img.ID, "sha256:")] = size
+	}
+	return shared, nil
+}
+
+// ImageHistory returns the layers of an image, newest first
+func (r *Docker) ImageHistory(name string) ([]LayerInfo, error) {
+	rr, err := r.Runner.RunCmd(exec.Command("docker", "history", "--no-trunc", "--human=false", "--format", "{{json .}}", name))
+	if err != nil {
+		return nil, errors.Wrap(err, "docker history")
+	}
+	steps, err := parseDockerHistory(rr.Stdout.String())
+	if err != nil {
+		return nil, err
+	}
+	diffIDs, err := inspectRootFSLayers(r.Runner, name, "docker")
+	if err != nil {
+		return nil, err
+	}
+	return layersFromHistory(steps, diffIDs), nil
+}
+
+// ImageScanTarget scans the images of docker from an archive, which works whatever the docker socket
+func (r *Docker) ImageScanTarget() ImageScanTarget {
+	return ImageScanTarget{Archive: true}
+}
+
+// Capabilities returns what docker supports
+func (r *Docker) Capabilities() Capabilities {
+	c := Capabilities{
+		SupportsBuild:   true,
+		SupportsPause:   true,
+		SnapshotterName: "overlay2",
+		NativeBuildkit:  true,
+	}
+	if r.UseCRI {
+		c.CRISocket = r.SocketPath()
+	}
+	return c
+}
+
 // LoadImage loads an image into this runtime
-func (r *Docker) LoadImage(path string) error {
+func (r *Docker) LoadImage(path string) ([]string, error) {
+	return r.LoadImageContext(context.Background(), path)
+}
+
+// LoadImageContext loads an image into this runtime, stopping when ctx is done
+func (r *Docker) LoadImageContext(ctx context.Context, path string) ([]string, error) {
 	klog.Infof("Loading image: %s", path)
-	c := exec.Command("/bin/bash", "-c", fmt.Sprintf("sudo cat %s | docker load", path))
-	if _, err := r.Runner.RunCmd(c); err != nil {
-		return errors.Wrap(err, "loadimage docker")
-	}
-	return nil
+	var refs []string
+	err := trackImage(path, register.ImageLoad, func() string { return fileSize(r.Runner, path) }, func() error {
+		p := r.osProfile()
+		c := p.Shell(p.LoadPipeline(path))
+		streamImageOutput(r.ImageOutput, c, path, register.ImageLoad)
+		rr, err := r.Runner.RunCmdContext(ctx, c)
+		if err != nil {
+			return classifyCLIError(errors.Wrap(err, "loadimage docker"))
+		}
+		refs = parseLoadedImages(rr.Stdout.String())
+		return nil
+	})
+	return refs, err
 }
 
 // PullImage pulls an image
 func (r *Docker) PullImage(name string) error {
+	return r.PullImageContext(context.Background(), name)
+}
+
+// PullImageContext pulls an image, stopping when ctx is done
+func (r *Docker) PullImageContext(ctx context.Context, name string) error {
 	klog.Infof("Pulling image: %s", name)
-	if r.UseCRI {
-		return pullCRIImage(r.Runner, name)
+	if r.Offline {
+		return NewErrOffline(name)
 	}
-	c := exec.Command("docker", "pull", name)
-	if _, err := r.Runner.RunCmd(c); err != nil {
-		return errors.Wrap(err, "pull image docker")
+	if r.UseCRI {
+		return pullWithClockCheck(r.Runner, r.Init, r.Driver, func() error { return pullCRIImage(r.Runner, name, r.ImageOutput) })
+	}
+	return pullWithClockCheck(r.Runner, r.Init, r.Driver, func() error {
+		return trackImage(name, register.ImagePull, func() string { return dockerImageSize(r.Runner, name) }, func() error {
+			c := exec.Command("docker", "pull", name)
+			streamImageOutput(r.ImageOutput, c, name, register.ImagePull)
+			if _, err := r.Runner.RunCmdContext(ctx, c); err != nil {
+				return classifyCLIError(errors.Wrap(err, "pull image docker"))
+			}
+			return nil
+		})
+	})
+}
+
+// dockerImageSize returns the size in bytes of an image, or "" if unknown
+func dockerImageSize(cr CommandRunner, name string) string {
+	rr, err := cr.RunCmd(exec.Command("docker", "image", "inspect", "--format", "{{.Size}}", name))
+	if err != nil {
+		klog.Warningf("unable to get size of %s: %v", name, err)
+		return ""
 	}
-	return nil
+	return strings.TrimSpace(rr.Stdout.String())
 }
 
 // SaveImage saves an image from this runtime
 func (r *Docker) SaveImage(name string, path string) error {
 	klog.Infof("Saving image %s: %s", name, path)
-	c := exec.Command("/bin/bash", "-c", fmt.Sprintf("docker save '%s' | sudo tee %s >/dev/null", name, path))
+	p := r.osProfile()
+	c := p.Shell(p.SavePipeline(path, name))
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "saveimage docker")
 	}
 	return nil
 }
 
+// ExportImageStore saves all the tagged images of docker into the archive at path
+func (r *Docker) ExportImageStore(path string) error {
+	refs, err := imageStoreRefs(r)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Exporting %d images: %s", len(refs), path)
+	return trackImage(path, register.ImageStoreExport, func() string { return fileSize(r.Runner, path) }, func() error {
+		p := r.osProfile()
+		if _, err := r.Runner.RunCmd(p.Shell(p.SavePipeline(path, refs...))); err != nil {
+			return errors.Wrap(err, "docker save")
+		}
+		return nil
+	})
+}
+
+// ImportImageStore loads all the images of the archive at path into docker
+func (r *Docker) ImportImageStore(path string) error {
+	klog.Infof("Importing images: %s", path)
+	return trackImage(path, register.ImageStoreImport, func() string { return fileSize(r.Runner, path) }, func() error {
+		p := r.osProfile()
+		if _, err := r.Runner.RunCmd(p.Shell(p.LoadPipeline(path))); err != nil {
+			return errors.Wrap(err, "docker load")
+		}
+		return nil
+	})
+}
+
 // RemoveImage removes a image
 func (r *Docker) RemoveImage(name string) error {
 	klog.Infof("Removing image: %s", name)
 	if r.UseCRI {
-		return removeCRIImage(r.Runner, name)
+		return removeCRIImage(r.Runner, name, false)
+	}
+	ref, err := dockerImageRef(r.Runner, name)
+	if err != nil {
+		return errors.Wrap(err, "remove image docker")
+	}
+	c := exec.Command("docker", "rmi", ref)
+	if _, err := r.Runner.RunCmd(c); err != nil {
+		return errors.Wrap(err, "remove image docker")
+	}
+	return nil
+}
+
+// ForceRemoveImage removes an image with all of its references
+func (r *Docker) ForceRemoveImage(name string) error {
+	klog.Infof("Removing image with all of its references: %s", name)
+	if r.UseCRI {
+		return removeCRIImage(r.Runner, name, true)
+	}
+	ref, _ := digestRef(name)
+	id, err := dockerImageID(r.Runner, ref)
+	if err != nil {
+		return errors.Wrap(err, "remove image docker")
 	}
-	c := exec.Command("docker", "rmi", name)
+	c := exec.Command("docker", "rmi", "--force", id)
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "remove image docker")
 	}
@@ -304,6 +1085,10 @@ func (r *Docker) RemoveImage(name string) error {
 // TagImage tags an image in this runtime
 func (r *Docker) TagImage(source string, target string) error {
 	klog.Infof("Tagging image %s: %s", source, target)
+	source, err := dockerImageRef(r.Runner, source)
+	if err != nil {
+		return errors.Wrap(err, "tag image docker")
+	}
 	c := exec.Command("docker", "tag", source, target)
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "tag image docker")
@@ -311,35 +1096,73 @@ func (r *Docker) TagImage(source string, target string) error {
 	return nil
 }
 
+// RetagImages tags each source image as its target in one script, untagging the targets it created if any fails
+func (r *Docker) RetagImages(mapping map[string]string) error {
+	return retagImages(r, r.Runner, retagCommands{
+		script: r.osProfile().Script,
+		tag: func(source, target string) string {
+			return fmt.Sprintf("docker tag %s %s", source, target)
+		},
+		untag: func(target string) string {
+			return fmt.Sprintf("docker rmi %s", target)
+		},
+	}, mapping)
+}
+
 // BuildImage builds an image into this runtime
-func (r *Docker) BuildImage(src string, file string, tag string, push bool, env []string, opts []string) error {
+func (r *Docker) BuildImage(src string, opts BuildOptions) error {
+	return r.BuildImageContext(context.Background(), src, opts)
+}
+
+// BuildImageContext builds an image into this runtime, stopping when ctx is done
+func (r *Docker) BuildImageContext(ctx context.Context, src string, opts BuildOptions) error {
 	klog.Infof("Building image: %s", src)
+	d := r.BuildDaemon()
+	builder := r.builder(opts)
+	cacheArgs, err := buildCacheArgs(builder, opts)
+	if err != nil {
+		return err
+	}
 	args := []string{"build"}
-	if file != "" {
-		args = append(args, "-f", file)
+	if builder == builderBuildx {
+		// buildx keeps the image in its build cache, unless loaded into docker
+		args = []string{"buildx", "build", "--load"}
 	}
-	if tag != "" {
-		args = append(args, "-t", tag)
+	if opts.File != "" {
+		args = append(args, "-f", opts.File)
 	}
+	if opts.Tag != "" {
+		args = append(args, "-t", opts.Tag)
+	}
+	args = append(args, cacheArgs...)
 	args = append(args, src)
-	for _, opt := range opts {
+	for _, opt := range opts.Opts {
 		args = append(args, "--"+opt)
 	}
-	c := exec.Command("docker", args...)
+	if r.IsolatedBuilder && !hasBuildOpt(opts, "network") {
+		// the isolated builder has no bridge
+		args = append(args, "--network", "host")
+	}
+	c := d.command(args...)
 	e := os.Environ()
-	e = append(e, env...)
+	e = append(e, opts.Env...)
 	c.Env = e
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	if _, err := r.Runner.RunCmd(c); err != nil {
-		return errors.Wrap(err, "buildimage docker")
+	c.Stdout, c.Stderr = opts.streams()
+	if _, err := r.Runner.RunCmdContext(ctx, c); err != nil {
+		return classifyCLIError(errors.Wrap(err, "buildimage docker"))
+	}
+	if r.IsolatedBuilder {
+		if opts.Tag == "" {
+			klog.Warningf("not promoting the untagged image built by the isolated builder")
+		} else if err := r.promoteImage(ctx, opts.Tag); err != nil {
+			return err
+		}
 	}
-	if tag != "" && push {
-		c := exec.Command("docker", "push", tag)
-		c.Stdout = os.Stdout
-		c.Stderr = os.Stderr
-		if _, err := r.Runner.RunCmd(c); err != nil {
-			return errors.Wrap(err, "pushimage docker")
+	if opts.Tag != "" && opts.Push {
+		c := d.command("push", opts.Tag)
+		c.Stdout, c.Stderr = opts.streams()
+		if _, err := r.Runner.RunCmdContext(ctx, c); err != nil {
+			return classifyCLIError(errors.Wrap(err, "pushimage docker"))
 		}
 	}
 	return nil
@@ -361,6 +1184,9 @@ func (r *Docker) CGroupDriver() (string, error) {
 	c := exec.Command("docker", "info", "--format", "{{.CgroupDriver}}")
 	rr, err := r.Runner.RunCmd(c)
 	if err != nil {
+		if !r.Active() {
+			return "", &ErrDaemonNotRunning{Runtime: r.Name(), Err: err}
+		}
 		return "", err
 	}
 	return strings.Split(rr.Stdout.String(), "\n")[0], nil
@@ -386,6 +1212,38 @@ func (r *Docker) ListContainers(o ListContainersOptions) ([]string, error) {
 	if r.UseCRI {
 		return listCRIContainers(r.Runner, "", o)
 	}
+	o, ok := selectNamespaces(o)
+	if !ok {
+		return nil, nil
+	}
+	if len(o.ExcludeNamespaces) > 0 {
+		// the namespaces of the containers are needed to leave out the excluded ones
+		infos, err := r.ListContainerInfo(o)
+		if err != nil {
+			return nil, err
+		}
+		var ids []string
+		for _, c := range infos {
+			ids = append(ids, c.ID)
+		}
+		return ids, nil
+	}
+	args := append(dockerPsArgs(o), "--format={{.ID}}")
+	rr, err := r.Runner.RunCmd(exec.Command("docker", args...))
+	if err != nil {
+		return nil, errors.Wrapf(err, "docker")
+	}
+	var ids []string
+	for _, line := range strings.Split(rr.Stdout.String(), "\n") {
+		if line != "" {
+			ids = append(ids, line)
+		}
+	}
+	return ids, nil
+}
+
+// dockerPsArgs returns the 'docker ps' arguments which select the containers matching the options
+func dockerPsArgs(o ListContainersOptions) []string {
 	args := []string{"ps"}
 	switch o.State {
 	case All:
@@ -396,24 +1254,89 @@ func (r *Docker) ListContainers(o ListContainersOptions) ([]string, error) {
 		args = append(args, "--filter", "status=paused")
 	}
 
+	// the excluded namespaces are left out of the listing, as the regular expressions of docker can not exclude them
 	nameFilter := KubernetesContainerPrefix + o.Name
 	if len(o.Namespaces) > 0 {
 		// Example result: k8s.*(kube-system|kubernetes-dashboard)
 		nameFilter = fmt.Sprintf("%s.*_(%s)_", nameFilter, strings.Join(o.Namespaces, "|"))
 	}
+	if o.PodUID != "" {
+		args = append(args, fmt.Sprintf("--filter=label=%s=%s", podUIDLabel, o.PodUID))
+	}
+	return append(args, fmt.Sprintf("--filter=name=%s", nameFilter))
+}
 
-	args = append(args, fmt.Sprintf("--filter=name=%s", nameFilter), "--format={{.ID}}")
+// ListContainerInfo returns details of the containers managed by docker
+func (r *Docker) ListContainerInfo(o ListContainersOptions) ([]ContainerInfo, error) {
+	if r.UseCRI {
+		return listCRIContainerInfo(r.Runner, "", o)
+	}
+	o, ok := selectNamespaces(o)
+	if !ok {
+		return []ContainerInfo{}, nil
+	}
+	args := append(dockerPsArgs(o), "--no-trunc", "--format", "{{json .}}")
 	rr, err := r.Runner.RunCmd(exec.Command("docker", args...))
 	if err != nil {
 		return nil, errors.Wrapf(err, "docker")
 	}
-	var ids []string
+	type dockerContainer struct {
+		ID        string `json:"ID"`
+		Image     string `json:"Image"`
+		State     string `json:"State"`
+		CreatedAt string `json:"CreatedAt"`
+		Labels    string `json:"Labels"`
+	}
+	result := []ContainerInfo{}
 	for _, line := range strings.Split(rr.Stdout.String(), "\n") {
-		if line != "" {
-			ids = append(ids, line)
+		if line == "" {
+			continue
+		}
+		var c dockerContainer
+		if err := json.Unmarshal([]byte(line), &c); err != nil {
+			return nil, errors.Wrap(err, "container convert problem")
+		}
+		labels := map[string]string{}
+		for _, l := range strings.Split(c.Labels, ",") {
+			if k, v, ok := strings.Cut(l, "="); ok {
+				labels[k] = v
+			}
+		}
+		if !o.SelectsNamespace(labels["io.kubernetes.pod.namespace"]) {
+			continue
 		}
+		// Example: 2022-10-17 10:00:00 +0000 UTC
+		created, err := time.Parse("2006-01-02 15:04:05 -0700 MST", c.CreatedAt)
+		if err != nil {
+			klog.Warningf("unable to parse creation time %q: %v", c.CreatedAt, err)
+		}
+		result = append(result, ContainerInfo{
+			ID:        c.ID,
+			Name:      labels["io.kubernetes.container.name"],
+			Pod:       labels["io.kubernetes.pod.name"],
+			PodUID:    labels[podUIDLabel],
+			Namespace: labels["io.kubernetes.pod.namespace"],
+			State:     c.State,
+			Image:     c.Image,
+			Created:   created,
+		})
 	}
-	return ids, nil
+	return result, nil
+}
+
+// CopyToContainer copies the file src of the node into the directory dir of a container, keeping its name
+func (r *Docker) CopyToContainer(c ContainerInfo, src string, dir string, o CopyOptions) error {
+	return copyIntoDocker(r.Runner, c, src, dir, o)
+}
+
+// PortMappings returns the ports a container, given by its name, ID or a prefix of its ID, exposes or publishes on the node
+func (r *Docker) PortMappings(id string) ([]PortMapping, error) {
+	return dockerPortMappings(r.Runner, id)
+}
+
+// CheckpointContainer is unsupported by docker, whose checkpoints cri-dockerd does not expose through the CRI
+func (r *Docker) CheckpointContainer(id string, destPath string) error {
+	return &ErrUnsupported{Runtime: r.Name(), Operation: "checkpointing containers"}
 }
 
 // KillContainers forcibly removes a running container based on ID
@@ -425,9 +1348,9 @@ func (r *Docker) KillContainers(ids []string) error {
 		return nil
 	}
 	klog.Infof("Killing containers: %s", ids)
-	args := append([]string{"rm", "-f"}, ids...)
-	c := exec.Command("docker", args...)
-	if _, err := r.Runner.RunCmd(c); err != nil {
+	if _, err := runOnContainers(r.Runner, ids, containerBatchSize, func(ids []string) *exec.Cmd {
+		return exec.Command("docker", append([]string{"rm", "-f"}, ids...)...)
+	}); err != nil {
 		return errors.Wrap(err, "killing containers docker")
 	}
 	return nil
@@ -435,21 +1358,99 @@ func (r *Docker) KillContainers(ids []string) error {
 
 // StopContainers stops a running container based on ID
 func (r *Docker) StopContainers(ids []string) error {
+	return r.stopContainersWithin(ids, 0)
+}
+
+// stopContainersWithin stops containers, killing them after timeout, or after the default timeout of docker if 0.
+// The batches of containers share the timeout, so that all of them are stopped within it.
+func (r *Docker) stopContainersWithin(ids []string, timeout time.Duration) error {
 	if r.UseCRI {
-		return stopCRIContainers(r.Runner, ids)
+		return stopCRIContainersWithin(r.Runner, ids, timeout)
 	}
 	if len(ids) == 0 {
 		return nil
 	}
 	klog.Infof("Stopping containers: %s", ids)
-	args := append([]string{"stop"}, ids...)
-	c := exec.Command("docker", args...)
-	if _, err := r.Runner.RunCmd(c); err != nil {
+	deadline := time.Now().Add(timeout)
+	if _, err := runOnContainers(r.Runner, ids, containerBatchSize, func(ids []string) *exec.Cmd {
+		args := []string{"stop"}
+		if timeout > 0 {
+			args = append(args, "-t", stopSeconds(time.Until(deadline)))
+		}
+		return exec.Command("docker", append(args, ids...)...)
+	}); err != nil {
 		return errors.Wrap(err, "docker")
 	}
 	return nil
 }
 
+// PrepareStop stops all the running Kubernetes containers at once within timeout, or each in turn within it, then docker,
+// which then has nothing left to wait for
+func (r *Docker) PrepareStop(timeout time.Duration, inTurn bool) (int, error) {
+	services := []string{"docker.socket", "docker.service"}
+	if r.CRIService != "" {
+		services = append([]string{r.CRIService}, services...)
+	}
+	return prepareStop(r, r.Init, inTurn, func(ids []string) error { return r.stopContainersWithin(ids, timeout) }, services...)
+}
+
+// dockerGCFormat is the 'docker inspect' format used to find the containers to garbage collect
+const dockerGCFormat = "{{.Id}}\t{{.State.Status}}\t{{.State.FinishedAt}}\t{{index .Config.Labels \"io.kubernetes.docker.type\"}}\t{{index .Config.Labels \"io.kubernetes.sandbox.id\"}}"
+
+// parseDockerGCCandidates parses the output of 'docker inspect' with dockerGCFormat
+func parseDockerGCCandidates(output string) []gcCandidate {
+	candidates := []gcCandidate{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		c := gcCandidate{
+			ID:        fields[0],
+			Sandbox:   fields[4],
+			IsSandbox: fields[3] == "podsandbox",
+			Running:   fields[1] != "exited" && fields[1] != "dead",
+		}
+		// containers which never ran report the zero time
+		if t, err := time.Parse(time.RFC3339Nano, fields[2]); err == nil && t.Year() > 1 {
+			c.Stopped = t
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
+
+// GarbageCollect removes the containers and pod sandboxes which stopped longer ago than the given age
+func (r *Docker) GarbageCollect(olderThan time.Duration) (int, error) {
+	if r.UseCRI {
+		return garbageCollectCRI(r.Runner, olderThan)
+	}
+	rr, err := r.Runner.RunCmd(exec.Command("docker", "ps", "-a", "-q", "--no-trunc", "--filter", "label=io.kubernetes.pod.name"))
+	if err != nil {
+		return 0, errors.Wrap(err, "docker ps")
+	}
+	ids := strings.Fields(rr.Stdout.String())
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := append([]string{"inspect", "--format", dockerGCFormat}, ids...)
+	rr, err = r.Runner.RunCmd(exec.Command("docker", args...))
+	if err != nil {
+		return 0, errors.Wrap(err, "docker inspect")
+	}
+	containers, sandboxes := collectable(parseDockerGCCandidates(rr.Stdout.String()), time.Now().Add(-olderThan))
+	// sandboxes are containers as well for docker, and go last so that their containers are gone already
+	remove := append(containers, sandboxes...)
+	if len(remove) == 0 {
+		return 0, nil
+	}
+	klog.Infof("removing %d stopped containers: %v", len(remove), remove)
+	if _, err := r.Runner.RunCmd(exec.Command("docker", append([]string{"rm"}, remove...)...)); err != nil {
+		return 0, errors.Wrap(err, "docker rm")
+	}
+	return len(remove), nil
+}
+
 // PauseContainers pauses a running container based on ID
 func (r *Docker) PauseContainers(ids []string) error {
 	if r.UseCRI {
@@ -459,9 +1460,9 @@ func (r *Docker) PauseContainers(ids []string) error {
 		return nil
 	}
 	klog.Infof("Pausing containers: %s", ids)
-	args := append([]string{"pause"}, ids...)
-	c := exec.Command("docker", args...)
-	if _, err := r.Runner.RunCmd(c); err != nil {
+	if _, err := runOnContainers(r.Runner, ids, containerBatchSize, func(ids []string) *exec.Cmd {
+		return exec.Command("docker", append([]string{"pause"}, ids...)...)
+	}); err != nil {
 		return errors.Wrap(err, "docker")
 	}
 	return nil
@@ -476,9 +1477,9 @@ func (r *Docker) UnpauseContainers(ids []string) error {
 		return nil
 	}
 	klog.Infof("Unpausing containers: %s", ids)
-	args := append([]string{"unpause"}, ids...)
-	c := exec.Command("docker", args...)
-	if _, err := r.Runner.RunCmd(c); err != nil {
+	if _, err := runOnContainers(r.Runner, ids, containerBatchSize, func(ids []string) *exec.Cmd {
+		return exec.Command("docker", append([]string{"unpause"}, ids...)...)
+	}); err != nil {
 		return errors.Wrap(err, "docker")
 	}
 	return nil
@@ -502,25 +1503,19 @@ func (r *Docker) ContainerLogCmd(id string, len int, follow bool) string {
 	return cmd.String()
 }
 
-// SystemLogCmd returns the command to retrieve system logs
-func (r *Docker) SystemLogCmd(len int) string {
-	return fmt.Sprintf("sudo journalctl -u docker -n %d", len)
+// StreamContainerLogs writes the log of a container to w, until the log ends or ctx is done
+func (r *Docker) StreamContainerLogs(ctx context.Context, id string, w io.Writer, opts StreamLogsOptions) error {
+	return streamContainerLogs(ctx, r.Runner, r.ContainerLogCmd(id, opts.Lines, opts.Follow), w)
 }
 
-// ForceSystemd forces the docker daemon to use systemd as cgroup manager
-func (r *Docker) forceSystemd() error {
-	klog.Infof("Forcing docker to use systemd as cgroup manager...")
-	daemonConfig := `{
-"exec-opts": ["native.cgroupdriver=systemd"],
-"log-driver": "json-file",
-"log-opts": {
-	"max-size": "100m"
-},
-"storage-driver": "overlay2"
+// RunDiagnosticContainer runs a one-off container of img with cmd in the network of the host, returning its exit code
+func (r *Docker) RunDiagnosticContainer(ctx context.Context, img string, cmd []string, o DiagnosticOptions) (int, error) {
+	return runDockerDiagnosticContainer(ctx, r, r.Runner, img, cmd, o)
 }
-`
-	ma := assets.NewMemoryAsset([]byte(daemonConfig), "/etc/docker", "daemon.json", "0644")
-	return r.Runner.Copy(ma)
+
+// SystemLogCmd returns the command to retrieve system logs
+func (r *Docker) SystemLogCmd(len int) string {
+	return r.osProfile().SystemLogCmd(len)
 }
 
 // Preload preloads docker with k8s images:
@@ -528,36 +1523,59 @@ func (r *Docker) forceSystemd() error {
 // 2. Extract the preloaded tarball to the correct directory
 // 3. Remove the tarball within the VM
 func (r *Docker) Preload(cc config.ClusterConfig) error {
-	if !download.PreloadExists(cc.KubernetesConfig.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver) {
-		return nil
-	}
+	return r.PreloadContext(context.Background(), cc)
+}
+
+// PreloadContext preloads the images of Kubernetes, killing its commands in the host once ctx is done
+func (r *Docker) PreloadContext(ctx context.Context, cc config.ClusterConfig) error {
+	return r.inPhase(ctx, "docker.preload", func(p *Docker) error { return p.preload(ctx, cc) })
+}
+
+// preload preloads the images of Kubernetes, stopping when ctx is done
+func (r *Docker) preload(ctx context.Context, cc config.ClusterConfig) error {
 	k8sVersion := cc.KubernetesConfig.KubernetesVersion
 	cRuntime := cc.KubernetesConfig.ContainerRuntime
+	imageRepository := cc.KubernetesConfig.ImageRepository
+
+	official := download.PreloadExists(k8sVersion, cRuntime, cc.Driver)
+	// the official preloads only hold images of the default repository
+	if (!official || imageRepository != "") && download.LocalPreloadExists(k8sVersion, cRuntime, imageRepository) {
+		return r.preloadLocal(ctx, cc)
+	}
+	if !official {
+		return missingPreload(r.Offline, cc)
+	}
 
 	// If images already exist, return
 	images, err := images.Kubeadm(cc.KubernetesConfig.ImageRepository, k8sVersion)
 	if err != nil {
 		return errors.Wrap(err, "getting images")
 	}
-	if dockerImagesPreloaded(r.Runner, images) {
+	// the preload holds the overlay2 store of docker, which the containerd image store ignores
+	containerdStore, err := r.preloadStore(ctx)
+	if err != nil {
+		return err
+	}
+	if r.preloadedWithAliases(images) {
 		klog.Info("Images already preloaded, skipping extraction")
 		return nil
 	}
 
 	refStore := docker.NewStorage(r.Runner)
-	if err := refStore.Save(); err != nil {
-		klog.Infof("error saving reference store: %v", err)
+	if !containerdStore {
+		if err := refStore.Save(); err != nil {
+			klog.Infof("error saving reference store: %v", err)
+		}
 	}
 
-	tarballPath := download.TarballPath(k8sVersion, cRuntime)
-	targetDir := "/"
-	targetName := "preloaded.tar.lz4"
-	dest := path.Join(targetDir, targetName)
-
-	c := exec.Command("which", "lz4")
-	if _, err := r.Runner.RunCmd(c); err != nil {
-		return NewErrISOFeature("lz4")
+	tarballPath, compression, err := preloadTarball(r.Runner, k8sVersion, cRuntime)
+	if err != nil {
+		return err
 	}
+	paths := r.pathConfig()
+	targetDir := paths.PreloadDir
+	targetName := NodeTempName("preloaded" + compression.Extension())
+	dest := path.Join(targetDir, targetName)
 
 	// Copy over tarball into host
 	fa, err := assets.NewFileAsset(tarballPath, targetDir, targetName, "0644")
@@ -570,21 +1588,48 @@ func (r *Docker) Preload(cc config.ClusterConfig) error {
 		}
 	}()
 
-	t := time.Now()
-	if err := r.Runner.Copy(fa); err != nil {
+	if err := checkPreloadSpace(r.Runner, paths.PreloadRoot, fa.GetLength(), r.PreloadFactor); err != nil {
+		return err
+	}
+
+	done := timePhase("docker.preload.copy")
+	err = trackImage(tarballPath, register.ImagePreloadCopy, func() string { return fmt.Sprintf("%d", fa.GetLength()) }, func() error {
+		return r.Runner.Copy(newProgressFile(fa, tarballPath, register.ImagePreloadCopy, r.ImageOutput))
+	})
+	done()
+	if err != nil {
 		return errors.Wrap(err, "copying file")
 	}
-	klog.Infof("Took %f seconds to copy over tarball", time.Since(t).Seconds())
+	if ctx.Err() != nil {
+		return errors.Wrap(ctx.Err(), "copying file")
+	}
 
-	// extract the tarball to /var in the VM
-	if rr, err := r.Runner.RunCmd(exec.Command("sudo", "tar", "-I", "lz4", "-C", "/var", "-xf", dest)); err != nil {
-		return errors.Wrapf(err, "extracting tarball: %s", rr.Output())
+	// extract the tarball to the PreloadRoot in the VM, /var by default, or import its images into the containerd image store
+	done = timePhase("docker.preload.extract")
+	err = trackImage(dest, register.ImagePreloadExtract, func() string { return "" }, func() error {
+		if containerdStore {
+			return r.importPreload(ctx, compression, dest)
+		}
+		if rr, err := r.Runner.RunCmdContext(ctx, tarExtractCmd(compression, paths.PreloadRoot, dest)); err != nil {
+			return errors.Wrapf(err, "extracting tarball: %s", rr.Output())
+		}
+		return nil
+	})
+	done()
+	if err != nil {
+		return err
 	}
 
 	//  remove the tarball in the VM
 	if err := r.Runner.Remove(fa); err != nil {
 		klog.Infof("error removing tarball: %v", err)
 	}
+	if containerdStore {
+		if !r.preloadedWithAliases(images) {
+			klog.Infof("preload does not hold all the images of Kubernetes %s", k8sVersion)
+		}
+		return nil
+	}
 
 	// save new reference store again
 	if err := refStore.Save(); err != nil {
@@ -594,27 +1639,161 @@ func (r *Docker) Preload(cc config.ClusterConfig) error {
 	if err := refStore.Update(); err != nil {
 		klog.Infof("error updating reference store: %v", err)
 	}
-	return r.Restart()
+	// drop references to images whose content did not survive, and retag preloaded images
+	if _, err := refStore.Verify(); err != nil {
+		klog.Infof("error verifying reference store: %v", err)
+	}
+	// docker only sees the preloaded images once restarted
+	err = r.restarts.restartLater("docker", func() error {
+		defer timePhase("docker.preload.restart")()
+		return r.RestartContext(ctx)
+	}, func() {
+		if !r.preloadedWithAliases(images) {
+			klog.Infof("preload does not hold all the images of Kubernetes %s", k8sVersion)
+		}
+	})
+	return errors.Wrap(err, "restarting docker with the preloaded images")
 }
 
-// dockerImagesPreloaded returns true if all images have been preloaded
-func dockerImagesPreloaded(runner command.Runner, images []string) bool {
+// preloadedWithAliases tags the missing images from their registry alias, and returns true if all images have been preloaded
+func (r *Docker) preloadedWithAliases(images []string) bool {
+	if tags, err := dockerImageTags(r.Runner); err == nil {
+		want := []string{}
+		for _, i := range images {
+			want = append(want, image.TrimDockerIO(i))
+		}
+		if err := retagAliases(r, want, tags); err != nil {
+			klog.Warningf("unable to tag preloaded images: %v", err)
+		}
+	}
+	return dockerImagesPreloaded(r.Runner, images)
+}
+
+// RepairImageStore removes dangling references from the docker reference store, restarting docker if needed
+func (r *Docker) RepairImageStore() (docker.VerifyResult, error) {
+	// containerd keeps the references to the images with their content
+	if r.ContainerdImageStore() {
+		return docker.VerifyResult{}, nil
+	}
+	refStore := docker.NewStorage(r.Runner)
+	result, err := refStore.Verify()
+	if err != nil {
+		return result, errors.Wrap(err, "verifying reference store")
+	}
+	if !result.Repaired() {
+		return result, nil
+	}
+	return result, r.Restart()
+}
+
+// preloadLocal loads the images of a local preload, saved from a node by a previous start
+func (r *Docker) preloadLocal(ctx context.Context, cc config.ClusterConfig) error {
+	k8sVersion := cc.KubernetesConfig.KubernetesVersion
+	imageRepository := cc.KubernetesConfig.ImageRepository
+
+	images, err := images.Kubeadm(imageRepository, k8sVersion)
+	if err != nil {
+		return errors.Wrap(err, "getting images")
+	}
+	// the images are loaded into the image store docker runs with once enabled
+	if _, err := r.preloadStore(ctx); err != nil {
+		return err
+	}
+	if dockerImagesPreloaded(r.Runner, images) {
+		klog.Info("Images already preloaded, skipping local preload")
+		return nil
+	}
+
+	tarballPath := download.LocalPreloadPath(k8sVersion, cc.KubernetesConfig.ContainerRuntime, imageRepository)
+	if err := download.VerifyLocalPreload(tarballPath); err != nil {
+		return errors.Wrap(err, "verifying local preload")
+	}
+	archives, err := download.LocalPreloadArchives(tarballPath)
+	if err != nil {
+		return err
+	}
+	compression, err := download.DetectCompression(tarballPath)
+	if err != nil {
+		return err
+	}
+	if err := decompressible(r.Runner, compression); err != nil {
+		return err
+	}
+
+	targetName := NodeTempName(localPreloadTargetName)
+	dest := path.Join(localPreloadTargetDir, targetName)
+	extractDir := NodeTempName(localPreloadExtractDir)
+
+	fa, err := assets.NewFileAsset(tarballPath, localPreloadTargetDir, targetName, "0644")
+	if err != nil {
+		return errors.Wrap(err, "getting file asset")
+	}
+	defer func() {
+		if err := fa.Close(); err != nil {
+			klog.Warningf("error closing the file %s: %v", fa.GetSourcePath(), err)
+		}
+	}()
+
+	t := time.Now()
+	if err := trackImage(tarballPath, register.ImagePreloadCopy, func() string { return fmt.Sprintf("%d", fa.GetLength()) }, func() error {
+		return r.Runner.Copy(newProgressFile(fa, tarballPath, register.ImagePreloadCopy, r.ImageOutput))
+	}); err != nil {
+		return errors.Wrap(err, "copying file")
+	}
+	klog.Infof("Took %f seconds to copy over local preload", time.Since(t).Seconds())
+	if ctx.Err() != nil {
+		return errors.Wrap(ctx.Err(), "copying file")
+	}
+
+	defer func() {
+		if _, err := r.Runner.RunCmd(command.Sudo("rm", "-rf", dest, extractDir)); err != nil {
+			klog.Infof("error removing local preload: %v", err)
+		}
+	}()
+	if rr, err := r.Runner.RunCmd(command.Sudo("mkdir", "-p", extractDir)); err != nil {
+		return errors.Wrapf(err, "making %s: %s", extractDir, rr.Output())
+	}
+	if rr, err := r.Runner.RunCmdContext(ctx, tarExtractCmd(compression, extractDir, dest)); err != nil {
+		return errors.Wrapf(err, "extracting local preload: %s", rr.Output())
+	}
+	for _, a := range archives {
+		if _, err := r.LoadImageContext(ctx, path.Join(extractDir, a)); err != nil {
+			return errors.Wrapf(err, "loading %s", a)
+		}
+	}
+	return nil
+}
+
+// dockerImageTags returns the names of the images of docker, without the docker.io prefix
+func dockerImageTags(runner command.Runner) ([]string, error) {
 	rr, err := runner.RunCmd(exec.Command("docker", "images", "--format", "{{.Repository}}:{{.Tag}}"))
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof("Got preloaded images: %s", rr.Output())
+	tags := []string{}
+	for _, i := range strings.Split(strings.TrimSpace(rr.Stdout.String()), "\n") {
+		tags = append(tags, image.TrimDockerIO(i))
+	}
+	return tags, nil
+}
+
+// dockerImagesPreloaded returns true if all images have been preloaded
+func dockerImagesPreloaded(runner command.Runner, images []string) (preloaded bool) {
+	start := time.Now()
+	defer func() { recordPreloadCheck(start, preloaded) }()
+	tags, err := dockerImageTags(runner)
 	if err != nil {
 		return false
 	}
 	preloadedImages := map[string]struct{}{}
-	for _, i := range strings.Split(rr.Stdout.String(), "\n") {
-		i = image.TrimDockerIO(i)
-		preloadedImages[i] = struct{}{}
+	for _, i := range tags {
+		preloadedImages[normalizeImageRef(i)] = struct{}{}
 	}
 
-	klog.Infof("Got preloaded images: %s", rr.Output())
-
 	// Make sure images == imgs
 	for _, i := range images {
-		i = image.TrimDockerIO(i)
-		if _, ok := preloadedImages[i]; !ok {
+		if _, ok := preloadedImages[normalizeImageRef(i)]; !ok {
 			klog.Infof("%s wasn't preloaded", i)
 			return false
 		}
@@ -622,32 +1801,9 @@ func dockerImagesPreloaded(runner command.Runner, images []string) bool {
 	return true
 }
 
-// Add docker.io prefix
-func addDockerIO(name string) string {
-	var reg, usr, img string
-	p := strings.SplitN(name, "/", 2)
-	if len(p) > 1 && strings.Contains(p[0], ".") {
-		reg = p[0]
-		img = p[1]
-	} else {
-		reg = "docker.io"
-		img = name
-		p = strings.SplitN(img, "/", 2)
-		if len(p) > 1 {
-			usr = p[0]
-			img = p[1]
-		} else {
-			usr = "library"
-			img = name
-		}
-		return reg + "/" + usr + "/" + img
-	}
-	return reg + "/" + img
-}
-
 func dockerBoundToContainerd(runner command.Runner) bool {
 	// NOTE: assumes systemd
-	rr, err := runner.RunCmd(exec.Command("sudo", "systemctl", "cat", "docker.service"))
+	rr, err := runner.RunCmd(command.Sudo("systemctl", "cat", "docker.service"))
 	if err != nil {
 		klog.Warningf("unable to check if docker is bound to containerd")
 		return false
@@ -665,51 +1821,147 @@ func (r *Docker) ImagesPreloaded(images []string) bool {
 	return dockerImagesPreloaded(r.Runner, images)
 }
 
+// Purge removes the Kubernetes containers and the images of o, then restores daemon.json and removes the drop-ins of cri-docker
+// minikube wrote, restarting docker once if it changed any of them. Containers and images of the user are left alone.
+func (r *Docker) Purge(o PurgeOptions) error {
+	if err := purgeKubernetes(r, o); err != nil {
+		return err
+	}
+	restored, err := r.restoreDaemonConfig()
+	if err != nil {
+		return err
+	}
+	removed, err := removeCRIDockerServiceConfs(r.Runner, r.pathConfig())
+	if err != nil {
+		return err
+	}
+	if !restored && !removed {
+		klog.Infof("purge: the settings of docker are unchanged, not restarting it")
+		return nil
+	}
+	klog.Infof("purge: restarting docker")
+	return r.Restart()
+}
+
 const (
 	CNIBinDir   = "/opt/cni/bin"
 	CNIConfDir  = "/etc/cni/net.d"
 	CNICacheDir = "/var/lib/cni/cache"
 )
 
-func dockerConfigureNetworkPlugin(r Docker, cr CommandRunner, networkPlugin string) error {
-	if networkPlugin == "" {
-		// no-op plugin
-		return nil
+const (
+	// criDockerServiceConfFile is the drop-in of the cri-docker service setting the network plugin
+	// It sorts before the drop-ins of users, whose ExecStart settings are merged into it.
+	criDockerServiceConfFile = "/etc/systemd/system/cri-docker.service.d/05-minikube-cni.conf"
+	// legacyCRIDockerServiceConfFile is where older versions wrote criDockerServiceConfFile
+	legacyCRIDockerServiceConfFile = "/etc/systemd/system/cri-docker.service.d/10-cni.conf"
+	// criDockerServiceConfHeader starts the drop-ins written by minikube
+	criDockerServiceConfHeader = "[Service]\nExecStart=\nExecStart=/usr/bin/cri-dockerd --container-runtime-endpoint "
+	// criDockerActivatedEndpoint is the endpoint of cri-dockerd when it serves the socket of cri-docker.socket
+	criDockerActivatedEndpoint = "fd://"
+)
+
+var criDockerServiceConfTemplate = template.Must(template.New("criDockerServiceConfTemplate").Parse(criDockerServiceConfHeader + `{{.Endpoint}} --network-plugin={{.NetworkPlugin}}{{if eq .NetworkPlugin "cni"}} --cni-bin-dir={{.BinDir}} --cni-cache-dir={{.CacheDir}} --cni-conf-dir={{.ConfDir}} --hairpin-mode=promiscuous-bridge{{end}}{{if .DualStack}} --ipv6-dual-stack{{end}}`))
+
+// criDockerServiceConf renders the cri-docker drop-in serving endpoint, for the network plugin settings s,
+// or for networkPlugin with the standard CNI directories if s is nil, and for both IP families if dualStack
+func criDockerServiceConf(endpoint string, networkPlugin string, s *cni.CNIRuntimeSettings, dualStack bool) ([]byte, error) {
+	settings := cni.CNIRuntimeSettings{NetworkPlugin: networkPlugin, BinDir: CNIBinDir, ConfDir: cni.ConfDir, CacheDir: CNICacheDir}
+	if s != nil {
+		settings = *s
 	}
+	b := bytes.Buffer{}
+	data := struct {
+		cni.CNIRuntimeSettings
+		Endpoint  string
+		DualStack bool
+	}{settings, endpoint, dualStack}
+	if err := criDockerServiceConfTemplate.Execute(&b, data); err != nil {
+		return nil, errors.Wrap(err, "failed to execute template")
+	}
+	return b.Bytes(), nil
+}
 
-	args := ""
-	if networkPlugin == "cni" {
-		args += " --cni-bin-dir=" + CNIBinDir
-		args += " --cni-cache-dir=" + CNICacheDir
-		args += " --cni-conf-dir=" + cni.ConfDir
-		args += " --hairpin-mode=promiscuous-bridge"
+// removeCRIDockerServiceConfs removes the drop-ins of cri-docker written by minikube, leaving files of the user alone,
+// and returns whether it removed any
+func removeCRIDockerServiceConfs(cr CommandRunner, paths PathConfig) (bool, error) {
+	removed := false
+	for _, conf := range []string{paths.systemdPath(criDockerServiceConfFile), paths.systemdPath(legacyCRIDockerServiceConfFile)} {
+		rr, err := cr.RunCmd(command.Sudo("cat", conf))
+		if err != nil || !strings.HasPrefix(rr.Stdout.String(), criDockerServiceConfHeader) {
+			continue
+		}
+		klog.Infof("purge: removing %s", conf)
+		if _, err := cr.RunCmd(command.Sudo("rm", "-f", conf)); err != nil {
+			return removed, errors.Wrapf(err, "removing %s", conf)
+		}
+		removed = true
 	}
+	return removed, nil
+}
 
-	opts := struct {
-		NetworkPlugin  string
-		ExtraArguments string
-	}{
-		NetworkPlugin:  networkPlugin,
-		ExtraArguments: args,
+// criDockerEndpoint returns the endpoint cri-dockerd serves: the custom socket of the user, or the socket activated by cri-docker.socket
+func (r *Docker) criDockerEndpoint() string {
+	if customDockerCRISocket(r.Socket) {
+		return SocketURL(r.Socket)
 	}
+	return criDockerActivatedEndpoint
+}
 
-	const CRIDockerServiceConfFile = "/etc/systemd/system/cri-docker.service.d/10-cni.conf"
-	var CRIDockerServiceConfTemplate = template.Must(template.New("criDockerServiceConfTemplate").Parse(`[Service]
-ExecStart=
-ExecStart=/usr/bin/cri-dockerd --container-runtime-endpoint fd:// --network-plugin={{.NetworkPlugin}}{{.ExtraArguments}}`))
+// validateCRISocketDir checks that cri-dockerd can create the custom socket of the user
+func (r *Docker) validateCRISocketDir() error {
+	dir := path.Dir(SocketFile(r.Socket))
+	if _, err := r.Runner.RunCmd(command.Sudo("test", "-d", dir, "-a", "-w", dir)); err != nil {
+		return errors.Errorf("the directory %s of the CRI socket %s does not exist or is not writable", dir, r.Socket)
+	}
+	return nil
+}
 
-	b := bytes.Buffer{}
-	if err := CRIDockerServiceConfTemplate.Execute(&b, opts); err != nil {
-		return errors.Wrap(err, "failed to execute template")
+// expectedCRIDockerServiceConf renders the drop-in of cri-docker for networkPlugin, before the flags of the drop-ins of users are merged in,
+// or returns nil if minikube writes none
+func (r Docker) expectedCRIDockerServiceConf(networkPlugin string) ([]byte, error) {
+	if networkPlugin == "" && !customDockerCRISocket(r.Socket) {
+		// no-op plugin
+		return nil, nil
+	}
+	v4, v6, err := cni.SplitCIDRs(r.PodCIDR)
+	if err != nil {
+		return nil, errors.Wrap(err, "pod CIDR")
+	}
+	return criDockerServiceConf(r.criDockerEndpoint(), networkPlugin, r.CNI, v4 != "" && v6 != "")
+}
+
+func dockerConfigureNetworkPlugin(r Docker, cr CommandRunner, networkPlugin string) error {
+	serviceConf, err := r.expectedCRIDockerServiceConf(networkPlugin)
+	if err != nil || serviceConf == nil {
+		return err
+	}
+	defer timePhase("docker.configure-network-plugin")()
+
+	if customDockerCRISocket(r.Socket) {
+		if err := r.validateCRISocketDir(); err != nil {
+			return err
+		}
+	}
+	paths := r.pathConfig()
+	serviceConf, dropInsChanged, err := reconcileCRIDockerDropIns(cr, paths, serviceConf)
+	if err != nil {
+		return err
+	}
+	conf := paths.systemdPath(criDockerServiceConfFile)
+	if rr, err := cr.RunCmd(command.Sudo("cat", conf)); !dropInsChanged && err == nil && bytes.Equal(rr.Stdout.Bytes(), serviceConf) {
+		klog.Infof("%s is up to date", conf)
+		return nil
 	}
-	criDockerService := b.Bytes()
-	c := exec.Command("sudo", "mkdir", "-p", path.Dir(CRIDockerServiceConfFile))
+	c := command.Sudo("mkdir", "-p", path.Dir(conf))
 	if _, err := cr.RunCmd(c); err != nil {
 		return errors.Wrapf(err, "failed to create directory")
 	}
-	svc := assets.NewMemoryAssetTarget(criDockerService, CRIDockerServiceConfFile, "0644")
+	svc := assets.NewMemoryAssetTarget(serviceConf, conf, "0644")
 	if err := cr.Copy(svc); err != nil {
 		return errors.Wrap(err, "failed to copy template")
 	}
-	return r.Init.Restart("cri-docker")
+	// cri-dockerd only reads its settings as it starts, so restart it for the CNI of the cluster or its socket to change.
+	// Restarting reloads the unit files first, so that the changed drop-ins apply.
+	return r.restarts.restartLater(criDockerService, func() error { return r.restartCRIDocker(context.Background()) }, nil)
 }
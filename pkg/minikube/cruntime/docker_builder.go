@@ -0,0 +1,268 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+const (
+	// BuilderService is the systemd unit of the isolated builder dockerd
+	BuilderService = "docker-builder"
+	// BuilderSocket is the socket of the isolated builder dockerd in the node
+	BuilderSocket = "/var/run/docker-builder.sock"
+	// builderUnitFile is the unit file of BuilderService
+	builderUnitFile = "/etc/systemd/system/docker-builder.service"
+	// builderConfigFile is the daemon.json of the builder, which does not read the one of the daemon of Kubernetes
+	builderConfigFile = "/etc/docker-builder/daemon.json"
+	// builderNamespace is the containerd namespace of the builder, when its images are not shared with the daemon of Kubernetes
+	builderNamespace = "moby-builder"
+	// sharedNamespace is the containerd namespace of the daemon of Kubernetes, which the builder shares with the containerd image store
+	sharedNamespace = "moby"
+	// containerdSnapshotterDriver is the driver type docker reports when it stores its images in containerd
+	containerdSnapshotterDriver = "io.containerd.snapshotter.v1"
+)
+
+// DockerDaemon is one of the docker daemons of a node
+type DockerDaemon struct {
+	// Service is the systemd unit running the daemon
+	Service string
+	// Socket is the path of the socket of the daemon in the node
+	Socket string
+	// Port is the TCP port the daemon listens on with TLS
+	Port int
+}
+
+var (
+	// kubernetesDaemon is the docker daemon running the containers of Kubernetes
+	kubernetesDaemon = DockerDaemon{Service: "docker", Socket: "/var/run/docker.sock", Port: constants.DockerDaemonPort}
+	// builderDaemon is the isolated builder, which runs image builds apart from the containers of Kubernetes
+	builderDaemon = DockerDaemon{Service: BuilderService, Socket: BuilderSocket, Port: constants.BuilderDaemonPort}
+)
+
+// command returns the docker command running args against the daemon
+func (d DockerDaemon) command(args ...string) *exec.Cmd {
+	if d.Service != kubernetesDaemon.Service {
+		args = append([]string{"--host", SocketURL(d.Socket)}, args...)
+	}
+	return exec.Command("docker", args...)
+}
+
+// Daemons returns the docker daemons of the node: the one of Kubernetes, followed by the isolated builder if enabled
+func (r *Docker) Daemons() []DockerDaemon {
+	if r.IsolatedBuilder {
+		return []DockerDaemon{kubernetesDaemon, builderDaemon}
+	}
+	return []DockerDaemon{kubernetesDaemon}
+}
+
+// BuildDaemon returns the docker daemon building the images: the isolated builder if enabled, or the one of Kubernetes
+func (r *Docker) BuildDaemon() DockerDaemon {
+	ds := r.Daemons()
+	return ds[len(ds)-1]
+}
+
+// BuilderEndpoint returns the endpoint of the isolated builder reached at host, with the client certificates in tlsDir.
+// Kubernetes only runs the images it builds without promoting them if both daemons store their images in containerd.
+func (r *Docker) BuilderEndpoint(host, tlsDir string) DaemonEndpoint {
+	return DaemonEndpoint{Host: host, TLSDir: tlsDir, BuildTarget: r.builderSharesImages()}
+}
+
+// builderSettings are the settings of the unit of the isolated builder
+type builderSettings struct {
+	// Socket is the socket of the builder
+	Socket string
+	// Port is the TCP port of the builder, reached with the certificates of the daemon of Kubernetes
+	Port int
+	// ConfigDir is the directory of the certificates of the daemon of Kubernetes
+	ConfigDir string
+	// ConfigFile is the daemon.json of the builder
+	ConfigFile string
+	// Namespace is the containerd namespace of the builder
+	Namespace string
+}
+
+var builderUnitTmpl = template.Must(template.New("docker-builder").Parse(`[Unit]
+Description=Docker daemon of the image builds of minikube, isolated from the runtime of Kubernetes
+After=network-online.target containerd.service docker.service
+Wants=network-online.target
+
+[Service]
+Type=notify
+ExecStart=/usr/bin/dockerd --host unix://{{.Socket}} --host tcp://0.0.0.0:{{.Port}} --tlsverify --tlscacert {{.ConfigDir}}/ca.pem --tlscert {{.ConfigDir}}/server.pem --tlskey {{.ConfigDir}}/server-key.pem --config-file {{.ConfigFile}} --data-root /var/lib/docker-builder --exec-root /var/run/docker-builder --pidfile /var/run/docker-builder.pid --containerd /run/containerd/containerd.sock --containerd-namespace {{.Namespace}} --containerd-plugins-namespace {{.Namespace}}-plugins --bridge none --iptables=false --ip-masq=false --group docker
+ExecReload=/bin/kill -s HUP $MAINPID
+Restart=on-failure
+# builds are killed first when the node runs out of memory, sparing the runtime of Kubernetes
+OOMScoreAdjust=500
+Delegate=yes
+KillMode=process
+LimitNOFILE=infinity
+TasksMax=infinity
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// builderUnit returns the unit file of the isolated builder
+func builderUnit(s builderSettings) ([]byte, error) {
+	var b bytes.Buffer
+	if err := builderUnitTmpl.Execute(&b, s); err != nil {
+		return nil, errors.Wrap(err, "docker-builder unit")
+	}
+	return b.Bytes(), nil
+}
+
+// sharedImageStore returns whether the containerd-snapshotter feature stores the images of docker in containerd,
+// where the isolated builder shares them with the daemon of Kubernetes
+func (r *Docker) sharedImageStore() bool {
+	for _, f := range r.Features {
+		k, v, _ := strings.Cut(f, "=")
+		if k != "containerd-snapshotter" {
+			continue
+		}
+		on, err := strconv.ParseBool(v)
+		return err == nil && on
+	}
+	return false
+}
+
+// builderConfig returns the daemon.json of the isolated builder, with the daemon features and the registry mirrors of r.
+// The builder has no bridge, so builds run with the network of the node.
+func (r *Docker) builderConfig() ([]byte, error) {
+	var features []string
+	for _, f := range r.Features {
+		// the IPv6 settings configure the bridge, which the builder lacks
+		if k, _, _ := strings.Cut(f, "="); dockerTopLevelParsers[k] == nil {
+			features = append(features, f)
+		}
+	}
+	data, err := mergeDaemonConfig(nil, daemonSettings{features: features, mirrors: r.RegistryMirrors})
+	if err != nil {
+		return nil, err
+	}
+	daemonConfig := map[string]interface{}{}
+	if err := json.Unmarshal(data, &daemonConfig); err != nil {
+		return nil, err
+	}
+	daemonConfig["builder"] = map[string]interface{}{"entitlements": map[string]interface{}{"network-host": true}}
+	return json.MarshalIndent(daemonConfig, "", "  ")
+}
+
+// writeBuilderFile writes data to the file of the builder at dst if it changed, returning whether it did
+func (r *Docker) writeBuilderFile(dst string, data []byte) (bool, error) {
+	if rr, err := r.Runner.RunCmd(command.Sudo("cat", dst)); err == nil && bytes.Equal(rr.Stdout.Bytes(), data) {
+		klog.Infof("%s is up to date", dst)
+		return false, nil
+	}
+	if _, err := r.Runner.RunCmd(command.Sudo("mkdir", "-p", path.Dir(dst))); err != nil {
+		return false, errors.Wrapf(err, "creating %s", path.Dir(dst))
+	}
+	if err := r.Runner.Copy(assets.NewMemoryAssetTarget(data, dst, "0644")); err != nil {
+		return false, errors.Wrapf(err, "writing %s", dst)
+	}
+	return true, nil
+}
+
+// enableBuilder installs and starts the isolated builder, restarting it if its settings changed
+func (r *Docker) enableBuilder() error {
+	if r.osProfile().OS != "linux" {
+		return fmt.Errorf("the isolated builder requires a Linux node, not %s", r.osProfile().OS)
+	}
+	ns := builderNamespace
+	if r.sharedImageStore() {
+		ns = sharedNamespace
+	}
+	paths := r.pathConfig()
+	confFile := path.Join(paths.BuilderConfigDir, path.Base(builderConfigFile))
+	unit, err := builderUnit(builderSettings{Socket: BuilderSocket, Port: constants.BuilderDaemonPort, ConfigDir: r.osProfile().ConfigDir, ConfigFile: confFile, Namespace: ns})
+	if err != nil {
+		return err
+	}
+	conf, err := r.builderConfig()
+	if err != nil {
+		return err
+	}
+	unitChanged, err := r.writeBuilderFile(paths.systemdPath(builderUnitFile), unit)
+	if err != nil {
+		return err
+	}
+	confChanged, err := r.writeBuilderFile(confFile, append(conf, '\n'))
+	if err != nil {
+		return err
+	}
+	if err := r.Init.Enable(BuilderService); err != nil {
+		return err
+	}
+	if unitChanged || confChanged {
+		return r.Init.Restart(BuilderService)
+	}
+	return r.Init.Start(BuilderService)
+}
+
+// disableBuilder stops the isolated builder, if running, keeping its images for when it is enabled again
+func (r *Docker) disableBuilder() error {
+	if !r.Init.Active(BuilderService) {
+		return nil
+	}
+	klog.Infof("stopping the isolated builder, which the cluster no longer uses")
+	return r.Init.DisableNow(BuilderService)
+}
+
+// builderSharesImages returns whether the isolated builder stores its images in containerd, next to those of Kubernetes
+func (r *Docker) builderSharesImages() bool {
+	return containerdImageStore(r.Runner, builderDaemon)
+}
+
+// promoteImage makes the image built by the isolated builder available to Kubernetes: the containerd image store
+// shares it already, otherwise it is streamed from the builder into the daemon of Kubernetes.
+func (r *Docker) promoteImage(ctx context.Context, name string) error {
+	if r.builderSharesImages() {
+		klog.Infof("%s is in the containerd image store shared with Kubernetes", name)
+		return nil
+	}
+	klog.Infof("Promoting %s from the isolated builder", name)
+	save := shellquote.Join(builderDaemon.command("save", name).Args...)
+	if _, err := r.Runner.RunCmdContext(ctx, exec.Command("/bin/bash", "-c", fmt.Sprintf("%s | docker load", save))); err != nil {
+		return errors.Wrapf(err, "promoting %s", name)
+	}
+	return nil
+}
+
+// hasBuildOpt returns whether the options of the build set the flag name
+func hasBuildOpt(opts BuildOptions, name string) bool {
+	for _, o := range opts.Opts {
+		if k, _, _ := strings.Cut(o, "="); k == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,589 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/cni"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// dockerFeatureParsers parse the values of the known daemon features, which go into the "features" object of daemon.json
+var dockerFeatureParsers = map[string]func(string) (interface{}, error){
+	"buildkit":               parseBoolFeature,
+	"containerd-snapshotter": parseBoolFeature,
+}
+
+// dockerTopLevelParsers parse the values of the known daemon options set with --docker-feature, which are top-level keys of daemon.json
+var dockerTopLevelParsers = map[string]func(string) (interface{}, error){
+	"ipv6": parseBoolFeature,
+	"fixed-cidr-v6": func(v string) (interface{}, error) {
+		ip, _, err := net.ParseCIDR(v)
+		if err != nil || ip.To4() != nil {
+			return nil, fmt.Errorf("%q is not an IPv6 CIDR", v)
+		}
+		return v, nil
+	},
+}
+
+func parseBoolFeature(v string) (interface{}, error) {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a boolean", v)
+	}
+	return b, nil
+}
+
+// ValidateDockerFeatures checks docker features formatted as key=value, returning the keys minikube does not know
+func ValidateDockerFeatures(features []string) ([]string, error) {
+	unknown := []string{}
+	for _, f := range features {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid docker feature %q, expected key=value", f)
+		}
+		parse, ok := dockerFeatureParsers[kv[0]]
+		if !ok {
+			parse, ok = dockerTopLevelParsers[kv[0]]
+		}
+		if !ok {
+			unknown = append(unknown, kv[0])
+			continue
+		}
+		if _, err := parse(kv[1]); err != nil {
+			return nil, errors.Wrapf(err, "docker feature %s", kv[0])
+		}
+	}
+	return unknown, nil
+}
+
+// daemonSettings are the settings minikube merges into daemon.json
+type daemonSettings struct {
+	// forceSystemd sets the systemd cgroup settings
+	forceSystemd bool
+	// features are formatted as key=value
+	features []string
+	mirrors  []string
+	// bridgeIP is the address of docker0 with its prefix length, as "bip" expects it
+	bridgeIP string
+	mtu      int
+	// nvidia registers the NVIDIA container runtime as the default one
+	nvidia bool
+	// fixedCIDRv6 is the IPv6 network of docker0, enabling IPv6 if not empty
+	fixedCIDRv6 string
+	// experimental enables the experimental features of docker, which ip6tables is one of before docker 27
+	experimental bool
+	// selinux makes docker label the containers, for SELinux enforcing hosts
+	selinux bool
+}
+
+// ownedDaemonConfig returns the keys of daemon.json minikube sets for s, with their values: the systemd cgroup settings, if forced,
+// the registry mirrors, the bridge settings, the NVIDIA runtime, the IPv6 settings, the SELinux support and the docker features, if any.
+// The keys of the "features" and "runtimes" objects are prefixed with their name and a dot, as "features.buildkit".
+// Unknown features are passed as booleans if they parse as such, and as strings otherwise.
+func ownedDaemonConfig(s daemonSettings) (map[string]interface{}, error) {
+	owned := map[string]interface{}{}
+	if s.forceSystemd {
+		owned["exec-opts"] = []string{"native.cgroupdriver=systemd"}
+		owned["log-driver"] = "json-file"
+		owned["log-opts"] = map[string]string{"max-size": "100m"}
+		owned["storage-driver"] = "overlay2"
+	}
+	if len(s.mirrors) > 0 {
+		owned["registry-mirrors"] = s.mirrors
+	}
+	if s.bridgeIP != "" {
+		owned["bip"] = s.bridgeIP
+	}
+	if s.mtu > 0 {
+		owned["mtu"] = s.mtu
+	}
+	if s.nvidia {
+		owned["runtimes."+nvidiaRuntime] = map[string]interface{}{"path": nvidiaRuntimePath, "args": []string{}}
+		owned["default-runtime"] = nvidiaRuntime
+	}
+	if s.fixedCIDRv6 != "" {
+		owned["ipv6"] = true
+		owned["fixed-cidr-v6"] = s.fixedCIDRv6
+		owned["ip6tables"] = true
+	}
+	if s.experimental {
+		owned["experimental"] = true
+	}
+	if s.selinux {
+		owned["selinux-enabled"] = true
+	}
+	for _, f := range s.features {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid docker feature %q, expected key=value", f)
+		}
+		if parse, ok := dockerTopLevelParsers[kv[0]]; ok {
+			v, err := parse(kv[1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "docker feature %s", kv[0])
+			}
+			owned[kv[0]] = v
+			continue
+		}
+		var v interface{} = kv[1]
+		if parse, ok := dockerFeatureParsers[kv[0]]; ok {
+			var err error
+			if v, err = parse(kv[1]); err != nil {
+				return nil, errors.Wrapf(err, "docker feature %s", kv[0])
+			}
+		} else if b, err := strconv.ParseBool(kv[1]); err == nil {
+			v = b
+		}
+		owned["features."+kv[0]] = v
+	}
+	return owned, nil
+}
+
+// ownedDaemonObjects are the objects of daemon.json minikube sets some keys of, keeping the others
+var ownedDaemonObjects = []string{"features", "runtimes"}
+
+// mergeDaemonConfig merges the keys minikube owns for s into the daemon.json content current.
+// Other runtimes are kept, as nvidia-ctk does when it configures docker, and so are other features.
+func mergeDaemonConfig(current []byte, s daemonSettings) ([]byte, error) {
+	daemonConfig := map[string]interface{}{}
+	if len(bytes.TrimSpace(current)) > 0 {
+		if err := json.Unmarshal(current, &daemonConfig); err != nil {
+			return nil, errors.Wrap(err, "parsing daemon.json")
+		}
+	}
+	owned, err := ownedDaemonConfig(s)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range owned {
+		object, key := splitOwnedDaemonKey(k)
+		if object == "" {
+			daemonConfig[k] = v
+			continue
+		}
+		o, ok := daemonConfig[object].(map[string]interface{})
+		if !ok {
+			o = map[string]interface{}{}
+			daemonConfig[object] = o
+		}
+		o[key] = v
+	}
+	// encoding/json sorts the keys of maps, so that unchanged settings give the same content
+	return json.MarshalIndent(daemonConfig, "", "  ")
+}
+
+// splitOwnedDaemonKey returns the object of daemon.json a key of ownedDaemonConfig is in, or an empty one for top-level keys, and its key there
+func splitOwnedDaemonKey(k string) (string, string) {
+	for _, o := range ownedDaemonObjects {
+		if strings.HasPrefix(k, o+".") {
+			return o, strings.TrimPrefix(k, o+".")
+		}
+	}
+	return "", k
+}
+
+// daemonSettings returns the settings of r to merge into daemon.json
+func (r *Docker) daemonSettings(forceSystemd bool) (daemonSettings, error) {
+	s := daemonSettings{forceSystemd: forceSystemd, features: r.Features, mirrors: r.RegistryMirrors, mtu: r.MTU, nvidia: r.GPUs != "", selinux: r.selinux}
+	if r.BridgeCIDR != "" {
+		bip, err := DockerBridgeIP(r.BridgeCIDR)
+		if err != nil {
+			return s, err
+		}
+		s.bridgeIP = bip
+	}
+	_, v6, err := cni.SplitCIDRs(r.PodCIDR)
+	if err != nil {
+		return s, errors.Wrap(err, "pod CIDR")
+	}
+	if v6 != "" {
+		if s.fixedCIDRv6, err = dockerFixedCIDRv6(v6); err != nil {
+			return s, err
+		}
+		s.experimental = r.ip6tablesExperimental()
+	}
+	return s, nil
+}
+
+// dockerStableIP6Tables is the first version of docker with ip6tables out of the experimental features
+var dockerStableIP6Tables = semver.Version{Major: 27}
+
+// ip6tablesExperimental returns whether the experimental features of docker must be enabled for ip6tables.
+// dockerd reports its version without the daemon running, which may not be yet. They are enabled if the version is unknown.
+func (r *Docker) ip6tablesExperimental() bool {
+	rr, err := r.Runner.RunCmd(exec.Command("dockerd", "--version"))
+	if err != nil {
+		klog.Warningf("unable to get the version of dockerd, enabling the experimental features for ip6tables: %v", err)
+		return true
+	}
+	// Docker version 27.1.1, build cc13f95
+	fields := strings.Fields(strings.TrimSpace(rr.Stdout.String()))
+	if len(fields) < 3 {
+		klog.Warningf("unable to parse the version of dockerd %q, enabling the experimental features for ip6tables", rr.Stdout.String())
+		return true
+	}
+	v, err := semver.ParseTolerant(strings.TrimSuffix(fields[2], ","))
+	if err != nil {
+		klog.Warningf("unable to parse the version of dockerd %q, enabling the experimental features for ip6tables: %v", fields[2], err)
+		return true
+	}
+	return v.LT(dockerStableIP6Tables)
+}
+
+// dockerFixedCIDRv6 returns the IPv6 network of docker0 for the IPv6 pod network: its last /64,
+// which Kubernetes allocates to the nodes last, or the whole pod network if it is not larger
+func dockerFixedCIDRv6(podCIDR string) (string, error) {
+	_, n, err := net.ParseCIDR(podCIDR)
+	if err != nil || n.IP.To4() != nil {
+		return "", fmt.Errorf("%q is not an IPv6 CIDR", podCIDR)
+	}
+	ones, bits := n.Mask.Size()
+	if ones >= 64 {
+		return n.String(), nil
+	}
+	ip := make(net.IP, len(n.IP))
+	copy(ip, n.IP)
+	for i := ones; i < 64; i++ {
+		ip[i/8] |= 1 << (7 - uint(i%8))
+	}
+	return (&net.IPNet{IP: ip, Mask: net.CIDRMask(64, bits)}).String(), nil
+}
+
+// pendingDaemonConfig returns daemon.json with the systemd cgroup settings and the docker features merged in, or nil if it is up to date
+func (r *Docker) pendingDaemonConfig(forceSystemd bool) ([]byte, error) {
+	settings, err := r.daemonSettings(forceSystemd)
+	if err != nil {
+		return nil, err
+	}
+	if !forceSystemd && len(r.Features) == 0 && len(r.RegistryMirrors) == 0 && settings.bridgeIP == "" && settings.mtu == 0 && !settings.nvidia && settings.fixedCIDRv6 == "" && !settings.selinux {
+		return nil, nil
+	}
+	var current []byte
+	if rr, err := r.Runner.RunCmd(command.Sudo("cat", r.daemonConfigFile())); err == nil {
+		current = rr.Stdout.Bytes()
+	}
+	if len(bytes.TrimSpace(current)) > 0 && !json.Valid(current) {
+		klog.Warningf("replacing the invalid daemon.json: %s", current)
+		current = nil
+	}
+	merged, err := mergeDaemonConfig(current, settings)
+	if err != nil {
+		return nil, err
+	}
+	if normalized, err := mergeDaemonConfig(current, daemonSettings{}); err == nil && bytes.Equal(normalized, merged) {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+// daemonConfigBackupSuffix names the copy of daemon.json as it was before minikube first changed it, which Purge restores
+const daemonConfigBackupSuffix = ".minikube-backup"
+
+// daemonConfigFile returns the path of daemon.json
+func (r *Docker) daemonConfigFile() string {
+	return path.Join(r.pathConfig().ConfigDir, "daemon.json")
+}
+
+// backupDaemonConfig keeps daemon.json as it was before minikube first changes it, an empty backup meaning there was none
+func (r *Docker) backupDaemonConfig() error {
+	file := r.daemonConfigFile()
+	backup := file + daemonConfigBackupSuffix
+	if _, err := r.Runner.RunCmd(command.Sudo("test", "-e", backup)); err == nil {
+		return nil
+	}
+	var data []byte
+	if rr, err := r.Runner.RunCmd(command.Sudo("cat", file)); err == nil {
+		data = rr.Stdout.Bytes()
+	}
+	klog.Infof("backing up %s to %s", file, backup)
+	if err := r.Runner.Copy(assets.NewMemoryAssetTarget(data, backup, "0644")); err != nil {
+		return errors.Wrap(err, "backing up daemon.json")
+	}
+	return nil
+}
+
+// restoreDaemonConfig restores daemon.json from the backup of backupDaemonConfig, removing it if there was none before,
+// and returns whether there was a backup to restore
+func (r *Docker) restoreDaemonConfig() (bool, error) {
+	file := r.daemonConfigFile()
+	backup := file + daemonConfigBackupSuffix
+	if _, err := r.Runner.RunCmd(command.Sudo("test", "-e", backup)); err != nil {
+		klog.Infof("purge: %s was not changed by minikube, leaving it", file)
+		return false, nil
+	}
+	rr, err := r.Runner.RunCmd(command.Sudo("cat", backup))
+	if err != nil {
+		return false, errors.Wrap(err, "reading the backup of daemon.json")
+	}
+	if data := rr.Stdout.Bytes(); len(bytes.TrimSpace(data)) > 0 {
+		klog.Infof("purge: restoring %s from %s", file, backup)
+		if err := r.Runner.Copy(assets.NewMemoryAssetTarget(data, file, "0644")); err != nil {
+			return false, errors.Wrap(err, "restoring daemon.json")
+		}
+	} else {
+		klog.Infof("purge: removing %s, which did not exist before minikube", file)
+		if _, err := r.Runner.RunCmd(command.Sudo("rm", "-f", file)); err != nil {
+			return false, errors.Wrap(err, "removing daemon.json")
+		}
+	}
+	if _, err := r.Runner.RunCmd(command.Sudo("rm", "-f", backup)); err != nil {
+		return true, errors.Wrap(err, "removing the backup of daemon.json")
+	}
+	return true, nil
+}
+
+// writeDaemonConfig writes the pending daemon.json, returning whether it changed
+func (r *Docker) writeDaemonConfig(forceSystemd bool) (bool, error) {
+	if forceSystemd {
+		klog.Infof("Forcing docker to use systemd as cgroup manager...")
+	}
+	if len(r.Features) > 0 {
+		fs := append([]string{}, r.Features...)
+		sort.Strings(fs)
+		klog.Infof("Setting docker features: %s", strings.Join(fs, ", "))
+	}
+	data, err := r.pendingDaemonConfig(forceSystemd)
+	if err != nil || data == nil {
+		return false, err
+	}
+	if err := r.backupDaemonConfig(); err != nil {
+		return false, err
+	}
+	ma := assets.NewMemoryAssetTarget(append(data, '\n'), r.daemonConfigFile(), "0644")
+	if err := r.Runner.Copy(ma); err != nil {
+		return false, errors.Wrap(err, "writing daemon.json")
+	}
+	return true, nil
+}
+
+// dockerDaemonKeys are the top-level keys of daemon.json known to dockerd, checked when dockerd can not validate the file itself.
+// As that is a dockerd older than 23.0, they include the legacy keys which later ones removed, such as those of the cluster store.
+var dockerDaemonKeys = map[string]bool{
+	"allow-nondistributable-artifacts": true, "api-cors-header": true, "authorization-plugins": true, "bip": true, "bridge": true,
+	"builder": true, "cdi-spec-dirs": true, "cgroup-parent": true, "cluster-advertise": true, "cluster-store": true,
+	"cluster-store-opts": true, "containerd": true, "containerd-namespace": true, "containerd-plugins-namespace": true,
+	"cpu-rt-period": true, "cpu-rt-runtime": true, "cri-containerd": true, "data-root": true, "debug": true,
+	"default-address-pools": true, "default-cgroupns-mode": true, "default-gateway": true, "default-gateway-v6": true,
+	"default-ipc-mode": true, "default-network-opts": true, "default-runtime": true, "default-shm-size": true,
+	"default-ulimits": true, "disable-legacy-registry": true, "dns": true, "dns-opts": true, "dns-search": true, "exec-opts": true,
+	"exec-root": true, "experimental": true, "features": true, "fixed-cidr": true, "fixed-cidr-v6": true, "graph": true,
+	"group": true, "host-gateway-ip": true, "hosts": true, "icc": true, "init": true, "init-path": true, "insecure-registries": true,
+	"ip": true, "ip-forward": true, "ip-masq": true, "ip6tables": true, "iptables": true, "ipv6": true, "labels": true,
+	"live-restore": true, "log-driver": true, "log-format": true, "log-level": true, "log-opts": true,
+	"max-concurrent-downloads": true, "max-concurrent-uploads": true, "max-download-attempts": true, "metrics-addr": true,
+	"mtu": true, "network-control-plane-mtu": true, "no-new-privileges": true, "node-generic-resources": true,
+	"oom-score-adjust": true, "pidfile": true, "proxies": true, "raw-logs": true, "registry-mirrors": true, "rootless": true,
+	"runtimes": true, "seccomp-profile": true, "selinux-enabled": true, "shutdown-timeout": true, "storage-driver": true,
+	"storage-opts": true, "swarm-default-advertise-addr": true, "tls": true, "tlscacert": true, "tlscert": true, "tlskey": true,
+	"tlsverify": true, "userland-proxy": true, "userland-proxy-path": true, "userns-remap": true,
+}
+
+// unknownDaemonKeys returns the sorted top-level keys of daemon.json which dockerd does not know
+func unknownDaemonKeys(data []byte) ([]string, error) {
+	daemonConfig := map[string]interface{}{}
+	if err := json.Unmarshal(data, &daemonConfig); err != nil {
+		return nil, err
+	}
+	unknown := []string{}
+	for k := range daemonConfig {
+		if !dockerDaemonKeys[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// validateUnsupported returns whether dockerd failed as it does not know --validate, which dockerd 23.0 added
+func validateUnsupported(rr *command.RunResult, out string) bool {
+	return (rr != nil && rr.ExitCode == 127) || strings.Contains(out, "unknown flag") || strings.Contains(out, "flag provided but not defined")
+}
+
+// validateDaemonConfig checks daemon.json before docker is restarted with it, so that a bad value fails with the reason instead of the restart
+// dockerd validates the file itself if it can, else the file must be JSON with known keys only.
+func (r *Docker) validateDaemonConfig() error {
+	file := r.daemonConfigFile()
+	rr, err := r.Runner.RunCmd(command.Sudo("dockerd", "--validate", "--config-file", file))
+	if err == nil {
+		return nil
+	}
+	out := ""
+	if rr != nil {
+		out = strings.TrimSpace(rr.Stderr.String())
+		if out == "" {
+			out = strings.TrimSpace(rr.Stdout.String())
+		}
+	}
+	data := []byte{}
+	if rr, err := r.Runner.RunCmd(command.Sudo("cat", file)); err == nil {
+		data = rr.Stdout.Bytes()
+	}
+	unknown, jsonErr := unknownDaemonKeys(data)
+	if !validateUnsupported(rr, out) {
+		if len(unknown) > 0 {
+			return errors.Errorf("dockerd rejected %s: %s (unknown keys: %s)", file, out, strings.Join(unknown, ", "))
+		}
+		return errors.Errorf("dockerd rejected %s: %s", file, out)
+	}
+	klog.Infof("dockerd can not validate %s, checking its keys instead: %s", file, out)
+	if jsonErr != nil {
+		return errors.Wrapf(jsonErr, "%s is not valid JSON", file)
+	}
+	if len(unknown) > 0 {
+		return errors.Errorf("%s has keys unknown to dockerd: %s", file, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+const (
+	// nvidiaRuntime is the name docker knows the NVIDIA container runtime by
+	nvidiaRuntime = "nvidia"
+	// nvidiaRuntimePath is the binary of the NVIDIA container runtime, installed by nvidia-container-toolkit
+	nvidiaRuntimePath = "nvidia-container-runtime"
+)
+
+// configureGPU checks that the NVIDIA container toolkit is installed in the node, as daemon.json makes its runtime the default one
+func (r *Docker) configureGPU() error {
+	if _, err := r.Runner.RunCmd(exec.Command("which", nvidiaRuntimePath)); err != nil {
+		return errors.Wrapf(err, "%s was not found in the node, install nvidia-container-toolkit to use GPUs with docker", nvidiaRuntimePath)
+	}
+	klog.Infof("Setting %s as the default docker runtime", nvidiaRuntimePath)
+	return nil
+}
+
+// DockerBridgeIP returns the address of the docker0 bridge for an IPv4 CIDR, formatted as the "bip" of daemon.json
+// The first host address is used if cidr is the network address, as for 10.200.0.0/24.
+func DockerBridgeIP(cidr string) (string, error) {
+	ip, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return "", fmt.Errorf("%q is not an IPv4 CIDR", cidr)
+	}
+	ones, bits := ipnet.Mask.Size()
+	if bits-ones < 2 {
+		return "", fmt.Errorf("%q is too small for a bridge network", cidr)
+	}
+	ip = ip.To4()
+	if ip.Equal(ipnet.IP) {
+		ip = net.IPv4(ip[0], ip[1], ip[2], ip[3]+1).To4()
+	}
+	return fmt.Sprintf("%s/%d", ip, ones), nil
+}
+
+// ValidateDockerBridge checks that the docker bridge cidr does not overlap the networks in others, keyed by their description
+// Empty networks are skipped.
+func ValidateDockerBridge(cidr string, others map[string]string) error {
+	if _, err := DockerBridgeIP(cidr); err != nil {
+		return err
+	}
+	return validateBridgeOverlap("docker bridge network", cidr, others)
+}
+
+// ValidateDockerFixedCIDRv6 checks that the IPv6 network docker0 gets from the IPv6 pod network podCIDR
+// does not overlap the networks in others, keyed by their description. Empty networks are skipped.
+func ValidateDockerFixedCIDRv6(podCIDR string, others map[string]string) error {
+	cidr, err := dockerFixedCIDRv6(podCIDR)
+	if err != nil {
+		return err
+	}
+	return validateBridgeOverlap("docker bridge IPv6 network", cidr, others)
+}
+
+// validateBridgeOverlap checks that the network cidr, described by what, does not overlap the networks in others
+func validateBridgeOverlap(what, cidr string, others map[string]string) error {
+	_, bridge, _ := net.ParseCIDR(cidr)
+	names := []string{}
+	for name := range others {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if others[name] == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(others[name])
+		if err != nil {
+			klog.Warningf("unable to check the %s against the %s %q: %v", what, name, others[name], err)
+			continue
+		}
+		if bridge.Contains(n.IP) || n.Contains(bridge.IP) {
+			return fmt.Errorf("the %s %s overlaps the %s %s", what, cidr, name, others[name])
+		}
+	}
+	return nil
+}
+
+// ValidateDockerMTU checks that mtu can be set on the docker bridge
+func ValidateDockerMTU(mtu int) error {
+	// 68 is the minimum MTU of IPv4
+	if mtu < 68 || mtu > 65535 {
+		return fmt.Errorf("the docker MTU %d is not between 68 and 65535", mtu)
+	}
+	return nil
+}
+
+// bridgeAddress parses the address of docker0 from the output of 'ip -4 -o addr show dev docker0'
+func bridgeAddress(out string) string {
+	fields := strings.Fields(out)
+	for i, f := range fields {
+		if f == "inet" && i+1 < len(fields) {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
+
+// removeStaleBridge stops docker and deletes docker0 if its address is not the configured one, as docker does not readdress an existing bridge
+func (r *Docker) removeStaleBridge() error {
+	if r.BridgeCIDR == "" {
+		return nil
+	}
+	want, err := DockerBridgeIP(r.BridgeCIDR)
+	if err != nil {
+		return err
+	}
+	rr, err := r.Runner.RunCmd(exec.Command("ip", "-4", "-o", "addr", "show", "dev", "docker0"))
+	if err != nil {
+		klog.Infof("no docker0 bridge to check: %v", err)
+		return nil
+	}
+	current := bridgeAddress(rr.Stdout.String())
+	if current == "" || current == want {
+		return nil
+	}
+	klog.Infof("docker0 has address %s instead of %s, recreating it", current, want)
+	if err := r.Init.Stop("docker"); err != nil {
+		return errors.Wrap(err, "stopping docker")
+	}
+	if _, err := r.Runner.RunCmd(command.Sudo("ip", "link", "delete", "docker0")); err != nil {
+		return errors.Wrap(err, "deleting docker0")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/driver"
+)
+
+// dockerGroup is the group of the users running docker without sudo, which owns the socket of docker
+const dockerGroup = "docker"
+
+// manageGroup returns whether minikube sets up the docker group of the host, which is left to the users of bare metal
+// and ssh hosts, as membership in the group is as good as root
+func (r *Docker) manageGroup() bool {
+	return !r.BareMetal && !driver.IsSSH(r.Driver) && r.osProfile().OS == "linux"
+}
+
+// configureDockerGroup creates the docker group and adds GroupUser to it, unless they are already set up,
+// as usermod rewrites /etc/group and only applies to the sessions the user opens afterwards
+func (r *Docker) configureDockerGroup() error {
+	if _, err := r.Runner.RunCmd(exec.Command("getent", "group", dockerGroup)); err != nil {
+		klog.Infof("creating the %s group", dockerGroup)
+		if _, err := r.Runner.RunCmd(command.Sudo("groupadd", "--system", dockerGroup)); err != nil {
+			return errors.Wrapf(err, "creating the %s group", dockerGroup)
+		}
+	}
+	// root runs docker without the group
+	if r.GroupUser == "" || r.GroupUser == "root" {
+		return nil
+	}
+	rr, err := r.Runner.RunCmd(exec.Command("id", "-nG", r.GroupUser))
+	if err != nil {
+		return errors.Wrapf(err, "listing the groups of %s", r.GroupUser)
+	}
+	if containsString(strings.Fields(rr.Stdout.String()), dockerGroup) {
+		klog.Infof("%s is already in the %s group", r.GroupUser, dockerGroup)
+		return nil
+	}
+	klog.Infof("adding %s to the %s group", r.GroupUser, dockerGroup)
+	if _, err := r.Runner.RunCmd(command.Sudo("usermod", "-aG", dockerGroup, r.GroupUser)); err != nil {
+		return errors.Wrapf(err, "adding %s to the %s group", r.GroupUser, dockerGroup)
+	}
+	return nil
+}
+
+// configureSocketAccess gives the docker group access to the socket of docker, and to the one of cri-dockerd if CRISocketGroup is set.
+// The sockets are created by systemd, so that they are owned by root if the group did not exist yet.
+// Failures are only logged, as the users can still reach the sockets with sudo.
+func (r *Docker) configureSocketAccess() {
+	sockets := []string{SocketFile(r.osProfile().EngineSocket)}
+	if r.CRISocketGroup && r.CRIService != "" {
+		sockets = append(sockets, SocketFile(r.SocketPath()))
+	}
+	for _, s := range sockets {
+		if err := groupSocketAccess(r.Runner, s); err != nil {
+			klog.Warningf("unable to give the %s group access to %s: %v", dockerGroup, s, err)
+		}
+	}
+}
+
+// groupSocketAccess makes the docker group own the socket at path with read and write access, changing only what differs
+func groupSocketAccess(cr CommandRunner, path string) error {
+	rr, err := cr.RunCmd(command.Sudo("stat", "-c", "%G %a", path))
+	if err != nil {
+		return errors.Wrapf(err, "stat %s", path)
+	}
+	fields := strings.Fields(rr.Stdout.String())
+	if len(fields) != 2 {
+		return errors.Errorf("unexpected stat output %q", rr.Stdout.String())
+	}
+	mode, err := strconv.ParseUint(fields[1], 8, 32)
+	if err != nil {
+		return errors.Wrapf(err, "parsing mode %q", fields[1])
+	}
+	if fields[0] != dockerGroup {
+		klog.Infof("%s is owned by the %s group, changing it to %s", path, fields[0], dockerGroup)
+		if _, err := cr.RunCmd(command.Sudo("chgrp", dockerGroup, path)); err != nil {
+			return errors.Wrapf(err, "chgrp %s", path)
+		}
+	}
+	if mode&0o060 != 0o060 {
+		klog.Infof("%s has mode %s, giving its group read and write access", path, fields[1])
+		if _, err := cr.RunCmd(command.Sudo("chmod", "g+rw", path)); err != nil {
+			return errors.Wrapf(err, "chmod %s", path)
+		}
+	}
+	return nil
+}
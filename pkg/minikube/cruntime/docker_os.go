@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/driver"
+)
+
+// dockerOSProfile holds the details of a docker host which depend on its operating system
+type dockerOSProfile struct {
+	// OS is the name of the operating system, as in runtime.GOOS
+	OS string
+	// EngineSocket is the endpoint of the docker engine
+	EngineSocket string
+	// InternalCRISocket is the endpoint of dockershim
+	InternalCRISocket string
+	// ExternalCRISocket is the endpoint of cri-dockerd
+	ExternalCRISocket string
+	// ConfigDir is the directory containing daemon.json
+	ConfigDir string
+	// Shell returns the command running a pipeline
+	Shell func(pipeline string) *exec.Cmd
+	// Script returns the command running several commands in one invocation
+	Script scriptFunc
+	// LoadPipeline returns the pipeline loading the image archive at path
+	LoadPipeline func(path string) string
+	// SavePipeline returns the pipeline saving images to the archive at path
+	SavePipeline func(path string, names ...string) string
+	// SystemLogCmd returns the command to retrieve the last len lines of the docker logs
+	SystemLogCmd func(len int) string
+}
+
+var linuxDockerProfile = dockerOSProfile{
+	OS:                "linux",
+	EngineSocket:      "unix:///var/run/docker.sock",
+	InternalCRISocket: InternalDockerCRISocket,
+	ExternalCRISocket: ExternalDockerCRISocket,
+	ConfigDir:         "/etc/docker",
+	Shell: func(pipeline string) *exec.Cmd {
+		return exec.Command("/bin/bash", "-c", pipeline)
+	},
+	Script: bashScript,
+	LoadPipeline: func(path string) string {
+		return fmt.Sprintf("%s | docker load", shellquote.Join("sudo", "cat", path))
+	},
+	SavePipeline: func(path string, names ...string) string {
+		return fmt.Sprintf("%s | %s >/dev/null", shellquote.Join(append([]string{"docker", "save"}, names...)...), shellquote.Join("sudo", "tee", path))
+	},
+	SystemLogCmd: func(len int) string {
+		return fmt.Sprintf("sudo journalctl -u docker -n %d", len)
+	},
+}
+
+// windowsDockerProfile describes a Windows host running docker with Windows containers.
+// PowerShell pipes text rather than bytes, so the archives are read and written by docker itself.
+var windowsDockerProfile = dockerOSProfile{
+	OS:                "windows",
+	EngineSocket:      "npipe:////./pipe/docker_engine",
+	InternalCRISocket: "npipe:////./pipe/dockershim",
+	ExternalCRISocket: "npipe:////./pipe/cri-dockerd",
+	// %ProgramData%\docker\config with the default %ProgramData%, as files are copied without a shell expanding it
+	ConfigDir: `C:\ProgramData\docker\config`,
+	Shell: func(pipeline string) *exec.Cmd {
+		return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", pipeline)
+	},
+	Script: powershellScript,
+	LoadPipeline: func(path string) string {
+		return fmt.Sprintf("docker load -i %s", powershellQuote(path))
+	},
+	SavePipeline: func(path string, names ...string) string {
+		quoted := []string{}
+		for _, name := range names {
+			quoted = append(quoted, powershellQuote(name))
+		}
+		return fmt.Sprintf("docker save -o %s %s", powershellQuote(path), strings.Join(quoted, " "))
+	},
+	SystemLogCmd: func(len int) string {
+		return fmt.Sprintf("powershell -NoProfile -Command \"Get-WinEvent -ProviderName docker -MaxEvents %d | Format-List TimeCreated,Message\"", len)
+	},
+}
+
+// powershellScript runs several commands in one invocation of PowerShell, which does not stop on the exit codes of programs
+func powershellScript(lines []string, stopOnError bool) *exec.Cmd {
+	script := []string{"$rc = 0"}
+	for _, l := range lines {
+		if stopOnError {
+			script = append(script, l+"; if ($LASTEXITCODE) { exit $LASTEXITCODE }")
+		} else {
+			script = append(script, l+"; if ($LASTEXITCODE) { $rc = 1 }")
+		}
+	}
+	script = append(script, "exit $rc")
+	return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", strings.Join(script, "; "))
+}
+
+// powershellQuote quotes s as a single literal argument of a PowerShell command.
+// Only single quotes need escaping, by doubling them, but PowerShell also takes the typographic ones for single quotes.
+func powershellQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, c := range s {
+		switch c {
+		case '\'', '\u2018', '\u2019', '\u201a', '\u201b':
+			b.WriteRune(c)
+		}
+		b.WriteRune(c)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// dockerProfiles are the supported operating systems of docker hosts
+var dockerProfiles = map[string]*dockerOSProfile{
+	linuxDockerProfile.OS:   &linuxDockerProfile,
+	windowsDockerProfile.OS: &windowsDockerProfile,
+}
+
+// detectOS returns the operating system of the host behind a runner, as in runtime.GOOS
+func detectOS(cr CommandRunner) string {
+	if _, err := cr.RunCmd(exec.Command("uname", "-s")); err == nil {
+		return "linux"
+	}
+	rr, err := cr.RunCmd(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", "[System.Environment]::OSVersion.Platform"))
+	if err == nil && strings.TrimSpace(rr.Stdout.String()) == "Win32NT" {
+		return "windows"
+	}
+	klog.Warningf("unable to detect the operating system of the docker host, assuming linux")
+	return "linux"
+}
+
+// driverOS returns the operating system of the docker hosts of drv, which is empty for the hosts of the ssh driver and unknown drivers
+func driverOS(drv string) string {
+	if drv == "" || driver.IsSSH(drv) {
+		return ""
+	}
+	// the guests and kic containers minikube creates, as the host of the none driver, run linux
+	return "linux"
+}
+
+// osProfile returns the profile matching the operating system of the docker host, known from its driver or detected on first use
+func (r *Docker) osProfile() *dockerOSProfile {
+	if r.profile != nil {
+		return r.profile
+	}
+	if r.OS == "" {
+		r.OS = driverOS(r.Driver)
+	}
+	if r.OS == "" && r.Runner != nil {
+		r.OS = detectOS(r.Runner)
+	}
+	p, ok := dockerProfiles[r.OS]
+	if !ok {
+		if r.OS != "" {
+			klog.Warningf("unsupported docker host operating system %q, assuming linux", r.OS)
+		}
+		p = &linuxDockerProfile
+	}
+	r.profile = p
+	return p
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// PathConfig holds where the docker runtime writes in the node, which differs from DefaultPathConfig when parts of the root filesystem are read-only
+type PathConfig struct {
+	// ConfigDir is the directory of daemon.json
+	ConfigDir string
+	// BuilderConfigDir is the directory of the daemon.json of the isolated builder
+	BuilderConfigDir string
+	// SystemdDir is the directory of the unit files and drop-ins written for systemd
+	SystemdDir string
+	// CRIConfigFile is the configuration of crictl, empty if it can not be written
+	CRIConfigFile string
+	// PreloadDir is the directory the preload tarball is copied to
+	PreloadDir string
+	// PreloadRoot is the directory the preload tarball is extracted in
+	PreloadRoot string
+	// Binds are the writable directories mounted over the read-only directories which docker reads its settings from
+	Binds []BindMount
+}
+
+// BindMount is a writable directory mounted over a read-only one
+type BindMount struct {
+	Source string
+	Target string
+}
+
+// DefaultPathConfig is where the docker runtime writes in nodes whose root filesystem is writable
+var DefaultPathConfig = PathConfig{
+	ConfigDir:        "/etc/docker",
+	BuilderConfigDir: path.Dir(builderConfigFile),
+	SystemdDir:       "/etc/systemd/system",
+	CRIConfigFile:    crictlConfigFile,
+	PreloadDir:       "/",
+	PreloadRoot:      "/var",
+}
+
+const (
+	// persistentSettingsDir holds the settings moved off read-only directories, when /var is writable
+	persistentSettingsDir = "/var/lib/minikube/etc"
+	// runtimeSettingsDir holds the settings moved off read-only directories, when /var is read-only too, until the node reboots
+	runtimeSettingsDir = "/run/minikube/etc"
+	// runtimeSystemdDir is the directory of the unit files which systemd reads until the node reboots
+	runtimeSystemdDir = "/run/systemd/system"
+)
+
+// readOnlyMount returns whether dir is on a read-only mount of the mounts of /proc/mounts
+func readOnlyMount(mounts string, dir string) bool {
+	for _, o := range parseMountOptions(mounts, path.Clean(dir)) {
+		if o == "ro" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePathConfig returns where the docker runtime writes in a node with the mounts of /proc/mounts.
+// The settings in read-only directories move to writable ones: systemd reads the units of /run, daemon.json
+// is mounted over /etc/docker, and crictl, which only reads /etc/crictl.yaml, finds the socket itself.
+func resolvePathConfig(mounts string) PathConfig {
+	p := DefaultPathConfig
+	p.Binds = nil
+	settings := persistentSettingsDir
+	if readOnlyMount(mounts, settings) {
+		settings = runtimeSettingsDir
+	}
+	if readOnlyMount(mounts, p.ConfigDir) {
+		p.ConfigDir = path.Join(settings, "docker")
+		p.Binds = append(p.Binds, BindMount{Source: p.ConfigDir, Target: DefaultPathConfig.ConfigDir})
+	}
+	if readOnlyMount(mounts, p.BuilderConfigDir) {
+		// the builder is given the path to its daemon.json
+		p.BuilderConfigDir = path.Join(settings, path.Base(DefaultPathConfig.BuilderConfigDir))
+	}
+	if readOnlyMount(mounts, p.SystemdDir) {
+		p.SystemdDir = runtimeSystemdDir
+	}
+	if readOnlyMount(mounts, p.CRIConfigFile) {
+		p.CRIConfigFile = ""
+	}
+	if readOnlyMount(mounts, p.PreloadDir) {
+		p.PreloadDir = p.PreloadRoot
+	}
+	return p
+}
+
+// pathConfig returns where docker writes in the node, resolving it from the mounts of the node on first use
+func (r *Docker) pathConfig() PathConfig {
+	if r.Paths != nil {
+		return *r.Paths
+	}
+	p := DefaultPathConfig
+	if prof := r.osProfile(); prof.OS != linuxDockerProfile.OS {
+		p.ConfigDir = prof.ConfigDir
+	} else if rr, err := r.Runner.RunCmd(exec.Command("cat", "/proc/mounts")); err == nil {
+		p = resolvePathConfig(rr.Stdout.String())
+	} else {
+		klog.Warningf("unable to read the mounts of the node, assuming its root filesystem is writable: %v", err)
+	}
+	if p.ConfigDir != DefaultPathConfig.ConfigDir || p.SystemdDir != DefaultPathConfig.SystemdDir || p.PreloadDir != DefaultPathConfig.PreloadDir {
+		klog.Infof("docker writes its settings to %+v", p)
+	}
+	r.Paths = &p
+	return p
+}
+
+// systemdPath returns where the unit file or drop-in at file, below the SystemdDir of DefaultPathConfig, is written
+func (p PathConfig) systemdPath(file string) string {
+	return path.Join(p.SystemdDir, strings.TrimPrefix(file, DefaultPathConfig.SystemdDir))
+}
+
+// mountPaths mounts the writable directories of the Binds over the read-only ones, seeding them with the files of the read-only ones.
+// Targets already writable, such as those mounted over by an earlier start, are left as they are.
+func (r *Docker) mountPaths() error {
+	binds := r.pathConfig().Binds
+	if len(binds) == 0 {
+		return nil
+	}
+	rr, err := r.Runner.RunCmd(exec.Command("cat", "/proc/mounts"))
+	if err != nil {
+		return errors.Wrap(err, "reading the mounts of the node")
+	}
+	mounts := rr.Stdout.String()
+	for _, b := range binds {
+		if !readOnlyMount(mounts, b.Target) {
+			klog.Infof("%s is writable, %s is not mounted over it", b.Target, b.Source)
+			continue
+		}
+		klog.Infof("%s is read-only, mounting %s over it", b.Target, b.Source)
+		if _, err := r.Runner.RunCmd(command.Sudo("test", "-d", b.Target)); err != nil {
+			return errors.Errorf("%s is read-only and does not exist, so that %s can not be mounted over it", b.Target, b.Source)
+		}
+		for _, args := range [][]string{{"mkdir", "-p", b.Source}, {"cp", "-an", b.Target + "/.", b.Source}, {"mount", "--bind", b.Source, b.Target}} {
+			if rr, err := r.Runner.RunCmd(command.Sudo(args...)); err != nil {
+				return errors.Wrapf(err, "%s: %s", rr.Command(), rr.Output())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/download"
+)
+
+const (
+	// preloadDataRoot is where the preload is extracted when docker stores its images in containerd, which ignores /var/lib/docker
+	preloadDataRoot = "/var/lib/minikube/preload-docker"
+	// preloadDaemonTimeout is how long the transient daemon reading the preload has to answer
+	preloadDaemonTimeout = 30 * time.Second
+)
+
+// preloadDaemon is the transient daemon serving the images of the preload, which holds the overlay2 store of docker,
+// for them to be loaded into the containerd image store
+var preloadDaemon = DockerDaemon{Service: "docker-preload", Socket: "/var/run/docker-preload.sock"}
+
+// containerdImageStore returns whether the daemon stores its images in containerd, with the containerd-snapshotter feature of docker 24+
+func containerdImageStore(runner CommandRunner, d DockerDaemon) bool {
+	rr, err := runner.RunCmd(d.command("info", "--format", "{{json .DriverStatus}}"))
+	if err != nil {
+		klog.Warningf("unable to tell the image store of %s: %v", d.Service, err)
+		return false
+	}
+	return strings.Contains(rr.Stdout.String(), containerdSnapshotterDriver)
+}
+
+// ContainerdImageStore returns whether docker stores its images in containerd, where the preload can not be extracted as is
+func (r *Docker) ContainerdImageStore() bool {
+	return containerdImageStore(r.Runner, kubernetesDaemon)
+}
+
+// snapshotterFeature returns whether the containerd-snapshotter feature is enabled in the features of r
+func (r *Docker) snapshotterFeature() bool {
+	for _, f := range r.Features {
+		if k, v, _ := strings.Cut(f, "="); k == "containerd-snapshotter" {
+			enabled, err := strconv.ParseBool(v)
+			return err == nil && enabled
+		}
+	}
+	return false
+}
+
+// preloadStore returns whether the preload goes into the containerd image store. As docker only runs with its features
+// once enabled, which comes after the preload, they are applied first if they switch docker to the containerd image store.
+func (r *Docker) preloadStore(ctx context.Context) (bool, error) {
+	if r.ContainerdImageStore() {
+		return true, nil
+	}
+	if !r.snapshotterFeature() {
+		return false, nil
+	}
+	klog.Infof("restarting docker with the containerd image store before preloading")
+	if _, err := r.writeDaemonConfig(r.ForceSystemd); err != nil {
+		return false, err
+	}
+	if err := r.RestartContext(ctx); err != nil {
+		return false, errors.Wrap(err, "restarting docker with the containerd image store")
+	}
+	if !r.ContainerdImageStore() {
+		klog.Warningf("docker does not store its images in containerd despite the containerd-snapshotter feature, which needs docker 24+")
+		return false, nil
+	}
+	return true, nil
+}
+
+// importPreload loads the images of the preload at src, compressed with c, into the containerd image store of docker.
+// The preload is extracted apart, for a transient daemon to serve its overlay2 store to 'docker save', streamed into 'docker load'.
+func (r *Docker) importPreload(ctx context.Context, c download.Compression, src string) error {
+	defer func() {
+		if _, err := r.Runner.RunCmd(command.Sudo("rm", "-rf", preloadDataRoot)); err != nil {
+			klog.Infof("error removing %s: %v", preloadDataRoot, err)
+		}
+	}()
+	if rr, err := r.Runner.RunCmd(command.Sudo("mkdir", "-p", preloadDataRoot)); err != nil {
+		return errors.Wrapf(err, "making %s: %s", preloadDataRoot, rr.Output())
+	}
+	// the preload holds lib/docker, as it is extracted into /var otherwise
+	if rr, err := r.Runner.RunCmdContext(ctx, tarExtractCmd(c, preloadDataRoot, src)); err != nil {
+		return errors.Wrapf(err, "extracting tarball: %s", rr.Output())
+	}
+
+	dataRoot := path.Join(preloadDataRoot, "lib/docker")
+	execRoot := path.Join("/var/run", preloadDaemon.Service)
+	start := command.Sudo("systemd-run", "--unit="+preloadDaemon.Service, "--collect",
+		"/usr/bin/dockerd", "--host", SocketURL(preloadDaemon.Socket), "--data-root", dataRoot, "--exec-root", execRoot,
+		"--pidfile", execRoot+".pid", "--storage-driver", "overlay2", "--bridge", "none", "--iptables=false", "--ip-masq=false")
+	if rr, err := r.Runner.RunCmd(start); err != nil {
+		return errors.Wrapf(err, "starting %s: %s", preloadDaemon.Service, rr.Output())
+	}
+	defer func() {
+		if err := r.Init.Stop(preloadDaemon.Service); err != nil {
+			klog.Warningf("error stopping %s: %v", preloadDaemon.Service, err)
+		}
+	}()
+	if err := waitReady(ctx, r.Runner, preloadDaemon.Service, preloadDaemonTimeout, func() error {
+		_, err := r.Runner.RunCmdContext(ctx, preloadDaemon.command("version", "--format", "{{.Server.Version}}"))
+		return err
+	}); err != nil {
+		return err
+	}
+
+	rr, err := r.Runner.RunCmd(preloadDaemon.command("images", "--format", "{{.Repository}}:{{.Tag}}"))
+	if err != nil {
+		return errors.Wrap(err, "listing the preloaded images")
+	}
+	tags := []string{}
+	for _, t := range strings.Fields(rr.Stdout.String()) {
+		if !strings.Contains(t, "<none>") {
+			tags = append(tags, t)
+		}
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("the preload %s holds no images", src)
+	}
+	klog.Infof("Loading %d preloaded images into the containerd image store", len(tags))
+	save := shellquote.Join(preloadDaemon.command(append([]string{"save"}, tags...)...).Args...)
+	if rr, err := r.Runner.RunCmdContext(ctx, exec.Command("/bin/bash", "-c", fmt.Sprintf("set -o pipefail; %s | docker load", save))); err != nil {
+		return errors.Wrapf(err, "loading the preloaded images: %s", rr.Output())
+	}
+	return nil
+}
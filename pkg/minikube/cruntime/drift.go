@@ -0,0 +1,191 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// unitUnmasked is the expected state of the units which minikube only unmasks, leaving them to be started on demand
+const unitUnmasked = "unmasked"
+
+// RuntimeState is the configuration of a runtime which minikube owns, as it renders it from the profile or as it reads it from a node
+type RuntimeState struct {
+	// DaemonConfig are the JSON values of the keys of daemon.json minikube sets, the keys of its objects prefixed as "features.buildkit"
+	DaemonConfig map[string]string
+	// CRIDockerDropIn is the drop-in of cri-docker setting its ExecStart, or empty if minikube writes none
+	CRIDockerDropIn string
+	// Units are the states of the units as 'systemctl is-enabled' prints them, those only unmasked being expected as "unmasked"
+	Units map[string]string
+	// CgroupDriver is the cgroup driver of the runtime, or empty if minikube leaves it to the runtime
+	CgroupDriver string
+}
+
+// StateOptions are the settings of the profile the configuration of a runtime is rendered from, which start passes to Enable
+type StateOptions struct {
+	ForceSystemd  bool
+	NetworkPlugin string
+}
+
+// Drift is a setting of a runtime whose state in the node differs from the one minikube sets, an empty state being unset
+type Drift struct {
+	Setting  string
+	Expected string
+	Actual   string
+}
+
+// DriftVerifier is implemented by the runtimes whose configuration in a node can be compared with the one minikube sets
+type DriftVerifier interface {
+	// ExpectedState renders the configuration Enable sets, without running any command
+	ExpectedState(o StateOptions) (RuntimeState, error)
+	// LiveState reads the settings of expected from the node
+	LiveState(expected RuntimeState) (RuntimeState, error)
+}
+
+// CompareRuntimeState returns the settings of expected whose state differs in live, sorted by setting.
+// The drop-in of cri-docker may have the flags of the drop-ins of users appended.
+func CompareRuntimeState(expected, live RuntimeState) []Drift {
+	drifts := []Drift{}
+	for k, v := range expected.DaemonConfig {
+		if live.DaemonConfig[k] != v {
+			drifts = append(drifts, Drift{Setting: "daemon.json " + k, Expected: v, Actual: live.DaemonConfig[k]})
+		}
+	}
+	if want := expected.CRIDockerDropIn; want != "" && live.CRIDockerDropIn != want && !strings.HasPrefix(live.CRIDockerDropIn, want+" ") {
+		drifts = append(drifts, Drift{Setting: criDockerServiceConfFile, Expected: execStartLine(want), Actual: execStartLine(live.CRIDockerDropIn)})
+	}
+	for unit, want := range expected.Units {
+		got := live.Units[unit]
+		if got == want || (want == unitUnmasked && got != "" && got != "masked") {
+			continue
+		}
+		drifts = append(drifts, Drift{Setting: "unit " + unit, Expected: want, Actual: got})
+	}
+	if expected.CgroupDriver != "" && live.CgroupDriver != expected.CgroupDriver {
+		drifts = append(drifts, Drift{Setting: "cgroup driver", Expected: expected.CgroupDriver, Actual: live.CgroupDriver})
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].Setting < drifts[j].Setting })
+	return drifts
+}
+
+// execStartLine returns the last ExecStart setting of a drop-in, which is the one systemd runs
+func execStartLine(dropIn string) string {
+	s := execStarts(dropIn)
+	if len(s) == 0 {
+		return ""
+	}
+	return s[len(s)-1]
+}
+
+// expectedDockerUnits returns the states Enable leaves the units of docker in: docker unmasked, and its socket and that of cri-dockerd, if used, enabled
+func expectedDockerUnits(criService string) map[string]string {
+	units := map[string]string{"docker.service": unitUnmasked, "docker.socket": "enabled"}
+	if criService != "" {
+		units[criService] = "enabled"
+	}
+	return units
+}
+
+// ExpectedState renders the keys of daemon.json, the drop-in of cri-docker, the states of the units and the cgroup driver Enable sets
+func (r *Docker) ExpectedState(o StateOptions) (RuntimeState, error) {
+	s := RuntimeState{DaemonConfig: map[string]string{}, Units: expectedDockerUnits(r.CRIService)}
+	settings, err := r.daemonSettings(o.ForceSystemd)
+	if err != nil {
+		return s, err
+	}
+	owned, err := ownedDaemonConfig(settings)
+	if err != nil {
+		return s, err
+	}
+	for k, v := range owned {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return s, errors.Wrapf(err, "daemon.json %s", k)
+		}
+		s.DaemonConfig[k] = string(b)
+	}
+	// start configures the network plugin of cri-dockerd for Kubernetes 1.24 and later only
+	if r.KubernetesVersion.GTE(semver.MustParse("1.24.0-alpha.2")) {
+		conf, err := r.expectedCRIDockerServiceConf(o.NetworkPlugin)
+		if err != nil {
+			return s, err
+		}
+		s.CRIDockerDropIn = string(conf)
+	}
+	if o.ForceSystemd {
+		s.CgroupDriver = "systemd"
+	}
+	return s, nil
+}
+
+// LiveState reads the keys of daemon.json, the drop-in of cri-docker, the states of the units and the cgroup driver of expected from the node
+func (r *Docker) LiveState(expected RuntimeState) (RuntimeState, error) {
+	s := RuntimeState{DaemonConfig: map[string]string{}, Units: map[string]string{}}
+	if len(expected.DaemonConfig) > 0 {
+		daemonConfig := map[string]interface{}{}
+		if rr, err := r.Runner.RunCmd(command.Sudo("cat", r.daemonConfigFile())); err == nil && len(strings.TrimSpace(rr.Stdout.String())) > 0 {
+			if err := json.Unmarshal(rr.Stdout.Bytes(), &daemonConfig); err != nil {
+				return s, errors.Wrapf(err, "parsing %s", r.daemonConfigFile())
+			}
+		}
+		for k := range expected.DaemonConfig {
+			v, ok := daemonConfig[k]
+			if object, key := splitOwnedDaemonKey(k); object != "" {
+				o, _ := daemonConfig[object].(map[string]interface{})
+				v, ok = o[key]
+			}
+			if !ok {
+				continue
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				return s, errors.Wrapf(err, "daemon.json %s", k)
+			}
+			s.DaemonConfig[k] = string(b)
+		}
+	}
+	if expected.CRIDockerDropIn != "" {
+		if rr, err := r.Runner.RunCmd(command.Sudo("cat", r.pathConfig().systemdPath(criDockerServiceConfFile))); err == nil {
+			s.CRIDockerDropIn = rr.Stdout.String()
+		}
+	}
+	for unit := range expected.Units {
+		// is-enabled fails for the units which are not enabled, but still prints their state
+		rr, err := r.Runner.RunCmd(command.Sudo("systemctl", "is-enabled", unit))
+		if rr != nil {
+			s.Units[unit] = strings.TrimSpace(rr.Stdout.String())
+		}
+		if err != nil {
+			klog.Infof("%s is not enabled: %v", unit, err)
+		}
+	}
+	if expected.CgroupDriver != "" {
+		driver, err := r.CGroupDriver()
+		if err != nil {
+			return s, errors.Wrap(err, "cgroup driver")
+		}
+		s.CgroupDriver = driver
+	}
+	return s, nil
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+const (
+	// EventOOM is a process or container killed for lack of memory
+	EventOOM = "oom"
+	// EventUnit is a state change of a systemd unit, such as a restart of the runtime
+	EventUnit = "unit"
+	// EventContainerDie is a container exiting with an error
+	EventContainerDie = "container-die"
+	// EventImageDelete is an image deleted or untagged, as by the image garbage collection of kubelet
+	EventImageDelete = "image-delete"
+	// EventImagePull is an image pulled, as after it was deleted
+	EventImagePull = "image-pull"
+	// EventDaemonReload is the runtime reloading its configuration
+	EventDaemonReload = "daemon-reload"
+)
+
+// RuntimeEvent is an incident of a container runtime or its host, which the events of Kubernetes do not capture
+type RuntimeEvent struct {
+	Time time.Time `json:"time"`
+	// Source is what reported the event, such as docker, containerd, kubelet, systemd or the kernel
+	Source string `json:"source"`
+	// Type is one of the Event constants
+	Type string `json:"type"`
+	// Subject is what the event is about, such as an image, a container, a process or a systemd unit
+	Subject string `json:"subject"`
+	// Message describes the event
+	Message string `json:"message"`
+}
+
+// journalPattern matches the journal lines of a type of event, whose first group, if any, is its subject
+type journalPattern struct {
+	re  *regexp.Regexp
+	typ string
+}
+
+// kernelOOMPatterns match the OOM kills of the kernel log
+var kernelOOMPatterns = []journalPattern{
+	{regexp.MustCompile(`(?:Out of memory|Memory cgroup out of memory): Kill(?:ed)? process \d+ \(([^)]+)\)`), EventOOM},
+}
+
+// kubeletGCPatterns match the images removed by the image garbage collection of kubelet
+var kubeletGCPatterns = []journalPattern{
+	{regexp.MustCompile(`"Removing image to free bytes" imageID="([^"]+)"`), EventImageDelete},
+}
+
+// sinceArg returns a time relative to now as understood by journalctl, such as -1800s
+func sinceArg(since time.Duration) string {
+	s := int(since.Seconds())
+	if s < 1 {
+		s = 1
+	}
+	return fmt.Sprintf("-%ds", s)
+}
+
+// parseShortUnix splits a line of 'journalctl -o short-unix' into its time and message
+func parseShortUnix(line string) (time.Time, string, bool) {
+	// 1666030000.123456 minikube kernel: message
+	fields := strings.SplitN(strings.TrimSpace(line), " ", 3)
+	if len(fields) != 3 {
+		return time.Time{}, "", false
+	}
+	secs, frac, _ := strings.Cut(fields[0], ".")
+	s, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	us, _ := strconv.ParseInt((frac + "000000")[:6], 10, 64)
+	_, msg, ok := strings.Cut(fields[2], ": ")
+	if !ok {
+		return time.Time{}, "", false
+	}
+	return time.Unix(s, us*1000), msg, true
+}
+
+// parseJournalEvents returns the events of the lines of 'journalctl -o short-unix' matching the patterns
+func parseJournalEvents(out string, source string, patterns []journalPattern) []RuntimeEvent {
+	events := []RuntimeEvent{}
+	for _, line := range strings.Split(out, "\n") {
+		t, msg, ok := parseShortUnix(line)
+		if !ok {
+			continue
+		}
+		for _, p := range patterns {
+			m := p.re.FindStringSubmatch(msg)
+			if m == nil {
+				continue
+			}
+			subject := ""
+			if len(m) > 1 {
+				subject = m[1]
+			}
+			events = append(events, RuntimeEvent{Time: t, Source: source, Type: p.typ, Subject: subject, Message: strings.TrimSpace(msg)})
+			break
+		}
+	}
+	return events
+}
+
+// journalEvents reads the events matching the patterns from the journal, selected by args such as -k or -u unit
+func journalEvents(cr CommandRunner, source string, args []string, since time.Duration, patterns []journalPattern) ([]RuntimeEvent, error) {
+	c := command.Sudo(append([]string{"journalctl", "--no-pager", "-o", "short-unix", "--since", sinceArg(since)}, args...)...)
+	rr, err := cr.RunCmd(c)
+	if err != nil {
+		return nil, errors.Wrapf(err, "journalctl %s", strings.Join(args, " "))
+	}
+	return parseJournalEvents(rr.Stdout.String(), source, patterns), nil
+}
+
+// parseUnitEvents returns the state changes of systemd units from the output of 'journalctl -o json' for the messages of systemd
+func parseUnitEvents(out string) []RuntimeEvent {
+	type journalEntry struct {
+		Timestamp string `json:"__REALTIME_TIMESTAMP"`
+		Unit      string `json:"UNIT"`
+		Message   string `json:"MESSAGE"`
+	}
+	events := []RuntimeEvent{}
+	for _, line := range strings.Split(out, "\n") {
+		var e journalEntry
+		if strings.TrimSpace(line) == "" || json.Unmarshal([]byte(line), &e) != nil || e.Unit == "" {
+			continue
+		}
+		us, err := strconv.ParseInt(e.Timestamp, 10, 64)
+		if err != nil {
+			continue
+		}
+		typ := EventUnit
+		if oomRe.MatchString(e.Message) {
+			typ = EventOOM
+		}
+		events = append(events, RuntimeEvent{Time: time.UnixMicro(us), Source: "systemd", Type: typ, Subject: e.Unit, Message: strings.TrimSpace(e.Message)})
+	}
+	return events
+}
+
+// unitEvents reads the state changes of the systemd units from the messages of systemd in the journal
+func unitEvents(cr CommandRunner, units []string, since time.Duration) ([]RuntimeEvent, error) {
+	args := []string{"journalctl", "--no-pager", "-o", "json", "--since", sinceArg(since), "_PID=1"}
+	for _, u := range units {
+		args = append(args, "UNIT="+u)
+	}
+	rr, err := cr.RunCmd(command.Sudo(args...))
+	if err != nil {
+		return nil, errors.Wrap(err, "journalctl")
+	}
+	return parseUnitEvents(rr.Stdout.String()), nil
+}
+
+// runtimeEvents merges the events of a runtime with the OOM kills of the kernel, the state changes of its units and kubelet,
+// and the image garbage collection of kubelet, oldest first. Only the events of the runtime itself are required.
+func runtimeEvents(cr CommandRunner, units []string, since time.Duration, own func() ([]RuntimeEvent, error)) ([]RuntimeEvent, error) {
+	events, err := own()
+	if err != nil {
+		return nil, err
+	}
+	if ev, err := journalEvents(cr, "kernel", []string{"-k"}, since, kernelOOMPatterns); err != nil {
+		klog.Infof("unable to read the OOM kills: %v", err)
+	} else {
+		events = append(events, ev...)
+	}
+	if ev, err := unitEvents(cr, append(units, "kubelet.service"), since); err != nil {
+		klog.Infof("unable to read the state changes of %v: %v", units, err)
+	} else {
+		events = append(events, ev...)
+	}
+	if ev, err := journalEvents(cr, "kubelet", []string{"-u", "kubelet"}, since, kubeletGCPatterns); err != nil {
+		klog.Infof("unable to read the image garbage collection of kubelet: %v", err)
+	} else {
+		events = append(events, ev...)
+	}
+	SortEvents(events)
+	return events, nil
+}
+
+// SortEvents sorts events oldest first, keeping the order of those at the same time
+func SortEvents(events []RuntimeEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+}
+
+// dockerEventActions are the actions of docker events which are incidents, by type of object
+var dockerEventActions = map[string]map[string]string{
+	"container": {"oom": EventOOM, "die": EventContainerDie},
+	"image":     {"delete": EventImageDelete, "untag": EventImageDelete, "pull": EventImagePull},
+	"daemon":    {"reload": EventDaemonReload},
+}
+
+// parseDockerEvents returns the incidents among the output of 'docker events --format "{{json .}}"'
+func parseDockerEvents(out string) ([]RuntimeEvent, error) {
+	type dockerEvent struct {
+		Type   string `json:"Type"`
+		Action string `json:"Action"`
+		Actor  struct {
+			ID         string            `json:"ID"`
+			Attributes map[string]string `json:"Attributes"`
+		} `json:"Actor"`
+		TimeNano int64 `json:"timeNano"`
+	}
+	events := []RuntimeEvent{}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var e dockerEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, errors.Wrap(err, "parsing docker event")
+		}
+		typ, ok := dockerEventActions[e.Type][e.Action]
+		if !ok {
+			continue
+		}
+		attrs := e.Actor.Attributes
+		// containers exiting successfully, as jobs do, are not incidents
+		if typ == EventContainerDie && attrs["exitCode"] == "0" {
+			continue
+		}
+		subject := e.Actor.ID
+		if name := attrs["name"]; name != "" {
+			subject = name
+		}
+		msg := fmt.Sprintf("%s %s", e.Type, e.Action)
+		if code := attrs["exitCode"]; code != "" {
+			msg += fmt.Sprintf(" (exit code %s)", code)
+		}
+		events = append(events, RuntimeEvent{Time: time.Unix(0, e.TimeNano), Source: "docker", Type: typ, Subject: subject, Message: msg})
+	}
+	return events, nil
+}
+
+// dockerEvents returns the incidents reported by docker since then, in a window ending now so that the command does not follow them
+func dockerEvents(cr CommandRunner, since time.Duration) ([]RuntimeEvent, error) {
+	c := exec.Command("docker", "events", "--since", fmt.Sprintf("%ds", int(since.Seconds())), "--until", "0s", "--format", "{{json .}}")
+	rr, err := cr.RunCmd(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "docker events")
+	}
+	return parseDockerEvents(rr.Stdout.String())
+}
+
+// containerdPatterns match the incidents among the log lines of containerd
+var containerdPatterns = []journalPattern{
+	{regexp.MustCompile(`msg="TaskOOM event &TaskOOM\{ContainerID:(\w+)`), EventOOM},
+	{regexp.MustCompile(`msg="ImageDelete event &ImageDelete\{Name:([^,]+),`), EventImageDelete},
+	{regexp.MustCompile(`msg="PullImage \\"([^\\]+)\\" returns image reference`), EventImagePull},
+}
+
+// crioPatterns match the incidents among the log lines of CRI-O, whose OOM kills are found in the kernel log
+var crioPatterns = []journalPattern{
+	{regexp.MustCompile(`msg="Remov(?:ed|ing) image:? ([^"]+)"`), EventImageDelete},
+	{regexp.MustCompile(`msg="Pulled image: ([^"]+)"`), EventImagePull},
+}
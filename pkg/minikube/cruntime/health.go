@@ -0,0 +1,235 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+)
+
+const (
+	// HealthRunning means the runtime is serving requests without recent trouble
+	HealthRunning = "Running"
+	// HealthDegraded means the runtime is up, but was restarted or OOM-killed recently, or does not answer
+	HealthDegraded = "Degraded"
+	// HealthCrashloop means systemd keeps restarting the runtime
+	HealthCrashloop = "Crashloop"
+	// HealthStopped means the runtime is not running
+	HealthStopped = "Stopped"
+)
+
+// crashloopRestarts is the number of restarts of a unit which is not active, from which it is considered crash looping
+const crashloopRestarts = 3
+
+// OOMPatterns match the log lines of the kernel and systemd reporting a process killed for lack of memory
+var OOMPatterns = []string{
+	`Out of memory: Kill(ed)? process`,
+	`Memory cgroup out of memory`,
+	`killed by the OOM killer`,
+	`Failed with result 'oom-kill'`,
+}
+
+var oomRe = regexp.MustCompile(strings.Join(OOMPatterns, "|"))
+
+// UnitHealth is the state of a systemd unit of a runtime
+type UnitHealth struct {
+	// Unit is the name of the systemd unit
+	Unit string
+	// ActiveState and SubState are the states reported by systemd, such as "active" and "running"
+	ActiveState string
+	SubState    string
+	// Result is how the unit last stopped, such as "success" or "oom-kill"
+	Result string
+	// Restarts is the number of automatic restarts of the unit
+	Restarts int
+}
+
+// CheckElevation returns an error if the runner can not run commands as root, which all runtimes need
+func CheckElevation(cr CommandRunner) error {
+	rr, err := cr.RunCmd(command.Sudo("true"))
+	if err != nil {
+		stderr := ""
+		if rr != nil {
+			stderr = strings.TrimSpace(rr.Stderr.String())
+		}
+		return fmt.Errorf("unable to run commands as root, which needs sudo without a password, or another --ssh-sudo mode with the ssh driver: %s", stderr)
+	}
+	return nil
+}
+
+// Health is a report of the health of a runtime
+type Health struct {
+	// State is one of HealthRunning, HealthDegraded, HealthCrashloop or HealthStopped
+	State string
+	// Reason explains why the runtime is not running
+	Reason string
+	// Units are the states of the systemd units of the runtime
+	Units []UnitHealth
+	// OOMEvents are the recent kernel log lines reporting the runtime processes as OOM-killed
+	OOMEvents []string
+	// Responsive is whether the runtime answered a version request
+	Responsive bool
+	// ClockSkew is how far the clock of the node is ahead of the one of the host, which breaks the pulls beyond a threshold
+	ClockSkew time.Duration
+	// PauseImage is the pause image of Kubernetes, which no pod starts without
+	PauseImage string
+	// PauseImageMissing is whether the pause image is missing, as after the image GC of the kubelet removed it
+	PauseImageMissing bool
+	// PauseImageRepaired is whether the pause image was missing, and restored while checking the health
+	PauseImageRepaired bool
+}
+
+// healthUnit is a systemd unit to check, along with the name of its main process
+type healthUnit struct {
+	unit    string
+	process string
+}
+
+// unitHealth returns the state of a systemd unit
+func unitHealth(cr CommandRunner, unit string) (UnitHealth, error) {
+	uh := UnitHealth{Unit: unit}
+	rr, err := cr.RunCmd(command.Sudo("systemctl", "show", "-p", "ActiveState", "-p", "SubState", "-p", "Result", "-p", "NRestarts", unit))
+	if err != nil {
+		return uh, errors.Wrapf(err, "systemctl show %s", unit)
+	}
+	for _, line := range strings.Split(rr.Stdout.String(), "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "ActiveState":
+			uh.ActiveState = kv[1]
+		case "SubState":
+			uh.SubState = kv[1]
+		case "Result":
+			uh.Result = kv[1]
+		case "NRestarts":
+			// NRestarts is missing before systemd 235, which is fine
+			uh.Restarts, _ = strconv.Atoi(kv[1])
+		}
+	}
+	return uh, nil
+}
+
+// oomEvents returns the recent kernel log lines reporting one of the processes as OOM-killed
+func oomEvents(cr CommandRunner, processes []string) []string {
+	rr, err := cr.RunCmd(command.Sudo("journalctl", "--no-pager", "-k", "-o", "cat", "-n", "500"))
+	if err != nil {
+		klog.Infof("unable to read the kernel log: %v", err)
+		return nil
+	}
+	events := []string{}
+	for _, line := range strings.Split(rr.Stdout.String(), "\n") {
+		if !oomRe.MatchString(line) {
+			continue
+		}
+		for _, p := range processes {
+			if strings.Contains(line, "("+p+")") {
+				events = append(events, strings.TrimSpace(line))
+				break
+			}
+		}
+	}
+	return events
+}
+
+// runtimeHealth checks the units of a runtime, the kernel log for OOM kills of their processes, and whether ready succeeds
+func runtimeHealth(cr CommandRunner, units []healthUnit, ready func() error) (*Health, error) {
+	h := &Health{}
+	processes := []string{}
+	for _, u := range units {
+		uh, err := unitHealth(cr, u.unit)
+		if err != nil {
+			return nil, err
+		}
+		h.Units = append(h.Units, uh)
+		processes = append(processes, u.process)
+	}
+	h.OOMEvents = oomEvents(cr, processes)
+	if err := ready(); err != nil {
+		klog.Infof("runtime is not responsive: %v", err)
+	} else {
+		h.Responsive = true
+	}
+	h.State, h.Reason = classifyHealth(h.Units, len(h.OOMEvents), h.Responsive)
+	skew, err := ClockSkew(cr)
+	if err != nil {
+		klog.Infof("unable to check the clock of the node: %v", err)
+	}
+	h.ClockSkew = skew
+	if h.State == HealthRunning && clockSkewed(skew) {
+		h.State, h.Reason = HealthDegraded, fmt.Sprintf("clock skewed by %s, which fails the validation of registry certificates", skew)
+	}
+	return h, nil
+}
+
+// classifyHealth derives the state of a runtime, and the reason for it, from the state of its units
+func classifyHealth(units []UnitHealth, ooms int, responsive bool) (string, string) {
+	oomReason := ""
+	if ooms > 0 {
+		oomReason = fmt.Sprintf(", OOM-killed %d times recently", ooms)
+	}
+	for _, u := range units {
+		if u.ActiveState != "active" && u.Restarts >= crashloopRestarts {
+			return HealthCrashloop, fmt.Sprintf("%s restarted %d times, last result %s%s", u.Unit, u.Restarts, u.Result, oomReason)
+		}
+	}
+	for _, u := range units {
+		if u.ActiveState != "active" {
+			return HealthStopped, fmt.Sprintf("%s is %s (%s)%s", u.Unit, u.ActiveState, u.SubState, oomReason)
+		}
+	}
+	if !responsive {
+		return HealthDegraded, "not answering requests" + oomReason
+	}
+	for _, u := range units {
+		if u.Restarts > 0 {
+			return HealthDegraded, fmt.Sprintf("%s restarted %d times%s", u.Unit, u.Restarts, oomReason)
+		}
+	}
+	if ooms > 0 {
+		return HealthDegraded, strings.TrimPrefix(oomReason, ", ")
+	}
+	return HealthRunning, ""
+}
+
+// servicesHealthy returns an error if one of the services is not active, or if ready fails
+func servicesHealthy(init sysinit.Manager, services []string, ready func() error) error {
+	for _, svc := range services {
+		if !init.Active(svc) {
+			return fmt.Errorf("%s is not active", svc)
+		}
+	}
+	return ready()
+}
+
+// criResponsive returns an error if the runtime does not answer a version request on its CRI socket
+func criResponsive(cr CommandRunner, socket string) error {
+	if _, err := cr.RunCmd(command.Sudo(getCrictlPath(cr), "--runtime-endpoint", SocketURL(socket), "version")); err != nil {
+		return errors.Wrapf(err, "%s is not answering", socket)
+	}
+	return nil
+}
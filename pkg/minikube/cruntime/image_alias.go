@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+)
+
+// registryAliases maps each registry of the Kubernetes images to the other, as both serve the same images
+var registryAliases = map[string]string{
+	images.OldDefaultKubernetesRepo: images.NewDefaultKubernetesRepo,
+	images.NewDefaultKubernetesRepo: images.OldDefaultKubernetesRepo,
+}
+
+// registryAlias returns the name of an image in the alias of its registry, or "" if its registry has none
+func registryAlias(img string) string {
+	parts := strings.SplitN(img, "/", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	alias, ok := registryAliases[parts[0]]
+	if !ok {
+		return ""
+	}
+	return alias + "/" + parts[1]
+}
+
+// retag is a tag to add to an image
+type retag struct {
+	source string
+	target string
+}
+
+// aliasRetags returns the tags to add so that the wanted images which are missing resolve to the images present under their alias
+func aliasRetags(want []string, present []string) []retag {
+	have := map[string]bool{}
+	for _, p := range present {
+		have[p] = true
+	}
+	retags := []retag{}
+	for _, w := range want {
+		if have[w] {
+			continue
+		}
+		if alias := registryAlias(w); alias != "" && have[alias] {
+			retags = append(retags, retag{source: alias, target: w})
+		}
+	}
+	return retags
+}
+
+// retagAliases tags the wanted images which are missing from the runtime from their alias, if present,
+// so that the images of a Kubernetes version resolve locally whichever registry the preload used
+func retagAliases(r Manager, want []string, present []string) error {
+	for _, t := range aliasRetags(want, present) {
+		klog.Infof("preload has %s as %s, tagging it", t.target, t.source)
+		if err := r.TagImage(t.source, t.target); err != nil {
+			return errors.Wrapf(err, "tagging %s as %s", t.source, t.target)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/image"
+)
+
+// digestRef returns an image reference with a digest without its tag, as busybox@sha256:... for busybox:1.36@sha256:..., and whether it has a digest
+func digestRef(ref string) (string, bool) {
+	i := strings.Index(ref, "@")
+	if i == -1 {
+		return ref, false
+	}
+	name, digest := ref[:i], ref[i:]
+	// the tag follows the last colon after the registry, whose port also follows a colon
+	if tag := strings.LastIndex(name, ":"); tag > strings.LastIndex(name, "/") {
+		name = name[:tag]
+	}
+	return name + digest, true
+}
+
+// dockerImageRef returns the ID of the image a reference with a digest names, as docker rmi and docker tag do not treat digests like tags, or else the reference itself
+func dockerImageRef(cr CommandRunner, name string) (string, error) {
+	ref, ok := digestRef(name)
+	if !ok {
+		return name, nil
+	}
+	return dockerImageID(cr, ref)
+}
+
+// dockerImageID returns the ID of an image, with its sha256: prefix
+func dockerImageID(cr CommandRunner, name string) (string, error) {
+	rr, err := cr.RunCmd(exec.Command("docker", "image", "inspect", "--format", "{{.Id}}", name))
+	if err != nil {
+		return "", errors.Wrapf(err, "resolving %s", name)
+	}
+	return strings.TrimSpace(rr.Stdout.String()), nil
+}
+
+// criImageRef returns the ID of the image a reference with a digest names, resolved by crictl like the tags, or else the reference itself
+func criImageRef(cr CommandRunner, name string) (string, error) {
+	ref, ok := digestRef(name)
+	if !ok {
+		return name, nil
+	}
+	img, err := inspectCRIImage(cr, ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "resolving %s", name)
+	}
+	return img.Status.ID, nil
+}
+
+// criImageExists returns whether the image a reference with a digest names exists, and has the sha if not empty
+func criImageExists(cr CommandRunner, name string, sha string) bool {
+	id, err := criImageRef(cr, name)
+	if err != nil {
+		return false
+	}
+	return sha == "" || strings.Contains(id, sha)
+}
+
+// PullVerifiedImage pulls an image whose signature was verified for the digest of pinned, a reference with a digest,
+// and tags it as name, so that the runtime does not resolve the tag of name again after the verification
+func PullVerifiedImage(cr Manager, name string, pinned string) error {
+	if _, ok := digestRef(pinned); !ok {
+		return fmt.Errorf("the verified reference %s has no digest", pinned)
+	}
+	if err := cr.PullImage(pinned); err != nil {
+		return err
+	}
+	if _, ok := digestRef(name); ok {
+		return nil
+	}
+	// the runtimes which take fully qualified names only, like ctr, find the tag under the name the kubelet resolves
+	target, err := image.NormalizeReference(name)
+	if err != nil {
+		return errors.Wrapf(err, "tagging %s", name)
+	}
+	return cr.TagImage(pinned, target)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/image"
+)
+
+// normalizeImageRef returns the fully qualified form of an image reference, or the reference itself if it is not valid
+func normalizeImageRef(ref string) string {
+	n, err := image.NormalizeReference(ref)
+	if err != nil {
+		return ref
+	}
+	return n
+}
+
+// imagesIn returns which of the images named are among the references listed by a runtime
+func imagesIn(listed []string, names []string) map[string]bool {
+	have := map[string]bool{}
+	for _, l := range listed {
+		if l == "" || strings.Contains(l, "<none>") {
+			continue
+		}
+		have[normalizeImageRef(l)] = true
+	}
+	exist := map[string]bool{}
+	for _, n := range names {
+		exist[n] = have[normalizeImageRef(n)]
+	}
+	return exist
+}
+
+// dockerImagesExist returns which of the images exist, listing the tags and digests of all the images at once
+func dockerImagesExist(cr CommandRunner, names []string) (map[string]bool, error) {
+	if len(names) == 0 {
+		return map[string]bool{}, nil
+	}
+	rr, err := cr.RunCmd(exec.Command("docker", "images", "--digests", "--format", "{{.Repository}}:{{.Tag}} {{.Repository}}@{{.Digest}}"))
+	if err != nil {
+		return nil, errors.Wrap(err, "docker images")
+	}
+	return imagesIn(strings.Fields(rr.Stdout.String()), names), nil
+}
+
+// criImagesExist returns which of the images exist, listing all the images at once
+func criImagesExist(cr CommandRunner, names []string) (map[string]bool, error) {
+	if len(names) == 0 {
+		return map[string]bool{}, nil
+	}
+	images, err := crictlImageList(cr, ListImagesOptions{})
+	if err != nil {
+		return nil, err
+	}
+	listed := []string{}
+	for _, img := range images {
+		listed = append(listed, img.RepoTags...)
+		listed = append(listed, img.RepoDigests...)
+	}
+	return imagesIn(listed, names), nil
+}
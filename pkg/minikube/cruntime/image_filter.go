@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// validateReferencePattern returns an error if pattern is not a valid glob
+func validateReferencePattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return errors.Wrapf(err, "invalid reference pattern %q", pattern)
+	}
+	return nil
+}
+
+// referenceForms returns the forms of an image reference a pattern may match, as docker does:
+// the reference as listed and without its tag or digest, in both the fully qualified and the familiar form.
+func referenceForms(ref string) []string {
+	forms := []string{}
+	for _, r := range []string{ref, normalizeImageRef(ref)} {
+		name := r
+		if i := strings.Index(name, "@"); i != -1 {
+			name = name[:i]
+		}
+		// the tag follows the last colon after the registry, whose port also follows a colon
+		if tag := strings.LastIndex(name, ":"); tag > strings.LastIndex(name, "/") {
+			name = name[:tag]
+		}
+		forms = append(forms, r, name)
+		for _, prefix := range []string{"docker.io/library/", "docker.io/"} {
+			if strings.HasPrefix(r, prefix) {
+				forms = append(forms, strings.TrimPrefix(r, prefix), strings.TrimPrefix(name, prefix))
+				break
+			}
+		}
+	}
+	return forms
+}
+
+// matchReference returns whether the image reference ref matches the glob pattern
+func matchReference(pattern, ref string) bool {
+	for _, form := range referenceForms(ref) {
+		if ok, _ := path.Match(pattern, form); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterImages returns the images matching opts, for the runtimes whose CLI can not filter them.
+// Only the tags matching the reference pattern are kept, so that the images are listed as docker lists them.
+func FilterImages(images []ListImage, opts ListImagesOptions) ([]ListImage, error) {
+	if opts.Reference != "" {
+		if err := validateReferencePattern(opts.Reference); err != nil {
+			return nil, err
+		}
+	}
+	result := []ListImage{}
+	for _, img := range images {
+		if opts.Dangling != nil && *opts.Dangling != (len(img.RepoTags) == 0) {
+			continue
+		}
+		if opts.Platform != "" && !matchPlatform(img, opts.Platform) {
+			continue
+		}
+		if opts.Reference == "" {
+			result = append(result, img)
+			continue
+		}
+		tags := []string{}
+		for _, tag := range img.RepoTags {
+			if matchReference(opts.Reference, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		digested := false
+		for _, digest := range img.RepoDigests {
+			if matchReference(opts.Reference, digest) {
+				digested = true
+				break
+			}
+		}
+		switch {
+		case len(tags) > 0:
+			img.RepoTags = tags
+		case !digested:
+			continue
+		}
+		result = append(result, img)
+	}
+	return result, nil
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// LayerInfo describes a step of the history of an image, and the layer it added
+type LayerInfo struct {
+	// Digest is the diff ID of the layer, or empty if the step did not add a layer
+	Digest    string    `json:"digest" yaml:"digest"`
+	Size      int64     `json:"size" yaml:"size"`
+	CreatedBy string    `json:"createdBy" yaml:"createdBy"`
+	Created   time.Time `json:"created" yaml:"created"`
+}
+
+// historyStep is a step of the history of an image, which adds a layer unless empty
+type historyStep struct {
+	LayerInfo
+	empty bool
+}
+
+// layersFromHistory matches the steps of a history, oldest first, with the diff IDs of the image, and returns the layers newest first.
+// The digests are left empty if the steps adding a layer do not match the diff IDs.
+func layersFromHistory(steps []historyStep, diffIDs []string) []LayerInfo {
+	adding := 0
+	for _, s := range steps {
+		if !s.empty {
+			adding++
+		}
+	}
+	i := 0
+	layers := make([]LayerInfo, len(steps))
+	for n, s := range steps {
+		if !s.empty && adding == len(diffIDs) {
+			s.Digest = diffIDs[i]
+			i++
+		}
+		layers[len(steps)-1-n] = s.LayerInfo
+	}
+	return layers
+}
+
+// UniqueLayersSize returns the total size of the layers of images, counting the layers shared between images once
+func UniqueLayersSize(histories ...[]LayerInfo) int64 {
+	seen := map[string]bool{}
+	var total int64
+	for _, h := range histories {
+		for _, l := range h {
+			if l.Digest != "" {
+				if seen[l.Digest] {
+					continue
+				}
+				seen[l.Digest] = true
+			}
+			total += l.Size
+		}
+	}
+	return total
+}
+
+// parseDockerHistory parses the output of 'docker history --human=false --format "{{json .}}"', returning the steps oldest first
+func parseDockerHistory(out string) ([]historyStep, error) {
+	type dockerHistory struct {
+		CreatedAt string `json:"CreatedAt"`
+		CreatedBy string `json:"CreatedBy"`
+		Size      string `json:"Size"`
+	}
+	steps := []historyStep{}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var h dockerHistory
+		if err := json.Unmarshal([]byte(line), &h); err != nil {
+			return nil, errors.Wrap(err, "parsing docker history")
+		}
+		size, err := strconv.ParseInt(h.Size, 10, 64)
+		if err != nil {
+			if size, err = units.FromHumanSize(h.Size); err != nil {
+				return nil, errors.Wrapf(err, "parsing layer size %q", h.Size)
+			}
+		}
+		// the creation time is missing for images built without a history
+		created, _ := time.Parse(time.RFC3339, h.CreatedAt)
+		steps = append([]historyStep{{LayerInfo: LayerInfo{Size: size, CreatedBy: h.CreatedBy, Created: created}, empty: size == 0}}, steps...)
+	}
+	return steps, nil
+}
+
+// parsePodmanHistory parses the output of 'podman history --format json', returning the steps oldest first
+func parsePodmanHistory(out []byte) ([]historyStep, error) {
+	var history []struct {
+		Created   time.Time `json:"created"`
+		CreatedBy string    `json:"CreatedBy"`
+		Size      int64     `json:"size"`
+	}
+	if err := json.Unmarshal(out, &history); err != nil {
+		return nil, errors.Wrap(err, "parsing podman history")
+	}
+	steps := make([]historyStep, len(history))
+	for i, h := range history {
+		steps[len(history)-1-i] = historyStep{LayerInfo: LayerInfo{Size: h.Size, CreatedBy: h.CreatedBy, Created: h.Created}, empty: h.Size == 0}
+	}
+	return steps, nil
+}
+
+// inspectRootFSLayers returns the diff IDs of an image, using docker or podman
+func inspectRootFSLayers(cr CommandRunner, name string, cmd ...string) ([]string, error) {
+	args := append(append([]string{}, cmd...), "image", "inspect", "--format", "{{json .RootFS.Layers}}", name)
+	rr, err := cr.RunCmd(exec.Command(args[0], args[1:]...))
+	if err != nil {
+		return nil, errors.Wrap(err, "image inspect")
+	}
+	var diffIDs []string
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &diffIDs); err != nil {
+		return nil, errors.Wrap(err, "parsing image layers")
+	}
+	return diffIDs, nil
+}
+
+// chainIDs returns the chain IDs of the layers of an image, which name their snapshots in containerd
+func chainIDs(diffIDs []string) []string {
+	ids := []string{}
+	for i, d := range diffIDs {
+		if i == 0 {
+			ids = append(ids, d)
+			continue
+		}
+		sum := sha256.Sum256([]byte(ids[i-1] + " " + d))
+		ids = append(ids, "sha256:"+hex.EncodeToString(sum[:]))
+	}
+	return ids
+}
+
+// parseSnapshotsUsage parses the output of 'ctr snapshots usage', returning the sizes of the snapshots by key
+func parseSnapshotsUsage(out string) map[string]int64 {
+	usage := map[string]int64{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		// KEY SIZE UNIT INODES, such as: sha256:4fc2... 7.3 MiB 521
+		if len(fields) != 4 || fields[0] == "KEY" {
+			continue
+		}
+		size, err := units.RAMInBytes(fields[1] + fields[2])
+		if err != nil {
+			continue
+		}
+		usage[fields[0]] = size
+	}
+	return usage
+}
+
+// containerdImageHistory returns the layers of an image using crictl, with the sizes of their snapshots in containerd
+func containerdImageHistory(cr CommandRunner, name string) ([]LayerInfo, error) {
+	img, err := inspectCRIImage(cr, name)
+	if err != nil {
+		return nil, err
+	}
+	spec := img.Info.ImageSpec
+	diffIDs := spec.RootFS.DiffIDs
+	usage := map[string]int64{}
+	if rr, err := cr.RunCmd(command.Sudo("ctr", "-n=k8s.io", "snapshots", "usage")); err == nil {
+		usage = parseSnapshotsUsage(rr.Stdout.String())
+	}
+	chains := chainIDs(diffIDs)
+
+	steps := []historyStep{}
+	layer := 0
+	for _, h := range spec.History {
+		s := historyStep{LayerInfo: LayerInfo{CreatedBy: h.CreatedBy, Created: h.Created}, empty: h.EmptyLayer}
+		if !h.EmptyLayer && layer < len(chains) {
+			s.Size = usage[chains[layer]]
+			layer++
+		}
+		steps = append(steps, s)
+	}
+	return layersFromHistory(steps, diffIDs), nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"strings"
+)
+
+// IsImageID returns whether a reference loaded from an archive is the ID of an untagged image, rather than a name
+func IsImageID(ref string) bool {
+	return strings.HasPrefix(ref, "sha256:")
+}
+
+// parseLoadedImages returns the references of the images loaded from an archive, from the output of
+// 'docker load' ("Loaded image: ..." or "Loaded image ID: ..." for untagged images),
+// 'podman load' ("Loaded image(s): ..." with the references separated by commas) or
+// 'ctr images import' ("unpacking ... (sha256:...)...done").
+func parseLoadedImages(out string) []string {
+	refs := []string{}
+	seen := map[string]bool{}
+	add := func(ref string) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || seen[ref] {
+			return
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "Loaded image ID:"):
+			add(strings.TrimPrefix(line, "Loaded image ID:"))
+		case strings.HasPrefix(line, "Loaded image(s):"):
+			for _, ref := range strings.Split(strings.TrimPrefix(line, "Loaded image(s):"), ",") {
+				add(ref)
+			}
+		case strings.HasPrefix(line, "Loaded image:"):
+			add(strings.TrimPrefix(line, "Loaded image:"))
+		case strings.HasPrefix(line, "unpacking "):
+			if f := strings.Fields(line); len(f) > 1 {
+				add(f[1])
+			}
+		}
+	}
+	return refs
+}
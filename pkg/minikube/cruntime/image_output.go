@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/out/register"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+// ImageOutput is how the output of the commands pulling and loading images reaches the user
+type ImageOutput int
+
+const (
+	// ImageOutputBuffered only logs the output once the command completes, as for the other commands of the runtime
+	ImageOutputBuffered ImageOutput = iota
+	// ImageOutputTerminal streams the output to the terminal of the user, where docker shows its own progress bars
+	ImageOutputTerminal
+	// ImageOutputSummary prints the last line of the output periodically, for the outputs which are not terminals
+	ImageOutputSummary
+	// ImageOutputEvents converts each line of the output into a JSON event
+	ImageOutputEvents
+	// ImageOutputDiscard drops the output, in quiet mode
+	ImageOutputDiscard
+)
+
+// imageSummaryInterval is how often the output of an image command is summarized, when it is not streamed to a terminal
+const imageSummaryInterval = 5 * time.Second
+
+// SelectImageOutput returns how to show the output of the image commands: as JSON events with --output=json,
+// not at all in quiet mode, as is on terminals, and summarized otherwise
+func SelectImageOutput(json, quiet, terminal bool) ImageOutput {
+	switch {
+	case json:
+		return ImageOutputEvents
+	case quiet:
+		return ImageOutputDiscard
+	case terminal:
+		return ImageOutputTerminal
+	default:
+		return ImageOutputSummary
+	}
+}
+
+// streamImageOutput sets where the output of the image command c goes by mode, rather than only to its result.
+// The runners still record the output in the result, which the runtimes parse.
+func streamImageOutput(mode ImageOutput, c *exec.Cmd, artifact, action string) {
+	switch mode {
+	case ImageOutputTerminal:
+		// the kic runner allocates a terminal in the container for the files which are terminals, so that docker shows its progress bars;
+		// over ssh the output is streamed as is, without a terminal
+		c.Stdout, c.Stderr = out.Streams()
+	case ImageOutputSummary:
+		start := time.Now()
+		var last time.Time
+		w := &lineWriter{onLine: func(line string) {
+			if now := time.Now(); now.Sub(last) >= imageSummaryInterval {
+				out.Styled(style.Pulling, "{{.artifact}}: {{.line}} ({{.elapsed}})", out.V{"artifact": artifact, "line": line, "elapsed": now.Sub(start).Round(time.Second)})
+				last = now
+			}
+		}}
+		c.Stdout, c.Stderr = w, w
+	case ImageOutputEvents:
+		start := time.Now()
+		w := &lineWriter{onLine: func(line string) {
+			register.PrintImageProgress(artifact, action, register.ImageInProgress, time.Since(start), map[string]string{"line": line})
+		}}
+		c.Stdout, c.Stderr = w, w
+	case ImageOutputDiscard:
+		c.Stdout, c.Stderr = io.Discard, io.Discard
+	}
+}
+
+// lineWriter calls onLine with each line written to it, the progress lines ending with a carriage return included
+type lineWriter struct {
+	onLine func(line string)
+	mu     sync.Mutex
+	buf    bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexAny(w.buf.Bytes(), "\r\n")
+		if i < 0 {
+			return len(p), nil
+		}
+		line := strings.TrimSpace(string(w.buf.Next(i + 1)))
+		if line != "" {
+			w.onLine(line)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// unameArchs are the architectures of the Go toolchain and of the images, by the machine name uname prints
+var unameArchs = map[string]string{
+	"x86_64":  "amd64",
+	"amd64":   "amd64",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"armv7l":  "arm",
+	"armv6l":  "arm",
+	"i386":    "386",
+	"i686":    "386",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+// NodeArch returns the architecture of a node, as the images name it
+func NodeArch(cr CommandRunner) (string, error) {
+	rr, err := cr.RunCmd(exec.Command("uname", "-m"))
+	if err != nil {
+		return "", errors.Wrap(err, "uname")
+	}
+	m := strings.TrimSpace(rr.Stdout.String())
+	if arch, ok := unameArchs[m]; ok {
+		return arch, nil
+	}
+	return m, nil
+}
+
+// Platform returns the platform of an image as os/arch[/variant], or an empty string if the runtime does not know
+// its architecture, as with some images built FROM scratch
+func (i ListImage) Platform() string {
+	if i.Architecture == "" {
+		return ""
+	}
+	os := i.OS
+	if os == "" {
+		os = "linux"
+	}
+	p := os + "/" + i.Architecture
+	if i.Variant != "" {
+		p += "/" + i.Variant
+	}
+	return p
+}
+
+// ForeignArch returns whether the architecture of an image is known and is not arch, so that it does not run on a node of arch
+func (i ListImage) ForeignArch(arch string) bool {
+	return i.Architecture != "" && arch != "" && i.Architecture != arch
+}
+
+// ValidatePlatform returns an error if p is not a platform as os/arch[/variant], as linux/arm64
+func ValidatePlatform(p string) error {
+	parts := strings.Split(p, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf("invalid platform %q, expected os/arch[/variant] as linux/amd64", p)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return fmt.Errorf("invalid platform %q, expected os/arch[/variant] as linux/amd64", p)
+		}
+	}
+	return nil
+}
+
+// matchPlatform returns whether an image is of the platform p, whose variant is ignored if p has none.
+// Images whose architecture is unknown match no platform.
+func matchPlatform(img ListImage, p string) bool {
+	if img.Architecture == "" {
+		return false
+	}
+	parts := strings.Split(p, "/")
+	os := img.OS
+	if os == "" {
+		os = "linux"
+	}
+	if len(parts) < 2 || parts[0] != os || parts[1] != img.Architecture {
+		return false
+	}
+	if len(parts) == 2 {
+		return true
+	}
+	return normalizeVariant(img.Architecture, parts[2]) == normalizeVariant(img.Architecture, img.Variant)
+}
+
+// normalizeVariant returns the variant of an architecture, which is v8 for arm64 images that name none
+func normalizeVariant(arch, variant string) string {
+	if arch == "arm64" && variant == "" {
+		return "v8"
+	}
+	return variant
+}
+
+// imagePlatform is the platform of an image, as its configuration records it
+type imagePlatform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant"`
+}
+
+// setPlatforms fills in the platforms of the images, by ID with or without the sha256: prefix
+func setPlatforms(images []ListImage, platforms map[string]imagePlatform) {
+	for i, img := range images {
+		p, ok := platforms[strings.TrimPrefix(img.ID, "sha256:")]
+		if !ok {
+			continue
+		}
+		images[i].OS = p.OS
+		images[i].Architecture = p.Architecture
+		images[i].Variant = p.Variant
+	}
+}
+
+// criImagePlatforms returns the platforms of the images by ID, inspecting them all with a single crictl inspecti
+func criImagePlatforms(cr CommandRunner, ids []string) (map[string]imagePlatform, error) {
+	if len(ids) == 0 {
+		return map[string]imagePlatform{}, nil
+	}
+	args := append([]string{getCrictlPath(cr), "inspecti", "--output", "json"}, ids...)
+	rr, err := cr.RunCmd(command.Sudo(args...))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl inspecti")
+	}
+	return parseCRIImagePlatforms(rr.Stdout.Bytes())
+}
+
+// parseCRIImagePlatforms parses the output of 'crictl inspecti --output json' for several images, one document each.
+// containerd reports the configuration of the image as info.imageSpec, and CRI-O as the imageSpec of the JSON string info.info.
+func parseCRIImagePlatforms(out []byte) (map[string]imagePlatform, error) {
+	platforms := map[string]imagePlatform{}
+	d := json.NewDecoder(bytes.NewReader(out))
+	for d.More() {
+		var img struct {
+			Status struct {
+				ID string `json:"id"`
+			} `json:"status"`
+			Info map[string]json.RawMessage `json:"info"`
+		}
+		if err := d.Decode(&img); err != nil {
+			return nil, errors.Wrap(err, "crictl inspecti output")
+		}
+		spec := img.Info["imageSpec"]
+		if spec == nil {
+			var info string
+			if err := json.Unmarshal(img.Info["info"], &info); err == nil {
+				var nested struct {
+					ImageSpec json.RawMessage `json:"imageSpec"`
+				}
+				if err := json.Unmarshal([]byte(info), &nested); err == nil {
+					spec = nested.ImageSpec
+				}
+			}
+		}
+		var p imagePlatform
+		if spec != nil {
+			if err := json.Unmarshal(spec, &p); err != nil {
+				return nil, errors.Wrapf(err, "image configuration of %s", img.Status.ID)
+			}
+		}
+		platforms[strings.TrimPrefix(img.Status.ID, "sha256:")] = p
+	}
+	return platforms, nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/image"
+)
+
+// scriptFunc returns the command running several commands in one invocation of the shell of a host.
+// With stopOnError, the script stops at the first failing command, otherwise it runs them all and fails if any failed.
+type scriptFunc func(lines []string, stopOnError bool) *exec.Cmd
+
+// bashScript runs several commands in one invocation of bash
+func bashScript(lines []string, stopOnError bool) *exec.Cmd {
+	script := []string{}
+	if stopOnError {
+		script = append(append(script, "set -e"), lines...)
+	} else {
+		script = append(script, "rc=0")
+		for _, l := range lines {
+			script = append(script, l+" || rc=1")
+		}
+		script = append(script, "exit $rc")
+	}
+	return exec.Command("/bin/bash", "-c", strings.Join(script, "\n"))
+}
+
+// retagCommands are the commands of a runtime adding and removing the tags of images
+type retagCommands struct {
+	// script runs the commands in one invocation
+	script scriptFunc
+	// tag returns the command tagging source as target
+	tag func(source, target string) string
+	// untag returns the command removing the tag target, leaving the image its other tags
+	untag func(target string) string
+}
+
+// retagImages tags each source of mapping as its target, running all the tags in one invocation and checking all the
+// targets exist with a single listing afterwards. When any tag fails, the targets it created are untagged in one invocation,
+// so that a failure does not leave the images under a mix of names. The references must be valid, which also makes them
+// safe to run in the shell of the host unquoted.
+func retagImages(r Manager, cr CommandRunner, cmds retagCommands, mapping map[string]string) error {
+	if len(mapping) == 0 {
+		return nil
+	}
+	sources := []string{}
+	for source, target := range mapping {
+		for _, ref := range []string{source, target} {
+			if _, err := image.NormalizeReference(ref); err != nil {
+				return errors.Wrap(err, "retag images")
+			}
+		}
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	targets := []string{}
+	tags := []string{}
+	for _, source := range sources {
+		targets = append(targets, mapping[source])
+		tags = append(tags, cmds.tag(source, mapping[source]))
+	}
+
+	before, err := r.ImagesExist(targets)
+	if err != nil {
+		return errors.Wrap(err, "listing images")
+	}
+	klog.Infof("Retagging %d images of %s", len(sources), r.Name())
+	rb := &rollback{}
+	err = rb.run("tagging images", func() error {
+		if rr, err := cr.RunCmd(cmds.script(tags, true)); err != nil {
+			return errors.Wrapf(err, "tag: %s", rr.Output())
+		}
+		exist, err := r.ImagesExist(targets)
+		if err != nil {
+			return errors.Wrap(err, "listing images")
+		}
+		missing := []string{}
+		for _, t := range targets {
+			if !exist[t] {
+				missing = append(missing, t)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("missing after tagging: %s", strings.Join(missing, ", "))
+		}
+		return nil
+	}, func() error {
+		exist, err := r.ImagesExist(targets)
+		if err != nil {
+			return errors.Wrap(err, "listing images")
+		}
+		untags := []string{}
+		for _, t := range targets {
+			if exist[t] && !before[t] {
+				untags = append(untags, cmds.untag(t))
+			}
+		}
+		if len(untags) == 0 {
+			return nil
+		}
+		if rr, err := cr.RunCmd(cmds.script(untags, false)); err != nil {
+			return errors.Wrapf(err, "untag: %s", rr.Output())
+		}
+		return nil
+	})
+	if err != nil {
+		return rb.fail(err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+// ImageScanTarget tells an image scanner how to reach the images of a runtime
+type ImageScanTarget struct {
+	// Archive is true if the image must be saved with SaveImage, and the archive scanned
+	Archive bool
+	// Source is the name of the image store for the scanner, when scanning it directly
+	Source string
+	// Env is the environment the scanner needs to reach the image store
+	Env []string
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"path"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/download"
+)
+
+const (
+	// localPreloadTargetDir and localPreloadTargetName are where a local preload is copied to in the node, with a name unique to the invocation
+	localPreloadTargetDir  = "/"
+	localPreloadTargetName = "local-preload.tar"
+	// localPreloadExtractDir is where the image archives of a local preload are extracted to in the node, with a name unique to the invocation
+	localPreloadExtractDir = "/var/lib/minikube/local-preload"
+)
+
+// ExtractImagesFromPreload loads the images among names which the local preload of cc holds, extracting only their archives
+// rather than overwriting the image store, and returns the images it does not hold.
+// The official preloads are snapshots of the whole image store, so their images are left to be pulled.
+func ExtractImagesFromPreload(cr Manager, runner CommandRunner, cc config.ClusterConfig, names []string) ([]string, error) {
+	k8s := cc.KubernetesConfig
+	if len(names) == 0 || !download.LocalPreloadExists(k8s.KubernetesVersion, k8s.ContainerRuntime, k8s.ImageRepository) {
+		return names, nil
+	}
+
+	tarballPath := download.LocalPreloadPath(k8s.KubernetesVersion, k8s.ContainerRuntime, k8s.ImageRepository)
+	if err := download.VerifyLocalPreload(tarballPath); err != nil {
+		return names, errors.Wrap(err, "verifying local preload")
+	}
+	archives, err := download.LocalPreloadArchives(tarballPath)
+	if err != nil {
+		return names, err
+	}
+	held := map[string]bool{}
+	for _, a := range archives {
+		held[a] = true
+	}
+	members := []string{}
+	missing := []string{}
+	for _, n := range names {
+		if a := download.LocalPreloadArchiveName(n); held[a] {
+			members = append(members, a)
+		} else {
+			missing = append(missing, n)
+		}
+	}
+	if len(members) == 0 {
+		return names, nil
+	}
+	compression, err := download.DetectCompression(tarballPath)
+	if err != nil {
+		return names, err
+	}
+	if err := decompressible(runner, compression); err != nil {
+		return names, err
+	}
+
+	targetName := NodeTempName(localPreloadTargetName)
+	extractDir := NodeTempName(localPreloadExtractDir)
+	fa, err := assets.NewFileAsset(tarballPath, localPreloadTargetDir, targetName, "0644")
+	if err != nil {
+		return names, errors.Wrap(err, "getting file asset")
+	}
+	defer func() {
+		if err := fa.Close(); err != nil {
+			klog.Warningf("error closing the file %s: %v", fa.GetSourcePath(), err)
+		}
+	}()
+	if err := runner.Copy(fa); err != nil {
+		return names, errors.Wrap(err, "copying file")
+	}
+
+	dest := path.Join(localPreloadTargetDir, targetName)
+	defer func() {
+		if _, err := runner.RunCmd(command.Sudo("rm", "-rf", dest, extractDir)); err != nil {
+			klog.Infof("error removing local preload: %v", err)
+		}
+	}()
+	if rr, err := runner.RunCmd(command.Sudo("mkdir", "-p", extractDir)); err != nil {
+		return names, errors.Wrapf(err, "making %s: %s", extractDir, rr.Output())
+	}
+	if rr, err := runner.RunCmd(tarExtractCmd(compression, extractDir, dest, members...)); err != nil {
+		return names, errors.Wrapf(err, "extracting local preload: %s", rr.Output())
+	}
+	for _, m := range members {
+		if _, err := cr.LoadImage(path.Join(extractDir, m)); err != nil {
+			return names, errors.Wrapf(err, "loading %s", m)
+		}
+	}
+	klog.Infof("Extracted %d images from the local preload %s", len(members), tarballPath)
+	return missing, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/download"
+)
+
+// ErrOffline is the error returned instead of downloading images while the node is offline
+type ErrOffline struct {
+	// Missing are the images, or the preload, which would have to be downloaded
+	Missing []string
+}
+
+// NewErrOffline creates a new ErrOffline
+func NewErrOffline(missing ...string) *ErrOffline {
+	return &ErrOffline{Missing: missing}
+}
+
+func (e *ErrOffline) Error() string {
+	return fmt.Sprintf("offline, unable to download: %s", strings.Join(e.Missing, ", "))
+}
+
+// offlineExitCodes are the exit codes of curl for connection failures: unable to resolve the proxy or the host, to connect, or timed out
+var offlineExitCodes = map[int]bool{5: true, 6: true, 7: true, 28: true}
+
+// offlineProbeTTL is how long a failed probe is trusted, so that a transient failure does not keep the process offline
+const offlineProbeTTL = 30 * time.Second
+
+// offlineProbe is the result of probing a registry
+type offlineProbe struct {
+	offline bool
+	at      time.Time
+}
+
+var (
+	offlineMu sync.Mutex
+	// offlineProbes are the results of probing each registry: the registry is probed again once an offline result is older than offlineProbeTTL
+	offlineProbes = map[string]offlineProbe{}
+)
+
+// Offline returns whether the node is unable to download images, either assumed or detected by probing registry
+// A reachable registry is probed once per process, and an unreachable one once per offlineProbeTTL, so that a start does not wait for the probe on every node.
+func Offline(assume bool, cr CommandRunner, registry string) bool {
+	if assume {
+		return true
+	}
+	offlineMu.Lock()
+	defer offlineMu.Unlock()
+	if p, ok := offlineProbes[registry]; ok && (!p.offline || time.Since(p.at) < offlineProbeTTL) {
+		return p.offline
+	}
+	offline := probeOffline(cr, registry)
+	offlineProbes[registry] = offlineProbe{offline: offline, at: time.Now()}
+	return offline
+}
+
+// probeOffline returns whether registry is unreachable from the node
+// Only connection failures count, as registries answer the probe with all kinds of errors.
+func probeOffline(cr CommandRunner, registry string) bool {
+	opts := []string{"-sS", "-m", "2", "-o", "/dev/null"}
+	proxy := os.Getenv("HTTPS_PROXY")
+	if proxy != "" && !strings.HasPrefix(proxy, "localhost") && !strings.HasPrefix(proxy, "127.0") {
+		opts = append([]string{"-x", proxy}, opts...)
+	}
+	rr, err := cr.RunCmd(exec.Command("curl", append(opts, fmt.Sprintf("https://%s/", registry))...))
+	if err == nil {
+		return false
+	}
+	if rr != nil && offlineExitCodes[rr.ExitCode] {
+		klog.Warningf("%s is unreachable, assuming the node is offline: %v", registry, err)
+		return true
+	}
+	klog.Infof("probing %s failed, assuming the node is online: %v", registry, err)
+	return false
+}
+
+// missingPreload returns the error of a runtime without preload, which is fatal when offline as the images can not be pulled either
+func missingPreload(offline bool, cc config.ClusterConfig) error {
+	if !offline {
+		return nil
+	}
+	return NewErrOffline(download.TarballName(cc.KubernetesConfig.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime))
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+)
+
+const (
+	// containerdPinnedLabel is the label of the images containerd 1.7+ reports to the kubelet as pinned, which its image GC spares
+	containerdPinnedLabel = "io.cri-containerd.pinned=pinned"
+	// crioPinnedImagesFile is the drop-in configuration of CRI-O pinning the pause image
+	crioPinnedImagesFile = "/etc/crio/crio.conf.d/03-pinned-images.conf"
+)
+
+// containerdPinning is the version of containerd from which it reports pinned images to the kubelet
+var containerdPinning = semver.MustParse("1.7.0")
+
+// crioPinning is the version of CRI-O from which its configuration has pinned_images, which older versions reject
+var crioPinning = semver.MustParse("1.28.0")
+
+// pauseImage returns the pause image of the Kubernetes version in the image repository, or "" if the version is not known
+func pauseImage(kv semver.Version, imageRepository string) string {
+	if kv.Equals(semver.Version{}) {
+		return ""
+	}
+	return images.Pause(kv, imageRepository)
+}
+
+// checkPauseImage reports the pause image in h, marking the runtime degraded if it is missing, as no pod starts without it
+func checkPauseImage(h *Health, r Manager, name string) {
+	if name == "" || !h.Responsive {
+		return
+	}
+	h.PauseImage = name
+	if r.ImageExists(name, "") {
+		return
+	}
+	h.PauseImageMissing = true
+	if h.State == HealthRunning {
+		h.State, h.Reason = HealthDegraded, fmt.Sprintf("the pause image %s is missing, which no pod starts without", name)
+	}
+}
+
+// ensurePauseImage repairs the pause image if it is missing, with each of repairs in turn until it exists,
+// and returns whether it repaired it
+func ensurePauseImage(r Manager, name string, repairs ...func(name string) error) (bool, error) {
+	if name == "" || r.ImageExists(name, "") {
+		return false, nil
+	}
+	klog.Warningf("the pause image %s is missing, repairing it", name)
+	errs := []string{}
+	for _, repair := range repairs {
+		if err := repair(name); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if r.ImageExists(name, "") {
+			klog.Infof("repaired the pause image %s", name)
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("unable to repair the pause image %s: %s", name, strings.Join(errs, "; "))
+}
+
+// pauseFromPreload returns the repair loading the pause image from the local preload of the Kubernetes version, if any
+func pauseFromPreload(r Manager, runner CommandRunner, runtime string, kv semver.Version, imageRepository string) func(string) error {
+	return func(name string) error {
+		cc := config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v" + kv.String(), ContainerRuntime: runtime, ImageRepository: imageRepository}}
+		missing, err := ExtractImagesFromPreload(r, runner, cc, []string{name})
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("no local preload holds %s", name)
+		}
+		return nil
+	}
+}
+
+// pinContainerdImage labels the image for containerd to report it as pinned, pulling it first if missing
+func pinContainerdImage(r *Containerd, name string) error {
+	v, err := r.Version()
+	if err != nil {
+		return err
+	}
+	if sv, err := semver.ParseTolerant(v); err != nil || sv.LT(containerdPinning) {
+		klog.Infof("containerd %s does not report pinned images, leaving %s unpinned", v, name)
+		return nil
+	}
+	if !r.ImageExists(name, "") {
+		if err := r.PullImage(name); err != nil {
+			return err
+		}
+	}
+	if _, err := r.Runner.RunCmd(command.Sudo("ctr", "-n=k8s.io", "images", "label", name, containerdPinnedLabel)); err != nil {
+		return errors.Wrapf(err, "pinning %s", name)
+	}
+	return nil
+}
+
+// pinCRIOImage writes the drop-in configuration pinning the image, which CRI-O reads as it starts
+func pinCRIOImage(cr CommandRunner, name string) error {
+	v, err := crioVersion(cr)
+	if err != nil {
+		return err
+	}
+	if sv, err := semver.ParseTolerant(v); err != nil || sv.LT(crioPinning) {
+		klog.Infof("CRI-O %s does not pin images, leaving %s unpinned", v, name)
+		return nil
+	}
+	data := fmt.Sprintf("[crio.image]\npinned_images = [\n\t%q,\n]\n", name)
+	if err := cr.Copy(assets.NewMemoryAssetTarget([]byte(data), crioPinnedImagesFile, "0644")); err != nil {
+		return errors.Wrap(err, "pinning the pause image")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/pause"
+)
+
+// ErrPauseUntracked is returned when listing paused containers of a CRI runtime that neither runc nor the record of paused containers can answer for
+var ErrPauseUntracked = errors.New("the paused state of the containers is not tracked")
+
+// recordPausedContainers updates the paused state of the node, marking ids as paused or not
+func recordPausedContainers(cr CommandRunner, ids []string, paused bool) {
+	if len(ids) == 0 {
+		return
+	}
+	st, err := pause.LoadState(cr)
+	if err != nil {
+		klog.Warningf("unable to record paused containers %v: %v", ids, err)
+		return
+	}
+	if st == nil {
+		st = &pause.State{}
+	}
+	st.SetContainersPaused(ids, paused)
+	if err := pause.SaveState(cr, st); err != nil {
+		klog.Warningf("unable to record paused containers %v: %v", ids, err)
+	}
+}
+
+// filterPausedState returns the ids in the state o wants when runc cannot tell, using the record of paused containers
+func filterPausedState(cr CommandRunner, ids []string, o ListContainersOptions) ([]string, error) {
+	st, err := pause.LoadState(cr)
+	if err != nil {
+		klog.Warningf("unable to load the paused state: %v", err)
+	}
+	if o.State == Paused && !st.TracksContainers() {
+		return nil, ErrPauseUntracked
+	}
+	recorded := map[string]bool{}
+	if st != nil {
+		recorded = st.PausedContainers()
+	}
+
+	want := map[string]bool{}
+	if o.State == Running {
+		// crictl reports paused containers as running
+		rr, err := cr.RunCmd(command.Sudo(getCrictlPath(cr), "ps", "--quiet", "--state", "running"))
+		if err != nil {
+			return nil, errors.Wrap(err, "crictl ps")
+		}
+		for _, id := range strings.Split(rr.Stdout.String(), "\n") {
+			if id != "" && !recorded[id] {
+				want[id] = true
+			}
+		}
+	} else {
+		want = recorded
+	}
+
+	var fids []string
+	for _, id := range ids {
+		if want[id] {
+			fids = append(fids, id)
+		}
+	}
+	return fids, nil
+}
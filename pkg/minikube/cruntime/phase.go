@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+)
+
+// phaseOutputLines is how many of the last output lines of a phase its timeout error shows
+const phaseOutputLines = 10
+
+// ErrPhaseTimeout is returned when a phase of a runtime, such as enabling it or extracting the preload, did not complete before its deadline
+type ErrPhaseTimeout struct {
+	// Phase is the name of the phase, as docker.preload
+	Phase string
+	// Elapsed is how long the phase ran before its commands were killed
+	Elapsed time.Duration
+	// Command is the command which was running when the phase timed out
+	Command string
+	// Output are the last output lines of the commands of the phase
+	Output []string
+}
+
+func (e *ErrPhaseTimeout) Error() string {
+	msg := fmt.Sprintf("%s timed out after %s", e.Phase, e.Elapsed.Round(time.Second))
+	if e.Command != "" {
+		msg += fmt.Sprintf(" running %q", e.Command)
+	}
+	if len(e.Output) > 0 {
+		msg += ", last output:\n" + strings.Join(e.Output, "\n")
+	}
+	return msg
+}
+
+// contextRunner runs the commands of a phase in the host, killing them once the context of the phase is done,
+// and keeps the last lines of their output to report them if it times out
+type contextRunner struct {
+	CommandRunner
+	ctx context.Context
+
+	mu      sync.Mutex
+	command string
+	output  []string
+}
+
+// record keeps the command which ran, and the last lines of its output
+func (c *contextRunner) record(cmd *exec.Cmd, rr *command.RunResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.command = strings.Join(cmd.Args, " ")
+	if rr == nil {
+		return
+	}
+	for _, l := range strings.Split(strings.TrimSpace(rr.Stdout.String()+"\n"+rr.Stderr.String()), "\n") {
+		if strings.TrimSpace(l) != "" {
+			c.output = append(c.output, l)
+		}
+	}
+	if len(c.output) > phaseOutputLines {
+		c.output = c.output[len(c.output)-phaseOutputLines:]
+	}
+}
+
+// RunCmd runs a command, killing it once the context of the phase is done
+func (c *contextRunner) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
+	return c.RunCmdContext(context.Background(), cmd)
+}
+
+// RunCmdContext runs a command, killing it once either ctx or the context of the phase is done
+func (c *contextRunner) RunCmdContext(ctx context.Context, cmd *exec.Cmd) (*command.RunResult, error) {
+	if err := c.ctx.Err(); err != nil {
+		rr := &command.RunResult{Args: cmd.Args}
+		return rr, errors.Wrap(err, rr.Command())
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	rr, err := c.CommandRunner.RunCmdContext(ctx, cmd)
+	c.record(cmd, rr)
+	return rr, err
+}
+
+// runPhase runs a phase of a runtime with its runner and init system replaced by ones killing their commands once ctx is done,
+// returning an ErrPhaseTimeout if the deadline of ctx passed before the phase completed.
+// The runner and init system are those of a copy of the runtime, as others may run commands with the runtime meanwhile.
+func runPhase(ctx context.Context, phase string, runner *CommandRunner, init *sysinit.Manager, f func() error) error {
+	if ctx.Done() == nil {
+		return f()
+	}
+	cr := &contextRunner{CommandRunner: *runner, ctx: ctx}
+	prevRunner, prevInit := *runner, *init
+	*runner, *init = cr, sysinit.New(cr)
+	defer func() { *runner, *init = prevRunner, prevInit }()
+
+	start := time.Now()
+	err := f()
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	// a nested phase, such as the restart of a preload, timed out first
+	var perr *ErrPhaseTimeout
+	if errors.As(err, &perr) {
+		return err
+	}
+	klog.Warningf("%s timed out after %s: %v", phase, time.Since(start), err)
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	return &ErrPhaseTimeout{Phase: phase, Elapsed: time.Since(start), Command: cr.command, Output: cr.output}
+}
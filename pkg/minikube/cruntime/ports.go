@@ -0,0 +1,319 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+const (
+	// containerPortsAnnotation is the annotation of kubelet on the containers of pods, listing the ports they declare
+	containerPortsAnnotation = "io.kubernetes.container.ports"
+	// crioPortMappingsAnnotation is the annotation of cri-o on the pod sandboxes, listing their port mappings
+	crioPortMappingsAnnotation = "io.kubernetes.cri-o.PortMappings"
+)
+
+// criProtocols are the protocols of the CRI, by the value of their enum
+var criProtocols = []string{"tcp", "udp", "sctp"}
+
+// PortMapping is a port a container exposes, and where the node publishes it, if it does
+type PortMapping struct {
+	// Protocol is the protocol of the port: tcp, udp or sctp
+	Protocol string
+	// ContainerIP is the address of the container in the node, which is empty for the containers in the network of the node
+	ContainerIP string
+	// ContainerPort is the port in the container
+	ContainerPort int
+	// HostIP is the address of the node the port is published on, which is empty for all of its addresses
+	HostIP string
+	// HostPort is the port of the node the port is published on, which is 0 if the port is only exposed
+	HostPort int
+}
+
+// dockerContainerInspect maps to the output of 'docker container inspect --format {{json .}}'
+type dockerContainerInspect struct {
+	Config struct {
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+		Labels       map[string]string   `json:"Labels"`
+	} `json:"Config"`
+	HostConfig struct {
+		NetworkMode string `json:"NetworkMode"`
+	} `json:"HostConfig"`
+	NetworkSettings struct {
+		IPAddress string `json:"IPAddress"`
+		Ports     map[string][]struct {
+			HostIP   string `json:"HostIp"`
+			HostPort string `json:"HostPort"`
+		} `json:"Ports"`
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// crictlPodInspect maps to the output of 'crictl inspectp --output json'
+type crictlPodInspect struct {
+	Status struct {
+		Network struct {
+			IP string `json:"ip"`
+		} `json:"network"`
+		Linux struct {
+			Namespaces struct {
+				Options struct {
+					Network string `json:"network"`
+				} `json:"options"`
+			} `json:"namespaces"`
+		} `json:"linux"`
+	} `json:"status"`
+	Info struct {
+		// Config is the configuration of the sandbox, which containerd reports
+		Config struct {
+			PortMappings []struct {
+				Protocol      int    `json:"protocol"`
+				ContainerPort int    `json:"container_port"`
+				HostPort      int    `json:"host_port"`
+				HostIP        string `json:"host_ip"`
+			} `json:"port_mappings"`
+		} `json:"config"`
+		// RuntimeSpec is the OCI spec of the sandbox, whose annotations cri-o reports the port mappings in
+		RuntimeSpec struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"runtimeSpec"`
+	} `json:"info"`
+}
+
+// dockerPortMappings returns the ports of a docker container. The containers sharing the network of another one,
+// as those of pods do with their sandbox, have the address and the published ports of that one.
+func dockerPortMappings(cr CommandRunner, id string) ([]PortMapping, error) {
+	c, err := inspectDockerContainer(cr, id)
+	if err != nil {
+		return nil, err
+	}
+	network := c
+	if sandbox := strings.TrimPrefix(c.HostConfig.NetworkMode, "container:"); sandbox != c.HostConfig.NetworkMode {
+		if network, err = inspectDockerContainer(cr, sandbox); err != nil {
+			return nil, err
+		}
+	}
+	ip := ""
+	if network.HostConfig.NetworkMode != "host" {
+		ip = network.address()
+	}
+
+	published := []PortMapping{}
+	exposed := []PortMapping{}
+	for _, inspected := range []*dockerContainerInspect{c, network} {
+		for spec, bindings := range inspected.NetworkSettings.Ports {
+			port, protocol, err := parsePortSpec(spec)
+			if err != nil {
+				klog.Warningf("skipping port %q of container %s: %v", spec, id, err)
+				continue
+			}
+			if len(bindings) == 0 {
+				exposed = append(exposed, PortMapping{Protocol: protocol, ContainerPort: port})
+			}
+			for _, b := range bindings {
+				hostPort, err := strconv.Atoi(b.HostPort)
+				if err != nil {
+					klog.Warningf("skipping host port %q of container %s: %v", b.HostPort, id, err)
+					continue
+				}
+				published = append(published, PortMapping{Protocol: protocol, ContainerPort: port, HostIP: b.HostIP, HostPort: hostPort})
+			}
+		}
+		for spec := range inspected.Config.ExposedPorts {
+			if port, protocol, err := parsePortSpec(spec); err == nil {
+				exposed = append(exposed, PortMapping{Protocol: protocol, ContainerPort: port})
+			}
+		}
+	}
+	// cri-dockerd keeps the annotations of the containers of pods in their labels
+	exposed = append(exposed, kubeletContainerPorts(c.Config.Labels["annotation."+containerPortsAnnotation])...)
+	return mergePortMappings(ip, published, exposed), nil
+}
+
+// inspectDockerContainer returns the details of a docker container, given by its name, ID or a prefix of its ID
+func inspectDockerContainer(cr CommandRunner, id string) (*dockerContainerInspect, error) {
+	rr, err := cr.RunCmd(exec.Command("docker", "container", "inspect", "--format", "{{json .}}", id))
+	if err != nil {
+		return nil, errors.Wrapf(err, "docker container inspect %s", id)
+	}
+	var c dockerContainerInspect
+	if err := json.Unmarshal(bytes.TrimSpace(rr.Stdout.Bytes()), &c); err != nil {
+		return nil, errors.Wrap(err, "docker container inspect output")
+	}
+	return &c, nil
+}
+
+// address returns the address of a docker container, in its first network if it has several
+func (c *dockerContainerInspect) address() string {
+	if c.NetworkSettings.IPAddress != "" {
+		return c.NetworkSettings.IPAddress
+	}
+	names := []string{}
+	for name := range c.NetworkSettings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ip := c.NetworkSettings.Networks[name].IPAddress; ip != "" {
+			return ip
+		}
+	}
+	return ""
+}
+
+// criPortMappings returns the ports of a CRI container, given by its ID or a prefix of it,
+// which has the address and the port mappings of its pod sandbox
+func criPortMappings(cr CommandRunner, id string) ([]PortMapping, error) {
+	crictl := getCrictlPath(cr)
+	rr, err := cr.RunCmd(command.Sudo(crictl, "ps", "-a", "--id", id, "--output", "json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl ps")
+	}
+	var cs crictlContainers
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &cs); err != nil {
+		return nil, errors.Wrap(err, "crictl ps output")
+	}
+	switch {
+	case len(cs.Containers) == 0:
+		return nil, fmt.Errorf("no such container: %s", id)
+	case len(cs.Containers) > 1:
+		return nil, fmt.Errorf("%s matches %d containers", id, len(cs.Containers))
+	}
+	c := cs.Containers[0]
+
+	rr, err = cr.RunCmd(command.Sudo(crictl, "inspectp", "--output", "json", c.PodSandboxID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "crictl inspectp %s", c.PodSandboxID)
+	}
+	var pod crictlPodInspect
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &pod); err != nil {
+		return nil, errors.Wrap(err, "crictl inspectp output")
+	}
+	ip := ""
+	if pod.Status.Linux.Namespaces.Options.Network != "NODE" {
+		ip = pod.Status.Network.IP
+	}
+
+	published := []PortMapping{}
+	for _, m := range pod.Info.Config.PortMappings {
+		protocol := "tcp"
+		if m.Protocol >= 0 && m.Protocol < len(criProtocols) {
+			protocol = criProtocols[m.Protocol]
+		}
+		published = append(published, PortMapping{Protocol: protocol, ContainerPort: m.ContainerPort, HostIP: m.HostIP, HostPort: m.HostPort})
+	}
+	if annotation := pod.Info.RuntimeSpec.Annotations[crioPortMappingsAnnotation]; annotation != "" {
+		var ms []struct {
+			HostPort      int    `json:"hostPort"`
+			ContainerPort int    `json:"containerPort"`
+			Protocol      string `json:"protocol"`
+			HostIP        string `json:"hostIP"`
+		}
+		if err := json.Unmarshal([]byte(annotation), &ms); err != nil {
+			klog.Warningf("unable to parse the port mappings of pod %s: %v", c.PodSandboxID, err)
+		}
+		for _, m := range ms {
+			published = append(published, PortMapping{Protocol: normalizeProtocol(m.Protocol), ContainerPort: m.ContainerPort, HostIP: m.HostIP, HostPort: m.HostPort})
+		}
+	}
+	return mergePortMappings(ip, published, kubeletContainerPorts(c.Annotations[containerPortsAnnotation])), nil
+}
+
+// kubeletContainerPorts returns the ports listed by the annotation of kubelet on the containers of pods
+func kubeletContainerPorts(annotation string) []PortMapping {
+	if annotation == "" {
+		return nil
+	}
+	var ports []struct {
+		ContainerPort int    `json:"containerPort"`
+		Protocol      string `json:"protocol"`
+	}
+	if err := json.Unmarshal([]byte(annotation), &ports); err != nil {
+		klog.Warningf("unable to parse the ports of a container %q: %v", annotation, err)
+		return nil
+	}
+	ms := []PortMapping{}
+	for _, p := range ports {
+		ms = append(ms, PortMapping{Protocol: normalizeProtocol(p.Protocol), ContainerPort: p.ContainerPort})
+	}
+	return ms
+}
+
+// mergePortMappings returns the published ports, once per port of the node, and the exposed ports which are not published.
+// They are sorted by port, and have the address ip of the container.
+func mergePortMappings(ip string, published []PortMapping, exposed []PortMapping) []PortMapping {
+	// prefer the IPv4 addresses docker publishes the ports on as well as IPv6 ones
+	sort.SliceStable(published, func(i, j int) bool { return published[i].HostIP < published[j].HostIP })
+	result := []PortMapping{}
+	seen := map[string]bool{}
+	for _, m := range published {
+		if key := fmt.Sprintf("%d/%s:%d", m.ContainerPort, m.Protocol, m.HostPort); !seen[key] {
+			seen[key] = true
+			seen[fmt.Sprintf("%d/%s", m.ContainerPort, m.Protocol)] = true
+			m.ContainerIP = ip
+			result = append(result, m)
+		}
+	}
+	for _, m := range exposed {
+		if key := fmt.Sprintf("%d/%s", m.ContainerPort, m.Protocol); !seen[key] {
+			seen[key] = true
+			m.ContainerIP = ip
+			result = append(result, m)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.ContainerPort != b.ContainerPort {
+			return a.ContainerPort < b.ContainerPort
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.HostPort < b.HostPort
+	})
+	return result
+}
+
+// parsePortSpec parses the PORT/PROTOCOL ports of docker
+func parsePortSpec(spec string) (int, string, error) {
+	p, protocol, _ := strings.Cut(spec, "/")
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return 0, "", errors.Wrapf(err, "port of %q", spec)
+	}
+	return port, normalizeProtocol(protocol), nil
+}
+
+// normalizeProtocol returns the protocol of a port in lower case, which is tcp if unspecified
+func normalizeProtocol(protocol string) string {
+	if protocol == "" {
+		return "tcp"
+	}
+	return strings.ToLower(protocol)
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultPreloadSpaceFactor scales the estimated needs of extracting a preload, compared with the free space of the node
+	DefaultPreloadSpaceFactor = 1.0
+	// preloadExpansion is how much larger than the tarball the extracted preload is at least, as the layers compress about 2.5 times
+	preloadExpansion = 2
+	// preloadBytesPerFile is the size of the tarball per extracted file at most, so that the inodes are not overestimated
+	preloadBytesPerFile = 64 * 1024
+)
+
+// ErrPreloadSpace is returned when the filesystem of the node can not hold the extracted preload
+type ErrPreloadSpace struct {
+	// Dir is the directory the preload is extracted into
+	Dir string
+	// Options are the mount options of Dir preventing the runtime from using the extracted preload, such as noexec
+	Options []string
+	// NeedBytes and FreeBytes are the estimated size of the extracted preload, and the free space of Dir
+	NeedBytes, FreeBytes uint64
+	// NeedInodes and FreeInodes are the estimated number of files of the extracted preload, and the free inodes of Dir
+	NeedInodes, FreeInodes uint64
+}
+
+func (e *ErrPreloadSpace) Error() string {
+	if len(e.Options) > 0 {
+		return fmt.Sprintf("%s is mounted %s", e.Dir, strings.Join(e.Options, ","))
+	}
+	if e.FreeBytes < e.NeedBytes {
+		return fmt.Sprintf("%s has %s free, but the preload needs about %s", e.Dir, units.HumanSizeWithPrecision(float64(e.FreeBytes), 3), units.HumanSizeWithPrecision(float64(e.NeedBytes), 3))
+	}
+	return fmt.Sprintf("%s has %d free inodes, but the preload needs about %d", e.Dir, e.FreeInodes, e.NeedInodes)
+}
+
+// diskFree is the free space of the filesystem of a directory
+type diskFree struct {
+	// Inodes is the number of inodes, 0 for the filesystems which do not limit them, such as btrfs
+	Inodes uint64
+	// FreeInodes is the number of free inodes
+	FreeInodes uint64
+	// FreeBytes is the free space available to unprivileged users
+	FreeBytes uint64
+}
+
+// parseDFColumns returns the numbers of the last line of the output of df, the columns of which are counted from the end,
+// as the name of a filesystem with a long name may be printed on a line of its own
+func parseDFColumns(output string, fromEnd ...int) ([]uint64, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("unexpected df output: %q", output)
+	}
+	fields := strings.Fields(lines[len(lines)-1])
+	var nums []uint64
+	for _, i := range fromEnd {
+		if i > len(fields) {
+			return nil, fmt.Errorf("unexpected df output: %q", output)
+		}
+		n, err := strconv.ParseUint(fields[len(fields)-i], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unexpected df output: %q", output)
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+// parseCoreutilsDF parses the output of 'df --output=itotal,iavail,avail', the available space being in 1K blocks
+func parseCoreutilsDF(output string) (diskFree, error) {
+	nums, err := parseDFColumns(output, 3, 2, 1)
+	if err != nil {
+		return diskFree{}, err
+	}
+	return diskFree{Inodes: nums[0], FreeInodes: nums[1], FreeBytes: nums[2] * 1024}, nil
+}
+
+// parseBusyboxDF parses the outputs of 'df -Pk' and 'df -Pi', for the df of busybox which lacks --output:
+// Filesystem, the total, used and available 1K blocks or inodes, the capacity and the mount point
+func parseBusyboxDF(space, inodes string) (diskFree, error) {
+	s, err := parseDFColumns(space, 3)
+	if err != nil {
+		return diskFree{}, err
+	}
+	i, err := parseDFColumns(inodes, 5, 3)
+	if err != nil {
+		return diskFree{}, err
+	}
+	return diskFree{Inodes: i[0], FreeInodes: i[1], FreeBytes: s[0] * 1024}, nil
+}
+
+// diskFreeOf returns the free space of the filesystem of dir in the node
+func diskFreeOf(runner CommandRunner, dir string) (diskFree, error) {
+	rr, err := runner.RunCmd(exec.Command("df", "--output=itotal,iavail,avail", dir))
+	if err == nil {
+		return parseCoreutilsDF(rr.Stdout.String())
+	}
+	klog.Infof("df lacks --output, as the one of busybox: %v", err)
+	space, err := runner.RunCmd(exec.Command("df", "-Pk", dir))
+	if err != nil {
+		return diskFree{}, errors.Wrap(err, "df -Pk")
+	}
+	inodes, err := runner.RunCmd(exec.Command("df", "-Pi", dir))
+	if err != nil {
+		return diskFree{}, errors.Wrap(err, "df -Pi")
+	}
+	return parseBusyboxDF(space.Stdout.String(), inodes.Stdout.String())
+}
+
+// FreeSpace returns the space available to unprivileged users on the filesystem of dir in the node
+func FreeSpace(runner CommandRunner, dir string) (uint64, error) {
+	df, err := diskFreeOf(runner, dir)
+	if err != nil {
+		return 0, err
+	}
+	return df.FreeBytes, nil
+}
+
+// parseMountOptions returns the options of the mount holding dir in the content of /proc/mounts
+func parseMountOptions(mounts string, dir string) []string {
+	best := ""
+	var opts []string
+	for _, line := range strings.Split(mounts, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		mp := fields[1]
+		if mp != "/" && dir != mp && !strings.HasPrefix(dir, mp+"/") {
+			continue
+		}
+		// the last mount of the longest mount point hides the others
+		if len(mp) >= len(best) {
+			best, opts = mp, strings.Split(fields[3], ",")
+		}
+	}
+	return opts
+}
+
+// blockingMountOptions are the mount options preventing the runtime from running the extracted preload
+var blockingMountOptions = map[string]bool{"ro": true, "noexec": true}
+
+// checkPreloadSpace returns an ErrPreloadSpace if dir can not hold the preload extracted from a tarball of size bytes,
+// with its needs estimated conservatively and scaled by factor, which defaults to DefaultPreloadSpaceFactor if 0
+// and skips the check if negative. The check is also skipped when the node can not tell its free space.
+func checkPreloadSpace(runner CommandRunner, dir string, size int, factor float64) error {
+	if factor < 0 {
+		return nil
+	}
+	if factor == 0 {
+		factor = DefaultPreloadSpaceFactor
+	}
+	dir = path.Clean(dir)
+	if rr, err := runner.RunCmd(exec.Command("cat", "/proc/mounts")); err == nil {
+		var blocking []string
+		for _, o := range parseMountOptions(rr.Stdout.String(), dir) {
+			if blockingMountOptions[o] {
+				blocking = append(blocking, o)
+			}
+		}
+		if len(blocking) > 0 {
+			return &ErrPreloadSpace{Dir: dir, Options: blocking}
+		}
+	}
+	df, err := diskFreeOf(runner, dir)
+	if err != nil {
+		klog.Warningf("unable to check the free space of %s for the preload: %v", dir, err)
+		return nil
+	}
+	e := &ErrPreloadSpace{
+		Dir:        dir,
+		NeedBytes:  uint64(float64(size) * preloadExpansion * factor),
+		FreeBytes:  df.FreeBytes,
+		NeedInodes: uint64(float64(size) / preloadBytesPerFile * factor),
+		FreeInodes: df.FreeInodes,
+	}
+	klog.Infof("preload needs about %d bytes and %d inodes in %s, which has %+v", e.NeedBytes, e.NeedInodes, dir, df)
+	if e.FreeBytes < e.NeedBytes || df.Inodes > 0 && e.FreeInodes < e.NeedInodes {
+		return e
+	}
+	return nil
+}
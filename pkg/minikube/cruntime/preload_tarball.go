@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/download"
+)
+
+// preloadTarball returns the cached preload tarball which the node can decompress, along with its compression.
+// The tarballs are tried in order of preference, falling back to the next one when the node lacks the decompressor of one.
+func preloadTarball(runner CommandRunner, k8sVersion, cRuntime string) (string, download.Compression, error) {
+	cached := download.CachedTarballs(k8sVersion, cRuntime)
+	if len(cached) == 0 {
+		return "", "", fmt.Errorf("no preload tarball of Kubernetes %s for %s in the cache", k8sVersion, cRuntime)
+	}
+	var err error
+	for _, p := range cached {
+		var c download.Compression
+		if c, err = download.DetectCompression(p); err != nil {
+			klog.Warningf("skipping preload tarball %s: %v", p, err)
+			continue
+		}
+		if err = decompressible(runner, c); err != nil {
+			klog.Infof("skipping preload tarball %s: %v", p, err)
+			continue
+		}
+		return p, c, nil
+	}
+	return "", "", err
+}
+
+// decompressible returns an ErrISOFeature if the node lacks the program decompressing tarballs with the compression c
+func decompressible(runner CommandRunner, c download.Compression) error {
+	if c.Binary() == "" {
+		return nil
+	}
+	if _, err := runner.RunCmd(exec.Command("which", c.Binary())); err != nil {
+		return NewErrISOFeature(c.Binary())
+	}
+	return nil
+}
+
+// tarExtractCmd returns the command extracting the tarball at src, compressed with c, into dir
+func tarExtractCmd(c download.Compression, dir, src string, members ...string) *exec.Cmd {
+	args := append([]string{"tar"}, c.TarFlags()...)
+	args = append(args, "-C", dir, "-xf", src)
+	return command.Sudo(append(args, members...)...)
+}
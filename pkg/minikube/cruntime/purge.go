@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// purgeKubernetes removes all the Kubernetes containers of a runtime, then the images of o.
+// Images which can not be removed, such as the ones already gone, are logged and skipped.
+func purgeKubernetes(m Manager, o PurgeOptions) error {
+	ids, err := m.ListContainers(ListContainersOptions{State: All})
+	if err != nil {
+		return errors.Wrap(err, "listing kubernetes containers")
+	}
+	klog.Infof("purge: removing %d kubernetes containers of %s", len(ids), m.Name())
+	if err := m.KillContainers(ids); err != nil {
+		return errors.Wrap(err, "removing kubernetes containers")
+	}
+	if len(o.Images) == 0 {
+		klog.Infof("purge: keeping the images of %s", m.Name())
+		return nil
+	}
+	removed := 0
+	for _, img := range o.Images {
+		if err := m.RemoveImage(img); err != nil {
+			klog.Warningf("purge: unable to remove image %s: %v", img, err)
+			continue
+		}
+		removed++
+	}
+	klog.Infof("purge: removed %d of %d images of %s", removed, len(o.Images), m.Name())
+	return nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// crioRegistriesDir holds the drop-in registries configurations of CRI-O and podman
+const crioRegistriesDir = "/etc/containers/registries.conf.d"
+
+// EnsureInsecureRegistry makes a runtime accept the plain HTTP registry at addr (host:port), restarting it only if needed
+func EnsureInsecureRegistry(r Manager, addr string) error {
+	switch rt := r.(type) {
+	case *Docker:
+		return rt.ensureInsecureRegistry(addr)
+	case *Containerd:
+		return rt.ensureInsecureRegistry(addr)
+	case *CRIO:
+		return rt.ensureInsecureRegistry(addr)
+	}
+	return fmt.Errorf("unable to configure insecure registries for %s", r.Name())
+}
+
+// AcceptsInsecureRegistry returns whether a runtime already accepts the plain HTTP registry at addr (host:port)
+func AcceptsInsecureRegistry(r Manager, addr string) (bool, error) {
+	switch rt := r.(type) {
+	case *Docker:
+		return rt.acceptsInsecureRegistry(addr)
+	case *Containerd:
+		return rt.acceptsInsecureRegistry(addr), nil
+	case *CRIO:
+		return rt.acceptsInsecureRegistry(addr), nil
+	}
+	return false, fmt.Errorf("unable to configure insecure registries for %s", r.Name())
+}
+
+// dockerRegistryConfig is the registry configuration reported by docker info
+type dockerRegistryConfig struct {
+	InsecureRegistryCIDRs []string
+	IndexConfigs          map[string]struct {
+		Secure bool
+	}
+}
+
+// insecure returns whether the registry at addr is accepted over plain HTTP
+func (c dockerRegistryConfig) insecure(addr string) bool {
+	if ic, ok := c.IndexConfigs[addr]; ok && !ic.Secure {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range c.InsecureRegistryCIDRs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err == nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsInsecureRegistry returns whether docker reports the registry as insecure
+func (r *Docker) acceptsInsecureRegistry(addr string) (bool, error) {
+	rr, err := r.Runner.RunCmd(exec.Command("docker", "info", "--format", "{{json .RegistryConfig}}"))
+	if err != nil {
+		return false, errors.Wrap(err, "docker registry config")
+	}
+	var rc dockerRegistryConfig
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &rc); err != nil {
+		return false, errors.Wrap(err, "parsing docker registry config")
+	}
+	return rc.insecure(addr), nil
+}
+
+// ensureInsecureRegistry adds the registry to the insecure registries of daemon.json
+func (r *Docker) ensureInsecureRegistry(addr string) error {
+	insecure, err := r.acceptsInsecureRegistry(addr)
+	if err != nil {
+		return err
+	}
+	if insecure {
+		klog.Infof("docker already accepts the insecure registry %s", addr)
+		return nil
+	}
+
+	// dockerd refuses to start when a directive is given both as a flag and in daemon.json
+	rr, err := r.Runner.RunCmd(command.Sudo("systemctl", "cat", "docker.service"))
+	if err == nil && strings.Contains(rr.Stdout.String(), "--insecure-registry") {
+		return fmt.Errorf("docker does not accept the insecure registry %s, and gets its insecure registries as flags: restart minikube with --insecure-registry=%s", addr, addr)
+	}
+
+	daemonConfig := map[string]interface{}{}
+	if rr, err := r.Runner.RunCmd(command.Sudo("cat", r.daemonConfigFile())); err == nil && strings.TrimSpace(rr.Stdout.String()) != "" {
+		if err := json.Unmarshal(rr.Stdout.Bytes(), &daemonConfig); err != nil {
+			return errors.Wrap(err, "parsing daemon.json")
+		}
+	}
+	registries, _ := daemonConfig["insecure-registries"].([]interface{})
+	daemonConfig["insecure-registries"] = append(registries, addr)
+	data, err := json.MarshalIndent(daemonConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	klog.Infof("adding %s to the insecure registries of docker", addr)
+	if err := r.backupDaemonConfig(); err != nil {
+		return err
+	}
+	if err := r.Runner.Copy(assets.NewMemoryAssetTarget(data, r.daemonConfigFile(), "0644")); err != nil {
+		return errors.Wrap(err, "writing daemon.json")
+	}
+	return r.Restart()
+}
+
+// acceptsInsecureRegistry returns whether containerd has a hosts.toml for the registry
+func (r *Containerd) acceptsInsecureRegistry(addr string) bool {
+	_, err := r.Runner.RunCmd(command.Sudo("test", "-f", path.Join(containerdMirrorsRoot, addr, "hosts.toml")))
+	return err == nil
+}
+
+// ensureInsecureRegistry adds a hosts.toml for the registry, which containerd reads on each pull and push
+func (r *Containerd) ensureInsecureRegistry(addr string) error {
+	if r.acceptsInsecureRegistry(addr) {
+		klog.Infof("containerd already accepts the insecure registry %s", addr)
+		return nil
+	}
+	klog.Infof("adding %s to the insecure registries of containerd", addr)
+	return addContainerdInsecureRegistry(r.Runner, addr)
+}
+
+// crioRegistryConf returns the registries.conf drop-in of the registry
+func crioRegistryConf(addr string) string {
+	return path.Join(crioRegistriesDir, fmt.Sprintf("99-minikube-%s.conf", strings.NewReplacer(":", "-", "/", "-").Replace(addr)))
+}
+
+// acceptsInsecureRegistry returns whether CRI-O has a registries.conf drop-in for the registry, or gets it as a flag
+func (r *CRIO) acceptsInsecureRegistry(addr string) bool {
+	if _, err := r.Runner.RunCmd(command.Sudo("test", "-f", crioRegistryConf(addr))); err == nil {
+		return true
+	}
+	_, err := r.Runner.RunCmd(command.Sudo("grep", "-qF", "--", fmt.Sprintf("--insecure-registry %s ", addr), "/etc/sysconfig/crio.minikube"))
+	return err == nil
+}
+
+// ensureInsecureRegistry adds a registries.conf drop-in for the registry, and reloads CRI-O to pick it up
+func (r *CRIO) ensureInsecureRegistry(addr string) error {
+	conf := crioRegistryConf(addr)
+	if r.acceptsInsecureRegistry(addr) {
+		klog.Infof("crio already accepts the insecure registry %s", addr)
+		return nil
+	}
+	klog.Infof("adding %s to the insecure registries of crio", addr)
+	data := fmt.Sprintf("[[registry]]\nlocation = %q\ninsecure = true\n", addr)
+	if err := r.Runner.Copy(assets.NewMemoryAssetTarget([]byte(data), conf, "0644")); err != nil {
+		return errors.Wrapf(err, "writing %s", conf)
+	}
+	return r.Init.Reload("crio")
+}
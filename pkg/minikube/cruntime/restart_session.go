@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import "k8s.io/klog/v2"
+
+// restartSession collects the services whose configuration the steps of a start changed, such as Preload and Enable,
+// so that they are restarted once by ApplyPendingRestart instead of after each step
+type restartSession struct {
+	pending []string
+	// after are run once the services restarted, as they need the new configuration
+	after []func()
+}
+
+// newRestartSession returns a session if restarts are deferred, or nil for the runtime to restart its services right away
+func newRestartSession(deferred bool) *restartSession {
+	if !deferred {
+		return nil
+	}
+	return &restartSession{}
+}
+
+// require records that svc has to be restarted
+func (s *restartSession) require(svc string) {
+	if !s.requires(svc) {
+		s.pending = append(s.pending, svc)
+	}
+}
+
+// requires returns whether svc has to be restarted
+func (s *restartSession) requires(svc string) bool {
+	for _, p := range s.pending {
+		if p == svc {
+			return true
+		}
+	}
+	return false
+}
+
+// restartLater runs restart right away outside of a session, or records that svc has to be restarted in a session.
+// after is run once svc restarted, if not nil.
+func (s *restartSession) restartLater(svc string, restart func() error, after func()) error {
+	if s == nil {
+		if err := restart(); err != nil {
+			return err
+		}
+		if after != nil {
+			after()
+		}
+		return nil
+	}
+	klog.Infof("deferring the restart of %s", svc)
+	s.require(svc)
+	if after != nil {
+		s.after = append(s.after, after)
+	}
+	return nil
+}
+
+// afterRestarts runs f once the pending restarts are done, or right away if there are none
+func (s *restartSession) afterRestarts(f func()) {
+	if s == nil || len(s.pending) == 0 {
+		f()
+		return
+	}
+	s.after = append(s.after, f)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// undoStep is how to undo a step of enabling a runtime
+type undoStep struct {
+	name string
+	undo func() error
+}
+
+// rollback runs the steps of enabling a runtime, recording how to undo each of them
+type rollback struct {
+	undos []undoStep
+}
+
+// run runs a step, recording its undo action first, as a failed step may have been partially done.
+// undo must be safe to run whether or not the step had any effect, and may be nil.
+func (rb *rollback) run(name string, do func() error, undo func() error) error {
+	if undo != nil {
+		rb.undos = append(rb.undos, undoStep{name: name, undo: undo})
+	}
+	if err := do(); err != nil {
+		return errors.Wrap(err, name)
+	}
+	return nil
+}
+
+// fail undoes the steps which ran, newest first, and returns err.
+// Undoing is best effort: failures are logged together, and do not stop the other steps from being undone.
+func (rb *rollback) fail(err error) error {
+	if len(rb.undos) == 0 {
+		return err
+	}
+	klog.Warningf("rolling back after failure: %v", err)
+	failed := []string{}
+	for i := len(rb.undos) - 1; i >= 0; i-- {
+		u := rb.undos[i]
+		klog.Infof("rolling back: %s", u.name)
+		if uerr := u.undo(); uerr != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", u.name, uerr))
+		}
+	}
+	rb.undos = nil
+	if len(failed) > 0 {
+		klog.Warningf("rollback incomplete:\n  %s", strings.Join(failed, "\n  "))
+		return errors.Wrapf(err, "rollback incomplete (%s)", strings.Join(failed, "; "))
+	}
+	return errors.Wrap(err, "rolled back")
+}
+
+// backupFile returns how to restore a file to its current content, removing it if it does not exist
+func backupFile(cr CommandRunner, file string) func() error {
+	rr, err := cr.RunCmd(command.Sudo("cat", file))
+	if err != nil {
+		return func() error {
+			_, err := cr.RunCmd(command.Sudo("rm", "-f", file))
+			return err
+		}
+	}
+	data := rr.Stdout.Bytes()
+	return func() error {
+		return cr.Copy(assets.NewMemoryAsset(data, path.Dir(file), path.Base(file), "0644"))
+	}
+}
+
+// runtimeServices are the services of the runtimes which may be disabled for another, by Manager.Name
+var runtimeServices = map[string]string{
+	"containerd": "containerd",
+	"CRI-O":      "crio",
+	"Docker":     "docker",
+}
+
+// activeOthers returns the services of the runtimes other than me which are active
+func activeOthers(me Manager, cr CommandRunner) []string {
+	active := []string{}
+	for _, name := range []string{"containerd", "crio", "docker"} {
+		r, err := New(Config{Type: name, Runner: cr})
+		if err != nil || r.Name() == me.Name() {
+			continue
+		}
+		if r.Active() {
+			active = append(active, runtimeServices[r.Name()])
+		}
+	}
+	return active
+}
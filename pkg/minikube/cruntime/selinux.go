@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+const (
+	// SELinuxEnforcing, SELinuxPermissive and SELinuxDisabled are the modes of SELinux printed by getenforce
+	SELinuxEnforcing  = "Enforcing"
+	SELinuxPermissive = "Permissive"
+	SELinuxDisabled   = "Disabled"
+	// selinuxContainerType is the type of the files the containers may read and write
+	selinuxContainerType = "container_file_t"
+)
+
+// selinuxDirs are the directories of the node which kubeadm bind-mounts into the static pods
+var selinuxDirs = []string{
+	vmpath.GuestKubernetesCertsDir,
+	path.Join(vmpath.GuestPersistentDir, "etcd"),
+}
+
+// ErrSELinux is returned when SELinux enforces on the host, and the directories bind-mounted into the static pods are not labeled for containers
+type ErrSELinux struct {
+	// Dirs are the directories to label
+	Dirs []string
+}
+
+func (e *ErrSELinux) Error() string {
+	return fmt.Sprintf("SELinux is enforcing, and denies the containers access to %s", strings.Join(e.Dirs, ", "))
+}
+
+// Commands returns the commands labeling the directories for containers, as the user runs them
+func (e *ErrSELinux) Commands() []string {
+	cmds := []string{}
+	for _, dir := range e.Dirs {
+		cmds = append(cmds,
+			fmt.Sprintf("sudo mkdir -p %s", dir),
+			fmt.Sprintf("sudo semanage fcontext -a -t %s '%s(/.*)?'", selinuxContainerType, dir),
+			fmt.Sprintf("sudo restorecon -R %s", dir))
+	}
+	return cmds
+}
+
+// selinuxAction is what enabling docker does about SELinux
+type selinuxAction int
+
+const (
+	// selinuxIgnore leaves SELinux alone, as it does not deny anything or is not ours to configure
+	selinuxIgnore selinuxAction = iota
+	// selinuxRelabel enables SELinux in docker, and labels the directories bind-mounted into the static pods
+	selinuxRelabel
+	// selinuxFail fails before changing anything, telling the user how to label the directories
+	selinuxFail
+)
+
+// selinuxDecision returns what to do about the SELinux mode of the node: only the host of the bare metal drivers is
+// not set up by minikube, and only an enforcing SELinux denies the static pods access to their directories
+func selinuxDecision(mode string, bareMetal, relabel bool) selinuxAction {
+	if !bareMetal || mode != SELinuxEnforcing {
+		return selinuxIgnore
+	}
+	if relabel {
+		return selinuxRelabel
+	}
+	return selinuxFail
+}
+
+// selinuxMode returns the mode of SELinux printed by getenforce, disabled if SELinux is not installed
+func selinuxMode(cr CommandRunner) string {
+	rr, err := cr.RunCmd(exec.Command("getenforce"))
+	if err != nil {
+		klog.Infof("getenforce: %v, assuming SELinux is disabled", err)
+		return SELinuxDisabled
+	}
+	switch mode := strings.TrimSpace(rr.Stdout.String()); mode {
+	case SELinuxEnforcing, SELinuxPermissive:
+		return mode
+	default:
+		return SELinuxDisabled
+	}
+}
+
+// labelSELinuxDirs labels the directories bind-mounted into the static pods for containers, persistently with semanage
+// if installed, and else with chcon, which a relabeling of the whole filesystem undoes
+func labelSELinuxDirs(cr CommandRunner) error {
+	_, err := cr.RunCmd(exec.Command("which", "semanage"))
+	semanage := err == nil
+	for _, dir := range selinuxDirs {
+		// the files created later inherit the label of the directory
+		if _, err := cr.RunCmd(command.Sudo("mkdir", "-p", dir)); err != nil {
+			return errors.Wrapf(err, "creating %s", dir)
+		}
+		cmds := []*exec.Cmd{}
+		if semanage {
+			// -a fails if the rule exists, so that it is modified instead
+			rule := fmt.Sprintf("%s(/.*)?", dir)
+			if _, err := cr.RunCmd(command.Sudo("semanage", "fcontext", "-a", "-t", selinuxContainerType, rule)); err != nil {
+				cmds = append(cmds, command.Sudo("semanage", "fcontext", "-m", "-t", selinuxContainerType, rule))
+			}
+			cmds = append(cmds, command.Sudo("restorecon", "-R", dir))
+		} else {
+			klog.Warningf("semanage is not installed, labeling %s with chcon, which does not survive a relabeling of the filesystem", dir)
+			cmds = append(cmds, command.Sudo("chcon", "-R", "-t", selinuxContainerType, dir))
+		}
+		for _, c := range cmds {
+			if _, err := cr.RunCmd(c); err != nil {
+				return errors.Wrapf(err, "labeling %s for containers", dir)
+			}
+		}
+	}
+	return nil
+}
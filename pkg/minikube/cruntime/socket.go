@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+const (
+	// InternalDockerCRISocket is the socket of dockershim, built into kubelet before Kubernetes 1.24
+	InternalDockerCRISocket = "/var/run/dockershim.sock"
+	// ExternalDockerCRISocket is the socket of cri-dockerd, which replaces dockershim
+	ExternalDockerCRISocket = "/var/run/cri-dockerd.sock"
+	// ContainerdCRISocket is the default socket of containerd
+	ContainerdCRISocket = "/run/containerd/containerd.sock"
+	// CRIOCRISocket is the default socket of CRI-O
+	CRIOCRISocket = "/var/run/crio/crio.sock"
+)
+
+// dockershimRemoved is the first Kubernetes version without dockershim
+var dockershimRemoved = semver.MustParse("1.24.0-alpha.0")
+
+// DefaultCRISocket returns the CRI socket which a runtime serves for a Kubernetes version
+func DefaultCRISocket(runtime string, kv semver.Version) string {
+	switch runtime {
+	case "", "docker":
+		if kv.GTE(dockershimRemoved) {
+			return ExternalDockerCRISocket
+		}
+		return InternalDockerCRISocket
+	case "crio", "cri-o":
+		return CRIOCRISocket
+	case "containerd":
+		return ContainerdCRISocket
+	}
+	return ""
+}
+
+// CRISocket returns the CRI socket to use with a runtime for a Kubernetes version:
+// the requested socket if it is still served, and the default socket otherwise.
+// This migrates the dockershim socket to cri-dockerd on Kubernetes 1.24 and later.
+func CRISocket(runtime string, socket string, kv semver.Version) string {
+	if socket == "" {
+		return DefaultCRISocket(runtime, kv)
+	}
+	if (runtime == "" || runtime == "docker") && SocketFile(socket) == InternalDockerCRISocket && kv.GTE(dockershimRemoved) {
+		return ExternalDockerCRISocket
+	}
+	return socket
+}
+
+// customDockerCRISocket returns whether a socket of docker is one chosen by the user for cri-dockerd, rather than a default one
+func customDockerCRISocket(socket string) bool {
+	f := SocketFile(socket)
+	return f != "" && f != InternalDockerCRISocket && f != ExternalDockerCRISocket
+}
+
+// SocketFile returns the path of a socket given either as a path or as a unix:// URL
+func SocketFile(socket string) string {
+	return strings.TrimPrefix(socket, "unix://")
+}
+
+// SocketURL returns a socket as a URL, which kubeadm requires from Kubernetes 1.24
+func SocketURL(socket string) string {
+	if socket == "" || strings.Contains(socket, "://") {
+		return socket
+	}
+	return "unix://" + socket
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+)
+
+// archiveExtRe matches the extensions of archives, which the temporary names keep
+var archiveExtRe = regexp.MustCompile(`\.tar(\.(gz|lz4|zst|bz2|xz))?$`)
+
+// NodeTempName returns the name of a temporary file or directory in a node, unique to this invocation of minikube,
+// so that the invocations running at once do not overwrite each other's files. It keeps the archive extension of name.
+func NodeTempName(name string) string {
+	dir, base := path.Split(name)
+	ext := archiveExtRe.FindString(base)
+	return dir + fmt.Sprintf("%s-%d%s", base[:len(base)-len(ext)], os.Getpid(), ext)
+}
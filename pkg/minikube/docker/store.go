@@ -20,6 +20,8 @@ import (
 	"encoding/json"
 	"os/exec"
 	"path"
+	"sort"
+	"strings"
 
 	"github.com/opencontainers/go-digest"
 	"k8s.io/klog/v2"
@@ -29,6 +31,7 @@ import (
 
 const (
 	referenceStorePath = "/var/lib/docker/image/overlay2/repositories.json"
+	imageContentPath   = "/var/lib/docker/image/overlay2/imagedb/content/sha256"
 )
 
 // Storage keeps track of reference stores
@@ -102,3 +105,106 @@ func (s *Storage) mergeReferenceStores() ReferenceStore {
 	}
 	return merged
 }
+
+// VerifyResult describes the changes made to repositories.json by Verify
+type VerifyResult struct {
+	// Removed are the references which pointed to missing image content
+	Removed []string
+	// Restored are the references recovered from the saved reference stores
+	Restored []string
+}
+
+// Repaired returns true if repositories.json was rewritten
+func (v VerifyResult) Repaired() bool {
+	return len(v.Removed) > 0 || len(v.Restored) > 0
+}
+
+// Verify cross-checks repositories.json against the image content known to the docker daemon.
+// References to missing images are removed, and references from the saved reference stores
+// whose image content still exists are restored. The docker daemon must be restarted to
+// pick up a repaired store.
+func (s *Storage) Verify() (VerifyResult, error) {
+	result := VerifyResult{}
+	rr, err := s.runner.RunCmd(exec.Command("sudo", "cat", referenceStorePath))
+	if err != nil {
+		klog.Infof("repositories.json doesn't exist: %v", err)
+		return result, nil
+	}
+	var current ReferenceStore
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &current); err != nil {
+		return result, err
+	}
+	if current.Repositories == nil {
+		current.Repositories = map[string]repository{}
+	}
+
+	rr, err = s.runner.RunCmd(exec.Command("sudo", "ls", imageContentPath))
+	if err != nil {
+		return result, err
+	}
+	content := map[string]bool{}
+	for _, id := range strings.Fields(rr.Stdout.String()) {
+		content[id] = true
+	}
+
+	result.Removed = removeDanglingReferences(current, content)
+	result.Restored = restoreReferences(current, s.refStores, content)
+	if !result.Repaired() {
+		return result, nil
+	}
+	klog.Warningf("repairing repositories.json: removed %v, restored %v", result.Removed, result.Restored)
+
+	contents, err := json.Marshal(current)
+	if err != nil {
+		return result, err
+	}
+	asset := assets.NewMemoryAsset(contents, path.Dir(referenceStorePath), path.Base(referenceStorePath), "0644")
+	return result, s.runner.Copy(asset)
+}
+
+// removeDanglingReferences removes references to images without content, returning the removed references
+func removeDanglingReferences(rs ReferenceStore, content map[string]bool) []string {
+	removed := []string{}
+	for name, repo := range rs.Repositories {
+		for ref, id := range repo {
+			if !content[contentID(id)] {
+				removed = append(removed, ref)
+				delete(repo, ref)
+			}
+		}
+		if len(repo) == 0 {
+			delete(rs.Repositories, name)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// restoreReferences adds missing references from the saved stores if their image content exists
+func restoreReferences(rs ReferenceStore, saved []ReferenceStore, content map[string]bool) []string {
+	restored := []string{}
+	for _, s := range saved {
+		for name, repo := range s.Repositories {
+			for ref, id := range repo {
+				if !content[contentID(id)] {
+					continue
+				}
+				if _, ok := rs.Repositories[name]; !ok {
+					rs.Repositories[name] = repository{}
+				}
+				if _, ok := rs.Repositories[name][ref]; ok {
+					continue
+				}
+				rs.Repositories[name][ref] = id
+				restored = append(restored, ref)
+			}
+		}
+	}
+	sort.Strings(restored)
+	return restored
+}
+
+// contentID returns the name of the imagedb content file for an image ID
+func contentID(id digest.Digest) string {
+	return strings.TrimPrefix(string(id), digest.SHA256.String()+":")
+}
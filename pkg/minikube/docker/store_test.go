@@ -17,9 +17,15 @@ limitations under the License.
 package docker
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
 )
 
 func TestMergeReferenceStores(t *testing.T) {
@@ -73,3 +79,87 @@ func TestMergeReferenceStores(t *testing.T) {
 		t.Errorf("Actual: %v, Expected: %v, Diff: %s", actual, expected, diff)
 	}
 }
+
+func readReferenceStore(t *testing.T, name string) (string, ReferenceStore) {
+	t.Helper()
+	contents, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	var rs ReferenceStore
+	if err := json.Unmarshal(contents, &rs); err != nil {
+		t.Fatalf("parsing fixture: %v", err)
+	}
+	return string(contents), rs
+}
+
+func TestVerify(t *testing.T) {
+	current, _ := readReferenceStore(t, "repositories-dangling.json")
+	_, preload := readReferenceStore(t, "repositories-preload.json")
+	content := []string{
+		"6270bb605e12e581514ada5fd5b3216f727db55dc87d5889c790e4c760683fee",
+		"a4ca41631cc7ac19ce1be3ebf0314ac5f47af7c711f17066006db82ee3b75b03",
+		"97801f83949087fbdcc09b1c84ddda0ed5d01f4aabd17787a7714eb2796082b3",
+	}
+
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo cat " + referenceStorePath: current,
+		"sudo ls " + imageContentPath:    strings.Join(content, "\n"),
+	})
+	s := NewStorage(runner)
+	s.refStores = []ReferenceStore{preload}
+
+	result, err := s.Verify()
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	expected := VerifyResult{
+		Removed:  []string{"registry.k8s.io/etcd:3.5.4-0"},
+		Restored: []string{"registry.k8s.io/kube-apiserver:v1.25.2"},
+	}
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("Verify() diff (-want +got):\n%s", diff)
+	}
+
+	written, err := runner.GetFileToContents(assets.MemorySource)
+	if err != nil {
+		t.Fatalf("repositories.json was not rewritten: %v", err)
+	}
+	var actual ReferenceStore
+	if err := json.Unmarshal([]byte(written), &actual); err != nil {
+		t.Fatalf("parsing rewritten store: %v", err)
+	}
+	if _, ok := actual.Repositories["registry.k8s.io/etcd"]; ok {
+		t.Errorf("dangling repository registry.k8s.io/etcd was not removed")
+	}
+	if len(actual.Repositories["registry.k8s.io/coredns/coredns"]) != 2 {
+		t.Errorf("expected coredns references to be kept, got %v", actual.Repositories["registry.k8s.io/coredns/coredns"])
+	}
+	if _, ok := actual.Repositories["registry.k8s.io/kube-apiserver"]["registry.k8s.io/kube-apiserver:v1.25.2"]; !ok {
+		t.Errorf("expected kube-apiserver reference to be restored, got %v", actual.Repositories)
+	}
+}
+
+func TestVerifyNoChanges(t *testing.T) {
+	_, preload := readReferenceStore(t, "repositories-preload.json")
+	current, _ := readReferenceStore(t, "repositories-preload.json")
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo cat " + referenceStorePath: current,
+		"sudo ls " + imageContentPath:    "6270bb605e12e581514ada5fd5b3216f727db55dc87d5889c790e4c760683fee 97801f83949087fbdcc09b1c84ddda0ed5d01f4aabd17787a7714eb2796082b3",
+	})
+	s := NewStorage(runner)
+	s.refStores = []ReferenceStore{preload}
+
+	result, err := s.Verify()
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if result.Repaired() {
+		t.Errorf("expected no repairs, got %+v", result)
+	}
+	if _, err := runner.GetFileToContents(assets.MemorySource); err == nil {
+		t.Errorf("repositories.json should not be rewritten when nothing changed")
+	}
+}
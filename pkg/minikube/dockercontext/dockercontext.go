@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dockercontext manages the docker CLI contexts of the host, pointing at the docker daemons of clusters
+package dockercontext
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+// dockerEndpoint is the name of the endpoint of a context which the docker CLI connects to
+const dockerEndpoint = "docker"
+
+var (
+	// lookPath finds the docker CLI, which creates the contexts if available
+	lookPath = exec.LookPath
+	// runDocker runs the docker CLI
+	runDocker = func(bin string, args ...string) ([]byte, error) {
+		return exec.Command(bin, args...).CombinedOutput()
+	}
+)
+
+// Context is a docker CLI context pointing at the daemon of a node
+type Context struct {
+	// Name is the name of the context, such as the name of the profile
+	Name string
+	// Description is shown by 'docker context ls'
+	Description string
+	// Endpoint is the daemon the context points at
+	Endpoint cruntime.DaemonEndpoint
+}
+
+// metadata is the content of the meta.json file of a context, as the docker CLI writes it
+type metadata struct {
+	Name      string
+	Metadata  contextMetadata
+	Endpoints map[string]endpointMetadata
+}
+
+// contextMetadata is the metadata of a context which is specific to the docker CLI
+type contextMetadata struct {
+	Description string `json:",omitempty"`
+}
+
+// endpointMetadata is the metadata of the docker endpoint of a context
+type endpointMetadata struct {
+	Host          string `json:",omitempty"`
+	SkipTLSVerify bool
+}
+
+// ConfigDir returns the configuration directory of the docker CLI, which holds its contexts
+func ConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		klog.Warningf("unable to find the home directory: %v", err)
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// contextID returns the name of the directories of a context, as the docker CLI names them
+func contextID(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+// metaDir returns the directory of the meta.json file of a context
+func metaDir(dir, name string) string {
+	return filepath.Join(dir, "contexts", "meta", contextID(name))
+}
+
+// tlsDir returns the directory of the TLS material of a context
+func tlsDir(dir, name string) string {
+	return filepath.Join(dir, "contexts", "tls", contextID(name))
+}
+
+// Exists returns whether the context exists in the configuration directory of the docker CLI
+func Exists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(metaDir(dir, name), "meta.json"))
+	return err == nil
+}
+
+// Create idempotently creates the context in the configuration directory of the docker CLI, updating it if it exists.
+// It uses the docker CLI if available, and writes the files of the context otherwise.
+func Create(dir string, c Context) error {
+	bin, err := lookPath("docker")
+	if err != nil {
+		klog.Infof("docker CLI not found, writing the files of the %q context: %v", c.Name, err)
+		return write(dir, c)
+	}
+	action := "create"
+	if Exists(dir, c.Name) {
+		action = "update"
+	}
+	args := []string{"--config", dir, "context", action, c.Name, "--description", c.Description, "--docker", dockerOption(c.Endpoint)}
+	if out, err := runDocker(bin, args...); err != nil {
+		return errors.Wrapf(err, "docker context %s: %s", action, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// dockerOption returns the value of the --docker option of 'docker context create' pointing at the endpoint
+func dockerOption(e cruntime.DaemonEndpoint) string {
+	opts := []string{"host=" + e.Host}
+	if ca, cert, key := e.TLSFiles(); ca != "" {
+		opts = append(opts, "ca="+ca, "cert="+cert, "key="+key)
+	}
+	return strings.Join(opts, ",")
+}
+
+// write writes the meta.json file of the context and copies its TLS material, as the docker CLI does
+func write(dir string, c Context) error {
+	m := metadata{
+		Name:      c.Name,
+		Metadata:  contextMetadata{Description: c.Description},
+		Endpoints: map[string]endpointMetadata{dockerEndpoint: {Host: c.Endpoint.Host}},
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "marshal context")
+	}
+	md := metaDir(dir, c.Name)
+	if err := os.MkdirAll(md, 0755); err != nil {
+		return errors.Wrap(err, "create context directory")
+	}
+	if err := os.WriteFile(filepath.Join(md, "meta.json"), data, 0644); err != nil {
+		return errors.Wrap(err, "write context")
+	}
+
+	// the TLS material of a previous endpoint must not be left over
+	td := tlsDir(dir, c.Name)
+	if err := os.RemoveAll(td); err != nil {
+		return errors.Wrap(err, "remove context TLS material")
+	}
+	ca, cert, key := c.Endpoint.TLSFiles()
+	if ca == "" {
+		return nil
+	}
+	ed := filepath.Join(td, dockerEndpoint)
+	if err := os.MkdirAll(ed, 0700); err != nil {
+		return errors.Wrap(err, "create context TLS directory")
+	}
+	for _, f := range []struct {
+		src  string
+		name string
+		perm os.FileMode
+	}{{ca, "ca.pem", 0644}, {cert, "cert.pem", 0644}, {key, "key.pem", 0600}} {
+		data, err := os.ReadFile(f.src)
+		if err != nil {
+			return errors.Wrap(err, "read TLS material")
+		}
+		if err := os.WriteFile(filepath.Join(ed, f.name), data, f.perm); err != nil {
+			return errors.Wrap(err, "write TLS material")
+		}
+	}
+	return nil
+}
+
+// Remove removes the context from the configuration directory of the docker CLI, if it exists.
+// The docker CLI goes back to its default context if the context was the current one.
+func Remove(dir, name string) error {
+	if !Exists(dir, name) {
+		return nil
+	}
+	if bin, err := lookPath("docker"); err == nil {
+		if out, err := runDocker(bin, "--config", dir, "context", "rm", "--force", name); err != nil {
+			return errors.Wrapf(err, "docker context rm: %s", strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	if err := os.RemoveAll(metaDir(dir, name)); err != nil {
+		return errors.Wrap(err, "remove context")
+	}
+	if err := os.RemoveAll(tlsDir(dir, name)); err != nil {
+		return errors.Wrap(err, "remove context TLS material")
+	}
+	return unsetCurrent(dir, name)
+}
+
+// unsetCurrent makes the docker CLI use its default context if name is the current one, keeping the rest of its configuration
+func unsetCurrent(dir, name string) error {
+	path := filepath.Join(dir, "config.json")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "read docker config")
+	}
+	cfg := map[string]interface{}{}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return errors.Wrap(err, "parse docker config")
+	}
+	if cfg["currentContext"] != name {
+		return nil
+	}
+	delete(cfg, "currentContext")
+	data, err = json.MarshalIndent(cfg, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "marshal docker config")
+	}
+	return os.WriteFile(path, data, 0600)
+}
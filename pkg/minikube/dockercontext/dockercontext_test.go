@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockercontext
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+// withoutCLI makes the docker CLI missing for the test
+func withoutCLI(t *testing.T) {
+	orig := lookPath
+	lookPath = func(string) (string, error) { return "", fmt.Errorf("docker: not found") }
+	t.Cleanup(func() { lookPath = orig })
+}
+
+// withCLI makes the docker CLI available for the test, recording its command lines
+func withCLI(t *testing.T) *[]string {
+	origLook, origRun := lookPath, runDocker
+	calls := []string{}
+	lookPath = func(string) (string, error) { return "docker", nil }
+	runDocker = func(bin string, args ...string) ([]byte, error) {
+		calls = append(calls, strings.Join(append([]string{bin}, args...), " "))
+		return nil, nil
+	}
+	t.Cleanup(func() { lookPath, runDocker = origLook, origRun })
+	return &calls
+}
+
+// certsDir returns a directory with the client certificates of a daemon
+func certsDir(t *testing.T) string {
+	dir := t.TempDir()
+	for _, f := range []string{"ca.pem", "cert.pem", "key.pem"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte(f), 0600); err != nil {
+			t.Fatalf("write %s: %v", f, err)
+		}
+	}
+	return dir
+}
+
+func readMeta(t *testing.T, dir, name string) metadata {
+	data, err := os.ReadFile(filepath.Join(metaDir(dir, name), "meta.json"))
+	if err != nil {
+		t.Fatalf("read meta.json: %v", err)
+	}
+	m := metadata{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("parse meta.json: %v", err)
+	}
+	return m
+}
+
+func TestCreateWithoutCLI(t *testing.T) {
+	withoutCLI(t)
+	dir := t.TempDir()
+	tcp := Context{Name: "minikube", Description: "minikube", Endpoint: cruntime.DaemonEndpoint{Host: "tcp://192.168.49.2:2376", TLSDir: certsDir(t)}}
+	ssh := Context{Name: "minikube", Description: "minikube", Endpoint: cruntime.DaemonEndpoint{Host: "ssh://docker@127.0.0.1:32772"}}
+
+	if err := Create(dir, tcp); err != nil {
+		t.Fatalf("Create(tcp): %v", err)
+	}
+	m := readMeta(t, dir, "minikube")
+	if m.Name != "minikube" || m.Endpoints[dockerEndpoint].Host != tcp.Endpoint.Host {
+		t.Errorf("meta.json = %+v, want the endpoint %s", m, tcp.Endpoint.Host)
+	}
+	key := filepath.Join(tlsDir(dir, "minikube"), dockerEndpoint, "key.pem")
+	if data, err := os.ReadFile(key); err != nil || string(data) != "key.pem" {
+		t.Errorf("key.pem = %q, %v, want the key of the daemon", data, err)
+	}
+
+	// creating the context again points it at the new endpoint, without the TLS material of the previous one
+	if err := Create(dir, ssh); err != nil {
+		t.Fatalf("Create(ssh): %v", err)
+	}
+	if got := readMeta(t, dir, "minikube").Endpoints[dockerEndpoint].Host; got != ssh.Endpoint.Host {
+		t.Errorf("host = %s, want %s", got, ssh.Endpoint.Host)
+	}
+	if _, err := os.Stat(tlsDir(dir, "minikube")); !os.IsNotExist(err) {
+		t.Errorf("the TLS material of the previous endpoint is left over: %v", err)
+	}
+	if err := Create(dir, ssh); err != nil {
+		t.Errorf("Create(ssh) again: %v", err)
+	}
+}
+
+func TestCreateWithCLI(t *testing.T) {
+	calls := withCLI(t)
+	dir := t.TempDir()
+	certs := certsDir(t)
+	tcp := Context{Name: "p1", Description: "minikube p1", Endpoint: cruntime.DaemonEndpoint{Host: "tcp://192.168.49.2:2376", TLSDir: certs}}
+	ssh := Context{Name: "p1", Description: "minikube p1", Endpoint: cruntime.DaemonEndpoint{Host: "ssh://docker@127.0.0.1:32772"}}
+
+	if err := Create(dir, tcp); err != nil {
+		t.Fatalf("Create(tcp): %v", err)
+	}
+	// the docker CLI would have written the context
+	if err := os.MkdirAll(metaDir(dir, "p1"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(metaDir(dir, "p1"), "meta.json"), []byte(`{"Name":"p1"}`), 0644); err != nil {
+		t.Fatalf("write meta.json: %v", err)
+	}
+	if err := Create(dir, ssh); err != nil {
+		t.Fatalf("Create(ssh): %v", err)
+	}
+	want := []string{
+		fmt.Sprintf("docker --config %s context create p1 --description minikube p1 --docker host=tcp://192.168.49.2:2376,ca=%s,cert=%s,key=%s",
+			dir, filepath.Join(certs, "ca.pem"), filepath.Join(certs, "cert.pem"), filepath.Join(certs, "key.pem")),
+		fmt.Sprintf("docker --config %s context update p1 --description minikube p1 --docker host=ssh://docker@127.0.0.1:32772", dir),
+	}
+	if strings.Join(*calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("docker commands = %q, want %q", *calls, want)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	withoutCLI(t)
+	dir := t.TempDir()
+	if err := Remove(dir, "minikube"); err != nil {
+		t.Errorf("Remove of a missing context: %v", err)
+	}
+	if err := Create(dir, Context{Name: "minikube", Endpoint: cruntime.DaemonEndpoint{Host: "tcp://192.168.49.2:2376", TLSDir: certsDir(t)}}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	config := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(config, []byte(`{"currentContext":"minikube","auths":{}}`), 0600); err != nil {
+		t.Fatalf("write config.json: %v", err)
+	}
+	if err := Remove(dir, "minikube"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if Exists(dir, "minikube") {
+		t.Errorf("the context still exists")
+	}
+	if _, err := os.Stat(tlsDir(dir, "minikube")); !os.IsNotExist(err) {
+		t.Errorf("the TLS material is left over: %v", err)
+	}
+	data, err := os.ReadFile(config)
+	if err != nil {
+		t.Fatalf("read config.json: %v", err)
+	}
+	if strings.Contains(string(data), "currentContext") || !strings.Contains(string(data), "auths") {
+		t.Errorf("config.json = %s, want the configuration without the current context", data)
+	}
+}
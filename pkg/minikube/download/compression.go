@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package download
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/pkg/errors"
+)
+
+// Compression is the compression of a tarball
+type Compression string
+
+const (
+	// CompressionNone is an uncompressed tarball
+	CompressionNone Compression = "none"
+	// CompressionLZ4 is a tarball compressed with lz4, which the preload bucket serves
+	CompressionLZ4 Compression = "lz4"
+	// CompressionZstd is a tarball compressed with zstd
+	CompressionZstd Compression = "zstd"
+)
+
+// PreloadCompressions are the compressions of the preload tarballs, in order of preference
+var PreloadCompressions = []Compression{CompressionZstd, CompressionLZ4}
+
+var (
+	compressionExtensions = map[Compression]string{
+		CompressionNone: ".tar",
+		CompressionLZ4:  ".tar.lz4",
+		CompressionZstd: ".tar.zst",
+	}
+	compressionMagics = map[Compression][]byte{
+		CompressionLZ4:  {0x04, 0x22, 0x4d, 0x18},
+		CompressionZstd: {0x28, 0xb5, 0x2f, 0xfd},
+	}
+)
+
+// Extension returns the extension of the name of a tarball with the compression
+func (c Compression) Extension() string {
+	return compressionExtensions[c]
+}
+
+// Binary returns the program decompressing the tarball, or an empty string if it is not compressed
+func (c Compression) Binary() string {
+	if c == CompressionNone {
+		return ""
+	}
+	return string(c)
+}
+
+// TarFlags returns the flags of tar creating or extracting a tarball with the compression
+func (c Compression) TarFlags() []string {
+	switch c {
+	case CompressionLZ4:
+		return []string{"-I", "lz4"}
+	case CompressionZstd:
+		return []string{"--zstd"}
+	}
+	return nil
+}
+
+// CompressionFromName returns the compression of a tarball from the extension of its name
+func CompressionFromName(name string) (Compression, bool) {
+	for c, ext := range compressionExtensions {
+		if strings.HasSuffix(name, ext) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// CompressionFromMagic returns the compression of a tarball from its first bytes
+func CompressionFromMagic(header []byte) (Compression, bool) {
+	for c, magic := range compressionMagics {
+		if bytes.HasPrefix(header, magic) {
+			return c, true
+		}
+	}
+	// uncompressed tarballs have the ustar magic at the end of the header of their first member
+	if len(header) >= 262 && bytes.HasPrefix(header[257:], []byte("ustar")) {
+		return CompressionNone, true
+	}
+	return "", false
+}
+
+// DetectCompression returns the compression of the tarball at path, from its first bytes or else from its name
+func DetectCompression(path string) (Compression, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.Wrap(err, "opening tarball")
+	}
+	defer f.Close()
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", errors.Wrap(err, "reading tarball")
+	}
+	if c, ok := CompressionFromMagic(header[:n]); ok {
+		return c, nil
+	}
+	if c, ok := CompressionFromName(path); ok {
+		return c, nil
+	}
+	return "", errors.Errorf("unknown compression of %s", path)
+}
+
+// decompress returns the reader of the tarball decompressed from r, and the function releasing it
+func decompress(r io.Reader, c Compression) (io.Reader, func(), error) {
+	switch c {
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "zstd reader")
+		}
+		return zr, zr.Close, nil
+	case CompressionLZ4:
+		return lz4.NewReader(r), func() {}, nil
+	}
+	return r, func() {}, nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package download
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompressionFromName(t *testing.T) {
+	var tests = []struct {
+		name string
+		want Compression
+		ok   bool
+	}{
+		{"preloaded-images-k8s-v18-v1.24.1-docker-overlay2-amd64.tar.lz4", CompressionLZ4, true},
+		{"preloaded-images-k8s-v18-v1.24.1-docker-overlay2-amd64.tar.zst", CompressionZstd, true},
+		{"v1.25.0-rc.1-docker-default-amd64.tar", CompressionNone, true},
+		{"preloaded.tar.gz", "", false},
+	}
+	for _, tc := range tests {
+		got, ok := CompressionFromName(tc.name)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("CompressionFromName(%s) = %q, %v, want %q, %v", tc.name, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCompressionFromMagic(t *testing.T) {
+	var uncompressed bytes.Buffer
+	tw := tar.NewWriter(&uncompressed)
+	if err := tw.WriteHeader(&tar.Header{Name: "lib", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		description string
+		header      []byte
+		want        Compression
+		ok          bool
+	}{
+		{"lz4", []byte{0x04, 0x22, 0x4d, 0x18, 0x64, 0x40}, CompressionLZ4, true},
+		{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x00}, CompressionZstd, true},
+		{"uncompressed", uncompressed.Bytes(), CompressionNone, true},
+		{"gzip", []byte{0x1f, 0x8b, 0x08, 0x00}, "", false},
+		{"empty", nil, "", false},
+	}
+	for _, tc := range tests {
+		got, ok := CompressionFromMagic(tc.header)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("CompressionFromMagic(%s) = %q, %v, want %q, %v", tc.description, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestDetectCompression(t *testing.T) {
+	dir := t.TempDir()
+	var tests = []struct {
+		description string
+		name        string
+		content     []byte
+		want        Compression
+		wantErr     bool
+	}{
+		{"magic", "preloaded.tar.lz4", []byte{0x28, 0xb5, 0x2f, 0xfd}, CompressionZstd, false},
+		{"extension", "preloaded.tar.lz4", []byte("truncated"), CompressionLZ4, false},
+		{"unknown", "preloaded", []byte("truncated"), "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			p := filepath.Join(dir, tc.description+"-"+tc.name)
+			if err := os.WriteFile(p, tc.content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := DetectCompression(p)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("DetectCompression() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("DetectCompression() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompressedTarballName(t *testing.T) {
+	lz4 := TarballName("v1.24.1", "docker")
+	zst := CompressedTarballName("v1.24.1", "docker", CompressionZstd)
+	if c, _ := CompressionFromName(lz4); c != CompressionLZ4 {
+		t.Errorf("TarballName() = %s, want a lz4 tarball", lz4)
+	}
+	if c, _ := CompressionFromName(zst); c != CompressionZstd {
+		t.Errorf("CompressedTarballName(zstd) = %s, want a zstd tarball", zst)
+	}
+}
@@ -19,10 +19,15 @@ package download
 import (
 	"fmt"
 	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/spf13/viper"
 	"k8s.io/minikube/pkg/minikube/constants"
 )
 
@@ -236,3 +241,148 @@ func testPreloadExistsCaching(t *testing.T) {
 		t.Errorf("Expected preload to exist and no check to be performed. Existence: %v, Check: %v", existence, checkCalled)
 	}
 }
+
+func TestTrivyWithChecksumURL(t *testing.T) {
+	got, err := trivyWithChecksumURL("0.36.1", "arm64")
+	if err != nil {
+		t.Fatalf("trivyWithChecksumURL: %v", err)
+	}
+	want := "https://github.com/aquasecurity/trivy/releases/download/v0.36.1/trivy_0.36.1_Linux-ARM64.tar.gz?checksum=file:https://github.com/aquasecurity/trivy/releases/download/v0.36.1/trivy_0.36.1_checksums.txt"
+	if got != want {
+		t.Errorf("trivyWithChecksumURL() = %q, want %q", got, want)
+	}
+	if _, err := trivyWithChecksumURL("0.36.1", "mips64le"); err == nil {
+		t.Errorf("trivyWithChecksumURL(mips64le) returned no error")
+	}
+}
+
+func TestPreloadSource(t *testing.T) {
+	const k8sVersion = "v1.25.0"
+	const sum = "sha256:4d7b5e2b8d3a0e0f1c9c2a7e5f3b6d8a1c0e9f7b5d3a1c2e4f6a8b0d2c4e6f8a"
+	containerd := fmt.Sprintf("/%s/%s/%s", PreloadVersion, k8sVersion, TarballName(k8sVersion, constants.Containerd))
+	docker := fmt.Sprintf("/%s/%s/%s", PreloadVersion, k8sVersion, TarballName(k8sVersion, constants.Docker))
+	serve := func(paths ...string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, p := range paths {
+				if r.URL.Path == p {
+					return
+				}
+			}
+			http.NotFound(w, r)
+		}))
+	}
+	mirror := serve(containerd)
+	defer mirror.Close()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(containerd)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, containerd), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	local := "file://" + filepath.ToSlash(dir)
+	bucket := serve(containerd, docker)
+	defer bucket.Close()
+
+	oldBase, oldRemote, oldExists := defaultPreloadBaseURL, checkRemotePreloadExists, checkPreloadExists
+	oldChecksum, oldValid := getChecksum, ensureChecksumValid
+	defer func() {
+		defaultPreloadBaseURL, checkRemotePreloadExists, checkPreloadExists = oldBase, oldRemote, oldExists
+		getChecksum, ensureChecksumValid = oldChecksum, oldValid
+		DownloadMock = nil
+		SetPreloadSource(PreloadSource{})
+	}()
+	t.Setenv("MINIKUBE_HOME", t.TempDir())
+	viper.Set("preload", true)
+	defer viper.Set("preload", nil)
+	defaultPreloadBaseURL = func() string { return bucket.URL }
+	checkRemotePreloadExists = remotePreloadExists
+	checkPreloadExists = PreloadExists
+	getChecksum = func(k8sVersion, containerRuntime string) ([]byte, error) { return []byte{0xca, 0xfe}, nil }
+	ensureChecksumValid = func(k8sVersion, containerRuntime, path string, checksum []byte) error { return nil }
+	checkCache = func(file string) (fs.FileInfo, error) { return nil, fmt.Errorf("cache not found") }
+	var downloaded []string
+	DownloadMock = func(src, dst string) error {
+		downloaded = append(downloaded, src)
+		return CreateDstDownloadMock(src, dst)
+	}
+
+	var tests = []struct {
+		description string
+		source      PreloadSource
+		runtime     string
+		exists      bool
+		want        string
+	}{
+		{"mirror", PreloadSource{BaseURL: mirror.URL + "/"}, constants.Containerd, true, mirror.URL + containerd + "?checksum=file:" + mirror.URL + containerd + ".sha256"},
+		{"missing from mirror", PreloadSource{BaseURL: mirror.URL}, constants.Docker, false, ""},
+		{"fallback", PreloadSource{BaseURL: mirror.URL, Fallback: true}, constants.Docker, true, bucket.URL + docker + "?checksum=md5:cafe"},
+		{"checksum", PreloadSource{BaseURL: mirror.URL, Checksum: sum}, constants.Containerd, true, mirror.URL + containerd + "?checksum=" + sum},
+		{"checksum of another version", PreloadSource{BaseURL: mirror.URL, Checksum: sum, ChecksumVersion: "v1.24.0"}, constants.Containerd, true, mirror.URL + containerd + "?checksum=file:" + mirror.URL + containerd + ".sha256"},
+		{"file mirror", PreloadSource{BaseURL: local}, constants.Containerd, true, local + containerd + "?checksum=file:" + local + containerd + ".sha256"},
+		{"missing from file mirror", PreloadSource{BaseURL: local, Fallback: true}, constants.Docker, true, bucket.URL + docker + "?checksum=md5:cafe"},
+		{"skip checksum", PreloadSource{BaseURL: mirror.URL, Checksum: PreloadChecksumSkip}, constants.Containerd, true, mirror.URL + containerd},
+		{"default", PreloadSource{}, constants.Docker, true, bucket.URL + docker + "?checksum=md5:cafe"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			SetPreloadSource(PreloadSource{})
+			SetPreloadSource(tc.source)
+			downloaded = nil
+			if got := PreloadExists(k8sVersion, tc.runtime, "docker", true); got != tc.exists {
+				t.Errorf("PreloadExists() = %v, want %v", got, tc.exists)
+			}
+			if err := Preload(k8sVersion, tc.runtime, "docker"); err != nil {
+				t.Fatalf("Preload: %v", err)
+			}
+			want := []string{}
+			if tc.want != "" {
+				want = []string{tc.want}
+			}
+			if len(downloaded) != len(want) || (len(want) == 1 && downloaded[0] != want[0]) {
+				t.Errorf("Preload() downloaded %q, want %q", downloaded, want)
+			}
+		})
+	}
+}
+
+func TestValidatePreloadChecksum(t *testing.T) {
+	var tests = []struct {
+		checksum string
+		valid    bool
+	}{
+		{"", true},
+		{"skip", true},
+		{"sha256:4d7b5e2b8d3a0e0f1c9c2a7e5f3b6d8a1c0e9f7b5d3a1c2e4f6a8b0d2c4e6f8a", true},
+		{"sha256:4d7b", false},
+		{"md5:cafe", false},
+		{"4d7b5e2b8d3a0e0f1c9c2a7e5f3b6d8a1c0e9f7b5d3a1c2e4f6a8b0d2c4e6f8a", false},
+	}
+	for _, tc := range tests {
+		if err := ValidatePreloadChecksum(tc.checksum); (err == nil) != tc.valid {
+			t.Errorf("ValidatePreloadChecksum(%q) = %v, want valid %v", tc.checksum, err, tc.valid)
+		}
+	}
+}
+
+func TestImageInDaemonOutput(t *testing.T) {
+	digest := "sha256:18eb69d1418e854ad5a19e399310e52808a8321e4c441c1dddad8977a0d7a944"
+	output := "busybox:1.35@<none>\nlocalhost:5000/myimage:latest@<none>\ngcr.io/k8s-minikube/kicbase:v0.0.35@" + digest + "\n"
+	tests := []struct {
+		img  string
+		want bool
+	}{
+		{"docker.io/library/busybox:1.35", true},
+		{"busybox:1.36", false},
+		{"localhost:5000/myimage", true},
+		{"docker.io/localhost:5000/myimage", false},
+		{"gcr.io/k8s-minikube/kicbase:v0.0.35@" + digest, true},
+		{"gcr.io/k8s-minikube/kicbase:v0.0.35", true},
+		{"gcr.io/k8s-minikube/kicbase:v0.0.34", false},
+	}
+	for _, tc := range tests {
+		if got := imageInDaemonOutput(output, tc.img); got != tc.want {
+			t.Errorf("imageInDaemonOutput(%q) = %v, want %v", tc.img, got, tc.want)
+		}
+	}
+}
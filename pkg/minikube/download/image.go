@@ -75,11 +75,9 @@ func ImageExistsInDaemon(img string) bool {
 	// Check if image exists locally
 	klog.Infof("Checking for %s in local docker daemon", img)
 	cmd := exec.Command("docker", "images", "--format", "{{.Repository}}:{{.Tag}}@{{.Digest}}")
-	if output, err := cmd.Output(); err == nil {
-		if strings.Contains(string(output), image.TrimDockerIO(img)) {
-			klog.Infof("Found %s in local docker daemon, skipping pull", img)
-			return true
-		}
+	if output, err := cmd.Output(); err == nil && imageInDaemonOutput(string(output), img) {
+		klog.Infof("Found %s in local docker daemon, skipping pull", img)
+		return true
 	}
 	// Else, pull it
 	return false
@@ -87,6 +85,30 @@ func ImageExistsInDaemon(img string) bool {
 
 var checkImageExistsInDaemon = ImageExistsInDaemon
 
+// imageInDaemonOutput returns whether img is among the images listed by docker as repository:tag@digest
+func imageInDaemonOutput(output, img string) bool {
+	want, err := image.NormalizeReference(img)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(output, "\n") {
+		repoTag, digest, _ := strings.Cut(strings.TrimSpace(line), "@")
+		if repoTag == "" {
+			continue
+		}
+		repo := repoTag
+		if i := strings.LastIndex(repoTag, ":"); i != -1 {
+			repo = repoTag[:i]
+		}
+		for _, ref := range []string{repoTag, repo + "@" + digest} {
+			if n, err := image.NormalizeReference(ref); err == nil && n == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // ImageToCache downloads img (if not present in cache) and writes it to the local cache directory
 func ImageToCache(img string) error {
 	f := imagePathInCache(img)
@@ -0,0 +1,278 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package download
+
+import (
+	"archive/tar"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/detect"
+)
+
+// LocalPreloadFlag is the name of the flag enabling the generation and use of local preloads
+const LocalPreloadFlag = "local-preload"
+
+// LocalPreload is a preload generated from the images of a running node, for when no official preload applies
+type LocalPreload struct {
+	Name string
+	Path string
+	Size int64
+}
+
+// LocalPreloadEnabled returns whether local preloads are generated and used
+func LocalPreloadEnabled() bool {
+	if viper.IsSet("preload") && !viper.GetBool("preload") {
+		return false
+	}
+	return !viper.IsSet(LocalPreloadFlag) || viper.GetBool(LocalPreloadFlag)
+}
+
+// returns the dir of the local preloads, apart from the official ones which CleanUpOlderPreloads manages
+func localPreloadDir() string {
+	return filepath.Join(targetDir(), "local")
+}
+
+// localPreloadCompressions are the compressions of the local preloads, in order of preference
+var localPreloadCompressions = []Compression{CompressionZstd, CompressionNone}
+
+// LocalPreloadName returns the name of the local preload with the compression c for a Kubernetes version, runtime and image repository
+func LocalPreloadName(k8sVersion, containerRuntime, imageRepository string, c Compression) string {
+	repo := "default"
+	if imageRepository != "" {
+		repo = strings.NewReplacer("/", "_", ":", "_").Replace(imageRepository)
+	}
+	return fmt.Sprintf("%s-%s-%s-%s%s", k8sVersion, containerRuntime, repo, detect.EffectiveArch(), c.Extension())
+}
+
+// LocalPreloadPath returns the path to the local preload for a Kubernetes version, runtime and image repository.
+// It is the compressed one if both exist, and the uncompressed one if none does.
+func LocalPreloadPath(k8sVersion, containerRuntime, imageRepository string) string {
+	for _, c := range localPreloadCompressions {
+		p := filepath.Join(localPreloadDir(), LocalPreloadName(k8sVersion, containerRuntime, imageRepository, c))
+		if _, err := os.Stat(p + ".checksum"); err == nil {
+			return p
+		}
+	}
+	return filepath.Join(localPreloadDir(), LocalPreloadName(k8sVersion, containerRuntime, imageRepository, CompressionNone))
+}
+
+// LocalPreloadArchiveName returns the name of the archive of an image in a local preload
+func LocalPreloadArchiveName(img string) string {
+	return strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(img)
+}
+
+// LocalPreloadExists returns true if a complete local preload exists
+func LocalPreloadExists(k8sVersion, containerRuntime, imageRepository string) bool {
+	if !LocalPreloadEnabled() {
+		return false
+	}
+	p := LocalPreloadPath(k8sVersion, containerRuntime, imageRepository)
+	// the checksum is written last, so a preload without one is incomplete
+	for _, f := range []string{p, p + ".checksum"} {
+		if _, err := os.Stat(f); err != nil {
+			return false
+		}
+	}
+	klog.Infof("Found local preload: %s", p)
+	return true
+}
+
+// WriteLocalPreload bundles the image archives of srcDir into the local preload with the compression c, along with its checksum
+func WriteLocalPreload(k8sVersion, containerRuntime, imageRepository, srcDir string, c Compression) error {
+	if c != CompressionNone && c != CompressionZstd {
+		return fmt.Errorf("local preloads cannot be compressed with %s", c)
+	}
+	dst := filepath.Join(localPreloadDir(), LocalPreloadName(k8sVersion, containerRuntime, imageRepository, c))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return errors.Wrap(err, "making local preload dir")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*")
+	if err != nil {
+		return errors.Wrap(err, "tempfile")
+	}
+	defer os.Remove(tmp.Name())
+
+	h := md5.New()
+	var w io.Writer = io.MultiWriter(tmp, h)
+	var zw *zstd.Encoder
+	if c == CompressionZstd {
+		if zw, err = zstd.NewWriter(w); err != nil {
+			tmp.Close()
+			return errors.Wrap(err, "zstd writer")
+		}
+		w = zw
+	}
+	tw := tar.NewWriter(w)
+	err = filepath.Walk(srcDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "bundling %s", srcDir)
+	}
+	if err := tw.Close(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "closing tarball")
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			tmp.Close()
+			return errors.Wrap(err, "closing zstd writer")
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "closing tempfile")
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return errors.Wrap(err, "rename")
+	}
+	if err := os.WriteFile(dst+".checksum", []byte(hex.EncodeToString(h.Sum(nil))), 0o644); err != nil {
+		return err
+	}
+	// the preload with the other compression is stale now
+	for _, o := range localPreloadCompressions {
+		if o == c {
+			continue
+		}
+		stale := filepath.Join(localPreloadDir(), LocalPreloadName(k8sVersion, containerRuntime, imageRepository, o))
+		for _, f := range []string{stale + ".checksum", stale} {
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				klog.Warningf("failed to remove stale local preload %s: %v", f, err)
+			}
+		}
+	}
+	return nil
+}
+
+// VerifyLocalPreload returns an error if the local preload at path does not match its checksum
+func VerifyLocalPreload(path string) error {
+	want, err := os.ReadFile(path + ".checksum")
+	if err != nil {
+		return errors.Wrap(err, "reading checksum file")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "opening local preload")
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return errors.Wrap(err, "reading local preload")
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != strings.TrimSpace(string(want)) {
+		return fmt.Errorf("checksum of %s does not match (%s != %s)", path, got, want)
+	}
+	return nil
+}
+
+// LocalPreloadArchives returns the names of the image archives bundled in the local preload at path
+func LocalPreloadArchives(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening local preload")
+	}
+	defer f.Close()
+	c, err := DetectCompression(path)
+	if err != nil {
+		return nil, err
+	}
+	if c == CompressionLZ4 {
+		return nil, fmt.Errorf("local preloads cannot be compressed with %s", c)
+	}
+	r, release, err := decompress(f, c)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	names := []string{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading local preload")
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			names = append(names, hdr.Name)
+		}
+	}
+}
+
+// LocalPreloads lists the local preloads in the cache
+func LocalPreloads() ([]LocalPreload, error) {
+	entries, err := os.ReadDir(localPreloadDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "listing local preloads")
+	}
+	preloads := []LocalPreload{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if _, ok := CompressionFromName(e.Name()); !ok {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		preloads = append(preloads, LocalPreload{Name: e.Name(), Path: filepath.Join(localPreloadDir(), e.Name()), Size: info.Size()})
+	}
+	return preloads, nil
+}
+
+// DeleteLocalPreloads deletes all the local preloads from the cache
+func DeleteLocalPreloads() error {
+	klog.Infof("deleting local preloads in %s", localPreloadDir())
+	return os.RemoveAll(localPreloadDir())
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLocalPreload(t *testing.T) {
+	t.Setenv("MINIKUBE_HOME", t.TempDir())
+
+	src := t.TempDir()
+	for _, name := range []string{"registry.example.com_k8s_pause_3.7", "registry.example.com_k8s_etcd_3.5.3-0"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if LocalPreloadExists("v1.25.0-rc.1", "docker", "registry.example.com/k8s") {
+		t.Fatalf("local preload exists before it was written")
+	}
+	if err := WriteLocalPreload("v1.25.0-rc.1", "docker", "registry.example.com/k8s", src, CompressionNone); err != nil {
+		t.Fatalf("WriteLocalPreload: %v", err)
+	}
+	if !LocalPreloadExists("v1.25.0-rc.1", "docker", "registry.example.com/k8s") {
+		t.Fatalf("local preload does not exist after it was written")
+	}
+	if LocalPreloadExists("v1.25.0-rc.1", "docker", "") {
+		t.Errorf("local preload of another image repository exists")
+	}
+
+	p := LocalPreloadPath("v1.25.0-rc.1", "docker", "registry.example.com/k8s")
+	if err := VerifyLocalPreload(p); err != nil {
+		t.Errorf("VerifyLocalPreload: %v", err)
+	}
+	got, err := LocalPreloadArchives(p)
+	if err != nil {
+		t.Fatalf("LocalPreloadArchives: %v", err)
+	}
+	want := []string{"registry.example.com_k8s_etcd_3.5.3-0", "registry.example.com_k8s_pause_3.7"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LocalPreloadArchives() = %v, want %v", got, want)
+	}
+
+	preloads, err := LocalPreloads()
+	if err != nil {
+		t.Fatalf("LocalPreloads: %v", err)
+	}
+	if len(preloads) != 1 || preloads[0].Path != p || preloads[0].Size == 0 {
+		t.Errorf("LocalPreloads() = %+v, want a single preload at %s", preloads, p)
+	}
+
+	// a zstd local preload supersedes the uncompressed one
+	if err := WriteLocalPreload("v1.25.0-rc.1", "docker", "registry.example.com/k8s", src, CompressionZstd); err != nil {
+		t.Fatalf("WriteLocalPreload with zstd: %v", err)
+	}
+	p = LocalPreloadPath("v1.25.0-rc.1", "docker", "registry.example.com/k8s")
+	if filepath.Ext(p) != ".zst" {
+		t.Errorf("LocalPreloadPath() = %s, want the zstd local preload", p)
+	}
+	if err := VerifyLocalPreload(p); err != nil {
+		t.Errorf("VerifyLocalPreload of the zstd local preload: %v", err)
+	}
+	got, err = LocalPreloadArchives(p)
+	if err != nil {
+		t.Fatalf("LocalPreloadArchives of the zstd local preload: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LocalPreloadArchives() = %v, want %v", got, want)
+	}
+	preloads, err = LocalPreloads()
+	if err != nil {
+		t.Fatalf("LocalPreloads: %v", err)
+	}
+	if len(preloads) != 1 || preloads[0].Path != p {
+		t.Errorf("LocalPreloads() = %+v, want a single preload at %s", preloads, p)
+	}
+
+	if err := os.WriteFile(p+".checksum", []byte("0123"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyLocalPreload(p); err == nil {
+		t.Errorf("VerifyLocalPreload succeeded with a wrong checksum")
+	}
+
+	if err := DeleteLocalPreloads(); err != nil {
+		t.Fatalf("DeleteLocalPreloads: %v", err)
+	}
+	if preloads, _ := LocalPreloads(); len(preloads) != 0 {
+		t.Errorf("LocalPreloads() = %+v after deleting them", preloads)
+	}
+}
@@ -19,6 +19,7 @@ package download
 import (
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"net/http"
@@ -47,14 +48,64 @@ const (
 	PreloadVersion = "v18"
 	// PreloadBucket is the name of the GCS bucket where preloaded volume tarballs exist
 	PreloadBucket = "minikube-preloaded-volume-tarballs"
+	// PreloadChecksumSkip disables the verification of the preload tarballs
+	PreloadChecksumSkip = "skip"
 )
 
+// PreloadSource is where the preload tarballs are downloaded from
+type PreloadSource struct {
+	// BaseURL replaces the URL of the preload bucket, keeping its layout: BaseURL/PreloadVersion/k8sVersion/TarballName
+	BaseURL string
+	// Checksum is PreloadChecksumSkip, "sha256:<hex>", or empty to verify the tarballs with the checksums of their source.
+	// The tarballs of a mirror are verified with the sha256sum file next to them, at TarballName.sha256.
+	Checksum string
+	// ChecksumVersion is the Kubernetes version of the tarball a "sha256:<hex>" Checksum verifies, the tarballs of the other versions being verified as usual
+	ChecksumVersion string
+	// Fallback downloads the tarballs missing from the mirror from the preload bucket
+	Fallback bool
+}
+
 var (
 	preloadStates = make(map[string]map[string]bool)
+
+	preloadSource PreloadSource
+	// preloadFallbacks are the names of the tarballs missing from the mirror, found in the preload bucket
+	preloadFallbacks = map[string]bool{}
 )
 
-// TarballName returns name of the tarball
+// SetPreloadSource sets where the preload tarballs are downloaded from, the preload bucket if BaseURL is empty
+func SetPreloadSource(s PreloadSource) {
+	s.BaseURL = strings.TrimSuffix(s.BaseURL, "/")
+	if s == preloadSource {
+		return
+	}
+	preloadSource = s
+	preloadStates = make(map[string]map[string]bool)
+	preloadFallbacks = map[string]bool{}
+}
+
+// ValidatePreloadChecksum returns an error if checksum is not PreloadChecksumSkip or "sha256:<hex>"
+func ValidatePreloadChecksum(checksum string) error {
+	if checksum == "" || checksum == PreloadChecksumSkip {
+		return nil
+	}
+	sum := strings.TrimPrefix(checksum, "sha256:")
+	if sum == checksum {
+		return fmt.Errorf("invalid preload checksum %q: valid values are %q and sha256:<value>", checksum, PreloadChecksumSkip)
+	}
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("invalid preload checksum %q: %s is not a SHA-256 hex digest", checksum, sum)
+	}
+	return nil
+}
+
+// TarballName returns name of the tarball which the preload bucket serves
 func TarballName(k8sVersion, containerRuntime string) string {
+	return CompressedTarballName(k8sVersion, containerRuntime, CompressionLZ4)
+}
+
+// CompressedTarballName returns name of the tarball with the compression c
+func CompressedTarballName(k8sVersion, containerRuntime string, c Compression) string {
 	if containerRuntime == "crio" {
 		containerRuntime = "cri-o"
 	}
@@ -65,7 +116,7 @@ func TarballName(k8sVersion, containerRuntime string) string {
 		storageDriver = "overlay2"
 	}
 	arch := detect.EffectiveArch()
-	return fmt.Sprintf("preloaded-images-k8s-%s-%s-%s-%s-%s.tar.lz4", PreloadVersion, k8sVersion, containerRuntime, storageDriver, arch)
+	return fmt.Sprintf("preloaded-images-k8s-%s-%s-%s-%s-%s%s", PreloadVersion, k8sVersion, containerRuntime, storageDriver, arch, c.Extension())
 }
 
 // returns the name of the checksum file
@@ -83,14 +134,49 @@ func PreloadChecksumPath(k8sVersion, containerRuntime string) string {
 	return filepath.Join(targetDir(), checksumName(k8sVersion, containerRuntime))
 }
 
-// TarballPath returns the local path to the cached preload tarball
+// TarballPath returns the local path to the cached preload tarball which the preload bucket serves
 func TarballPath(k8sVersion, containerRuntime string) string {
-	return filepath.Join(targetDir(), TarballName(k8sVersion, containerRuntime))
+	return CompressedTarballPath(k8sVersion, containerRuntime, CompressionLZ4)
+}
+
+// CompressedTarballPath returns the local path to the cached preload tarball with the compression c
+func CompressedTarballPath(k8sVersion, containerRuntime string, c Compression) string {
+	return filepath.Join(targetDir(), CompressedTarballName(k8sVersion, containerRuntime, c))
+}
+
+// CachedTarballs returns the paths to the cached preload tarballs, in order of preference of their compression
+func CachedTarballs(k8sVersion, containerRuntime string) []string {
+	paths := []string{}
+	for _, c := range PreloadCompressions {
+		p := CompressedTarballPath(k8sVersion, containerRuntime, c)
+		if _, err := checkCache(p); err == nil {
+			paths = append(paths, p)
+		}
+	}
+	return paths
 }
 
-// remoteTarballURL returns the URL for the remote tarball in GCS
+// defaultPreloadBaseURL returns the URL of the preload bucket
+var defaultPreloadBaseURL = func() string {
+	return fmt.Sprintf("https://%s/%s", downloadHost, PreloadBucket)
+}
+
+// tarballURL returns the URL of the tarball below base
+func tarballURL(base, k8sVersion, containerRuntime string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", base, PreloadVersion, k8sVersion, TarballName(k8sVersion, containerRuntime))
+}
+
+// remoteTarballURL returns the URL for the remote tarball, in the mirror unless it lacks the tarball
 func remoteTarballURL(k8sVersion, containerRuntime string) string {
-	return fmt.Sprintf("https://%s/%s/%s/%s/%s", downloadHost, PreloadBucket, PreloadVersion, k8sVersion, TarballName(k8sVersion, containerRuntime))
+	if preloadSource.BaseURL == "" || preloadFallbacks[TarballName(k8sVersion, containerRuntime)] {
+		return tarballURL(defaultPreloadBaseURL(), k8sVersion, containerRuntime)
+	}
+	return tarballURL(preloadSource.BaseURL, k8sVersion, containerRuntime)
+}
+
+// mirrored returns whether the tarball is downloaded from a mirror rather than the preload bucket
+func mirrored(k8sVersion, containerRuntime string) bool {
+	return preloadSource.BaseURL != "" && !preloadFallbacks[TarballName(k8sVersion, containerRuntime)]
 }
 
 func setPreloadState(k8sVersion, containerRuntime string, value bool) {
@@ -102,22 +188,51 @@ func setPreloadState(k8sVersion, containerRuntime string, value bool) {
 	cRuntimes[containerRuntime] = value
 }
 
-var checkRemotePreloadExists = func(k8sVersion, containerRuntime string) bool {
+var checkRemotePreloadExists = remotePreloadExists
+
+// remotePreloadExists returns whether the tarball can be downloaded, from the mirror or the preload bucket
+func remotePreloadExists(k8sVersion, containerRuntime string) bool {
 	url := remoteTarballURL(k8sVersion, containerRuntime)
+	status := remoteStatus(url)
+	if status == http.StatusNotFound && mirrored(k8sVersion, containerRuntime) && preloadSource.Fallback {
+		klog.Infof("%s is missing from the preload mirror, falling back to the preload bucket", TarballName(k8sVersion, containerRuntime))
+		preloadFallbacks[TarballName(k8sVersion, containerRuntime)] = true
+		url = remoteTarballURL(k8sVersion, containerRuntime)
+		status = remoteStatus(url)
+	}
+	if status != http.StatusOK {
+		return false
+	}
+
+	klog.Infof("Found remote preload: %s", url)
+	return true
+}
+
+// remoteStatus returns the status code of a HEAD request of url, or 0 if it failed.
+// The files of file:// URLs answer as a HTTP server would.
+func remoteStatus(url string) int {
+	if p := strings.TrimPrefix(url, "file://"); p != url {
+		if _, err := os.Stat(filepath.FromSlash(p)); err != nil {
+			klog.Warningf("%s stat error: %v", url, err)
+			if os.IsNotExist(err) {
+				return http.StatusNotFound
+			}
+			return 0
+		}
+		return http.StatusOK
+	}
 	resp, err := http.Head(url)
 	if err != nil {
 		klog.Warningf("%s fetch error: %v", url, err)
-		return false
+		return 0
 	}
+	defer resp.Body.Close()
 
 	// note: err won't be set if it's a 404
 	if resp.StatusCode != http.StatusOK {
 		klog.Warningf("%s status code: %d", url, resp.StatusCode)
-		return false
 	}
-
-	klog.Infof("Found remote preload: %s", url)
-	return true
+	return resp.StatusCode
 }
 
 // PreloadExists returns true if there is a preloaded tarball that can be used
@@ -143,9 +258,8 @@ func PreloadExists(k8sVersion, containerRuntime, driverName string, forcePreload
 	}
 
 	// Omit remote check if tarball exists locally
-	targetPath := TarballPath(k8sVersion, containerRuntime)
-	if _, err := checkCache(targetPath); err == nil {
-		klog.Infof("Found local preload: %s", targetPath)
+	if cached := CachedTarballs(k8sVersion, containerRuntime); len(cached) > 0 {
+		klog.Infof("Found local preload: %s", cached[0])
 		setPreloadState(k8sVersion, containerRuntime, true)
 		return true
 	}
@@ -158,7 +272,7 @@ func PreloadExists(k8sVersion, containerRuntime, driverName string, forcePreload
 var checkPreloadExists = PreloadExists
 
 // Preload caches the preloaded images tarball on the host machine
-func Preload(k8sVersion, containerRuntime, driverName string) error {
+func Preload(k8sVersion, containerRuntime, driverName string, forcePreload ...bool) error {
 	targetPath := TarballPath(k8sVersion, containerRuntime)
 	targetLock := targetPath + ".lock"
 
@@ -170,13 +284,13 @@ func Preload(k8sVersion, containerRuntime, driverName string) error {
 		return err
 	}
 
-	if _, err := checkCache(targetPath); err == nil {
-		klog.Infof("Found %s in cache, skipping download", targetPath)
+	if cached := CachedTarballs(k8sVersion, containerRuntime); len(cached) > 0 {
+		klog.Infof("Found %s in cache, skipping download", cached[0])
 		return nil
 	}
 
 	// Make sure we support this k8s version
-	if !checkPreloadExists(k8sVersion, containerRuntime, driverName) {
+	if !checkPreloadExists(k8sVersion, containerRuntime, driverName, forcePreload...) {
 		klog.Infof("Preloaded tarball for k8s version %s does not exist", k8sVersion)
 		return nil
 	}
@@ -184,6 +298,18 @@ func Preload(k8sVersion, containerRuntime, driverName string) error {
 	out.Step(style.FileDownload, "Downloading Kubernetes {{.version}} preload ...", out.V{"version": k8sVersion})
 	url := remoteTarballURL(k8sVersion, containerRuntime)
 
+	if param, ok := checksumParam(k8sVersion, containerRuntime); ok {
+		if param != "" {
+			// go-getter verifies the checksum before moving the download to targetPath
+			url += "?checksum=" + param
+		}
+		if err := download(url, targetPath); err != nil {
+			return errors.Wrapf(err, "download failed: %s", url)
+		}
+		setPreloadState(k8sVersion, containerRuntime, true)
+		return nil
+	}
+
 	checksum, err := getChecksum(k8sVersion, containerRuntime)
 	var realPath string
 	if err != nil {
@@ -220,6 +346,26 @@ func Preload(k8sVersion, containerRuntime, driverName string) error {
 	return nil
 }
 
+// checksumParam returns the go-getter checksum parameter verifying the tarball, if it is not verified with the MD5 checksum of the preload bucket.
+// The parameter is empty when the verification is skipped.
+func checksumParam(k8sVersion, containerRuntime string) (string, bool) {
+	switch {
+	case preloadSource.Checksum == PreloadChecksumSkip:
+		klog.Warningf("Skipping the verification of %s", TarballName(k8sVersion, containerRuntime))
+		return "", true
+	case preloadSource.Checksum != "" && (preloadSource.ChecksumVersion == "" || preloadSource.ChecksumVersion == k8sVersion):
+		return preloadSource.Checksum, true
+	case preloadSource.Checksum != "":
+		klog.Infof("The preload checksum is for Kubernetes %s, verifying the tarball of %s as usual", preloadSource.ChecksumVersion, k8sVersion)
+		if mirrored(k8sVersion, containerRuntime) {
+			return "file:" + remoteTarballURL(k8sVersion, containerRuntime) + ".sha256", true
+		}
+	case mirrored(k8sVersion, containerRuntime):
+		return "file:" + remoteTarballURL(k8sVersion, containerRuntime) + ".sha256", true
+	}
+	return "", false
+}
+
 func getStorageAttrs(name string) (*storage.ObjectAttrs, error) {
 	ctx := context.Background()
 	client, err := storage.NewClient(ctx, option.WithoutAuthentication())
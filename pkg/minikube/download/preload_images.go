@@ -0,0 +1,263 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package download
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/driver"
+)
+
+// PreloadImage is an image of a preload tarball
+type PreloadImage struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+	ID         string `json:"id,omitempty"`
+}
+
+// preloadMetadata is the file where a container runtime keeps the names of its images
+type preloadMetadata struct {
+	// pattern matches the name of the file in the tarball
+	pattern string
+	parse   func(io.Reader) ([]PreloadImage, error)
+}
+
+var preloadMetadataFiles = []preloadMetadata{
+	{pattern: "lib/docker/image/*/repositories.json", parse: dockerRepositoriesImages},
+	{pattern: "lib/containerd/io.containerd.metadata.v1.bolt/meta.db", parse: containerdMetadataImages},
+	{pattern: "lib/containers/storage/overlay-images/images.json", parse: crioImages},
+}
+
+// FetchPreload returns the path to the cached preload tarball for the Kubernetes version and container runtime, downloading it if it is not cached
+func FetchPreload(k8sVersion, containerRuntime string) (string, error) {
+	if !PreloadExists(k8sVersion, containerRuntime, driver.Docker, true) {
+		return "", errors.Errorf("no preload tarball for Kubernetes %s and %s", k8sVersion, containerRuntime)
+	}
+	if err := Preload(k8sVersion, containerRuntime, driver.Docker, true); err != nil {
+		return "", err
+	}
+	cached := CachedTarballs(k8sVersion, containerRuntime)
+	if len(cached) == 0 {
+		return "", errors.Errorf("%s is not in the cache", TarballName(k8sVersion, containerRuntime))
+	}
+	return cached[0], nil
+}
+
+// TarballImages lists the images of the preload tarball at path without extracting it
+func TarballImages(path string) ([]PreloadImage, error) {
+	c, err := DetectCompression(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening tarball")
+	}
+	defer f.Close()
+	r, release, err := decompress(f, c)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ReadPreloadImages(r)
+}
+
+// ReadPreloadImages lists the images of the uncompressed preload tarball r from the metadata of its container runtime.
+// It stops reading r after the metadata.
+func ReadPreloadImages(r io.Reader) ([]PreloadImage, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("no image metadata of a container runtime in the tarball")
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading tarball")
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		for _, m := range preloadMetadataFiles {
+			if ok, _ := path.Match(m.pattern, name); ok {
+				klog.Infof("reading the images from %s", name)
+				images, err := m.parse(tr)
+				return images, errors.Wrapf(err, "reading %s", name)
+			}
+		}
+	}
+}
+
+// dockerRepositoriesImages returns the images of the repositories.json of docker
+func dockerRepositoriesImages(r io.Reader) ([]PreloadImage, error) {
+	var repos struct {
+		Repositories map[string]map[string]string
+	}
+	if err := json.NewDecoder(r).Decode(&repos); err != nil {
+		return nil, err
+	}
+	ids := map[string]string{}
+	for _, refs := range repos.Repositories {
+		for ref, id := range refs {
+			ids[ref] = id
+		}
+	}
+	return referencedImages(ids), nil
+}
+
+// crioImages returns the images of the images.json of the storage of cri-o
+func crioImages(r io.Reader) ([]PreloadImage, error) {
+	var images []struct {
+		ID    string   `json:"id"`
+		Names []string `json:"names"`
+	}
+	if err := json.NewDecoder(r).Decode(&images); err != nil {
+		return nil, err
+	}
+	ids := map[string]string{}
+	for _, img := range images {
+		for _, ref := range img.Names {
+			ids[ref] = "sha256:" + img.ID
+		}
+	}
+	return referencedImages(ids), nil
+}
+
+// containerdMetadataImages returns the images of the metadata database of containerd.
+// The images named by their ID share the target of the images named by their references.
+func containerdMetadataImages(r io.Reader) ([]PreloadImage, error) {
+	// bolt maps the database from a file
+	f, err := os.CreateTemp("", "meta.db.*")
+	if err != nil {
+		return nil, errors.Wrap(err, "creating temp file")
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "writing temp file")
+	}
+	if err := f.Close(); err != nil {
+		return nil, errors.Wrap(err, "closing temp file")
+	}
+	db, err := bolt.Open(f.Name(), 0600, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "opening database")
+	}
+	defer db.Close()
+
+	targets := map[string]string{}
+	idOf := map[string]string{}
+	err = db.View(func(tx *bolt.Tx) error {
+		v1 := tx.Bucket([]byte("v1"))
+		if v1 == nil {
+			return errors.New("no v1 bucket")
+		}
+		return v1.ForEach(func(ns, _ []byte) error {
+			nsb := v1.Bucket(ns)
+			if nsb == nil {
+				return nil
+			}
+			images := nsb.Bucket([]byte("images"))
+			if images == nil {
+				return nil
+			}
+			return images.ForEach(func(name, _ []byte) error {
+				target := ""
+				if img := images.Bucket(name); img != nil {
+					if t := img.Bucket([]byte("target")); t != nil {
+						target = string(t.Get([]byte("digest")))
+					}
+				}
+				if strings.HasPrefix(string(name), "sha256:") {
+					idOf[target] = string(name)
+				} else {
+					targets[string(name)] = target
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]string{}
+	for ref, target := range targets {
+		ids[ref] = idOf[target]
+	}
+	return referencedImages(ids), nil
+}
+
+// referencedImages returns the images of the references mapped to the IDs of their images, sorted by repository and tag.
+// The digest references of an image give the digest of its tags in the same repository.
+func referencedImages(ids map[string]string) []PreloadImage {
+	type key struct{ repo, id string }
+	tags := map[key][]string{}
+	digests := map[key]string{}
+	for ref, id := range ids {
+		repo, tag, digest := splitReference(ref)
+		k := key{repo, id}
+		if digest != "" {
+			digests[k] = digest
+			if _, ok := tags[k]; !ok {
+				tags[k] = nil
+			}
+			continue
+		}
+		tags[k] = append(tags[k], tag)
+	}
+	images := []PreloadImage{}
+	for k, ts := range tags {
+		if len(ts) == 0 {
+			images = append(images, PreloadImage{Repository: k.repo, Digest: digests[k], ID: k.id})
+		}
+		for _, t := range ts {
+			images = append(images, PreloadImage{Repository: k.repo, Tag: t, Digest: digests[k], ID: k.id})
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Repository != images[j].Repository {
+			return images[i].Repository < images[j].Repository
+		}
+		if images[i].Tag != images[j].Tag {
+			return images[i].Tag < images[j].Tag
+		}
+		return images[i].Digest < images[j].Digest
+	})
+	return images
+}
+
+// splitReference splits an image reference into its repository, and its tag or digest
+func splitReference(ref string) (repo, tag, digest string) {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[:i], "", ref[i+1:]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:], ""
+	}
+	return ref, "", ""
+}
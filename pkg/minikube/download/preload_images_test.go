@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package download
+
+import (
+	"archive/tar"
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTarballImages(t *testing.T) {
+	var tests = []struct {
+		tarball string
+		want    []PreloadImage
+	}{
+		{
+			tarball: "preload-docker.tar.lz4",
+			want: []PreloadImage{
+				{Repository: "gcr.io/k8s-minikube/storage-provisioner", Tag: "v5", ID: "sha256:6e38f40d628db3002f5617342c8872c935de530d867d0f709a2fbda1a302a562"},
+				{Repository: "registry.k8s.io/kube-apiserver", Tag: "v1.25.3", ID: "sha256:0346dbd74bcb9485bb4da1b33027094d79488470d8d1b9baa4d927db564e4fe0"},
+				{Repository: "registry.k8s.io/pause", Tag: "3.8", Digest: "sha256:9001185023633d17a2f98ff69b6ff2615b8ea02a825adffa40422f51dfdcde9d", ID: "sha256:4873874c08efc72e9729683a83ffbb7502ee729e9a5ac097723806ea7fa13517"},
+			},
+		},
+		{
+			tarball: "preload-containerd.tar.zst",
+			want: []PreloadImage{
+				{Repository: "docker.io/kindest/kindnetd", Tag: "v20221004-44d545d1"},
+				{Repository: "registry.k8s.io/etcd", Tag: "3.5.4-0", Digest: "sha256:6f72b851544986cb0921b53ea655ec04c36131248f16d4ad110cb3ca0c369dc1", ID: "sha256:a8a176a5d5d698f9409dc246f81fa69d37d4a2f4132ba5e62e72a78476b27f66"},
+			},
+		},
+		{
+			tarball: "preload-cri-o.tar.zst",
+			want: []PreloadImage{
+				{Repository: "registry.k8s.io/coredns/coredns", Tag: "v1.9.3", ID: "sha256:5185b96f0becf59032b8e3646e99f84d9655dff3ac9e2605e0dc77f9c441ae4a"},
+				{Repository: "registry.k8s.io/pause", Tag: "3.8", Digest: "sha256:f5944f2d1daf66463768a1503d0c8c5e8dde7c1674d3f85abc70cef9c7e32e95", ID: "sha256:4873874c08efc72e9729683a83ffbb7502ee729e9a5ac097723806ea7fa13517"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.tarball, func(t *testing.T) {
+			got, err := TarballImages(filepath.Join("testdata", tc.tarball))
+			if err != nil {
+				t.Fatalf("TarballImages: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("TarballImages() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadPreloadImagesStopsAfterMetadata(t *testing.T) {
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	repos := []byte(`{"Repositories":{"registry.k8s.io/pause":{"registry.k8s.io/pause:3.8":"sha256:4873874c"}}}`)
+	if err := tw.WriteHeader(&tar.Header{Name: "./lib/docker/image/overlay2/repositories.json", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(repos))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(repos); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// the rest of the tarball is not read
+	b.WriteString("not a tar header")
+
+	got, err := ReadPreloadImages(&b)
+	if err != nil {
+		t.Fatalf("ReadPreloadImages: %v", err)
+	}
+	want := []PreloadImage{{Repository: "registry.k8s.io/pause", Tag: "3.8", ID: "sha256:4873874c"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadPreloadImages() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadPreloadImagesWithoutMetadata(t *testing.T) {
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	if err := tw.WriteHeader(&tar.Header{Name: "./lib/minikube/binaries", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPreloadImages(&b); err == nil {
+		t.Errorf("ReadPreloadImages() of a tarball without image metadata succeeded")
+	}
+}
+
+func TestSplitReference(t *testing.T) {
+	var tests = []struct {
+		ref               string
+		repo, tag, digest string
+	}{
+		{"registry.k8s.io/pause:3.8", "registry.k8s.io/pause", "3.8", ""},
+		{"localhost:5000/app", "localhost:5000/app", "", ""},
+		{"localhost:5000/app:v1", "localhost:5000/app", "v1", ""},
+		{"registry.k8s.io/pause@sha256:9001", "registry.k8s.io/pause", "", "sha256:9001"},
+	}
+	for _, tc := range tests {
+		repo, tag, digest := splitReference(tc.ref)
+		if repo != tc.repo || tag != tc.tag || digest != tc.digest {
+			t.Errorf("splitReference(%s) = %q, %q, %q, want %q, %q, %q", tc.ref, repo, tag, digest, tc.repo, tc.tag, tc.digest)
+		}
+	}
+}
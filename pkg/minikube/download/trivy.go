@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package download
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/localpath"
+)
+
+// TrivyVersion is the version of trivy used to scan images in the node
+const TrivyVersion = "0.36.1"
+
+// trivyArchs are the names of the architectures in the trivy releases
+var trivyArchs = map[string]string{
+	"amd64":   "64bit",
+	"arm64":   "ARM64",
+	"arm":     "ARM",
+	"ppc64le": "PPC64LE",
+	"s390x":   "s390x",
+}
+
+// trivyWithChecksumURL gets the location of a trivy release archive
+func trivyWithChecksumURL(version, archName string) (string, error) {
+	arch, ok := trivyArchs[archName]
+	if !ok {
+		return "", fmt.Errorf("trivy is not available for %s", archName)
+	}
+	base := fmt.Sprintf("https://github.com/aquasecurity/trivy/releases/download/v%s", version)
+	return fmt.Sprintf("%s/trivy_%s_Linux-%s.tar.gz?checksum=file:%s/trivy_%s_checksums.txt", base, version, arch, base, version), nil
+}
+
+// TrivyPath returns the path of the cached trivy release archive
+func TrivyPath(version, archName string) string {
+	return localpath.MakeMiniPath("cache", "linux", archName, "trivy", version, "trivy.tar.gz")
+}
+
+// TrivyExists returns true if the trivy release archive is already cached
+func TrivyExists(version, archName string) bool {
+	_, err := checkCache(TrivyPath(version, archName))
+	return err == nil
+}
+
+// Trivy downloads the trivy release archive for linux onto the host, returning its path
+func Trivy(version, archName string) (string, error) {
+	dst := TrivyPath(version, archName)
+	url, err := trivyWithChecksumURL(version, archName)
+	if err != nil {
+		return "", err
+	}
+
+	releaser, err := lockDownload(dst + ".lock")
+	if releaser != nil {
+		defer releaser.Release()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := checkCache(dst); err == nil {
+		klog.Infof("Found %s in cache, skipping download", filepath.Base(dst))
+		return dst, nil
+	}
+	if err := download(url, dst); err != nil {
+		return "", errors.Wrapf(err, "download failed: %s", url)
+	}
+	return dst, nil
+}
@@ -19,6 +19,7 @@ package image
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/go-containerregistry/pkg/name"
@@ -54,10 +55,33 @@ func DeleteFromCacheDir(images []string) error {
 		if err := os.Remove(path); err != nil {
 			return err
 		}
+		if err := os.Remove(path + cachedIDSuffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
 	return cleanImageCacheDir()
 }
 
+// cachedIDSuffix is appended to the path of a cached image to record the image ID it was saved from
+const cachedIDSuffix = ".id"
+
+// WriteCachedImageID records the ID of the image stored at the given cache path
+func WriteCachedImageID(path string, id string) error {
+	return os.WriteFile(path+cachedIDSuffix, []byte(id), 0644)
+}
+
+// CachedImageID returns the recorded ID of the image stored at the given cache path, or "" if unknown
+func CachedImageID(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	id, err := os.ReadFile(path + cachedIDSuffix)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(id))
+}
+
 // SaveToDir will cache images on the host
 //
 // The cache directory currently caches images using the imagename_tag
@@ -78,6 +102,9 @@ func SaveToDir(images []string, cacheDir string, overwrite bool) error {
 				return errors.Wrapf(err, "caching image %q", dst)
 			}
 			klog.Infof("save to tar file %s -> %s succeeded", image, dst)
+			if err := TouchCachedImages(cacheDir, dst); err != nil {
+				klog.Warningf("unable to record %s in the image cache: %v", dst, err)
+			}
 			return nil
 		})
 	}
@@ -129,6 +156,18 @@ func saveToTarFile(iname, rawDest string, overwrite bool) error {
 		return errors.Wrapf(err, "nil reference for %s", iname)
 	}
 
+	if useDaemon {
+		// the images of podman and nerdctl are saved by their clients, as the library only reads those of docker
+		saved, err := saveFromHostEngine(iname, dst)
+		if err != nil {
+			return errors.Wrapf(err, "saving %s from the host engine", iname)
+		}
+		if saved {
+			klog.Infof("%s exists", dst)
+			return nil
+		}
+	}
+
 	img, cname, err := retrieveImage(ref, iname)
 	if err != nil {
 		return errCacheImageDoesntExist
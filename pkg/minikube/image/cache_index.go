@@ -0,0 +1,267 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juju/mutex"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/util/lock"
+)
+
+// cacheIndexFile is the name of the index of the image archives, at the root of the image cache directory
+const cacheIndexFile = "index.json"
+
+// CacheEntry is the accounting of an image archive in the cache
+type CacheEntry struct {
+	// Path is the path of the archive, relative to the cache directory
+	Path string `json:"path"`
+	// Size is the size of the archive in bytes
+	Size int64 `json:"size"`
+	// LastUsed is when the archive was last cached or loaded into a node
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// cacheIndex is the content of the index file, with the entries by path
+type cacheIndex struct {
+	Entries map[string]CacheEntry `json:"entries"`
+}
+
+// CachePruneOptions are the limits of the cache, ignored if zero
+type CachePruneOptions struct {
+	// MaxSize is the size in bytes the cache is shrunk to, by evicting the least recently used archives
+	MaxSize int64
+	// OlderThan evicts the archives not used within this period
+	OlderThan time.Duration
+}
+
+// updateCacheIndex applies update to the index of the cache, holding a lock shared with the other minikube processes
+func updateCacheIndex(cacheDir string, update func(idx *cacheIndex) error) error {
+	path := filepath.Join(cacheDir, cacheIndexFile)
+	spec := lock.PathMutexSpec(path)
+	releaser, err := mutex.Acquire(spec)
+	if err != nil {
+		return errors.Wrapf(err, "unable to acquire lock for %+v", spec)
+	}
+	defer releaser.Release()
+
+	idx, err := readCacheIndex(cacheDir)
+	if err != nil {
+		return err
+	}
+	if err := update(idx); err != nil {
+		return err
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return errors.Wrap(err, "marshal cache index")
+	}
+	if err := os.MkdirAll(cacheDir, 0777); err != nil {
+		return errors.Wrap(err, "making cache directory")
+	}
+	// a process reading the index without the lock sees either the previous or the new one
+	tmp, err := os.CreateTemp(cacheDir, cacheIndexFile+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "create cache index")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "write cache index")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "close cache index")
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readCacheIndex returns the index of the cache, which is empty if missing or corrupt
+func readCacheIndex(cacheDir string) (*cacheIndex, error) {
+	idx := &cacheIndex{Entries: map[string]CacheEntry{}}
+	data, err := os.ReadFile(filepath.Join(cacheDir, cacheIndexFile))
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read cache index")
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		// the index is rebuilt from the archives in the cache
+		klog.Warningf("ignoring the corrupt cache index: %v", err)
+		return &cacheIndex{Entries: map[string]CacheEntry{}}, nil
+	}
+	if idx.Entries == nil {
+		idx.Entries = map[string]CacheEntry{}
+	}
+	return idx, nil
+}
+
+// cacheRelPath returns the path of an archive relative to the cache directory, with forward slashes
+func cacheRelPath(cacheDir, path string) (string, error) {
+	rel, err := filepath.Rel(cacheDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", errors.Errorf("%s is not in the cache directory %s", path, cacheDir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// TouchCachedImages records the image archives at the paths as used now, along with their current size
+func TouchCachedImages(cacheDir string, paths ...string) error {
+	now := time.Now()
+	return updateCacheIndex(cacheDir, func(idx *cacheIndex) error {
+		for _, path := range paths {
+			rel, err := cacheRelPath(cacheDir, path)
+			if err != nil {
+				return err
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				return errors.Wrap(err, "stat cached image")
+			}
+			idx.Entries[rel] = CacheEntry{Path: rel, Size: fi.Size(), LastUsed: now}
+		}
+		return nil
+	})
+}
+
+// isCacheArchive returns whether a file in the cache directory is an image archive, rather than the index or metadata
+func isCacheArchive(rel string) bool {
+	return !strings.HasPrefix(rel, cacheIndexFile) && !strings.HasSuffix(rel, cachedIDSuffix) && !strings.HasSuffix(rel, ".tmp")
+}
+
+// cacheEntries returns the entries of the archives in the cache, least recently used first.
+// The archives missing from the index, such as those cached by older versions, were last used when they were last modified.
+func cacheEntries(cacheDir string, idx *cacheIndex) ([]CacheEntry, error) {
+	entries := []CacheEntry{}
+	err := filepath.Walk(cacheDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := cacheRelPath(cacheDir, path)
+		if err != nil || !isCacheArchive(rel) {
+			return nil
+		}
+		e, ok := idx.Entries[rel]
+		if !ok {
+			e = CacheEntry{Path: rel, LastUsed: info.ModTime()}
+		}
+		e.Size = info.Size()
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "walk cache directory")
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].LastUsed.Equal(entries[j].LastUsed) {
+			return entries[i].Path < entries[j].Path
+		}
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// CacheEntries returns the entries of the image archives in the cache, least recently used first
+func CacheEntries(cacheDir string) ([]CacheEntry, error) {
+	idx, err := readCacheIndex(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	return cacheEntries(cacheDir, idx)
+}
+
+// CacheSize returns the total size of the image archives in the cache
+func CacheSize(cacheDir string) (int64, error) {
+	entries, err := CacheEntries(cacheDir)
+	if err != nil {
+		return 0, err
+	}
+	return totalCacheSize(entries), nil
+}
+
+func totalCacheSize(entries []CacheEntry) int64 {
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	return total
+}
+
+// evictions returns the entries to evict from the least recently used ones: those unused within the period,
+// then as many as needed for the rest to fit the size
+func evictions(entries []CacheEntry, o CachePruneOptions, now time.Time) []CacheEntry {
+	total := totalCacheSize(entries)
+	evict := []CacheEntry{}
+	for _, e := range entries {
+		old := o.OlderThan > 0 && now.Sub(e.LastUsed) > o.OlderThan
+		big := o.MaxSize > 0 && total > o.MaxSize
+		if !old && !big {
+			break
+		}
+		evict = append(evict, e)
+		total -= e.Size
+	}
+	return evict
+}
+
+// PruneCache evicts the least recently used image archives from the cache, until it fits the options.
+// It returns the entries evicted.
+func PruneCache(cacheDir string, o CachePruneOptions) ([]CacheEntry, error) {
+	var evicted []CacheEntry
+	err := updateCacheIndex(cacheDir, func(idx *cacheIndex) error {
+		entries, err := cacheEntries(cacheDir, idx)
+		if err != nil {
+			return err
+		}
+		for _, e := range evictions(entries, o, time.Now()) {
+			path := filepath.Join(cacheDir, filepath.FromSlash(e.Path))
+			klog.Infof("evicting %s from the image cache, last used %s", path, e.LastUsed)
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return errors.Wrap(err, "remove cached image")
+			}
+			if err := os.Remove(path + cachedIDSuffix); err != nil && !os.IsNotExist(err) {
+				return errors.Wrap(err, "remove cached image ID")
+			}
+			delete(idx.Entries, e.Path)
+			evicted = append(evicted, e)
+		}
+		// forget the archives removed by other means, such as 'minikube cache delete'
+		for rel := range idx.Entries {
+			if _, err := os.Stat(filepath.Join(cacheDir, filepath.FromSlash(rel))); os.IsNotExist(err) {
+				delete(idx.Entries, rel)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return evicted, err
+	}
+	return evicted, removeEmptyDirs(cacheDir)
+}
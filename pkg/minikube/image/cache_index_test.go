@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeArchive writes an image archive of the given size into the cache
+func writeArchive(t *testing.T, cacheDir, rel string, size int) string {
+	path := filepath.Join(cacheDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatalf("write %s: %v", rel, err)
+	}
+	return path
+}
+
+// entryPaths returns the paths of the entries
+func entryPaths(entries []CacheEntry) string {
+	paths := []string{}
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+	}
+	return strings.Join(paths, " ")
+}
+
+func TestTouchCachedImagesConcurrently(t *testing.T) {
+	cacheDir := t.TempDir()
+	var paths []string
+	for i := 0; i < 16; i++ {
+		paths = append(paths, writeArchive(t, cacheDir, fmt.Sprintf("registry.k8s.io/image%d_v1", i), i+1))
+	}
+
+	// as 'minikube image load' does for each image, and as concurrent minikube processes do
+	var wg sync.WaitGroup
+	errs := make(chan error, len(paths))
+	for _, p := range paths {
+		wg.Add(1)
+		go func(p string) {
+			defer wg.Done()
+			errs <- TouchCachedImages(cacheDir, p)
+		}(p)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("TouchCachedImages: %v", err)
+		}
+	}
+
+	idx, err := readCacheIndex(cacheDir)
+	if err != nil {
+		t.Fatalf("readCacheIndex: %v", err)
+	}
+	if len(idx.Entries) != len(paths) {
+		t.Errorf("the index has %d entries, want %d, as updates were lost: %+v", len(idx.Entries), len(paths), idx.Entries)
+	}
+	if e := idx.Entries["registry.k8s.io/image3_v1"]; e.Size != 4 || e.LastUsed.IsZero() {
+		t.Errorf("entry = %+v, want the size and the time of use", e)
+	}
+	if err := TouchCachedImages(cacheDir, filepath.Join(t.TempDir(), "busybox_latest")); err == nil {
+		t.Errorf("TouchCachedImages of an archive outside of the cache succeeded")
+	}
+}
+
+func TestEvictions(t *testing.T) {
+	now := time.Now()
+	entries := []CacheEntry{
+		{Path: "a", Size: 40, LastUsed: now.Add(-60 * 24 * time.Hour)},
+		{Path: "b", Size: 30, LastUsed: now.Add(-40 * 24 * time.Hour)},
+		{Path: "c", Size: 20, LastUsed: now.Add(-2 * 24 * time.Hour)},
+		{Path: "d", Size: 10, LastUsed: now.Add(-time.Hour)},
+	}
+	var tests = []struct {
+		description string
+		opts        CachePruneOptions
+		want        string
+	}{
+		{"none", CachePruneOptions{}, ""},
+		{"fits", CachePruneOptions{MaxSize: 100}, ""},
+		{"max size", CachePruneOptions{MaxSize: 50}, "a b"},
+		{"max size evicting one", CachePruneOptions{MaxSize: 60}, "a"},
+		{"older than", CachePruneOptions{OlderThan: 30 * 24 * time.Hour}, "a b"},
+		{"both", CachePruneOptions{MaxSize: 15, OlderThan: 30 * 24 * time.Hour}, "a b c"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			if got := entryPaths(evictions(entries, tc.opts, now)); got != tc.want {
+				t.Errorf("evictions = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	old := writeArchive(t, cacheDir, "docker.io/library/busybox_latest", 300)
+	if err := WriteCachedImageID(old, "1234"); err != nil {
+		t.Fatalf("WriteCachedImageID: %v", err)
+	}
+	recent := writeArchive(t, cacheDir, "registry.k8s.io/pause_3.8", 200)
+	// cached by an older minikube, without an entry in the index
+	unindexed := writeArchive(t, cacheDir, "docker.io/library/nginx_latest", 100)
+	if err := os.Chtimes(unindexed, time.Now().Add(-48*time.Hour), time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if err := TouchCachedImages(cacheDir, old); err != nil {
+		t.Fatalf("TouchCachedImages: %v", err)
+	}
+	// the oldest use of an archive in the index is its last one
+	if err := updateCacheIndex(cacheDir, func(idx *cacheIndex) error {
+		e := idx.Entries["docker.io/library/busybox_latest"]
+		e.LastUsed = time.Now().Add(-72 * time.Hour)
+		idx.Entries[e.Path] = e
+		return nil
+	}); err != nil {
+		t.Fatalf("updateCacheIndex: %v", err)
+	}
+	if err := TouchCachedImages(cacheDir, recent); err != nil {
+		t.Fatalf("TouchCachedImages: %v", err)
+	}
+
+	entries, err := CacheEntries(cacheDir)
+	if err != nil {
+		t.Fatalf("CacheEntries: %v", err)
+	}
+	want := "docker.io/library/busybox_latest docker.io/library/nginx_latest registry.k8s.io/pause_3.8"
+	if got := entryPaths(entries); got != want {
+		t.Errorf("CacheEntries = %q, want %q, least recently used first", got, want)
+	}
+
+	evicted, err := PruneCache(cacheDir, CachePruneOptions{MaxSize: 250})
+	if err != nil {
+		t.Fatalf("PruneCache: %v", err)
+	}
+	if got := entryPaths(evicted); got != "docker.io/library/busybox_latest docker.io/library/nginx_latest" {
+		t.Errorf("PruneCache evicted %q, want busybox then nginx", got)
+	}
+	for _, p := range []string{old, old + cachedIDSuffix, unindexed} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s was not evicted: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "docker.io")); !os.IsNotExist(err) {
+		t.Errorf("the empty directory of the evicted images is left over: %v", err)
+	}
+	size, err := CacheSize(cacheDir)
+	if err != nil || size != 200 {
+		t.Errorf("CacheSize = %d, %v, want 200", size, err)
+	}
+	idx, err := readCacheIndex(cacheDir)
+	if err != nil {
+		t.Fatalf("readCacheIndex: %v", err)
+	}
+	if _, ok := idx.Entries["docker.io/library/busybox_latest"]; ok || len(idx.Entries) != 1 {
+		t.Errorf("index entries = %+v, want only pause", idx.Entries)
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2021 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCachedImageID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busybox_latest")
+
+	if got := CachedImageID(path); got != "" {
+		t.Errorf("CachedImageID() for missing image = %q, want \"\"", got)
+	}
+
+	const id = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if err := WriteCachedImageID(path, id); err != nil {
+		t.Fatalf("WriteCachedImageID: %v", err)
+	}
+	if got := CachedImageID(path); got != "" {
+		t.Errorf("CachedImageID() without cached tarball = %q, want \"\"", got)
+	}
+
+	if err := os.WriteFile(path, []byte("tarball"), 0644); err != nil {
+		t.Fatalf("write tarball: %v", err)
+	}
+	if got := CachedImageID(path); got != id {
+		t.Errorf("CachedImageID() = %q, want %q", got, id)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// hostEngine is a container engine on the host holding images to load into minikube
+type hostEngine struct {
+	name string
+	// saveArgs are the arguments of the engine saving an image as a docker archive, which all the runtimes load.
+	// The images of docker are read from its daemon instead.
+	saveArgs func(ref, path string) []string
+}
+
+// hostEngines are the engines images are loaded from, in the order they are preferred in when several hold an image
+var hostEngines = []hostEngine{
+	{name: "docker"},
+	{
+		name: "podman",
+		saveArgs: func(ref, path string) []string {
+			// podman may be configured to save oci-archive, which not all the runtimes load
+			return []string{"save", "--format", "docker-archive", "-o", path, ref}
+		},
+	},
+	{
+		name: "nerdctl",
+		saveArgs: func(ref, path string) []string {
+			return []string{"save", "-o", path, ref}
+		},
+	},
+}
+
+// HostEngineNames returns the names of the host engines images may be loaded from
+func HostEngineNames() []string {
+	names := []string{}
+	for _, e := range hostEngines {
+		names = append(names, e.name)
+	}
+	return names
+}
+
+var (
+	// useHostEngines is whether images are looked for in all the host engines, instead of in the daemon of docker only
+	useHostEngines = false
+	// hostEngineName is the engine images are loaded from, or empty to pick the one holding the image
+	hostEngineName = ""
+	// lookPath finds the client of a host engine
+	lookPath = exec.LookPath
+	// runHostEngine runs a host engine, returning its combined output
+	runHostEngine = func(name string, args ...string) ([]byte, error) {
+		return exec.Command(name, args...).CombinedOutput()
+	}
+)
+
+// UseHostEngine looks for the images in the engine of the host name, or in the first installed engine holding each image if empty
+func UseHostEngine(name string) error {
+	if name != "" {
+		if _, err := findHostEngine(name); err != nil {
+			return err
+		}
+	}
+	useHostEngines = true
+	hostEngineName = name
+	return nil
+}
+
+func findHostEngine(name string) (hostEngine, error) {
+	for _, e := range hostEngines {
+		if e.name == name {
+			return e, nil
+		}
+	}
+	return hostEngine{}, fmt.Errorf("unknown host engine %q, expected one of %s", name, strings.Join(HostEngineNames(), ", "))
+}
+
+// availableHostEngines returns the engines whose client is installed, in the order of hostEngines
+func availableHostEngines() []hostEngine {
+	available := []hostEngine{}
+	for _, e := range hostEngines {
+		if _, err := lookPath(e.name); err != nil {
+			continue
+		}
+		available = append(available, e)
+	}
+	return available
+}
+
+// hostEngineHolding returns the engine to load an image from: the one set by UseHostEngine, else the first installed one
+// holding the image. ok is false if no engine holds it, to look for it in the registry instead.
+func hostEngineHolding(ref string) (e hostEngine, ok bool, err error) {
+	if hostEngineName != "" {
+		e, err := findHostEngine(hostEngineName)
+		if err != nil {
+			return e, false, err
+		}
+		// the daemon of docker is reached without its client
+		if e.saveArgs == nil {
+			return e, true, nil
+		}
+		if _, err := lookPath(e.name); err != nil {
+			return e, false, errors.Errorf("the host engine %s is not installed", e.name)
+		}
+		return e, true, nil
+	}
+	holding := []string{}
+	for _, e := range availableHostEngines() {
+		if _, err := runHostEngine(e.name, "image", "inspect", ref); err != nil {
+			continue
+		}
+		holding = append(holding, e.name)
+	}
+	if len(holding) == 0 {
+		return hostEngine{}, false, nil
+	}
+	if len(holding) > 1 {
+		klog.Infof("%s is in the host engines %v, loading it from %s, select another with --host-engine", ref, holding, holding[0])
+	}
+	e, err = findHostEngine(holding[0])
+	return e, err == nil, err
+}
+
+// saveFromHostEngine saves an image of a host engine to the docker archive dst, which the cache loads into the nodes.
+// It returns false for the images of docker, which are read from its daemon.
+func saveFromHostEngine(ref, dst string) (bool, error) {
+	if !useHostEngines {
+		return false, nil
+	}
+	e, ok, err := hostEngineHolding(ref)
+	if err != nil || !ok || e.saveArgs == nil {
+		return false, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	// only reserve the name, for the engine to create the archive
+	if err := os.Remove(tmp.Name()); err != nil {
+		return false, err
+	}
+	klog.Infof("saving %s from %s to %s", ref, e.name, dst)
+	if b, err := runHostEngine(e.name, e.saveArgs(ref, tmp.Name())...); err != nil {
+		return false, errors.Wrapf(err, "%s save: %s", e.name, b)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return false, errors.Wrap(err, "rename")
+	}
+	return true, nil
+}
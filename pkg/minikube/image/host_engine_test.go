@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeHostEngines installs the engines of installed, holding the images of images by engine, and records the commands run
+func fakeHostEngines(t *testing.T, installed []string, images map[string][]string) *[]string {
+	oldLookPath, oldRun, oldUse, oldName := lookPath, runHostEngine, useHostEngines, hostEngineName
+	t.Cleanup(func() {
+		lookPath, runHostEngine, useHostEngines, hostEngineName = oldLookPath, oldRun, oldUse, oldName
+	})
+	ran := []string{}
+	lookPath = func(name string) (string, error) {
+		for _, i := range installed {
+			if i == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", fmt.Errorf("%s: executable file not found in $PATH", name)
+	}
+	runHostEngine = func(name string, args ...string) ([]byte, error) {
+		ran = append(ran, name+" "+strings.Join(args, " "))
+		if args[0] == "image" && args[1] == "inspect" {
+			for _, img := range images[name] {
+				if img == args[2] {
+					return []byte("[{}]"), nil
+				}
+			}
+			return []byte("Error: no such image"), fmt.Errorf("exit status 1")
+		}
+		if args[0] == "save" {
+			return nil, os.WriteFile(args[len(args)-2], []byte("archive of "+name), 0644)
+		}
+		return nil, nil
+	}
+	return &ran
+}
+
+func TestAvailableHostEngines(t *testing.T) {
+	fakeHostEngines(t, []string{"nerdctl", "podman"}, nil)
+	got := []string{}
+	for _, e := range availableHostEngines() {
+		got = append(got, e.name)
+	}
+	if diff := cmp.Diff([]string{"podman", "nerdctl"}, got); diff != "" {
+		t.Errorf("availableHostEngines() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHostEngineHolding(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []string
+		images    map[string][]string
+		use       string
+		want      string
+		wantErr   bool
+	}{
+		{name: "only podman", installed: []string{"docker", "podman", "nerdctl"}, images: map[string][]string{"podman": {"app:latest"}}, want: "podman"},
+		{name: "only nerdctl", installed: []string{"docker", "nerdctl"}, images: map[string][]string{"nerdctl": {"app:latest"}}, want: "nerdctl"},
+		{name: "docker first", installed: []string{"docker", "podman"}, images: map[string][]string{"docker": {"app:latest"}, "podman": {"app:latest"}}, want: "docker"},
+		{name: "podman before nerdctl", installed: []string{"podman", "nerdctl"}, images: map[string][]string{"podman": {"app:latest"}, "nerdctl": {"app:latest"}}, want: "podman"},
+		{name: "not installed", installed: []string{"docker"}, images: map[string][]string{"podman": {"app:latest"}}},
+		{name: "nowhere", installed: []string{"docker", "podman"}},
+		{name: "selected", installed: []string{"docker", "podman"}, images: map[string][]string{"docker": {"app:latest"}}, use: "podman", want: "podman"},
+		{name: "selected docker without client", use: "docker", want: "docker"},
+		{name: "selected not installed", installed: []string{"docker"}, use: "nerdctl", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeHostEngines(t, tc.installed, tc.images)
+			if err := UseHostEngine(tc.use); err != nil {
+				t.Fatalf("UseHostEngine(%q): %v", tc.use, err)
+			}
+			e, ok, err := hostEngineHolding("app:latest")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("hostEngineHolding() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if ok != (tc.want != "") || (ok && e.name != tc.want) {
+				t.Errorf("hostEngineHolding() = %q, %t, want %q", e.name, ok, tc.want)
+			}
+		})
+	}
+	if err := UseHostEngine("buildah"); err == nil {
+		t.Errorf("UseHostEngine(buildah) accepted an unknown engine")
+	}
+}
+
+func TestSaveFromHostEngine(t *testing.T) {
+	for _, tc := range []struct {
+		engine string
+		want   []string
+	}{
+		{engine: "podman", want: []string{"podman image inspect app:latest", "podman save --format docker-archive -o ARCHIVE app:latest"}},
+		{engine: "nerdctl", want: []string{"nerdctl image inspect app:latest", "nerdctl save -o ARCHIVE app:latest"}},
+	} {
+		t.Run(tc.engine, func(t *testing.T) {
+			ran := fakeHostEngines(t, []string{tc.engine}, map[string][]string{tc.engine: {"app:latest"}})
+			dst := filepath.Join(t.TempDir(), "app_latest")
+
+			// only image load looks for the images in the other engines
+			if saved, err := saveFromHostEngine("app:latest", dst); saved || err != nil {
+				t.Fatalf("saveFromHostEngine() without UseHostEngine = %t, %v", saved, err)
+			}
+
+			if err := UseHostEngine(""); err != nil {
+				t.Fatalf("UseHostEngine: %v", err)
+			}
+			saved, err := saveFromHostEngine("app:latest", dst)
+			if err != nil || !saved {
+				t.Fatalf("saveFromHostEngine() = %t, %v", saved, err)
+			}
+			b, err := os.ReadFile(dst)
+			if err != nil || string(b) != "archive of "+tc.engine {
+				t.Errorf("archive = %q, %v", b, err)
+			}
+			got := []string{}
+			for _, c := range *ran {
+				fields := strings.Fields(c)
+				for i, f := range fields {
+					if strings.HasPrefix(f, dst) {
+						fields[i] = "ARCHIVE"
+					}
+				}
+				got = append(got, strings.Join(fields, " "))
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("host engine commands mismatch (-want +got):\n%s", diff)
+			}
+			if matches, _ := filepath.Glob(dst + ".*.tmp"); len(matches) != 0 {
+				t.Errorf("temporary archives were left: %v", matches)
+			}
+		})
+	}
+}
+
+func TestSaveFromHostEngineDocker(t *testing.T) {
+	ran := fakeHostEngines(t, []string{"docker", "podman"}, map[string][]string{"docker": {"app:latest"}})
+	if err := UseHostEngine(""); err != nil {
+		t.Fatalf("UseHostEngine: %v", err)
+	}
+	// the images of docker are read from its daemon by the caller
+	saved, err := saveFromHostEngine("app:latest", filepath.Join(t.TempDir(), "app_latest"))
+	if err != nil || saved {
+		t.Errorf("saveFromHostEngine() = %t, %v, want the daemon of docker to be used", saved, err)
+	}
+	for _, c := range *ran {
+		if strings.Contains(c, "save") {
+			t.Errorf("ran %q for an image of docker", c)
+		}
+	}
+}
@@ -275,29 +275,40 @@ func fixPlatform(ref name.Reference, img v1.Image, p v1.Platform) (v1.Image, err
 }
 
 func cleanImageCacheDir() error {
-	err := filepath.Walk(localpath.MakeMiniPath("cache", "images"), func(path string, info os.FileInfo, err error) error {
+	return removeEmptyDirs(localpath.MakeMiniPath("cache", "images"))
+}
+
+// removeEmptyDirs removes the empty directories under dir, including those left empty by removing their subdirectories
+func removeEmptyDirs(dir string) error {
+	dirs := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		// If error is not nil, it's because the path was already deleted and doesn't exist
 		// Move on to next path
 		if err != nil {
 			return nil
 		}
-		// Check if path is directory
-		if !info.IsDir() {
-			return nil
+		if info.IsDir() {
+			dirs = append(dirs, path)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// subdirectories come after their parent
+	for i := len(dirs) - 1; i >= 0; i-- {
 		// If directory is empty, delete it
-		entries, err := os.ReadDir(path)
+		entries, err := os.ReadDir(dirs[i])
 		if err != nil {
 			return err
 		}
 		if len(entries) == 0 {
-			if err = os.Remove(path); err != nil {
+			if err = os.Remove(dirs[i]); err != nil {
 				return err
 			}
 		}
-		return nil
-	})
-	return err
+	}
+	return nil
 }
 
 // normalizeTagName automatically tag latest to image
@@ -320,8 +331,46 @@ func normalizeTagName(image string) string {
 	return base + ":" + tag
 }
 
+// NormalizeReference returns the fully qualified form of an image reference, as docker.io/library/busybox:latest for busybox,
+// dropping the tag of the references pinned by digest. The first component of the reference is its registry when it holds
+// a dot or a port, or is localhost, as in docker. References which are not valid, as those with uppercase letters, are an error.
+//
+//	busybox -> docker.io/library/busybox:latest
+//	localhost:5000/app -> localhost:5000/app:latest
+//	localhost/app:1 -> localhost/app:1
+//	registry.k8s.io/pause:3.7@sha256:... -> registry.k8s.io/pause@sha256:...
+func NormalizeReference(ref string) (string, error) {
+	opts := []name.Option{name.WeakValidation}
+	repo := strings.TrimSpace(ref)
+	// go-containerregistry only tells registries by their dots and ports
+	if strings.HasPrefix(repo, "localhost/") {
+		repo = strings.TrimPrefix(repo, "localhost/")
+		opts = append(opts, name.WithDefaultRegistry("localhost"))
+	}
+	r, err := name.ParseReference(repo, opts...)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing reference %q", ref)
+	}
+	return canonicalName(r), nil
+}
+
 // Remove docker.io prefix since it won't be included in image names
 // when we call `docker images`.
 func TrimDockerIO(name string) string {
 	return strings.TrimPrefix(name, "docker.io/")
 }
+
+// Retag returns the name of an image within another registry, keeping its repository path and tag
+//
+//	busybox, 10.96.0.5:80 -> 10.96.0.5:80/busybox:latest
+//	docker.io/library/busybox:1.35, 10.96.0.5:80 -> 10.96.0.5:80/library/busybox:1.35
+//	localhost:5000/app/web:v1, 10.96.0.5:80 -> 10.96.0.5:80/app/web:v1
+func Retag(img string, registry string) string {
+	img = normalizeTagName(img)
+	parts := strings.SplitN(img, "/", 2)
+	// the first component is a registry when it looks like a host, as in go-containerregistry
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		img = parts[1]
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + img
+}
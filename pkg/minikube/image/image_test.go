@@ -91,3 +91,61 @@ func TestNormalizeImageName(t *testing.T) {
 		})
 	}
 }
+
+func TestRetag(t *testing.T) {
+	cases := []struct {
+		image    string
+		expected string
+	}{
+		{"busybox", "10.96.0.5:80/busybox:latest"},
+		{"busybox:1.35", "10.96.0.5:80/busybox:1.35"},
+		{"docker.io/library/busybox:1.35", "10.96.0.5:80/library/busybox:1.35"},
+		{"localhost:5000/app/web:v1", "10.96.0.5:80/app/web:v1"},
+		{"localhost/web", "10.96.0.5:80/web:latest"},
+		{"team/web", "10.96.0.5:80/team/web:latest"},
+	}
+	for _, c := range cases {
+		t.Run(c.image, func(t *testing.T) {
+			if got := Retag(c.image, "10.96.0.5:80"); got != c.expected {
+				t.Errorf("Retag(%q) = %q, want %q", c.image, got, c.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeReference(t *testing.T) {
+	digest := "sha256:18eb69d1418e854ad5a19e399310e52808a8321e4c441c1dddad8977a0d7a944"
+	cases := []struct {
+		image    string
+		expected string
+		wantErr  bool
+	}{
+		{image: "busybox", expected: "docker.io/library/busybox:latest"},
+		{image: "busybox:1.35", expected: "docker.io/library/busybox:1.35"},
+		{image: "docker.io/library/busybox:1.35", expected: "docker.io/library/busybox:1.35"},
+		{image: "index.docker.io/kubernetesui/dashboard:v2.6.0", expected: "docker.io/kubernetesui/dashboard:v2.6.0"},
+		{image: "kubernetesui/dashboard", expected: "docker.io/kubernetesui/dashboard:latest"},
+		{image: "registry.k8s.io/pause:3.7", expected: "registry.k8s.io/pause:3.7"},
+		{image: "localhost:5000/myimage", expected: "localhost:5000/myimage:latest"},
+		{image: "localhost/app:1", expected: "localhost/app:1"},
+		{image: "myregistry:443/team/app", expected: "myregistry:443/team/app:latest"},
+		{image: "myregistry:443/team/app:v1", expected: "myregistry:443/team/app:v1"},
+		{image: "busybox@" + digest, expected: "docker.io/library/busybox@" + digest},
+		{image: "gcr.io/k8s-minikube/storage-provisioner:v5@" + digest, expected: "gcr.io/k8s-minikube/storage-provisioner@" + digest},
+		{image: "localhost:5000/app:v1@" + digest, expected: "localhost:5000/app@" + digest},
+		{image: "BusyBox", wantErr: true},
+		{image: "localhost:5000/MyImage", wantErr: true},
+		{image: "", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.image, func(t *testing.T) {
+			got, err := NormalizeReference(c.image)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("NormalizeReference(%q) error = %v, want error: %v", c.image, err, c.wantErr)
+			}
+			if got != c.expected {
+				t.Errorf("NormalizeReference(%q) = %q, want %q", c.image, got, c.expected)
+			}
+		})
+	}
+}
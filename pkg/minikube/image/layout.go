@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/validate"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ociRefName is the annotation naming an image of an OCI layout, either a tag or a full reference
+	ociRefName = "org.opencontainers.image.ref.name"
+	// containerdImageName is the annotation holding the full reference of an image, set by containerd and nerdctl
+	containerdImageName = "io.containerd.image.name"
+)
+
+// IsOCILayout returns whether dir is an OCI image layout, as written by buildah or rules_oci
+func IsOCILayout(dir string) bool {
+	for _, f := range []string{"oci-layout", "index.json"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ArchiveFromLayout writes an image of the OCI layout at dir to a docker archive at dst, which all the runtimes load,
+// and returns the name of the image in the archive. ref selects the image by annotation when the layout holds several,
+// and tag names it, defaulting to the full reference in its annotations.
+func ArchiveFromLayout(dir, ref, tag, dst string) (string, error) {
+	if err := validateLayoutVersion(dir); err != nil {
+		return "", err
+	}
+	idx, err := layout.ImageIndexFromPath(dir)
+	if err != nil {
+		return "", errors.Wrap(err, "reading index")
+	}
+	im, err := idx.IndexManifest()
+	if err != nil {
+		return "", errors.Wrap(err, "reading index manifest")
+	}
+	desc, err := selectLayoutManifest(im.Manifests, ref)
+	if err != nil {
+		return "", err
+	}
+	img, err := layoutImage(idx, desc)
+	if err != nil {
+		return "", err
+	}
+	if err := validate.Image(img, validate.Fast); err != nil {
+		return "", errors.Wrapf(err, "invalid image %s", desc.Digest)
+	}
+
+	if tag == "" {
+		tag = layoutImageName(desc)
+	}
+	if tag == "" {
+		return "", fmt.Errorf("the image %s of the layout has no full reference in its annotations, please name it", desc.Digest)
+	}
+	t, err := name.NewTag(tag)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing %s", tag)
+	}
+	klog.Infof("writing image %s of %s as %s to %s", desc.Digest, dir, t, dst)
+	if err := tarball.WriteToFile(dst, t, img); err != nil {
+		return "", errors.Wrap(err, "writing archive")
+	}
+	return t.String(), nil
+}
+
+// validateLayoutVersion returns an error unless dir holds the oci-layout file of version 1.0.0
+func validateLayoutVersion(dir string) error {
+	b, err := os.ReadFile(filepath.Join(dir, "oci-layout"))
+	if err != nil {
+		return errors.Wrap(err, "not an OCI layout")
+	}
+	var l struct {
+		ImageLayoutVersion string `json:"imageLayoutVersion"`
+	}
+	if err := json.Unmarshal(b, &l); err != nil {
+		return errors.Wrap(err, "parsing oci-layout")
+	}
+	if l.ImageLayoutVersion != "1.0.0" {
+		return fmt.Errorf("unsupported OCI layout version %q", l.ImageLayoutVersion)
+	}
+	return nil
+}
+
+// selectLayoutManifest returns the manifest of the index annotated with ref, or the only one if ref is empty
+func selectLayoutManifest(manifests []v1.Descriptor, ref string) (v1.Descriptor, error) {
+	if len(manifests) == 0 {
+		return v1.Descriptor{}, fmt.Errorf("the layout holds no image")
+	}
+	if ref == "" {
+		if len(manifests) > 1 {
+			return v1.Descriptor{}, fmt.Errorf("the layout holds %d images, please select one of: %s", len(manifests), strings.Join(layoutRefs(manifests), ", "))
+		}
+		return manifests[0], nil
+	}
+	for _, m := range manifests {
+		if m.Annotations[ociRefName] == ref || m.Annotations[containerdImageName] == ref {
+			return m, nil
+		}
+	}
+	return v1.Descriptor{}, fmt.Errorf("the layout holds no image annotated %q, please select one of: %s", ref, strings.Join(layoutRefs(manifests), ", "))
+}
+
+// layoutRefs returns the annotations selecting the manifests of an index
+func layoutRefs(manifests []v1.Descriptor) []string {
+	refs := []string{}
+	for _, m := range manifests {
+		r := m.Annotations[ociRefName]
+		if r == "" {
+			r = m.Annotations[containerdImageName]
+		}
+		if r == "" {
+			r = fmt.Sprintf("(unnamed %s)", m.Digest)
+		}
+		refs = append(refs, r)
+	}
+	return refs
+}
+
+// layoutImage returns the image of a manifest of the index, picking the one of the platform of the node from a nested index
+func layoutImage(idx v1.ImageIndex, desc v1.Descriptor) (v1.Image, error) {
+	if !desc.MediaType.IsIndex() {
+		img, err := idx.Image(desc.Digest)
+		return img, errors.Wrapf(err, "reading image %s", desc.Digest)
+	}
+	child, err := idx.ImageIndex(desc.Digest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading index %s", desc.Digest)
+	}
+	im, err := child.IndexManifest()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading index manifest %s", desc.Digest)
+	}
+	for _, m := range im.Manifests {
+		if m.Platform != nil && m.Platform.OS == defaultPlatform.OS && m.Platform.Architecture == defaultPlatform.Architecture {
+			img, err := child.Image(m.Digest)
+			return img, errors.Wrapf(err, "reading image %s", m.Digest)
+		}
+	}
+	return nil, fmt.Errorf("the image %s has no variant for %s/%s", desc.Digest, defaultPlatform.OS, defaultPlatform.Architecture)
+}
+
+// layoutImageName returns the full reference of an image from its annotations, or "" if they only hold a tag
+func layoutImageName(desc v1.Descriptor) string {
+	if n := desc.Annotations[containerdImageName]; n != "" {
+		return n
+	}
+	// a bare ref.name such as "latest" is only a tag, without the repository of the image
+	if n := desc.Annotations[ociRefName]; strings.ContainsAny(n, "/:") {
+		return n
+	}
+	return ""
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// writeLayout writes an OCI layout holding the images with the given annotations, and returns its directory
+func writeLayout(t *testing.T, annotations ...map[string]string) (string, []v1.Image) {
+	t.Helper()
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		t.Fatalf("writing layout: %v", err)
+	}
+	imgs := []v1.Image{}
+	for _, a := range annotations {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatalf("random image: %v", err)
+		}
+		if err := p.AppendImage(img, layout.WithAnnotations(a)); err != nil {
+			t.Fatalf("appending image: %v", err)
+		}
+		imgs = append(imgs, img)
+	}
+	return dir, imgs
+}
+
+// archivedConfig returns the config digest of the image named tag in the docker archive at path
+func archivedConfig(t *testing.T, path, tag string) v1.Hash {
+	t.Helper()
+	nt, err := name.NewTag(tag)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", tag, err)
+	}
+	img, err := tarball.ImageFromPath(path, &nt)
+	if err != nil {
+		t.Fatalf("reading archive: %v", err)
+	}
+	h, err := img.ConfigName()
+	if err != nil {
+		t.Fatalf("config name: %v", err)
+	}
+	return h
+}
+
+func configName(t *testing.T, img v1.Image) v1.Hash {
+	t.Helper()
+	h, err := img.ConfigName()
+	if err != nil {
+		t.Fatalf("config name: %v", err)
+	}
+	return h
+}
+
+func TestArchiveFromLayout(t *testing.T) {
+	t.Run("single image", func(t *testing.T) {
+		dir, imgs := writeLayout(t, map[string]string{containerdImageName: "example.com/app:1.0"})
+		if !IsOCILayout(dir) {
+			t.Fatalf("IsOCILayout(%s) = false", dir)
+		}
+		dst := filepath.Join(t.TempDir(), "app.tar")
+		got, err := ArchiveFromLayout(dir, "", "", dst)
+		if err != nil {
+			t.Fatalf("ArchiveFromLayout: %v", err)
+		}
+		if got != "example.com/app:1.0" {
+			t.Errorf("ArchiveFromLayout() = %s, want example.com/app:1.0", got)
+		}
+		if archivedConfig(t, dst, got) != configName(t, imgs[0]) {
+			t.Errorf("the archive does not hold the image of the layout")
+		}
+	})
+
+	t.Run("select by annotation", func(t *testing.T) {
+		dir, imgs := writeLayout(t, map[string]string{ociRefName: "v1"}, map[string]string{ociRefName: "v2"})
+		dst := filepath.Join(t.TempDir(), "app.tar")
+		if _, err := ArchiveFromLayout(dir, "", "app:v2", dst); err == nil || !strings.Contains(err.Error(), "v1, v2") {
+			t.Errorf("ArchiveFromLayout() without ref = %v, want the refs to select from", err)
+		}
+		got, err := ArchiveFromLayout(dir, "v2", "app:v2", dst)
+		if err != nil {
+			t.Fatalf("ArchiveFromLayout: %v", err)
+		}
+		if archivedConfig(t, dst, got) != configName(t, imgs[1]) {
+			t.Errorf("the archive does not hold the selected image")
+		}
+		if _, err := ArchiveFromLayout(dir, "v3", "app:v3", dst); err == nil {
+			t.Errorf("ArchiveFromLayout() with an unknown ref succeeded")
+		}
+	})
+
+	t.Run("bare tag", func(t *testing.T) {
+		dir, _ := writeLayout(t, map[string]string{ociRefName: "latest"})
+		if _, err := ArchiveFromLayout(dir, "", "", filepath.Join(t.TempDir(), "app.tar")); err == nil {
+			t.Errorf("ArchiveFromLayout() named an image from a bare tag")
+		}
+	})
+
+	t.Run("nested index", func(t *testing.T) {
+		dir := t.TempDir()
+		p, err := layout.Write(dir, empty.Index)
+		if err != nil {
+			t.Fatalf("writing layout: %v", err)
+		}
+		other, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatalf("random image: %v", err)
+		}
+		want, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatalf("random image: %v", err)
+		}
+		idx := mutate.AppendManifests(empty.Index,
+			mutate.IndexAddendum{Add: other, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "windows", Architecture: defaultPlatform.Architecture}}},
+			mutate.IndexAddendum{Add: want, Descriptor: v1.Descriptor{Platform: &defaultPlatform}},
+		)
+		if err := p.AppendIndex(idx, layout.WithAnnotations(map[string]string{ociRefName: "example.com/app:multi"})); err != nil {
+			t.Fatalf("appending index: %v", err)
+		}
+		dst := filepath.Join(t.TempDir(), "app.tar")
+		got, err := ArchiveFromLayout(dir, "", "", dst)
+		if err != nil {
+			t.Fatalf("ArchiveFromLayout: %v", err)
+		}
+		if archivedConfig(t, dst, got) != configName(t, want) {
+			t.Errorf("the archive does not hold the image of the platform of the node")
+		}
+	})
+
+	t.Run("invalid layout", func(t *testing.T) {
+		dir, _ := writeLayout(t, map[string]string{containerdImageName: "example.com/app:1.0"})
+		if err := os.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion":"2.0.0"}`), 0644); err != nil {
+			t.Fatalf("writing oci-layout: %v", err)
+		}
+		if _, err := ArchiveFromLayout(dir, "", "", filepath.Join(t.TempDir(), "app.tar")); err == nil {
+			t.Errorf("ArchiveFromLayout() accepted an unsupported layout version")
+		}
+		if IsOCILayout(t.TempDir()) {
+			t.Errorf("IsOCILayout() = true for an empty directory")
+		}
+	})
+}
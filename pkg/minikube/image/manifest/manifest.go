@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package manifest finds the images the workloads of Kubernetes manifests run, such as the output of 'helm template'
+package manifest
+
+import (
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// podSpecPaths are the paths of the pod specs in the built-in workloads, by kind, for the API groups they are served by.
+// Resources of other kinds or groups, such as custom resources, are ignored.
+var podSpecPaths = map[string]struct {
+	groups []string
+	path   []string
+}{
+	"Pod":                   {groups: []string{""}, path: []string{"spec"}},
+	"PodTemplate":           {groups: []string{""}, path: []string{"template", "spec"}},
+	"ReplicationController": {groups: []string{""}, path: []string{"spec", "template", "spec"}},
+	"Deployment":            {groups: []string{"apps", "extensions"}, path: []string{"spec", "template", "spec"}},
+	"ReplicaSet":            {groups: []string{"apps", "extensions"}, path: []string{"spec", "template", "spec"}},
+	"DaemonSet":             {groups: []string{"apps", "extensions"}, path: []string{"spec", "template", "spec"}},
+	"StatefulSet":           {groups: []string{"apps"}, path: []string{"spec", "template", "spec"}},
+	"Job":                   {groups: []string{"batch"}, path: []string{"spec", "template", "spec"}},
+	"CronJob":               {groups: []string{"batch"}, path: []string{"spec", "jobTemplate", "spec", "template", "spec"}},
+}
+
+// containerFields are the fields of pod specs listing containers
+var containerFields = []string{"initContainers", "containers", "ephemeralContainers"}
+
+// Images returns the images run by the workloads of the YAML or JSON manifests read from r, in the order they are first referenced, without duplicates.
+// The documents may be separated by "---", and lists of resources are looked into.
+func Images(r ...io.Reader) ([]string, error) {
+	images := []string{}
+	seen := map[string]bool{}
+	for _, rd := range r {
+		d := yaml.NewYAMLOrJSONDecoder(rd, 4096)
+		for {
+			var doc map[string]interface{}
+			if err := d.Decode(&doc); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, errors.Wrap(err, "decoding manifest")
+			}
+			for _, img := range resourceImages(doc) {
+				if !seen[img] {
+					seen[img] = true
+					images = append(images, img)
+				}
+			}
+		}
+	}
+	return images, nil
+}
+
+// resourceImages returns the images of the containers of a resource, or of the items of a list
+func resourceImages(res map[string]interface{}) []string {
+	if res == nil {
+		return nil
+	}
+	kind, _ := res["kind"].(string)
+	if items, ok := res["items"].([]interface{}); ok && strings.HasSuffix(kind, "List") {
+		images := []string{}
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				images = append(images, resourceImages(m)...)
+			}
+		}
+		return images
+	}
+	p, ok := podSpecPaths[kind]
+	if !ok || !servedBy(res, p.groups) {
+		return nil
+	}
+	spec, ok := lookup(res, p.path)
+	if !ok {
+		return nil
+	}
+	images := []string{}
+	for _, f := range containerFields {
+		containers, _ := spec[f].([]interface{})
+		for _, c := range containers {
+			m, _ := c.(map[string]interface{})
+			if img, _ := m["image"].(string); img != "" {
+				images = append(images, img)
+			}
+		}
+	}
+	return images
+}
+
+// servedBy returns whether the API group of a resource is one of groups, the core group being ""
+func servedBy(res map[string]interface{}, groups []string) bool {
+	apiVersion, _ := res["apiVersion"].(string)
+	group := ""
+	if i := strings.LastIndex(apiVersion, "/"); i >= 0 {
+		group = apiVersion[:i]
+	}
+	for _, g := range groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the object at path in res
+func lookup(res map[string]interface{}, path []string) (map[string]interface{}, bool) {
+	m := res
+	for _, k := range path {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		m = next
+	}
+	return m, true
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func open(t *testing.T, name string) io.Reader {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestImages(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{
+			name:  "multi-document helm template output",
+			files: []string{"helm-template.yaml"},
+			want: []string{
+				"example.com/app-migrate:1.0",
+				"example.com/app:1.0",
+				"envoyproxy/envoy:v1.24.0",
+				"example.com/agent@sha256:4bbf4e3c4b1d6fc3e2b6a7e8d7a19c7f8b2b6e2e4c1f9a9d8f0f4c1a7e6b2c3d",
+				"busybox:1.36",
+			},
+		},
+		{
+			name:  "list with ephemeral containers and custom resources",
+			files: []string{"list.yaml"},
+			want:  []string{"nginx:1.23", "busybox:1.36", "alpine:3.17"},
+		},
+		{
+			name:  "json",
+			files: []string{"pod.json"},
+			want:  []string{"registry.k8s.io/pause:3.9"},
+		},
+		{
+			name:  "duplicates across files",
+			files: []string{"list.yaml", "helm-template.yaml", "pod.json"},
+			want: []string{
+				"nginx:1.23",
+				"busybox:1.36",
+				"alpine:3.17",
+				"example.com/app-migrate:1.0",
+				"example.com/app:1.0",
+				"envoyproxy/envoy:v1.24.0",
+				"example.com/agent@sha256:4bbf4e3c4b1d6fc3e2b6a7e8d7a19c7f8b2b6e2e4c1f9a9d8f0f4c1a7e6b2c3d",
+				"registry.k8s.io/pause:3.9",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			readers := []io.Reader{}
+			for _, f := range tc.files {
+				readers = append(readers, open(t, f))
+			}
+			got, err := Images(readers...)
+			if err != nil {
+				t.Fatalf("Images: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Images() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImagesOfKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []string
+	}{
+		{
+			name:     "replication controller",
+			manifest: "apiVersion: v1\nkind: ReplicationController\nspec:\n  template:\n    spec:\n      containers:\n      - image: rc:1\n",
+			want:     []string{"rc:1"},
+		},
+		{
+			name:     "extensions replica set",
+			manifest: "apiVersion: extensions/v1beta1\nkind: ReplicaSet\nspec:\n  template:\n    spec:\n      containers:\n      - image: rs:1\n",
+			want:     []string{"rs:1"},
+		},
+		{
+			name:     "pod template",
+			manifest: "apiVersion: v1\nkind: PodTemplate\ntemplate:\n  spec:\n    containers:\n    - image: tmpl:1\n",
+			want:     []string{"tmpl:1"},
+		},
+		{
+			name:     "deployment list",
+			manifest: "apiVersion: apps/v1\nkind: DeploymentList\nitems:\n- apiVersion: apps/v1\n  kind: Deployment\n  spec:\n    template:\n      spec:\n        containers:\n        - image: a:1\n",
+			want:     []string{"a:1"},
+		},
+		{
+			name:     "pod in a custom group",
+			manifest: "apiVersion: example.com/v1\nkind: Pod\nspec:\n  containers:\n  - image: custom:1\n",
+			want:     []string{},
+		},
+		{
+			name:     "deployment without a template",
+			manifest: "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 1\n",
+			want:     []string{},
+		},
+		{
+			name:     "comments only",
+			manifest: "# nothing rendered\n---\n",
+			want:     []string{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Images(strings.NewReader(tc.manifest))
+			if err != nil {
+				t.Fatalf("Images: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Images() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImagesInvalid(t *testing.T) {
+	if _, err := Images(open(t, "invalid.yaml")); err == nil {
+		t.Errorf("Images(invalid.yaml) succeeded, want an error")
+	}
+}
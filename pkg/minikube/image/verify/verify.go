@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package verify checks the cosign signatures of images in their registry, before they are loaded into the cluster
+package verify
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// SignatureAnnotation is the annotation of the layers of a cosign signature image holding the base64 signature of the layer
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// signatureType is the type of the simple signing payloads of cosign
+	signatureType = "cosign container image signature"
+)
+
+// ErrUnverified is returned when no signature of an image passes all the checks
+type ErrUnverified struct {
+	// Image is the reference of the image
+	Image string
+	// Failed are the checks which failed, prefixed with the signature they failed for
+	Failed []string
+}
+
+func (e *ErrUnverified) Error() string {
+	return fmt.Sprintf("%s failed signature verification: %s", e.Image, strings.Join(e.Failed, "; "))
+}
+
+// Payload is the simple signing payload of a cosign signature
+type Payload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]interface{} `json:"optional,omitempty"`
+}
+
+// Signature is a signed payload of a cosign signature image
+type Signature struct {
+	// Payload is the signed simple signing payload
+	Payload []byte
+	// Signature is the signature of Payload
+	Signature []byte
+}
+
+// LoadKey reads a PEM encoded public key, such as the cosign.pub written by 'cosign generate-key-pair'
+func LoadKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s is not a PEM encoded public key", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", path)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%s holds an unsupported %T key", path, key)
+	}
+}
+
+// verifySignature checks that sig signs payload with key
+func verifySignature(key crypto.PublicKey, payload, sig []byte) bool {
+	sum := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, sum[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, sig)
+	}
+	return false
+}
+
+// Check returns the checks s fails for the image of digest in repo, which it passes all if empty
+func Check(s Signature, key crypto.PublicKey, repo name.Repository, digest v1.Hash) []string {
+	if !verifySignature(key, s.Payload, s.Signature) {
+		return []string{"the signature does not match the key"}
+	}
+	var p Payload
+	if err := json.Unmarshal(s.Payload, &p); err != nil {
+		return []string{fmt.Sprintf("the payload is not a simple signing payload: %v", err)}
+	}
+	var failed []string
+	if p.Critical.Type != signatureType {
+		failed = append(failed, fmt.Sprintf("the payload is a %q rather than a %q", p.Critical.Type, signatureType))
+	}
+	if p.Critical.Image.DockerManifestDigest != digest.String() {
+		failed = append(failed, fmt.Sprintf("the payload signs %s rather than %s", p.Critical.Image.DockerManifestDigest, digest))
+	}
+	// the identity is compared normalized, as cosign writes the repositories of Docker Hub as index.docker.io
+	if signed, err := name.NewRepository(p.Critical.Identity.DockerReference); err != nil || signed.Name() != repo.Name() {
+		failed = append(failed, fmt.Sprintf("the payload signs an image of %q rather than of %s", p.Critical.Identity.DockerReference, repo.Name()))
+	}
+	return failed
+}
+
+// SignatureReference returns the tag cosign stores the signatures of the image of digest in ref at
+func SignatureReference(ref name.Reference, digest v1.Hash) name.Tag {
+	return ref.Context().Tag(fmt.Sprintf("%s-%s.sig", digest.Algorithm, digest.Hex))
+}
+
+// Signatures returns the signatures of the image of digest in ref, which are none if it is unsigned
+func Signatures(ref name.Reference, digest v1.Hash, opts ...remote.Option) ([]Signature, error) {
+	img, err := remote.Image(SignatureReference(ref, digest), opts...)
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == 404 {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "fetching signatures")
+	}
+	m, err := img.Manifest()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading signature manifest")
+	}
+	var sigs []Signature
+	for _, l := range m.Layers {
+		b64, ok := l.Annotations[SignatureAnnotation]
+		if !ok {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			klog.Warningf("signature layer %s of %s has an invalid signature: %v", l.Digest, ref, err)
+			sig = nil
+		}
+		layer, err := img.LayerByDigest(l.Digest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading signature layer %s", l.Digest)
+		}
+		rc, err := layer.Compressed()
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading signature layer %s", l.Digest)
+		}
+		payload, err := io.ReadAll(rc)
+		if cerr := rc.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading signature layer %s", l.Digest)
+		}
+		sigs = append(sigs, Signature{Payload: payload, Signature: sig})
+	}
+	return sigs, nil
+}
+
+// Verify checks that an image of a registry is signed with key, returning the reference pinning its verified digest,
+// which the runtimes pull so that they do not resolve a tag again after the verification. The transparency log is not checked.
+func Verify(image string, key crypto.PublicKey, opts ...remote.Option) (string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing %s", image)
+	}
+	opts = append([]remote.Option{remote.WithAuthFromKeychain(authn.DefaultKeychain)}, opts...)
+	desc, err := remote.Head(ref, opts...)
+	if err != nil {
+		return "", errors.Wrapf(err, "resolving %s", image)
+	}
+	if d, ok := ref.(name.Digest); ok && d.DigestStr() != desc.Digest.String() {
+		return "", fmt.Errorf("%s resolved to %s", image, desc.Digest)
+	}
+	sigs, err := Signatures(ref, desc.Digest, opts...)
+	if err != nil {
+		return "", err
+	}
+	if len(sigs) == 0 {
+		return "", &ErrUnverified{Image: image, Failed: []string{fmt.Sprintf("no signature found at %s", SignatureReference(ref, desc.Digest))}}
+	}
+	var failed []string
+	for i, s := range sigs {
+		f := Check(s, key, ref.Context(), desc.Digest)
+		if len(f) == 0 {
+			klog.Infof("signature %d of %s verified for %s", i+1, image, desc.Digest)
+			return ref.Context().Digest(desc.Digest.String()).String(), nil
+		}
+		for _, c := range f {
+			failed = append(failed, fmt.Sprintf("signature %d: %s", i+1, c))
+		}
+	}
+	return "", &ErrUnverified{Image: image, Failed: failed}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+)
+
+// The fixture signature in testdata signs the digest of empty.Image in example.com/app with the key of testdata/cosign.pub
+
+// push writes img to ref of the test registry
+func push(t *testing.T, ref string, img v1.Image) v1.Hash {
+	t.Helper()
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(r, img); err != nil {
+		t.Fatalf("pushing %s: %v", ref, err)
+	}
+	d, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// sign returns the simple signing payload for the image of digest in identity, and its base64 signature with key, as 'cosign sign' writes them
+func sign(t *testing.T, key *ecdsa.PrivateKey, identity string, digest v1.Hash) ([]byte, string) {
+	t.Helper()
+	var p Payload
+	p.Critical.Identity.DockerReference = identity
+	p.Critical.Image.DockerManifestDigest = digest.String()
+	p.Critical.Type = signatureType
+	payload, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload, base64.StdEncoding.EncodeToString(sig)
+}
+
+// pushSignature writes payload and sig as the signature of the image of digest in repo, as 'cosign sign' does
+func pushSignature(t *testing.T, repo string, digest v1.Hash, payload []byte, sig string) {
+	t.Helper()
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, "application/vnd.dev.cosign.simplesigning.v1+json"),
+		Annotations: map[string]string{SignatureAnnotation: sig},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := name.ParseReference(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	push(t, SignatureReference(r, digest).String(), img)
+}
+
+func loadKey(t *testing.T, file string) crypto.PublicKey {
+	t.Helper()
+	key, err := LoadKey(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatalf("LoadKey(%s): %v", file, err)
+	}
+	return key
+}
+
+func TestLoadKey(t *testing.T) {
+	loadKey(t, "cosign.pub")
+	if _, err := LoadKey(filepath.Join("testdata", "payload.json")); err == nil {
+		t.Errorf("LoadKey(payload.json) succeeded, want an error")
+	}
+	if _, err := LoadKey(filepath.Join("testdata", "missing.pub")); err == nil {
+		t.Errorf("LoadKey(missing.pub) succeeded, want an error")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	payload, err := os.ReadFile(filepath.Join("testdata", "payload.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64, err := os.ReadFile(filepath.Join("testdata", "payload.json.sig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(b64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := empty.Image.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		repo   string
+		key    string
+		failed []string
+	}{
+		{"example.com/app", "cosign.pub", nil},
+		{"example.com/app", "other.pub", []string{"the signature does not match the key"}},
+		{"example.com/other", "cosign.pub", []string{`the payload signs an image of "example.com/app" rather than of example.com/other`}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.repo+" "+tc.key, func(t *testing.T) {
+			repo, err := name.NewRepository(tc.repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Check(Signature{Payload: payload, Signature: sig}, loadKey(t, tc.key), repo, digest)
+			if strings.Join(got, "; ") != strings.Join(tc.failed, "; ") {
+				t.Errorf("Check() = %q, want %q", got, tc.failed)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	s := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer s.Close()
+	host := strings.TrimPrefix(s.URL, "http://")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]crypto.PublicKey{"signing": key.Public(), "other.pub": loadKey(t, "other.pub")}
+
+	signed := push(t, host+"/app:v1", empty.Image)
+	payload, sig := sign(t, key, host+"/app", signed)
+	pushSignature(t, host+"/app", signed, payload, sig)
+
+	unsigned, err := mutate.ConfigFile(empty.Image, &v1.ConfigFile{Architecture: "arm64", OS: "linux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	push(t, host+"/unsigned:v1", unsigned)
+	// a signature of another image copied next to this one
+	copied := push(t, host+"/copied:v1", unsigned)
+	payload, sig = sign(t, key, host+"/copied", signed)
+	pushSignature(t, host+"/copied", copied, payload, sig)
+	// a signature of the same image in another repository
+	moved := push(t, host+"/moved:v1", empty.Image)
+	payload, sig = sign(t, key, host+"/app", moved)
+	pushSignature(t, host+"/moved", moved, payload, sig)
+
+	var tests = []struct {
+		image  string
+		key    string
+		want   string
+		failed string
+	}{
+		{host + "/app:v1", "signing", host + "/app@" + signed.String(), ""},
+		{host + "/app@" + signed.String(), "signing", host + "/app@" + signed.String(), ""},
+		{host + "/app:v1", "other.pub", "", "signature 1: the signature does not match the key"},
+		{host + "/unsigned:v1", "signing", "", "no signature found at " + host + "/unsigned:sha256-"},
+		{host + "/copied:v1", "signing", "", "signature 1: the payload signs " + signed.String() + " rather than " + copied.String()},
+		{host + "/moved:v1", "signing", "", "signature 1: the payload signs an image of \"" + host + "/app\" rather than of " + host + "/moved"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.image+" "+tc.key, func(t *testing.T) {
+			got, err := Verify(tc.image, keys[tc.key])
+			if tc.failed == "" {
+				if err != nil || got != tc.want {
+					t.Errorf("Verify() = %q, %v, want %q", got, err, tc.want)
+				}
+				return
+			}
+			var uerr *ErrUnverified
+			if !errors.As(err, &uerr) {
+				t.Fatalf("Verify() = %q, %v, want an ErrUnverified", got, err)
+			}
+			if len(uerr.Failed) != 1 || !strings.HasPrefix(uerr.Failed[0], tc.failed) {
+				t.Errorf("Verify() failed checks = %q, want %q", uerr.Failed, tc.failed)
+			}
+		})
+	}
+}
@@ -74,6 +74,12 @@ func AuditLog() string {
 	return filepath.Join(MiniPath(), "logs", "audit.json")
 }
 
+// ImageAuditLog returns the path to the image log.
+// This log contains the image operations of the audited commands, such as pulls and loads, and how long they took.
+func ImageAuditLog() string {
+	return filepath.Join(MiniPath(), "logs", "audit_images.json")
+}
+
 // LastStartLog returns the path to the last start log.
 func LastStartLog() string {
 	return filepath.Join(MiniPath(), "logs", "lastStart.txt")
@@ -21,14 +21,13 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
-	"github.com/pkg/errors"
 	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/audit"
 	"k8s.io/minikube/pkg/minikube/bootstrapper"
@@ -66,8 +65,8 @@ var rootCauses = []string{
 	`failed to start daemon`,
 }
 
-// rootCauseRe combines rootCauses into a single regex
-var rootCauseRe = regexp.MustCompile(strings.Join(rootCauses, "|"))
+// rootCauseRe combines rootCauses, and the OOM kills which take down runtimes, into a single regex
+var rootCauseRe = regexp.MustCompile(strings.Join(append(rootCauses, cruntime.OOMPatterns...), "|"))
 
 // ignoreCauseRe is a regular expression that matches spurious errors to not surface
 var ignoreCauseRe = regexp.MustCompile("error: no objects passed to apply")
@@ -93,23 +92,6 @@ type logRunner interface {
 // include usage messages from a failed binary, but small enough to not include irrelevant problems.
 const lookBackwardsCount = 400
 
-// Follow follows logs from multiple files in tail(1) format
-func Follow(r cruntime.Manager, bs bootstrapper.Bootstrapper, cfg config.ClusterConfig, cr logRunner, logOutput io.Writer) error {
-	cs := []string{}
-	for _, v := range logCommands(r, bs, cfg, 0, true) {
-		cs = append(cs, v+" &")
-	}
-	cs = append(cs, "wait")
-
-	cmd := exec.Command("/bin/bash", "-c", strings.Join(cs, " "))
-	cmd.Stdout = logOutput
-	cmd.Stderr = logOutput
-	if _, err := cr.RunCmd(cmd); err != nil {
-		return errors.Wrapf(err, "log follow")
-	}
-	return nil
-}
-
 // IsProblem returns whether this line matches a known problem
 func IsProblem(line string) bool {
 	return rootCauseRe.MatchString(line) && !ignoreCauseRe.MatchString(line)
@@ -162,6 +144,31 @@ func OutputProblems(problems map[string][]string, maxLines int, logOutput *os.Fi
 	}
 }
 
+// runtimeEventsPeriod is how far back to look for the incidents of the container runtime among the problems
+const runtimeEventsPeriod = 30 * time.Minute
+
+// OutputRuntimeEvents outputs the incidents of the container runtime in the last half hour, such as OOM kills and restarts
+func OutputRuntimeEvents(r cruntime.Manager, maxLines int, logOutput *os.File) {
+	events, err := r.Events(runtimeEventsPeriod)
+	if err != nil {
+		klog.Warningf("unable to read the events of %s: %v", r.Name(), err)
+		return
+	}
+	if len(events) == 0 {
+		return
+	}
+	out.SetErrFile(logOutput)
+	defer out.SetErrFile(os.Stderr)
+
+	out.FailureT("Incidents of {{.runtime}} in the last {{.period}}:", out.V{"runtime": r.Name(), "period": runtimeEventsPeriod})
+	if len(events) > maxLines {
+		events = events[len(events)-maxLines:]
+	}
+	for _, e := range events {
+		out.ErrT(style.LogEntry, "{{.time}} {{.source}} {{.type}} {{.subject}}: {{.message}}", out.V{"time": e.Time.Local().Format(time.RFC3339), "source": e.Source, "type": e.Type, "subject": e.Subject, "message": e.Message})
+	}
+}
+
 // Output displays logs from multiple sources in tail(1) format
 func Output(r cruntime.Manager, bs bootstrapper.Bootstrapper, cfg config.ClusterConfig, runner command.Runner, lines int, logOutput *os.File) error {
 	cmds := logCommands(r, bs, cfg, lines, false)
@@ -263,6 +270,20 @@ func OutputOffline(lines int, logOutput *os.File) {
 }
 
 // logCommands returns a list of commands that would be run to receive the anticipated logs
+// FollowUnitCommands returns the commands following the journals of the kubelet and of the container runtime, by name,
+// from their last lines on
+func FollowUnitCommands(r cruntime.Manager, bs bootstrapper.Bootstrapper, cfg config.ClusterConfig, lines int) map[string]string {
+	cmds := map[string]string{}
+	if c, ok := bs.LogCommands(cfg, bootstrapper.LogOptions{Lines: lines, Follow: true})["kubelet"]; ok {
+		cmds["kubelet"] = c
+	}
+	// the runtimes log to the journal of their units, except on hosts without systemd
+	if c := r.SystemLogCmd(lines); strings.Contains(c, "journalctl") {
+		cmds[r.Name()] = c + " -f"
+	}
+	return cmds
+}
+
 func logCommands(r cruntime.Manager, bs bootstrapper.Bootstrapper, cfg config.ClusterConfig, length int, follow bool) map[string]string {
 	cmds := bs.LogCommands(cfg, bootstrapper.LogOptions{Lines: length, Follow: follow})
 	for _, pod := range importantPods {
@@ -17,7 +17,18 @@ limitations under the License.
 package logs
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/bootstrapper"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/cruntime/cruntimetest"
 )
 
 func TestIsProblem(t *testing.T) {
@@ -51,6 +62,8 @@ func TestIsProblem(t *testing.T) {
 		{"kubelet rbac fail", true, `k8s.io/kubernetes/pkg/kubelet/kubelet.go:526: Failed to list *v1.Node: nodes "m01" is forbidden: User "system:node:m01" cannot list resource "nodes" in API group "" at the cluster scope`},
 		{"kubelet pids cgroup", true, `Failed to start ContainerManager failed to initialize top level QOS containers: failed to update top level Burstable QOS cgroup : failed to set supported cgroup subsystems for cgroup [kubepods burstable]: failed to find subsystem mount for required subsystem: pids`},
 		{"docker cgroups v2 fail", true, `failed to start daemon: Devices cgroup isn't mounted`},
+		{"dockerd oom", true, `Out of memory: Killed process 1234 (dockerd) total-vm:1843612kB, anon-rss:61124kB, file-rss:0kB, shmem-rss:0kB, UID:0 pgtables:512kB oom_score_adj:-500`},
+		{"docker unit oom", true, `docker.service: Failed with result 'oom-kill'.`},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -61,3 +74,87 @@ func TestIsProblem(t *testing.T) {
 		})
 	}
 }
+
+// fakeBootstrapper only implements LogCommands
+type fakeBootstrapper struct {
+	bootstrapper.Bootstrapper
+}
+
+func (fakeBootstrapper) LogCommands(cfg config.ClusterConfig, o bootstrapper.LogOptions) map[string]string {
+	return map[string]string{"kubelet": "sudo journalctl -u kubelet"}
+}
+
+func TestLogCommands(t *testing.T) {
+	r := cruntimetest.NewFakeRuntime()
+	r.AddContainer("a1", cruntimetest.FakeContainer{Name: "kube-apiserver", Namespace: "kube-system"})
+	r.AddContainer("a2", cruntimetest.FakeContainer{Name: "kube-apiserver", Namespace: "kube-system", State: cruntimetest.StateExited})
+	r.AddContainer("e1", cruntimetest.FakeContainer{Name: "etcd", Namespace: "kube-system"})
+
+	got := logCommands(r, fakeBootstrapper{}, config.ClusterConfig{}, 60, false)
+	want := map[string]string{
+		"kubelet":             "sudo journalctl -u kubelet",
+		"kube-apiserver [a1]": "fake logs --tail 60 a1",
+		"kube-apiserver [a2]": "fake logs --tail 60 a2",
+		"etcd [e1]":           "fake logs --tail 60 e1",
+		"fake":                "sudo journalctl -u fake -n 60",
+		"container status":    cruntime.ContainerStatusCommand(),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("logCommands() mismatch (-want +got):\n%s", diff)
+	}
+	if n := len(r.Called("ListContainers")); n != len(importantPods) {
+		t.Errorf("ListContainers called %d times, want %d", n, len(importantPods))
+	}
+
+	r.Fail("ListContainers", errors.New("runtime is down"))
+	got = logCommands(r, fakeBootstrapper{}, config.ClusterConfig{}, 60, false)
+	want = map[string]string{
+		"kubelet":          "sudo journalctl -u kubelet",
+		"fake":             "sudo journalctl -u fake -n 60",
+		"container status": cruntime.ContainerStatusCommand(),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("logCommands() with failing runtime mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFollowUnitCommands(t *testing.T) {
+	got := FollowUnitCommands(cruntimetest.NewFakeRuntime(), fakeBootstrapper{}, config.ClusterConfig{}, 60)
+	want := map[string]string{
+		"kubelet": "sudo journalctl -u kubelet",
+		"fake":    "sudo journalctl -u fake -n 60 -f",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FollowUnitCommands() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOutputRuntimeEvents(t *testing.T) {
+	r := cruntimetest.NewFakeRuntime()
+	r.RuntimeEvents = []cruntime.RuntimeEvent{
+		{Time: time.Now().Add(-2 * time.Hour), Source: "kernel", Type: cruntime.EventOOM, Subject: "etcd", Message: "Out of memory: Killed process 42 (etcd)"},
+		{Time: time.Now().Add(-10 * time.Minute), Source: "kernel", Type: cruntime.EventOOM, Subject: "dockerd", Message: "Out of memory: Killed process 812 (dockerd)"},
+		{Time: time.Now().Add(-5 * time.Minute), Source: "systemd", Type: cruntime.EventUnit, Subject: "docker.service", Message: "Started Docker Application Container Engine."},
+	}
+	f, err := os.Create(filepath.Join(t.TempDir(), "logs.txt"))
+	if err != nil {
+		t.Fatalf("creating output: %v", err)
+	}
+	defer f.Close()
+
+	OutputRuntimeEvents(r, 25, f)
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	got := string(b)
+	// only the incidents of the last half hour are among the problems
+	for _, want := range []string{"Incidents of fake", "kernel oom dockerd: Out of memory: Killed process 812 (dockerd)", "systemd unit docker.service"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("OutputRuntimeEvents() output is missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "etcd") {
+		t.Errorf("OutputRuntimeEvents() output has an incident older than half an hour:\n%s", got)
+	}
+}
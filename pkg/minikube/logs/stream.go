@@ -0,0 +1,188 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+// StreamComponents are the control plane components whose logs Stream follows by default
+var StreamComponents = []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "etcd"}
+
+// streamRetry is how long to wait before looking up the container of a component again
+var streamRetry = 2 * time.Second
+
+// streamRunner runs the commands following the journals of units, until their context is done
+type streamRunner interface {
+	RunCmdContext(context.Context, *exec.Cmd) (*command.RunResult, error)
+}
+
+// StreamOptions are the options for streaming the logs of containers
+type StreamOptions struct {
+	// Components are the names of the containers to follow, defaulting to StreamComponents
+	Components []string
+	// Units are the commands following the journals of units, such as the kubelet, by name
+	Units map[string]string
+	// Lines is how many of the last lines of each log to write first, or all of them if 0
+	Lines int
+	// Color colors the component names prefixing the lines
+	Color bool
+}
+
+// Stream follows the logs of the running containers of the components and the journals of the units concurrently,
+// writing each line after the name of its component or unit. When the log of a container ends before ctx is done,
+// as when the container restarted, Stream looks up the container again. It returns once ctx is done.
+func Stream(ctx context.Context, r cruntime.Manager, runner streamRunner, w io.Writer, opts StreamOptions) {
+	components := opts.Components
+	if len(components) == 0 {
+		components = StreamComponents
+	}
+	units := []string{}
+	for u := range opts.Units {
+		units = append(units, u)
+	}
+	sort.Strings(units)
+	width := 0
+	for _, c := range append(append([]string{}, components...), units...) {
+		if len(c) > width {
+			width = len(c)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	prefixWriterFor := func(i int, name string) *prefixWriter {
+		name = fmt.Sprintf("%-*s", width, name)
+		if opts.Color {
+			name = style.LogColor(i, name)
+		}
+		return &prefixWriter{mu: &mu, w: w, prefix: name + " | "}
+	}
+	for i, c := range components {
+		pw := prefixWriterFor(i, c)
+		wg.Add(1)
+		go func(component string) {
+			defer wg.Done()
+			streamComponent(ctx, r, component, pw, opts.Lines)
+			pw.Flush()
+		}(c)
+	}
+	for i, u := range units {
+		pw := prefixWriterFor(len(components)+i, u)
+		wg.Add(1)
+		go func(unit string) {
+			defer wg.Done()
+			streamUnit(ctx, runner, unit, opts.Units[unit], pw)
+			pw.Flush()
+		}(u)
+	}
+	wg.Wait()
+}
+
+// streamUnit follows the journal of a unit with cmd until ctx is done. journalctl keeps following the unit as it restarts.
+func streamUnit(ctx context.Context, runner streamRunner, unit, cmd string, w io.Writer) {
+	c := exec.Command("/bin/bash", "-c", cmd)
+	c.Stdout = w
+	c.Stderr = w
+	if _, err := runner.RunCmdContext(ctx, c); err != nil && ctx.Err() == nil {
+		klog.Warningf("journal of %s: %v", unit, err)
+	}
+	klog.Infof("journal of %s ended", unit)
+}
+
+// streamComponent follows the log of the running container of a component, looking it up again whenever the log ends, until ctx is done
+func streamComponent(ctx context.Context, r cruntime.Manager, component string, w io.Writer, lines int) {
+	last := ""
+	for ctx.Err() == nil {
+		ids, err := r.ListContainers(cruntime.ListContainersOptions{State: cruntime.Running, Name: component})
+		switch {
+		case err != nil:
+			klog.Warningf("unable to list the %s containers: %v", component, err)
+		case len(ids) == 0:
+			klog.Infof("no %s container is running", component)
+		default:
+			id := ids[0]
+			opts := cruntime.StreamLogsOptions{Lines: lines, Follow: true}
+			if last != "" && id != last {
+				// the log of the new container is all new
+				opts.Lines = 0
+				fmt.Fprintf(w, "==> restarted as container %s <==\n", id)
+			}
+			last = id
+			if err := r.StreamContainerLogs(ctx, id, w, opts); err != nil && ctx.Err() == nil {
+				klog.Warningf("log of %s [%s]: %v", component, id, err)
+			}
+			klog.Infof("log of %s [%s] ended", component, id)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(streamRetry):
+		}
+	}
+}
+
+// prefixWriter writes the whole lines written to it to w, each after the prefix, one writer at a time
+type prefixWriter struct {
+	// mu is shared by all the writers to w
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	// partial is the last line written, until its newline is
+	partial []byte
+}
+
+// Write writes the lines ending in p, keeping its last line until it ends
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	pw.partial = append(pw.partial, p...)
+	for {
+		i := bytes.IndexByte(pw.partial, '\n')
+		if i == -1 {
+			return len(p), nil
+		}
+		if _, err := io.WriteString(pw.w, pw.prefix+string(pw.partial[:i+1])); err != nil {
+			return 0, err
+		}
+		pw.partial = pw.partial[i+1:]
+	}
+}
+
+// Flush writes the last line, if it did not end
+func (pw *prefixWriter) Flush() {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	if len(pw.partial) == 0 {
+		return
+	}
+	if _, err := io.WriteString(pw.w, pw.prefix+string(pw.partial)+"\n"); err != nil {
+		klog.Warningf("unable to write log: %v", err)
+	}
+	pw.partial = nil
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime/cruntimetest"
+)
+
+// syncBuffer is a bytes.Buffer safe to write and read concurrently
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+// waitFor waits until the buffer contains all of the strings
+func waitFor(t *testing.T, b *syncBuffer, want ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		missing := false
+		for _, w := range want {
+			if !strings.Contains(b.String(), w) {
+				missing = true
+			}
+		}
+		if !missing {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %q in:\n%s", want, b.String())
+}
+
+// fakeJournal writes the lines of the journals of the commands it runs, and follows them until cancelled
+type fakeJournal struct {
+	mu    sync.Mutex
+	lines map[string]string
+	ran   []string
+}
+
+func (f *fakeJournal) RunCmdContext(ctx context.Context, cmd *exec.Cmd) (*command.RunResult, error) {
+	f.mu.Lock()
+	f.ran = append(f.ran, strings.Join(cmd.Args, " "))
+	line := f.lines[cmd.Args[len(cmd.Args)-1]]
+	f.mu.Unlock()
+	if _, err := io.WriteString(cmd.Stdout, line); err != nil {
+		return nil, err
+	}
+	<-ctx.Done()
+	return &command.RunResult{Args: cmd.Args}, ctx.Err()
+}
+
+func TestStream(t *testing.T) {
+	defer func(d time.Duration) { streamRetry = d }(streamRetry)
+	streamRetry = 10 * time.Millisecond
+
+	r := cruntimetest.NewFakeRuntime()
+	r.AddContainer("a1", cruntimetest.FakeContainer{Name: "kube-apiserver", Namespace: "kube-system"})
+	r.AddContainer("e1", cruntimetest.FakeContainer{Name: "etcd", Namespace: "kube-system"})
+	r.Logs["a1"] = []string{"old apiserver line", "apiserver line 1", "apiserver line 2"}
+	r.Logs["e1"] = []string{"etcd line 1", "etcd line 2", "etcd line 3"}
+	r.Logs["a2"] = []string{"restarted apiserver line"}
+
+	const kubelet = "sudo journalctl -u kubelet -n 2 -f"
+	journal := &fakeJournal{lines: map[string]string{kubelet: "kubelet line 1\nkubelet line 2\n"}}
+
+	var out syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Stream(ctx, r, journal, &out, StreamOptions{Components: []string{"kube-apiserver", "etcd"}, Units: map[string]string{"kubelet": kubelet}, Lines: 2})
+		close(done)
+	}()
+
+	waitFor(t, &out, "kube-apiserver | apiserver line 2", "etcd           | etcd line 3", "kubelet        | kubelet line 2")
+
+	// the apiserver restarts in a new container
+	if err := r.StopContainers([]string{"a1"}); err != nil {
+		t.Fatalf("StopContainers: %v", err)
+	}
+	r.AddContainer("a2", cruntimetest.FakeContainer{Name: "kube-apiserver", Namespace: "kube-system"})
+	waitFor(t, &out, "kube-apiserver | ==> restarted as container a2 <==", "kube-apiserver | restarted apiserver line")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Stream did not return once cancelled")
+	}
+
+	// every line is whole, after the name of its component
+	line := regexp.MustCompile(`^(kube-apiserver \| (apiserver line \d|==> restarted as container a2 <==|restarted apiserver line)|etcd {11}\| etcd line \d|kubelet {8}\| kubelet line \d)$`)
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	for _, l := range got {
+		if !line.MatchString(l) {
+			t.Errorf("unexpected line %q", l)
+		}
+	}
+	if strings.Contains(out.String(), "old apiserver line") || strings.Contains(out.String(), "etcd line 1") {
+		t.Errorf("Stream wrote more than the last 2 lines of the logs:\n%s", out.String())
+	}
+	if len(journal.ran) != 1 || journal.ran[0] != "/bin/bash -c "+kubelet {
+		t.Errorf("Stream ran %q, want the kubelet journal followed once", journal.ran)
+	}
+	for _, call := range r.Called("StreamContainerLogs") {
+		if strings.HasPrefix(call, "a2 ") && call != "a2 0 true" {
+			t.Errorf("StreamContainerLogs(%s), want all the log of the restarted container", call)
+		}
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var mu sync.Mutex
+	var b bytes.Buffer
+	a := &prefixWriter{mu: &mu, w: &b, prefix: "a | "}
+	e := &prefixWriter{mu: &mu, w: &b, prefix: "e | "}
+
+	// the streams write parts of their lines, interleaved
+	for _, w := range []struct {
+		pw *prefixWriter
+		s  string
+	}{
+		{a, "first "}, {e, "one\ntw"}, {a, "line\nsecond"}, {e, "o\n"}, {a, " line\nlast"},
+	} {
+		if _, err := w.pw.Write([]byte(w.s)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	a.Flush()
+	e.Flush()
+
+	want := "e | one\na | first line\ne | two\na | second line\na | last\n"
+	if got := b.String(); got != want {
+		t.Errorf("prefixWriter wrote %q, want %q", got, want)
+	}
+}
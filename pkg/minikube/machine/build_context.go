@@ -0,0 +1,317 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/localpath"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// buildContextRoot is where build contexts are kept between builds within the guest VM
+var buildContextRoot = path.Join(vmpath.GuestPersistentDir, "build-ctx")
+
+// contextManifest maps the paths in a build context to the hashes of their content
+type contextManifest map[string]string
+
+// contextKey returns the key used to cache the build context rooted at dir
+func contextKey(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return hex.EncodeToString(sum[:])[:16], nil
+}
+
+// contextManifestPath returns where the manifest of the last build context transferred to a machine is stored
+func contextManifestPath(profile string, machine string, key string) string {
+	return filepath.Join(localpath.MiniPath(), "build-cache", profile, machine, key+".json")
+}
+
+// loadContextManifest loads a manifest, returning an empty one if none was stored yet
+func loadContextManifest(p string) (contextManifest, error) {
+	m := contextManifest{}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", p)
+	}
+	return m, nil
+}
+
+// save stores the manifest at the given path
+func (m contextManifest) save(p string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0644)
+}
+
+// digest returns a hash identifying the whole content of the manifest
+func (m contextManifest) digest() string {
+	paths := make([]string, 0, len(m))
+	for p := range m {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	h := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintf(h, "%s\x00%s\n", p, m[p])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// entryHash returns the hash of a build context entry, prefixed by its type
+func entryHash(hdr *tar.Header, content io.Reader) (string, error) {
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return fmt.Sprintf("dir:%o", hdr.Mode), nil
+	case tar.TypeSymlink:
+		return "symlink:" + hdr.Linkname, nil
+	case tar.TypeLink:
+		return "link:" + hdr.Linkname, nil
+	case tar.TypeReg:
+		h := sha256.New()
+		if _, err := io.Copy(h, content); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("file:%o:%s", hdr.Mode, hex.EncodeToString(h.Sum(nil))), nil
+	}
+	return "", fmt.Errorf("unsupported type %q for %s", hdr.Typeflag, hdr.Name)
+}
+
+// entryType returns the type prefix of an entry hash
+func entryType(hash string) string {
+	return strings.SplitN(hash, ":", 2)[0]
+}
+
+// changed returns whether an entry has to be transferred again
+func changed(old string, hash string) bool {
+	// hard links share the content of their target, which may have changed
+	return old != hash || entryType(hash) == "link"
+}
+
+// manifestOf returns the manifest of a build context tarball
+func manifestOf(full io.Reader) (contextManifest, error) {
+	m := contextManifest{}
+	tr := tar.NewReader(full)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading build context")
+		}
+		name := path.Clean(hdr.Name)
+		if name == "." {
+			continue
+		}
+		hash, err := entryHash(hdr, tr)
+		if err != nil {
+			return nil, err
+		}
+		m[name] = hash
+	}
+}
+
+// contextDelta reads the tarball of a full build context, and writes the entries which changed since the previous manifest to w.
+// It returns the manifest of the full context, and the paths which have to be deleted before extracting the delta.
+func contextDelta(full io.ReadSeeker, previous contextManifest, w io.Writer) (contextManifest, []string, error) {
+	current, err := manifestOf(full)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := full.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+
+	tr := tar.NewReader(full)
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "reading build context")
+		}
+		name := path.Clean(hdr.Name)
+		if name == "." || !changed(previous[name], current[name]) {
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, nil, errors.Wrapf(err, "writing %s", name)
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return nil, nil, errors.Wrapf(err, "writing %s", name)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	deleted := []string{}
+	for name, old := range previous {
+		hash, ok := current[name]
+		// tar can not replace a file by a directory and vice versa, nor write through an existing hard link
+		if !ok || entryType(old) != entryType(hash) || entryType(hash) == "link" {
+			deleted = append(deleted, name)
+		}
+	}
+	sort.Strings(deleted)
+	return current, deleted, nil
+}
+
+// transferContextAndBuildImage transfers the files of a build context which changed since the last build, and builds a single image
+func transferContextAndBuildImage(cr command.Runner, k8s config.KubernetesConfig, profile string, machine string, dir string, src string, opts cruntime.BuildOptions) error {
+	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr, DockerIsolatedBuilder: k8s.IsolatedBuilder})
+	if err != nil {
+		return errors.Wrap(err, "runtime")
+	}
+	klog.Infof("Building image from cached context of: %s", dir)
+
+	key, err := contextKey(dir)
+	if err != nil {
+		return errors.Wrap(err, "context key")
+	}
+	context := path.Join(buildContextRoot, key)
+	marker := context + ".manifest"
+	manifestPath := contextManifestPath(profile, machine, key)
+
+	previous, err := loadContextManifest(manifestPath)
+	if err != nil {
+		klog.Warningf("unable to load build context manifest, transferring the whole context: %v", err)
+		previous = contextManifest{}
+	}
+
+	// the context within the guest may be gone or out of sync, e.g. after recreating the machine or an interrupted transfer
+	rr, err := cr.RunCmd(exec.Command("sudo", "cat", marker))
+	if err != nil || strings.TrimSpace(rr.Stdout.String()) != previous.digest() {
+		klog.Infof("build context %s is not in sync, transferring the whole context", context)
+		previous = contextManifest{}
+		if _, err := cr.RunCmd(exec.Command("sudo", "rm", "-rf", context)); err != nil {
+			return err
+		}
+	}
+	if _, err := cr.RunCmd(exec.Command("sudo", "rm", "-f", marker)); err != nil {
+		return err
+	}
+	if _, err := cr.RunCmd(exec.Command("sudo", "mkdir", "-p", context, buildRoot)); err != nil {
+		return err
+	}
+
+	full, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer full.Close()
+	delta, err := os.CreateTemp("", "build-delta.*.tar")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(delta.Name())
+	current, deleted, err := contextDelta(full, previous, delta)
+	delta.Close()
+	if err != nil {
+		return errors.Wrap(err, "build context delta")
+	}
+
+	if len(deleted) > 0 {
+		args := []string{"rm", "-rf", "--"}
+		for _, name := range deleted {
+			klog.Infof("deleting %s from build context", name)
+			args = append(args, path.Join(context, name))
+		}
+		if _, err := cr.RunCmd(exec.Command("sudo", args...)); err != nil {
+			return err
+		}
+	}
+
+	filename := key + ".tar"
+	dst := path.Join(buildRoot, filename)
+	f, err := assets.NewFileAsset(delta.Name(), buildRoot, filename, "0644")
+	if err != nil {
+		return errors.Wrapf(err, "creating copyable file asset: %s", filename)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			klog.Warningf("error closing the file %s: %v", f.GetSourcePath(), err)
+		}
+	}()
+	transferred := 0
+	for name, hash := range current {
+		if changed(previous[name], hash) {
+			transferred++
+		}
+	}
+	klog.Infof("Transferring %d of %d build context entries", transferred, len(current))
+	if err := cr.Copy(f); err != nil {
+		return errors.Wrap(err, "transferring build context")
+	}
+	if _, err := cr.RunCmd(exec.Command("sudo", "tar", "-C", context, "-xf", dst)); err != nil {
+		return err
+	}
+	if _, err := cr.RunCmd(exec.Command("sudo", "rm", "-f", dst)); err != nil {
+		return err
+	}
+
+	if err := cr.Copy(assets.NewMemoryAssetTarget([]byte(current.digest()), marker, "0644")); err != nil {
+		return errors.Wrap(err, "writing build context manifest")
+	}
+	if err := current.save(manifestPath); err != nil {
+		return errors.Wrap(err, "saving build context manifest")
+	}
+
+	if opts.File != "" && !path.IsAbs(opts.File) {
+		opts.File = path.Join(context, opts.File)
+	}
+	if err := r.BuildImage(context, opts); err != nil {
+		return errors.Wrapf(err, "%s build %s", r.Name(), dir)
+	}
+
+	klog.Infof("Built %s from %s", opts.Tag, dir)
+	return nil
+}
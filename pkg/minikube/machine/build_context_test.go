@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// contextTar returns a build context tarball containing the given files, directories ending with a slash
+func contextTar(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(files[name]))}
+		if name[len(name)-1] == '/' {
+			hdr = &tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// tarNames returns the names of the entries of a tarball
+func tarNames(t *testing.T, r io.Reader) []string {
+	t.Helper()
+	names := []string{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatalf("read tar: %v", err)
+		}
+		names = append(names, hdr.Name)
+	}
+}
+
+func TestContextDelta(t *testing.T) {
+	first := map[string]string{
+		"Dockerfile":   "FROM busybox\nCOPY . /app\n",
+		"src/":         "",
+		"src/main.go":  "package main",
+		"src/util.go":  "package util",
+		"docs/":        "",
+		"docs/README":  "readme",
+		"config.yaml":  "a: 1",
+		"unchanged.sh": "#!/bin/sh",
+	}
+	second := map[string]string{
+		"Dockerfile":   "FROM busybox\nCOPY . /app\n",
+		"src/":         "",
+		"src/main.go":  "package main // modified",
+		"src/new.go":   "package main",
+		"config.yaml/": "",
+		"unchanged.sh": "#!/bin/sh",
+	}
+
+	var delta bytes.Buffer
+	m1, deleted, err := contextDelta(contextTar(t, first), contextManifest{}, &delta)
+	if err != nil {
+		t.Fatalf("contextDelta(first): %v", err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("first build deleted %v, want nothing", deleted)
+	}
+	if got := tarNames(t, &delta); len(got) != len(first) {
+		t.Errorf("first build transferred %v, want all %d entries", got, len(first))
+	}
+
+	delta.Reset()
+	m2, deleted, err := contextDelta(contextTar(t, second), m1, &delta)
+	if err != nil {
+		t.Fatalf("contextDelta(second): %v", err)
+	}
+	want := []string{"config.yaml/", "src/main.go", "src/new.go"}
+	if diff := cmp.Diff(want, tarNames(t, &delta)); diff != "" {
+		t.Errorf("second build transferred diff (-want +got):\n%s", diff)
+	}
+	// config.yaml turned into a directory, so it has to be removed before extracting
+	wantDeleted := []string{"config.yaml", "docs", "docs/README", "src/util.go"}
+	if diff := cmp.Diff(wantDeleted, deleted); diff != "" {
+		t.Errorf("second build deleted diff (-want +got):\n%s", diff)
+	}
+	if len(m2) != len(second) {
+		t.Errorf("manifest has %d entries, want %d", len(m2), len(second))
+	}
+
+	delta.Reset()
+	_, deleted, err = contextDelta(contextTar(t, second), m2, &delta)
+	if err != nil {
+		t.Fatalf("contextDelta(unchanged): %v", err)
+	}
+	if got := tarNames(t, &delta); len(got) != 0 || len(deleted) != 0 {
+		t.Errorf("unchanged build transferred %v and deleted %v, want nothing", got, deleted)
+	}
+}
+
+func TestContextManifest(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "profile", "machine", "key.json")
+
+	m, err := loadContextManifest(p)
+	if err != nil {
+		t.Fatalf("loadContextManifest(missing): %v", err)
+	}
+	if len(m) != 0 {
+		t.Errorf("missing manifest has %d entries, want none", len(m))
+	}
+
+	m = contextManifest{"Dockerfile": "file:644:abc", "src": "dir:755"}
+	if err := m.save(p); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	loaded, err := loadContextManifest(p)
+	if err != nil {
+		t.Fatalf("loadContextManifest: %v", err)
+	}
+	if loaded.digest() != m.digest() {
+		t.Errorf("loaded manifest digest %s, want %s", loaded.digest(), m.digest())
+	}
+	if (contextManifest{}).digest() == m.digest() {
+		t.Errorf("empty and non empty manifests share the digest %s", m.digest())
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package machine
 
 import (
+	"io"
 	"net/url"
 	"os"
 	"os/exec"
@@ -39,8 +40,18 @@ import (
 // buildRoot is where images should be built from within the guest VM
 var buildRoot = path.Join(vmpath.GuestPersistentDir, "build")
 
+// BuildOutput receives the output of the builds of an image, one node at a time
+type BuildOutput interface {
+	// Start returns the writers of the output and errors of the build on a node
+	Start(node string) (stdout, stderr io.Writer)
+	// Done reports the end of the build on a node, with its error if it failed
+	Done(node string, err error)
+}
+
 // BuildImage builds image to all profiles
-func BuildImage(path string, file string, tag string, push bool, env []string, opt []string, profiles []*config.Profile, allNodes bool, nodeName string) error {
+// If contextDir is set, path is a tarball of that directory, of which only the files changed since the last build are transferred.
+// The output of the builds goes to output, or to os.Stdout and os.Stderr if nil.
+func BuildImage(path string, opts cruntime.BuildOptions, profiles []*config.Profile, allNodes bool, nodeName string, contextDir string, output BuildOutput) error {
 	api, err := NewAPIClient()
 	if err != nil {
 		return errors.Wrap(err, "api")
@@ -70,6 +81,10 @@ func BuildImage(path string, file string, tag string, push bool, env []string, o
 			continue
 		}
 
+		if err := buildSupported(c.KubernetesConfig); err != nil {
+			return err
+		}
+
 		cp, err := config.PrimaryControlPlane(p.Config)
 		if err != nil {
 			return err
@@ -105,10 +120,23 @@ func BuildImage(path string, file string, tag string, push bool, env []string, o
 				if err != nil {
 					return err
 				}
+				k8s := config.ForNode(*c, n).KubernetesConfig
+				if err := prepareBuild(cr, k8s, opts); err != nil {
+					return errors.Wrapf(err, "building on %s", m)
+				}
+				nodeOpts := opts
+				if output != nil {
+					nodeOpts.Stdout, nodeOpts.Stderr = output.Start(m)
+				}
 				if remote {
-					err = buildImage(cr, c.KubernetesConfig, path, file, tag, push, env, opt)
+					err = buildImage(cr, k8s, path, nodeOpts)
+				} else if contextDir != "" {
+					err = transferContextAndBuildImage(cr, k8s, pName, m, contextDir, path, nodeOpts)
 				} else {
-					err = transferAndBuildImage(cr, c.KubernetesConfig, path, file, tag, push, env, opt)
+					err = transferAndBuildImage(cr, k8s, path, nodeOpts)
+				}
+				if output != nil {
+					output.Done(m, err)
 				}
 				if err != nil {
 					failed = append(failed, m)
@@ -125,26 +153,47 @@ func BuildImage(path string, file string, tag string, push bool, env []string, o
 	return nil
 }
 
+// buildSupported returns an error if images cannot be built in the runtime of the cluster
+func buildSupported(k8s config.KubernetesConfig) error {
+	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime})
+	if err != nil {
+		return errors.Wrap(err, "runtime")
+	}
+	if !r.Capabilities().SupportsBuild {
+		return errors.Errorf("building images is not supported by the %s runtime", r.Name())
+	}
+	return nil
+}
+
+// prepareBuild returns an error if the runtime of the node cannot build with opts, before the build context is transferred
+func prepareBuild(cr command.Runner, k8s config.KubernetesConfig, opts cruntime.BuildOptions) error {
+	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr, DockerIsolatedBuilder: k8s.IsolatedBuilder})
+	if err != nil {
+		return errors.Wrap(err, "runtime")
+	}
+	return cruntime.PrepareBuild(r, opts)
+}
+
 // buildImage builds a single image
-func buildImage(cr command.Runner, k8s config.KubernetesConfig, src string, file string, tag string, push bool, env []string, opt []string) error {
-	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr})
+func buildImage(cr command.Runner, k8s config.KubernetesConfig, src string, opts cruntime.BuildOptions) error {
+	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr, DockerIsolatedBuilder: k8s.IsolatedBuilder})
 	if err != nil {
 		return errors.Wrap(err, "runtime")
 	}
 	klog.Infof("Building image from url: %s", src)
 
-	err = r.BuildImage(src, file, tag, push, env, opt)
+	err = r.BuildImage(src, opts)
 	if err != nil {
 		return errors.Wrapf(err, "%s build %s", r.Name(), src)
 	}
 
-	klog.Infof("Built %s from %s", tag, src)
+	klog.Infof("Built %s from %s", opts.Tag, src)
 	return nil
 }
 
 // transferAndBuildImage transfers and builds a single image
-func transferAndBuildImage(cr command.Runner, k8s config.KubernetesConfig, src string, file string, tag string, push bool, env []string, opt []string) error {
-	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr})
+func transferAndBuildImage(cr command.Runner, k8s config.KubernetesConfig, src string, opts cruntime.BuildOptions) error {
+	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr, DockerIsolatedBuilder: k8s.IsolatedBuilder})
 	if err != nil {
 		return errors.Wrap(err, "runtime")
 	}
@@ -187,10 +236,10 @@ func transferAndBuildImage(cr command.Runner, k8s config.KubernetesConfig, src s
 		return err
 	}
 
-	if file != "" && !path.IsAbs(file) {
-		file = path.Join(context, file)
+	if opts.File != "" && !path.IsAbs(opts.File) {
+		opts.File = path.Join(context, opts.File)
 	}
-	err = r.BuildImage(context, file, tag, push, env, opt)
+	err = r.BuildImage(context, opts)
 	if err != nil {
 		return errors.Wrapf(err, "%s build %s", r.Name(), dst)
 	}
@@ -204,6 +253,6 @@ func transferAndBuildImage(cr command.Runner, k8s config.KubernetesConfig, src s
 		return err
 	}
 
-	klog.Infof("Built %s from %s", tag, src)
+	klog.Infof("Built %s from %s", opts.Tag, src)
 	return nil
 }
@@ -31,6 +31,7 @@ import (
 
 	"github.com/docker/docker/client"
 	"github.com/docker/go-units"
+	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
@@ -39,6 +40,7 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/assets"
 	"k8s.io/minikube/pkg/minikube/bootstrapper"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
 	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/cruntime"
@@ -73,15 +75,32 @@ func CacheImagesForBootstrapper(imageRepository string, version string, clusterB
 }
 
 // LoadCachedImages loads previously cached images into the container runtime
-func LoadCachedImages(cc *config.ClusterConfig, runner command.Runner, images []string, cacheDir string, overwrite bool) error {
-	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Runner: runner})
+// If remap, the images are also tagged with their names in the image repository of the cluster.
+// The output of the loads reaches the user as selected by output.
+func LoadCachedImages(cc *config.ClusterConfig, runner command.Runner, images []string, cacheDir string, overwrite bool, remap bool, output cruntime.ImageOutput) error {
+	releaser, err := acquireImageLock(cc.Name)
+	if err != nil {
+		return err
+	}
+	defer releaser.Release()
+
+	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Driver: cc.Driver, Runner: runner, ImageOutput: output})
 	if err != nil {
 		return errors.Wrap(err, "runtime")
 	}
+	repo := ""
+	if remap {
+		repo = cc.KubernetesConfig.ImageRepository
+	}
 
 	// Skip loading images if images already exist
 	if !overwrite && cr.ImagesPreloaded(images) {
 		klog.Infof("Images are preloaded, skipping loading")
+		for _, img := range images {
+			if err := remapImage(cr, img, repo); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 
@@ -102,19 +121,33 @@ func LoadCachedImages(cc *config.ClusterConfig, runner command.Runner, images []
 		}
 	}
 
+	// Checking all the images at once spares looking up the digests of the images which are missing anyway
+	exist, err := cr.ImagesExist(images)
+	if err != nil {
+		klog.Warningf("unable to check the existing images: %v", err)
+		exist = nil
+	}
+
 	for _, image := range images {
 		image := image
 		g.Go(func() error {
-			// Put a ten second limit on deciding if an image needs transfer
-			// because it takes much less than that time to just transfer the image.
-			// This is needed because if running in offline mode, we can spend minutes here
-			// waiting for i/o timeout.
-			err := timedNeedsTransfer(imgClient, image, cr, 10*time.Second)
-			if err == nil {
-				return nil
-			}
-			klog.Infof("%q needs transfer: %v", image, err)
-			return transferAndLoadCachedImage(runner, cc.KubernetesConfig, image, cacheDir)
+			transfer := exist != nil && !exist[image]
+			if !transfer {
+				// Put a ten second limit on deciding if an image needs transfer
+				// because it takes much less than that time to just transfer the image.
+				// This is needed because if running in offline mode, we can spend minutes here
+				// waiting for i/o timeout.
+				if err := timedNeedsTransfer(imgClient, image, cr, 10*time.Second); err != nil {
+					klog.Infof("%q needs transfer: %v", image, err)
+					transfer = true
+				}
+			}
+			if transfer {
+				if _, err := transferAndLoadCachedImage(runner, cc.KubernetesConfig, image, cacheDir, output); err != nil {
+					return err
+				}
+			}
+			return remapImage(cr, image, repo)
 		})
 	}
 	if err := g.Wait(); err != nil {
@@ -169,46 +202,87 @@ func needsTransfer(imgClient *client.Client, imgName string, cr cruntime.Manager
 	return nil
 }
 
-// LoadLocalImages loads images into the container runtime
-func LoadLocalImages(cc *config.ClusterConfig, runner command.Runner, images []string) error {
+// remapImage tags an image with its name in the image repository repo, which the manifests generated by minikube use
+func remapImage(cr cruntime.Manager, img string, repo string) error {
+	remapped := images.Remap(img, repo)
+	if remapped == img {
+		return nil
+	}
+	// the runtimes other than docker only tag with full references
+	target, err := image.NormalizeReference(remapped)
+	if err != nil {
+		return errors.Wrapf(err, "tagging %s for the image repository %s", img, repo)
+	}
+	klog.Infof("Tagging %s as %s for the image repository %s", img, target, repo)
+	if err := cr.TagImage(img, target); err != nil {
+		return errors.Wrapf(err, "tagging %s as %s", img, target)
+	}
+	return nil
+}
+
+// LoadLocalImages loads image archives into the container runtime, returning the references of the images loaded
+func LoadLocalImages(cc *config.ClusterConfig, runner command.Runner, images []string, output cruntime.ImageOutput) ([]string, error) {
+	releaser, err := acquireImageLock(cc.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer releaser.Release()
+
 	var g errgroup.Group
+	var mu sync.Mutex
+	loaded := []string{}
 	for _, image := range images {
 		image := image
 		g.Go(func() error {
-			return transferAndLoadImage(runner, cc.KubernetesConfig, image, image)
+			refs, err := transferAndLoadImage(runner, cc.KubernetesConfig, image, image, output)
+			mu.Lock()
+			loaded = append(loaded, refs...)
+			mu.Unlock()
+			return err
 		})
 	}
 	if err := g.Wait(); err != nil {
-		return errors.Wrap(err, "loading images")
+		return loaded, errors.Wrap(err, "loading images")
 	}
-	klog.Infoln("Successfully loaded all images")
-	return nil
+	klog.Infof("Successfully loaded all images: %s", loaded)
+	return loaded, nil
 }
 
-// CacheAndLoadImages caches and loads images to all profiles
-func CacheAndLoadImages(images []string, profiles []*config.Profile, overwrite bool) error {
+// CacheAndLoadImages caches and loads images to all profiles, remapping them to the image repository of each if remap
+func CacheAndLoadImages(images []string, profiles []*config.Profile, overwrite bool, remap bool, output cruntime.ImageOutput) error {
 	if len(images) == 0 {
 		return nil
 	}
 
+	release, err := acquireImageLocks(profiles)
+	if err != nil {
+		return err
+	}
 	// This is the most important thing
-	if err := image.SaveToDir(images, detect.ImageCacheDir(), overwrite); err != nil {
+	err = image.SaveToDir(images, detect.ImageCacheDir(), overwrite)
+	release()
+	if err != nil {
 		return errors.Wrap(err, "save to dir")
 	}
 
-	return DoLoadImages(images, profiles, detect.ImageCacheDir(), overwrite)
+	_, err = DoLoadImages(images, profiles, detect.ImageCacheDir(), overwrite, remap, output)
+	return err
 }
 
 // DoLoadImages loads images to all profiles
-func DoLoadImages(images []string, profiles []*config.Profile, cacheDir string, overwrite bool) error {
+// Images loaded from the cache are remapped to the image repository of each profile if remap, which images loaded from files can not be.
+// For the images loaded from files, it returns the references the runtimes reported, as the names are only known from the archives.
+func DoLoadImages(images []string, profiles []*config.Profile, cacheDir string, overwrite bool, remap bool, output cruntime.ImageOutput) ([]string, error) {
 	api, err := NewAPIClient()
 	if err != nil {
-		return errors.Wrap(err, "api")
+		return nil, errors.Wrap(err, "api")
 	}
 	defer api.Close()
 
 	succeeded := []string{}
 	failed := []string{}
+	loaded := []string{}
+	seen := map[string]bool{}
 
 	for _, p := range profiles { // loading images to all running profiles
 		pName := p.Name // capture the loop variable
@@ -240,14 +314,22 @@ func DoLoadImages(images []string, profiles []*config.Profile, cacheDir string,
 				}
 				cr, err := CommandRunner(h)
 				if err != nil {
-					return err
+					return loaded, err
 				}
+				nc := config.ForNode(*c, n)
 				if cacheDir != "" {
 					// loading image names, from cache
-					err = LoadCachedImages(c, cr, images, cacheDir, overwrite)
+					err = LoadCachedImages(&nc, cr, images, cacheDir, overwrite, remap, output)
 				} else {
 					// loading image files
-					err = LoadLocalImages(c, cr, images)
+					var refs []string
+					refs, err = LoadLocalImages(&nc, cr, images, output)
+					for _, ref := range refs {
+						if !seen[ref] {
+							seen[ref] = true
+							loaded = append(loaded, ref)
+						}
+					}
 				}
 				if err != nil {
 					failed = append(failed, m)
@@ -262,37 +344,51 @@ func DoLoadImages(images []string, profiles []*config.Profile, cacheDir string,
 	klog.Infof("succeeded pushing to: %s", strings.Join(succeeded, " "))
 	klog.Infof("failed pushing to: %s", strings.Join(failed, " "))
 	// Live pushes are not considered a failure
-	return nil
+	return loaded, nil
 }
 
 // transferAndLoadCachedImage transfers and loads a single image from the cache
-func transferAndLoadCachedImage(cr command.Runner, k8s config.KubernetesConfig, imgName string, cacheDir string) error {
+func transferAndLoadCachedImage(cr command.Runner, k8s config.KubernetesConfig, imgName string, cacheDir string, output cruntime.ImageOutput) ([]string, error) {
 	src := filepath.Join(cacheDir, imgName)
 	src = localpath.SanitizeCacheDir(src)
-	return transferAndLoadImage(cr, k8s, src, imgName)
+	loaded, err := transferAndLoadImage(cr, k8s, src, imgName, output)
+	if err != nil {
+		return nil, err
+	}
+	if err := image.TouchCachedImages(cacheDir, src); err != nil {
+		klog.Warningf("unable to record the use of %s in the image cache: %v", src, err)
+	}
+	return loaded, nil
 }
 
-// transferAndLoadImage transfers and loads a single image
-func transferAndLoadImage(cr command.Runner, k8s config.KubernetesConfig, src string, imgName string) error {
-	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr})
+// transferAndLoadImage transfers and loads a single image, returning the references of the images loaded
+func transferAndLoadImage(cr command.Runner, k8s config.KubernetesConfig, src string, imgName string, output cruntime.ImageOutput) ([]string, error) {
+	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr, ImageOutput: output})
 	if err != nil {
-		return errors.Wrap(err, "runtime")
+		return nil, errors.Wrap(err, "runtime")
 	}
+	return loadImage(r, cr, src, imgName)
+}
 
+// loadImage transfers the image archive at src to the host and loads it into the runtime, replacing imgName.
+// It returns the references of the images loaded, as reported by the runtime.
+func loadImage(r cruntime.Manager, cr command.Runner, src string, imgName string) ([]string, error) {
 	if err := removeExistingImage(r, src, imgName); err != nil {
-		return err
+		return nil, err
 	}
 
 	klog.Infof("Loading image from: %s", src)
 	filename := filepath.Base(src)
 	if _, err := os.Stat(src); err != nil {
-		return err
+		return nil, err
 	}
 
-	dst := path.Join(loadRoot, filename)
-	f, err := assets.NewFileAsset(src, loadRoot, filename, "0644")
+	// the archive has a name of its own, as other invocations may be loading an image of the same name
+	target := cruntime.NodeTempName(filename)
+	dst := path.Join(loadRoot, target)
+	f, err := assets.NewFileAsset(src, loadRoot, target, "0644")
 	if err != nil {
-		return errors.Wrapf(err, "creating copyable file asset: %s", filename)
+		return nil, errors.Wrapf(err, "creating copyable file asset: %s", filename)
 	}
 	defer func() {
 		if err := f.Close(); err != nil {
@@ -301,19 +397,24 @@ func transferAndLoadImage(cr command.Runner, k8s config.KubernetesConfig, src st
 	}()
 
 	if err := cr.Copy(f); err != nil {
-		return errors.Wrap(err, "transferring cached image")
+		return nil, errors.Wrap(err, "transferring cached image")
 	}
+	defer func() {
+		if err := cr.Remove(f); err != nil {
+			klog.Warningf("unable to remove %s: %v", dst, err)
+		}
+	}()
 
 	loadImageLock.Lock()
 	defer loadImageLock.Unlock()
 
-	err = r.LoadImage(dst)
+	refs, err := r.LoadImage(dst)
 	if err != nil {
-		return errors.Wrapf(err, "%s load %s", r.Name(), dst)
+		return nil, errors.Wrapf(err, "%s load %s", r.Name(), dst)
 	}
 
-	klog.Infof("Transferred and loaded %s from cache", src)
-	return nil
+	klog.Infof("Transferred and loaded %s from cache: %s", src, refs)
+	return refs, nil
 }
 
 func removeExistingImage(r cruntime.Manager, src string, imgName string) error {
@@ -338,6 +439,12 @@ func removeExistingImage(r cruntime.Manager, src string, imgName string) error {
 
 // SaveCachedImages saves from the container runtime to the cache
 func SaveCachedImages(cc *config.ClusterConfig, runner command.Runner, images []string, cacheDir string) error {
+	releaser, err := acquireImageLock(cc.Name)
+	if err != nil {
+		return err
+	}
+	defer releaser.Release()
+
 	klog.Infof("SaveImages start: %s", images)
 	start := time.Now()
 
@@ -362,6 +469,12 @@ func SaveCachedImages(cc *config.ClusterConfig, runner command.Runner, images []
 
 // SaveLocalImages saves images from the container runtime
 func SaveLocalImages(cc *config.ClusterConfig, runner command.Runner, images []string, output string) error {
+	releaser, err := acquireImageLock(cc.Name)
+	if err != nil {
+		return err
+	}
+	defer releaser.Release()
+
 	var g errgroup.Group
 	for _, image := range images {
 		image := image
@@ -430,12 +543,13 @@ func DoSaveImages(images []string, output string, profiles []*config.Profile, ca
 				if err != nil {
 					return err
 				}
+				nc := config.ForNode(*c, n)
 				if cacheDir != "" {
 					// saving image names, to cache
-					err = SaveCachedImages(c, cr, images, cacheDir)
+					err = SaveCachedImages(&nc, cr, images, cacheDir)
 				} else {
 					// saving mage files
-					err = SaveLocalImages(c, cr, images, output)
+					err = SaveLocalImages(&nc, cr, images, output)
 				}
 				if err != nil {
 					failed = append(failed, m)
@@ -457,7 +571,32 @@ func DoSaveImages(images []string, output string, profiles []*config.Profile, ca
 func transferAndSaveCachedImage(cr command.Runner, k8s config.KubernetesConfig, imgName string, cacheDir string) error {
 	dst := filepath.Join(cacheDir, imgName)
 	dst = localpath.SanitizeCacheDir(dst)
-	return transferAndSaveImage(cr, k8s, dst, imgName)
+
+	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr})
+	if err != nil {
+		return errors.Wrap(err, "runtime")
+	}
+	id, err := r.ImageID(imgName)
+	if err != nil {
+		klog.Warningf("unable to get ID of %s: %v", imgName, err)
+	}
+	if id != "" && image.CachedImageID(dst) == id {
+		klog.Infof("%s is up to date in cache at %s", imgName, dst)
+		return nil
+	}
+
+	if err := transferAndSaveImage(cr, k8s, dst, imgName); err != nil {
+		return err
+	}
+	if id != "" {
+		if err := image.WriteCachedImageID(dst, id); err != nil {
+			klog.Warningf("unable to record ID of %s: %v", imgName, err)
+		}
+	}
+	if err := image.TouchCachedImages(cacheDir, dst); err != nil {
+		klog.Warningf("unable to record %s in the image cache: %v", dst, err)
+	}
+	return nil
 }
 
 // transferAndSaveImage transfers and loads a single image
@@ -479,7 +618,9 @@ func transferAndSaveImage(cr command.Runner, k8s config.KubernetesConfig, dst st
 		return err
 	}
 
-	f, err := assets.NewFileAsset(dst, saveRoot, filename, "0644")
+	// the archive has a name of its own, as other invocations may be saving an image of the same name
+	target := cruntime.NodeTempName(filename)
+	f, err := assets.NewFileAsset(dst, saveRoot, target, "0644")
 	if err != nil {
 		return errors.Wrapf(err, "creating copyable file asset: %s", filename)
 	}
@@ -489,11 +630,12 @@ func transferAndSaveImage(cr command.Runner, k8s config.KubernetesConfig, dst st
 		}
 	}()
 
-	src := path.Join(saveRoot, filename)
+	src := path.Join(saveRoot, target)
 	args := append([]string{"rm", "-f"}, src)
 	if _, err := cr.RunCmd(exec.Command("sudo", args...)); err != nil {
 		return err
 	}
+	defer removeNodeArchive(cr, src)
 	err = r.SaveImage(imgName, src)
 	if err != nil {
 		return errors.Wrapf(err, "%s save %s", r.Name(), src)
@@ -507,8 +649,8 @@ func transferAndSaveImage(cr command.Runner, k8s config.KubernetesConfig, dst st
 	return nil
 }
 
-// pullImages pulls images to the container run time
-func pullImages(cruntime cruntime.Manager, images []string) error {
+// pullImages pulls images to the container run time, by the verified references of pinned if any
+func pullImages(cr cruntime.Manager, images []string, pinned map[string]string, output cruntime.ImageOutput) error {
 	klog.Infof("PullImages start: %s", images)
 	start := time.Now()
 
@@ -517,11 +659,18 @@ func pullImages(cruntime cruntime.Manager, images []string) error {
 	}()
 
 	var g errgroup.Group
+	// progress bars written straight to the terminal would clobber each other
+	if output == cruntime.ImageOutputTerminal {
+		g.SetLimit(1)
+	}
 
 	for _, image := range images {
 		image := image
 		g.Go(func() error {
-			return cruntime.PullImage(image)
+			if p, ok := pinned[image]; ok {
+				return cruntime.PullVerifiedImage(cr, image, p)
+			}
+			return cr.PullImage(image)
 		})
 	}
 	if err := g.Wait(); err != nil {
@@ -533,6 +682,12 @@ func pullImages(cruntime cruntime.Manager, images []string) error {
 
 // PullImages pulls images to all nodes in profile
 func PullImages(images []string, profile *config.Profile) error {
+	return PullVerifiedImages(images, nil, profile, cruntime.ImageOutputBuffered)
+}
+
+// PullVerifiedImages pulls images to all nodes in profile, pulling those whose signatures were verified
+// by the references pinning their verified digest in pinned, showing the output of the runtime as selected by output
+func PullVerifiedImages(images []string, pinned map[string]string, profile *config.Profile, output cruntime.ImageOutput) error {
 	api, err := NewAPIClient()
 	if err != nil {
 		return errors.Wrap(err, "error creating api client")
@@ -569,11 +724,11 @@ func PullImages(images []string, profile *config.Profile) error {
 			if err != nil {
 				return err
 			}
-			cruntime, err := cruntime.New(cruntime.Config{Type: c.KubernetesConfig.ContainerRuntime, Runner: runner})
+			cruntime, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(*c, n), Driver: c.Driver, Runner: runner, ImageOutput: output})
 			if err != nil {
 				return errors.Wrap(err, "error creating container runtime")
 			}
-			err = pullImages(cruntime, images)
+			err = pullImages(cruntime, images, pinned, output)
 			if err != nil {
 				failed = append(failed, m)
 				klog.Warningf("Failed to pull images for profile %s %v", pName, err.Error())
@@ -588,8 +743,8 @@ func PullImages(images []string, profile *config.Profile) error {
 	return nil
 }
 
-// removeImages removes images from the container run time
-func removeImages(cruntime cruntime.Manager, images []string) error {
+// removeImages removes images from the container run time, with all of their references if force
+func removeImages(cruntime cruntime.Manager, images []string, force bool) error {
 	klog.Infof("RemovingImages start: %s", images)
 	start := time.Now()
 
@@ -602,6 +757,9 @@ func removeImages(cruntime cruntime.Manager, images []string) error {
 	for _, image := range images {
 		image := image
 		g.Go(func() error {
+			if force {
+				return cruntime.ForceRemoveImage(image)
+			}
 			return cruntime.RemoveImage(image)
 		})
 	}
@@ -612,8 +770,8 @@ func removeImages(cruntime cruntime.Manager, images []string) error {
 	return nil
 }
 
-// RemoveImages removes images from all nodes in profile
-func RemoveImages(images []string, profile *config.Profile) error {
+// RemoveImages removes images from all nodes in profile, untagging all the references of the images if force
+func RemoveImages(images []string, profile *config.Profile, force bool) error {
 	api, err := NewAPIClient()
 	if err != nil {
 		return errors.Wrap(err, "error creating api client")
@@ -650,11 +808,11 @@ func RemoveImages(images []string, profile *config.Profile) error {
 			if err != nil {
 				return err
 			}
-			cruntime, err := cruntime.New(cruntime.Config{Type: c.KubernetesConfig.ContainerRuntime, Runner: runner})
+			cruntime, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(*c, n), Driver: c.Driver, Runner: runner})
 			if err != nil {
 				return errors.Wrap(err, "error creating container runtime")
 			}
-			err = removeImages(cruntime, images)
+			err = removeImages(cruntime, images, force)
 			if err != nil {
 				failed = append(failed, m)
 				klog.Warningf("Failed to remove images for profile %s %v", pName, err.Error())
@@ -670,8 +828,8 @@ func RemoveImages(images []string, profile *config.Profile) error {
 	return nil
 }
 
-// ListImages lists images on all nodes in profile
-func ListImages(profile *config.Profile, format string) error {
+// ListImages lists the images matching opts on all nodes in profile
+func ListImages(profile *config.Profile, format string, opts cruntime.ListImagesOptions) error {
 	api, err := NewAPIClient()
 	if err != nil {
 		return errors.Wrap(err, "error creating api client")
@@ -687,6 +845,8 @@ func ListImages(profile *config.Profile, format string) error {
 	}
 
 	images := map[string]cruntime.ListImage{}
+	// nodeArchs are the architectures of the first nodes listing the images, by image ID
+	nodeArchs := map[string]string{}
 	for _, n := range c.Nodes {
 		m := config.MachineName(*c, n)
 
@@ -706,19 +866,24 @@ func ListImages(profile *config.Profile, format string) error {
 			if err != nil {
 				return err
 			}
-			cr, err := cruntime.New(cruntime.Config{Type: c.KubernetesConfig.ContainerRuntime, Runner: runner})
+			cr, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(*c, n), Driver: c.Driver, Runner: runner})
 			if err != nil {
 				return errors.Wrap(err, "error creating container runtime")
 			}
-			list, err := cr.ListImages(cruntime.ListImagesOptions{})
+			list, err := cr.ListImages(opts)
 			if err != nil {
 				klog.Warningf("Failed to list images for profile %s %v", pName, err.Error())
 				continue
 			}
+			arch, err := cruntime.NodeArch(runner)
+			if err != nil {
+				klog.Warningf("Failed to get the architecture of %s: %v", m, err)
+			}
 
 			for _, img := range list {
 				if _, ok := images[img.ID]; !ok {
 					images[img.ID] = img
+					nodeArchs[img.ID] = arch
 				}
 			}
 		}
@@ -732,18 +897,29 @@ func ListImages(profile *config.Profile, format string) error {
 	switch format {
 	case "table":
 		var data [][]string
+		foreign := 0
 		for _, item := range uniqueImages {
 			imageSize := humanImageSize(item.Size)
 			id := parseImageID(item.ID)
+			platform := imagePlatform(item, nodeArchs[item.ID])
+			if item.ForeignArch(nodeArchs[item.ID]) {
+				foreign++
+			}
+			if len(item.RepoTags) == 0 {
+				data = append(data, []string{"<none>", "<none>", id, platform, imageSize})
+			}
 			for _, img := range item.RepoTags {
 				imageName, tag := parseRepoTag(img)
 				if imageName == "" {
 					continue
 				}
-				data = append(data, []string{imageName, tag, id, imageSize})
+				data = append(data, []string{imageName, tag, id, platform, imageSize})
 			}
 		}
-		renderImagesTable(data)
+		renderImagesTable(data, imagesTotalSize(uniqueImages))
+		if foreign > 0 {
+			out.WarningT("{{.count}} images marked with ⚠️ are not of the architecture of their node, and will not run there without emulation", out.V{"count": foreign})
+		}
 	case "json":
 		json, err := json.Marshal(uniqueImages)
 		if err != nil {
@@ -761,6 +937,10 @@ func ListImages(profile *config.Profile, format string) error {
 	default:
 		res := []string{}
 		for _, item := range uniqueImages {
+			// dangling images have no tags, so list them by their ID
+			if len(item.RepoTags) == 0 {
+				res = append(res, item.ID)
+			}
 			res = append(res, item.RepoTags...)
 		}
 		sort.Sort(sort.Reverse(sort.StringSlice(res)))
@@ -788,19 +968,46 @@ func parseImageID(id string) string {
 	return id
 }
 
+// imagePlatform prints the platform of an image, marking those whose architecture is not arch, that of their node
+func imagePlatform(img cruntime.ListImage, arch string) string {
+	p := img.Platform()
+	if p == "" {
+		return "unknown"
+	}
+	if img.ForeignArch(arch) {
+		return p + " ⚠️"
+	}
+	return p
+}
+
 // humanImageSize prints size of image in human readable format
 func humanImageSize(imageSize string) string {
-	f, err := strconv.ParseFloat(imageSize, 32)
+	f, err := strconv.ParseFloat(imageSize, 64)
 	if err == nil {
 		return units.HumanSizeWithPrecision(f, 3)
 	}
 	return imageSize
 }
 
+// imagesTotalSize sums the sizes in bytes of images, counting each image once however many tags it has
+func imagesTotalSize(images []cruntime.ListImage) int64 {
+	var total int64
+	for _, img := range images {
+		size, err := strconv.ParseInt(img.Size, 10, 64)
+		if err != nil {
+			klog.Warningf("unable to parse size %q of image %s: %v", img.Size, img.ID, err)
+			continue
+		}
+		total += size
+	}
+	return total
+}
+
 // renderImagesTable renders pretty table for images list
-func renderImagesTable(images [][]string) {
+func renderImagesTable(images [][]string, total int64) {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Image", "Tag", "Image ID", "Size"})
+	table.SetHeader([]string{"Image", "Tag", "Image ID", "Platform", "Size"})
+	table.SetFooter([]string{"", "", "", "Total", units.HumanSizeWithPrecision(float64(total), 3)})
 	table.SetAutoFormatHeaders(false)
 	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
@@ -847,7 +1054,7 @@ func TagImage(profile *config.Profile, source string, target string) error {
 			if err != nil {
 				return err
 			}
-			cruntime, err := cruntime.New(cruntime.Config{Type: c.KubernetesConfig.ContainerRuntime, Runner: runner})
+			cruntime, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(*c, n), Driver: c.Driver, Runner: runner})
 			if err != nil {
 				return errors.Wrap(err, "error creating container runtime")
 			}
@@ -928,7 +1135,7 @@ func PushImages(images []string, profile *config.Profile) error {
 			if err != nil {
 				return err
 			}
-			cruntime, err := cruntime.New(cruntime.Config{Type: c.KubernetesConfig.ContainerRuntime, Runner: runner})
+			cruntime, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(*c, n), Driver: c.Driver, Runner: runner})
 			if err != nil {
 				return errors.Wrap(err, "error creating container runtime")
 			}
@@ -946,3 +1153,140 @@ func PushImages(images []string, profile *config.Profile) error {
 	klog.Infof("failed pushing in: %s", strings.Join(failed, " "))
 	return nil
 }
+
+// PushImagesToRegistry retags images for the registry at addr (host:port) and pushes them from the primary control plane.
+// It returns the references to the pushed images.
+func PushImagesToRegistry(images []string, profile *config.Profile, addr string) ([]string, error) {
+	api, err := NewAPIClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating api client")
+	}
+	defer api.Close()
+
+	cr, _, err := primaryControlPlaneRuntime(api, profile)
+	if err != nil {
+		return nil, err
+	}
+	// restarting the runtime here would stop the workloads of the node, and the other nodes could still not pull
+	insecure, err := cruntime.AcceptsInsecureRegistry(cr, addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "checking the insecure registry %s", addr)
+	}
+	if !insecure {
+		return nil, fmt.Errorf("%s does not accept the plain HTTP registry %s: start minikube with --insecure-registry=%s", cr.Name(), addr, addr)
+	}
+
+	refs := []string{}
+	for _, img := range images {
+		ref := image.Retag(img, addr)
+		if err := cr.TagImage(img, ref); err != nil {
+			return refs, errors.Wrapf(err, "tagging %s as %s", img, ref)
+		}
+		if err := cr.PushImage(ref); err != nil {
+			return refs, errors.Wrapf(err, "pushing %s", ref)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// primaryControlPlaneRuntime returns the container runtime of the primary control plane of a profile, and its command runner
+func primaryControlPlaneRuntime(api libmachine.API, profile *config.Profile) (cruntime.Manager, command.Runner, error) {
+	c, err := config.Load(profile.Name)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error loading config for profile :%v", profile.Name)
+	}
+	cp, err := config.PrimaryControlPlane(c)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "getting primary control plane")
+	}
+	h, err := api.Load(config.MachineName(*c, cp))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "loading primary control plane")
+	}
+	runner, err := CommandRunner(h)
+	if err != nil {
+		return nil, nil, err
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(*c, cp), Driver: c.Driver, Runner: runner})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating container runtime")
+	}
+	return cr, runner, nil
+}
+
+// ImageHistory is the history of an image, as printed by ShowImageHistory
+type ImageHistory struct {
+	Image  string               `json:"image" yaml:"image"`
+	Layers []cruntime.LayerInfo `json:"layers" yaml:"layers"`
+}
+
+// ShowImageHistory prints the layers of images on the primary control plane, along with their total size, counting shared layers once
+func ShowImageHistory(profile *config.Profile, images []string, format string) error {
+	api, err := NewAPIClient()
+	if err != nil {
+		return errors.Wrap(err, "error creating api client")
+	}
+	defer api.Close()
+
+	cr, _, err := primaryControlPlaneRuntime(api, profile)
+	if err != nil {
+		return err
+	}
+	histories := []ImageHistory{}
+	layers := [][]cruntime.LayerInfo{}
+	for _, img := range images {
+		h, err := cr.ImageHistory(img)
+		if err != nil {
+			return errors.Wrapf(err, "history of %s", img)
+		}
+		histories = append(histories, ImageHistory{Image: img, Layers: h})
+		layers = append(layers, h)
+	}
+	total := cruntime.UniqueLayersSize(layers...)
+
+	switch format {
+	case "json", "yaml":
+		report := struct {
+			Images          []ImageHistory `json:"images" yaml:"images"`
+			TotalUniqueSize int64          `json:"totalUniqueSize" yaml:"totalUniqueSize"`
+		}{histories, total}
+		var b []byte
+		if format == "json" {
+			b, err = json.Marshal(report)
+		} else {
+			b, err = yaml.Marshal(report)
+		}
+		if err != nil {
+			return errors.Wrap(err, "marshalling image history")
+		}
+		fmt.Println(string(b))
+	default:
+		for _, h := range histories {
+			fmt.Println(h.Image)
+			renderHistoryTable(h.Layers)
+		}
+		fmt.Printf("Total unique size: %s\n", units.HumanSizeWithPrecision(float64(total), 3))
+	}
+	return nil
+}
+
+// renderHistoryTable renders pretty table for the layers of an image
+func renderHistoryTable(layers []cruntime.LayerInfo) {
+	data := [][]string{}
+	for _, l := range layers {
+		created := "<missing>"
+		if !l.Created.IsZero() {
+			created = l.Created.Format(time.RFC3339)
+		}
+		data = append(data, []string{parseImageID(strings.TrimPrefix(l.Digest, "sha256:")), units.HumanSizeWithPrecision(float64(l.Size), 3), created, l.CreatedBy})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Layer", "Size", "Created", "Created By"})
+	table.SetAutoFormatHeaders(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("|")
+	table.AppendBulk(data)
+	table.Render()
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/cruntime/cruntimetest"
+)
+
+func TestLoadImage(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "busybox_latest")
+	if err := os.WriteFile(src, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dst := path.Join(loadRoot, cruntime.NodeTempName("busybox_latest"))
+
+	r := cruntimetest.NewFakeRuntime()
+	old := r.AddImage("busybox:latest")
+	r.Archives[dst] = "busybox:latest"
+	cr := command.NewFakeCommandRunner()
+
+	refs, err := loadImage(r, cr, src, "busybox:latest")
+	if err != nil {
+		t.Fatalf("loadImage: %v", err)
+	}
+	if diff := cmp.Diff([]string{"busybox:latest"}, refs); diff != "" {
+		t.Errorf("loaded images mismatch (-want +got):\n%s", diff)
+	}
+	if got, err := cr.GetFileToContents(src); err == nil {
+		t.Errorf("transferred archive = %q, want it removed once loaded", got)
+	}
+	if diff := cmp.Diff([]string{"busybox:latest"}, r.Called("RemoveImage")); diff != "" {
+		t.Errorf("RemoveImage calls mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{dst}, r.Called("LoadImage")); diff != "" {
+		t.Errorf("LoadImage calls mismatch (-want +got):\n%s", diff)
+	}
+	if !r.ImageExists("busybox:latest", old) {
+		t.Errorf("busybox:latest is missing after the load")
+	}
+
+	r.Fail("LoadImage", errors.New("invalid archive"))
+	if _, err := loadImage(r, cr, src, "busybox:latest"); err == nil {
+		t.Errorf("loadImage succeeded with a failing runtime")
+	}
+}
+
+func TestRemoveExistingImage(t *testing.T) {
+	r := cruntimetest.NewFakeRuntime()
+	if err := removeExistingImage(r, "/cache/busybox_latest", "busybox:latest"); err != nil {
+		t.Errorf("removing a missing image: %v", err)
+	}
+	if err := removeExistingImage(r, "/tmp/busybox.tar", "/tmp/busybox.tar"); err != nil {
+		t.Errorf("removing the image of an archive: %v", err)
+	}
+	if n := len(r.Called("RemoveImage")); n != 1 {
+		t.Errorf("RemoveImage called %d times, want 1", n)
+	}
+
+	r.Fail("RemoveImage", errors.New("image is in use by a container"))
+	if err := removeExistingImage(r, "/cache/busybox_latest", "busybox:latest"); err == nil {
+		t.Errorf("removing an image in use succeeded")
+	}
+}
+
+func TestRemapImage(t *testing.T) {
+	r := cruntimetest.NewFakeRuntime()
+	id := r.AddImage("registry.k8s.io/pause:3.6")
+
+	if err := remapImage(r, "registry.k8s.io/pause:3.6", ""); err != nil {
+		t.Fatalf("remapImage without a repository: %v", err)
+	}
+	if n := len(r.Called("TagImage")); n != 0 {
+		t.Errorf("TagImage called %d times without a repository", n)
+	}
+
+	if err := remapImage(r, "registry.k8s.io/pause:3.6", "example.com/k8s"); err != nil {
+		t.Fatalf("remapImage: %v", err)
+	}
+	if !r.ImageExists("example.com/k8s/pause:3.6", id) {
+		t.Errorf("example.com/k8s/pause:3.6 is missing, calls: %v", r.Calls)
+	}
+
+	// a repository of Docker Hub is tagged with its full reference
+	if err := remapImage(r, "registry.k8s.io/pause:3.6", "mirror"); err != nil {
+		t.Fatalf("remapImage: %v", err)
+	}
+	if !r.ImageExists("docker.io/mirror/pause:3.6", id) {
+		t.Errorf("docker.io/mirror/pause:3.6 is missing, calls: %v", r.Calls)
+	}
+
+	r.Fail("TagImage", errors.New("tag failed"))
+	if err := remapImage(r, "registry.k8s.io/pause:3.6", "example.com/k8s"); err == nil {
+		t.Errorf("remapImage succeeded with a failing runtime")
+	}
+}
+
+func TestImagePlatform(t *testing.T) {
+	tests := []struct {
+		img  cruntime.ListImage
+		arch string
+		want string
+	}{
+		{img: cruntime.ListImage{OS: "linux", Architecture: "amd64"}, arch: "amd64", want: "linux/amd64"},
+		{img: cruntime.ListImage{OS: "linux", Architecture: "arm64", Variant: "v8"}, arch: "amd64", want: "linux/arm64/v8 ⚠️"},
+		{img: cruntime.ListImage{OS: "linux", Architecture: "arm64"}, arch: "", want: "linux/arm64"},
+		{img: cruntime.ListImage{}, arch: "amd64", want: "unknown"},
+	}
+	for _, tc := range tests {
+		if got := imagePlatform(tc.img, tc.arch); got != tc.want {
+			t.Errorf("imagePlatform(%+v, %q) = %q, want %q", tc.img, tc.arch, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// checkpointRoot is where the checkpoints of containers are written on the node, before they are copied to the host
+var checkpointRoot = path.Join(vmpath.GuestPersistentDir, "checkpoints")
+
+// CheckpointContainer checkpoints a running container of a node into the tarball at output on the host, returning the ID of the container.
+// The container is given by its ID, or a prefix of it, or as POD/CONTAINER within namespace.
+func CheckpointContainer(api libmachine.API, cc config.ClusterConfig, n config.Node, namespace string, target string, output string) (string, error) {
+	cr, runner, err := nodeRuntime(api, cc, n)
+	if err != nil {
+		return "", err
+	}
+	id, err := findContainer(cr, namespace, target)
+	if err != nil {
+		return "", err
+	}
+
+	out.Step(style.Caching, "Checkpointing container {{.container}} ...", out.V{"container": target})
+	if _, err := runner.RunCmd(exec.Command("sudo", "mkdir", "-p", checkpointRoot)); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("checkpoint-%s.tar", id)
+	src := path.Join(checkpointRoot, name)
+	defer removeNodeArchive(runner, src)
+	if err := cr.CheckpointContainer(id, src); err != nil {
+		return "", err
+	}
+
+	out.Step(style.Copying, "Copying the checkpoint to {{.output}} ...", out.V{"output": output})
+	return id, writeAtomically(output, func(tmp string) error {
+		f, err := assets.NewFileAsset(tmp, checkpointRoot, name, "0644")
+		if err != nil {
+			return errors.Wrapf(err, "creating copyable file asset: %s", tmp)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				klog.Warningf("error closing the file %s: %v", f.GetSourcePath(), err)
+			}
+		}()
+		return errors.Wrap(runner.CopyFrom(f), "transferring checkpoint")
+	})
+}
+
+// findContainer returns the ID of the running container given by an ID prefix, or as POD/CONTAINER within namespace
+func findContainer(cr cruntime.Manager, namespace string, target string) (string, error) {
+	o := cruntime.ListContainersOptions{State: cruntime.Running}
+	pod, name, byName := strings.Cut(target, "/")
+	if byName {
+		o.Name = name
+		o.Namespaces = []string{namespace}
+	}
+	infos, err := cr.ListContainerInfo(o)
+	if err != nil {
+		return "", errors.Wrap(err, "listing containers")
+	}
+	ids := []string{}
+	for _, c := range infos {
+		if byName && c.Pod == pod && c.Name == name && c.Namespace == namespace {
+			ids = append(ids, c.ID)
+		}
+		if !byName && strings.HasPrefix(c.ID, target) {
+			ids = append(ids, c.ID)
+		}
+	}
+	switch {
+	case len(ids) == 0 && byName:
+		return "", fmt.Errorf("no running container %s in pod %s/%s", name, namespace, pod)
+	case len(ids) == 0:
+		return "", fmt.Errorf("no running container %s", target)
+	case len(ids) > 1:
+		return "", fmt.Errorf("%s matches several running containers: %s", target, strings.Join(ids, ", "))
+	}
+	return ids[0], nil
+}
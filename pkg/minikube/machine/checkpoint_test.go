@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/cruntime/cruntimetest"
+)
+
+func TestFindContainer(t *testing.T) {
+	cr := cruntimetest.NewFakeRuntime()
+	cr.AddContainer("abc123", cruntimetest.FakeContainer{Name: "nginx", Pod: "web", Namespace: "default"})
+	cr.AddContainer("abd456", cruntimetest.FakeContainer{Name: "nginx", Pod: "web", Namespace: "staging"})
+	cr.AddContainer("ef7890", cruntimetest.FakeContainer{Name: "sidecar", Pod: "web", Namespace: "default", State: cruntimetest.StateExited})
+
+	var tests = []struct {
+		namespace string
+		target    string
+		want      string
+		wantErr   string
+	}{
+		{"default", "web/nginx", "abc123", ""},
+		{"staging", "web/nginx", "abd456", ""},
+		{"default", "abd", "abd456", ""},
+		{"default", "ab", "", "matches several running containers"},
+		{"default", "web/sidecar", "", "no running container sidecar in pod default/web"},
+		{"default", "ef7890", "", "no running container ef7890"},
+	}
+	for _, tc := range tests {
+		got, err := findContainer(cr, tc.namespace, tc.target)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("findContainer(%q, %q) = %q, %v, want an error containing %q", tc.namespace, tc.target, got, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("findContainer(%q, %q) = %q, %v, want %q", tc.namespace, tc.target, got, err, tc.want)
+		}
+	}
+}
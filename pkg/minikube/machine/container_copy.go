@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/docker/machine/libmachine"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+// ContainerTarget is the container to copy a file into
+type ContainerTarget struct {
+	// Pod and Namespace are the pod of the container, if given by its pod
+	Pod       string
+	Namespace string
+	// Container is the name of the container within the pod, which may be omitted if the pod has a single one,
+	// or else the ID of the container, or a prefix of it
+	Container string
+}
+
+func (t ContainerTarget) String() string {
+	if t.Pod == "" {
+		return t.Container
+	}
+	if t.Container == "" {
+		return fmt.Sprintf("%s/%s", t.Namespace, t.Pod)
+	}
+	return fmt.Sprintf("%s/%s/%s", t.Namespace, t.Pod, t.Container)
+}
+
+// stagedFile is a file staged in dir of the node, before it is copied into a container
+type stagedFile struct {
+	assets.CopyableFile
+	dir string
+}
+
+func (f stagedFile) GetTargetDir() string {
+	return f.dir
+}
+
+func (f stagedFile) GetTargetPath() string {
+	return path.Join(f.dir, f.GetTargetName())
+}
+
+// CopyToContainer copies the file into a container of a node, as dst, and returns the ID of the container.
+// It streams the file to the node, then lets the runtime copy it, so that it copies into crashed containers as well where the runtime allows.
+func CopyToContainer(api libmachine.API, cc config.ClusterConfig, n config.Node, t ContainerTarget, f assets.CopyableFile, dst string, o cruntime.CopyOptions) (string, error) {
+	cr, runner, err := nodeRuntime(api, cc, n)
+	if err != nil {
+		return "", err
+	}
+	c, err := findContainerToCopy(cr, t)
+	if err != nil {
+		return "", err
+	}
+
+	staged := stagedFile{CopyableFile: f, dir: path.Join(cruntime.ContainerCopyRoot, "staged-"+c.ID)}
+	defer func() {
+		if _, err := runner.RunCmd(exec.Command("sudo", "rm", "-rf", staged.dir)); err != nil {
+			klog.Warningf("unable to remove %s: %v", staged.dir, err)
+		}
+	}()
+	if _, err := runner.RunCmd(exec.Command("sudo", "mkdir", "-p", staged.dir)); err != nil {
+		return "", err
+	}
+	if err := runner.Copy(staged); err != nil {
+		return "", err
+	}
+	return c.ID, cr.CopyToContainer(c, staged.GetTargetPath(), path.Dir(dst), o)
+}
+
+// findContainerToCopy returns the container given by the target, whether running or not.
+// Among the containers of a pod with the same name, which the restarts leave behind, it prefers the running one, then the latest.
+func findContainerToCopy(cr cruntime.Manager, t ContainerTarget) (cruntime.ContainerInfo, error) {
+	o := cruntime.ListContainersOptions{State: cruntime.All}
+	if t.Pod != "" {
+		o.Name = t.Container
+		o.Namespaces = []string{t.Namespace}
+	}
+	infos, err := cr.ListContainerInfo(o)
+	if err != nil {
+		return cruntime.ContainerInfo{}, err
+	}
+	matches := []cruntime.ContainerInfo{}
+	names := map[string]bool{}
+	for _, c := range infos {
+		if t.Pod != "" && c.Pod == t.Pod && c.Namespace == t.Namespace && (t.Container == "" || c.Name == t.Container) {
+			matches = append(matches, c)
+			names[c.Name] = true
+		}
+		if t.Pod == "" && strings.HasPrefix(c.ID, t.Container) {
+			matches = append(matches, c)
+			names[c.ID] = true
+		}
+	}
+	if len(matches) == 0 {
+		return cruntime.ContainerInfo{}, fmt.Errorf("no container %s", t)
+	}
+	if len(names) > 1 {
+		several := []string{}
+		for name := range names {
+			several = append(several, name)
+		}
+		sort.Strings(several)
+		if t.Pod != "" {
+			return cruntime.ContainerInfo{}, fmt.Errorf("pod %s/%s has several containers, choose one of: %s", t.Namespace, t.Pod, strings.Join(several, ", "))
+		}
+		return cruntime.ContainerInfo{}, fmt.Errorf("%s matches several containers: %s", t, strings.Join(several, ", "))
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		ri, rj := matches[i].State == cruntime.Running.String(), matches[j].State == cruntime.Running.String()
+		if ri != rj {
+			return ri
+		}
+		return matches[i].Created.After(matches[j].Created)
+	})
+	return matches[0], nil
+}
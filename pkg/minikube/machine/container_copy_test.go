@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/minikube/pkg/minikube/cruntime/cruntimetest"
+)
+
+func TestFindContainerToCopy(t *testing.T) {
+	cr := cruntimetest.NewFakeRuntime()
+	now := time.Now()
+	cr.AddContainer("abc123", cruntimetest.FakeContainer{Name: "app", Pod: "web", Namespace: "default", State: cruntimetest.StateExited, Created: now.Add(-2 * time.Minute)})
+	cr.AddContainer("abd456", cruntimetest.FakeContainer{Name: "app", Pod: "web", Namespace: "default", State: cruntimetest.StateExited, Created: now.Add(-time.Minute)})
+	cr.AddContainer("ef7890", cruntimetest.FakeContainer{Name: "app", Pod: "api", Namespace: "default", State: cruntimetest.StateExited, Created: now.Add(-time.Minute)})
+	cr.AddContainer("ef7891", cruntimetest.FakeContainer{Name: "app", Pod: "api", Namespace: "default", Created: now.Add(-2 * time.Minute)})
+	cr.AddContainer("ef7892", cruntimetest.FakeContainer{Name: "sidecar", Pod: "api", Namespace: "default"})
+
+	var tests = []struct {
+		target  ContainerTarget
+		want    string
+		wantErr string
+	}{
+		{ContainerTarget{Pod: "web", Namespace: "default"}, "abd456", ""},
+		{ContainerTarget{Pod: "web", Namespace: "default", Container: "app"}, "abd456", ""},
+		{ContainerTarget{Pod: "api", Namespace: "default", Container: "app"}, "ef7891", ""},
+		{ContainerTarget{Pod: "api", Namespace: "default"}, "", "pod default/api has several containers, choose one of: app, sidecar"},
+		{ContainerTarget{Pod: "web", Namespace: "staging"}, "", "no container staging/web"},
+		{ContainerTarget{Container: "abc"}, "abc123", ""},
+		{ContainerTarget{Container: "ab"}, "", "ab matches several containers: abc123, abd456"},
+	}
+	for _, tc := range tests {
+		got, err := findContainerToCopy(cr, tc.target)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("findContainerToCopy(%s) = %q, %v, want an error containing %q", tc.target, got.ID, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || got.ID != tc.want {
+			t.Errorf("findContainerToCopy(%s) = %q, %v, want %q", tc.target, got.ID, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"context"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+// RunDiagnosticContainer runs a one-off container of img with cmd in the network of a node, streaming its output, and returns its exit code.
+// The image is pulled if the node does not have it, unless the cluster is assumed to be offline.
+func RunDiagnosticContainer(ctx context.Context, api libmachine.API, cc config.ClusterConfig, n config.Node, img string, cmd []string, o cruntime.DiagnosticOptions) (int, error) {
+	h, err := LoadHost(api, config.MachineName(cc, n))
+	if err != nil {
+		return -1, errors.Wrap(err, "load host")
+	}
+	runner, err := CommandRunner(h)
+	if err != nil {
+		return -1, err
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(cc, n), Driver: cc.Driver, Runner: runner, Socket: cc.KubernetesConfig.CRISocket, Offline: cc.AssumeOffline})
+	if err != nil {
+		return -1, errors.Wrap(err, "error creating container runtime")
+	}
+	return cr.RunDiagnosticContainer(ctx, img, cmd, o)
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/image"
+)
+
+// ImageCategory is why an image is on a node
+type ImageCategory string
+
+const (
+	// ImageControlPlane are the images of kubeadm, and those of the containers of kube-system which no addon uses
+	ImageControlPlane ImageCategory = "control-plane"
+	// ImageAddon are the images of the enabled addons
+	ImageAddon ImageCategory = "addon"
+	// ImageWorkload are the other images which containers use, running or not
+	ImageWorkload ImageCategory = "workload"
+	// ImageCached are the unused images of the cache of the host, which each start loads again
+	ImageCached ImageCategory = "cached"
+	// ImageUnreferenced are the other images, which are safe to prune
+	ImageUnreferenced ImageCategory = "unreferenced"
+)
+
+// imageCategories are the categories in the order of their precedence, when an image falls into several
+var imageCategories = []ImageCategory{ImageControlPlane, ImageAddon, ImageWorkload, ImageCached, ImageUnreferenced}
+
+// ImageInventory is what AnalyzeImages cross-references: the images of a node, the containers using them, and the images expected
+type ImageInventory struct {
+	Images     []cruntime.ListImage
+	Containers []cruntime.ContainerInfo
+	// ControlPlane are the images of kubeadm for the Kubernetes version
+	ControlPlane []string
+	// Addons are the images of the enabled addons
+	Addons []string
+	// Cached are the images of the cache of the host, as listed by 'minikube cache list'
+	Cached []string
+}
+
+// AnalyzedImage is an image of a node along with its category
+type AnalyzedImage struct {
+	ID   string   `json:"id"`
+	Tags []string `json:"tags"`
+	// Size is the size of the image in bytes, including the layers it shares with other images
+	Size int64 `json:"size"`
+	// SharedSize is the number of bytes the image shares with other images, if the runtime reports it
+	SharedSize int64         `json:"sharedSize,omitempty"`
+	Category   ImageCategory `json:"category"`
+	// Containers is how many containers use the image, running or not
+	Containers int `json:"containers"`
+	// Cached is whether the cache of the host holds the image
+	Cached bool `json:"cached"`
+}
+
+// ImageCategorySummary sums up the images of a category
+type ImageCategorySummary struct {
+	Category ImageCategory `json:"category"`
+	Images   int           `json:"images"`
+	Size     int64         `json:"size"`
+}
+
+// ImageAnalysis is the report of AnalyzeImages
+type ImageAnalysis struct {
+	Node       string                 `json:"node"`
+	Images     []AnalyzedImage        `json:"images"`
+	Categories []ImageCategorySummary `json:"categories"`
+	// Size is the size of all the images, counting the layers they share once per image
+	Size int64 `json:"size"`
+	// Reclaimable is the number of bytes pruning the unreferenced images frees at least, as the layers they share may be used by others
+	Reclaimable int64 `json:"reclaimable"`
+	// Prune are the references of the unreferenced images: their tags, or their IDs if dangling
+	Prune []string `json:"prune"`
+}
+
+// imageKeys returns the keys an image is referenced by: its ID, its tags and its digests, fully qualified if valid
+func imageKeys(img cruntime.ListImage) []string {
+	keys := []string{strings.TrimPrefix(img.ID, "sha256:")}
+	for _, ref := range append(append([]string{}, img.RepoTags...), img.RepoDigests...) {
+		if n, err := image.NormalizeReference(ref); err == nil {
+			ref = n
+		}
+		keys = append(keys, ref)
+	}
+	return keys
+}
+
+// refSet returns the references, fully qualified if valid
+func refSet(refs []string) map[string]bool {
+	set := map[string]bool{}
+	for _, r := range refs {
+		if n, err := image.NormalizeReference(r); err == nil {
+			r = n
+		}
+		set[r] = true
+	}
+	return set
+}
+
+// usesImage returns whether the image of a container, which is a reference, an ID or a prefix of one, is among the keys of an image
+func usesImage(container string, keys []string) bool {
+	id := strings.TrimPrefix(container, "sha256:")
+	ref := container
+	if n, err := image.NormalizeReference(container); err == nil {
+		ref = n
+	}
+	for i, k := range keys {
+		if k == ref || k == id || (i == 0 && len(id) >= 12 && strings.HasPrefix(k, id)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalyzeImages categorizes the images of a node, to tell which take its disk space and which are safe to prune
+func AnalyzeImages(node string, inv ImageInventory) ImageAnalysis {
+	controlPlane, addons, cached := refSet(inv.ControlPlane), refSet(inv.Addons), refSet(inv.Cached)
+	a := ImageAnalysis{Node: node, Images: []AnalyzedImage{}, Prune: []string{}}
+	for _, img := range inv.Images {
+		keys := imageKeys(img)
+		ai := AnalyzedImage{ID: img.ID, Tags: img.RepoTags, Category: ImageUnreferenced}
+		if ai.Tags == nil {
+			ai.Tags = []string{}
+		}
+		if size, err := strconv.ParseInt(img.Size, 10, 64); err == nil {
+			ai.Size = size
+		}
+		if shared, err := strconv.ParseInt(img.SharedSize, 10, 64); err == nil {
+			ai.SharedSize = shared
+		}
+
+		in := func(set map[string]bool) bool {
+			for _, k := range keys[1:] {
+				if set[k] {
+					return true
+				}
+			}
+			return false
+		}
+		system := false
+		for _, c := range inv.Containers {
+			if usesImage(c.Image, keys) {
+				ai.Containers++
+				system = system || c.Namespace == "kube-system"
+			}
+		}
+		ai.Cached = in(cached)
+		switch {
+		case in(controlPlane):
+			ai.Category = ImageControlPlane
+		case in(addons):
+			ai.Category = ImageAddon
+		case system:
+			ai.Category = ImageControlPlane
+		case ai.Containers > 0:
+			ai.Category = ImageWorkload
+		case ai.Cached:
+			ai.Category = ImageCached
+		}
+		a.Images = append(a.Images, ai)
+	}
+
+	sort.SliceStable(a.Images, func(i, j int) bool {
+		if a.Images[i].Category != a.Images[j].Category {
+			return categoryRank(a.Images[i].Category) < categoryRank(a.Images[j].Category)
+		}
+		return a.Images[i].Size > a.Images[j].Size
+	})
+	sums := map[ImageCategory]*ImageCategorySummary{}
+	for _, c := range imageCategories {
+		sums[c] = &ImageCategorySummary{Category: c}
+	}
+	for _, ai := range a.Images {
+		s := sums[ai.Category]
+		s.Images++
+		s.Size += ai.Size
+		a.Size += ai.Size
+		if ai.Category != ImageUnreferenced {
+			continue
+		}
+		a.Reclaimable += ai.Size - ai.SharedSize
+		if len(ai.Tags) == 0 {
+			a.Prune = append(a.Prune, ai.ID)
+		}
+		a.Prune = append(a.Prune, ai.Tags...)
+	}
+	for _, c := range imageCategories {
+		a.Categories = append(a.Categories, *sums[c])
+	}
+	return a
+}
+
+// categoryRank returns the precedence of a category, the lowest first
+func categoryRank(c ImageCategory) int {
+	for i, ic := range imageCategories {
+		if ic == c {
+			return i
+		}
+	}
+	return len(imageCategories)
+}
+
+// AnalyzeNodeImages categorizes the images of a node against the images kubeadm and the enabled addons of the cluster expect,
+// the containers of the node, and the images of the cache of the host
+func AnalyzeNodeImages(api libmachine.API, cc config.ClusterConfig, n config.Node, cached []string) (*ImageAnalysis, error) {
+	cr, _, err := nodeRuntime(api, cc, n)
+	if err != nil {
+		return nil, err
+	}
+	inv := ImageInventory{Cached: cached}
+	if inv.Images, err = cr.ListImages(cruntime.ListImagesOptions{}); err != nil {
+		return nil, errors.Wrap(err, "listing images")
+	}
+	if inv.Containers, err = cr.ListContainerInfo(cruntime.ListContainersOptions{State: cruntime.All}); err != nil {
+		return nil, errors.Wrap(err, "listing containers")
+	}
+	if inv.ControlPlane, err = images.Kubeadm(cc.KubernetesConfig.ImageRepository, cc.KubernetesConfig.KubernetesVersion); err != nil {
+		klog.Warningf("unable to list the images of kubeadm %s: %v", cc.KubernetesConfig.KubernetesVersion, err)
+	}
+	for _, addon := range assets.Addons {
+		if !addon.IsEnabled(&cc) {
+			continue
+		}
+		imgs, registries := assets.PersistedImages(addon, &cc)
+		inv.Addons = append(inv.Addons, assets.ImageRefs(addon, &cc, imgs, registries)...)
+	}
+	a := AnalyzeImages(config.MachineName(cc, n), inv)
+	return &a, nil
+}
+
+// PruneCandidates returns the unreferenced images of the nodes which no node uses otherwise, as 'minikube image rm' removes them from all nodes
+func PruneCandidates(analyses []ImageAnalysis) []string {
+	kept := map[string]bool{}
+	for _, a := range analyses {
+		for _, ai := range a.Images {
+			if ai.Category == ImageUnreferenced {
+				continue
+			}
+			kept[ai.ID] = true
+			for _, t := range ai.Tags {
+				kept[t] = true
+			}
+		}
+	}
+	seen := map[string]bool{}
+	refs := []string{}
+	for _, a := range analyses {
+		for _, ref := range a.Prune {
+			if !kept[ref] && !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+func TestAnalyzeImages(t *testing.T) {
+	inv := ImageInventory{
+		Images: []cruntime.ListImage{
+			{ID: "sha256:aaaa00000000", RepoTags: []string{"registry.k8s.io/kube-apiserver:v1.25.3"}, Size: "1000"},
+			{ID: "sha256:bbbb00000000", RepoTags: []string{"registry.k8s.io/kube-proxy:v1.25.3"}, Size: "800"},
+			{ID: "sha256:cccc00000000", RepoTags: []string{"docker.io/kindest/kindnetd:v20221004-44d545d1"}, Size: "500"},
+			{ID: "sha256:dddd00000000", RepoTags: []string{"docker.io/kubernetesui/dashboard:v2.7.0"}, Size: "400"},
+			{ID: "sha256:eeee00000000", RepoTags: []string{"docker.io/library/nginx:1.23"}, Size: "300"},
+			{ID: "sha256:ffff00000000", RepoTags: []string{"docker.io/library/redis:7"}, Size: "200"},
+			{ID: "sha256:1111000000000000", RepoTags: []string{"docker.io/library/busybox:latest"}, Size: "100"},
+			{ID: "sha256:2222000000000000", RepoTags: []string{"docker.io/library/alpine:3.16"}, Size: "50", SharedSize: "20"},
+			{ID: "sha256:3333000000000000", Size: "30"},
+		},
+		Containers: []cruntime.ContainerInfo{
+			{ID: "c1", Namespace: "kube-system", Image: "sha256:bbbb00000000"},
+			{ID: "c2", Namespace: "kube-system", Image: "docker.io/kindest/kindnetd:v20221004-44d545d1"},
+			{ID: "c3", Namespace: "kubernetes-dashboard", Image: "kubernetesui/dashboard:v2.7.0"},
+			{ID: "c4", Namespace: "default", Image: "nginx:1.23", State: "running"},
+			{ID: "c5", Namespace: "default", Image: "nginx:1.23", State: "exited"},
+			{ID: "c6", Namespace: "default", Image: "eeee0000", State: "running"},
+			{ID: "c7", Namespace: "default", Image: "ffff00000000", State: "exited"},
+		},
+		ControlPlane: []string{"registry.k8s.io/kube-apiserver:v1.25.3", "registry.k8s.io/kube-proxy:v1.25.3"},
+		Addons:       []string{"kubernetesui/dashboard:v2.7.0"},
+		Cached:       []string{"busybox", "nginx:1.23"},
+	}
+	a := AnalyzeImages("minikube", inv)
+
+	got := map[string]ImageCategory{}
+	containers := map[string]int{}
+	for _, ai := range a.Images {
+		got[ai.ID] = ai.Category
+		containers[ai.ID] = ai.Containers
+	}
+	want := map[string]ImageCategory{
+		"sha256:aaaa00000000":     ImageControlPlane,
+		"sha256:bbbb00000000":     ImageControlPlane,
+		"sha256:cccc00000000":     ImageControlPlane,
+		"sha256:dddd00000000":     ImageAddon,
+		"sha256:eeee00000000":     ImageWorkload,
+		"sha256:ffff00000000":     ImageWorkload,
+		"sha256:1111000000000000": ImageCached,
+		"sha256:2222000000000000": ImageUnreferenced,
+		"sha256:3333000000000000": ImageUnreferenced,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("AnalyzeImages() categories mismatch (-want +got):\n%s", diff)
+	}
+	// the short ID prefix "eeee0000" is too short to be told from a name, so only the references count
+	if containers["sha256:eeee00000000"] != 2 {
+		t.Errorf("containers of nginx = %d, want 2", containers["sha256:eeee00000000"])
+	}
+
+	wantCategories := []ImageCategorySummary{
+		{Category: ImageControlPlane, Images: 3, Size: 2300},
+		{Category: ImageAddon, Images: 1, Size: 400},
+		{Category: ImageWorkload, Images: 2, Size: 500},
+		{Category: ImageCached, Images: 1, Size: 100},
+		{Category: ImageUnreferenced, Images: 2, Size: 80},
+	}
+	if diff := cmp.Diff(wantCategories, a.Categories); diff != "" {
+		t.Errorf("AnalyzeImages() summaries mismatch (-want +got):\n%s", diff)
+	}
+	if a.Size != 3380 {
+		t.Errorf("Size = %d, want 3380", a.Size)
+	}
+	if a.Reclaimable != 60 {
+		t.Errorf("Reclaimable = %d, want 60", a.Reclaimable)
+	}
+	if diff := cmp.Diff([]string{"docker.io/library/alpine:3.16", "sha256:3333000000000000"}, a.Prune); diff != "" {
+		t.Errorf("Prune mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPruneCandidates(t *testing.T) {
+	analyses := []ImageAnalysis{
+		{Node: "minikube", Prune: []string{"docker.io/library/alpine:3.16", "sha256:3333"}, Images: []AnalyzedImage{
+			{ID: "sha256:1111", Tags: []string{"docker.io/library/nginx:1.23"}, Category: ImageWorkload},
+		}},
+		{Node: "minikube-m02", Prune: []string{"docker.io/library/alpine:3.16", "docker.io/library/nginx:1.23"}},
+	}
+	want := []string{"docker.io/library/alpine:3.16", "sha256:3333"}
+	if diff := cmp.Diff(want, PruneCandidates(analyses)); diff != "" {
+		t.Errorf("PruneCandidates() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/juju/mutex"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/localpath"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/util/lock"
+)
+
+const (
+	// imageLockWait is how long an image operation waits for another one before telling the user
+	imageLockWait = 2 * time.Second
+	// imageLockTimeout is how long an image operation waits for another one at most, which may be loading large images
+	imageLockTimeout = 30 * time.Minute
+)
+
+// imageLockPath returns the path the lock of the image operations of a profile is named after
+func imageLockPath(profile string) string {
+	return filepath.Join(localpath.Profile(profile), "image.lock")
+}
+
+// acquireImageLock acquires the lock serializing the image operations of a profile among all the minikube processes,
+// which changes the image cache of the host and shares the paths of the archives in the nodes
+func acquireImageLock(profile string) (mutex.Releaser, error) {
+	spec := lock.PathMutexSpec(imageLockPath(profile))
+	spec.Timeout = imageLockWait
+	klog.Infof("acquiring image lock for %s: %+v", profile, spec)
+	start := time.Now()
+	r, err := mutex.Acquire(spec)
+	if err == mutex.ErrTimeout {
+		out.Step(style.Waiting, "waiting for another minikube image operation on {{.profile}} ...", out.V{"profile": profile})
+		spec.Timeout = imageLockTimeout - imageLockWait
+		r, err = mutex.Acquire(spec)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to acquire the image lock of %s", profile)
+	}
+	klog.Infof("acquired image lock for %s in %s", profile, time.Since(start))
+	return r, nil
+}
+
+// acquireImageLocks acquires the image locks of the profiles, in the order of their names so that no two processes wait for each other.
+// It returns a function releasing them.
+func acquireImageLocks(profiles []*config.Profile) (func(), error) {
+	names := []string{}
+	for _, p := range profiles {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	releasers := []mutex.Releaser{}
+	release := func() {
+		for _, r := range releasers {
+			r.Release()
+		}
+	}
+	for i, name := range names {
+		if i > 0 && name == names[i-1] {
+			continue
+		}
+		r, err := acquireImageLock(name)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releasers = append(releasers, r)
+	}
+	return release, nil
+}
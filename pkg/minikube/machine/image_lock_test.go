@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+// loadedArchiveRe matches the archive 'docker load' reads
+var loadedArchiveRe = regexp.MustCompile(`cat (\S+) \| docker load`)
+
+// lockedNode is a node recording the image archives copied into it, loaded and removed, and how many loads run at once
+type lockedNode struct {
+	*command.FakeCommandRunner
+	mu      sync.Mutex
+	events  []string
+	loading int
+	overlap bool
+}
+
+func (n *lockedNode) record(event string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+}
+
+func (n *lockedNode) Copy(f assets.CopyableFile) error {
+	n.record("copy " + f.GetTargetName())
+	return n.FakeCommandRunner.Copy(f)
+}
+
+func (n *lockedNode) Remove(f assets.CopyableFile) error {
+	n.record("remove " + f.GetTargetName())
+	return n.FakeCommandRunner.Remove(f)
+}
+
+func (n *lockedNode) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
+	line := strings.Join(cmd.Args, " ")
+	if !strings.Contains(line, "docker load") {
+		return &command.RunResult{Args: cmd.Args}, nil
+	}
+	n.mu.Lock()
+	n.loading++
+	if n.loading > 1 {
+		n.overlap = true
+	}
+	n.mu.Unlock()
+	// the load takes a while, giving the other invocations the chance to interleave
+	time.Sleep(20 * time.Millisecond)
+	n.mu.Lock()
+	n.loading--
+	n.mu.Unlock()
+	n.record("load " + filepath.Base(loadedArchiveRe.FindStringSubmatch(line)[1]))
+	rr := &command.RunResult{Args: cmd.Args}
+	rr.Stdout.WriteString("Loaded image: app:latest\n")
+	return rr, nil
+}
+
+func (n *lockedNode) RunCmdContext(_ context.Context, cmd *exec.Cmd) (*command.RunResult, error) {
+	return n.RunCmd(cmd)
+}
+
+func TestLoadLocalImagesConcurrently(t *testing.T) {
+	t.Setenv("MINIKUBE_HOME", t.TempDir())
+	const invocations = 5
+	dir := t.TempDir()
+	node := &lockedNode{FakeCommandRunner: command.NewFakeCommandRunner()}
+	cc := &config.ClusterConfig{Name: "stress", KubernetesConfig: config.KubernetesConfig{ContainerRuntime: "docker"}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, invocations)
+	for i := 0; i < invocations; i++ {
+		archive := filepath.Join(dir, fmt.Sprintf("app-%d.tar", i))
+		if err := os.WriteFile(archive, []byte("archive"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := LoadLocalImages(cc, node, []string{archive}, cruntime.ImageOutputBuffered)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("LoadLocalImages: %v", err)
+		}
+	}
+
+	if node.overlap {
+		t.Errorf("the loads of the invocations ran at once: %v", node.events)
+	}
+	if len(node.events) != 3*invocations {
+		t.Fatalf("events = %v, want a copy, load and remove per invocation", node.events)
+	}
+	// each invocation copies, loads and removes its archive before the next one starts
+	for i := 0; i < len(node.events); i += 3 {
+		name := strings.TrimPrefix(node.events[i], "copy ")
+		want := []string{"copy " + name, "load " + name, "remove " + name}
+		if got := node.events[i : i+3]; strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("critical section %d = %v, want %v, in %v", i/3, got, want, node.events)
+		}
+	}
+}
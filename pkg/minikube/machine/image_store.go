@@ -0,0 +1,219 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+
+	"github.com/docker/go-units"
+	"github.com/docker/machine/libmachine"
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/v3/disk"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+// imageStoreArchive is the name of the archive of all the images of a node, within saveRoot and loadRoot, made unique to the invocation
+const imageStoreArchive = "image-store.tar"
+
+// nodeRuntime returns the container runtime of a node, and its command runner
+func nodeRuntime(api libmachine.API, cc config.ClusterConfig, n config.Node) (cruntime.Manager, command.Runner, error) {
+	h, err := LoadHost(api, config.MachineName(cc, n))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "load host")
+	}
+	runner, err := CommandRunner(h)
+	if err != nil {
+		return nil, nil, err
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(cc, n), Driver: cc.Driver, Runner: runner})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating container runtime")
+	}
+	return cr, runner, nil
+}
+
+// SnapshotImages exports all the tagged images of a node into the archive at output on the host.
+// The archive replaces output only once it is complete, so that a failed or interrupted snapshot leaves output as it was.
+func SnapshotImages(ctx context.Context, api libmachine.API, cc config.ClusterConfig, n config.Node, output string) error {
+	cr, runner, err := nodeRuntime(api, cc, n)
+	if err != nil {
+		return err
+	}
+	tagged := false
+	images, err := cr.ListImages(cruntime.ListImagesOptions{Dangling: &tagged})
+	if err != nil {
+		return errors.Wrap(err, "listing images")
+	}
+	// layers shared by images are only exported once, so the archive is usually smaller
+	size := imagesTotalSize(images)
+	machineName := config.MachineName(cc, n)
+	// the archive is staged on the disk of the node before it is copied to the host
+	warnNodeDiskSpace(runner, machineName, saveRoot, size)
+	warnDiskSpace(output, size)
+
+	out.Step(style.Caching, "Exporting {{.count}} images of {{.node}} ({{.size}}) ...", out.V{"count": len(images), "node": machineName, "size": units.HumanSizeWithPrecision(float64(size), 3)})
+	archive := cruntime.NodeTempName(imageStoreArchive)
+	src := path.Join(saveRoot, archive)
+	if _, err := runner.RunCmd(exec.Command("sudo", "rm", "-f", src)); err != nil {
+		return err
+	}
+	defer removeNodeArchive(runner, src)
+	if err := cr.ExportImageStore(src); err != nil {
+		return errors.Wrapf(err, "%s export %s", cr.Name(), src)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	out.Step(style.Copying, "Copying the images to {{.output}} ...", out.V{"output": output})
+	return writeAtomically(output, func(tmp string) error {
+		f, err := assets.NewFileAsset(tmp, saveRoot, archive, "0644")
+		if err != nil {
+			return errors.Wrapf(err, "creating copyable file asset: %s", tmp)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				klog.Warningf("error closing the file %s: %v", f.GetSourcePath(), err)
+			}
+		}()
+		if err := runner.CopyFrom(f); err != nil {
+			return errors.Wrap(err, "transferring image archive")
+		}
+		return ctx.Err()
+	})
+}
+
+// RestoreImages imports the images of an archive written by SnapshotImages into a node
+func RestoreImages(ctx context.Context, api libmachine.API, cc config.ClusterConfig, n config.Node, input string) error {
+	fi, err := os.Stat(input)
+	if err != nil {
+		return err
+	}
+	cr, runner, err := nodeRuntime(api, cc, n)
+	if err != nil {
+		return err
+	}
+
+	machineName := config.MachineName(cc, n)
+	warnNodeDiskSpace(runner, machineName, loadRoot, fi.Size())
+	out.Step(style.Copying, "Copying {{.input}} to {{.node}} ...", out.V{"input": input, "node": machineName})
+	archive := cruntime.NodeTempName(imageStoreArchive)
+	f, err := assets.NewFileAsset(input, loadRoot, archive, "0644")
+	if err != nil {
+		return errors.Wrapf(err, "creating copyable file asset: %s", input)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			klog.Warningf("error closing the file %s: %v", f.GetSourcePath(), err)
+		}
+	}()
+	dst := path.Join(loadRoot, archive)
+	defer removeNodeArchive(runner, dst)
+	if err := runner.Copy(f); err != nil {
+		return errors.Wrap(err, "transferring image archive")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	loadImageLock.Lock()
+	defer loadImageLock.Unlock()
+
+	out.Step(style.Caching, "Importing the images into {{.node}} ...", out.V{"node": machineName})
+	if err := cr.ImportImageStore(dst); err != nil {
+		return errors.Wrapf(err, "%s import %s", cr.Name(), dst)
+	}
+	return nil
+}
+
+// removeNodeArchive removes an image archive from a node, which only takes up its disk once imported or copied
+func removeNodeArchive(runner command.Runner, p string) {
+	if _, err := runner.RunCmd(exec.Command("sudo", "rm", "-f", p)); err != nil {
+		klog.Warningf("unable to remove %s: %v", p, err)
+	}
+}
+
+// warnDiskSpace warns if an archive of the given size may not fit in the free disk space of the host where output goes
+func warnDiskSpace(output string, size int64) {
+	usage, err := disk.Usage(filepath.Dir(output))
+	if err != nil {
+		klog.Warningf("unable to get the free disk space of %s: %v", filepath.Dir(output), err)
+		return
+	}
+	if size <= 0 || uint64(size) <= usage.Free {
+		return
+	}
+	out.WarningT("The images take up to {{.size}}, but only {{.free}} is free on the disk of {{.dir}}", out.V{
+		"size": units.HumanSizeWithPrecision(float64(size), 3),
+		"free": units.HumanSizeWithPrecision(float64(usage.Free), 3),
+		"dir":  filepath.Dir(output),
+	})
+}
+
+// warnNodeDiskSpace warns if an archive of the given size may not fit in the free disk space of dir in a node, where it is staged
+func warnNodeDiskSpace(runner command.Runner, node, dir string, size int64) {
+	free, err := cruntime.FreeSpace(runner, dir)
+	if err != nil {
+		klog.Warningf("unable to get the free disk space of %s in %s: %v", dir, node, err)
+		return
+	}
+	if size <= 0 || uint64(size) <= free {
+		return
+	}
+	out.WarningT("The images take up to {{.size}}, but only {{.free}} is free on the disk of {{.dir}} in {{.node}}", out.V{
+		"size": units.HumanSizeWithPrecision(float64(size), 3),
+		"free": units.HumanSizeWithPrecision(float64(free), 3),
+		"dir":  dir,
+		"node": node,
+	})
+}
+
+// writeAtomically calls write with a temporary file next to dst, and renames it to dst only if write succeeds,
+// so that dst is never left partially written
+func writeAtomically(dst string, write func(tmp string) error) error {
+	f, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.partial")
+	if err != nil {
+		return errors.Wrap(err, "creating temporary file")
+	}
+	tmp := f.Name()
+	if err := f.Close(); err != nil {
+		klog.Warningf("error closing the file %s: %v", tmp, err)
+	}
+	if err := write(tmp); err != nil {
+		if err := os.Remove(tmp); err != nil {
+			klog.Warningf("unable to remove %s: %v", tmp, err)
+		}
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		if err := os.Remove(tmp); err != nil {
+			klog.Warningf("unable to remove %s: %v", tmp, err)
+		}
+		return errors.Wrapf(err, "renaming %s", tmp)
+	}
+	return nil
+}
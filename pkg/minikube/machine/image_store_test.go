@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/tests"
+)
+
+func TestWriteAtomically(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "images.tar")
+	if err := os.WriteFile(dst, []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeAtomically(dst, func(tmp string) error {
+		if err := os.WriteFile(tmp, []byte("partial"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return errors.New("interrupted")
+	})
+	if err == nil {
+		t.Fatalf("writeAtomically succeeded although the write failed")
+	}
+	if b, _ := os.ReadFile(dst); string(b) != "previous" {
+		t.Errorf("failed write replaced %s with %q", dst, b)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("failed write left temporary files behind: %v", entries)
+	}
+
+	err = writeAtomically(dst, func(tmp string) error {
+		if filepath.Dir(tmp) != dir {
+			t.Errorf("temporary file %s is not next to %s", tmp, dst)
+		}
+		return os.WriteFile(tmp, []byte("complete"), 0644)
+	})
+	if err != nil {
+		t.Fatalf("writeAtomically: %v", err)
+	}
+	if b, _ := os.ReadFile(dst); string(b) != "complete" {
+		t.Errorf("%s = %q, want the complete archive", dst, b)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("write left temporary files behind: %v", entries)
+	}
+}
+
+func TestWarnNodeDiskSpace(t *testing.T) {
+	testCases := []struct {
+		description string
+		size        int64
+		warn        bool
+	}{
+		{description: "fits", size: 1024 * 1024},
+		{description: "too large", size: 4 * 1024 * 1024 * 1024, warn: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := command.NewFakeCommandRunner()
+			// 1G free, in 1K blocks
+			runner.SetCommandToOutput(map[string]string{
+				"df --output=itotal,iavail,avail /var/lib/minikube/images": "Inodes IFree Avail\n1000 900 1048576\n",
+			})
+			f := tests.NewFakeFile()
+			out.SetErrFile(f)
+			warnNodeDiskSpace(runner, "minikube", saveRoot, tc.size)
+			if warned := strings.Contains(f.String(), "is free on the disk of /var/lib/minikube/images in minikube"); warned != tc.warn {
+				t.Errorf("warned = %v, want %v: %q", warned, tc.warn, f.String())
+			}
+		})
+	}
+}
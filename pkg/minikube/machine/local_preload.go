@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/download"
+)
+
+// GenerateLocalPreload saves the kubeadm images of a node into a local preload, for the next starts to load
+func GenerateLocalPreload(cc *config.ClusterConfig, runner command.Runner) error {
+	k8s := cc.KubernetesConfig
+	imgs, err := images.Kubeadm(k8s.ImageRepository, k8s.KubernetesVersion)
+	if err != nil {
+		return errors.Wrap(err, "kubeadm images")
+	}
+
+	klog.Infof("GenerateLocalPreload start: %s", imgs)
+	start := time.Now()
+	defer func() {
+		klog.Infof("GenerateLocalPreload completed in %s", time.Since(start))
+	}()
+
+	dir, err := os.MkdirTemp("", "local-preload")
+	if err != nil {
+		return errors.Wrap(err, "tempdir")
+	}
+	defer os.RemoveAll(dir)
+
+	var g errgroup.Group
+	for _, img := range imgs {
+		img := img
+		g.Go(func() error {
+			dst := filepath.Join(dir, download.LocalPreloadArchiveName(img))
+			return transferAndSaveImage(runner, k8s, dst, img)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return errors.Wrap(err, "saving images")
+	}
+	return download.WriteLocalPreload(k8s.KubernetesVersion, k8s.ContainerRuntime, k8s.ImageRepository, dir, localPreloadCompression(runner))
+}
+
+// localPreloadCompression returns zstd if the node can decompress it, as the next nodes are likely to run the same image
+func localPreloadCompression(runner command.Runner) download.Compression {
+	if _, err := runner.RunCmd(exec.Command("which", download.CompressionZstd.Binary())); err != nil {
+		klog.Infof("zstd is missing in the node, not compressing the local preload: %v", err)
+		return download.CompressionNone
+	}
+	return download.CompressionZstd
+}
@@ -104,8 +104,15 @@ func fastDetectProvisioner(h *host.Host) (libprovision.Provisioner, error) {
 	switch {
 	case driver.IsKIC(d):
 		return provision.NewUbuntuProvisioner(h.Driver), nil
-	case driver.BareMetal(d), driver.IsSSH(d):
+	case driver.BareMetal(d):
 		return libprovision.DetectProvisioner(h.Driver)
+	case driver.IsSSH(d):
+		p, err := libprovision.DetectProvisioner(h.Driver)
+		if err != nil {
+			return nil, err
+		}
+		provision.ElevateSSHCommands(p, h.Driver)
+		return p, nil
 	default:
 		return provision.NewBuildrootProvisioner(h.Driver), nil
 	}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"github.com/docker/machine/libmachine/state"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/detect"
+	"k8s.io/minikube/pkg/minikube/image"
+)
+
+// ImageLoadSummary is how images were loaded into the nodes missing them
+type ImageLoadSummary struct {
+	// Loaded are the images loaded into the nodes which were missing them
+	Loaded []string
+	// Skipped are the images all the running nodes had already
+	Skipped []string
+	// Failed are the images which failed to load into a node, with the error of the first node they failed for
+	Failed map[string]error
+}
+
+// LoadMissingImages loads images into the running nodes of profile which are missing them, checking them all at once on each node.
+// The runtime of the nodes pulls them if pull, else they are cached from the local daemon or their registry and loaded,
+// remapped to the image repository of the profile if remap.
+func LoadMissingImages(images []string, profile *config.Profile, pull bool, remap bool) (ImageLoadSummary, error) {
+	summary := ImageLoadSummary{Loaded: []string{}, Skipped: []string{}, Failed: map[string]error{}}
+	api, err := NewAPIClient()
+	if err != nil {
+		return summary, errors.Wrap(err, "api")
+	}
+	defer api.Close()
+
+	c, err := config.Load(profile.Name)
+	if err != nil {
+		return summary, errors.Wrapf(err, "error loading config for profile :%v", profile.Name)
+	}
+
+	missing := map[string]bool{}
+	// images are cached once for all the nodes, remembering the error of those which failed to
+	cached := map[string]error{}
+	for _, n := range c.Nodes {
+		m := config.MachineName(*c, n)
+		status, err := Status(api, m)
+		if err != nil || status != state.Running.String() {
+			klog.Warningf("skipping %s, status %q: %v", m, status, err)
+			continue
+		}
+		h, err := api.Load(m)
+		if err != nil {
+			return summary, errors.Wrapf(err, "load machine %s", m)
+		}
+		runner, err := CommandRunner(h)
+		if err != nil {
+			return summary, err
+		}
+		cr, err := cruntime.New(cruntime.Config{Type: config.NodeRuntime(*c, n), Driver: c.Driver, Runner: runner})
+		if err != nil {
+			return summary, errors.Wrap(err, "error creating container runtime")
+		}
+		exist, err := cr.ImagesExist(images)
+		if err != nil {
+			return summary, errors.Wrapf(err, "checking the images of %s", m)
+		}
+		nc := config.ForNode(*c, n)
+		for _, img := range images {
+			if exist[img] || summary.Failed[img] != nil {
+				continue
+			}
+			missing[img] = true
+			if err := loadMissingImage(cr, runner, &nc, img, pull, remap, cached); err != nil {
+				klog.Warningf("failed to load %s into %s: %v", img, m, err)
+				summary.Failed[img] = errors.Wrap(err, m)
+			}
+		}
+	}
+
+	for _, img := range images {
+		switch {
+		case summary.Failed[img] != nil:
+		case missing[img]:
+			summary.Loaded = append(summary.Loaded, img)
+		default:
+			summary.Skipped = append(summary.Skipped, img)
+		}
+	}
+	return summary, nil
+}
+
+// loadMissingImage pulls an image by the runtime of a node, or caches it if not cached yet and loads it
+func loadMissingImage(cr cruntime.Manager, runner command.Runner, cc *config.ClusterConfig, img string, pull bool, remap bool, cached map[string]error) error {
+	if pull {
+		return cr.PullImage(img)
+	}
+	err, ok := cached[img]
+	if !ok {
+		err = image.SaveToDir([]string{img}, detect.ImageCacheDir(), false)
+		cached[img] = err
+	}
+	if err != nil {
+		return err
+	}
+	return LoadCachedImages(cc, runner, []string{img}, detect.ImageCacheDir(), false, remap, cruntime.ImageOutputBuffered)
+}
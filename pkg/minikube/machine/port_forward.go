@@ -0,0 +1,50 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"strings"
+
+	"github.com/docker/machine/libmachine"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+// ContainerPortResolver returns a function returning the ports of a container of a node, with its current address in the node.
+// The container is given by its name, its ID or a prefix of it, or as POD/CONTAINER within namespace,
+// which finds the new container of the pod once it restarts.
+func ContainerPortResolver(api libmachine.API, cc config.ClusterConfig, n config.Node, namespace string, target string) (func() ([]cruntime.PortMapping, error), error) {
+	cr, _, err := nodeRuntime(api, cc, n)
+	if err != nil {
+		return nil, err
+	}
+	return func() ([]cruntime.PortMapping, error) {
+		return containerPortMappings(cr, namespace, target)
+	}, nil
+}
+
+// containerPortMappings returns the ports of the container given by target
+func containerPortMappings(cr cruntime.Manager, namespace string, target string) ([]cruntime.PortMapping, error) {
+	id := target
+	if strings.Contains(target, "/") {
+		var err error
+		if id, err = findContainer(cr, namespace, target); err != nil {
+			return nil, err
+		}
+	}
+	return cr.PortMappings(id)
+}
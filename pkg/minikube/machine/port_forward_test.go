@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/cruntime/cruntimetest"
+)
+
+func TestContainerPortMappings(t *testing.T) {
+	cr := cruntimetest.NewFakeRuntime()
+	before := []cruntime.PortMapping{{Protocol: "tcp", ContainerIP: "10.244.0.5", ContainerPort: 80}}
+	cr.AddContainer("abc123", cruntimetest.FakeContainer{Name: "nginx", Pod: "web", Namespace: "default", Ports: before})
+
+	for _, target := range []string{"web/nginx", "abc"} {
+		got, err := containerPortMappings(cr, "default", target)
+		if err != nil {
+			t.Fatalf("containerPortMappings(%q) = %v", target, err)
+		}
+		if diff := cmp.Diff(before, got); diff != "" {
+			t.Errorf("containerPortMappings(%q) mismatch (-want +got):\n%s", target, diff)
+		}
+	}
+
+	// the restarted container of the pod has a new ID and address
+	cr.Containers["abc123"].State = cruntimetest.StateExited
+	after := []cruntime.PortMapping{{Protocol: "tcp", ContainerIP: "10.244.0.9", ContainerPort: 80}}
+	cr.AddContainer("def456", cruntimetest.FakeContainer{Name: "nginx", Pod: "web", Namespace: "default", Ports: after})
+	got, err := containerPortMappings(cr, "default", "web/nginx")
+	if err != nil {
+		t.Fatalf("containerPortMappings(web/nginx) after restart = %v", err)
+	}
+	if diff := cmp.Diff(after, got); diff != "" {
+		t.Errorf("containerPortMappings(web/nginx) after restart mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := containerPortMappings(cr, "default", "missing"); err == nil {
+		t.Errorf("containerPortMappings(missing) succeeded")
+	}
+}
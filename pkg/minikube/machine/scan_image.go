@@ -0,0 +1,202 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/detect"
+	"k8s.io/minikube/pkg/minikube/download"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// scanRoot is where trivy and its vulnerability database are kept within the guest VM
+var scanRoot = path.Join(vmpath.GuestPersistentDir, "trivy")
+
+// ErrScanOffline is returned when scanning offline needs something which is not cached yet
+var ErrScanOffline = errors.New("not available offline")
+
+// trivyBinary returns the path of trivy within the guest VM
+func trivyBinary(version string) string {
+	return path.Join(scanRoot, version, "trivy")
+}
+
+// trivyDBCached returns true if the vulnerability database has been fetched into the cache of trivy
+func trivyDBCached(cr command.Runner) bool {
+	_, err := cr.RunCmd(exec.Command("sudo", "test", "-f", path.Join(scanRoot, "cache", "db", "trivy.db")))
+	return err == nil
+}
+
+// ensureTrivy makes sure trivy is installed within the guest VM, copying it from the host cache
+func ensureTrivy(cr command.Runner, offline bool) (string, error) {
+	version := download.TrivyVersion
+	bin := trivyBinary(version)
+	if _, err := cr.RunCmd(exec.Command("sudo", "test", "-x", bin)); err == nil {
+		return bin, nil
+	}
+
+	arch := detect.EffectiveArch()
+	if offline && !download.TrivyExists(version, arch) {
+		return "", errors.Wrapf(ErrScanOffline, "trivy %s is not cached", version)
+	}
+	out.Step(style.FileDownload, "Downloading trivy {{.version}} ...", out.V{"version": version})
+	src, err := download.Trivy(version, arch)
+	if err != nil {
+		return "", errors.Wrap(err, "downloading trivy")
+	}
+	archive := path.Join(path.Dir(bin), "trivy.tar.gz")
+	if err := CopyBinary(cr, src, archive); err != nil {
+		return "", errors.Wrap(err, "copying trivy")
+	}
+	if _, err := cr.RunCmd(exec.Command("sudo", "tar", "-C", path.Dir(bin), "-xzf", archive, "trivy")); err != nil {
+		return "", errors.Wrap(err, "extracting trivy")
+	}
+	if _, err := cr.RunCmd(exec.Command("sudo", "rm", "-f", archive)); err != nil {
+		klog.Warningf("unable to remove %s: %v", archive, err)
+	}
+	return bin, nil
+}
+
+// trivyArgs returns the command scanning an image with trivy, from an archive if not empty
+func trivyArgs(bin string, target cruntime.ImageScanTarget, img string, archive string, offline bool) []string {
+	args := []string{"sudo"}
+	if len(target.Env) > 0 {
+		args = append(append(args, "env"), target.Env...)
+	}
+	args = append(args, bin, "image", "--quiet", "--format", "json", "--cache-dir", path.Join(scanRoot, "cache"))
+	if offline {
+		args = append(args, "--skip-db-update", "--offline-scan")
+	}
+	if archive != "" {
+		return append(args, "--input", archive)
+	}
+	if target.Source != "" {
+		args = append(args, "--image-src", target.Source)
+	}
+	return append(args, img)
+}
+
+// scanImage scans an image of the runtime with trivy, returning the JSON report
+func scanImage(cr command.Runner, r cruntime.Manager, bin string, img string, offline bool) ([]byte, error) {
+	target := r.ImageScanTarget()
+	archive := ""
+	if target.Archive {
+		archive = path.Join(scanRoot, fmt.Sprintf("scan-%d.tar", time.Now().UnixNano()))
+		if err := r.SaveImage(img, archive); err != nil {
+			return nil, errors.Wrapf(err, "saving %s", img)
+		}
+		defer func() {
+			if _, err := cr.RunCmd(exec.Command("sudo", "rm", "-f", archive)); err != nil {
+				klog.Warningf("unable to remove %s: %v", archive, err)
+			}
+		}()
+	}
+	args := trivyArgs(bin, target, img, archive, offline)
+	rr, err := cr.RunCmd(exec.Command(args[0], args[1:]...))
+	if err != nil {
+		return nil, errors.Wrap(err, "trivy image")
+	}
+	return rr.Stdout.Bytes(), nil
+}
+
+// ScanReport is the part of a trivy JSON report rendered as a table
+type ScanReport struct {
+	Results []struct {
+		Target          string
+		Vulnerabilities []struct {
+			VulnerabilityID  string
+			PkgName          string
+			InstalledVersion string
+			FixedVersion     string
+			Severity         string
+			Title            string
+		}
+	}
+}
+
+// ScanImage scans an image on the primary control plane for vulnerabilities with trivy, and prints the findings
+// If offline, trivy is neither downloaded nor does it update its vulnerability database, which must have been fetched before.
+func ScanImage(profile *config.Profile, img string, format string, offline bool) error {
+	api, err := NewAPIClient()
+	if err != nil {
+		return errors.Wrap(err, "error creating api client")
+	}
+	defer api.Close()
+
+	r, cr, err := primaryControlPlaneRuntime(api, profile)
+	if err != nil {
+		return err
+	}
+	bin, err := ensureTrivy(cr, offline)
+	if err != nil {
+		return err
+	}
+	if offline && !trivyDBCached(cr) {
+		return errors.Wrap(ErrScanOffline, "the vulnerability database has not been fetched into the node yet")
+	}
+	report, err := scanImage(cr, r, bin, img, offline)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		fmt.Println(strings.TrimSpace(string(report)))
+		return nil
+	}
+	var sr ScanReport
+	if err := json.Unmarshal(report, &sr); err != nil {
+		return errors.Wrap(err, "parsing trivy report")
+	}
+	renderScanTable(sr)
+	return nil
+}
+
+// renderScanTable renders pretty table for the vulnerabilities found in an image
+func renderScanTable(sr ScanReport) {
+	data := [][]string{}
+	for _, res := range sr.Results {
+		for _, v := range res.Vulnerabilities {
+			data = append(data, []string{res.Target, v.PkgName, v.VulnerabilityID, v.Severity, v.InstalledVersion, v.FixedVersion, v.Title})
+		}
+	}
+	if len(data) == 0 {
+		out.Step(style.Check, "No vulnerabilities found")
+		return
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Target", "Library", "Vulnerability", "Severity", "Installed Version", "Fixed Version", "Title"})
+	table.SetAutoFormatHeaders(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("|")
+	table.AppendBulk(data)
+	table.Render()
+}
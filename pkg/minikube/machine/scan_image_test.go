@@ -0,0 +1,50 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+func TestTrivyArgs(t *testing.T) {
+	containerd := cruntime.ImageScanTarget{Source: "containerd", Env: []string{"CONTAINERD_ADDRESS=/run/containerd/containerd.sock", "CONTAINERD_NAMESPACE=k8s.io"}}
+	tests := []struct {
+		name    string
+		target  cruntime.ImageScanTarget
+		archive string
+		offline bool
+		want    string
+	}{
+		{"archive", cruntime.ImageScanTarget{Archive: true}, "/var/lib/minikube/trivy/scan-1.tar", false,
+			"sudo /trivy image --quiet --format json --cache-dir /var/lib/minikube/trivy/cache --input /var/lib/minikube/trivy/scan-1.tar"},
+		{"containerd", containerd, "", false,
+			"sudo env CONTAINERD_ADDRESS=/run/containerd/containerd.sock CONTAINERD_NAMESPACE=k8s.io /trivy image --quiet --format json --cache-dir /var/lib/minikube/trivy/cache --image-src containerd app:1"},
+		{"offline", containerd, "", true,
+			"sudo env CONTAINERD_ADDRESS=/run/containerd/containerd.sock CONTAINERD_NAMESPACE=k8s.io /trivy image --quiet --format json --cache-dir /var/lib/minikube/trivy/cache --skip-db-update --offline-scan --image-src containerd app:1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := strings.Join(trivyArgs("/trivy", tc.target, "app:1", tc.archive, tc.offline), " ")
+			if got != tc.want {
+				t.Errorf("trivyArgs() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
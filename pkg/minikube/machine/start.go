@@ -114,13 +114,22 @@ func engineOptions(cfg config.ClusterConfig) *engine.Options {
 	o := engine.Options{
 		Env:              uniqueEnvs,
 		InsecureRegistry: append([]string{constants.DefaultServiceCIDR}, cfg.InsecureRegistry...),
-		RegistryMirror:   cfg.RegistryMirror,
+		RegistryMirror:   engineRegistryMirrors(cfg),
 		ArbitraryFlags:   cfg.DockerOpt,
 		InstallURL:       drivers.DefaultEngineInstallURL,
 	}
 	return &o
 }
 
+// engineRegistryMirrors returns the registry mirrors passed to dockerd as flags. With the registry cache, the runtime
+// writes all the mirrors to daemon.json instead, as dockerd refuses mirrors set both ways.
+func engineRegistryMirrors(cfg config.ClusterConfig) []string {
+	if cfg.RegistryCache {
+		return nil
+	}
+	return cfg.RegistryMirror
+}
+
 func createHost(api libmachine.API, cfg *config.ClusterConfig, n *config.Node) (*host.Host, error) {
 	klog.Infof("createHost starting for %q (driver=%q)", n.Name, cfg.Driver)
 	start := time.Now()
@@ -26,13 +26,46 @@ import (
 	"github.com/pkg/errors"
 	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/drivers/kic/oci"
+	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/out/register"
 	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/minikube/sysinit"
 	"k8s.io/minikube/pkg/util/retry"
 )
 
+// PrepareStop stops kubelet, then all the running containers of a node at once within timeout, or each in turn within it
+// if inTurn is set, and its container runtime, so that stopping the node does not wait for the runtime to stop each container.
+// The runtimes of bare metal and ssh hosts are left running, as they are not the node's own.
+func PrepareStop(api libmachine.API, cc config.ClusterConfig, n config.Node, timeout time.Duration, inTurn bool) error {
+	machineName := config.MachineName(cc, n)
+	if driver.BareMetal(cc.Driver) || driver.IsSSH(cc.Driver) {
+		return nil
+	}
+	st, err := Status(api, machineName)
+	if err != nil || st != state.Running.String() {
+		klog.Infof("skipping the bulk stop of the containers of %s, status %q: %v", machineName, st, err)
+		return nil
+	}
+	cr, runner, err := nodeRuntime(api, cc, n)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	// kubelet would restart the containers otherwise
+	if err := sysinit.New(runner).Stop("kubelet"); err != nil {
+		return errors.Wrap(err, "stop kubelet")
+	}
+	stopped, err := cr.PrepareStop(timeout, inTurn)
+	if err != nil {
+		return errors.Wrapf(err, "%s prepare stop", cr.Name())
+	}
+	klog.Infof("duration metric: stopped %d containers of %s in bulk within %s", stopped, machineName, time.Since(start))
+	return nil
+}
+
 // StopHost stops the host VM, saving state to disk.
 func StopHost(api libmachine.API, machineName string) error {
 	register.Reg.SetStep(register.Stopping)
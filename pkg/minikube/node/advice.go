@@ -25,6 +25,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/bootstrapper/kubeadm"
 	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/reason"
 	"k8s.io/minikube/pkg/minikube/style"
 )
@@ -65,6 +66,15 @@ func ExitIfFatal(err error) {
 		}, "The kubeadm binary within the Docker container is not executable")
 	}
 
+	if ppErr, ok := err.(*kubeadm.PrePullError); ok {
+		exit.Message(reason.Kind{
+			ID:       "GUEST_IMAGE_PREPULL",
+			ExitCode: reason.ExGuestError,
+			Style:    style.Pulling,
+			Advice:   "Check that the node can reach the image registry, or load the image with 'minikube image load', then try again",
+		}, "Unable to pull {{.image}} before reconfiguring the control plane, which was left running: {{.error}}", out.V{"image": ppErr.Image, "error": ppErr.Err})
+	}
+
 	if rtErr, ok := err.(*cruntime.ErrServiceVersion); ok {
 		exit.Message(reason.Kind{
 			ID:       "PROVIDER_INVALID_VERSION",
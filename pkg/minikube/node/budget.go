@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// preloadShare, enableShare and restartShare are the shares of the --wait-timeout extracting the preload, enabling the runtime
+	// and restarting it once for both may spend at most
+	preloadShare = 0.5
+	enableShare  = 0.25
+	restartShare = 0.25
+)
+
+// budget accounts for the time the runtime phases of a start spent, so that together they do not exceed its --wait-timeout.
+// The waits of Kubernetes get what the phases left of it.
+type budget struct {
+	total time.Duration
+
+	mu    sync.Mutex
+	spent time.Duration
+}
+
+// newBudget returns the budget of a start, which is unlimited if total is not positive
+func newBudget(total time.Duration) *budget {
+	return &budget{total: total}
+}
+
+// remaining returns the time the phases have left, at least a second so that a phase still runs its first command
+func (b *budget) remaining() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	left := b.total - b.spent
+	if left < time.Second {
+		left = time.Second
+	}
+	return left
+}
+
+// waits returns how long the waits of Kubernetes may take once the phases spent their part of the budget,
+// total if it is not positive, as the waits then keep their own meaning of it
+func (b *budget) waits() time.Duration {
+	if b.total <= 0 {
+		return b.total
+	}
+	return b.remaining()
+}
+
+// phase returns the context of a phase allowed share of the budget, which is also cut to what the phases left of it.
+// Cancelling it charges the budget with the time the phase ran.
+func (b *budget) phase(ctx context.Context, share float64) (context.Context, context.CancelFunc) {
+	if b.total <= 0 {
+		return context.WithCancel(ctx)
+	}
+	d := time.Duration(float64(b.total) * share)
+	if left := b.remaining(); left < d {
+		d = left
+	}
+	pctx, cancel := context.WithTimeout(ctx, d)
+	start := time.Now()
+	var once sync.Once
+	return pctx, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			b.spent += time.Since(start)
+			b.mu.Unlock()
+		})
+		cancel()
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBudgetWaits(t *testing.T) {
+	b := newBudget(6 * time.Minute)
+	_, cancel := b.phase(context.Background(), preloadShare)
+	b.mu.Lock()
+	b.spent = 2 * time.Minute
+	b.mu.Unlock()
+	cancel()
+	if got := b.waits(); got < 4*time.Minute-time.Second || got > 4*time.Minute {
+		t.Errorf("waits() = %s after the phases spent 2m of 6m, want 4m", got)
+	}
+	if got := newBudget(0).waits(); got != 0 {
+		t.Errorf("waits() of an unlimited budget = %s, want 0", got)
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"k8s.io/minikube/pkg/drivers/kic"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/download"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/exit"
@@ -63,6 +64,11 @@ func beginCacheKubernetesImages(g *errgroup.Group, imageRepository string, k8sVe
 		klog.Warningf("Error downloading preloaded artifacts will continue without preload: %v", err)
 	}
 
+	if download.LocalPreloadExists(k8sVersion, cRuntime, imageRepository) {
+		klog.Infof("Found local preload for %s on %s, skipping caching images", k8sVersion, cRuntime)
+		return
+	}
+
 	if !viper.GetBool(cacheImages) {
 		return
 	}
@@ -221,7 +227,7 @@ func waitCacheRequiredImages(g *errgroup.Group) {
 // saveImagesToTarFromConfig saves images to tar in cache which specified in config file.
 // currently only used by download-only option
 func saveImagesToTarFromConfig() error {
-	images, err := imagesInConfigFile()
+	images, err := ImagesInConfigFile()
 	if err != nil {
 		return err
 	}
@@ -234,17 +240,18 @@ func saveImagesToTarFromConfig() error {
 // CacheAndLoadImagesInConfig loads the images currently in the config file
 // called by 'start' and 'cache reload' commands.
 func CacheAndLoadImagesInConfig(profiles []*config.Profile) error {
-	images, err := imagesInConfigFile()
+	images, err := ImagesInConfigFile()
 	if err != nil {
 		return errors.Wrap(err, "images")
 	}
 	if len(images) == 0 {
 		return nil
 	}
-	return machine.CacheAndLoadImages(images, profiles, false)
+	return machine.CacheAndLoadImages(images, profiles, false, true, cruntime.ImageOutputBuffered)
 }
 
-func imagesInConfigFile() ([]string, error) {
+// ImagesInConfigFile returns the images added with 'minikube cache add', which are cached and loaded on start
+func ImagesInConfigFile() ([]string, error) {
 	configFile, err := config.ReadConfig(localpath.ConfigFile())
 	if err != nil {
 		return nil, errors.Wrap(err, "read")
@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+// migrateImageRepository tags the Kubernetes images of an existing node, named after the image repository of the previous start,
+// with their names in the current one, so that switching mirrors does not pull them all again.
+// The images are tagged all at once, and none of them is when any fails, after which the missing ones will be pulled.
+func migrateImageRepository(cr cruntime.Manager, cc config.ClusterConfig, previous string) {
+	k8s := cc.KubernetesConfig
+	if previous == k8s.ImageRepository {
+		return
+	}
+	klog.Infof("image repository changed from %q to %q", previous, k8s.ImageRepository)
+	from, err := images.Kubeadm(previous, k8s.KubernetesVersion)
+	if err != nil {
+		klog.Warningf("unable to get the Kubernetes images of %q: %v", previous, err)
+		return
+	}
+	to, err := images.Kubeadm(k8s.ImageRepository, k8s.KubernetesVersion)
+	if err != nil {
+		klog.Warningf("unable to get the Kubernetes images of %q: %v", k8s.ImageRepository, err)
+		return
+	}
+	if len(from) != len(to) {
+		klog.Warningf("the Kubernetes images of %q and %q do not match: %v, %v", previous, k8s.ImageRepository, from, to)
+		return
+	}
+	exist, err := cr.ImagesExist(append(append([]string{}, from...), to...))
+	if err != nil {
+		klog.Warningf("unable to list the images to migrate: %v", err)
+		return
+	}
+	mapping := map[string]string{}
+	for i := range from {
+		if exist[from[i]] && !exist[to[i]] {
+			mapping[from[i]] = to[i]
+		}
+	}
+	if len(mapping) == 0 {
+		klog.Infof("no image to migrate to %q", k8s.ImageRepository)
+		return
+	}
+	for source, target := range mapping {
+		klog.Infof("migrating %s to %s", source, target)
+	}
+	out.Step(style.Copying, "Retagging {{.count}} Kubernetes images for the new image repository {{.repository}} ...", out.V{"count": len(mapping), "repository": k8s.ImageRepository})
+	if err := cr.RetagImages(mapping); err != nil {
+		out.WarningT("Unable to retag the Kubernetes images for the new image repository, they will be pulled: {{.error}}", out.V{"error": err})
+	}
+}
@@ -32,8 +32,9 @@ import (
 	"k8s.io/minikube/pkg/minikube/machine"
 )
 
-// Add adds a new node config to an existing cluster.
-func Add(cc *config.ClusterConfig, n config.Node, delOnFail bool) error {
+// Add adds a new node config to an existing cluster. previousImageRepository is the
+// image repository the cluster pulled from before this start, which the node migrates from.
+func Add(cc *config.ClusterConfig, n config.Node, delOnFail bool, previousImageRepository string) error {
 	profiles, err := config.ListValidProfiles()
 	if err != nil {
 		return err
@@ -52,6 +53,13 @@ func Add(cc *config.ClusterConfig, n config.Node, delOnFail bool) error {
 		}
 	}
 
+	// existing nodes being re-added still carry the runtime they were running before
+	previousRuntime := n.ContainerRuntime
+	if u, _, err := Retrieve(*cc, n.Name); err == nil {
+		// while the cluster config holds the runtime they have to run from now on
+		n.ContainerRuntime = u.ContainerRuntime
+	}
+
 	if err := config.SaveNode(cc, &n); err != nil {
 		return errors.Wrap(err, "save node")
 	}
@@ -61,13 +69,15 @@ func Add(cc *config.ClusterConfig, n config.Node, delOnFail bool) error {
 		return err
 	}
 	s := Starter{
-		Runner:         r,
-		PreExists:      p,
-		MachineAPI:     m,
-		Host:           h,
-		Cfg:            cc,
-		Node:           &n,
-		ExistingAddons: nil,
+		Runner:                  r,
+		PreExists:               p,
+		MachineAPI:              m,
+		Host:                    h,
+		Cfg:                     cc,
+		Node:                    &n,
+		ExistingAddons:          nil,
+		PreviousRuntime:         previousRuntime,
+		PreviousImageRepository: previousImageRepository,
 	}
 
 	_, err = Start(s, false)
@@ -101,7 +111,7 @@ func drainNode(cc config.ClusterConfig, name string) (*config.Node, error) {
 	// kubectl drain with extra options to prevent ending up stuck in the process
 	// ref: https://kubernetes.io/docs/reference/generated/kubectl/kubectl-commands#drain
 	kubectl := kapi.KubectlBinaryPath(cc.KubernetesConfig.KubernetesVersion)
-	cmd := exec.Command("sudo", "KUBECONFIG=/var/lib/minikube/kubeconfig", kubectl, "drain", m,
+	cmd := exec.Command("sudo", kubectl, "--kubeconfig=/var/lib/minikube/kubeconfig", "drain", m,
 		"--force", "--grace-period=1", "--skip-wait-for-delete-timeout=1", "--disable-eviction", "--ignore-daemonsets", "--delete-emptydir-data", "--delete-local-data")
 	if _, err := runner.RunCmd(cmd); err != nil {
 		klog.Warningf("unable to drain node %q: %v", name, err)
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/download"
+	"k8s.io/minikube/pkg/minikube/driver"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+// NoAutoRepairFlag is the name of the flag disabling the repair of the images of existing nodes
+const NoAutoRepairFlag = "no-auto-repair"
+
+// verifyImages restores the Kubernetes images of an existing KIC node from the preload cached on the host, if they are missing.
+// Restarting the docker of the host restarts the node, whose runtime may come back without its images on some filesystems,
+// and the kubelet would then pull them all from the network.
+func verifyImages(ctx context.Context, cr cruntime.Manager, cc config.ClusterConfig) {
+	if viper.GetBool(NoAutoRepairFlag) || !driver.IsKIC(cc.Driver) {
+		return
+	}
+	k8s := cc.KubernetesConfig
+	imgs, err := images.Kubeadm(k8s.ImageRepository, k8s.KubernetesVersion)
+	if err != nil {
+		klog.Warningf("unable to get the Kubernetes images to verify: %v", err)
+		return
+	}
+	repaired, err := repairImages(ctx, cr, cc, imgs, preloadCached(k8s))
+	switch {
+	case err != nil:
+		out.WarningT("Unable to restore the Kubernetes images from the preload, they will be pulled: {{.error}}", out.V{"error": err})
+	case repaired:
+		out.Step(style.Check, "Restored the Kubernetes images from the preload")
+	}
+}
+
+// repairImages runs the preload of the runtime again if some of imgs are missing and a preload is cached,
+// returning whether it did and restored them
+func repairImages(ctx context.Context, cr cruntime.Manager, cc config.ClusterConfig, imgs []string, cached bool) (bool, error) {
+	if cr.ImagesPreloaded(imgs) {
+		return false, nil
+	}
+	if !cached {
+		klog.Infof("%s is missing Kubernetes images, but no preload is cached to restore them from", cr.Name())
+		return false, nil
+	}
+	out.Step(style.Workaround, "The {{.runtime}} of the node lost Kubernetes images, restoring them from the preload ...", out.V{"runtime": cr.Name()})
+	var err error
+	if cm, ok := cr.(cruntime.ContextManager); ok {
+		err = cm.PreloadContext(ctx, cc)
+	} else {
+		err = cr.Preload(cc)
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "preload")
+	}
+	if !cr.ImagesPreloaded(imgs) {
+		return false, errors.New("images are still missing after the preload")
+	}
+	return true, nil
+}
+
+// preloadCached returns whether a preload holding the Kubernetes images is cached on the host
+func preloadCached(k8s config.KubernetesConfig) bool {
+	if download.LocalPreloadExists(k8s.KubernetesVersion, k8s.ContainerRuntime, k8s.ImageRepository) {
+		return true
+	}
+	// the official preloads only hold images of the default repository
+	if k8s.ImageRepository != "" {
+		return false
+	}
+	_, err := os.Stat(download.TarballPath(k8s.KubernetesVersion, k8s.ContainerRuntime))
+	return err == nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime/cruntimetest"
+)
+
+func TestRepairImages(t *testing.T) {
+	imgs := []string{"registry.k8s.io/kube-apiserver:v1.25.3", "registry.k8s.io/pause:3.8"}
+	tests := []struct {
+		description  string
+		present      []string
+		preloaded    []string
+		cached       bool
+		preloadErr   error
+		wantRepaired bool
+		wantErr      bool
+		wantPreload  bool
+	}{
+		{description: "all present", present: imgs},
+		{description: "missing without a cached preload", present: imgs[1:]},
+		{description: "restored", present: imgs[1:], preloaded: imgs[:1], cached: true, wantRepaired: true, wantPreload: true},
+		{description: "preload failed", present: imgs[1:], cached: true, preloadErr: errors.New("tarball corrupt"), wantErr: true, wantPreload: true},
+		{description: "still missing", present: imgs[1:], cached: true, wantErr: true, wantPreload: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			cr := cruntimetest.NewFakeRuntime()
+			for _, img := range tc.present {
+				cr.AddImage(img)
+			}
+			cr.PreloadedImages = tc.preloaded
+			cr.Fail("Preload", tc.preloadErr)
+			cc := config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.25.3"}}
+
+			repaired, err := repairImages(context.Background(), cr, cc, imgs, tc.cached)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("repairImages() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if repaired != tc.wantRepaired {
+				t.Errorf("repairImages() = %t, want %t", repaired, tc.wantRepaired)
+			}
+			if preloaded := len(cr.Called("Preload")) > 0; preloaded != tc.wantPreload {
+				t.Errorf("Preload called: %t, want %t", preloaded, tc.wantPreload)
+			}
+		})
+	}
+}
+
+func TestVerifyImagesSkipped(t *testing.T) {
+	tests := []struct {
+		description string
+		driver      string
+		noRepair    bool
+	}{
+		{description: "vm driver", driver: "kvm2"},
+		{description: "--no-auto-repair", driver: "docker", noRepair: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			viper.Set(NoAutoRepairFlag, tc.noRepair)
+			defer viper.Set(NoAutoRepairFlag, false)
+			cr := cruntimetest.NewFakeRuntime()
+			cc := config.ClusterConfig{Driver: tc.driver, KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.25.3"}}
+
+			verifyImages(context.Background(), cr, cc)
+			if len(cr.Calls) != 0 {
+				t.Errorf("verifyImages() called the runtime: %v", cr.Calls)
+			}
+		})
+	}
+}
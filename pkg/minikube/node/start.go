@@ -17,6 +17,7 @@ limitations under the License.
 package node
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
@@ -50,6 +51,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/download"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/kubeconfig"
@@ -62,7 +64,9 @@ import (
 	"k8s.io/minikube/pkg/minikube/proxy"
 	"k8s.io/minikube/pkg/minikube/reason"
 	"k8s.io/minikube/pkg/minikube/registry"
+	"k8s.io/minikube/pkg/minikube/registrycache"
 	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/minikube/sysinit"
 	"k8s.io/minikube/pkg/minikube/vmpath"
 	"k8s.io/minikube/pkg/network"
 	"k8s.io/minikube/pkg/util"
@@ -72,6 +76,15 @@ import (
 
 const waitTimeout = "wait-timeout"
 
+// PreloadSpaceFactorFlag is the name of the flag scaling the space the preload is estimated to need in the node
+const PreloadSpaceFactorFlag = "preload-space-factor"
+
+// SELinuxRelabelFlag is the name of the flag labeling the directories of the static pods for containers on SELinux enforcing hosts
+const SELinuxRelabelFlag = "selinux-relabel"
+
+// CRIDockerdSocketGroupFlag is the name of the flag giving the docker group access to the socket of cri-dockerd
+const CRIDockerdSocketGroupFlag = "cri-dockerd-socket-group"
+
 var (
 	kicGroup   errgroup.Group
 	cacheGroup errgroup.Group
@@ -87,35 +100,62 @@ type Starter struct {
 	Cfg            *config.ClusterConfig
 	Node           *config.Node
 	ExistingAddons map[string]bool
+	// PreviousRuntime is the container runtime the node was running before, if it already existed
+	PreviousRuntime string
+	// PreviousImageRepository is the image repository of the cluster before this start, if it already existed
+	PreviousImageRepository string
+	// Context stops the long running commands in the node, such as extracting the preload, when done
+	Context context.Context
+}
+
+// ctx returns the context of the start, which is never done if unset
+func (s Starter) ctx() context.Context {
+	if s.Context == nil {
+		return context.Background()
+	}
+	return s.Context
 }
 
 // Start spins up a guest and starts the Kubernetes node.
 func Start(starter Starter, apiServer bool) (*kubeconfig.Settings, error) {
 	var wg sync.WaitGroup
+	// the phases of the runtime share the --wait-timeout
+	b := newBudget(viper.GetDuration(waitTimeout))
+	// the node may run another container runtime than the cluster default
+	nodeCfg := config.ForNode(*starter.Cfg, *starter.Node)
 	stopk8s, err := handleNoKubernetes(starter)
 	if err != nil {
 		return nil, err
 	}
 	if stopk8s {
 		nv := semver.Version{Major: 0, Minor: 0, Patch: 0}
-		cr := configureRuntimes(starter.Runner, *starter.Cfg, nv)
+		cr := configureRuntimes(starter.ctx(), b, starter.Runner, nodeCfg, nv, starter.PreviousRuntime)
 
 		showNoK8sVersionInfo(cr)
 
 		configureMounts(&wg, *starter.Cfg)
+		recordRuntime(starter, cr)
 		return nil, config.Write(viper.GetString(config.ProfileName), starter.Cfg)
 	}
 
 	// wait for preloaded tarball to finish downloading before configuring runtimes
 	waitCacheRequiredImages(&cacheGroup)
 
+	connectRegistryCache(*starter.Cfg)
+
 	sv, err := util.ParseKubernetesVersion(starter.Node.KubernetesVersion)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to parse Kubernetes version")
 	}
 
 	// configure the runtime (docker, containerd, crio)
-	cr := configureRuntimes(starter.Runner, *starter.Cfg, sv)
+	cr := configureRuntimes(starter.ctx(), b, starter.Runner, nodeCfg, sv, starter.PreviousRuntime)
+	if fellBack(nodeCfg, cr) {
+		if err := recordRuntimeFallback(starter); err != nil {
+			return nil, errors.Wrap(err, "Failed to save the containerd runtime")
+		}
+		nodeCfg = config.ForNode(*starter.Cfg, *starter.Node)
+	}
 
 	// check if installed runtime is compatible with current minikube code
 	if err = cruntime.CheckCompatibility(cr); err != nil {
@@ -124,6 +164,11 @@ func Start(starter Starter, apiServer bool) (*kubeconfig.Settings, error) {
 
 	showVersionInfo(starter.Node.KubernetesVersion, cr)
 
+	if starter.PreExists {
+		migrateImageRepository(cr, nodeCfg, starter.PreviousImageRepository)
+		verifyImages(starter.ctx(), cr, nodeCfg)
+	}
+
 	// Add "host.minikube.internal" DNS alias (intentionally non-fatal)
 	hostIP, err := cluster.HostIP(starter.Host, starter.Cfg.Name)
 	if err != nil {
@@ -145,11 +190,11 @@ func Start(starter Starter, apiServer bool) (*kubeconfig.Settings, error) {
 			return nil, errors.Wrap(err, "Failed to get bootstrapper")
 		}
 
-		if err = bs.SetupCerts(*starter.Cfg, *starter.Node); err != nil {
+		if err = bs.SetupCerts(nodeCfg, *starter.Node); err != nil {
 			return nil, errors.Wrap(err, "setting up certs")
 		}
 
-		if err := bs.UpdateNode(*starter.Cfg, *starter.Node, cr); err != nil {
+		if err := bs.UpdateNode(nodeCfg, *starter.Node, cr); err != nil {
 			return nil, errors.Wrap(err, "update node")
 		}
 	}
@@ -174,10 +219,17 @@ func Start(starter Starter, apiServer bool) (*kubeconfig.Settings, error) {
 		if viper.GetBool("force") {
 			addons.Force = true
 		}
+		addons.Preloading = hasPreload(*starter.Cfg)
 		wg.Add(1)
 		go addons.Start(&wg, starter.Cfg, starter.ExistingAddons, addonList)
 	}
 
+	// pull the images of the addons to enable later and of the enabled ones, one at a time in the background,
+	// which neither delays nor fails the start
+	if names := addons.PrefetchNames(starter.Cfg); len(names) > 0 {
+		addons.PrefetchImages(starter.ctx(), starter.Cfg, cr, names)
+	}
+
 	// discourage use of the virtualbox driver
 	if starter.Cfg.Driver == driver.VirtualBox && viper.GetBool(config.WantVirtualBoxDriverWarning) {
 		warnVirtualBox()
@@ -196,7 +248,7 @@ func Start(starter Starter, apiServer bool) (*kubeconfig.Settings, error) {
 			return nil, errors.Wrap(err, "getting control plane bootstrapper")
 		}
 
-		if err := joinCluster(starter, cpBs, bs); err != nil {
+		if err := joinCluster(starter, nodeCfg, cpBs, bs); err != nil {
 			return nil, errors.Wrap(err, "joining cp")
 		}
 
@@ -209,25 +261,67 @@ func Start(starter Starter, apiServer bool) (*kubeconfig.Settings, error) {
 			return nil, errors.Wrap(err, "cni apply")
 		}
 	}
-	klog.Infof("Will wait %s for node %+v", viper.GetDuration(waitTimeout), starter.Node)
-	if err := bs.WaitForNode(*starter.Cfg, *starter.Node, viper.GetDuration(waitTimeout)); err != nil {
-		return nil, errors.Wrapf(err, "wait %s for node", viper.GetDuration(waitTimeout))
+	// the runtime phases already spent part of the --wait-timeout
+	timeout := b.waits()
+	klog.Infof("Will wait %s for node %+v", timeout, starter.Node)
+	if err := bs.WaitForNode(*starter.Cfg, *starter.Node, timeout); err != nil {
+		return nil, errors.Wrapf(err, "wait %s for node", timeout)
 	}
 
 	klog.Infof("waiting for startup goroutines ...")
 	wg.Wait()
 
+	if apiServer {
+		generateLocalPreload(nodeCfg, starter)
+	}
+
+	recordRuntime(starter, cr)
+
 	// Write enabled addons to the config before completion
 	return kcs, config.Write(viper.GetString(config.ProfileName), starter.Cfg)
 }
 
+// connectRegistryCache attaches the registry cache of the host to the network of the kic nodes, as it only listens on the loopback of the host.
+// The nodes pull from Docker Hub directly when the cache is unreachable, so failing to connect it is not fatal.
+func connectRegistryCache(cc config.ClusterConfig) {
+	if !cc.RegistryCache || !driver.IsKIC(cc.Driver) {
+		return
+	}
+	network := cc.Network
+	if network == "" {
+		network = cc.Name
+	}
+	if err := registrycache.Connect(cc.Driver, network); err != nil {
+		klog.Warningf("unable to connect the registry cache to the %s network: %v", network, err)
+	}
+}
+
+// generateLocalPreload saves the Kubernetes images of the node as a local preload, for the next starts to load when no official preload applies
+func generateLocalPreload(cc config.ClusterConfig, starter Starter) {
+	k8s := cc.KubernetesConfig
+	if !download.LocalPreloadEnabled() || driver.BareMetal(cc.Driver) || k8s.ContainerRuntime != constants.Docker {
+		return
+	}
+	if k8s.ImageRepository == "" && download.PreloadExists(k8s.KubernetesVersion, k8s.ContainerRuntime, cc.Driver) {
+		return
+	}
+	if download.LocalPreloadExists(k8s.KubernetesVersion, k8s.ContainerRuntime, k8s.ImageRepository) {
+		return
+	}
+	out.Step(style.Caching, "Saving Kubernetes {{.version}} images as a local preload ...", out.V{"version": k8s.KubernetesVersion})
+	if err := machine.GenerateLocalPreload(&cc, starter.Runner); err != nil {
+		klog.Warningf("unable to generate local preload: %v", err)
+	}
+}
+
 // handleNoKubernetes handles starting minikube without Kubernetes.
 func handleNoKubernetes(starter Starter) (bool, error) {
 	// Do not bootstrap cluster if --no-kubernetes.
 	if starter.Node.KubernetesVersion == constants.NoKubernetesVersion {
 		// Stop existing Kubernetes node if applicable.
 		if starter.StopK8s {
-			cr, err := cruntime.New(cruntime.Config{Type: starter.Cfg.KubernetesConfig.ContainerRuntime, Runner: starter.Runner, Socket: starter.Cfg.KubernetesConfig.CRISocket})
+			cc := config.ForNode(*starter.Cfg, *starter.Node)
+			cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Driver: cc.Driver, Runner: starter.Runner, Socket: cc.KubernetesConfig.CRISocket})
 			if err != nil {
 				return false, err
 			}
@@ -281,14 +375,15 @@ func handleAPIServer(starter Starter, cr cruntime.Manager, hostIP net.IP) (*kube
 }
 
 // joinCluster adds new or prepares and then adds existing node to the cluster.
-func joinCluster(starter Starter, cpBs bootstrapper.Bootstrapper, bs bootstrapper.Bootstrapper) error {
+// nodeCfg is the cluster config as seen by the node, so that it joins with the CRI socket of its own runtime.
+func joinCluster(starter Starter, nodeCfg config.ClusterConfig, cpBs bootstrapper.Bootstrapper, bs bootstrapper.Bootstrapper) error {
 	start := time.Now()
 	klog.Infof("JoinCluster: %+v", starter.Cfg)
 	defer func() {
 		klog.Infof("JoinCluster complete in %s", time.Since(start))
 	}()
 
-	joinCmd, err := cpBs.GenerateToken(*starter.Cfg)
+	joinCmd, err := cpBs.GenerateToken(nodeCfg)
 	if err != nil {
 		return fmt.Errorf("error generating join token: %w", err)
 	}
@@ -305,7 +400,7 @@ func joinCluster(starter Starter, cpBs bootstrapper.Bootstrapper, bs bootstrappe
 
 	join := func() error {
 		klog.Infof("trying to join worker node %q to cluster: %+v", starter.Node.Name, starter.Node)
-		if err := bs.JoinCluster(*starter.Cfg, *starter.Node, joinCmd); err != nil {
+		if err := bs.JoinCluster(nodeCfg, *starter.Node, joinCmd); err != nil {
 			klog.Errorf("worker node failed to join cluster, will retry: %v", err)
 
 			// reset worker node to revert any changes made by previous kubeadm init/join
@@ -344,12 +439,13 @@ func Provision(cc *config.ClusterConfig, n *config.Node, apiServer bool, delOnFa
 
 	}
 
+	download.SetPreloadSource(download.PreloadSource{BaseURL: cc.PreloadSource, Checksum: cc.PreloadChecksum, ChecksumVersion: cc.PreloadChecksumVersion, Fallback: cc.PreloadSourceFallback})
 	if driver.IsKIC(cc.Driver) {
 		beginDownloadKicBaseImage(&kicGroup, cc, viper.GetBool("download-only"))
 	}
 
 	if !driver.BareMetal(cc.Driver) {
-		beginCacheKubernetesImages(&cacheGroup, cc.KubernetesConfig.ImageRepository, n.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver)
+		beginCacheKubernetesImages(&cacheGroup, cc.KubernetesConfig.ImageRepository, n.KubernetesVersion, config.NodeRuntime(*cc, *n), cc.Driver)
 	}
 
 	// Abstraction leakage alert: startHost requires the config to be saved, to satistfy pkg/provision/buildroot.
@@ -358,14 +454,14 @@ func Provision(cc *config.ClusterConfig, n *config.Node, apiServer bool, delOnFa
 		return nil, false, nil, nil, errors.Wrap(err, "Failed to save config")
 	}
 
-	handleDownloadOnly(&cacheGroup, &kicGroup, n.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver)
+	handleDownloadOnly(&cacheGroup, &kicGroup, n.KubernetesVersion, config.NodeRuntime(*cc, *n), cc.Driver)
 	waitDownloadKicBaseImage(&kicGroup)
 
 	return startMachine(cc, n, delOnFail)
 }
 
-// ConfigureRuntimes does what needs to happen to get a runtime going.
-func configureRuntimes(runner cruntime.CommandRunner, cc config.ClusterConfig, kv semver.Version) cruntime.Manager {
+// RuntimeConfig returns the configuration of the runtime of the profile, as start enables it
+func RuntimeConfig(cc config.ClusterConfig, runner cruntime.CommandRunner, kv semver.Version) cruntime.Config {
 	co := cruntime.Config{
 		Type:              cc.KubernetesConfig.ContainerRuntime,
 		Socket:            cc.KubernetesConfig.CRISocket,
@@ -373,11 +469,70 @@ func configureRuntimes(runner cruntime.CommandRunner, cc config.ClusterConfig, k
 		ImageRepository:   cc.KubernetesConfig.ImageRepository,
 		KubernetesVersion: kv,
 		InsecureRegistry:  cc.InsecureRegistry,
+		// Restarting the runtime would stop the user's other containers on the host
+		AdoptRunning:     driver.BareMetal(cc.Driver) || driver.IsSSH(cc.Driver),
+		ForceRestart:     viper.GetBool("force"),
+		CNIConfigs:       cni.ConfFiles(cc),
+		DockerOnDemand:   cc.KubernetesConfig.DockerOnDemand,
+		DockerFeatures:   cc.DockerFeatures,
+		RegistryMirrors:  registrycache.Mirrors(cc.RegistryCache, cc.Driver, cc.RegistryMirror),
+		DockerBridgeCIDR: cc.DockerBridgeCIDR,
+		DockerMTU:        cc.DockerMTU,
+		PodCIDR:          cni.PodCIDR(cc),
+		GPUs:             cc.GPUs,
+	}
+	// cri-dockerd runs the plugins of the CNI, and is reconfigured when the CNI of the cluster changes
+	cs := cni.RuntimeSettings(cc)
+	co.CNI = &cs
+	co.DockerIsolatedBuilder = cc.KubernetesConfig.IsolatedBuilder
+	co.BareMetal = driver.BareMetal(cc.Driver)
+	co.Driver = cc.Driver
+	co.SELinuxRelabel = viper.GetBool(SELinuxRelabelFlag)
+	co.DockerGroupUser = nodeUser(cc)
+	co.CRIDockerdSocketGroup = cc.KubernetesConfig.CRISocketGroup
+	// the preload, the network plugin and the daemon settings each need a restart of docker, which is done once after Enable
+	co.DeferRestarts = true
+	return co
+}
+
+// nodeUser returns the user which minikube ssh logs in as, none on bare metal and ssh hosts, the groups of which are left to their owners
+func nodeUser(cc config.ClusterConfig) string {
+	if driver.BareMetal(cc.Driver) || driver.IsSSH(cc.Driver) {
+		return ""
 	}
+	return "docker"
+}
+
+// ConfigureRuntimes does what needs to happen to get a runtime going.
+func configureRuntimes(ctx context.Context, b *budget, runner cruntime.CommandRunner, cc config.ClusterConfig, kv semver.Version, previousRuntime string) cruntime.Manager {
+	co := RuntimeConfig(cc, runner, kv)
+	// Switching runtimes on an existing cluster leaves the network state of the previous one behind
+	co.CleanupNetwork = cruntime.Switched(previousRuntime, cc.KubernetesConfig.ContainerRuntime)
+	co.Offline = cruntime.Offline(cc.AssumeOffline, runner, kubernetesRepo(cc.KubernetesConfig.ImageRepository, cc.KubernetesConfig.KubernetesVersion))
+	co.PreloadSpaceFactor = viper.GetFloat64(PreloadSpaceFactorFlag)
+	co.ImageOutput = cruntime.SelectImageOutput(out.JSON, out.IsSilent(), out.IsTerminal(os.Stdout))
+	// the preload may restart docker with a new daemon.json, which keeps the cgroup manager docker is enabled with
+	force := forceSystemd(runner)
+	co.ForceSystemd = force
+	co.FallbackToContainerd = cc.KubernetesConfig.RuntimeFallback || promptRuntimeFallback(runner, cc, kv)
 	cr, err := cruntime.New(co)
 	if err != nil {
 		exit.Error(reason.InternalRuntime, "Failed runtime", err)
 	}
+	if fellBack(cc, cr) {
+		out.Step(style.Notice, "The image lacks the cri-dockerd required by Kubernetes {{.version}} with docker, using the containerd runtime instead", out.V{"version": kv})
+		cc.KubernetesConfig.ContainerRuntime = constants.Containerd
+		cc.KubernetesConfig.CRISocket = ""
+		if c, ok := cr.(*cruntime.Containerd); ok {
+			c.CleanupNetwork = cruntime.Switched(previousRuntime, constants.Containerd)
+		}
+		// the preload which was downloaded for docker does not work with containerd
+		beginCacheKubernetesImages(&cacheGroup, cc.KubernetesConfig.ImageRepository, cc.KubernetesConfig.KubernetesVersion, constants.Containerd, cc.Driver)
+		waitCacheRequiredImages(&cacheGroup)
+	}
+	if co.Offline && !driver.BareMetal(cc.Driver) {
+		checkOfflineImages(cr, cc)
+	}
 
 	disableOthers := true
 	if driver.BareMetal(cc.Driver) {
@@ -385,10 +540,27 @@ func configureRuntimes(runner cruntime.CommandRunner, cc config.ClusterConfig, k
 	}
 
 	// Preload is overly invasive for bare metal, and caching is not meaningful.
-	// KIC handles preload elsewhere.
-	if driver.IsVM(cc.Driver) {
-		if err := cr.Preload(cc); err != nil {
-			switch err.(type) {
+	// KIC handles official preloads elsewhere.
+	k8s := cc.KubernetesConfig
+	if driver.IsVM(cc.Driver) || driver.IsKIC(cc.Driver) && download.LocalPreloadExists(k8s.KubernetesVersion, k8s.ContainerRuntime, k8s.ImageRepository) {
+		if pm, ok := cr.(cruntime.PhaseManager); ok {
+			pctx, cancel := b.phase(ctx, preloadShare)
+			err = pm.PreloadContext(pctx, cc)
+			cancel()
+		} else {
+			err = cr.Preload(cc)
+		}
+		if ctx.Err() != nil {
+			exit.Error(reason.Interrupted, "Interrupted while preloading images", err)
+		}
+		if err != nil {
+			switch err := err.(type) {
+			case *cruntime.ErrOffline:
+				exitOffline(err)
+			case *cruntime.ErrPreloadSpace:
+				exitPreloadSpace(err, cc.Driver)
+			case *cruntime.ErrPhaseTimeout:
+				exitPhaseTimeout(err)
 			case *cruntime.ErrISOFeature:
 				out.ErrT(style.Tip, "Existing disk is missing new features ({{.error}}). To upgrade, run 'minikube delete'", out.V{"error": err})
 			default:
@@ -408,10 +580,36 @@ func configureRuntimes(runner cruntime.CommandRunner, cc config.ClusterConfig, k
 	}
 
 	inUserNamespace := strings.Contains(cc.KubernetesConfig.FeatureGates, "KubeletInUserNamespace=true")
-	err = cr.Enable(disableOthers, forceSystemd(), inUserNamespace)
+	if pm, ok := cr.(cruntime.PhaseManager); ok {
+		ectx, cancel := b.phase(ctx, enableShare)
+		err = pm.EnableContext(ectx, disableOthers, force, inUserNamespace)
+		cancel()
+	} else {
+		err = cr.Enable(disableOthers, force, inUserNamespace)
+	}
+	if perr, ok := err.(*cruntime.ErrPhaseTimeout); ok {
+		exitPhaseTimeout(perr)
+	}
+	if serr, ok := err.(*cruntime.ErrSELinux); ok {
+		exitSELinux(serr)
+	}
 	if err != nil {
 		exit.Error(reason.RuntimeEnable, "Failed to enable container runtime", err)
 	}
+	if pm, ok := cr.(cruntime.PhaseManager); ok {
+		rctx, cancel := b.phase(ctx, restartShare)
+		err = pm.ApplyPendingRestartContext(rctx)
+		cancel()
+	} else {
+		err = cr.ApplyPendingRestart()
+	}
+	if perr, ok := err.(*cruntime.ErrPhaseTimeout); ok {
+		exitPhaseTimeout(perr)
+	}
+	if err != nil {
+		exit.Error(reason.RuntimeEnable, "Failed to restart container runtime", err)
+	}
+	logRuntimeDrift(cr, cc, force)
 
 	// Wait for the CRI to be "live", before returning it
 	err = waitForCRISocket(runner, cr.SocketPath(), 60, 1)
@@ -424,11 +622,119 @@ func configureRuntimes(runner cruntime.CommandRunner, cc config.ClusterConfig, k
 	if err != nil {
 		exit.Error(reason.RuntimeEnable, "Failed to start container runtime", err)
 	}
+
+	// The runtime reports its cgroup driver only once running
+	if err := alignCgroupDrivers(runner, cr, force, inUserNamespace); err != nil {
+		exit.Error(reason.RuntimeEnable, "Failed to align the cgroup drivers of the container runtime and kubelet", err)
+	}
 	return cr
 }
 
-func forceSystemd() bool {
-	return viper.GetBool("force-systemd") || os.Getenv(constants.MinikubeForceSystemdEnv) == "true"
+// alignCgroupDrivers switches the container runtime to systemd if it is forced but the runtime still uses another driver,
+// as a mismatch with kubelet leaves the node NotReady. The kubelet configuration left by a previous start is switched
+// to the driver of the runtime by the bootstrapper, which reports it, once the runtime is enabled.
+func alignCgroupDrivers(runner cruntime.CommandRunner, cr cruntime.Manager, force bool, inUserNamespace bool) error {
+	runtimeDriver, err := cr.CGroupDriver()
+	if err != nil {
+		klog.Warningf("unable to get the cgroup driver of %s, not checking it: %v", cr.Name(), err)
+		return nil
+	}
+	kubeletCfg := ""
+	if rr, err := runner.RunCmd(command.Sudo("cat", bsutil.KubeletConfigFile)); err == nil {
+		kubeletCfg = rr.Stdout.String()
+	}
+	kubeletDriver := bsutil.KubeletCgroupDriver(kubeletCfg)
+	fix := bsutil.FixCgroupDrivers(runtimeDriver, kubeletDriver, force)
+	klog.Infof("cgroup drivers: %s=%q kubelet=%q, fix: %+v", cr.Name(), runtimeDriver, kubeletDriver, fix)
+
+	if fix.Runtime {
+		out.Step(style.Option, "Switching {{.runtime}} from the {{.from}} to the {{.to}} cgroup driver", out.V{"runtime": cr.Name(), "from": runtimeDriver, "to": fix.Driver})
+		if err := cr.Enable(false, true, inUserNamespace); err != nil {
+			return errors.Wrap(err, "forcing systemd")
+		}
+		if err := cr.ApplyPendingRestart(); err != nil {
+			return errors.Wrap(err, "restarting runtime")
+		}
+		if err := waitForCRIVersion(runner, cr.SocketPath(), 60, 10); err != nil {
+			return errors.Wrap(err, "restarting runtime")
+		}
+	}
+	return nil
+}
+
+// promptRuntimeFallback asks whether to use containerd, when the image lacks the cri-dockerd required by docker
+func promptRuntimeFallback(runner cruntime.CommandRunner, cc config.ClusterConfig, kv semver.Version) bool {
+	if cc.KubernetesConfig.ContainerRuntime != constants.Docker || kv.LT(semver.MustParse("1.24.0-alpha.0")) {
+		return false
+	}
+	if !viper.GetBool("interactive") || !out.IsTerminal(os.Stdin) || cruntime.CRIDockerdInstalled(runner) {
+		return false
+	}
+	out.WarningT("The image lacks the cri-dockerd required by Kubernetes {{.version}} with docker", out.V{"version": kv})
+	return cmdcfg.AskForYesNoConfirmation("Use the containerd runtime instead?", []string{"yes", "y"}, []string{"no", "n"})
+}
+
+// fellBack returns whether cruntime.New replaced the docker runtime of the node with containerd
+func fellBack(cc config.ClusterConfig, cr cruntime.Manager) bool {
+	return cc.KubernetesConfig.ContainerRuntime == constants.Docker && cr.Name() == "containerd"
+}
+
+// recordRuntimeFallback saves that the node runs containerd instead of docker, so that it is used from now on
+func recordRuntimeFallback(starter Starter) error {
+	if starter.Cfg.KubernetesConfig.ContainerRuntime == constants.Docker {
+		starter.Cfg.KubernetesConfig.ContainerRuntime = constants.Containerd
+		starter.Cfg.KubernetesConfig.CRISocket = ""
+		starter.Cfg.KubernetesConfig.RuntimeFallbackFrom = constants.Docker
+	}
+	starter.Node.ContainerRuntime = constants.Containerd
+	return config.SaveNode(starter.Cfg, starter.Node)
+}
+
+// recordRuntime stores the name, socket and version of the runtime of the node in the cluster config, to be saved with the profile
+func recordRuntime(starter Starter, cr cruntime.Manager) {
+	version, err := cr.Version()
+	if err != nil {
+		klog.Warningf("unable to get the version of %s: %v", cr.Name(), err)
+	}
+	starter.Node.Runtime = &config.RuntimeInfo{
+		Name:        cr.Name(),
+		Socket:      cr.SocketPath(),
+		Version:     version,
+		LastUpdated: time.Now(),
+	}
+	for i := range starter.Cfg.Nodes {
+		if starter.Cfg.Nodes[i].Name == starter.Node.Name {
+			starter.Cfg.Nodes[i].Runtime = starter.Node.Runtime
+		}
+	}
+}
+
+// forceSystemd returns whether the container runtime has to use systemd as cgroup manager.
+// Unless --force-systemd was set, systemd is chosen on hosts using both cgroup v2 and systemd, like kubelet.
+func forceSystemd(runner cruntime.CommandRunner) bool {
+	if os.Getenv(constants.MinikubeForceSystemdEnv) == "true" {
+		klog.Infof("forcing systemd as cgroup manager: %s=true", constants.MinikubeForceSystemdEnv)
+		return true
+	}
+	var requested *bool
+	if viper.IsSet("force-systemd") {
+		v := viper.GetBool("force-systemd")
+		requested = &v
+	}
+	version := 0
+	initSystem := ""
+	if requested == nil {
+		v, err := cruntime.DetectCgroupVersion(runner)
+		if err != nil {
+			klog.Warningf("unable to detect cgroup version, not forcing systemd: %v", err)
+			return false
+		}
+		version = v
+		initSystem = sysinit.New(runner).Name()
+	}
+	force, why := cruntime.ChooseForceSystemd(requested, version, initSystem)
+	klog.Infof("force-systemd=%t: %s", force, why)
+	return force
 }
 
 func pathExists(runner cruntime.CommandRunner, path string) (bool, error) {
@@ -444,7 +750,7 @@ func pathExists(runner cruntime.CommandRunner, path string) (bool, error) {
 
 func waitForCRISocket(runner cruntime.CommandRunner, socket string, wait int, interval int) error {
 
-	if socket == "" || socket == "/var/run/dockershim.sock" {
+	if socket == "" || cruntime.SocketFile(socket) == cruntime.InternalDockerCRISocket {
 		return nil
 	}
 
@@ -465,7 +771,7 @@ func waitForCRISocket(runner cruntime.CommandRunner, socket string, wait int, in
 
 func waitForCRIVersion(runner cruntime.CommandRunner, socket string, wait int, interval int) error {
 
-	if socket == "" || socket == "/var/run/dockershim.sock" {
+	if socket == "" || cruntime.SocketFile(socket) == cruntime.InternalDockerCRISocket {
 		return nil
 	}
 
@@ -727,6 +1033,78 @@ func trySSH(h *host.Host, ip string) error {
 	return err
 }
 
+// kubernetesRepo returns the repository the images of Kubernetes are pulled from
+func kubernetesRepo(imageRepository string, kubernetesVersion string) string {
+	if imageRepository == "" {
+		v, _ := util.ParseKubernetesVersion(kubernetesVersion)
+		imageRepository = images.DefaultKubernetesRepo(v)
+	}
+	return imageRepository
+}
+
+// hasPreload returns whether the images of Kubernetes come from a preload, official or local
+func hasPreload(cc config.ClusterConfig) bool {
+	k8s := cc.KubernetesConfig
+	return download.PreloadExists(k8s.KubernetesVersion, k8s.ContainerRuntime, cc.Driver) || download.LocalPreloadExists(k8s.KubernetesVersion, k8s.ContainerRuntime, k8s.ImageRepository)
+}
+
+// checkOfflineImages exits if the node is offline and misses images of Kubernetes, which no preload provides
+func checkOfflineImages(cr cruntime.Manager, cc config.ClusterConfig) {
+	k8s := cc.KubernetesConfig
+	if hasPreload(cc) {
+		return
+	}
+	imgs, err := images.Kubeadm(k8s.ImageRepository, k8s.KubernetesVersion)
+	if err != nil {
+		klog.Warningf("unable to list the images of Kubernetes %s: %v", k8s.KubernetesVersion, err)
+		return
+	}
+	exist, err := cr.ImagesExist(imgs)
+	if err != nil {
+		klog.Warningf("unable to check the images of Kubernetes %s: %v", k8s.KubernetesVersion, err)
+		return
+	}
+	missing := []string{}
+	for _, img := range imgs {
+		if !exist[img] {
+			missing = append(missing, img)
+		}
+	}
+	if len(missing) > 0 {
+		exitOffline(cruntime.NewErrOffline(append([]string{download.TarballName(k8s.KubernetesVersion, k8s.ContainerRuntime)}, missing...)...))
+	}
+}
+
+// exitOffline exits with guidance on getting the images an offline node misses
+func exitOffline(err *cruntime.ErrOffline) {
+	out.ErrT(style.Tip, "On a machine with internet access, run 'minikube start --download-only' with the same flags, or 'minikube cache add' for other images, then copy its {{.cache}} directory to this machine", out.V{"cache": localpath.MakeMiniPath("cache")})
+	exit.Message(reason.InetOffline, "The node is offline and misses: {{.missing}}", out.V{"missing": strings.Join(err.Missing, ", ")})
+}
+
+// exitPhaseTimeout exits with the phase of the runtime which did not complete within its share of the --wait-timeout
+func exitPhaseTimeout(err *cruntime.ErrPhaseTimeout) {
+	exit.Message(reason.RuntimeTimeout, "The container runtime did not complete {{.phase}} within its share of --wait-timeout: {{.error}}", out.V{"phase": err.Phase, "error": err})
+}
+
+// exitSELinux exits with the commands labeling the directories of the static pods on an SELinux enforcing host
+func exitSELinux(err *cruntime.ErrSELinux) {
+	out.ErrT(style.Tip, "Label the directories of the static pods for containers by running:\n\n  {{.commands}}\n\nor let minikube do it with 'minikube start --selinux-relabel'", out.V{"commands": strings.Join(err.Commands(), "\n  ")})
+	exit.Message(reason.RuntimeSELinux, "Unable to enable docker: {{.error}}", out.V{"error": err})
+}
+
+// exitPreloadSpace exits with guidance on growing the storage of a node which can not hold the preload
+func exitPreloadSpace(err *cruntime.ErrPreloadSpace, driverName string) {
+	if len(err.Options) > 0 {
+		exit.Message(reason.GuestPreloadMount, "Unable to extract the preload: {{.error}}", out.V{"error": err})
+	}
+	if driver.IsKIC(driverName) {
+		out.ErrT(style.Tip, "The {{.driver}} node shares the storage of the {{.driver}} daemon: free some space with '{{.driver}} system prune', or grow the disk image size of Docker Desktop or the storage of the {{.driver}} VM", out.V{"driver": driverName})
+	} else {
+		out.ErrT(style.Tip, "Recreate the cluster with a larger disk: 'minikube delete', then 'minikube start --disk-size=<size>'")
+	}
+	exit.Message(reason.GuestPreloadNoSpace, "Unable to extract the preload: {{.error}}", out.V{"error": err})
+}
+
 // tryRegistry tries to connect to the image repository
 func tryRegistry(r command.Runner, driverName string, imageRepository string, kubernetesVersion string, ip string) {
 	// 2 second timeout. For best results, call tryRegistry in a non-blocking manner.
@@ -737,10 +1115,7 @@ func tryRegistry(r command.Runner, driverName string, imageRepository string, ku
 		opts = append([]string{"-x", proxy}, opts...)
 	}
 
-	if imageRepository == "" {
-		v, _ := util.ParseKubernetesVersion(kubernetesVersion)
-		imageRepository = images.DefaultKubernetesRepo(v)
-	}
+	imageRepository = kubernetesRepo(imageRepository, kubernetesVersion)
 
 	opts = append(opts, fmt.Sprintf("https://%s/", imageRepository))
 	if rr, err := r.RunCmd(exec.Command("curl", opts...)); err != nil {
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/driver"
+	"k8s.io/minikube/pkg/util"
+)
+
+// RuntimeDrift compares the configuration of a runtime in its node with the one start sets for the profile, forcing systemd or not
+func RuntimeDrift(cr cruntime.Manager, cc config.ClusterConfig, forceSystemd bool) ([]cruntime.Drift, error) {
+	v, ok := cr.(cruntime.DriftVerifier)
+	if !ok {
+		return nil, fmt.Errorf("verifying the configuration of %s is not supported", cr.Name())
+	}
+	expected, err := v.ExpectedState(cruntime.StateOptions{ForceSystemd: forceSystemd, NetworkPlugin: cc.KubernetesConfig.NetworkPlugin})
+	if err != nil {
+		return nil, errors.Wrap(err, "rendering the expected configuration")
+	}
+	live, err := v.LiveState(expected)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the configuration of the node")
+	}
+	return cruntime.CompareRuntimeState(expected, live), nil
+}
+
+// runtimeOf returns the runtime of the profile in the node of runner, as start configures it
+func runtimeOf(cc config.ClusterConfig, runner cruntime.CommandRunner) (cruntime.Manager, semver.Version, error) {
+	kv, err := util.ParseKubernetesVersion(cc.KubernetesConfig.KubernetesVersion)
+	if err != nil {
+		return nil, kv, errors.Wrap(err, "parse kubernetes version")
+	}
+	cr, err := cruntime.New(RuntimeConfig(cc, runner, kv))
+	return cr, kv, err
+}
+
+// VerifyRuntime compares the configuration of the runtime in the node of runner with the one start sets for the profile
+func VerifyRuntime(cc config.ClusterConfig, runner cruntime.CommandRunner) ([]cruntime.Drift, error) {
+	cr, _, err := runtimeOf(cc, runner)
+	if err != nil {
+		return nil, err
+	}
+	return RuntimeDrift(cr, cc, startedWithSystemd(runner))
+}
+
+// startedWithSystemd returns whether start made the runtime in the node of runner use systemd as cgroup manager.
+// The kubelet uses the cgroup driver of the runtime, while --force-systemd is not saved in the profile.
+// If the kubelet is not configured yet, it is worked out as start does.
+func startedWithSystemd(runner cruntime.CommandRunner) bool {
+	if rr, err := runner.RunCmd(command.Sudo("cat", bsutil.KubeletConfigFile)); err == nil {
+		if driver := bsutil.KubeletCgroupDriver(rr.Stdout.String()); driver != "" {
+			return driver == "systemd"
+		}
+	}
+	return forceSystemd(runner)
+}
+
+// FixRuntime sets the configuration of the runtime in the node of runner again, as start does, restarting the runtime if it changed
+func FixRuntime(cc config.ClusterConfig, runner cruntime.CommandRunner) error {
+	cr, kv, err := runtimeOf(cc, runner)
+	if err != nil {
+		return err
+	}
+	if kv.GTE(semver.MustParse("1.24.0-alpha.2")) {
+		if err := cruntime.ConfigureNetworkPlugin(cr, runner, cc.KubernetesConfig.NetworkPlugin); err != nil {
+			return errors.Wrap(err, "configuring the network plugin")
+		}
+	}
+	inUserNamespace := strings.Contains(cc.KubernetesConfig.FeatureGates, "KubeletInUserNamespace=true")
+	if err := cr.Enable(!driver.BareMetal(cc.Driver), startedWithSystemd(runner), inUserNamespace); err != nil {
+		return errors.Wrap(err, "enabling the runtime")
+	}
+	return cr.ApplyPendingRestart()
+}
+
+// logRuntimeDrift logs how the configuration of the runtime differs from the one start just set, which other tools or users changed since,
+// when the logs are verbose, so that it shows in the output attached to bug reports
+func logRuntimeDrift(cr cruntime.Manager, cc config.ClusterConfig, forceSystemd bool) {
+	if !klog.V(1).Enabled() {
+		return
+	}
+	if _, ok := cr.(cruntime.DriftVerifier); !ok {
+		return
+	}
+	drifts, err := RuntimeDrift(cr, cc, forceSystemd)
+	if err != nil {
+		klog.Warningf("unable to verify the configuration of %s: %v", cr.Name(), err)
+		return
+	}
+	for _, d := range drifts {
+		klog.Warningf("%s configuration drift: %s is %q, expected %q", cr.Name(), d.Setting, d.Actual, d.Expected)
+	}
+	if len(drifts) == 0 {
+		klog.Infof("the configuration of %s is as expected", cr.Name())
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+func TestStartedWithSystemd(t *testing.T) {
+	tests := []struct {
+		description string
+		kubelet     string
+		want        bool
+	}{
+		{description: "systemd", kubelet: "kind: KubeletConfiguration\ncgroupDriver: systemd\n", want: true},
+		{description: "cgroupfs", kubelet: "kind: KubeletConfiguration\ncgroupDriver: cgroupfs\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := command.NewFakeCommandRunner()
+			runner.SetCommandToOutput(map[string]string{"sudo cat /var/lib/kubelet/config.yaml": tc.kubelet})
+			if got := startedWithSystemd(runner); got != tc.want {
+				t.Errorf("startedWithSystemd() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -291,6 +291,23 @@ func SetSilent(q bool) {
 	silent = q
 }
 
+// IsSilent returns whether output is disabled
+func IsSilent() bool {
+	return silent
+}
+
+// Streams returns the writers of the standard output and errors, for the output of commands shown as is
+func Streams() (io.Writer, io.Writer) {
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+	if outFile != nil {
+		stdout = outFile
+	}
+	if errFile != nil {
+		stderr = errFile
+	}
+	return stdout, stderr
+}
+
 // SetOutFile configures which writer standard output goes to.
 func SetOutFile(w fdWriter) {
 	klog.Infof("Setting OutFile to fd %d ...", w.Fd())
@@ -311,6 +328,11 @@ func SetErrFile(w fdWriter) {
 	useColor = wantsColor(w)
 }
 
+// WantsColor returns whether the user might want colorized output written to w.
+func WantsColor(w fdWriter) bool {
+	return wantsColor(w)
+}
+
 // wantsColor determines if the user might want colorized output.
 func wantsColor(w fdWriter) bool {
 	// First process the environment: we allow users to force colors on or off.
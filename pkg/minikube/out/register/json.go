@@ -16,6 +16,8 @@ limitations under the License.
 
 package register
 
+import "time"
+
 // PrintStep prints a Step type in JSON format
 func PrintStep(message string) {
 	s := NewStep(message)
@@ -46,6 +48,24 @@ func PrintDownloadProgress(artifact, progress string) {
 	printAsCloudEvent(s, s.data)
 }
 
+// PrintImageProgress prints an ImageProgress type in JSON format
+func PrintImageProgress(artifact, action, status string, elapsed time.Duration, additionalData ...map[string]string) {
+	s := NewImageProgress(artifact, action, status, elapsed, additionalData...)
+	printAsCloudEvent(s, s.data)
+}
+
+// PrintTiming prints a Timing type in JSON format
+func PrintTiming(phase string, elapsed time.Duration) {
+	s := NewTiming(phase, elapsed)
+	printAndRecordCloudEvent(s, s.data)
+}
+
+// RecordTiming records a Timing type in JSON format
+func RecordTiming(phase string, elapsed time.Duration) {
+	s := NewTiming(phase, elapsed)
+	recordCloudEvent(s, s.data)
+}
+
 // PrintError prints an Error type in JSON format
 func PrintError(err string) {
 	e := NewError(err)
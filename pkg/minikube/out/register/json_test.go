@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"k8s.io/minikube/pkg/minikube/tests"
 )
@@ -117,3 +118,24 @@ func TestWarning(t *testing.T) {
 
 	tests.CompareJSON(t, actual, []byte(expected))
 }
+
+func TestPrintImageProgress(t *testing.T) {
+	Reg.SetStep(InitialSetup)
+
+	expected := `{"data":{"action":"load","artifact":"/var/lib/minikube/images/busybox","currentstep":"0","elapsed":"1.500","size":"1024","status":"finished","totalsteps":"%v"},"datacontenttype":"application/json","id":"random-id","source":"https://minikube.sigs.k8s.io/","specversion":"1.0","type":"io.k8s.sigs.minikube.image.progress"}`
+	expected = fmt.Sprintf(expected, Reg.totalSteps())
+	expected += "\n"
+
+	buf := bytes.NewBuffer([]byte{})
+	SetOutputFile(buf)
+	defer func() { SetOutputFile(os.Stdout) }()
+
+	GetUUID = func() string {
+		return "random-id"
+	}
+
+	PrintImageProgress("/var/lib/minikube/images/busybox", ImageLoad, ImageFinished, 1500*time.Millisecond, map[string]string{"size": "1024"})
+	actual := buf.Bytes()
+
+	tests.CompareJSON(t, actual, []byte(expected))
+}
@@ -19,6 +19,7 @@ package register
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 // Log represents the different types of logs that can be output as JSON
@@ -86,6 +87,73 @@ func NewDownloadProgress(artifact, progress string) *DownloadProgress {
 	}}
 }
 
+// Image actions reported by ImageProgress
+const (
+	ImagePreloadCopy    = "preload-copy"
+	ImagePreloadExtract = "preload-extract"
+	ImageLoad           = "load"
+	ImagePull           = "pull"
+	ImageBuild          = "build"
+	ImageStoreExport    = "store-export"
+	ImageStoreImport    = "store-import"
+)
+
+// Image statuses reported by ImageProgress
+const (
+	ImageStarted    = "started"
+	ImageInProgress = "progress"
+	ImageFinished   = "finished"
+)
+
+// ImageProgress will be used to notify the user around the progress of image operations,
+// such as extracting the preload tarball or loading and pulling images
+type ImageProgress struct {
+	data map[string]string
+}
+
+// Type returns the cloud events compatible type of this struct
+func (s *ImageProgress) Type() string {
+	return "io.k8s.sigs.minikube.image.progress"
+}
+
+// NewImageProgress returns a new image progress type
+func NewImageProgress(artifact, action, status string, elapsed time.Duration, additionalData ...map[string]string) *ImageProgress {
+	s := &ImageProgress{data: map[string]string{
+		"totalsteps":  Reg.totalSteps(),
+		"currentstep": Reg.currentStep(),
+		"artifact":    artifact,
+		"action":      action,
+		"status":      status,
+		"elapsed":     fmt.Sprintf("%.3f", elapsed.Seconds()),
+	}}
+	for _, a := range additionalData {
+		for k, v := range a {
+			s.data[k] = v
+		}
+	}
+	return s
+}
+
+// Timing will be used to report the time spent in a phase, such as a step of enabling the container runtime
+type Timing struct {
+	data map[string]string
+}
+
+// Type returns the cloud events compatible type of this struct
+func (s *Timing) Type() string {
+	return "io.k8s.sigs.minikube.timing"
+}
+
+// NewTiming returns a new timing type
+func NewTiming(phase string, elapsed time.Duration) *Timing {
+	return &Timing{data: map[string]string{
+		"totalsteps":  Reg.totalSteps(),
+		"currentstep": Reg.currentStep(),
+		"phase":       phase,
+		"elapsed":     fmt.Sprintf("%.3f", elapsed.Seconds()),
+	}}
+}
+
 // Warning will be used to notify the user of warnings
 type Warning struct {
 	data map[string]string
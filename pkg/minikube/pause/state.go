@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pause
+
+import (
+	"encoding/json"
+	"os/exec"
+	"path"
+	"sort"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// stateFile records the pods and containers paused on a node, in its persistent directory so that it survives a restart
+var stateFile = path.Join(vmpath.GuestPersistentDir, "paused-pods.json")
+
+// Pod is a paused pod, recorded by UID as the IDs of its containers change when it restarts
+type Pod struct {
+	UID       string `json:"uid"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// State is the set of pods paused on a node
+type State struct {
+	Pods []Pod `json:"pods"`
+	// Containers are the IDs of the containers paused with runc on CRI runtimes, as crictl reports them as running.
+	// It is nil until containers were paused, so that an empty record is told apart from a missing one.
+	Containers []string `json:"containers"`
+}
+
+// Add records the pods as paused, once each
+func (s *State) Add(pods ...Pod) {
+	seen := map[string]bool{}
+	for _, p := range s.Pods {
+		seen[p.UID] = true
+	}
+	for _, p := range pods {
+		if !seen[p.UID] {
+			s.Pods = append(s.Pods, p)
+			seen[p.UID] = true
+		}
+	}
+}
+
+// Remove forgets the pods of the namespaces, or all of them if namespaces is nil, except those of the excluded namespaces
+func (s *State) Remove(namespaces []string, exclude []string) {
+	removed := map[string]bool{}
+	for _, ns := range namespaces {
+		removed[ns] = true
+	}
+	excluded := map[string]bool{}
+	for _, ns := range exclude {
+		excluded[ns] = true
+	}
+	kept := []Pod{}
+	for _, p := range s.Pods {
+		if excluded[p.Namespace] || (namespaces != nil && !removed[p.Namespace]) {
+			kept = append(kept, p)
+		}
+	}
+	s.Pods = kept
+}
+
+// SetContainersPaused records the containers as paused or not
+func (s *State) SetContainersPaused(ids []string, paused bool) {
+	recorded := s.PausedContainers()
+	for _, id := range ids {
+		if paused {
+			recorded[id] = true
+		} else {
+			delete(recorded, id)
+		}
+	}
+	s.Containers = []string{}
+	for id := range recorded {
+		s.Containers = append(s.Containers, id)
+	}
+	sort.Strings(s.Containers)
+}
+
+// PausedContainers returns the IDs of the containers recorded as paused
+func (s *State) PausedContainers() map[string]bool {
+	ids := map[string]bool{}
+	for _, id := range s.Containers {
+		ids[id] = true
+	}
+	return ids
+}
+
+// TracksContainers returns whether the paused containers are recorded, even if none are paused
+func (s *State) TracksContainers() bool {
+	return s != nil && s.Containers != nil
+}
+
+// Namespaces returns the sorted namespaces of the paused pods
+func (s *State) Namespaces() []string {
+	seen := map[string]bool{}
+	nss := []string{}
+	for _, p := range s.Pods {
+		if !seen[p.Namespace] {
+			nss = append(nss, p.Namespace)
+			seen[p.Namespace] = true
+		}
+	}
+	sort.Strings(nss)
+	return nss
+}
+
+// LoadState returns the pods recorded as paused on the node, or nil if none are
+func LoadState(r command.Runner) (*State, error) {
+	if _, err := r.RunCmd(exec.Command("sudo", "test", "-f", stateFile)); err != nil {
+		return nil, nil
+	}
+	rr, err := r.RunCmd(exec.Command("sudo", "cat", stateFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "read paused pods")
+	}
+	s := &State{}
+	if err := json.Unmarshal(rr.Stdout.Bytes(), s); err != nil {
+		return nil, errors.Wrapf(err, "parse %s", stateFile)
+	}
+	return s, nil
+}
+
+// SaveState records the pods and containers of s as paused on the node, removing the record if nothing was ever paused
+func SaveState(r command.Runner, s *State) error {
+	if s == nil || len(s.Pods) == 0 && s.Containers == nil {
+		if _, err := r.RunCmd(exec.Command("sudo", "rm", "-f", stateFile)); err != nil {
+			return errors.Wrap(err, "remove paused pods")
+		}
+		return nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "marshal paused pods")
+	}
+	if err := r.Copy(assets.NewMemoryAssetTarget(data, stateFile, "0644")); err != nil {
+		return errors.Wrap(err, "write paused pods")
+	}
+	return nil
+}
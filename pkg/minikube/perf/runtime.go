@@ -0,0 +1,303 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package perf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// RuntimeOperation is an operation of a container runtime timed by BenchmarkRuntime
+type RuntimeOperation string
+
+const (
+	// OperationPull pulls the image, after removing it
+	OperationPull RuntimeOperation = "pull"
+	// OperationLoad loads the image from an archive in the node, after removing it
+	OperationLoad RuntimeOperation = "load"
+	// OperationRun creates, starts and removes a container of the image running true, once it stopped
+	OperationRun RuntimeOperation = "run"
+)
+
+// overheadSamples is how many no-op commands are timed to estimate the round trip of the runner
+const overheadSamples = 5
+
+// benchmarkRoot is where the image archive of BenchmarkRuntime is copied within the node
+var benchmarkRoot = path.Join(vmpath.GuestPersistentDir, "benchmark")
+
+// RuntimeBenchmarkOptions are the options of BenchmarkRuntime
+type RuntimeBenchmarkOptions struct {
+	// Image is the image pulled, loaded and run, which must have the true command
+	Image string
+	// Archive is the image saved as a tarball on the host, which is loaded. The load is not timed if empty.
+	Archive string
+	// Iterations is how many times each operation is timed
+	Iterations int
+}
+
+// OperationStats are the statistics of the durations of an operation, from which the round trips of the runner were subtracted
+type OperationStats struct {
+	Operation RuntimeOperation
+	Samples   int
+	Mean      time.Duration
+	P95       time.Duration
+	Min       time.Duration
+	Max       time.Duration
+}
+
+// ms returns a duration in milliseconds, to the microsecond
+func ms(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
+
+// MarshalJSON writes the durations as milliseconds
+func (s OperationStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Operation RuntimeOperation `json:"operation"`
+		Samples   int              `json:"samples"`
+		Mean      float64          `json:"meanMs"`
+		P95       float64          `json:"p95Ms"`
+		Min       float64          `json:"minMs"`
+		Max       float64          `json:"maxMs"`
+	}{s.Operation, s.Samples, ms(s.Mean), ms(s.P95), ms(s.Min), ms(s.Max)})
+}
+
+// RuntimeReport is the result of BenchmarkRuntime
+type RuntimeReport struct {
+	Runtime    string `json:"runtime"`
+	Image      string `json:"image"`
+	Iterations int    `json:"iterations"`
+	// Overhead is the round trip of the runner, subtracted from the durations once per command
+	Overhead   time.Duration    `json:"-"`
+	Operations []OperationStats `json:"operations"`
+}
+
+// MarshalJSON writes the overhead as milliseconds
+func (r RuntimeReport) MarshalJSON() ([]byte, error) {
+	type report RuntimeReport
+	return json.Marshal(struct {
+		report
+		Overhead float64 `json:"overheadMs"`
+	}{report(r), ms(r.Overhead)})
+}
+
+// summarize returns the statistics of the samples of an operation, whose p95 is the nearest-rank percentile
+func summarize(op RuntimeOperation, samples []time.Duration) OperationStats {
+	s := OperationStats{Operation: op, Samples: len(samples)}
+	if len(samples) == 0 {
+		return s
+	}
+	sorted := append([]time.Duration{}, samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	s.Mean = total / time.Duration(len(sorted))
+	s.P95 = sorted[int(math.Ceil(0.95*float64(len(sorted))))-1]
+	s.Min = sorted[0]
+	s.Max = sorted[len(sorted)-1]
+	return s
+}
+
+// withoutOverhead subtracts the round trips of the runner from the duration of an operation
+func withoutOverhead(d time.Duration, roundTrips int64, overhead time.Duration) time.Duration {
+	d -= time.Duration(roundTrips) * overhead
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// countingRunner counts the commands run, which are round trips to the node
+type countingRunner struct {
+	cruntime.CommandRunner
+	commands int64
+}
+
+func (c *countingRunner) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
+	atomic.AddInt64(&c.commands, 1)
+	return c.CommandRunner.RunCmd(cmd)
+}
+
+func (c *countingRunner) RunCmdContext(ctx context.Context, cmd *exec.Cmd) (*command.RunResult, error) {
+	atomic.AddInt64(&c.commands, 1)
+	return c.CommandRunner.RunCmdContext(ctx, cmd)
+}
+
+// runtimeBenchmark times the operations of a runtime
+type runtimeBenchmark struct {
+	cr       *countingRunner
+	m        cruntime.Manager
+	overhead time.Duration
+}
+
+// time returns how long f took on the monotonic clock, without the round trips of the runner
+func (b *runtimeBenchmark) time(f func() error) (time.Duration, error) {
+	before := atomic.LoadInt64(&b.cr.commands)
+	start := time.Now()
+	err := f()
+	d := time.Since(start)
+	return withoutOverhead(d, atomic.LoadInt64(&b.cr.commands)-before, b.overhead), err
+}
+
+// estimateOverhead returns the mean duration of a no-op command, which is the round trip of the runner
+func (b *runtimeBenchmark) estimateOverhead(ctx context.Context) (time.Duration, error) {
+	var total time.Duration
+	for i := 0; i < overheadSamples; i++ {
+		start := time.Now()
+		if _, err := b.cr.RunCmdContext(ctx, exec.Command("true")); err != nil {
+			return 0, errors.Wrap(err, "timing a no-op command")
+		}
+		total += time.Since(start)
+	}
+	return total / overheadSamples, nil
+}
+
+// removeImage removes the image before it is pulled or loaded again
+func (b *runtimeBenchmark) removeImage(img string) error {
+	if err := b.m.RemoveImage(img); err != nil {
+		klog.Infof("removing %s: %v", img, err)
+	}
+	if b.m.ImageExists(img, "") {
+		return fmt.Errorf("unable to remove the image %s, which may be used by a container", img)
+	}
+	return nil
+}
+
+// pull pulls the image, stopping when ctx is done if the runtime supports it
+func (b *runtimeBenchmark) pull(ctx context.Context, img string) error {
+	if cm, ok := b.m.(cruntime.ContextManager); ok {
+		return cm.PullImageContext(ctx, img)
+	}
+	return b.m.PullImage(img)
+}
+
+// load loads the image archive in the node, stopping when ctx is done if the runtime supports it
+func (b *runtimeBenchmark) load(ctx context.Context, archive string) error {
+	var err error
+	if cm, ok := b.m.(cruntime.ContextManager); ok {
+		_, err = cm.LoadImageContext(ctx, archive)
+	} else {
+		_, err = b.m.LoadImage(archive)
+	}
+	return err
+}
+
+// BenchmarkRuntime times pulling, loading and running the image with the runtime of cfg, on the node of its runner
+func BenchmarkRuntime(ctx context.Context, cfg cruntime.Config, o RuntimeBenchmarkOptions) (*RuntimeReport, error) {
+	if o.Iterations < 1 {
+		return nil, fmt.Errorf("invalid number of iterations: %d", o.Iterations)
+	}
+	cr := &countingRunner{CommandRunner: cfg.Runner}
+	cfg.Runner = cr
+	m, err := cruntime.New(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "runtime")
+	}
+	b := &runtimeBenchmark{cr: cr, m: m}
+	if b.overhead, err = b.estimateOverhead(ctx); err != nil {
+		return nil, err
+	}
+	klog.Infof("round trip of the runner: %s", b.overhead)
+
+	samples := map[RuntimeOperation][]time.Duration{}
+	for i := 0; i < o.Iterations; i++ {
+		if err := b.removeImage(o.Image); err != nil {
+			return nil, err
+		}
+		d, err := b.time(func() error { return b.pull(ctx, o.Image) })
+		if err != nil {
+			return nil, errors.Wrapf(err, "pulling %s", o.Image)
+		}
+		samples[OperationPull] = append(samples[OperationPull], d)
+	}
+
+	if o.Archive != "" {
+		f, err := assets.NewFileAsset(o.Archive, benchmarkRoot, filepath.Base(o.Archive), "0644")
+		if err != nil {
+			return nil, errors.Wrapf(err, "opening %s", o.Archive)
+		}
+		defer func() {
+			if _, err := cr.RunCmd(command.Sudo("rm", "-rf", benchmarkRoot)); err != nil {
+				klog.Warningf("unable to remove %s: %v", benchmarkRoot, err)
+			}
+		}()
+		// the runners copy into existing directories only
+		if rr, err := cr.RunCmd(command.Sudo("mkdir", "-p", benchmarkRoot)); err != nil {
+			f.Close()
+			return nil, errors.Wrapf(err, "making %s: %s", benchmarkRoot, rr.Output())
+		}
+		err = cr.Copy(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "copying %s", o.Archive)
+		}
+		dst := path.Join(benchmarkRoot, filepath.Base(o.Archive))
+		for i := 0; i < o.Iterations; i++ {
+			if err := b.removeImage(o.Image); err != nil {
+				return nil, err
+			}
+			d, err := b.time(func() error { return b.load(ctx, dst) })
+			if err != nil {
+				return nil, errors.Wrapf(err, "loading %s", dst)
+			}
+			samples[OperationLoad] = append(samples[OperationLoad], d)
+		}
+	}
+
+	for i := 0; i < o.Iterations; i++ {
+		var code int
+		d, err := b.time(func() error {
+			var err error
+			code, err = m.RunDiagnosticContainer(ctx, o.Image, []string{"true"}, cruntime.DiagnosticOptions{Stdout: io.Discard, Stderr: io.Discard})
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "running %s", o.Image)
+		}
+		if code != 0 {
+			return nil, fmt.Errorf("running true in %s exited with %d: the image needs the true command", o.Image, code)
+		}
+		samples[OperationRun] = append(samples[OperationRun], d)
+	}
+
+	r := &RuntimeReport{Runtime: m.Name(), Image: o.Image, Iterations: o.Iterations, Overhead: b.overhead}
+	for _, op := range []RuntimeOperation{OperationPull, OperationLoad, OperationRun} {
+		if s, ok := samples[op]; ok {
+			r.Operations = append(r.Operations, summarize(op, s))
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package perf
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSummarize(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		description string
+		samples     []time.Duration
+		want        OperationStats
+	}{
+		{
+			description: "no samples",
+			want:        OperationStats{Operation: OperationPull},
+		},
+		{
+			description: "one sample",
+			samples:     []time.Duration{7 * ms},
+			want:        OperationStats{Operation: OperationPull, Samples: 1, Mean: 7 * ms, P95: 7 * ms, Min: 7 * ms, Max: 7 * ms},
+		},
+		{
+			description: "unsorted",
+			samples:     []time.Duration{30 * ms, 10 * ms, 20 * ms, 40 * ms},
+			want:        OperationStats{Operation: OperationPull, Samples: 4, Mean: 25 * ms, P95: 40 * ms, Min: 10 * ms, Max: 40 * ms},
+		},
+		{
+			description: "p95 of twenty is the nineteenth",
+			samples: []time.Duration{1 * ms, 2 * ms, 3 * ms, 4 * ms, 5 * ms, 6 * ms, 7 * ms, 8 * ms, 9 * ms, 10 * ms,
+				11 * ms, 12 * ms, 13 * ms, 14 * ms, 15 * ms, 16 * ms, 17 * ms, 18 * ms, 19 * ms, 100 * ms},
+			want: OperationStats{Operation: OperationPull, Samples: 20, Mean: 14500 * time.Microsecond, P95: 19 * ms, Min: 1 * ms, Max: 100 * ms},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			samples := append([]time.Duration(nil), tc.samples...)
+			got := summarize(OperationPull, tc.samples)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("summarize() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(samples, tc.samples); diff != "" {
+				t.Errorf("summarize() changed the samples (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithoutOverhead(t *testing.T) {
+	tests := []struct {
+		d          time.Duration
+		roundTrips int64
+		overhead   time.Duration
+		want       time.Duration
+	}{
+		{100 * time.Millisecond, 0, 5 * time.Millisecond, 100 * time.Millisecond},
+		{100 * time.Millisecond, 3, 5 * time.Millisecond, 85 * time.Millisecond},
+		{10 * time.Millisecond, 3, 5 * time.Millisecond, 0},
+	}
+	for _, tc := range tests {
+		if got := withoutOverhead(tc.d, tc.roundTrips, tc.overhead); got != tc.want {
+			t.Errorf("withoutOverhead(%s, %d, %s) = %s, want %s", tc.d, tc.roundTrips, tc.overhead, got, tc.want)
+		}
+	}
+}
+
+func TestRuntimeReportJSON(t *testing.T) {
+	r := RuntimeReport{
+		Runtime:    "containerd",
+		Image:      "busybox",
+		Iterations: 2,
+		Overhead:   1500 * time.Microsecond,
+		Operations: []OperationStats{summarize(OperationRun, []time.Duration{time.Second, 2 * time.Second})},
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"runtime":"containerd","image":"busybox","iterations":2,"operations":[{"operation":"run","samples":2,"meanMs":1500,"p95Ms":2000,"minMs":1000,"maxMs":2000}],"overheadMs":1.5}`
+	if string(b) != want {
+		t.Errorf("json = %s, want %s", b, want)
+	}
+}
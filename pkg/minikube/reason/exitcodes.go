@@ -135,6 +135,7 @@ const (
 
 	// Error codes specific to the container runtime
 	ExRuntimeError       = 90
+	ExRuntimeTimeout     = 92
 	ExRuntimeNotRunning  = 93
 	ExRuntimeNotFound    = 95
 	ExRuntimeUnavailable = 99
@@ -17,6 +17,7 @@ limitations under the License.
 package reason
 
 import (
+	"errors"
 	"regexp"
 
 	"k8s.io/klog/v2"
@@ -33,6 +34,11 @@ type match struct {
 	GOOS []string
 }
 
+// knownIssueError is implemented by errors which already know the issue they were caused by
+type knownIssueError interface {
+	KnownIssue() *Kind
+}
+
 func knownIssues() []match {
 	ps := []match{}
 	// This is intentionally in dependency order
@@ -57,6 +63,11 @@ func MatchKnownIssue(r Kind, err error, goos string) *Kind {
 		return nil
 	}
 
+	var ke knownIssueError
+	if errors.As(err, &ke) {
+		return ke.KnownIssue()
+	}
+
 	var genericMatch *Kind
 
 	for _, ki := range knownIssues() {
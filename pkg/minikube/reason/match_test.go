@@ -91,3 +91,27 @@ VBoxManage.exe: error: Details: code E_FAIL (0x80004005), component MachineWrap,
 		})
 	}
 }
+
+// knownErr is an error which already knows its issue, like the classified errors of the container runtimes
+type knownErr struct {
+	kind *Kind
+}
+
+func (e knownErr) Error() string {
+	return "Error response from daemon: Get https://k8s.gcr.io/v2/: net/http: request canceled while waiting for connection"
+}
+
+func (e knownErr) KnownIssue() *Kind {
+	return e.kind
+}
+
+func TestMatchKnownIssueOfError(t *testing.T) {
+	err := fmt.Errorf("pulling images: %w", knownErr{kind: &RuntimeNetworkTimeout})
+	got := MatchKnownIssue(Kind{}, err, "linux")
+	if got == nil || got.ID != RuntimeNetworkTimeout.ID {
+		t.Errorf("MatchKnownIssue(%v) = %+v, want %s rather than the issue matching its message", err, got, RuntimeNetworkTimeout.ID)
+	}
+	if got := MatchKnownIssue(Kind{NoMatch: true}, err, "linux"); got != nil {
+		t.Errorf("MatchKnownIssue(NoMatch) = %+v, want nil", got)
+	}
+}
@@ -102,6 +102,8 @@ var (
 	InternalDelConfig = Kind{ID: "MK_DEL_CONFIG", ExitCode: ExProgramError}
 	// minikube failed to generate script to activate minikube docker-env
 	InternalDockerScript = Kind{ID: "MK_DOCKER_SCRIPT", ExitCode: ExProgramError}
+	// minikube failed to create or remove the docker context of a profile
+	InternalDockerContext = Kind{ID: "MK_DOCKER_CONTEXT", ExitCode: ExProgramError}
 	// an error occurred when viper attempted to bind flags to configuration
 	InternalBindFlags = Kind{ID: "MK_BIND_FLAGS", ExitCode: ExProgramError}
 	// minkube was passed an invalid format string in the --format flag
@@ -252,6 +254,10 @@ var (
 	HostCurrentUser = Kind{ID: "HOST_CURRENT_USER", ExitCode: ExHostConfig}
 	// minikube failed to delete cached images from host
 	HostDelCache = Kind{ID: "HOST_DEL_CACHE", ExitCode: ExHostError}
+	// minikube failed to read the image operations of the starts from the audit log
+	HostImageStats = Kind{ID: "HOST_IMAGE_STATS", ExitCode: ExHostError}
+	// minikube failed to manage the registry cache on the host
+	HostRegistryCache = Kind{ID: "HOST_REGISTRY_CACHE", ExitCode: ExHostError}
 	// minikube failed to kill a mount process
 	HostKillMountProc = Kind{ID: "HOST_KILL_MOUNT_PROC", ExitCode: ExHostError}
 	// minikube failed to update host Kubernetes resources config
@@ -329,6 +335,18 @@ var (
 	GuestImagePush = Kind{ID: "GUEST_IMAGE_PUSH", ExitCode: ExGuestError}
 	// minikube failed to tag an image
 	GuestImageTag = Kind{ID: "GUEST_IMAGE_TAG", ExitCode: ExGuestError}
+	// minikube failed to scan an image for vulnerabilities
+	GuestImageScan = Kind{ID: "GUEST_IMAGE_SCAN", ExitCode: ExGuestError}
+	// the image failed the verification of its signatures
+	GuestImageVerify = Kind{ID: "GUEST_IMAGE_VERIFY", ExitCode: ExGuestError}
+	// minikube failed to checkpoint a container
+	GuestCheckpoint = Kind{ID: "GUEST_CHECKPOINT", ExitCode: ExGuestError}
+	// minikube failed to run a diagnostic container
+	GuestDiagnosticRun = Kind{ID: "GUEST_DIAGNOSTIC_RUN", ExitCode: ExGuestError}
+	// minikube failed to forward the ports of a container
+	GuestPortForward = Kind{ID: "GUEST_PORT_FORWARD", ExitCode: ExGuestError}
+	// minikube failed to benchmark the container runtime
+	GuestRuntimeBenchmark = Kind{ID: "GUEST_RUNTIME_BENCHMARK", ExitCode: ExGuestError}
 	// minikube failed to load host
 	GuestLoadHost = Kind{ID: "GUEST_LOAD_HOST", ExitCode: ExGuestError}
 	// minkube failed to create a mount
@@ -356,6 +374,14 @@ var (
 	GuestNodeStart = Kind{ID: "GUEST_NODE_START", ExitCode: ExGuestError}
 	// minikube failed to pause the cluster process
 	GuestPause = Kind{ID: "GUEST_PAUSE", ExitCode: ExGuestError}
+	// the filesystem of the node is too small to extract the preload
+	GuestPreloadNoSpace = Kind{ID: "GUEST_PRELOAD_NO_SPACE", ExitCode: ExInsufficientStorage, Style: style.UnmetRequirement,
+		Advice: "Grow the storage of the node, or pass a lower --preload-space-factor if the estimate is too conservative, or a negative one to skip the check",
+	}
+	// the filesystem of the node is mounted noexec or read-only, so that the runtime can not use the extracted preload
+	GuestPreloadMount = Kind{ID: "GUEST_PRELOAD_MOUNT", ExitCode: ExGuestConfig, Style: style.UnmetRequirement,
+		Advice: "Remount /var of the node without noexec, or rather use a driver or disk image whose /var is mounted exec and read-write",
+	}
 	// minikube failed to delete a machine profile directory
 	GuestProfileDeletion = Kind{ID: "GUEST_PROFILE_DELETION", ExitCode: ExGuestError}
 	// minikube failed while attempting to provision the guest
@@ -383,6 +409,8 @@ var (
 	IfMountIP = Kind{ID: "IF_MOUNT_IP", ExitCode: ExLocalNetworkError}
 	// minikube failed to parse or find port for mount
 	IfMountPort = Kind{ID: "IF_MOUNT_PORT", ExitCode: ExLocalNetworkError}
+	// a port of the host minikube was asked to listen on is in use
+	IfPortInUse = Kind{ID: "IF_PORT_IN_USE", ExitCode: ExLocalNetworkError}
 	// minikube failed to access an ssh client on the host machine
 	IfSSHClient = Kind{ID: "IF_SSH_CLIENT", ExitCode: ExLocalNetworkError}
 
@@ -402,11 +430,66 @@ var (
 	InetVersionUnavailable = Kind{ID: "INET_VERSION_UNAVAILABLE", ExitCode: ExInternetUnavailable}
 	// minikube received invalid empty data for latest release/version info from the server
 	InetVersionEmpty = Kind{ID: "INET_VERSION_EMPTY", ExitCode: ExInternetConfig}
+	// the node is offline and misses images which neither a preload nor the cache provide
+	InetOffline = Kind{ID: "INET_OFFLINE", ExitCode: ExInternetUnavailable}
 
 	// minikube failed to enable the current container runtime
 	RuntimeEnable = Kind{ID: "RUNTIME_ENABLE", ExitCode: ExRuntimeError}
 	// minikube failed to cache images for the current container runtime
 	RuntimeCache = Kind{ID: "RUNTIME_CACHE", ExitCode: ExRuntimeError}
+	// the container runtime is not running on the node
+	RuntimeNotRunning = Kind{ID: "RUNTIME_NOT_RUNNING", ExitCode: ExRuntimeNotRunning}
+	// minikube failed to list containers in the current container runtime
+	RuntimeListContainers = Kind{ID: "RUNTIME_LIST_CONTAINERS", ExitCode: ExRuntimeError}
+	// minikube failed to remove the stopped containers of the container runtime
+	RuntimeGarbageCollect = Kind{ID: "RUNTIME_GARBAGE_COLLECT", ExitCode: ExRuntimeError}
+	// minikube failed to repair the image references of the current container runtime
+	RuntimeRepairImages = Kind{ID: "RUNTIME_REPAIR_IMAGES", ExitCode: ExRuntimeError}
+	// minikube failed to compare the configuration of the current container runtime with the one of the profile
+	RuntimeVerify = Kind{ID: "RUNTIME_VERIFY", ExitCode: ExRuntimeError}
+	// minikube failed to read the events of the container runtime
+	RuntimeEvents = Kind{ID: "RUNTIME_EVENTS", ExitCode: ExRuntimeError}
+	// the container runtime ran out of disk space on the node
+	RuntimeNoSpace = Kind{ID: "RUNTIME_NO_SPACE", ExitCode: ExInsufficientStorage, Style: style.UnmetRequirement,
+		Advice: "Free some disk space on the node, e.g. by running 'minikube ssh -- docker system prune', or start a new cluster with a larger --disk-size",
+		URL:    "https://docs.docker.com/config/pruning/",
+	}
+	// the registry requires the container runtime to authenticate to pull the image
+	RuntimeAuthRequired = Kind{ID: "RUNTIME_AUTH_REQUIRED", ExitCode: ExRuntimeError,
+		Advice: "Log in to the registry with 'minikube ssh -- docker login <registry>', or enable the registry-creds addon",
+		URL:    "https://minikube.sigs.k8s.io/docs/handbook/registry/",
+	}
+	// the registry does not have the image
+	RuntimeImageNotFound = Kind{ID: "RUNTIME_IMAGE_NOT_FOUND", ExitCode: ExRuntimeError,
+		Advice: "Check the name and the tag of the image, and that it exists in the registry for the architecture of the node",
+	}
+	// the daemon of the container runtime is not running, so its CLI can not reach it
+	RuntimeDaemonNotRunning = Kind{ID: "RUNTIME_DAEMON_NOT_RUNNING", ExitCode: ExRuntimeNotRunning,
+		Advice: "Start the container runtime of the node, e.g. by running 'minikube ssh -- sudo systemctl start docker', or restart the cluster with 'minikube start'",
+	}
+	// the user may not use the socket of the container runtime
+	RuntimeSocketPermission = Kind{ID: "RUNTIME_SOCKET_PERMISSION", ExitCode: ExInsufficientPermission,
+		Advice: "Add your user to the docker group by running 'sudo usermod -aG docker $USER && newgrp docker'",
+		URL:    "https://docs.docker.com/engine/install/linux-postinstall/",
+	}
+	// the container runtime did not complete enabling or preloading within its share of the --wait-timeout
+	RuntimeTimeout = Kind{ID: "RUNTIME_TIMEOUT", ExitCode: ExRuntimeTimeout,
+		Advice: "Check the logs of the container runtime with 'minikube logs', or start with a longer --wait-timeout if the node is slow",
+	}
+	// SELinux enforces on the host of the none driver, and denies the static pods access to their directories
+	RuntimeSELinux = Kind{ID: "RUNTIME_SELINUX", ExitCode: ExRuntimeError, Style: style.UnmetRequirement,
+		Advice: "Label the directories of the static pods with 'semanage fcontext' and 'restorecon', as printed above, or start with --selinux-relabel to let minikube label them",
+		URL:    "https://minikube.sigs.k8s.io/docs/drivers/none/",
+	}
+	// the container runtime failed to validate the certificate of the registry, as the clock of the node is skewed
+	RuntimeClockSkew = Kind{ID: "RUNTIME_CLOCK_SKEW", ExitCode: ExRuntimeError,
+		Advice: "The clock of the node may be skewed after the host was suspended: resync it with 'minikube ssh -- sudo hwclock -s', or restart the cluster with 'minikube stop' and 'minikube start'",
+	}
+	// the container runtime timed out reaching the registry
+	RuntimeNetworkTimeout = Kind{ID: "RUNTIME_NETWORK_TIMEOUT", ExitCode: ExInternetTimeout,
+		Advice: "Check that the node can reach the registry, and pass your proxy settings to minikube start if you are behind a proxy",
+		URL:    proxyDoc,
+	}
 
 	// service check timed out while starting minikube dashboard
 	SvcCheckTimeout = Kind{ID: "SVC_CHECK_TIMEOUT", ExitCode: ExSvcTimeout}
@@ -429,6 +512,8 @@ var (
 	EnvMultiConflict = Kind{ID: "ENV_MULTINODE_CONFLICT", ExitCode: ExGuestConflict}
 	// the podman service was unavailable to the cluster
 	EnvPodmanUnavailable = Kind{ID: "ENV_PODMAN_UNAVAILABLE", ExitCode: ExRuntimeUnavailable}
+	// the socket activated docker service did not start
+	EnvDockerUnavailable = Kind{ID: "ENV_DOCKER_UNAVAILABLE", ExitCode: ExRuntimeUnavailable}
 
 	// user attempted to use an addon that is not supported
 	AddonUnsupported = Kind{ID: "SVC_ADDON_UNSUPPORTED", ExitCode: ExSvcUnsupported}
@@ -73,6 +73,11 @@ func configure(cc config.ClusterConfig, n config.Node) (interface{}, error) {
 		extraArgs = append(extraArgs, "-p", port)
 	}
 
+	if cc.GPUs != "" {
+		// the NVIDIA container toolkit of the host mounts the devices and libraries the runtime of the node needs
+		extraArgs = append(extraArgs, "--gpus", "all")
+	}
+
 	return kic.NewDriver(kic.Config{
 		ClusterName:       cc.Name,
 		MachineName:       config.MachineName(cc, n),
@@ -84,11 +89,12 @@ func configure(cc config.ClusterConfig, n config.Node) (interface{}, error) {
 		OCIBinary:         oci.Docker,
 		APIServerPort:     cc.Nodes[0].Port,
 		KubernetesVersion: cc.KubernetesConfig.KubernetesVersion,
-		ContainerRuntime:  cc.KubernetesConfig.ContainerRuntime,
+		ContainerRuntime:  config.NodeRuntime(cc, n),
 		ExtraArgs:         extraArgs,
 		Network:           cc.Network,
 		Subnet:            cc.Subnet,
 		ListenAddress:     cc.ListenAddress,
+		IsolatedBuilder:   cc.KubernetesConfig.IsolatedBuilder,
 	}), nil
 }
 
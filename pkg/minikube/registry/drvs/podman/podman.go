@@ -93,9 +93,10 @@ func configure(cc config.ClusterConfig, n config.Node) (interface{}, error) {
 		OCIBinary:         oci.Podman,
 		APIServerPort:     cc.Nodes[0].Port,
 		KubernetesVersion: cc.KubernetesConfig.KubernetesVersion,
-		ContainerRuntime:  cc.KubernetesConfig.ContainerRuntime,
+		ContainerRuntime:  config.NodeRuntime(cc, n),
 		ExtraArgs:         extraArgs,
 		ListenAddress:     cc.ListenAddress,
+		IsolatedBuilder:   cc.KubernetesConfig.IsolatedBuilder,
 		Subnet:            cc.Subnet,
 	}), nil
 }
@@ -51,7 +51,7 @@ func configure(cc config.ClusterConfig, n config.Node) (interface{}, error) {
 	d := ssh.NewDriver(ssh.Config{
 		MachineName:      config.MachineName(cc, n),
 		StorePath:        localpath.MiniPath(),
-		ContainerRuntime: cc.KubernetesConfig.ContainerRuntime,
+		ContainerRuntime: config.NodeRuntime(cc, n),
 	})
 
 	if cc.SSHIPAddress == "" {
@@ -78,6 +78,7 @@ func configure(cc config.ClusterConfig, n config.Node) (interface{}, error) {
 	}
 
 	d.SSHPort = cc.SSHPort
+	d.SSHSudo = cc.SSHSudo
 
 	return d, nil
 }
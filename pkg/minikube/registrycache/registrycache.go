@@ -0,0 +1,215 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package registrycache runs a pull-through cache of Docker Hub on the host, which the nodes of all profiles use as a
+// registry mirror, so that the images they pull outlive 'minikube delete'.
+package registrycache
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/drivers/kic/oci"
+	"k8s.io/minikube/pkg/minikube/driver"
+)
+
+const (
+	// ContainerName is the name of the container running the cache
+	ContainerName = "minikube-registry-cache"
+	// VolumeName is the name of the volume holding the cached images, which is kept when the container is recreated
+	VolumeName = "minikube-registry-cache"
+	// Image is the registry image run in mirror mode
+	Image = "registry:2.8.1"
+	// ListenAddress is the address of the host the cache listens on, so that it is not reachable from other machines
+	ListenAddress = "127.0.0.1"
+	// Port is the port the cache listens on, on the host and in its container
+	Port = 5000
+	// Upstream is the registry the cache mirrors
+	Upstream = "https://registry-1.docker.io"
+)
+
+// Supported returns whether the nodes of a driver can reach the cache: the kic nodes over their network, and the none driver on the host itself
+func Supported(driverName string) bool {
+	return driver.IsKIC(driverName) || driver.IsNone(driverName)
+}
+
+// HostURL returns the URL of the cache on the host
+func HostURL() string {
+	return fmt.Sprintf("http://%s:%d", ListenAddress, Port)
+}
+
+// Mirror returns the URL of the cache, as reached from the nodes of a driver
+func Mirror(driverName string) string {
+	if driver.IsKIC(driverName) {
+		return fmt.Sprintf("http://%s:%d", ContainerName, Port)
+	}
+	return HostURL()
+}
+
+// Mirrors returns the registry mirrors of the nodes: the cache, if enabled, followed by the mirrors set by the user
+func Mirrors(enabled bool, driverName string, userMirrors []string) []string {
+	if !enabled {
+		return nil
+	}
+	return append([]string{Mirror(driverName)}, userMirrors...)
+}
+
+// OCIBinary returns the container engine of the host running the cache: the one of the driver for kic drivers,
+// and docker or podman, whichever is found first, otherwise
+func OCIBinary(driverName string) (string, error) {
+	if driver.IsKIC(driverName) {
+		return driverName, nil
+	}
+	for _, bin := range []string{oci.Docker, oci.Podman} {
+		if _, err := exec.LookPath(bin); err == nil {
+			return bin, nil
+		}
+	}
+	return "", fmt.Errorf("the registry cache needs docker or podman on the host")
+}
+
+// run runs a command of the container engine, returning its output
+func run(ociBin string, args ...string) (string, error) {
+	cmd := oci.PrefixCmd(exec.Command(ociBin, args...))
+	klog.Infof("Run: %v", cmd.Args)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), errors.Wrapf(err, "%s: %s", strings.Join(cmd.Args, " "), out)
+	}
+	return string(out), nil
+}
+
+// runArgs returns the arguments running the cache container
+func runArgs() []string {
+	return []string{"run", "-d", "--name", ContainerName, "--restart", "always",
+		"--label", "registry-cache.minikube.sigs.k8s.io=true",
+		"-p", fmt.Sprintf("%s:%d:%d", ListenAddress, Port, Port),
+		"-v", VolumeName + ":/var/lib/registry",
+		"-e", "REGISTRY_PROXY_REMOTEURL=" + Upstream,
+		Image}
+}
+
+// Ensure starts the cache on the host, reusing its container if there is one
+func Ensure(ociBin string) error {
+	exists, err := oci.ContainerExists(ociBin, ContainerName)
+	if err != nil {
+		return errors.Wrap(err, "checking the registry cache container")
+	}
+	if exists && !listensLocally(ociBin) {
+		// the caches of older versions listened on all the addresses of the host, the volume keeps their images
+		klog.Infof("recreating the registry cache to listen on %s only", ListenAddress)
+		if _, err := run(ociBin, "rm", "-f", ContainerName); err != nil {
+			return err
+		}
+		exists = false
+	}
+	if !exists {
+		_, err := run(ociBin, runArgs()...)
+		return err
+	}
+	if running, err := oci.ContainerRunning(ociBin, ContainerName); err == nil && running {
+		klog.Infof("reusing the running registry cache")
+		return nil
+	}
+	_, err = run(ociBin, "start", ContainerName)
+	return err
+}
+
+// listensLocally returns whether the cache container publishes its port on ListenAddress only
+func listensLocally(ociBin string) bool {
+	out, err := run(ociBin, "container", "inspect", ContainerName, "--format", "{{range .HostConfig.PortBindings}}{{range .}}{{.HostIp}} {{end}}{{end}}")
+	if err != nil {
+		return false
+	}
+	ips := strings.Fields(out)
+	for _, ip := range ips {
+		if ip != ListenAddress {
+			return false
+		}
+	}
+	return len(ips) > 0
+}
+
+// Connect attaches the cache to the network of the kic nodes, which reach it by its name
+func Connect(ociBin string, network string) error {
+	out, err := run(ociBin, "container", "inspect", ContainerName, "--format", "{{range $name, $_ := .NetworkSettings.Networks}}{{$name}} {{end}}")
+	if err != nil {
+		return errors.Wrap(err, "inspecting the registry cache container")
+	}
+	for _, n := range strings.Fields(out) {
+		if n == network {
+			return nil
+		}
+	}
+	klog.Infof("connecting the registry cache to the %s network", network)
+	_, err = run(ociBin, "network", "connect", network, ContainerName)
+	return err
+}
+
+// State is the state of the cache
+type State struct {
+	Exists  bool   `json:"exists"`
+	Running bool   `json:"running"`
+	Mirror  string `json:"mirror"`
+	// Size is the size of the cached images, if the cache is running
+	Size string `json:"size,omitempty"`
+}
+
+// Status returns the state of the cache
+func Status(ociBin string) (State, error) {
+	s := State{Mirror: HostURL()}
+	exists, err := oci.ContainerExists(ociBin, ContainerName)
+	if err != nil {
+		return s, errors.Wrap(err, "checking the registry cache container")
+	}
+	s.Exists = exists
+	if !exists {
+		return s, nil
+	}
+	running, err := oci.ContainerRunning(ociBin, ContainerName)
+	if err != nil {
+		return s, errors.Wrap(err, "checking the registry cache container")
+	}
+	s.Running = running
+	if running {
+		if out, err := run(ociBin, "exec", ContainerName, "du", "-sh", "/var/lib/registry"); err == nil {
+			if fields := strings.Fields(out); len(fields) > 0 {
+				s.Size = fields[0]
+			}
+		}
+	}
+	return s, nil
+}
+
+// Prune removes the cache along with the cached images
+func Prune(ociBin string) error {
+	exists, err := oci.ContainerExists(ociBin, ContainerName)
+	if err != nil {
+		return errors.Wrap(err, "checking the registry cache container")
+	}
+	if exists {
+		if _, err := run(ociBin, "rm", "-f", ContainerName); err != nil {
+			return err
+		}
+	}
+	if err := oci.RemoveVolume(ociBin, VolumeName); err != nil {
+		return errors.Wrap(err, "removing the registry cache volume")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registrycache
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMirrors(t *testing.T) {
+	if got := Mirrors(false, "docker", []string{"https://mirror.gcr.io"}); got != nil {
+		t.Errorf("Mirrors(false) = %v, want nil so that the user's mirrors stay dockerd flags", got)
+	}
+	want := []string{"http://minikube-registry-cache:5000", "https://mirror.gcr.io"}
+	if diff := cmp.Diff(want, Mirrors(true, "docker", []string{"https://mirror.gcr.io"})); diff != "" {
+		t.Errorf("Mirrors(true, docker) diff (-want +got):\n%s", diff)
+	}
+	want = []string{"http://127.0.0.1:5000"}
+	if diff := cmp.Diff(want, Mirrors(true, "none", nil)); diff != "" {
+		t.Errorf("Mirrors(true, none) diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunArgs(t *testing.T) {
+	got := strings.Join(runArgs(), " ")
+	for _, want := range []string{"--name minikube-registry-cache", "-p 127.0.0.1:5000:5000", "-v minikube-registry-cache:/var/lib/registry", "-e REGISTRY_PROXY_REMOTEURL=https://registry-1.docker.io"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("runArgs() = %q, missing %q", got, want)
+		}
+	}
+	if !strings.HasSuffix(got, " "+Image) {
+		t.Errorf("runArgs() = %q, want the image last", got)
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/docker/machine/libmachine/shell"
@@ -55,6 +57,16 @@ type shellData struct {
 	unsetSuffix    string
 	unsetDelimiter string
 	usageHint      func(s ...interface{}) string
+	// quote escapes a value between delimiter and suffix, values are written as is if nil
+	quote func(string) string
+}
+
+// quoteValue escapes a value for the shell
+func (s shellData) quoteValue(v string) string {
+	if s.quote == nil {
+		return v
+	}
+	return s.quote(v)
 }
 
 var shellConfigMap = map[string]shellData{
@@ -71,6 +83,7 @@ var shellConfigMap = map[string]shellData{
 # %s | source
 `, s...)
 		},
+		quote: strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace,
 	},
 	"powershell": {
 		prefix:         "$Env:",
@@ -84,6 +97,22 @@ var shellConfigMap = map[string]shellData{
 # & %s --shell powershell | Invoke-Expression
 `, s...)
 		},
+		quote: strings.NewReplacer("`", "``", `"`, "`\"", `$`, "`$").Replace,
+	},
+	// pwsh is PowerShell 7 and later, whose single quoted strings keep Windows paths as they are
+	"pwsh": {
+		prefix:         "$Env:",
+		suffix:         "'\n",
+		delimiter:      " = '",
+		unsetPrefix:    "$Env:",
+		unsetSuffix:    "\n",
+		unsetDelimiter: " = $null",
+		usageHint: func(s ...interface{}) string {
+			return fmt.Sprintf(`# %s
+# & %s --shell pwsh | Out-String | Invoke-Expression
+`, s...)
+		},
+		quote: strings.NewReplacer(`'`, `''`).Replace,
 	},
 	"cmd": {
 		prefix:         "SET ",
@@ -110,6 +139,7 @@ REM @FOR /f "tokens=*" %%i IN ('%s --shell cmd') DO @%%i
 ;; (with-temp-buffer (shell-command "%s" (current-buffer)) (eval-buffer))
 `, s...)
 		},
+		quote: strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace,
 	},
 	"bash": {
 		prefix:         "export ",
@@ -124,6 +154,7 @@ REM @FOR /f "tokens=*" %%i IN ('%s --shell cmd') DO @%%i
 # eval $(%s)
 `, s...)
 		},
+		quote: strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`").Replace,
 	},
 	"tcsh": {
 		prefix:         "setenv ",
@@ -136,6 +167,23 @@ REM @FOR /f "tokens=*" %%i IN ('%s --shell cmd') DO @%%i
 			return fmt.Sprintf("\n: \"%s\"\n: eval `%s`\n", s...)
 		},
 	},
+	// nushell can not evaluate the output of a command, which is saved to a file to source instead
+	"nushell": {
+		prefix:         "$env.",
+		suffix:         "\"\n",
+		delimiter:      " = \"",
+		unsetPrefix:    "hide-env ",
+		unsetSuffix:    "\n",
+		unsetDelimiter: "",
+		usageHint: func(s ...interface{}) string {
+			return fmt.Sprintf(`
+# %s
+# %s --shell nushell | save --force minikube-env.nu
+# source minikube-env.nu
+`, s...)
+		},
+		quote: strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace,
+	},
 	"none": {
 		prefix:         "",
 		suffix:         "\n",
@@ -157,14 +205,50 @@ var (
 	ForceShell string
 )
 
-// Detect detects user's current shell.
+// shellAliases are the names of the shells as detected, which do not match the ones of shellConfigMap
+var shellAliases = map[string]string{
+	"nu": "nushell",
+}
+
+// detected are the shells detected so far, by the value of $SHELL they were detected with,
+// as detecting the shell on Windows walks the processes and may print a hint
+var detected = struct {
+	sync.Mutex
+	shells map[string]string
+}{shells: map[string]string{}}
+
+// Detect detects user's current shell. It is safe to call concurrently.
 func Detect() (string, error) {
 	sh := os.Getenv("SHELL")
 	// Don't error out when $SHELL has not been set
 	if sh == "" && runtime.GOOS != "windows" {
 		return defaultSh, nil
 	}
-	return shell.Detect()
+	detected.Lock()
+	defer detected.Unlock()
+	if name, ok := detected.shells[sh]; ok {
+		return name, nil
+	}
+	name, err := shell.Detect()
+	if err != nil {
+		return name, err
+	}
+	if alias, ok := shellAliases[name]; ok {
+		name = alias
+	}
+	detected.shells[sh] = name
+	return name, nil
+}
+
+// Resolve returns the shell forced by name, or the detected one if name is empty
+func Resolve(name string) (string, error) {
+	if name == "" {
+		return Detect()
+	}
+	if alias, ok := shellAliases[name]; ok {
+		return alias, nil
+	}
+	return name, nil
 }
 
 func (c EnvConfig) getShell() shellData {
@@ -201,6 +285,25 @@ func SetScript(ec EnvConfig, w io.Writer, envTmpl string, data interface{}) erro
 	return tmpl.Execute(w, data)
 }
 
+// EnvVar is an environment variable set by a script
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// SetVarsScript writes out a shell-compatible script setting vars in order, with their values quoted for the shell,
+// followed by the usage hint made of plz and cmd
+func SetVarsScript(ec EnvConfig, w io.Writer, vars []EnvVar, plz, cmd string) error {
+	shellCfg := ec.getShell()
+	for _, v := range vars {
+		if _, err := fmt.Fprintf(w, "%s%s%s%s%s", shellCfg.prefix, v.Name, shellCfg.delimiter, shellCfg.quoteValue(v.Value), shellCfg.suffix); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, shellCfg.usageHint(plz, cmd))
+	return err
+}
+
 type unsetConfigItem struct {
 	Env, Value string
 }
@@ -234,7 +337,7 @@ func UnsetScript(ec EnvConfig, w io.Writer, vars []string) error {
 		} else {
 			cfg.Set = append(cfg.Set, unsetConfigItem{
 				Env:   env,
-				Value: v,
+				Value: shellCfg.quoteValue(v),
 			})
 			tempUnset = append(tempUnset, exEnv)
 		}
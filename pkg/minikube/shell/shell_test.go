@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -42,6 +43,11 @@ REM @FOR /f "tokens=*" %i IN ('bar --shell cmd') DO @%i`},
 # bar | source`},
 		{EnvConfig{"none"}, ``},
 		{EnvConfig{"tcsh"}, "\n: \"foo\"\n: eval `bar`\n"},
+		{EnvConfig{"pwsh"}, `# foo
+# & bar --shell pwsh | Out-String | Invoke-Expression`},
+		{EnvConfig{"nushell"}, `# foo
+# bar --shell nushell | save --force minikube-env.nu
+# source minikube-env.nu`},
 	}
 	for _, tc := range testCases {
 		tc := tc
@@ -69,6 +75,8 @@ func TestCfgSet(t *testing.T) {
 		{"", "eval", EnvConfig{"none"}, ``},
 		{"", "eval", EnvConfig{"fish"}, `";`},
 		{"", "eval", EnvConfig{"tcsh"}, `";`},
+		{"", "eval", EnvConfig{"pwsh"}, `'`},
+		{"", "eval", EnvConfig{"nushell"}, `"`},
 	}
 	for _, tc := range testCases {
 		tc := tc
@@ -103,6 +111,8 @@ set -e bar;`},
 (setenv "bar" nil)`},
 		{[]string{"baz", "bar"}, EnvConfig{"none"}, "baz\nbar"},
 		{[]string{"baz", "bar"}, EnvConfig{"tcsh"}, "unsetenv baz;\nunsetenv bar;"},
+		{[]string{"baz", "bar"}, EnvConfig{"pwsh"}, "$Env:baz = $null\n$Env:bar = $null"},
+		{[]string{"baz", "bar"}, EnvConfig{"nushell"}, "hide-env baz\nhide-env bar"},
 	}
 	for _, tc := range testCases {
 		tc := tc
@@ -159,3 +169,69 @@ func TestSetScript(t *testing.T) {
 		t.Fatalf("Expected no empty shell")
 	}
 }
+
+func TestSetVarsScript(t *testing.T) {
+	vars := []EnvVar{{Name: "A", Value: `C:\My Files`}, {Name: "B", Value: "it's \"$HOME\" `x`"}}
+	var testCases = []struct {
+		shell    string
+		expected string
+	}{
+		{"bash", "export A=\"C:\\\\My Files\"\nexport B=\"it's \\\"\\$HOME\\\" \\`x\\`\"\n"},
+		{"fish", "set -gx A \"C:\\\\My Files\";\nset -gx B \"it's \\\"\\$HOME\\\" `x`\";\n"},
+		{"powershell", "$Env:A = \"C:\\My Files\"\n$Env:B = \"it's `\"`$HOME`\" ``x``\"\n"},
+		{"pwsh", "$Env:A = 'C:\\My Files'\n$Env:B = 'it''s \"$HOME\" `x`'\n"},
+		{"cmd", "SET A=C:\\My Files\nSET B=it's \"$HOME\" `x`\n"},
+		{"emacs", "(setenv \"A\" \"C:\\\\My Files\")\n(setenv \"B\" \"it's \\\"$HOME\\\" `x`\")\n"},
+		{"nushell", "$env.A = \"C:\\\\My Files\"\n$env.B = \"it's \\\"$HOME\\\" `x`\"\n"},
+		{"none", "A=C:\\My Files\nB=it's \"$HOME\" `x`\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.shell, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := SetVarsScript(EnvConfig{tc.shell}, &b, vars, "", ""); err != nil {
+				t.Fatalf("SetVarsScript: %v", err)
+			}
+			// the usage hint follows the variables
+			got := strings.Join(strings.SplitAfter(b.String(), "\n")[:len(vars)], "")
+			if got != tc.expected {
+				t.Errorf("SetVarsScript(%s) = %q, want %q", tc.shell, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/nu")
+	for _, tc := range []struct{ force, expected string }{
+		{"pwsh", "pwsh"},
+		{"nu", "nushell"},
+		{"", "nushell"},
+	} {
+		got, err := Resolve(tc.force)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", tc.force, err)
+		}
+		if got != tc.expected {
+			t.Errorf("Resolve(%q) = %q, want %q", tc.force, got, tc.expected)
+		}
+	}
+}
+
+func TestDetectConcurrent(t *testing.T) {
+	t.Setenv("SHELL", "/bin/zsh")
+	var wg sync.WaitGroup
+	shells := make([]string, 10)
+	for i := range shells {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			shells[i], _ = Detect()
+		}(i)
+	}
+	wg.Wait()
+	for _, s := range shells {
+		if s != "zsh" {
+			t.Errorf("Detect() = %q, want zsh", s)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package style
+
+import "fmt"
+
+// logColors are the ANSI colors which tell interleaved logs apart
+var logColors = []int{36, 33, 35, 32, 34, 31}
+
+// LogColor returns s in the color of the nth of several interleaved logs, cycling through the colors
+func LogColor(n int, s string) string {
+	return fmt.Sprintf("\033[%dm%s\033[0m", logColors[n%len(logColors)], s)
+}
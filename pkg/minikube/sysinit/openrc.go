@@ -115,6 +115,11 @@ func (s *OpenRC) Start(svc string) error {
 	return nil
 }
 
+// NeedsReload returns false, as OpenRC reads the service scripts as it runs them
+func (s *OpenRC) NeedsReload(svc string) bool {
+	return false
+}
+
 // Disable does nothing
 func (s *OpenRC) Disable(svc string) error {
 	return nil
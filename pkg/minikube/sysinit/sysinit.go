@@ -65,6 +65,9 @@ type Manager interface {
 	// Reload restarts a service
 	Reload(string) error
 
+	// NeedsReload returns whether the unit files of a service changed since the init system loaded them
+	NeedsReload(string) bool
+
 	// Stop stops a service
 	Stop(string) error
 
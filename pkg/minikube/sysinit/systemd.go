@@ -23,6 +23,7 @@ import (
 	"os/exec"
 	"strings"
 
+	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/assets"
 )
 
@@ -48,6 +49,16 @@ func (s *Systemd) Active(svc string) bool {
 	return err == nil
 }
 
+// NeedsReload returns whether the unit file or the drop-ins of a service changed on disk since the last daemon-reload
+func (s *Systemd) NeedsReload(svc string) bool {
+	rr, err := s.r.RunCmd(exec.Command("sudo", "systemctl", "show", "--property=NeedDaemonReload", svc))
+	if err != nil {
+		klog.Warningf("unable to check whether %s needs a reload: %v", svc, err)
+		return false
+	}
+	return strings.TrimSpace(rr.Stdout.String()) == "NeedDaemonReload=yes"
+}
+
 // Disable disables a service
 func (s *Systemd) Disable(svc string) error {
 	cmd := exec.Command("sudo", "systemctl", "disable", svc)
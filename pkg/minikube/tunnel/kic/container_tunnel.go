@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/phayes/freeport"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+// containerTunnelInterval is how often the address of the container is checked, and the tunnel restarted if it exited
+const containerTunnelInterval = 2 * time.Second
+
+// SSHEndpoint is the ssh server of a node
+type SSHEndpoint struct {
+	Host string
+	Port int
+	User string
+	Key  string
+}
+
+// ContainerForward forwards a port of the host to a TCP port of a container
+type ContainerForward struct {
+	// LocalPort is the port of the host, which is a free one if 0
+	LocalPort int
+	// Port is the port of the container
+	Port int
+	// Fixed is whether LocalPort was asked for, which no free port replaces when it is in use
+	Fixed bool
+}
+
+// ContainerTunnel forwards ports of the host to the ports of a container of a node over ssh.
+// It follows the container to its new address when it restarts, and restarts ssh when it exits.
+type ContainerTunnel struct {
+	node        SSHEndpoint
+	bindAddress string
+	container   string
+	resolve     func() ([]cruntime.PortMapping, error)
+	conn        *sshConn
+	done        chan struct{}
+	forwards    []string
+}
+
+// NewContainerTunnel returns a tunnel to the container of the node over ssh, whose ports resolve returns.
+// The forwards listen on bindAddress of the host, which is all of its addresses if '*'.
+func NewContainerTunnel(node SSHEndpoint, bindAddress string, container string, resolve func() ([]cruntime.PortMapping, error)) *ContainerTunnel {
+	return &ContainerTunnel{
+		node:        node,
+		bindAddress: bindAddress,
+		container:   container,
+		resolve:     resolve,
+	}
+}
+
+// AllocateLocalPorts returns the forwards with the ports of the host they listen on. The forwards whose port is not given,
+// or is in use, listen on a free port instead, unless their port was asked for, which fails.
+func (t *ContainerTunnel) AllocateLocalPorts(forwards []ContainerForward) ([]ContainerForward, error) {
+	allocated := []ContainerForward{}
+	used := map[int]bool{}
+	for _, f := range forwards {
+		if f.LocalPort == 0 || used[f.LocalPort] || !t.localPortAvailable(f.LocalPort) {
+			if f.Fixed {
+				return nil, errors.Errorf("port %d of the host is in use", f.LocalPort)
+			}
+			port, err := freeport.GetFreePort()
+			if err != nil {
+				return nil, errors.Wrap(err, "free port")
+			}
+			if f.LocalPort != 0 {
+				out.WarningT("Port {{.port}} of the host is in use, forwarding port {{.free}} instead", out.V{"port": f.LocalPort, "free": port})
+			}
+			f.LocalPort = port
+		}
+		used[f.LocalPort] = true
+		allocated = append(allocated, f)
+	}
+	return allocated, nil
+}
+
+// localPortAvailable returns whether the tunnel can listen on the port of the host
+func (t *ContainerTunnel) localPortAvailable(port int) bool {
+	host := t.bindAddress
+	if host == "*" {
+		host = ""
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		klog.Infof("port %d of the host is unavailable: %v", port, err)
+		return false
+	}
+	if err := l.Close(); err != nil {
+		klog.Warningf("closing listener on port %d: %v", port, err)
+	}
+	return true
+}
+
+// Run forwards the ports until the context is done, then stops ssh
+func (t *ContainerTunnel) Run(ctx context.Context, forwards []ContainerForward) error {
+	defer t.stop()
+	waiting := false
+	for {
+		args, err := t.forwardArgs(forwards)
+		switch {
+		case err != nil:
+			if !waiting {
+				out.WarningT("Waiting for container {{.container}}: {{.error}}", out.V{"container": t.container, "error": err})
+				waiting = true
+			}
+			t.stop()
+		case waiting || !t.running() || !equalStrings(args, t.forwards):
+			switch {
+			case waiting:
+				out.Step(style.Restarting, "Container {{.container}} is back, forwarding its ports again", out.V{"container": t.container})
+			case t.conn != nil && !equalStrings(args, t.forwards):
+				out.Step(style.Restarting, "Container {{.container}} restarted at a new address, forwarding its ports again", out.V{"container": t.container})
+			case t.conn != nil:
+				klog.Warningf("ssh tunnel to container %s exited, restarting it", t.container)
+			}
+			waiting = false
+			t.stop()
+			if err := t.start(args); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(containerTunnelInterval):
+		}
+	}
+}
+
+// forwardArgs returns the -L arguments of ssh forwarding the ports of the host to the current address of the container
+func (t *ContainerTunnel) forwardArgs(forwards []ContainerForward) ([]string, error) {
+	mappings, err := t.resolve()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{}
+	for _, f := range forwards {
+		target := ""
+		for _, m := range mappings {
+			if m.Protocol == "tcp" && m.ContainerPort == f.Port {
+				target = ContainerAddress(m)
+				break
+			}
+		}
+		if target == "" {
+			return nil, fmt.Errorf("the container does not expose port %d/tcp", f.Port)
+		}
+		args = append(args, fmt.Sprintf("%s:%d:%s", t.bindAddress, f.LocalPort, target))
+	}
+	return args, nil
+}
+
+// ContainerAddress returns the address of the port of a container in the node
+func ContainerAddress(m cruntime.PortMapping) string {
+	ip := m.ContainerIP
+	if ip == "" {
+		// the containers in the network of the node listen on its addresses
+		ip = "127.0.0.1"
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(m.ContainerPort))
+}
+
+// start starts ssh with the forwards
+func (t *ContainerTunnel) start(forwards []string) error {
+	conn := createSSHConnToNode(t.container, t.node, forwards)
+	klog.Infof("starting ssh tunnel: %s", conn.cmd.Args)
+	if err := conn.cmd.Start(); err != nil {
+		return errors.Wrap(err, "starting ssh")
+	}
+	conn.activeConn = true
+	done := make(chan struct{})
+	go func() {
+		// the process exits when it is killed, or fails to forward a port
+		if err := conn.cmd.Wait(); err != nil {
+			klog.Infof("ssh tunnel exited: %v", err)
+		}
+		close(done)
+	}()
+	t.conn = conn
+	t.done = done
+	t.forwards = forwards
+	return nil
+}
+
+// running returns whether ssh is running
+func (t *ContainerTunnel) running() bool {
+	if t.conn == nil {
+		return false
+	}
+	select {
+	case <-t.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// stop stops ssh, if it runs
+func (t *ContainerTunnel) stop() {
+	if t.conn == nil {
+		return
+	}
+	if err := t.conn.stop(); err != nil {
+		klog.Warningf("Failed to stop ssh tunnel: %v", err)
+	}
+	<-t.done
+	t.conn = nil
+	t.forwards = nil
+}
+
+// equalStrings returns whether a and b have the same strings in the same order
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
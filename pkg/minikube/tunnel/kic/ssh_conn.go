@@ -21,6 +21,7 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
 
 	"github.com/phayes/freeport"
 	v1 "k8s.io/api/core/v1"
@@ -152,6 +153,30 @@ func createSSHConnWithRandomPorts(name, sshPort, sshKey string, svc *v1.Service)
 	}, nil
 }
 
+func createSSHConnToNode(name string, node SSHEndpoint, forwards []string) *sshConn {
+	sshArgs := []string{
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "StrictHostKeyChecking=no",
+		// exit when a port of the host is in use, rather than run without forwarding it
+		"-o", "ExitOnForwardFailure=yes",
+		"-N",
+		fmt.Sprintf("%s@%s", node.User, node.Host),
+		"-p", strconv.Itoa(node.Port),
+		"-i", node.Key,
+	}
+	for _, f := range forwards {
+		sshArgs = append(sshArgs, "-L", f)
+	}
+
+	return &sshConn{
+		name:           name,
+		service:        name,
+		cmd:            exec.Command("ssh", sshArgs...),
+		activeConn:     false,
+		suppressStdOut: true,
+	}
+}
+
 func (c *sshConn) startAndWait() error {
 	if !c.suppressStdOut {
 		out.Step(style.Running, "Starting tunnel for service {{.service}}.", out.V{"service": c.service})
@@ -23,6 +23,7 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"text/template"
 	"time"
@@ -70,7 +71,7 @@ func init() {
 func NewSystemdProvisioner(osReleaseID string, d drivers.Driver) provision.SystemdProvisioner {
 	return provision.SystemdProvisioner{
 		GenericProvisioner: provision.GenericProvisioner{
-			SSHCommander:      provision.GenericSSHCommander{Driver: d},
+			SSHCommander:      sshCommander(d),
 			DockerOptionsDir:  "/etc/docker",
 			DaemonOptionsFile: "/etc/systemd/system/docker.service.d/10-machine.conf",
 			OsReleaseID:       osReleaseID,
@@ -79,6 +80,51 @@ func NewSystemdProvisioner(osReleaseID string, d drivers.Driver) provision.Syste
 	}
 }
 
+// elevatedSSHCommander runs the commands of the provisioners through the runner of the host, which elevates their sudo
+// with the elevation of the host, such as a sudo password or doas
+type elevatedSSHCommander struct {
+	runner command.Runner
+}
+
+// SSHCommand runs the command line args on the host, returning its output
+func (c elevatedSSHCommander) SSHCommand(args string) (string, error) {
+	rr, err := c.runner.RunCmd(exec.Command("/bin/bash", "-c", args))
+	if rr == nil {
+		return "", err
+	}
+	return rr.Stdout.String() + rr.Stderr.String(), err
+}
+
+// sshCommander returns what runs the commands of the provisioners on the host of d, which they run with sudo.
+// Unless the host elevates them in another way, it is the one of libmachine.
+func sshCommander(d drivers.Driver) provision.SSHCommander {
+	if e, ok := d.(command.Elevator); ok && e.Elevation() != command.ElevationSudo {
+		return elevatedSSHCommander{runner: command.NewSSHRunner(d)}
+	}
+	return provision.GenericSSHCommander{Driver: d}
+}
+
+// ElevateSSHCommands makes p, such as a provisioner of libmachine detected on the host of d, run its commands
+// with the elevation of the host, as libmachine only runs them with sudo
+func ElevateSSHCommands(p provision.Provisioner, d drivers.Driver) {
+	c := sshCommander(d)
+	if _, ok := c.(provision.GenericSSHCommander); ok {
+		return
+	}
+	v := reflect.ValueOf(p)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		klog.Warningf("unable to elevate the commands of the %s provisioner", p)
+		return
+	}
+	// the provisioners of libmachine all embed the GenericProvisioner, which runs their commands with its SSHCommander
+	f := v.Elem().FieldByName("SSHCommander")
+	if !f.IsValid() || !f.CanSet() || !reflect.TypeOf(c).AssignableTo(f.Type()) {
+		klog.Warningf("unable to elevate the commands of the %s provisioner", p)
+		return
+	}
+	f.Set(reflect.ValueOf(c))
+}
+
 func configureAuth(p miniProvisioner) error {
 	klog.Infof("configureAuth start")
 	start := time.Now()
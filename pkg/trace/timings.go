@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trace
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Timing is the time spent in a phase
+type Timing struct {
+	Phase    string
+	Duration time.Duration
+}
+
+var (
+	timingsMu sync.Mutex
+	timings   []Timing
+)
+
+// Time starts timing a phase, which is also sent as a span to the tracer, and returns the function ending it
+func Time(phase string) func() time.Duration {
+	StartSpan(phase)
+	start := time.Now()
+	return func() time.Duration {
+		d := time.Since(start)
+		EndSpan(phase)
+		timingsMu.Lock()
+		defer timingsMu.Unlock()
+		timings = append(timings, Timing{Phase: phase, Duration: d})
+		return d
+	}
+}
+
+// Timings returns the timings recorded so far, longest first
+func Timings() []Timing {
+	timingsMu.Lock()
+	defer timingsMu.Unlock()
+	ts := append([]Timing{}, timings...)
+	sort.SliceStable(ts, func(i, j int) bool { return ts[i].Duration > ts[j].Duration })
+	return ts
+}
+
+// ResetTimings forgets the timings recorded so far
+func ResetTimings() {
+	timingsMu.Lock()
+	defer timingsMu.Unlock()
+	timings = nil
+}
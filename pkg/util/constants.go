@@ -18,6 +18,7 @@ package util
 
 import (
 	"net"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -44,14 +45,17 @@ var DefaultV114AdmissionControllers = []string{
 // DefaultLegacyAdmissionControllers are admission controllers we include with Kubernetes <1.14.0
 var DefaultLegacyAdmissionControllers = append([]string{"Initializers"}, DefaultV114AdmissionControllers...)
 
-// GetServiceClusterIP returns the first IP of the ServiceCIDR
+// GetServiceClusterIP returns the first IP of the ServiceCIDR, or of its first network if dual-stack
 func GetServiceClusterIP(serviceCIDR string) (net.IP, error) {
-	ip, _, err := net.ParseCIDR(serviceCIDR)
+	primary, _, _ := strings.Cut(serviceCIDR, ",")
+	ip, _, err := net.ParseCIDR(primary)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing default service cidr")
 	}
-	ip = ip.To4()
-	ip[3]++
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	ip[len(ip)-1]++
 	return ip, nil
 }
 
@@ -18,6 +18,7 @@ limitations under the License.
 package retry
 
 import (
+	"context"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
@@ -60,6 +61,22 @@ func Expo(callback func() error, initInterval time.Duration, maxTime time.Durati
 	return backoff.RetryNotify(callback, bm, notify)
 }
 
+// ExpoContext is Expo, which stops retrying as soon as ctx is done, returning its error
+func ExpoContext(ctx context.Context, callback func() error, initInterval time.Duration, maxTime time.Duration, maxRetries ...uint64) error {
+	maxRetry := uint64(defaultMaxRetries)
+	if maxRetries != nil {
+		maxRetry = maxRetries[0]
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.MaxElapsedTime = maxTime
+	b.InitialInterval = initInterval
+	b.RandomizationFactor = 0.5
+	b.Multiplier = 1.5
+	bm := backoff.WithContext(backoff.WithMaxRetries(b, maxRetry), ctx)
+	return backoff.RetryNotify(callback, bm, notify)
+}
+
 // RetriableError is an error that can be tried again
 type RetriableError struct {
 	Err error
@@ -22,6 +22,8 @@ import (
 	"os/user"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/blang/semver/v4"
 	units "github.com/docker/go-units"
@@ -47,6 +49,18 @@ func CalculateSizeInMB(humanReadableSize string) (int, error) {
 	return int(size / units.MiB), nil
 }
 
+// ParseAge parses a period such as "30d" or "12h", which is a duration with days allowed as its unit
+func ParseAge(age string) (time.Duration, error) {
+	if days := strings.TrimSuffix(age, "d"); days != age {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days: %q", age)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(age)
+}
+
 // ConvertMBToBytes converts MB to bytes
 func ConvertMBToBytes(mbSize int) int64 {
 	return int64(mbSize) * units.MiB
@@ -21,6 +21,7 @@ import (
 	"os/user"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/google/go-cmp/cmp"
@@ -69,6 +70,31 @@ func TestCalculateSizeInMB(t *testing.T) {
 	}
 }
 
+func TestParseAge(t *testing.T) {
+	testData := []struct {
+		age      string
+		expected time.Duration
+		err      bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"1.5d", 0, true},
+		{"-1d", 0, true},
+		{"month", 0, true},
+	}
+
+	for _, tt := range testData {
+		age, err := ParseAge(tt.age)
+		if (err != nil) != tt.err {
+			t.Fatalf("ParseAge(%q) error = %v, want error: %v", tt.age, err, tt.err)
+		}
+		if age != tt.expected {
+			t.Fatalf("Expected '%s' but got '%s' from age '%s'", tt.expected, age, tt.age)
+		}
+	}
+}
+
 func TestParseKubernetesVersion(t *testing.T) {
 	version, err := ParseKubernetesVersion("v1.8.0-alpha.5")
 	if err != nil {
@@ -0,0 +1,63 @@
+//go:build integration && gpu
+
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestDockerGPUs makes sure --gpus makes the NVIDIA container runtime the default one of docker in the node
+// It needs a host with NVIDIA GPUs and the NVIDIA container toolkit, so it only runs with the gpu build tag.
+func TestDockerGPUs(t *testing.T) {
+	if !DockerDriver() {
+		t.Skip("skipping: only the docker driver exposes GPUs to the node")
+	}
+	if ContainerRuntime() != "docker" {
+		t.Skipf("skipping: only runs with docker container runtime, currently testing %s", ContainerRuntime())
+	}
+
+	profile := UniqueProfileName("docker-gpus")
+	ctx, cancel := context.WithTimeout(context.Background(), Minutes(30))
+	defer CleanupWithLogs(t, profile, cancel)
+
+	args := append([]string{"start", "-p", profile, "--memory=2048", "--wait=false", "--gpus=all", "--alsologtostderr", "-v=5"}, StartArgs()...)
+	rr, err := Run(t, exec.CommandContext(ctx, Target(), args...))
+	if err != nil {
+		t.Fatalf("failed to start minikube with args: %q : %v", rr.Command(), err)
+	}
+
+	rr, err = Run(t, exec.CommandContext(ctx, Target(), "-p", profile, "ssh", "docker info --format {{.DefaultRuntime}}"))
+	if err != nil {
+		t.Fatalf("failed to get the default docker runtime. args %q: %v", rr.Command(), err)
+	}
+	if got := strings.TrimSpace(rr.Stdout.String()); got != "nvidia" {
+		t.Errorf("expected the default docker runtime to be nvidia, got %q", got)
+	}
+
+	rr, err = Run(t, exec.CommandContext(ctx, Target(), "-p", profile, "ssh", "docker run --rm nvidia/cuda:11.8.0-base-ubuntu22.04 nvidia-smi -L"))
+	if err != nil {
+		t.Fatalf("failed to run nvidia-smi in a container of the node. args %q: %v", rr.Command(), err)
+	}
+	if !strings.Contains(rr.Stdout.String(), "GPU") {
+		t.Errorf("expected nvidia-smi to list GPUs, got %q", rr.Stdout)
+	}
+}
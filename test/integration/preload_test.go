@@ -81,3 +81,58 @@ func TestPreload(t *testing.T) {
 		t.Fatalf("Expected to find %s in output of `docker images`, instead got %s", image, rr.Output())
 	}
 }
+
+// TestPreloadContainerdImageStore verifies the preload and image load with the containerd image store of docker
+func TestPreloadContainerdImageStore(t *testing.T) {
+	if NoneDriver() {
+		t.Skipf("skipping %s - incompatible with none driver", t.Name())
+	}
+	if ContainerRuntime() != "docker" {
+		t.Skipf("skipping: only runs with docker container runtime, currently testing %s", ContainerRuntime())
+	}
+
+	profile := UniqueProfileName("containerd-store")
+	ctx, cancel := context.WithTimeout(context.Background(), Minutes(40))
+	defer CleanupWithLogs(t, profile, cancel)
+
+	startArgs := []string{"start", "-p", profile, "--memory=2200", "--alsologtostderr", "--wait=true", "--docker-feature=containerd-snapshotter=true"}
+	startArgs = append(startArgs, StartArgs()...)
+	rr, err := Run(t, exec.CommandContext(ctx, Target(), startArgs...))
+	if err != nil {
+		t.Fatalf("%s failed: %v", rr.Command(), err)
+	}
+
+	rr, err = Run(t, exec.CommandContext(ctx, Target(), "ssh", "-p", profile, "--", "docker", "info", "--format", "'{{json .DriverStatus}}'"))
+	if err != nil {
+		t.Fatalf("%s failed: %v", rr.Command(), err)
+	}
+	if !strings.Contains(rr.Output(), "io.containerd.snapshotter.v1") {
+		t.Fatalf("expected docker to store its images in containerd, got: %s", rr.Output())
+	}
+
+	// the preloaded images are in the containerd image store
+	rr, err = Run(t, exec.CommandContext(ctx, Target(), "ssh", "-p", profile, "--", "docker", "images"))
+	if err != nil {
+		t.Fatalf("%s failed: %v", rr.Command(), err)
+	}
+	if !strings.Contains(rr.Output(), "kube-apiserver") {
+		t.Errorf("expected the preloaded images in the output of `docker images`, instead got %s", rr.Output())
+	}
+
+	image := "gcr.io/k8s-minikube/busybox:latest"
+	rr, err = Run(t, exec.CommandContext(ctx, "docker", "pull", image))
+	if err != nil {
+		t.Skipf("unable to pull %s on the host: %v", image, err)
+	}
+	rr, err = Run(t, exec.CommandContext(ctx, Target(), "image", "load", "-p", profile, image))
+	if err != nil {
+		t.Fatalf("%s failed: %v", rr.Command(), err)
+	}
+	rr, err = Run(t, exec.CommandContext(ctx, Target(), "image", "ls", "-p", profile))
+	if err != nil {
+		t.Fatalf("%s failed: %v", rr.Command(), err)
+	}
+	if !strings.Contains(rr.Output(), "gcr.io/k8s-minikube/busybox") {
+		t.Errorf("expected %s in the images of the cluster, instead got %s", image, rr.Output())
+	}
+}
@@ -0,0 +1,98 @@
+//go:build integration
+
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"testing"
+)
+
+// TestRegistryCache makes sure that the images pulled by a cluster started with --registry-cache are pulled again from
+// the cache of the host by a recreated cluster, even without access to Docker Hub.
+func TestRegistryCache(t *testing.T) {
+	if NoneDriver() {
+		t.Skipf("skipping %s - the none driver pulls images with the docker of the host", t.Name())
+	}
+	if !KicDriver() {
+		t.Skipf("skipping %s - the registry cache is only reachable from the nodes of the kic drivers", t.Name())
+	}
+
+	profile := UniqueProfileName("registry-cache")
+	ctx, cancel := context.WithTimeout(context.Background(), Minutes(30))
+	defer CleanupWithLogs(t, profile, cancel)
+
+	// the cache outlives the profile, so it is removed by the test unless it was there before
+	if !registryCacheState(ctx, t).Exists {
+		defer func() {
+			if rr, err := Run(t, exec.CommandContext(context.Background(), Target(), "registry-cache", "prune")); err != nil {
+				t.Logf("failed to remove the registry cache: %q : %v", rr.Command(), err)
+			}
+		}()
+	}
+
+	const img = "busybox:1.35"
+	start := func() {
+		args := append([]string{"start", "-p", profile, "--registry-cache", "--memory=2048", "--alsologtostderr", "-v=1"}, StartArgs()...)
+		if rr, err := Run(t, exec.CommandContext(ctx, Target(), args...)); err != nil {
+			t.Fatalf("failed to start minikube with args: %q : %v", rr.Command(), err)
+		}
+	}
+
+	start()
+	if rr, err := Run(t, exec.CommandContext(ctx, Target(), "-p", profile, "image", "pull", img)); err != nil {
+		t.Fatalf("%q failed: %v", rr.Command(), err)
+	}
+	if rr, err := Run(t, exec.CommandContext(ctx, Target(), "delete", "-p", profile)); err != nil {
+		t.Fatalf("%q failed: %v", rr.Command(), err)
+	}
+
+	if state := registryCacheState(ctx, t); !state.Running {
+		t.Fatalf("the registry cache did not outlive 'minikube delete': %+v", state)
+	}
+
+	start()
+	// block the egress of the node to Docker Hub, which is reached over https, leaving the cache of the host reachable over http
+	if rr, err := Run(t, exec.CommandContext(ctx, Target(), "-p", profile, "ssh", "sudo iptables -I OUTPUT -p tcp --dport 443 -j REJECT")); err != nil {
+		t.Fatalf("%q failed: %v", rr.Command(), err)
+	}
+	if rr, err := Run(t, exec.CommandContext(ctx, Target(), "-p", profile, "image", "pull", img)); err != nil {
+		t.Errorf("pulling %s from the warm registry cache without access to Docker Hub failed: %q : %v", img, rr.Command(), err)
+	}
+}
+
+// registryCacheStatus is the state printed by 'minikube registry-cache status -o json'
+type registryCacheStatus struct {
+	Exists  bool `json:"exists"`
+	Running bool `json:"running"`
+}
+
+// registryCacheState returns the state of the registry cache of the host
+func registryCacheState(ctx context.Context, t *testing.T) registryCacheStatus {
+	rr, err := Run(t, exec.CommandContext(ctx, Target(), "registry-cache", "status", "-o", "json"))
+	if err != nil {
+		t.Fatalf("%q failed: %v", rr.Command(), err)
+	}
+	var state registryCacheStatus
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &state); err != nil {
+		t.Fatalf("failed to decode the registry cache state %q: %v", rr.Stdout.String(), err)
+	}
+	return state
+}